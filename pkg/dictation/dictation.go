@@ -0,0 +1,262 @@
+// Package dictation turns recorded clinical dictation into a sectioned note:
+// it transcribes the audio with clinical keyword biasing, then extracts the
+// sections (HPI, assessment, plan, ...) with a structured generator.
+package dictation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Section names a part of a clinical note.
+type Section string
+
+const (
+	SectionChiefComplaint Section = "chief_complaint"
+	SectionHPI            Section = "hpi"
+	SectionROS            Section = "review_of_systems"
+	SectionMedications    Section = "medications"
+	SectionAllergies      Section = "allergies"
+	SectionPhysicalExam   Section = "physical_exam"
+	SectionResults        Section = "results"
+	SectionAssessment     Section = "assessment"
+	SectionPlan           Section = "plan"
+)
+
+// DefaultSections are extracted when Config.Sections is empty, in note order.
+var DefaultSections = []Section{
+	SectionChiefComplaint,
+	SectionHPI,
+	SectionROS,
+	SectionMedications,
+	SectionAllergies,
+	SectionPhysicalExam,
+	SectionResults,
+	SectionAssessment,
+	SectionPlan,
+}
+
+var sectionDescriptions = map[Section]string{
+	SectionChiefComplaint: "the reason for the visit in the patient's words",
+	SectionHPI:            "history of present illness: onset, course and context of the current problem",
+	SectionROS:            "review of systems: symptoms asked about by system",
+	SectionMedications:    "current medications with doses",
+	SectionAllergies:      "allergies and reactions",
+	SectionPhysicalExam:   "examination findings and vital signs",
+	SectionResults:        "laboratory, imaging and other test results",
+	SectionAssessment:     "the clinician's diagnoses and clinical reasoning",
+	SectionPlan:           "orders, treatment changes, referrals and follow-up",
+}
+
+// Speaker roles reported in Note.Speakers and NoteSection.Speaker.
+const (
+	SpeakerClinician = "clinician"
+	SpeakerPatient   = "patient"
+	SpeakerOther     = "other"
+)
+
+// DefaultKeywords bias transcription towards clinical terms that speech
+// models commonly mishear. Config.Audio.Keywords are added to them.
+var DefaultKeywords = []model.AudioKeyword{
+	{Word: "HPI", CommonMistypes: []string{"h p i", "HBI"}, Definition: "History of present illness section."},
+	{Word: "eGFR", CommonMistypes: []string{"e g f r", "EGFR", "egg fr"}, Definition: "Estimated glomerular filtration rate."},
+	{Word: "creatinine", CommonMistypes: []string{"creating in", "creatine"}, Definition: "Serum marker of kidney function."},
+	{Word: "BUN", CommonMistypes: []string{"bun", "b u n"}, Definition: "Blood urea nitrogen."},
+	{Word: "hyperkalemia", CommonMistypes: []string{"hyper kalemia", "hypercalemia"}, Definition: "High serum potassium."},
+	{Word: "proteinuria", CommonMistypes: []string{"protein urea", "protein uria"}, Definition: "Protein in the urine."},
+	{Word: "CKD", CommonMistypes: []string{"c k d", "CKT"}, Definition: "Chronic kidney disease."},
+	{Word: "PRN", CommonMistypes: []string{"p r n"}, Definition: "As needed (medication frequency)."},
+	{Word: "b.i.d.", CommonMistypes: []string{"bid", "b i d"}, Definition: "Twice daily (medication frequency)."},
+	{Word: "furosemide", CommonMistypes: []string{"fur a semide", "furosemid"}, Definition: "Loop diuretic."},
+	{Word: "lisinopril", CommonMistypes: []string{"lysine april", "lisinapril"}, Definition: "ACE inhibitor."},
+}
+
+// Config configures a Dictation.
+type Config struct {
+	// Audio is passed to the transcription generator; DefaultKeywords are
+	// prepended to its keywords unless a custom Prompt is set.
+	Audio model.AudioOptions
+	// Sections to extract, in note order (default DefaultSections).
+	Sections []Section
+	// GeneratorOptions are passed to the extraction generator.
+	GeneratorOptions []model.GeneratorOption
+}
+
+// NoteSection is the text of one section and who said it.
+type NoteSection struct {
+	Section Section `json:"section"`
+	Text    string  `json:"text"`
+	// Speaker is the role the content came from: clinician, patient or other.
+	Speaker string `json:"speaker,omitempty"`
+}
+
+// Note is a sectioned dictation.
+type Note struct {
+	// Sections holds the non-empty configured sections in note order.
+	Sections []NoteSection `json:"sections"`
+	// Speakers lists the roles heard in the dictation.
+	Speakers []string `json:"speakers,omitempty"`
+	// Transcript is the text the note was extracted from.
+	Transcript string `json:"-"`
+}
+
+// Section returns the text of name, or "" when the note has no such section.
+func (n Note) Section(name Section) string {
+	texts := make([]string, 0, 1)
+	for _, section := range n.Sections {
+		if section.Section == name {
+			texts = append(texts, section.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Dictation transcribes and sections clinical dictation.
+type Dictation struct {
+	transcribe model.NewAudioTranscriptionGeneratorFunc
+	extract    model.NewStructureContentGeneratorFunc[Note]
+	cfg        Config
+}
+
+// New returns a Dictation that transcribes with transcribe (any provider's
+// NewAudioTranscriptionGenerator) and extracts sections with extract (for
+// example openai.NewStructureContentGenerator[dictation.Note]).
+func New(transcribe model.NewAudioTranscriptionGeneratorFunc, extract model.NewStructureContentGeneratorFunc[Note], cfg Config) (*Dictation, error) {
+	if transcribe == nil || extract == nil {
+		return nil, utils.WrapIfNotNil(errors.New("transcription and extraction generator factories are required"))
+	}
+	if len(cfg.Sections) == 0 {
+		cfg.Sections = DefaultSections
+	}
+	cfg.Sections = append([]Section(nil), cfg.Sections...)
+	cfg.GeneratorOptions = append([]model.GeneratorOption(nil), cfg.GeneratorOptions...)
+	if strings.TrimSpace(cfg.Audio.Prompt) == "" {
+		cfg.Audio.Keywords = append(append([]model.AudioKeyword(nil), DefaultKeywords...), cfg.Audio.Keywords...)
+	}
+	return &Dictation{transcribe: transcribe, extract: extract, cfg: cfg}, nil
+}
+
+// Transcribe transcribes the dictation at filePath and sections it. The
+// metadata sums the usage of both steps.
+func (d *Dictation) Transcribe(ctx context.Context, filePath string) (Note, model.GenerationMetadata, error) {
+	gen, err := d.transcribe(filePath, d.cfg.Audio)
+	if err != nil {
+		return Note{}, nil, utils.WrapIfNotNil(err)
+	}
+	transcript, transcriptMeta, err := gen.Generate(ctx)
+	if err != nil {
+		return Note{}, transcriptMeta, utils.WrapIfNotNil(err)
+	}
+
+	note, extractMeta, err := d.Extract(ctx, transcript)
+	meta := model.GenerationMetadata{}
+	model.MergeUsageMetadata(meta, transcriptMeta)
+	model.MergeUsageMetadata(meta, extractMeta)
+	if err != nil {
+		return Note{Transcript: transcript}, meta, utils.WrapIfNotNil(err)
+	}
+	return note, meta, nil
+}
+
+// Extract sections an existing transcript.
+func (d *Dictation) Extract(ctx context.Context, transcript string) (Note, model.GenerationMetadata, error) {
+	if strings.TrimSpace(transcript) == "" {
+		return Note{}, nil, utils.WrapIfNotNil(errors.New("transcript is required"))
+	}
+
+	gen, err := d.extract(buildExtractionPrompt(d.cfg.Sections, transcript), d.cfg.GeneratorOptions...)
+	if err != nil {
+		return Note{}, nil, utils.WrapIfNotNil(err)
+	}
+	gen.AddPromptContext(ctx, model.ContextMessageTypeSystem, extractionInstructions)
+
+	note, meta, err := gen.Generate(ctx)
+	if err != nil {
+		return Note{Transcript: transcript}, meta, utils.WrapIfNotNil(err)
+	}
+	note = normalizeNote(note, d.cfg.Sections)
+	note.Transcript = transcript
+	return note, meta, nil
+}
+
+const extractionInstructions = "You structure clinical dictation into note sections. Use only what the transcript says; never add findings, doses or diagnoses. " +
+	"Dictation may include the patient or others speaking: label each section with the role its content came from (clinician, patient or other) " +
+	"and put only the clinician's own conclusions in the assessment and plan. Keep the clinician's wording, fix obvious transcription errors of medical terms, " +
+	"and leave out sections that were not dictated."
+
+func buildExtractionPrompt(sections []Section, transcript string) string {
+	var prompt strings.Builder
+	prompt.WriteString("Split the transcript into these sections:\n")
+	for _, section := range sections {
+		description := sectionDescriptions[section]
+		if description == "" {
+			description = strings.ReplaceAll(string(section), "_", " ")
+		}
+		fmt.Fprintf(&prompt, "- %s: %s\n", section, description)
+	}
+	prompt.WriteString("\nTranscript:\n")
+	prompt.WriteString(transcript)
+	return prompt.String()
+}
+
+// normalizeNote keeps the configured, non-empty sections in configured order,
+// merging repeated sections, and normalizes speaker roles.
+func normalizeNote(note Note, sections []Section) Note {
+	order := make(map[Section]int, len(sections))
+	for i, section := range sections {
+		order[section] = i
+	}
+
+	merged := make([]*NoteSection, len(sections))
+	for _, section := range note.Sections {
+		name := Section(strings.ToLower(strings.TrimSpace(string(section.Section))))
+		index, ok := order[name]
+		text := strings.TrimSpace(section.Text)
+		if !ok || text == "" {
+			continue
+		}
+		speaker := normalizeSpeaker(section.Speaker)
+		if merged[index] == nil {
+			merged[index] = &NoteSection{Section: name, Text: text, Speaker: speaker}
+			continue
+		}
+		merged[index].Text += "\n" + text
+		if merged[index].Speaker != speaker {
+			merged[index].Speaker = ""
+		}
+	}
+
+	out := Note{Sections: make([]NoteSection, 0, len(sections))}
+	for _, section := range merged {
+		if section != nil {
+			out.Sections = append(out.Sections, *section)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, speaker := range note.Speakers {
+		speaker = normalizeSpeaker(speaker)
+		if speaker != "" && !seen[speaker] {
+			seen[speaker] = true
+			out.Speakers = append(out.Speakers, speaker)
+		}
+	}
+	return out
+}
+
+func normalizeSpeaker(speaker string) string {
+	switch strings.ToLower(strings.TrimSpace(speaker)) {
+	case "":
+		return ""
+	case SpeakerClinician, "doctor", "physician", "provider", "nurse":
+		return SpeakerClinician
+	case SpeakerPatient:
+		return SpeakerPatient
+	default:
+		return SpeakerOther
+	}
+}
@@ -0,0 +1,117 @@
+package dictation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type DictationSuite struct {
+	suite.Suite
+}
+
+func TestDictationSuite(t *testing.T) {
+	suite.Run(t, new(DictationSuite))
+}
+
+type fakeTranscriber struct {
+	transcript string
+}
+
+func (g *fakeTranscriber) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	return g.transcript, model.GenerationMetadata{model.MetadataKeyInputTokens: "100", model.MetadataKeyModel: "whisper-1"}, nil
+}
+
+type fakeExtractor struct {
+	prompt   string
+	contexts []string
+	note     Note
+}
+
+func (g *fakeExtractor) Generate(ctx context.Context) (Note, model.GenerationMetadata, error) {
+	return g.note, model.GenerationMetadata{model.MetadataKeyInputTokens: "40", model.MetadataKeyModel: "gpt-test"}, nil
+}
+
+func (g *fakeExtractor) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.contexts = append(g.contexts, content)
+}
+
+func (g *fakeExtractor) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+func (s *DictationSuite) TestTranscribeBiasesKeywordsAndSectionsTranscript() {
+	var audioOpts model.AudioOptions
+	transcribe := func(filePath string, opts model.AudioOptions) (model.AudioTranscriptionGenerator, error) {
+		s.Equal("visit.wav", filePath)
+		audioOpts = opts
+		return &fakeTranscriber{transcript: "HPI: swelling for two weeks. Plan: increase furosemide."}, nil
+	}
+	extractor := &fakeExtractor{note: Note{
+		Sections: []NoteSection{
+			{Section: "PLAN", Text: " Increase furosemide to 40 mg b.i.d. ", Speaker: "Doctor"},
+			{Section: SectionHPI, Text: "Leg swelling for two weeks.", Speaker: "patient"},
+			{Section: SectionPlan, Text: "Recheck BMP in one week.", Speaker: "clinician"},
+			{Section: "billing", Text: "99214"},
+			{Section: SectionAllergies, Text: " "},
+		},
+		Speakers: []string{"physician", "patient", "clinician"},
+	}}
+	extract := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[Note], error) {
+		extractor.prompt = prompt
+		return extractor, nil
+	}
+
+	d, err := New(transcribe, extract, Config{
+		Audio:    model.AudioOptions{Keywords: []model.AudioKeyword{{Word: "torsemide"}}},
+		Sections: []Section{SectionHPI, SectionAllergies, SectionPlan},
+	})
+	s.Require().NoError(err)
+
+	note, meta, err := d.Transcribe(context.Background(), "visit.wav")
+	s.Require().NoError(err)
+
+	s.Len(audioOpts.Keywords, len(DefaultKeywords)+1)
+	s.Equal("torsemide", audioOpts.Keywords[len(audioOpts.Keywords)-1].Word)
+
+	s.Contains(extractor.prompt, "- hpi: history of present illness")
+	s.NotContains(extractor.prompt, "physical_exam")
+	s.Contains(extractor.prompt, "Plan: increase furosemide.")
+	s.Require().Len(extractor.contexts, 1)
+	s.Contains(extractor.contexts[0], "clinician, patient or other")
+
+	s.Equal([]NoteSection{
+		{Section: SectionHPI, Text: "Leg swelling for two weeks.", Speaker: SpeakerPatient},
+		{Section: SectionPlan, Text: "Increase furosemide to 40 mg b.i.d.\nRecheck BMP in one week.", Speaker: SpeakerClinician},
+	}, note.Sections)
+	s.Equal([]string{SpeakerClinician, SpeakerPatient}, note.Speakers)
+	s.Equal("Leg swelling for two weeks.", note.Section(SectionHPI))
+	s.Empty(note.Section(SectionAllergies))
+	s.Contains(note.Transcript, "HPI: swelling")
+
+	s.Equal("140", meta[model.MetadataKeyInputTokens])
+	s.Equal("gpt-test", meta[model.MetadataKeyModel])
+}
+
+func (s *DictationSuite) TestCustomPromptSkipsKeywordsAndInputsAreValidated() {
+	transcribe := func(filePath string, opts model.AudioOptions) (model.AudioTranscriptionGenerator, error) {
+		return &fakeTranscriber{}, nil
+	}
+	extract := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[Note], error) {
+		return &fakeExtractor{}, nil
+	}
+
+	d, err := New(transcribe, extract, Config{Audio: model.AudioOptions{Prompt: "Transcribe."}})
+	s.Require().NoError(err)
+	s.Empty(d.cfg.Audio.Keywords)
+	s.Equal(DefaultSections, d.cfg.Sections)
+
+	_, _, err = d.Extract(context.Background(), " ")
+	s.Error(err)
+	_, _, err = d.Transcribe(context.Background(), "empty.wav")
+	s.Error(err)
+
+	_, err = New(nil, extract, Config{})
+	s.Error(err)
+}
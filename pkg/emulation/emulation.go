@@ -0,0 +1,143 @@
+// Package emulation provides prompt-described tool calling for models that do
+// not accept native tool definitions. Tools are described in the prompt and the
+// model requests a call by replying with a JSON command or ReAct "Action:" lines
+// that are parsed and executed locally. NewStringContentGenerator wraps any
+// text-only provider with this loop.
+package emulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const toolCallKey = "tool_call"
+
+// ToolCall is a tool invocation parsed from model text output.
+type ToolCall struct {
+	Name      string
+	Arguments json.RawMessage
+}
+
+type toolDescription struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type toolCallCommand struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// BuildToolInstructions returns the system instruction that describes the
+// available tools and the JSON command protocol the model must follow.
+func BuildToolInstructions(tools []model.Tool) (string, error) {
+	descriptions, err := describeTools(tools)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+
+	return "You can call tools to help answer the request. Available tools (arguments are described by JSON schema):\n" +
+		descriptions + "\n\n" +
+		"To call a tool, reply with ONLY a JSON object of the form " +
+		`{"tool_call":{"name":"<tool name>","arguments":{...}}}` +
+		" and nothing else. Call one tool at a time; the tool result will be sent in the next message.\n" +
+		"When you have enough information, reply with your final answer and do not include a tool_call object.", nil
+}
+
+// ParseToolCall extracts a tool_call command from model output.
+// The boolean is false when the text is a final answer rather than a tool call.
+func ParseToolCall(text string) (ToolCall, bool) {
+	trimmed := strings.TrimSpace(text)
+	trimmed = strings.TrimPrefix(trimmed, "```json")
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	trimmed = strings.TrimSuffix(trimmed, "```")
+	trimmed = strings.TrimSpace(trimmed)
+
+	start := strings.Index(trimmed, "{")
+	for start >= 0 {
+		var envelope map[string]json.RawMessage
+		decoder := json.NewDecoder(strings.NewReader(trimmed[start:]))
+		if err := decoder.Decode(&envelope); err == nil {
+			return toolCallFromEnvelope(envelope)
+		}
+
+		next := strings.Index(trimmed[start+1:], "{")
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	return ToolCall{}, false
+}
+
+// FormatToolResult renders a tool result as the follow-up message sent back to the model.
+func FormatToolResult(name string, result any) (string, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return "Tool result for " + strings.TrimSpace(name) + ":\n" + string(resultBytes), nil
+}
+
+func describeTools(tools []model.Tool) (string, error) {
+	if len(tools) == 0 {
+		return "", utils.WrapIfNotNil(errors.New("at least one tool is required"))
+	}
+
+	descriptions := make([]toolDescription, 0, len(tools))
+	for _, tool := range tools {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			return "", utils.WrapIfNotNil(errors.New("tool name is required"))
+		}
+
+		parameters := map[string]any{
+			"type":       "object",
+			"properties": map[string]any{},
+		}
+		if tool.InputSchema != nil {
+			parameters = map[string]any(tool.InputSchema)
+		}
+
+		descriptions = append(descriptions, toolDescription{
+			Name:        name,
+			Description: strings.TrimSpace(tool.Description),
+			Parameters:  parameters,
+		})
+	}
+
+	descriptionBytes, err := json.Marshal(descriptions)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return string(descriptionBytes), nil
+}
+
+func toolCallFromEnvelope(envelope map[string]json.RawMessage) (ToolCall, bool) {
+	raw, found := envelope[toolCallKey]
+	if !found {
+		return ToolCall{}, false
+	}
+
+	var command toolCallCommand
+	if err := json.Unmarshal(raw, &command); err != nil {
+		return ToolCall{}, false
+	}
+
+	name := strings.TrimSpace(command.Name)
+	if name == "" {
+		return ToolCall{}, false
+	}
+
+	arguments := bytes.TrimSpace(command.Arguments)
+	if len(arguments) == 0 || bytes.Equal(arguments, []byte("null")) {
+		arguments = []byte(`{}`)
+	}
+	return ToolCall{Name: name, Arguments: json.RawMessage(arguments)}, true
+}
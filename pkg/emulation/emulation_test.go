@@ -0,0 +1,112 @@
+package emulation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmulationSuite struct {
+	suite.Suite
+}
+
+func TestEmulationSuite(t *testing.T) {
+	suite.Run(t, new(EmulationSuite))
+}
+
+func (s *EmulationSuite) TestBuildToolInstructionsDescribesTools() {
+	instructions, err := BuildToolInstructions([]model.Tool{
+		{
+			Name:        "lookup_lab",
+			Description: "Look up a lab value",
+			InputSchema: model.JSONSchema{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string"},
+				},
+			},
+			Handler: func(ctx context.Context, args json.RawMessage) (any, error) { return nil, nil },
+		},
+	})
+
+	s.Require().NoError(err)
+	s.Contains(instructions, `"name":"lookup_lab"`)
+	s.Contains(instructions, "Look up a lab value")
+	s.Contains(instructions, `{"tool_call":{"name":"<tool name>","arguments":{...}}}`)
+}
+
+func (s *EmulationSuite) TestBuildToolInstructionsRequiresTools() {
+	_, err := BuildToolInstructions(nil)
+	s.Error(err)
+
+	_, err = BuildToolInstructions([]model.Tool{{Name: " "}})
+	s.Error(err)
+}
+
+func (s *EmulationSuite) TestParseToolCall() {
+	call, ok := ParseToolCall(`{"tool_call":{"name":"lookup_lab","arguments":{"name":"creatinine"}}}`)
+
+	s.Require().True(ok)
+	s.Equal("lookup_lab", call.Name)
+	s.JSONEq(`{"name":"creatinine"}`, string(call.Arguments))
+}
+
+func (s *EmulationSuite) TestParseToolCallWithSurroundingText() {
+	text := "I need the lab value first.\n```json\n{\"tool_call\": {\"name\": \"lookup_lab\"}}\n```"
+	call, ok := ParseToolCall(text)
+
+	s.Require().True(ok)
+	s.Equal("lookup_lab", call.Name)
+	s.JSONEq(`{}`, string(call.Arguments))
+}
+
+func (s *EmulationSuite) TestParseToolCallFinalAnswer() {
+	_, ok := ParseToolCall("Creatinine is 1.2 mg/dL.")
+	s.False(ok)
+
+	_, ok = ParseToolCall(`{"status":"ok"}`)
+	s.False(ok)
+
+	_, ok = ParseToolCall(`{"tool_call":{"name":""}}`)
+	s.False(ok)
+}
+
+func (s *EmulationSuite) TestFormatToolResult() {
+	message, err := FormatToolResult("lookup_lab", map[string]any{"value": 1.2})
+
+	s.Require().NoError(err)
+	s.Equal("Tool result for lookup_lab:\n{\"value\":1.2}", message)
+}
+
+func (s *EmulationSuite) TestParseActionReAct() {
+	call, ok := ParseAction("Thought: check labs\nAction: lookup_lab\nAction Input: {\"name\": \"potassium\"}")
+
+	s.Require().True(ok)
+	s.Equal("lookup_lab", call.Name)
+	s.JSONEq(`{"name":"potassium"}`, string(call.Arguments))
+}
+
+func (s *EmulationSuite) TestParseActionPlainTextInput() {
+	call, ok := ParseAction("Action: search\nAction Input: kidney function")
+
+	s.Require().True(ok)
+	s.Equal("search", call.Name)
+	s.JSONEq(`{"input":"kidney function"}`, string(call.Arguments))
+}
+
+func (s *EmulationSuite) TestParseActionFallsBackToJSONCommand() {
+	call, ok := ParseAction(`{"tool_call":{"name":"lookup_lab","arguments":{"name":"sodium"}}}`)
+
+	s.Require().True(ok)
+	s.Equal("lookup_lab", call.Name)
+}
+
+func (s *EmulationSuite) TestParseActionFinalAnswer() {
+	_, ok := ParseAction("Thought: done\nFinal Answer: all good")
+	s.False(ok)
+	s.Equal("all good", ExtractFinalAnswer("Thought: done\nFinal Answer: all good"))
+	s.Equal("plain", ExtractFinalAnswer(" plain "))
+}
@@ -0,0 +1,342 @@
+package emulation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/mcp"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+type toolHandler func(ctx context.Context, args json.RawMessage) (any, error)
+
+type textGenerator struct {
+	factory                model.NewStringContentGeneratorFunc
+	prompt                 string
+	opts                   []model.GeneratorOption
+	cfg                    model.GeneratorConfig
+	promptContextMu        sync.RWMutex
+	promptContexts         []*model.PromptContext
+	promptContextProviders []model.PromptContextProvider
+}
+
+// NewStringContentGenerator wraps a text-only provider factory with a ReAct-style
+// tool loop. Tools and MCP tools from opts are described in the prompt, executed
+// locally, and never passed to the wrapped provider, so any provider that can
+// produce text can use model.Tool handlers.
+//
+//	gen, err := emulation.NewStringContentGenerator(
+//	    ollama.NewStringContentGenerator,
+//	    "What is the latest creatinine?",
+//	    model.WithModel("gemma2:2b"),
+//	    model.WithTools(tools),
+//	)
+func NewStringContentGenerator(
+	factory model.NewStringContentGeneratorFunc,
+	prompt string,
+	opts ...model.GeneratorOption,
+) (model.ContentGenerator[string], error) {
+	if factory == nil {
+		return nil, utils.WrapIfNotNil(errors.New("generator factory is required"))
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("prompt is required"))
+	}
+
+	return &textGenerator{
+		factory: factory,
+		prompt:  prompt,
+		opts:    append([]model.GeneratorOption(nil), opts...),
+		cfg:     model.ResolveGeneratorOpts(opts...),
+	}, nil
+}
+
+func (g *textGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	log := logging.NewLogger(ctx)
+	g.promptContextMu.Lock()
+	defer g.promptContextMu.Unlock()
+
+	g.promptContexts = append(g.promptContexts, &model.PromptContext{
+		MessageType: messageType,
+		Content:     content,
+	})
+	log.Debugf("emulation.textGenerator.AddPromptContext total_contexts=%d", len(g.promptContexts))
+}
+
+func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+	if provider == nil {
+		return
+	}
+
+	g.promptContextMu.Lock()
+	defer g.promptContextMu.Unlock()
+	g.promptContextProviders = append(g.promptContextProviders, provider)
+	logging.NewLogger(ctx).Debugf(
+		"emulation.textGenerator.AddPromptContextProvider total_providers=%d",
+		len(g.promptContextProviders),
+	)
+}
+
+func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, "emulation", start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	meta := model.GenerationMetadata{}
+	defer func() {
+		meta[model.MetadataKeyLatencyMs] = strconv.FormatInt(time.Since(start).Milliseconds(), 10)
+	}()
+
+	log := logging.NewLogger(ctx)
+	contexts, err := g.resolvePromptContexts(ctx)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+
+	tools, handlers, cleanup, err := buildAllTools(ctx, g.cfg)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	defer cleanup()
+
+	// Tools are always executed here; the wrapped provider only ever sees text.
+	innerOpts := append(append([]model.GeneratorOption(nil), g.opts...), model.WithTools(nil), model.WithMCPTools(nil))
+	if g.cfg.ContextDedup != nil {
+		// Contexts are already deduplicated; skip the embedder on every round.
+		innerOpts = append(innerOpts, model.WithContextDedup(model.ContextDedupConfig{}))
+	}
+	if g.cfg.ContextCompression != nil {
+		// Contexts are already compressed; a ratio of 1 leaves them alone.
+		innerOpts = append(innerOpts, model.WithContextCompression(model.ContextCompressionConfig{TargetRatio: 1}))
+	}
+	if g.cfg.Disclosure != nil {
+		// The notice goes on the final answer only, not on every round.
+		innerOpts = append(innerOpts, model.WithDisclosure(model.DisclosureConfig{Notice: g.cfg.Disclosure.Notice, Placement: model.DisclosurePlacementMetadata}))
+	}
+	if g.cfg.MetricsRecorder != nil {
+		// The emulated generation is recorded once, not once per round.
+		innerOpts = append(innerOpts, model.WithMetricsRecorder(nil))
+	}
+
+	instructions := ""
+	if len(tools) > 0 {
+		instructions, err = BuildReActInstructions(tools)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+	}
+
+	log.Infof("prompt=%q context_count=%d tools=%d mcp_tools=%d", g.prompt, len(contexts), len(g.cfg.Tools), len(g.cfg.MCPTools))
+
+	var scratchpad strings.Builder
+	maxRounds := model.ResolveMaxToolRounds(g.cfg)
+	toolErrors := model.NewToolErrorPolicy(g.cfg)
+	var toolCalls []model.ToolCallRecord
+	for round := 0; round < maxRounds; round++ {
+		prompt := g.prompt
+		if scratchpad.Len() > 0 {
+			prompt += "\n\n" + scratchpad.String()
+		}
+
+		inner, err := g.factory(prompt, innerOpts...)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		if instructions != "" {
+			inner.AddPromptContext(ctx, model.ContextMessageTypeSystem, instructions)
+		}
+		if hasImages(contexts) {
+			// AddPromptContext carries text only; a provider keeps the images
+			// and the context order.
+			inner.AddPromptContextProvider(ctx, model.StaticPromptContextProvider(contexts...))
+		} else {
+			for _, contextItem := range contexts {
+				inner.AddPromptContext(ctx, contextItem.MessageType, contextItem.Content)
+			}
+		}
+
+		text, innerMeta, err := inner.Generate(ctx)
+		mergeMetadata(meta, innerMeta)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+
+		call, ok := ParseAction(text)
+		if len(tools) == 0 || !ok {
+			return model.ApplyDisclosure(g.cfg, ExtractFinalAnswer(text)), meta, nil
+		}
+
+		handler, found := handlers[call.Name]
+		var result any
+		if !found {
+			result = map[string]any{"error": fmt.Sprintf("unknown tool %q", call.Name)}
+		} else {
+			results := toolexec.Run(ctx, 1, 1, func(ctx context.Context, i int) (any, error) {
+				return handler(ctx, call.Arguments)
+			})
+			toolCalls = append(toolCalls, toolexec.Transcript(round+1, results, func(i int) (string, string) {
+				return call.Name, ""
+			})...)
+			model.SetToolCalls(meta, toolCalls)
+			result = results[0].Value
+			if callErr := results[0].Err; callErr != nil {
+				if abortErr := toolErrors.Handle(call.Name, callErr); abortErr != nil {
+					log.Errorf("error: %v", abortErr)
+					return "", meta, utils.WrapIfNotNil(abortErr)
+				}
+				result = model.ToolErrorResult(callErr)
+			}
+		}
+
+		resultBytes, err := json.Marshal(result)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+
+		scratchpad.WriteString(strings.TrimSpace(truncateAtObservation(text)))
+		scratchpad.WriteString("\n" + observationPrefix + " " + string(resultBytes) + "\n")
+		meta[model.MetadataKeyToolRounds] = strconv.Itoa(round + 1)
+	}
+
+	err = &model.MaxToolRoundsError{Limit: maxRounds}
+	log.Errorf("error: %v", err)
+	return "", meta, utils.WrapIfNotNil(err)
+}
+
+func (g *textGenerator) resolvePromptContexts(ctx context.Context) ([]*model.PromptContext, error) {
+	g.promptContextMu.RLock()
+	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
+	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
+	g.promptContextMu.RUnlock()
+
+	for _, provider := range providers {
+		provided, err := provider.GenerateContext(ctx)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		contexts = append(contexts, provided...)
+	}
+
+	out := make([]*model.PromptContext, 0, len(contexts))
+	for _, contextItem := range contexts {
+		if !contextItem.HasContent() {
+			continue
+		}
+		out = append(out, contextItem)
+	}
+	return out, nil
+}
+
+func hasImages(contexts []*model.PromptContext) bool {
+	for _, contextItem := range contexts {
+		if len(contextItem.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func buildAllTools(ctx context.Context, cfg model.GeneratorConfig) ([]model.Tool, map[string]toolHandler, func(), error) {
+	mcpTools, cleanup, err := mcp.ModelTools(ctx, cfg.MCPTools, cfg.MCPAdapterPool)
+	if err != nil {
+		return nil, nil, func() {}, utils.WrapIfNotNil(err)
+	}
+	combined := append(append([]model.Tool(nil), cfg.Tools...), mcpTools...)
+
+	combined = model.InterceptTools(combined, cfg.ToolInterceptors)
+	handlers := make(map[string]toolHandler, len(combined))
+	tools := make([]model.Tool, 0, len(combined))
+	for _, tool := range combined {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			cleanup()
+			return nil, nil, func() {}, utils.WrapIfNotNil(errors.New("tool name is required"))
+		}
+		if tool.Handler == nil {
+			cleanup()
+			return nil, nil, func() {}, utils.WrapIfNotNil(fmt.Errorf("tool handler is required for %q", name))
+		}
+		if _, exists := handlers[name]; exists {
+			cleanup()
+			return nil, nil, func() {}, utils.WrapIfNotNil(fmt.Errorf("duplicate tool name %q", name))
+		}
+
+		handlers[name] = tool.Call
+		tool.Name = name
+		tools = append(tools, tool)
+	}
+
+	return tools, handlers, cleanup, nil
+}
+
+// truncateAtObservation drops any Observation the model hallucinated after its action.
+func truncateAtObservation(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), observationPrefix) {
+			return strings.Join(lines[:i], "\n")
+		}
+	}
+	return text
+}
+
+// mergeMetadata copies provider/model keys and sums usage counters across rounds.
+func mergeMetadata(meta model.GenerationMetadata, roundMeta model.GenerationMetadata) {
+	if meta == nil || roundMeta == nil {
+		return
+	}
+
+	for _, key := range []string{model.MetadataKeyProvider, model.MetadataKeyModel, model.MetadataKeyResponseID, model.MetadataKeyResponseStatus} {
+		if value := strings.TrimSpace(roundMeta[key]); value != "" {
+			meta[key] = value
+		}
+	}
+
+	for _, key := range []string{
+		model.MetadataKeyInputTokens,
+		model.MetadataKeyOutputTokens,
+		model.MetadataKeyTotalTokens,
+		model.MetadataKeyCachedInputTokens,
+		model.MetadataKeyReasoningTokens,
+		model.MetadataKeyAPICalls,
+	} {
+		value, err := strconv.ParseInt(strings.TrimSpace(roundMeta[key]), 10, 64)
+		if err != nil {
+			continue
+		}
+		current, _ := strconv.ParseInt(meta[key], 10, 64)
+		meta[key] = strconv.FormatInt(current+value, 10)
+	}
+}
@@ -0,0 +1,166 @@
+package emulation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type GeneratorSuite struct {
+	suite.Suite
+}
+
+func TestGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(GeneratorSuite))
+}
+
+type scriptedGenerator struct {
+	prompt   string
+	contexts []*model.PromptContext
+	cfg      model.GeneratorConfig
+	reply    string
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	return g.reply, model.GenerationMetadata{
+		model.MetadataKeyProvider:    "scripted",
+		model.MetadataKeyModel:       "text-only",
+		model.MetadataKeyInputTokens: "10",
+		model.MetadataKeyAPICalls:    "1",
+	}, nil
+}
+
+func (g *scriptedGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.contexts = append(g.contexts, &model.PromptContext{MessageType: messageType, Content: content})
+}
+
+func (g *scriptedGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+func (s *GeneratorSuite) TestToolLoopWithReActReplies() {
+	replies := []string{
+		"Thought: I need the value.\nAction: lookup_lab\nAction Input: {\"name\": \"creatinine\"}\nObservation: made up",
+		"Thought: I now know the final answer\nFinal Answer: Creatinine is 1.2 mg/dL.",
+	}
+	var calls []*scriptedGenerator
+	factory := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		gen := &scriptedGenerator{prompt: prompt, cfg: model.ResolveGeneratorOpts(opts...), reply: replies[len(calls)]}
+		calls = append(calls, gen)
+		return gen, nil
+	}
+
+	var receivedArgs string
+	gen, err := NewStringContentGenerator(factory, "What is the creatinine?", model.WithTools([]model.Tool{
+		{
+			Name: "lookup_lab",
+			Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+				receivedArgs = string(args)
+				return map[string]any{"value": 1.2}, nil
+			},
+		},
+	}))
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeHuman, "patient context")
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Creatinine is 1.2 mg/dL.", text)
+	s.JSONEq(`{"name":"creatinine"}`, receivedArgs)
+
+	s.Require().Len(calls, 2)
+	s.Empty(calls[0].cfg.Tools)
+	s.Equal(model.ContextMessageTypeSystem, calls[0].contexts[0].MessageType)
+	s.Contains(calls[0].contexts[0].Content, "Action Input:")
+	s.Equal("patient context", calls[0].contexts[1].Content)
+	s.Contains(calls[1].prompt, "Observation: {\"value\":1.2}")
+	s.NotContains(calls[1].prompt, "made up")
+
+	s.Equal("scripted", meta[model.MetadataKeyProvider])
+	s.Equal("20", meta[model.MetadataKeyInputTokens])
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	s.NotEmpty(meta[model.MetadataKeyLatencyMs])
+	toolCalls, err := model.ParseToolCalls(meta)
+	s.Require().NoError(err)
+	s.Require().Len(toolCalls, 1)
+	s.Equal(model.ToolCallRecord{Round: 1, Name: "lookup_lab", DurationMs: toolCalls[0].DurationMs}, toolCalls[0])
+}
+
+func (s *GeneratorSuite) TestToolErrorsAreReportedToModel() {
+	replies := []string{
+		`{"tool_call":{"name":"missing","arguments":{}}}`,
+		"done",
+	}
+	var prompts []string
+	factory := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		prompts = append(prompts, prompt)
+		return &scriptedGenerator{prompt: prompt, reply: replies[len(prompts)-1]}, nil
+	}
+
+	gen, err := NewStringContentGenerator(factory, "prompt", model.WithTools([]model.Tool{
+		{Name: "present", Handler: func(ctx context.Context, args json.RawMessage) (any, error) { return nil, nil }},
+	}))
+	s.Require().NoError(err)
+
+	text, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("done", text)
+	s.Contains(prompts[1], `unknown tool \"missing\"`)
+}
+
+func (s *GeneratorSuite) TestExceedsToolLoopLimit() {
+	factory := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		return &scriptedGenerator{reply: "Action: echo\nAction Input: {}"}, nil
+	}
+
+	gen, err := NewStringContentGenerator(factory, "prompt", model.WithTools([]model.Tool{
+		{Name: "echo", Handler: func(ctx context.Context, args json.RawMessage) (any, error) { return "ok", nil }},
+	}))
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.Error(err)
+	s.Contains(err.Error(), "exceeded tool call loop limit")
+}
+
+func (s *GeneratorSuite) TestMetricsRecordedOncePerGeneration() {
+	replies := []string{"Action: echo\nAction Input: {}", "Final Answer: done"}
+	var calls []*scriptedGenerator
+	factory := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		gen := &scriptedGenerator{prompt: prompt, cfg: model.ResolveGeneratorOpts(opts...), reply: replies[len(calls)]}
+		calls = append(calls, gen)
+		return gen, nil
+	}
+	var records []model.GenerationRecord
+	gen, err := NewStringContentGenerator(factory, "prompt",
+		model.WithMetricsRecorder(model.MetricsRecorderFunc(func(ctx context.Context, record model.GenerationRecord) {
+			records = append(records, record)
+		})),
+		model.WithTools([]model.Tool{
+			{Name: "echo", Handler: func(ctx context.Context, args json.RawMessage) (any, error) { return "ok", nil }},
+		}),
+	)
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(calls, 2)
+	s.Nil(calls[0].cfg.MetricsRecorder)
+	s.Require().Len(records, 1)
+	s.Equal("scripted", records[0].Provider)
+	s.Equal(int64(20), records[0].InputTokens)
+	s.Equal(1, records[0].ToolRounds)
+}
+
+func (s *GeneratorSuite) TestConstructorValidation() {
+	_, err := NewStringContentGenerator(nil, "prompt")
+	s.Error(err)
+
+	_, err = NewStringContentGenerator(func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		return nil, nil
+	}, " ")
+	s.Error(err)
+}
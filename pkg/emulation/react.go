@@ -0,0 +1,126 @@
+package emulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const (
+	actionPrefix      = "Action:"
+	actionInputPrefix = "Action Input:"
+	observationPrefix = "Observation:"
+	finalAnswerPrefix = "Final Answer:"
+)
+
+// BuildReActInstructions returns the system instruction for the ReAct-style
+// protocol (Thought / Action / Action Input / Observation / Final Answer).
+// JSON tool_call commands are accepted as well when parsing replies.
+func BuildReActInstructions(tools []model.Tool) (string, error) {
+	descriptions, err := describeTools(tools)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+
+	return "You can use tools to help answer the request. Available tools (arguments are described by JSON schema):\n" +
+		descriptions + "\n\n" +
+		"Use the following format:\n" +
+		"Thought: your reasoning about what to do next\n" +
+		actionPrefix + " the tool name to call\n" +
+		actionInputPrefix + " the tool arguments as a JSON object\n" +
+		observationPrefix + " the tool result (provided to you; never write it yourself)\n" +
+		"... (Thought/Action/Action Input/Observation can repeat)\n" +
+		"Thought: I now know the final answer\n" +
+		finalAnswerPrefix + " the final answer to the request\n\n" +
+		"Call one tool at a time and stop after Action Input to wait for the Observation.", nil
+}
+
+// ParseAction extracts a tool call from model output, accepting either the
+// ReAct "Action:" / "Action Input:" lines or a JSON tool_call command.
+// The boolean is false when the text is a final answer.
+func ParseAction(text string) (ToolCall, bool) {
+	if call, ok := parseReActAction(text); ok {
+		return call, true
+	}
+	return ParseToolCall(text)
+}
+
+// ExtractFinalAnswer returns the text after "Final Answer:" when present,
+// otherwise the trimmed text.
+func ExtractFinalAnswer(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if index := strings.LastIndex(trimmed, finalAnswerPrefix); index >= 0 {
+		return strings.TrimSpace(trimmed[index+len(finalAnswerPrefix):])
+	}
+	return trimmed
+}
+
+func parseReActAction(text string) (ToolCall, bool) {
+	// A final answer wins over any action the model may have echoed before it.
+	if strings.Contains(text, finalAnswerPrefix) {
+		return ToolCall{}, false
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		name, found := strings.CutPrefix(strings.TrimSpace(line), actionPrefix)
+		if !found {
+			continue
+		}
+		name = strings.Trim(strings.TrimSpace(name), "`\"'")
+		if name == "" {
+			return ToolCall{}, false
+		}
+
+		input := ""
+		for j := i + 1; j < len(lines); j++ {
+			candidate := strings.TrimSpace(lines[j])
+			value, isInput := strings.CutPrefix(candidate, actionInputPrefix)
+			if !isInput {
+				continue
+			}
+
+			// The input may span several lines until the next Observation.
+			rest := []string{value}
+			for k := j + 1; k < len(lines); k++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[k]), observationPrefix) {
+					break
+				}
+				rest = append(rest, lines[k])
+			}
+			input = strings.Join(rest, "\n")
+			break
+		}
+
+		return ToolCall{Name: name, Arguments: normalizeActionInput(input)}, true
+	}
+	return ToolCall{}, false
+}
+
+func normalizeActionInput(input string) json.RawMessage {
+	trimmed := strings.TrimSpace(input)
+	trimmed = strings.TrimPrefix(trimmed, "```json")
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	trimmed = strings.TrimSuffix(trimmed, "```")
+	trimmed = strings.TrimSpace(trimmed)
+	if trimmed == "" {
+		return json.RawMessage(`{}`)
+	}
+
+	if start := strings.Index(trimmed, "{"); start >= 0 {
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(trimmed[start:])).Decode(&raw); err == nil {
+			return json.RawMessage(bytes.TrimSpace(raw))
+		}
+	}
+
+	// Plain-text input is passed as {"input": "..."} so handlers always receive an object.
+	encoded, err := json.Marshal(map[string]string{"input": trimmed})
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+	return json.RawMessage(encoded)
+}
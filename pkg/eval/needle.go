@@ -0,0 +1,258 @@
+// Package eval measures how well a provider and model serve a task before it
+// is adopted. RunNeedle is a needle-in-a-haystack test: it hides facts at
+// several depths of context built from the caller's own documents and checks
+// that the model can recall them, producing a report to compare models for
+// RAG.
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Defaults used when NeedleConfig leaves a field empty.
+var (
+	DefaultContextSizes = []int{1000, 4000, 16000}
+	DefaultDepths       = []float64{0, 0.25, 0.5, 0.75, 1}
+	DefaultNeedle       = Needle{
+		Fact:     "The archive passphrase is violet-harbor-42.",
+		Question: "What is the archive passphrase?",
+		Answer:   "violet-harbor-42",
+	}
+)
+
+// Needle is a fact hidden in the context and the question that recalls it.
+// A response counts as a recall when it contains Answer, ignoring case.
+type Needle struct {
+	Fact     string `json:"fact"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// NeedleConfig configures RunNeedle.
+type NeedleConfig struct {
+	// Documents are the haystack, for example the caller's own clinical notes
+	// or guidelines. They are joined and repeated until each context size is
+	// reached, so one document is enough.
+	Documents []string
+	// Needles are hidden one at a time (default DefaultNeedle). Use facts the
+	// model cannot know or guess.
+	Needles []Needle
+	// ContextSizes are haystack sizes in estimated tokens (see
+	// model.EstimateTokens; default DefaultContextSizes).
+	ContextSizes []int
+	// Depths place the needle as a fraction of the haystack, from 0 (start)
+	// to 1 (end) (default DefaultDepths).
+	Depths []float64
+	// GeneratorOptions are passed to every generator, for example WithModel.
+	GeneratorOptions []model.GeneratorOption
+}
+
+// NeedleResult is one trial: one needle at one depth of one context size.
+type NeedleResult struct {
+	ContextTokens int           `json:"context_tokens"`
+	Depth         float64       `json:"depth"`
+	Needle        int           `json:"needle"`
+	Found         bool          `json:"found"`
+	Response      string        `json:"response,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	Latency       time.Duration `json:"latency"`
+}
+
+// NeedleReport is the outcome of RunNeedle. Failed generations count as
+// misses.
+type NeedleReport struct {
+	Provider string         `json:"provider,omitempty"`
+	Model    string         `json:"model,omitempty"`
+	Results  []NeedleResult `json:"results"`
+	// Recall is the share of trials whose answer was found.
+	Recall float64 `json:"recall"`
+}
+
+// RecallAt returns the recall of the trials at one context size and depth,
+// and false when there were none.
+func (r NeedleReport) RecallAt(contextTokens int, depth float64) (float64, bool) {
+	trials, found := 0, 0
+	for _, result := range r.Results {
+		if result.ContextTokens == contextTokens && result.Depth == depth {
+			trials++
+			if result.Found {
+				found++
+			}
+		}
+	}
+	if trials == 0 {
+		return 0, false
+	}
+	return float64(found) / float64(trials), true
+}
+
+// Table renders recall as a text grid with one row per context size and one
+// column per depth.
+func (r NeedleReport) Table() string {
+	sizes, depths := map[int]bool{}, map[float64]bool{}
+	for _, result := range r.Results {
+		sizes[result.ContextTokens] = true
+		depths[result.Depth] = true
+	}
+	sortedSizes := make([]int, 0, len(sizes))
+	for size := range sizes {
+		sortedSizes = append(sortedSizes, size)
+	}
+	sort.Ints(sortedSizes)
+	sortedDepths := make([]float64, 0, len(depths))
+	for depth := range depths {
+		sortedDepths = append(sortedDepths, depth)
+	}
+	sort.Float64s(sortedDepths)
+
+	var b strings.Builder
+	b.WriteString("tokens")
+	for _, depth := range sortedDepths {
+		fmt.Fprintf(&b, "\t%.0f%%", depth*100)
+	}
+	b.WriteString("\n")
+	for _, size := range sortedSizes {
+		fmt.Fprintf(&b, "%d", size)
+		for _, depth := range sortedDepths {
+			recall, _ := r.RecallAt(size, depth)
+			fmt.Fprintf(&b, "\t%.2f", recall)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "recall %.2f over %d trials\n", r.Recall, len(r.Results))
+	return b.String()
+}
+
+// RunNeedle runs every needle at every context size and depth against
+// generators from factory, one trial at a time. The haystack is sent as a
+// human prompt context and the needle's question as the prompt. It stops
+// with the context's error when ctx is cancelled; other generation errors are
+// recorded in the trial.
+func RunNeedle(ctx context.Context, factory model.NewStringContentGeneratorFunc, cfg NeedleConfig) (NeedleReport, error) {
+	if factory == nil {
+		return NeedleReport{}, utils.WrapIfNotNil(errors.New("generator factory is required"))
+	}
+	corpus := strings.TrimSpace(strings.Join(cfg.Documents, "\n\n"))
+	if corpus == "" {
+		return NeedleReport{}, utils.WrapIfNotNil(errors.New("at least one non-empty document is required"))
+	}
+	needles := cfg.Needles
+	if len(needles) == 0 {
+		needles = []Needle{DefaultNeedle}
+	}
+	for i, needle := range needles {
+		if strings.TrimSpace(needle.Fact) == "" || strings.TrimSpace(needle.Question) == "" || strings.TrimSpace(needle.Answer) == "" {
+			return NeedleReport{}, utils.WrapIfNotNil(fmt.Errorf("needle %d needs a fact, question and answer", i))
+		}
+	}
+	sizes := cfg.ContextSizes
+	if len(sizes) == 0 {
+		sizes = DefaultContextSizes
+	}
+	depths := cfg.Depths
+	if len(depths) == 0 {
+		depths = DefaultDepths
+	}
+	for _, depth := range depths {
+		if depth < 0 || depth > 1 {
+			return NeedleReport{}, utils.WrapIfNotNil(fmt.Errorf("depth %v is outside [0, 1]", depth))
+		}
+	}
+
+	log := logging.NewLogger(ctx)
+	report := NeedleReport{}
+	found := 0
+	for _, size := range sizes {
+		if size <= 0 {
+			return NeedleReport{}, utils.WrapIfNotNil(fmt.Errorf("context size must be positive, got %d", size))
+		}
+		haystack := buildHaystack(corpus, size)
+		for _, depth := range depths {
+			for index, needle := range needles {
+				if err := ctx.Err(); err != nil {
+					return report, utils.WrapIfNotNil(err)
+				}
+				result, meta := runTrial(ctx, factory, cfg.GeneratorOptions, insertNeedle(haystack, needle.Fact, depth), needle)
+				result.ContextTokens, result.Depth, result.Needle = size, depth, index
+				if result.Found {
+					found++
+				}
+				if report.Provider == "" && meta != nil {
+					report.Provider = meta[model.MetadataKeyProvider]
+					report.Model = meta[model.MetadataKeyModel]
+				}
+				log.Debugf("needle trial tokens=%d depth=%v needle=%d found=%t", size, depth, index, result.Found)
+				report.Results = append(report.Results, result)
+			}
+		}
+	}
+	report.Recall = float64(found) / float64(len(report.Results))
+	return report, nil
+}
+
+func runTrial(
+	ctx context.Context,
+	factory model.NewStringContentGeneratorFunc,
+	opts []model.GeneratorOption,
+	haystack string,
+	needle Needle,
+) (NeedleResult, model.GenerationMetadata) {
+	start := time.Now()
+	prompt := needle.Question + " Answer only from the documents above, as briefly as possible."
+	gen, err := factory(prompt, opts...)
+	if err != nil {
+		return NeedleResult{Error: err.Error()}, nil
+	}
+	gen.AddPromptContext(ctx, model.ContextMessageTypeHuman, haystack)
+	response, meta, err := gen.Generate(ctx)
+	result := NeedleResult{Response: response, Latency: time.Since(start)}
+	if err != nil {
+		result.Error = err.Error()
+		return result, meta
+	}
+	result.Found = strings.Contains(strings.ToLower(response), strings.ToLower(strings.TrimSpace(needle.Answer)))
+	return result, meta
+}
+
+// buildHaystack repeats corpus until it reaches tokens estimated tokens and
+// cuts it there.
+func buildHaystack(corpus string, tokens int) string {
+	var b strings.Builder
+	for model.EstimateTokens(b.String()) < tokens {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(corpus)
+	}
+	runes := []rune(b.String())
+	// The longest prefix whose estimate still fits.
+	limit := sort.Search(len(runes)+1, func(n int) bool {
+		return model.EstimateTokens(string(runes[:n])) > tokens
+	}) - 1
+	return string(runes[:limit])
+}
+
+// insertNeedle places fact at depth of haystack, moved back to the nearest
+// sentence or paragraph boundary so it does not split a sentence.
+func insertNeedle(haystack string, fact string, depth float64) string {
+	runes := []rune(haystack)
+	position := int(float64(len(runes)) * depth)
+	for position > 0 && position < len(runes) {
+		previous := runes[position-1]
+		if previous == '\n' || (previous == ' ' && position > 1 && strings.ContainsRune(".!?", runes[position-2])) {
+			break
+		}
+		position--
+	}
+	before, after := string(runes[:position]), string(runes[position:])
+	return strings.TrimSpace(before + " " + fact + " " + after)
+}
@@ -0,0 +1,136 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type NeedleSuite struct {
+	suite.Suite
+}
+
+func TestNeedleSuite(t *testing.T) {
+	suite.Run(t, new(NeedleSuite))
+}
+
+// fakeReader answers with the passphrase only when it appears in the first
+// half of its context, like a model that loses track of late content.
+type fakeReader struct {
+	contexts []string
+	fail     bool
+}
+
+func (g *fakeReader) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	meta := model.GenerationMetadata{model.MetadataKeyProvider: "fake", model.MetadataKeyModel: "reader-1"}
+	if g.fail {
+		return "", meta, errors.New("upstream unavailable")
+	}
+	haystack := strings.Join(g.contexts, "\n")
+	index := strings.Index(haystack, DefaultNeedle.Fact)
+	if index >= 0 && index < len(haystack)/2 {
+		return "It is Violet-Harbor-42.", meta, nil
+	}
+	return "I could not find it.", meta, nil
+}
+
+func (g *fakeReader) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.contexts = append(g.contexts, content)
+}
+
+func (g *fakeReader) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+func readerFactory(fail bool) model.NewStringContentGeneratorFunc {
+	return func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		return &fakeReader{fail: fail}, nil
+	}
+}
+
+const document = "Patients with stage 3 chronic kidney disease should have eGFR checked regularly. " +
+	"Potassium and bicarbonate are reviewed at each visit.\n\nBlood pressure targets depend on proteinuria."
+
+func (s *NeedleSuite) TestReportsRecallByDepth() {
+	report, err := RunNeedle(context.Background(), readerFactory(false), NeedleConfig{
+		Documents:    []string{document},
+		ContextSizes: []int{200, 800},
+		Depths:       []float64{0, 0.25, 0.75, 1},
+	})
+	s.Require().NoError(err)
+
+	s.Equal("fake", report.Provider)
+	s.Equal("reader-1", report.Model)
+	s.Len(report.Results, 8)
+	s.InDelta(0.5, report.Recall, 1e-9)
+	for _, size := range []int{200, 800} {
+		recall, ok := report.RecallAt(size, 0.25)
+		s.True(ok)
+		s.Equal(1.0, recall)
+		recall, ok = report.RecallAt(size, 0.75)
+		s.True(ok)
+		s.Equal(0.0, recall)
+	}
+	_, ok := report.RecallAt(200, 0.5)
+	s.False(ok)
+
+	table := report.Table()
+	s.Contains(table, "tokens\t0%\t25%\t75%\t100%")
+	s.Contains(table, "200\t1.00\t1.00\t0.00\t0.00")
+	s.Contains(table, "recall 0.50 over 8 trials")
+}
+
+func (s *NeedleSuite) TestGenerationErrorsCountAsMisses() {
+	report, err := RunNeedle(context.Background(), readerFactory(true), NeedleConfig{
+		Documents:    []string{document},
+		ContextSizes: []int{100},
+		Depths:       []float64{0},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(report.Results, 1)
+	s.False(report.Results[0].Found)
+	s.Contains(report.Results[0].Error, "upstream unavailable")
+	s.Equal(0.0, report.Recall)
+}
+
+func (s *NeedleSuite) TestValidatesConfig() {
+	_, err := RunNeedle(context.Background(), readerFactory(false), NeedleConfig{})
+	s.Error(err)
+
+	_, err = RunNeedle(context.Background(), readerFactory(false), NeedleConfig{
+		Documents: []string{document},
+		Depths:    []float64{1.5},
+	})
+	s.Error(err)
+
+	_, err = RunNeedle(context.Background(), readerFactory(false), NeedleConfig{
+		Documents: []string{document},
+		Needles:   []Needle{{Fact: "x"}},
+	})
+	s.Error(err)
+}
+
+func (s *NeedleSuite) TestStopsWhenCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := RunNeedle(ctx, readerFactory(false), NeedleConfig{Documents: []string{document}})
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *NeedleSuite) TestHaystackFitsSizeAndKeepsSentences() {
+	haystack := buildHaystack(document, 300)
+	s.LessOrEqual(model.EstimateTokens(haystack), 300)
+	s.GreaterOrEqual(model.EstimateTokens(haystack), 299)
+
+	placed := insertNeedle(haystack, DefaultNeedle.Fact, 0.5)
+	index := strings.Index(placed, DefaultNeedle.Fact)
+	s.Require().Greater(index, 0)
+	before := strings.TrimRight(placed[:index], " ")
+	s.True(strings.HasSuffix(before, ".") || strings.HasSuffix(before, "\n"), "needle split a sentence: %q", before[len(before)-20:])
+
+	s.True(strings.HasPrefix(insertNeedle(haystack, DefaultNeedle.Fact, 0), DefaultNeedle.Fact))
+	s.True(strings.HasSuffix(insertNeedle(haystack, DefaultNeedle.Fact, 1), DefaultNeedle.Fact))
+}
@@ -0,0 +1,325 @@
+// Package feedback captures user feedback on generations (thumbs up/down,
+// corrections, comments) and ties it to the generation it rates. A Recorder
+// remembers generations by response ID and by the caller's correlation ID
+// through Track, and Record sends each Feedback, joined with the tracked
+// provider, model, tenant and prompt version, to pluggable Sinks for later
+// evaluation.
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// DefaultMaxTracked is the number of generations a Recorder remembers when
+// WithMaxTracked is not set; the oldest are forgotten first.
+const DefaultMaxTracked = 10000
+
+var (
+	// ErrMissingTarget is returned for feedback without a response or correlation ID.
+	ErrMissingTarget = errors.New("feedback needs a response ID or correlation ID")
+	// ErrEmptyFeedback is returned for feedback without a rating, correction or comment.
+	ErrEmptyFeedback = errors.New("feedback has no rating, correction or comment")
+)
+
+// Rating is a thumbs up or down; RatingNone leaves the generation unrated,
+// for example when only a correction is given.
+type Rating int
+
+const (
+	RatingThumbsDown Rating = -1
+	RatingNone       Rating = 0
+	RatingThumbsUp   Rating = 1
+)
+
+// Generation identifies the generation feedback refers to.
+type Generation struct {
+	ResponseID        string    `json:"response_id,omitempty"`
+	CorrelationID     string    `json:"correlation_id,omitempty"`
+	Provider          string    `json:"provider,omitempty"`
+	Model             string    `json:"model,omitempty"`
+	Tenant            string    `json:"tenant,omitempty"`
+	PromptVersion     string    `json:"prompt_version,omitempty"`
+	Experiment        string    `json:"experiment,omitempty"`
+	ExperimentVariant string    `json:"experiment_variant,omitempty"`
+	GeneratedAt       time.Time `json:"generated_at,omitzero"`
+}
+
+// Feedback is one piece of user feedback. ResponseID or CorrelationID names
+// the generation; Correction is the output the user expected.
+type Feedback struct {
+	ResponseID    string            `json:"response_id,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Rating        Rating            `json:"rating"`
+	Correction    string            `json:"correction,omitempty"`
+	Comment       string            `json:"comment,omitempty"`
+	UserID        string            `json:"user_id,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// Event is what sinks receive: the feedback and the generation it refers to.
+// Tracked is false when the Recorder did not know the generation, in which
+// case Generation only holds the IDs from the feedback.
+type Event struct {
+	Feedback   Feedback   `json:"feedback"`
+	Generation Generation `json:"generation"`
+	Tracked    bool       `json:"tracked"`
+	RecordedAt time.Time  `json:"recorded_at"`
+}
+
+// Sink stores feedback events.
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(ctx context.Context, event Event) error
+
+// Write calls f.
+func (f SinkFunc) Write(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithSink adds sinks that receive every recorded event.
+func WithSink(sinks ...Sink) Option {
+	return func(r *Recorder) {
+		for _, sink := range sinks {
+			if sink != nil {
+				r.sinks = append(r.sinks, sink)
+			}
+		}
+	}
+}
+
+// WithMaxTracked sets how many generations are remembered (default
+// DefaultMaxTracked). Values below 1 keep the default.
+func WithMaxTracked(n int) Option {
+	return func(r *Recorder) {
+		if n > 0 {
+			r.maxTracked = n
+		}
+	}
+}
+
+// WithClock overrides the clock used for event and tracking times.
+func WithClock(now func() time.Time) Option {
+	return func(r *Recorder) {
+		if now != nil {
+			r.now = now
+		}
+	}
+}
+
+// Recorder tracks generations and records feedback on them. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu            sync.Mutex
+	sinks         []Sink
+	maxTracked    int
+	now           func() time.Time
+	order         []*Generation
+	byResponse    map[string]*Generation
+	byCorrelation map[string]*Generation
+}
+
+// NewRecorder creates a Recorder with no tracked generations.
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{
+		maxTracked:    DefaultMaxTracked,
+		now:           time.Now,
+		byResponse:    map[string]*Generation{},
+		byCorrelation: map[string]*Generation{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// Track remembers the generation described by meta under its response ID and
+// under correlationID, the caller's own ID for it (for example a chat message
+// ID); either may be empty. It returns the tracked Generation.
+func (r *Recorder) Track(meta model.GenerationMetadata, correlationID string) Generation {
+	generation := GenerationFromMetadata(meta)
+	generation.CorrelationID = strings.TrimSpace(correlationID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	generation.GeneratedAt = r.now()
+	if generation.ResponseID == "" && generation.CorrelationID == "" {
+		return generation
+	}
+
+	tracked := &generation
+	if generation.ResponseID != "" {
+		r.byResponse[generation.ResponseID] = tracked
+	}
+	if generation.CorrelationID != "" {
+		r.byCorrelation[generation.CorrelationID] = tracked
+	}
+	r.order = append(r.order, tracked)
+	for len(r.order) > r.maxTracked {
+		r.forget(r.order[0])
+		r.order = r.order[1:]
+	}
+	return generation
+}
+
+// Record validates fb, joins it with the tracked generation and writes the
+// event to every sink. All sinks are tried; their errors are joined.
+func (r *Recorder) Record(ctx context.Context, fb Feedback) error {
+	fb.ResponseID = strings.TrimSpace(fb.ResponseID)
+	fb.CorrelationID = strings.TrimSpace(fb.CorrelationID)
+	if err := validate(fb); err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+
+	r.mu.Lock()
+	event := Event{Feedback: fb, RecordedAt: r.now()}
+	if generation, ok := r.lookup(fb); ok {
+		event.Generation = *generation
+		event.Tracked = true
+	} else {
+		event.Generation = Generation{ResponseID: fb.ResponseID, CorrelationID: fb.CorrelationID}
+	}
+	sinks := append([]Sink(nil), r.sinks...)
+	r.mu.Unlock()
+
+	logging.NewLogger(ctx).Debugf(
+		"feedback response_id=%q correlation_id=%q rating=%d tracked=%t provider=%q model=%q prompt_version=%q",
+		event.Generation.ResponseID,
+		event.Generation.CorrelationID,
+		fb.Rating,
+		event.Tracked,
+		event.Generation.Provider,
+		event.Generation.Model,
+		event.Generation.PromptVersion,
+	)
+
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Write(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utils.WrapIfNotNil(errors.Join(errs...))
+}
+
+// Lookup returns the tracked generation for a response or correlation ID.
+func (r *Recorder) Lookup(id string) (Generation, bool) {
+	id = strings.TrimSpace(id)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	generation, ok := r.lookup(Feedback{ResponseID: id, CorrelationID: id})
+	if !ok {
+		return Generation{}, false
+	}
+	return *generation, true
+}
+
+func (r *Recorder) lookup(fb Feedback) (*Generation, bool) {
+	if generation, ok := r.byResponse[fb.ResponseID]; ok && fb.ResponseID != "" {
+		return generation, true
+	}
+	if generation, ok := r.byCorrelation[fb.CorrelationID]; ok && fb.CorrelationID != "" {
+		return generation, true
+	}
+	return nil, false
+}
+
+// forget drops generation from the indexes unless a newer generation has
+// taken over its IDs.
+func (r *Recorder) forget(generation *Generation) {
+	if r.byResponse[generation.ResponseID] == generation {
+		delete(r.byResponse, generation.ResponseID)
+	}
+	if r.byCorrelation[generation.CorrelationID] == generation {
+		delete(r.byCorrelation, generation.CorrelationID)
+	}
+}
+
+func validate(fb Feedback) error {
+	if fb.ResponseID == "" && fb.CorrelationID == "" {
+		return ErrMissingTarget
+	}
+	if fb.Rating < RatingThumbsDown || fb.Rating > RatingThumbsUp {
+		return fmt.Errorf("invalid feedback rating %d", fb.Rating)
+	}
+	if fb.Rating == RatingNone && strings.TrimSpace(fb.Correction) == "" && strings.TrimSpace(fb.Comment) == "" {
+		return ErrEmptyFeedback
+	}
+	return nil
+}
+
+// GenerationFromMetadata reads the generation identity from provider metadata.
+func GenerationFromMetadata(meta model.GenerationMetadata) Generation {
+	return Generation{
+		ResponseID:        strings.TrimSpace(meta[model.MetadataKeyResponseID]),
+		Provider:          meta[model.MetadataKeyProvider],
+		Model:             meta[model.MetadataKeyModel],
+		Tenant:            meta[model.MetadataKeyTenant],
+		PromptVersion:     meta[model.MetadataKeyPromptVersion],
+		Experiment:        meta[model.MetadataKeyExperiment],
+		ExperimentVariant: meta[model.MetadataKeyExperimentVariant],
+	}
+}
+
+// MemorySink keeps events in memory, for tests and small tools.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Write stores event.
+func (s *MemorySink) Write(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Events returns a copy of the stored events in recording order.
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// JSONLSink writes each event as one JSON line to an io.Writer, such as a
+// file or a log shipper's stdin.
+type JSONLSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLSink returns a sink writing to w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w}
+}
+
+// Write appends event to the writer.
+func (s *JSONLSink) Write(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return utils.WrapIfNotNil(err)
+}
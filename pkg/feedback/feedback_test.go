@@ -0,0 +1,120 @@
+package feedback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type FeedbackSuite struct {
+	suite.Suite
+}
+
+func TestFeedbackSuite(t *testing.T) {
+	suite.Run(t, new(FeedbackSuite))
+}
+
+var fixedTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func fixedClock() time.Time {
+	return fixedTime
+}
+
+func (s *FeedbackSuite) TestRecordJoinsTrackedGeneration() {
+	sink := &MemorySink{}
+	recorder := NewRecorder(WithSink(sink), WithClock(fixedClock))
+	recorder.Track(model.GenerationMetadata{
+		model.MetadataKeyResponseID:        "resp_1",
+		model.MetadataKeyProvider:          "openai",
+		model.MetadataKeyModel:             "gpt-4o",
+		model.MetadataKeyTenant:            "acme",
+		model.MetadataKeyPromptVersion:     "v2",
+		model.MetadataKeyExperiment:        "tone",
+		model.MetadataKeyExperimentVariant: "formal",
+	}, "msg-42")
+
+	s.Require().NoError(recorder.Record(context.Background(), Feedback{CorrelationID: "msg-42", Rating: RatingThumbsDown, Correction: "Hi!"}))
+	s.Require().NoError(recorder.Record(context.Background(), Feedback{ResponseID: "resp_1", Rating: RatingThumbsUp}))
+
+	events := sink.Events()
+	s.Require().Len(events, 2)
+	want := Generation{
+		ResponseID:        "resp_1",
+		CorrelationID:     "msg-42",
+		Provider:          "openai",
+		Model:             "gpt-4o",
+		Tenant:            "acme",
+		PromptVersion:     "v2",
+		Experiment:        "tone",
+		ExperimentVariant: "formal",
+		GeneratedAt:       fixedTime,
+	}
+	for _, event := range events {
+		s.True(event.Tracked)
+		s.Equal(want, event.Generation)
+		s.Equal(fixedTime, event.RecordedAt)
+	}
+	s.Equal("Hi!", events[0].Feedback.Correction)
+}
+
+func (s *FeedbackSuite) TestUntrackedFeedbackIsStillRecorded() {
+	sink := &MemorySink{}
+	recorder := NewRecorder(WithSink(sink))
+
+	s.Require().NoError(recorder.Record(context.Background(), Feedback{ResponseID: " resp_9 ", Comment: "too long"}))
+	events := sink.Events()
+	s.Require().Len(events, 1)
+	s.False(events[0].Tracked)
+	s.Equal(Generation{ResponseID: "resp_9"}, events[0].Generation)
+}
+
+func (s *FeedbackSuite) TestValidation() {
+	recorder := NewRecorder()
+	s.ErrorIs(recorder.Record(context.Background(), Feedback{Rating: RatingThumbsUp}), ErrMissingTarget)
+	s.ErrorIs(recorder.Record(context.Background(), Feedback{ResponseID: "r"}), ErrEmptyFeedback)
+	s.Error(recorder.Record(context.Background(), Feedback{ResponseID: "r", Rating: 5}))
+}
+
+func (s *FeedbackSuite) TestOldestGenerationsAreForgotten() {
+	recorder := NewRecorder(WithMaxTracked(2))
+	recorder.Track(model.GenerationMetadata{model.MetadataKeyResponseID: "a"}, "")
+	recorder.Track(model.GenerationMetadata{model.MetadataKeyResponseID: "b"}, "")
+	recorder.Track(model.GenerationMetadata{model.MetadataKeyResponseID: "c"}, "")
+
+	_, ok := recorder.Lookup("a")
+	s.False(ok)
+	generation, ok := recorder.Lookup("c")
+	s.True(ok)
+	s.Equal("c", generation.ResponseID)
+}
+
+func (s *FeedbackSuite) TestSinkErrorsAreJoined() {
+	memory := &MemorySink{}
+	failing := SinkFunc(func(ctx context.Context, event Event) error {
+		return errors.New("sink down")
+	})
+	recorder := NewRecorder(WithSink(failing, memory))
+
+	err := recorder.Record(context.Background(), Feedback{ResponseID: "r", Rating: RatingThumbsUp})
+	s.ErrorContains(err, "sink down")
+	s.Len(memory.Events(), 1)
+}
+
+func (s *FeedbackSuite) TestJSONLSink() {
+	var buf bytes.Buffer
+	recorder := NewRecorder(WithSink(NewJSONLSink(&buf)), WithClock(fixedClock))
+
+	s.Require().NoError(recorder.Record(context.Background(), Feedback{CorrelationID: "m1", Rating: RatingThumbsUp, UserID: "u1"}))
+	var event Event
+	s.Require().NoError(json.Unmarshal(buf.Bytes(), &event))
+	s.Equal("u1", event.Feedback.UserID)
+	s.Equal(RatingThumbsUp, event.Feedback.Rating)
+	s.Equal("m1", event.Generation.CorrelationID)
+	s.Equal(byte('\n'), buf.Bytes()[buf.Len()-1])
+}
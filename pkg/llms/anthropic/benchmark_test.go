@@ -0,0 +1,136 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+type benchmarkAddress struct {
+	Street string `json:"street" jsonschema:"description=Street and number"`
+	City   string `json:"city"`
+	Zip    string `json:"zip,omitempty"`
+}
+
+type benchmarkPatient struct {
+	Name        string             `json:"name"`
+	Age         int                `json:"age"`
+	Conditions  []string           `json:"conditions"`
+	Addresses   []benchmarkAddress `json:"addresses"`
+	Labs        map[string]float64 `json:"labs"`
+	Medications []struct {
+		Name string  `json:"name"`
+		Dose float64 `json:"dose"`
+	} `json:"medications"`
+}
+
+const benchmarkPatientJSON = `{"name":"Ada","age":42,"conditions":["ckd"],"addresses":[{"street":"1 Main","city":"Oslo"}],"labs":{"egfr":48.5},"medications":[{"name":"lisinopril","dose":10}]}`
+
+func BenchmarkGenerateJSONSchema(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := model.StructuredOutputSchema[benchmarkPatient](model.GeneratorConfig{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildMessagesWithContext(b *testing.B) {
+	contexts := make([]*model.PromptContext, 0, 20)
+	for i := 0; i < 20; i++ {
+		messageType := model.ContextMessageTypeHuman
+		if i%2 == 1 {
+			messageType = model.ContextMessageTypeAssistant
+		}
+		contexts = append(contexts, &model.PromptContext{MessageType: messageType, Content: fmt.Sprintf("turn %d with some clinical context", i)})
+	}
+	contexts = append(contexts, &model.PromptContext{MessageType: model.ContextMessageTypeSystem, Content: "You are a nephrology assistant."})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := buildMessagesWithContext("Summarize the patient.", contexts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractJSONPayload(b *testing.B) {
+	text := "Here is the result:\n```json\n" + benchmarkPatientJSON + "\n```\nLet me know if you need more."
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if extractJSONPayload(text) == "" {
+			b.Fatal("empty payload")
+		}
+	}
+}
+
+// BenchmarkStructuredGenerateToolRound measures one full structured generation
+// with a single tool round against a local mock API, so the numbers reflect
+// client-side overhead (request building, JSON encoding, flow bookkeeping)
+// rather than network or model latency.
+func BenchmarkStructuredGenerateToolRound(b *testing.B) {
+	toolUse := []byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"lookup_labs","input":{"patient":"Ada"}}],"stop_reason":"tool_use","usage":{"input_tokens":50,"output_tokens":10}}`)
+	final, err := json.Marshal(map[string]any{
+		"id":          "msg_2",
+		"type":        "message",
+		"role":        "assistant",
+		"content":     []map[string]any{{"type": "text", "text": benchmarkPatientJSON}},
+		"stop_reason": "end_turn",
+		"usage":       map[string]any{"input_tokens": 80, "output_tokens": 40},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("content-type", "application/json")
+		if bytes.Contains(body, []byte(`"tool_result"`)) {
+			_, _ = w.Write(final)
+			return
+		}
+		_, _ = w.Write(toolUse)
+	}))
+	defer server.Close()
+
+	tool := model.Tool{
+		Name:        "lookup_labs",
+		Description: "Look up recent labs",
+		InputSchema: model.JSONSchema{"type": "object", "properties": map[string]any{"patient": map[string]any{"type": "string"}}},
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return map[string]any{"egfr": 48.5}, nil
+		},
+	}
+	opts := []model.GeneratorOption{
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithTools([]model.Tool{tool}),
+	}
+
+	previous := logging.GetLoggerFactory()
+	logging.SetLoggerFactory(logging.NopLoggerFactory{})
+	defer logging.SetLoggerFactory(previous)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gen, err := NewStructureContentGenerator[benchmarkPatient]("Extract the patient record.", opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "You are a nephrology assistant.")
+		if _, _, err := gen.Generate(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
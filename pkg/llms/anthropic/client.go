@@ -1,84 +1,166 @@
 package anthropic
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"cloud.google.com/go/auth"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 const (
-	providerName         = "anthropic"
-	defaultModelName     = "claude-3-7-sonnet-latest"
-	defaultBaseURL       = "https://api.anthropic.com"
-	anthropicVersion     = "2023-06-01"
-	anthropicMCPBeta     = "mcp-client-2025-11-20"
-	defaultMaxTokens     = 1024
-	maxToolRounds        = 12
-	defaultHTTPTimeout   = 90 * time.Second
-	envAnthropicAPIKey   = "ANTHROPIC_API_KEY"
-	envAnthropicBaseURL  = "ANTHROPIC_BASE_URL"
-	envAnthropicModel    = "ANTHROPIC_MODEL"
+	providerName        = "anthropic"
+	defaultModelName    = "claude-3-7-sonnet-latest"
+	defaultBaseURL      = "https://api.anthropic.com"
+	anthropicVersion    = "2023-06-01"
+	anthropicMCPBeta    = "mcp-client-2025-11-20"
+	defaultMaxTokens    = 1024
+	maxTemperature      = 1.0
+	defaultHTTPTimeout  = 90 * time.Second
+	envAnthropicAPIKey  = "ANTHROPIC_API_KEY"
+	envAnthropicBaseURL = "ANTHROPIC_BASE_URL"
+	envAnthropicModel   = "ANTHROPIC_MODEL"
+)
+
+// Thinking budgets for each ReasoningLevel. The API requires at least 1024
+// budget tokens, and the budget counts towards max_tokens.
+const (
+	minThinkingBudgetTokens  = 1024
+	lowThinkingBudgetTokens  = 1024
+	medThinkingBudgetTokens  = 4096
+	highThinkingBudgetTokens = 16384
+
+	// thinkingCharsPerToken converts thinking text into an approximate token
+	// count; the API folds thinking into output_tokens without a breakdown.
+	thinkingCharsPerToken = 4
+
+	// minThinkingTopP is the lowest top_p the API accepts with extended
+	// thinking enabled.
+	minThinkingTopP = 0.95
 )
 
 type apiClient struct {
 	httpClient *http.Client
 	baseURL    string
 	apiKey     string
+	platform   model.HostingPlatform
+	region     string
+	project    string
+	location   string
+
+	// gatewayHeaders are added to every request (see model.WithGateway).
+	gatewayHeaders map[string]string
+	// compressMinBytes is the body size from which requests are gzipped; 0
+	// disables compression (see model.WithRequestCompression).
+	compressMinBytes int
+
+	// awsCredentials signs Bedrock requests when no API key is set. It is
+	// loaded once per client and caches credentials until they expire.
+	awsCredentials aws.CredentialsProvider
+
+	gcpCredentialsMu sync.Mutex
+	gcpCredentials   *auth.Credentials
 }
 
 type flowUsageTotals struct {
-	APICalls          int
-	ToolRounds        int
-	InputTokens       int64
-	OutputTokens      int64
-	TotalTokens       int64
-	CachedInputTokens int64
-	ReasoningTokens   int64
+	APICalls                   int
+	ToolRounds                 int
+	ToolCalls                  []model.ToolCallRecord
+	InputTokens                int64
+	OutputTokens               int64
+	TotalTokens                int64
+	CachedInputTokens          int64
+	CacheReadInputTokens       int64
+	CacheCreationInputTokens   int64
+	CacheCreation5mInputTokens int64
+	CacheCreation1hInputTokens int64
+	ReasoningTokens            int64
+	ServiceTier                string
+	Gateway                    model.GatewayTotals
 }
 
 type anthropicUsage struct {
-	InputTokens        int64 `json:"input_tokens"`
-	OutputTokens       int64 `json:"output_tokens"`
-	CacheReadInput     int64 `json:"cache_read_input_tokens"`
-	CacheCreationInput int64 `json:"cache_creation_input_tokens"`
+	InputTokens        int64                   `json:"input_tokens"`
+	OutputTokens       int64                   `json:"output_tokens"`
+	CacheReadInput     int64                   `json:"cache_read_input_tokens"`
+	CacheCreationInput int64                   `json:"cache_creation_input_tokens"`
+	CacheCreation      *anthropicCacheCreation `json:"cache_creation,omitempty"`
+	ServiceTier        string                  `json:"service_tier,omitempty"`
+}
+
+// anthropicCacheCreation splits cache writes by TTL; 1h writes are billed at
+// a higher rate than 5m ones.
+type anthropicCacheCreation struct {
+	Ephemeral5mInputTokens int64 `json:"ephemeral_5m_input_tokens"`
+	Ephemeral1hInputTokens int64 `json:"ephemeral_1h_input_tokens"`
 }
 
 type anthropicContentBlock struct {
-	Type      string          `json:"type"`
-	Text      string          `json:"text,omitempty"`
-	ID        string          `json:"id,omitempty"`
-	Name      string          `json:"name,omitempty"`
-	Input     json.RawMessage `json:"input,omitempty"`
-	ToolUseID string          `json:"tool_use_id,omitempty"`
-	Content   json.RawMessage `json:"content,omitempty"`
-	IsError   bool            `json:"is_error,omitempty"`
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	Thinking     string                 `json:"thinking,omitempty"`
+	Signature    string                 `json:"signature,omitempty"`
+	Data         string                 `json:"data,omitempty"`
+	ID           string                 `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Input        json.RawMessage        `json:"input,omitempty"`
+	ToolUseID    string                 `json:"tool_use_id,omitempty"`
+	Content      json.RawMessage        `json:"content,omitempty"`
+	IsError      bool                   `json:"is_error,omitempty"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+	Source       *anthropicImageSource  `json:"source,omitempty"`
+	Title        string                 `json:"title,omitempty"`
+}
+
+// anthropicImageSource is the source of an image or document block: base64
+// data, a URL or, for plain-text documents, inline text.
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 type anthropicMessage struct {
-	Role    string                 `json:"role"`
+	Role    string                  `json:"role"`
 	Content []anthropicContentBlock `json:"content"`
 }
 
+// anthropicToolChoice constrains which tool the model calls: "auto", "any"
+// (some tool) or "tool" (the named tool). DisableParallelToolUse limits the
+// model to one tool call per response.
+type anthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
 type anthropicTool struct {
-	Type          string         `json:"type,omitempty"`
-	Name          string         `json:"name,omitempty"`
-	Description   string         `json:"description,omitempty"`
-	InputSchema   map[string]any `json:"input_schema,omitempty"`
-	MCPServerName string         `json:"mcp_server_name,omitempty"`
+	Type          string                            `json:"type,omitempty"`
+	Name          string                            `json:"name,omitempty"`
+	Description   string                            `json:"description,omitempty"`
+	InputSchema   map[string]any                    `json:"input_schema,omitempty"`
+	MCPServerName string                            `json:"mcp_server_name,omitempty"`
 	DefaultConfig *anthropicMCPToolConfig           `json:"default_config,omitempty"`
 	Configs       map[string]anthropicMCPToolConfig `json:"configs,omitempty"`
+	CacheControl  *anthropicCacheControl            `json:"cache_control,omitempty"`
+}
+
+// anthropicCacheControl marks a cache breakpoint: the prompt up to and
+// including the marked block is cached. Ephemeral entries live for 5 minutes.
+type anthropicCacheControl struct {
+	Type string `json:"type"`
 }
 
 type anthropicMCPToolConfig struct {
@@ -93,24 +175,60 @@ type anthropicMCPServer struct {
 	AuthorizationToken string `json:"authorization_token,omitempty"`
 }
 
+// anthropicThinking enables extended thinking; budget_tokens must be below
+// max_tokens.
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
 type anthropicMessageRequest struct {
 	Model       string               `json:"model"`
 	MaxTokens   int                  `json:"max_tokens"`
 	Temperature *float64             `json:"temperature,omitempty"`
+	TopP        *float64             `json:"top_p,omitempty"`
+	TopK        *int                 `json:"top_k,omitempty"`
+	StopSeqs    []string             `json:"stop_sequences,omitempty"`
+	Thinking    *anthropicThinking   `json:"thinking,omitempty"`
 	System      string               `json:"system,omitempty"`
 	Messages    []anthropicMessage   `json:"messages"`
 	Tools       []anthropicTool      `json:"tools,omitempty"`
 	MCPServers  []anthropicMCPServer `json:"mcp_servers,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+
+	// CacheSystem sends System as a text block carrying a cache breakpoint.
+	CacheSystem bool `json:"-"`
+	// ProviderParams are merged into the encoded body (see model.WithProviderParams).
+	ProviderParams map[string]any `json:"-"`
+}
+
+// MarshalJSON encodes System as a plain string unless CacheSystem needs it as
+// a content block with cache_control.
+func (r anthropicMessageRequest) MarshalJSON() ([]byte, error) {
+	type plainRequest anthropicMessageRequest
+	if !r.CacheSystem || r.System == "" {
+		return json.Marshal(plainRequest(r))
+	}
+	return json.Marshal(struct {
+		plainRequest
+		System []anthropicContentBlock `json:"system"`
+	}{
+		plainRequest: plainRequest(r),
+		System:       []anthropicContentBlock{{Type: "text", Text: r.System, CacheControl: ephemeralCacheControl()}},
+	})
 }
 
 type anthropicMessageResponse struct {
-	ID         string                 `json:"id"`
-	Type       string                 `json:"type"`
-	Role       string                 `json:"role"`
-	Model      string                 `json:"model"`
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Model      string                  `json:"model"`
 	Content    []anthropicContentBlock `json:"content"`
-	StopReason string                 `json:"stop_reason"`
-	Usage      *anthropicUsage        `json:"usage"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      *anthropicUsage         `json:"usage"`
+
+	// Header holds the HTTP response headers, for gateway metadata.
+	Header http.Header `json:"-"`
 }
 
 type anthropicErrorResponse struct {
@@ -121,58 +239,66 @@ type anthropicErrorResponse struct {
 }
 
 func newAPIClient(cfg model.GeneratorConfig) (*apiClient, error) {
-	apiKey := strings.TrimSpace(cfg.AuthToken)
-	if apiKey == "" {
-		apiKey = strings.TrimSpace(os.Getenv(envAnthropicAPIKey))
+	client, err := newPlatformAPIClient(cfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
 	}
+	client.gatewayHeaders, err = model.GatewayRequestHeaders(cfg.Gateway)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	client.compressMinBytes = model.ResolveRequestCompressionMinBytes(cfg)
+	return client, nil
+}
+
+func newPlatformAPIClient(cfg model.GeneratorConfig) (*apiClient, error) {
+	log := logging.NewLogger(context.Background())
+	switch platform := resolveHostingPlatform(cfg); platform {
+	case model.HostingPlatformBedrock:
+		return newBedrockAPIClient(cfg)
+	case model.HostingPlatformVertex:
+		return newVertexAPIClient(cfg)
+	case model.HostingPlatformDirect:
+	default:
+		return nil, utils.WrapIfNotNil(fmt.Errorf("unsupported hosting platform %q for anthropic provider", platform))
+	}
+
+	apiKey := model.EnvFallback(log, providerName, "auth_token", cfg.AuthToken, envAnthropicAPIKey)
 	if apiKey == "" {
 		return nil, utils.WrapIfNotNil(errors.New("auth token is required (set WithAuthToken or ANTHROPIC_API_KEY)"))
 	}
 
-	baseURL := strings.TrimSpace(cfg.URL)
-	if baseURL == "" {
-		baseURL = strings.TrimSpace(os.Getenv(envAnthropicBaseURL))
-	}
+	baseURL := model.EnvFallback(log, providerName, "url", cfg.URL, envAnthropicBaseURL)
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 	baseURL = strings.TrimSuffix(baseURL, "/")
 
 	return &apiClient{
-		httpClient: &http.Client{Timeout: defaultHTTPTimeout},
+		httpClient: model.NewHTTPClient(cfg, defaultHTTPTimeout),
 		baseURL:    baseURL,
 		apiKey:     apiKey,
+		platform:   model.HostingPlatformDirect,
 	}, nil
 }
 
 func (c *apiClient) createMessage(ctx context.Context, request anthropicMessageRequest, includeMCPBeta bool) (*anthropicMessageResponse, error) {
-	requestBits, err := json.Marshal(request)
+	httpRequest, requestBits, err := c.buildMessageHTTPRequest(ctx, request, includeMCPBeta)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
-
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		c.baseURL+"/v1/messages",
-		bytes.NewReader(requestBits),
-	)
-	if err != nil {
+	if err := c.authorizeRequest(ctx, httpRequest, requestBits, includeMCPBeta); err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
 
-	httpRequest.Header.Set("content-type", "application/json")
-	httpRequest.Header.Set("x-api-key", c.apiKey)
-	httpRequest.Header.Set("anthropic-version", anthropicVersion)
-	if includeMCPBeta {
-		httpRequest.Header.Set("anthropic-beta", anthropicMCPBeta)
-	}
-
 	httpResponse, err := c.httpClient.Do(httpRequest)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
 	defer httpResponse.Body.Close()
+	if err := model.DecompressResponse(httpResponse); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 
 	responseBits, err := io.ReadAll(httpResponse.Body)
 	if err != nil {
@@ -199,6 +325,7 @@ func (c *apiClient) createMessage(ctx context.Context, request anthropicMessageR
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
+	response.Header = httpResponse.Header
 	return &response, nil
 }
 
@@ -210,20 +337,78 @@ func resolveModelName(cfg model.GeneratorConfig) string {
 		}
 	}
 
-	fromEnv := strings.TrimSpace(os.Getenv(envAnthropicModel))
+	fromEnv := model.EnvFallback(logging.NewLogger(context.Background()), providerName, "model", "", envAnthropicModel)
 	if fromEnv != "" {
 		return fromEnv
 	}
-	return defaultModelName
+
+	switch resolveHostingPlatform(cfg) {
+	case model.HostingPlatformBedrock:
+		return defaultBedrockModelName
+	case model.HostingPlatformVertex:
+		return defaultVertexModelName
+	default:
+		return defaultModelName
+	}
 }
 
 func resolveMaxTokens(cfg model.GeneratorConfig) int {
 	if cfg.MaxTokens != nil && *cfg.MaxTokens > 0 {
 		return *cfg.MaxTokens
 	}
+	if budget := thinkingBudgetTokens(cfg.ReasoningLevel); budget > 0 {
+		// Leave the default answer allowance on top of the thinking budget.
+		return budget + defaultMaxTokens
+	}
 	return defaultMaxTokens
 }
 
+// thinkingBudgetTokens maps a reasoning level to a thinking budget. It returns
+// zero when thinking is off.
+func thinkingBudgetTokens(level *model.ReasoningLevel) int {
+	if level == nil {
+		return 0
+	}
+	switch *level {
+	case model.ReasoningLevelLow:
+		return lowThinkingBudgetTokens
+	case model.ReasoningLevelMed:
+		return medThinkingBudgetTokens
+	case model.ReasoningLevelHigh:
+		return highThinkingBudgetTokens
+	default:
+		return 0
+	}
+}
+
+// resolveThinking returns the thinking parameter for cfg, or nil when thinking
+// is off. An explicit max_tokens at or below the level budget shrinks the
+// budget to half of max_tokens so the answer keeps room;
+// normalizeGeneratorOptionsForProvider has already rejected limits too small
+// for the minimum budget.
+func resolveThinking(cfg model.GeneratorConfig) *anthropicThinking {
+	budget := thinkingBudgetTokens(cfg.ReasoningLevel)
+	if budget == 0 {
+		return nil
+	}
+	if maxTokens := resolveMaxTokens(cfg); budget >= maxTokens {
+		budget = max(maxTokens/2, minThinkingBudgetTokens)
+	}
+	return &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+}
+
+// estimateThinkingTokens approximates the tokens spent on thinking blocks.
+// Redacted thinking carries only encrypted data and is not counted.
+func estimateThinkingTokens(content []anthropicContentBlock) int64 {
+	chars := 0
+	for _, block := range content {
+		if block.Type == "thinking" {
+			chars += len(block.Thinking)
+		}
+	}
+	return int64((chars + thinkingCharsPerToken - 1) / thinkingCharsPerToken)
+}
+
 func initMetadata(modelName string) model.GenerationMetadata {
 	if strings.TrimSpace(modelName) == "" {
 		modelName = "unknown"
@@ -248,14 +433,30 @@ func accumulateUsageTotals(totals *flowUsageTotals, response *anthropicMessageRe
 	}
 
 	totals.APICalls++
+	totals.Gateway.Observe(response.Header)
 	if response.Usage == nil {
 		return
 	}
 
+	// Thinking is billed inside output_tokens, so the estimate never exceeds it.
+	totals.ReasoningTokens += min(estimateThinkingTokens(response.Content), response.Usage.OutputTokens)
 	totals.InputTokens += response.Usage.InputTokens
 	totals.OutputTokens += response.Usage.OutputTokens
 	totals.TotalTokens += response.Usage.InputTokens + response.Usage.OutputTokens
 	totals.CachedInputTokens += response.Usage.CacheReadInput + response.Usage.CacheCreationInput
+	totals.CacheReadInputTokens += response.Usage.CacheReadInput
+	totals.CacheCreationInputTokens += response.Usage.CacheCreationInput
+	if breakdown := response.Usage.CacheCreation; breakdown != nil {
+		totals.CacheCreation5mInputTokens += breakdown.Ephemeral5mInputTokens
+		totals.CacheCreation1hInputTokens += breakdown.Ephemeral1hInputTokens
+	} else {
+		// Responses without the breakdown predate 1h caching, so every
+		// write used the default 5m TTL.
+		totals.CacheCreation5mInputTokens += response.Usage.CacheCreationInput
+	}
+	if tier := strings.TrimSpace(response.Usage.ServiceTier); tier != "" {
+		totals.ServiceTier = tier
+	}
 }
 
 func applyAnthropicMetadata(meta model.GenerationMetadata, response *anthropicMessageResponse, totals flowUsageTotals) {
@@ -265,11 +466,20 @@ func applyAnthropicMetadata(meta model.GenerationMetadata, response *anthropicMe
 
 	meta[model.MetadataKeyAPICalls] = strconv.Itoa(totals.APICalls)
 	meta[model.MetadataKeyToolRounds] = strconv.Itoa(totals.ToolRounds)
+	model.SetToolCalls(meta, totals.ToolCalls)
 	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(totals.InputTokens, 10)
 	meta[model.MetadataKeyOutputTokens] = strconv.FormatInt(totals.OutputTokens, 10)
 	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(totals.TotalTokens, 10)
 	meta[model.MetadataKeyCachedInputTokens] = strconv.FormatInt(totals.CachedInputTokens, 10)
+	meta[model.MetadataKeyCacheReadInputTokens] = strconv.FormatInt(totals.CacheReadInputTokens, 10)
+	meta[model.MetadataKeyCacheCreationInputTokens] = strconv.FormatInt(totals.CacheCreationInputTokens, 10)
+	meta[model.MetadataKeyCacheCreation5mInputTokens] = strconv.FormatInt(totals.CacheCreation5mInputTokens, 10)
+	meta[model.MetadataKeyCacheCreation1hInputTokens] = strconv.FormatInt(totals.CacheCreation1hInputTokens, 10)
 	meta[model.MetadataKeyReasoningTokens] = strconv.FormatInt(totals.ReasoningTokens, 10)
+	if totals.ServiceTier != "" {
+		meta[model.MetadataKeyServiceTier] = totals.ServiceTier
+	}
+	totals.Gateway.Apply(meta)
 
 	if response == nil {
 		return
@@ -286,15 +496,91 @@ func applyAnthropicMetadata(meta model.GenerationMetadata, response *anthropicMe
 }
 
 func normalizeGeneratorOptionsForProvider(cfg model.GeneratorConfig, log logging.Logger) (model.GeneratorConfig, error) {
-	if cfg.ReasoningLevel != nil {
+	modelName := resolveModelName(cfg)
+	cfg, err := model.ApplyGenerationLimits(cfg, providerName, modelName, model.GenerationLimits{
+		MaxTemperature:  maxTemperature,
+		MaxOutputTokens: model.MaxOutputTokens(providerName, claudeModelFamily(modelName)),
+	}, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	if thinkingBudgetTokens(cfg.ReasoningLevel) > 0 {
+		if cfg.MaxTokens != nil && *cfg.MaxTokens > 0 && *cfg.MaxTokens <= minThinkingBudgetTokens {
+			if cfg.IgnoreInvalidGeneratorOptions {
+				if log != nil {
+					log.Warnf("ignoring reasoning level for anthropic provider: max tokens %d leaves no room for thinking", *cfg.MaxTokens)
+				}
+				model.LogOptionResolution(log, providerName, "reasoning_level", model.OptionActionDropped, fmt.Sprintf("max tokens %d leaves no room for thinking", *cfg.MaxTokens))
+				cfg.ReasoningLevel = nil
+			} else {
+				return cfg, utils.WrapIfNotNil(fmt.Errorf("max tokens must exceed %d when reasoning level is set for anthropic provider", minThinkingBudgetTokens))
+			}
+		}
+	}
+	if thinkingBudgetTokens(cfg.ReasoningLevel) > 0 && cfg.Temperature != nil {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring temperature for anthropic provider: not supported with extended thinking")
+			}
+			model.LogOptionResolution(log, providerName, "temperature", model.OptionActionDropped, "not supported with extended thinking")
+			cfg.Temperature = nil
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("temperature is not supported with reasoning level for anthropic provider"))
+		}
+	}
+	if thinkingBudgetTokens(cfg.ReasoningLevel) > 0 && cfg.TopK != nil {
 		if cfg.IgnoreInvalidGeneratorOptions {
 			if log != nil {
-				log.Warnf("ignoring reasoning level for anthropic provider")
+				log.Warnf("ignoring top_k for anthropic provider: not supported with extended thinking")
 			}
-			cfg.ReasoningLevel = nil
+			model.LogOptionResolution(log, providerName, "top_k", model.OptionActionDropped, "not supported with extended thinking")
+			cfg.TopK = nil
 		} else {
-			return cfg, utils.WrapIfNotNil(errors.New("reasoning level is not supported for anthropic provider"))
+			return cfg, utils.WrapIfNotNil(errors.New("top_k is not supported with reasoning level for anthropic provider"))
+		}
+	}
+	if thinkingBudgetTokens(cfg.ReasoningLevel) > 0 && cfg.TopP != nil && *cfg.TopP < minThinkingTopP {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring top_p for anthropic provider: must be at least %v with extended thinking", minThinkingTopP)
+			}
+			model.LogOptionResolution(log, providerName, "top_p", model.OptionActionDropped, fmt.Sprintf("must be at least %v with extended thinking", minThinkingTopP))
+			cfg.TopP = nil
+		} else {
+			return cfg, utils.WrapIfNotNil(fmt.Errorf("top_p must be at least %v with reasoning level for anthropic provider", minThinkingTopP))
+		}
+	}
+	if len(cfg.BuiltinTools) > 0 {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring built-in tools for anthropic provider")
+			}
+			model.LogOptionResolution(log, providerName, "builtin_tools", model.OptionActionDropped, "not supported")
+			cfg.BuiltinTools = nil
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("built-in tools are not supported for anthropic provider"))
+		}
+	}
+	if cfg.CachedContent != "" {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring cached content for anthropic provider")
+			}
+			model.LogOptionResolution(log, providerName, "cached_content", model.OptionActionDropped, "not supported")
+			cfg.CachedContent = ""
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("cached content is not supported for anthropic provider"))
 		}
 	}
 	return cfg, nil
 }
+
+// claudeModelFamily strips the Bedrock region and vendor prefixes, as in
+// "us.anthropic.claude-sonnet-4-20250514-v1:0", so limits registered for
+// Anthropic model names also match Bedrock model IDs.
+func claudeModelFamily(modelName string) string {
+	if i := strings.Index(strings.ToLower(modelName), "claude-"); i > 0 {
+		return modelName[i:]
+	}
+	return modelName
+}
@@ -0,0 +1,90 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/testsupport"
+)
+
+type conformanceWire struct{}
+
+func (conformanceWire) DecodeRequest(r *http.Request) (testsupport.Request, error) {
+	var request anthropicMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return testsupport.Request{}, err
+	}
+
+	out := testsupport.Request{Model: request.Model}
+	if request.System != "" {
+		out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleSystem, Content: request.System})
+	}
+	for _, message := range request.Messages {
+		var text []string
+		for _, block := range message.Content {
+			switch block.Type {
+			case "text":
+				text = append(text, block.Text)
+			case "tool_result":
+				var content string
+				if err := json.Unmarshal(block.Content, &content); err != nil {
+					return testsupport.Request{}, err
+				}
+				out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleTool, Content: content, ToolCallID: block.ToolUseID})
+			}
+		}
+		if len(text) > 0 {
+			out.Messages = append(out.Messages, testsupport.Message{Role: message.Role, Content: strings.Join(text, "\n")})
+		}
+	}
+	for _, tool := range request.Tools {
+		out.Tools = append(out.Tools, tool.Name)
+	}
+	return out, nil
+}
+
+func (conformanceWire) WriteResponse(w http.ResponseWriter, turn testsupport.Turn) {
+	w.Header().Set("content-type", "application/json")
+	if turn.StatusCode >= http.StatusMultipleChoices {
+		w.WriteHeader(turn.StatusCode)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": turn.ErrorMessage},
+		})
+		return
+	}
+
+	response := anthropicMessageResponse{
+		ID:         "msg_conformance",
+		Type:       "message",
+		Role:       "assistant",
+		Model:      "claude-conformance",
+		StopReason: "end_turn",
+		Usage:      &anthropicUsage{InputTokens: turn.InputTokens, OutputTokens: turn.OutputTokens},
+	}
+	if turn.Text != "" {
+		response.Content = append(response.Content, anthropicContentBlock{Type: "text", Text: turn.Text})
+	}
+	for _, call := range turn.ToolCalls {
+		response.Content = append(response.Content, anthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: call.Arguments})
+		response.StopReason = "tool_use"
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestConformance(t *testing.T) {
+	testsupport.RunConformance(t, testsupport.Harness{
+		Provider:      providerName,
+		Wire:          conformanceWire{},
+		Options:       []model.GeneratorOption{model.WithAuthToken("test-key")},
+		NewString:     NewStringContentGenerator,
+		NewStructured: NewStructureContentGenerator[testsupport.Record],
+		UnsupportedOptions: []model.GeneratorOption{
+			model.WithBuiltinTools(model.BuiltinWebSearch),
+			model.WithCachedContent("cachedContents/abc"),
+		},
+	})
+}
@@ -2,6 +2,7 @@ package anthropic
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,8 +12,8 @@ import (
 
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
-	"github.com/invopop/jsonschema"
 )
 
 type structuredGenerator[T any] struct {
@@ -22,6 +23,8 @@ type structuredGenerator[T any] struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 type textGenerator struct {
@@ -31,6 +34,8 @@ type textGenerator struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
@@ -122,6 +127,13 @@ func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider m
 }
 
 func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *structuredGenerator[T]) generate(ctx context.Context) (T, model.GenerationMetadata, error) {
 	start := time.Now()
 	log := logging.NewLogger(ctx)
 
@@ -135,18 +147,26 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
 
-	schema, err := generateJSONSchema[T]()
+	schema, err := model.StructuredOutputSchema[T](cfg)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
-	schemaInstruction, err := buildStructuredOutputInstruction(schema)
+	mode, err := resolveStructuredOutputMode(cfg, schema)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
+	schemaInstruction := ""
+	if mode == model.StructuredOutputModePrompt {
+		schemaInstruction, err = buildStructuredOutputInstruction(schema)
+		if err != nil {
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(err)
+		}
+	}
 
-	system, messages, contextCount, err := g.messagesWithContext(ctx, schemaInstruction)
+	system, messages, contextCount, err := g.messagesWithContext(ctx, meta, schemaInstruction)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
@@ -159,8 +179,18 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	}
 	defer cleanup()
 
+	var toolChoice *anthropicToolChoice
+	if mode == model.StructuredOutputModeNative {
+		if _, exists := handlers[structuredOutputToolName]; exists {
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(fmt.Errorf("tool name %q is reserved for structured output", structuredOutputToolName))
+		}
+		toolChoice = structuredOutputToolChoice(len(tools)+len(mcpServers) > 0)
+		tools = append(tools, structuredOutputTool(schema))
+	}
+
 	log.Infof(
-		"prompt=%q context_count=%d model=%q temperature=%v max_tokens=%v tools=%d mcp_tools=%d",
+		"prompt=%q context_count=%d model=%q temperature=%v max_tokens=%v tools=%d mcp_tools=%d structured_output_mode=%s",
 		g.prompt,
 		contextCount,
 		modelName,
@@ -168,24 +198,44 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		cfg.MaxTokens,
 		len(cfg.Tools),
 		len(cfg.MCPTools),
+		mode,
 	)
 
-	response, totals, err := runMessageFlow(ctx, g.client, cfg, modelName, system, messages, tools, handlers, mcpServers)
+	response, totals, history, err := runMessageFlow(ctx, g.client, cfg, modelName, system, messages, tools, handlers, mcpServers, toolChoice)
+	g.recordHistory(modelName, system, history)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 	applyAnthropicMetadata(meta, response, totals)
 
-	text := strings.TrimSpace(extractTextFromContentBlocks(response.Content))
+	text, fromTool := structuredOutputToolInput(response.Content)
+	if !fromTool {
+		// The model answered in text, either in prompt mode or by ignoring the
+		// forced tool; parse it the same way.
+		text = strings.TrimSpace(extractTextFromContentBlocks(response.Content))
+		mode = model.StructuredOutputModePrompt
+	}
+	meta[model.MetadataKeyStructuredOutputMode] = string(mode)
 	if text == "" {
 		err = errors.New("response output is empty")
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	var out T
-	err = json.Unmarshal([]byte(extractJSONPayload(text)), &out)
+	model.SetRawOutput(meta, g.cfg, text)
+	out, err := model.DecodeStructuredOutputWithRepair[T](
+		ctx,
+		g.cfg,
+		meta,
+		schema,
+		text,
+		model.ResolveStructuredRepairAttempts(cfg, 0),
+		extractJSONPayload,
+		func(ctx context.Context, prompt string) (string, model.GenerationMetadata, error) {
+			return repairStructuredOutput(ctx, g.client, cfg, modelName, prompt)
+		},
+	)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
@@ -193,7 +243,33 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	return out, meta, nil
 }
 
+// repairStructuredOutput sends a structured output repair prompt as a new
+// single-turn request without tools.
+func repairStructuredOutput(
+	ctx context.Context,
+	client *apiClient,
+	cfg model.GeneratorConfig,
+	modelName string,
+	prompt string,
+) (string, model.GenerationMetadata, error) {
+	meta := initMetadata(modelName)
+	messages := []anthropicMessage{makeTextMessage("user", prompt)}
+	response, totals, _, err := runMessageFlow(ctx, client, cfg, modelName, model.StructuredRepairSystemPrompt, messages, nil, nil, nil, nil)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	applyAnthropicMetadata(meta, response, totals)
+	return strings.TrimSpace(extractTextFromContentBlocks(response.Content)), meta, nil
+}
+
 func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generate(ctx context.Context) (string, model.GenerationMetadata, error) {
 	start := time.Now()
 	log := logging.NewLogger(ctx)
 
@@ -206,7 +282,7 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
 
-	system, messages, contextCount, err := g.messagesWithContext(ctx, "")
+	system, messages, contextCount, err := g.messagesWithContext(ctx, meta, "")
 	if err != nil {
 		return "", meta, utils.WrapIfNotNil(err)
 	}
@@ -228,15 +304,15 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		len(cfg.MCPTools),
 	)
 
-	response, totals, err := runMessageFlow(ctx, g.client, cfg, modelName, system, messages, tools, handlers, mcpServers)
+	response, totals, history, err := runMessageFlow(ctx, g.client, cfg, modelName, system, messages, tools, handlers, mcpServers, nil)
+	g.recordHistory(modelName, system, history)
 	if err != nil {
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	applyAnthropicMetadata(meta, response, totals)
 
-	text := strings.TrimSpace(extractTextFromContentBlocks(response.Content))
-	if text == "" {
-		err = errors.New("response output is empty")
+	text, err := model.FinishTextOutput(ctx, cfg, extractTextFromContentBlocks(response.Content))
+	if err != nil {
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 
@@ -253,12 +329,15 @@ func runMessageFlow(
 	tools []anthropicTool,
 	handlers map[string]toolHandler,
 	mcpServers []anthropicMCPServer,
-) (*anthropicMessageResponse, flowUsageTotals, error) {
+	toolChoice *anthropicToolChoice,
+) (*anthropicMessageResponse, flowUsageTotals, []anthropicMessage, error) {
 	log := logging.NewLogger(ctx)
-	totals := flowUsageTotals{}
+	totals := flowUsageTotals{Gateway: model.NewGatewayTotals(cfg.Gateway)}
 	messages := append([]anthropicMessage(nil), initialMessages...)
 
-	for round := 0; round < maxToolRounds; round++ {
+	maxRounds := model.ResolveMaxToolRounds(cfg)
+	toolErrors := model.NewToolErrorPolicy(cfg)
+	for round := 0; round < maxRounds; round++ {
 		request := anthropicMessageRequest{
 			Model:      modelName,
 			MaxTokens:  resolveMaxTokens(cfg),
@@ -266,17 +345,27 @@ func runMessageFlow(
 			Messages:   append([]anthropicMessage(nil), messages...),
 			Tools:      append([]anthropicTool(nil), tools...),
 			MCPServers: append([]anthropicMCPServer(nil), mcpServers...),
+			ToolChoice: requestToolChoice(cfg, toolChoice, len(tools)+len(mcpServers) > 0),
+
+			ProviderParams: cfg.ProviderParams,
 		}
 		if cfg.Temperature != nil {
 			request.Temperature = cfg.Temperature
 		}
+		request.TopP = cfg.TopP
+		request.TopK = cfg.TopK
+		request.StopSeqs = append([]string(nil), cfg.StopSequences...)
+		request.Thinking = resolveThinking(cfg)
+		if cfg.PromptCaching {
+			applyCacheBreakpoints(&request, len(initialMessages))
+		}
 
 		response, err := client.createMessage(ctx, request, len(mcpServers) > 0)
 		if err != nil {
-			return nil, totals, utils.WrapIfNotNil(err)
+			return nil, totals, messages, utils.WrapIfNotNil(err)
 		}
 		if response == nil {
-			return nil, totals, utils.WrapIfNotNil(errors.New("anthropic API returned nil response"))
+			return nil, totals, messages, utils.WrapIfNotNil(errors.New("anthropic API returned nil response"))
 		}
 
 		accumulateUsageTotals(&totals, response)
@@ -285,8 +374,14 @@ func runMessageFlow(
 			Content: append([]anthropicContentBlock(nil), response.Content...),
 		})
 
-		results := make([]anthropicContentBlock, 0)
-		localToolCalls := 0
+		if toolChoice != nil {
+			if _, found := structuredOutputToolInput(response.Content); found {
+				return response, totals, messages, nil
+			}
+		}
+
+		localCalls := make([]anthropicContentBlock, 0)
+		callHandlers := make([]toolHandler, 0)
 		for _, block := range response.Content {
 			if block.Type != "tool_use" {
 				continue
@@ -297,42 +392,116 @@ func runMessageFlow(
 				log.Warnf("tool_use for %q has no local handler; assuming remote MCP handling", block.Name)
 				continue
 			}
+			localCalls = append(localCalls, block)
+			callHandlers = append(callHandlers, handler)
+		}
 
-			localToolCalls++
-			result, callErr := handler(ctx, block.Input)
-			if callErr != nil {
-				return nil, totals, utils.WrapIfNotNil(callErr)
+		callResults := toolexec.Run(ctx, len(localCalls), model.ResolveToolParallelism(cfg), func(ctx context.Context, i int) (any, error) {
+			return callHandlers[i](ctx, localCalls[i].Input)
+		})
+		totals.ToolCalls = append(totals.ToolCalls, toolexec.Transcript(round+1, callResults, func(i int) (string, string) {
+			return localCalls[i].Name, localCalls[i].ID
+		})...)
+
+		results := make([]anthropicContentBlock, 0, len(localCalls))
+		for i, block := range localCalls {
+			output := callResults[i].Value
+			isError := false
+			if callErr := callResults[i].Err; callErr != nil {
+				if abortErr := toolErrors.Handle(block.Name, callErr); abortErr != nil {
+					return nil, totals, messages, utils.WrapIfNotNil(abortErr)
+				}
+				log.Warnf("tool %q failed, reporting to model: %v", block.Name, callErr)
+				output = model.ToolErrorResult(callErr)
+				isError = true
 			}
 
-			resultJSON, marshalErr := json.Marshal(result)
+			resultJSON, marshalErr := json.Marshal(output)
 			if marshalErr != nil {
-				return nil, totals, utils.WrapIfNotNil(marshalErr)
+				return nil, totals, messages, utils.WrapIfNotNil(marshalErr)
 			}
 			resultJSONText, marshalTextErr := json.Marshal(string(resultJSON))
 			if marshalTextErr != nil {
-				return nil, totals, utils.WrapIfNotNil(marshalTextErr)
+				return nil, totals, messages, utils.WrapIfNotNil(marshalTextErr)
 			}
 
 			results = append(results, anthropicContentBlock{
 				Type:      "tool_result",
 				ToolUseID: block.ID,
 				Content:   resultJSONText,
+				IsError:   isError,
 			})
 		}
 
-		if localToolCalls == 0 {
-			return response, totals, nil
+		if len(localCalls) == 0 {
+			return response, totals, messages, nil
 		}
 
 		totals.ToolRounds = round + 1
 		messages = append(messages, anthropicMessage{Role: "user", Content: results})
 	}
 
-	return nil, totals, utils.WrapIfNotNil(fmt.Errorf("exceeded tool call loop limit (%d)", maxToolRounds))
+	return nil, totals, messages, utils.WrapIfNotNil(&model.MaxToolRoundsError{Limit: maxRounds})
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *structuredGenerator[T]) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg, g.client)
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *textGenerator) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg, g.client)
+}
+
+// effectiveConfig applies the option normalization, model resolution and
+// platform endpoint of a generation to cfg.
+func effectiveConfig(ctx context.Context, cfg model.GeneratorConfig, client *apiClient) (model.GeneratorConfig, error) {
+	cfg, err := normalizeGeneratorOptionsForProvider(cfg, logging.NewLogger(ctx))
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	modelName := resolveModelName(cfg)
+	cfg.Model = &modelName
+	platform := client.platform
+	cfg.HostingPlatform = &platform
+	cfg.URL = client.baseURL
+	if platform == model.HostingPlatformVertex {
+		cfg.GCPProject = client.project
+		cfg.GCPLocation = client.location
+	}
+	return cfg, nil
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *structuredGenerator[T]) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *structuredGenerator[T]) recordHistory(modelName string, system string, messages []anthropicMessage) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, system, messages)
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *textGenerator) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *textGenerator) recordHistory(modelName string, system string, messages []anthropicMessage) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, system, messages)
 }
 
 func (g *structuredGenerator[T]) messagesWithContext(
 	ctx context.Context,
+	meta model.GenerationMetadata,
 	promptSuffix string,
 ) (string, []anthropicMessage, int, error) {
 	g.promptContextMu.RLock()
@@ -348,15 +517,36 @@ func (g *structuredGenerator[T]) messagesWithContext(
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+
 	prompt := g.prompt
 	if strings.TrimSpace(promptSuffix) != "" {
 		prompt += "\n\n" + promptSuffix
 	}
-	return buildMessagesWithContext(prompt, contexts)
+	model.SetContextTokens(meta, g.cfg, prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+	system, messages, contextCount, err := buildMessagesWithContext(prompt, contexts)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+	messages, err = attachDocuments(messages, g.cfg.Documents)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+	return system, messages, contextCount, nil
 }
 
 func (g *textGenerator) messagesWithContext(
 	ctx context.Context,
+	meta model.GenerationMetadata,
 	promptSuffix string,
 ) (string, []anthropicMessage, int, error) {
 	g.promptContextMu.RLock()
@@ -372,11 +562,31 @@ func (g *textGenerator) messagesWithContext(
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+
 	prompt := g.prompt
 	if strings.TrimSpace(promptSuffix) != "" {
 		prompt += "\n\n" + promptSuffix
 	}
-	return buildMessagesWithContext(prompt, contexts)
+	model.SetContextTokens(meta, g.cfg, prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+	system, messages, contextCount, err := buildMessagesWithContext(prompt, contexts)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+	messages, err = attachDocuments(messages, g.cfg.Documents)
+	if err != nil {
+		return "", nil, 0, utils.WrapIfNotNil(err)
+	}
+	return system, messages, contextCount, nil
 }
 
 func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) (string, []anthropicMessage, int, error) {
@@ -390,11 +600,20 @@ func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) (s
 		}
 
 		content := strings.TrimSpace(contextItem.Content)
-		if content == "" {
+		if !contextItem.HasContent() {
 			continue
 		}
 
+		images, err := model.ResolveImageParts(contextItem)
+		if err != nil {
+			return "", nil, 0, utils.WrapIfNotNil(err)
+		}
+
 		contextCount++
+		if len(images) > 0 {
+			messages = append(messages, makeImageMessage(content, images))
+			continue
+		}
 		switch contextItem.MessageType {
 		case model.ContextMessageTypeSystem:
 			systemParts = append(systemParts, content)
@@ -411,6 +630,45 @@ func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) (s
 	return strings.Join(systemParts, "\n\n"), messages, contextCount, nil
 }
 
+func ephemeralCacheControl() *anthropicCacheControl {
+	return &anthropicCacheControl{Type: "ephemeral"}
+}
+
+// applyCacheBreakpoints marks the cacheable prefixes of request, staying
+// within the API limit of four breakpoints: the last local tool definition,
+// the system prompt, the last context message before the prompt
+// (initialMessages counts the contexts plus the prompt) and, during tool
+// rounds, the newest message so each round reuses the previous one.
+// Prefixes below the model's minimum cacheable length are not cached by the
+// API, so marking them is harmless.
+func applyCacheBreakpoints(request *anthropicMessageRequest, initialMessages int) {
+	for i := len(request.Tools) - 1; i >= 0; i-- {
+		if request.Tools[i].Type == "" {
+			request.Tools[i].CacheControl = ephemeralCacheControl()
+			break
+		}
+	}
+	request.CacheSystem = true
+
+	if initialMessages > 1 {
+		markMessageCacheBreakpoint(request.Messages, initialMessages-2)
+	}
+	if len(request.Messages) > initialMessages {
+		markMessageCacheBreakpoint(request.Messages, len(request.Messages)-1)
+	}
+}
+
+// markMessageCacheBreakpoint sets cache_control on the last block of
+// messages[index]. The content is copied so the flow history is unchanged.
+func markMessageCacheBreakpoint(messages []anthropicMessage, index int) {
+	if index < 0 || index >= len(messages) || len(messages[index].Content) == 0 {
+		return
+	}
+	content := append([]anthropicContentBlock(nil), messages[index].Content...)
+	content[len(content)-1].CacheControl = ephemeralCacheControl()
+	messages[index].Content = content
+}
+
 func makeTextMessage(role string, content string) anthropicMessage {
 	return anthropicMessage{
 		Role: role,
@@ -423,6 +681,64 @@ func makeTextMessage(role string, content string) anthropicMessage {
 	}
 }
 
+// makeImageMessage builds a user message with image blocks followed by the
+// text, the order Anthropic recommends.
+func makeImageMessage(content string, images []model.ImagePart) anthropicMessage {
+	blocks := make([]anthropicContentBlock, 0, len(images)+1)
+	for _, image := range images {
+		source := &anthropicImageSource{Type: "url", URL: image.URL}
+		if len(image.Data) > 0 {
+			source = &anthropicImageSource{
+				Type:      "base64",
+				MediaType: image.MIMEType,
+				Data:      base64.StdEncoding.EncodeToString(image.Data),
+			}
+		}
+		blocks = append(blocks, anthropicContentBlock{Type: "image", Source: source})
+	}
+	if content != "" {
+		blocks = append(blocks, anthropicContentBlock{Type: "text", Text: content})
+	}
+	return anthropicMessage{Role: "user", Content: blocks}
+}
+
+// anthropicDocumentMaxBytes is the Messages API request size limit.
+const anthropicDocumentMaxBytes = 32 << 20
+
+var anthropicDocumentMIMETypes = []string{
+	model.DocumentMIMETypePDF,
+	model.DocumentMIMETypeText,
+}
+
+// attachDocuments puts document blocks ahead of the blocks of the final
+// prompt message. PDFs are sent as base64 or URL sources and text documents
+// as text sources.
+func attachDocuments(messages []anthropicMessage, documents []model.DocumentPart) ([]anthropicMessage, error) {
+	if len(documents) == 0 || len(messages) == 0 {
+		return messages, nil
+	}
+	resolved, err := model.ResolveDocumentParts(documents, anthropicDocumentMIMETypes, anthropicDocumentMaxBytes)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	last := messages[len(messages)-1]
+	blocks := make([]anthropicContentBlock, 0, len(resolved)+len(last.Content))
+	for _, document := range resolved {
+		source := &anthropicImageSource{Type: "url", URL: document.URL}
+		switch {
+		case len(document.Data) > 0 && document.MIMEType == model.DocumentMIMETypeText:
+			source = &anthropicImageSource{Type: "text", MediaType: document.MIMEType, Data: string(document.Data)}
+		case len(document.Data) > 0:
+			source = &anthropicImageSource{Type: "base64", MediaType: document.MIMEType, Data: document.Base64Data()}
+		}
+		blocks = append(blocks, anthropicContentBlock{Type: "document", Source: source, Title: document.Name})
+	}
+	last.Content = append(blocks, last.Content...)
+	messages[len(messages)-1] = last
+	return messages, nil
+}
+
 func extractTextFromContentBlocks(content []anthropicContentBlock) string {
 	if len(content) == 0 {
 		return ""
@@ -442,27 +758,78 @@ func extractTextFromContentBlocks(content []anthropicContentBlock) string {
 	return strings.Join(parts, "\n")
 }
 
-func generateJSONSchema[T any]() (map[string]any, error) {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
+// structuredOutputToolName is the tool whose input carries the structured
+// result in native mode.
+const structuredOutputToolName = "structured_output"
+
+// resolveStructuredOutputMode picks how the structured generator asks for
+// JSON. Native mode forces a call to the structured output tool, which the
+// API rejects together with extended thinking and which needs an object
+// schema; auto mode falls back to schema instructions in those cases.
+func resolveStructuredOutputMode(cfg model.GeneratorConfig, schema map[string]any) (model.StructuredOutputMode, error) {
+	mode := model.ResolveStructuredOutputMode(cfg)
+	if mode == model.StructuredOutputModePrompt {
+		return mode, nil
+	}
+
+	var reason string
+	switch {
+	case resolveThinking(cfg) != nil:
+		reason = "extended thinking does not allow forced tool use"
+	case schema["type"] != "object":
+		reason = "tool input schemas must be objects"
+	default:
+		return model.StructuredOutputModeNative, nil
+	}
+	if mode == model.StructuredOutputModeNative {
+		return "", fmt.Errorf("native structured output is unavailable: %s", reason)
+	}
+	return model.StructuredOutputModePrompt, nil
+}
 
-	var value T
-	schema := reflector.Reflect(value)
+// structuredOutputTool defines the tool whose input schema is the structured
+// output schema.
+func structuredOutputTool(schema map[string]any) anthropicTool {
+	return anthropicTool{
+		Name:        structuredOutputToolName,
+		Description: "Return the final answer. Call this tool exactly once with the complete result.",
+		InputSchema: schema,
+	}
+}
 
-	schemaJSON, err := json.Marshal(schema)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+// requestToolChoice adds disable_parallel_tool_use to toolChoice when
+// WithParallelToolCalls(false) is set and the request has tools.
+func requestToolChoice(cfg model.GeneratorConfig, toolChoice *anthropicToolChoice, hasTools bool) *anthropicToolChoice {
+	if !hasTools || !model.ParallelToolCallsDisabled(cfg) {
+		return toolChoice
+	}
+	choice := anthropicToolChoice{Type: "auto"}
+	if toolChoice != nil {
+		choice = *toolChoice
 	}
+	choice.DisableParallelToolUse = true
+	return &choice
+}
 
-	var schemaMap map[string]any
-	err = json.Unmarshal(schemaJSON, &schemaMap)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+// structuredOutputToolChoice forces the structured output tool. With other
+// tools available the model may call any tool, so it can use them before
+// returning the result.
+func structuredOutputToolChoice(otherTools bool) *anthropicToolChoice {
+	if otherTools {
+		return &anthropicToolChoice{Type: "any"}
 	}
+	return &anthropicToolChoice{Type: "tool", Name: structuredOutputToolName}
+}
 
-	return schemaMap, nil
+// structuredOutputToolInput returns the input of the structured output tool
+// call in content, if there is one.
+func structuredOutputToolInput(content []anthropicContentBlock) (string, bool) {
+	for _, block := range content {
+		if block.Type == "tool_use" && block.Name == structuredOutputToolName {
+			return strings.TrimSpace(string(block.Input)), true
+		}
+	}
+	return "", false
 }
 
 func buildStructuredOutputInstruction(schema map[string]any) (string, error) {
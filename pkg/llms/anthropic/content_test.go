@@ -2,6 +2,7 @@ package anthropic
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -55,7 +56,7 @@ func (s *ContentSuite) TestMessagesWithContextProviderError() {
 	g := &textGenerator{prompt: "hi"}
 	g.AddPromptContextProvider(context.Background(), &stubPromptContextProvider{err: errors.New("provider failed")})
 
-	_, _, _, err := g.messagesWithContext(context.Background(), "")
+	_, _, _, err := g.messagesWithContext(context.Background(), nil, "")
 	s.Error(err)
 	s.Contains(err.Error(), "provider failed")
 }
@@ -70,3 +71,77 @@ func (s *stubPromptContextProvider) GenerateContext(ctx context.Context) ([]*mod
 	}
 	return nil, nil
 }
+
+func (s *ContentSuite) TestBuildConversationHistory() {
+	history := buildConversationHistory("claude", "be brief", []anthropicMessage{
+		makeTextMessage("user", "weather?"),
+		{Role: "assistant", Content: []anthropicContentBlock{
+			{Type: "text", Text: "Checking."},
+			{Type: "tool_use", ID: "toolu_1", Name: "weather", Input: json.RawMessage(`{"city":"Oslo"}`)},
+		}},
+		{Role: "user", Content: []anthropicContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_1", Content: json.RawMessage(`"{\"temp\":3}"`)},
+		}},
+		makeTextMessage("assistant", "3 degrees"),
+	})
+
+	s.Equal(providerName, history.Provider)
+	s.Equal("claude", history.Model)
+	s.Require().Len(history.Messages, 5)
+	s.Equal(model.HistoryRoleSystem, history.Messages[0].Role)
+	s.Equal("Checking.", history.Messages[2].Content)
+	s.Require().Len(history.Messages[2].ToolCalls, 1)
+	s.Equal("weather", history.Messages[2].ToolCalls[0].Name)
+	s.Equal(model.HistoryRoleTool, history.Messages[3].Role)
+	s.Equal("weather", history.Messages[3].ToolName)
+	s.Equal(`{"temp":3}`, history.Messages[3].Content)
+	s.Equal("3 degrees", history.Messages[4].Content)
+}
+
+func (s *ContentSuite) TestBuildMessagesWithImageContext() {
+	_, messages, contextCount, err := buildMessagesWithContext("final prompt", []*model.PromptContext{
+		model.ImagePromptContext("What does the scan show?",
+			model.ImagePart{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")},
+			model.ImagePart{URL: "https://example.org/xray.jpg"},
+		),
+	})
+	s.Require().NoError(err)
+	s.Equal(1, contextCount)
+	s.Require().Len(messages, 2)
+
+	encoded, err := json.Marshal(messages[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"role":"user","content":[
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgoAAAANSUhEUg=="}},
+		{"type":"image","source":{"type":"url","url":"https://example.org/xray.jpg"}},
+		{"type":"text","text":"What does the scan show?"}]}`, string(encoded))
+
+	_, _, _, err = buildMessagesWithContext("final prompt", []*model.PromptContext{
+		{MessageType: model.ContextMessageTypeAssistant, Images: []model.ImagePart{{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")}}},
+	})
+	s.Error(err)
+}
+
+func (s *ContentSuite) TestAttachDocumentsAddsDocumentBlocks() {
+	_, messages, _, err := buildMessagesWithContext("Summarize the attachments.", nil)
+	s.Require().NoError(err)
+	messages, err = attachDocuments(messages, []model.DocumentPart{
+		{Name: "labs.pdf", Data: []byte("%PDF-1.7")},
+		{Name: "notes.txt", Data: []byte("eGFR 42")},
+		{URL: "https://example.org/discharge.pdf"},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(messages, 1)
+
+	encoded, err := json.Marshal(messages[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"role":"user","content":[
+		{"type":"document","title":"labs.pdf","source":{"type":"base64","media_type":"application/pdf","data":"JVBERi0xLjc="}},
+		{"type":"document","title":"notes.txt","source":{"type":"text","media_type":"text/plain","data":"eGFR 42"}},
+		{"type":"document","title":"discharge.pdf","source":{"type":"url","url":"https://example.org/discharge.pdf"}},
+		{"type":"text","text":"Summarize the attachments."}]}`, string(encoded))
+
+	_, err = attachDocuments(messages, []model.DocumentPart{{Name: "letter.docx", Data: []byte("PK\x03\x04")}})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not supported")
+}
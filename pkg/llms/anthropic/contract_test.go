@@ -0,0 +1,727 @@
+package anthropic
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+// ContractSuite checks the hand-rolled Messages API client against a fake
+// server, so request shape, error handling and metadata are verified without
+// an API key.
+type ContractSuite struct {
+	suite.Suite
+}
+
+func TestContractSuite(t *testing.T) {
+	suite.Run(t, new(ContractSuite))
+}
+
+func (s *ContractSuite) newGenerator(serverURL string, opts ...model.GeneratorOption) model.ContentGenerator[string] {
+	opts = append([]model.GeneratorOption{model.WithURL(serverURL), model.WithAuthToken("test-key")}, opts...)
+	gen, err := NewStringContentGenerator("Say hello.", opts...)
+	s.Require().NoError(err)
+	return gen
+}
+
+func (s *ContractSuite) TestRequestShapeAndMetadata() {
+	var request anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal(http.MethodPost, r.Method)
+		s.Equal("/v1/messages", r.URL.Path)
+		s.Equal("test-key", r.Header.Get("x-api-key"))
+		s.Equal(anthropicVersion, r.Header.Get("anthropic-version"))
+		s.Empty(r.Header.Get("anthropic-beta"))
+		s.Equal("application/json", r.Header.Get("content-type"))
+		body, err := io.ReadAll(r.Body)
+		s.Require().NoError(err)
+		s.Require().NoError(json.Unmarshal(body, &request))
+
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-test-1","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":3,"cache_read_input_tokens":4,"cache_creation_input_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	gen := s.newGenerator(server.URL, model.WithModel("claude-test"), model.WithMaxTokens(64), model.WithTemperature(0.2))
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeAssistant, "Earlier answer.")
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+
+	s.Equal("claude-test", request.Model)
+	s.Equal(64, request.MaxTokens)
+	s.Require().NotNil(request.Temperature)
+	s.InDelta(0.2, *request.Temperature, 1e-9)
+	s.Equal("Be brief.", request.System)
+	s.Require().Len(request.Messages, 2)
+	s.Equal("assistant", request.Messages[0].Role)
+	s.Equal("user", request.Messages[1].Role)
+	s.Equal("Say hello.", request.Messages[1].Content[0].Text)
+
+	s.Equal(providerName, meta[model.MetadataKeyProvider])
+	s.Equal("claude-test-1", meta[model.MetadataKeyModel])
+	s.Equal("msg_1", meta[model.MetadataKeyResponseID])
+	s.Equal("end_turn", meta[model.MetadataKeyResponseStatus])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+	s.Equal("0", meta[model.MetadataKeyToolRounds])
+	s.Equal("12", meta[model.MetadataKeyInputTokens])
+	s.Equal("3", meta[model.MetadataKeyOutputTokens])
+	s.Equal("15", meta[model.MetadataKeyTotalTokens])
+	s.Equal("5", meta[model.MetadataKeyCachedInputTokens])
+	s.NotEmpty(meta[model.MetadataKeyLatencyMs])
+}
+
+func (s *ContractSuite) TestRequestCompressionGzipsLargeBodies() {
+	var encodings []string
+	var request anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			s.Require().NoError(err)
+			reader = gz
+		}
+		s.Require().NoError(json.NewDecoder(reader).Decode(&request))
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	note := strings.Repeat("Creatinine 1.6 mg/dL, eGFR 48. ", 200)
+	gen := s.newGenerator(server.URL, model.WithRequestCompression(1024))
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeHuman, note)
+	out, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+	s.Equal(strings.TrimSpace(note), request.Messages[0].Content[0].Text)
+
+	_, _, err = s.newGenerator(server.URL, model.WithRequestCompression(1<<20)).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]string{"gzip", ""}, encodings)
+}
+
+func (s *ContractSuite) TestErrorBodies() {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "api error", status: http.StatusBadRequest, body: `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens is too large"}}`, want: "anthropic API error (400): max_tokens is too large"},
+		{name: "plain text", status: http.StatusBadGateway, body: "upstream unavailable", want: "anthropic API error (502): upstream unavailable"},
+		{name: "empty body", status: http.StatusInternalServerError, body: "", want: "anthropic API error (500): unknown anthropic error"},
+		{name: "overloaded", status: 529, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, want: "anthropic API error (529): Overloaded"},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			out, _, err := s.newGenerator(server.URL).Generate(context.Background())
+			s.Require().Error(err)
+			s.Contains(err.Error(), tc.want)
+			s.Empty(out)
+		})
+	}
+}
+
+func (s *ContractSuite) TestMalformedJSON() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":`))
+	}))
+	defer server.Close()
+
+	_, _, err := s.newGenerator(server.URL).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "unexpected end of JSON input")
+}
+
+func (s *ContractSuite) TestEmptyContentIsAnError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	_, _, err := s.newGenerator(server.URL).Generate(context.Background())
+	s.Error(err)
+}
+
+func (s *ContractSuite) TestSlowResponseHonoursContextDeadline() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := s.newGenerator(server.URL).Generate(ctx)
+	s.Require().Error(err)
+	s.True(errors.Is(err, context.DeadlineExceeded), err.Error())
+	s.Less(time.Since(start), 5*time.Second)
+}
+
+func (s *ContractSuite) TestToolRoundTrip() {
+	var requests []anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request anthropicMessageRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"lookup","input":{"id":7}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"text","text":"Found it."}],"stop_reason":"end_turn","usage":{"input_tokens":20,"output_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{
+		Name:        "lookup",
+		Description: "Look up a record",
+		InputSchema: model.JSONSchema{"type": "object"},
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			s.JSONEq(`{"id":7}`, string(args))
+			return map[string]any{"name": "Ada"}, nil
+		},
+	}
+	out, meta, err := s.newGenerator(server.URL, model.WithTools([]model.Tool{tool})).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Found it.", out)
+
+	s.Require().Len(requests, 2)
+	s.Require().Len(requests[0].Tools, 1)
+	s.Equal("lookup", requests[0].Tools[0].Name)
+	s.Equal("object", requests[0].Tools[0].InputSchema["type"])
+
+	second := requests[1].Messages
+	s.Require().Len(second, 3)
+	s.Equal("assistant", second[1].Role)
+	s.Equal("tool_use", second[1].Content[1].Type)
+	s.Equal("user", second[2].Role)
+	result := second[2].Content[0]
+	s.Equal("tool_result", result.Type)
+	s.Equal("toolu_1", result.ToolUseID)
+	var resultText string
+	s.Require().NoError(json.Unmarshal(result.Content, &resultText))
+	s.JSONEq(`{"name":"Ada"}`, resultText)
+
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	s.Equal("39", meta[model.MetadataKeyTotalTokens])
+}
+
+func (s *ContractSuite) TestStructuredOutputFromFencedJSON() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.True(strings.Contains(string(body), "JSON"), "structured requests should ask for JSON")
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"Sure:\n` + "```json" + `\n{\"status\":\"ok\"}\n` + "```" + `"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	gen, err := NewStructureContentGenerator[status](
+		"Report status.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("test-key"),
+		model.WithStructuredOutputMode(model.StructuredOutputModePrompt),
+	)
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModePrompt), meta[model.MetadataKeyStructuredOutputMode])
+}
+
+func (s *ContractSuite) TestStructuredOutputForcesOutputTool() {
+	var request anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"tool_use","id":"toolu_1","name":"structured_output","input":{"status":"ok"}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	gen, err := NewStructureContentGenerator[status]("Report status.", model.WithURL(server.URL), model.WithAuthToken("test-key"))
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModeNative), meta[model.MetadataKeyStructuredOutputMode])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+	s.Require().Len(request.Messages, 1)
+	s.Equal("Report status.", request.Messages[0].Content[0].Text)
+	s.Equal(&anthropicToolChoice{Type: "tool", Name: structuredOutputToolName}, request.ToolChoice)
+	s.Require().Len(request.Tools, 1)
+	s.Equal(structuredOutputToolName, request.Tools[0].Name)
+	s.Equal("object", request.Tools[0].InputSchema["type"])
+}
+
+func (s *ContractSuite) TestStructuredOutputToolAfterOtherTools() {
+	var requests []anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request anthropicMessageRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}],"stop_reason":"tool_use"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"tool_use","id":"toolu_2","name":"structured_output","input":{"status":"failed"}}],"stop_reason":"tool_use"}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	lookup := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "failed", nil
+	}}
+	gen, err := NewStructureContentGenerator[status](
+		"Report status.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("test-key"),
+		model.WithTools([]model.Tool{lookup}),
+	)
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("failed", out.Status)
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	s.Require().Len(requests, 2)
+	s.Equal(&anthropicToolChoice{Type: "any"}, requests[0].ToolChoice)
+	s.Require().Len(requests[0].Tools, 2)
+	s.Equal(structuredOutputToolName, requests[0].Tools[1].Name)
+}
+
+func (s *ContractSuite) TestParallelToolCallsDisabledSetsToolChoice() {
+	var requests []anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request anthropicMessageRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}],"stop_reason":"tool_use"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"tool_use","id":"toolu_2","name":"structured_output","input":{"status":"ok"}}],"stop_reason":"tool_use"}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	lookup := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+	structured, err := NewStructureContentGenerator[status](
+		"Report status.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("test-key"),
+		model.WithTools([]model.Tool{lookup}),
+		model.WithParallelToolCalls(false),
+	)
+	s.Require().NoError(err)
+	_, _, err = structured.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(requests, 2)
+	s.Equal(&anthropicToolChoice{Type: "any", DisableParallelToolUse: true}, requests[0].ToolChoice)
+
+	s.Equal(&anthropicToolChoice{Type: "auto", DisableParallelToolUse: true},
+		requestToolChoice(model.ResolveGeneratorOpts(model.WithParallelToolCalls(false)), nil, true))
+	s.Nil(requestToolChoice(model.ResolveGeneratorOpts(model.WithParallelToolCalls(false)), nil, false))
+	s.Nil(requestToolChoice(model.ResolveGeneratorOpts(model.WithParallelToolCalls(true)), nil, true))
+}
+
+func (s *ContractSuite) TestStructuredOutputUsesPromptWithThinking() {
+	var request anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"{\"status\":\"ok\"}"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	opts := []model.GeneratorOption{
+		model.WithURL(server.URL),
+		model.WithAuthToken("test-key"),
+		model.WithReasoningLevel(model.ReasoningLevelHigh),
+	}
+	gen, err := NewStructureContentGenerator[status]("Report status.", opts...)
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModePrompt), meta[model.MetadataKeyStructuredOutputMode])
+	s.Nil(request.ToolChoice)
+	s.Empty(request.Tools)
+	s.Require().Len(request.Messages, 1)
+	s.Contains(request.Messages[0].Content[0].Text, "Return ONLY valid JSON")
+
+	native, err := NewStructureContentGenerator[status](
+		"Report status.",
+		append(opts, model.WithStructuredOutputMode(model.StructuredOutputModeNative))...,
+	)
+	s.Require().NoError(err)
+	_, _, err = native.Generate(context.Background())
+	s.ErrorContains(err, "extended thinking")
+}
+
+func (s *ContractSuite) TestStructuredOutputIsValidatedAgainstSchema() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"{\"status\":\"maybe\",\"extra\":1}"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status" jsonschema:"enum=ok,enum=failed"`
+		Count  int    `json:"count"`
+	}
+	gen, err := NewStructureContentGenerator[status]("Report status.", model.WithURL(server.URL), model.WithAuthToken("test-key"))
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+	s.ErrorIs(err, model.ErrSchemaValidation)
+	var validationErr *model.SchemaValidationError
+	s.Require().True(errors.As(err, &validationErr))
+	s.Equal([]model.SchemaViolation{
+		{Path: "/count", Message: "missing required property"},
+		{Path: "/extra", Message: "additional property is not allowed"},
+		{Path: "/status", Message: `value "maybe" is not one of ["ok","failed"]`},
+	}, validationErr.Violations)
+}
+
+func (s *ContractSuite) TestOutputSchemaOverridesReflection() {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"{\"status\":\"maybe\"}"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	schema := model.JSONSchema{
+		"type": "object",
+		"properties": map[string]any{
+			"status": map[string]any{"type": "string", "enum": []string{"ok", "failed"}, "description": "Outcome of the run."},
+		},
+		"required":             []string{"status"},
+		"additionalProperties": false,
+	}
+	gen, err := NewStructureContentGenerator[status](
+		"Report status.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("test-key"),
+		model.WithOutputSchema(schema),
+	)
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.ErrorIs(err, model.ErrSchemaValidation)
+	s.Contains(string(body), `"enum":["ok","failed"]`)
+	s.Contains(string(body), "Outcome of the run.")
+}
+
+func (s *ContractSuite) TestStructuredOutputRepairAttempts() {
+	var requests []anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request anthropicMessageRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"Status is ok."}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":4}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"text","text":"{\"status\":\"ok\"}"}],"stop_reason":"end_turn","usage":{"input_tokens":20,"output_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	gen, err := NewStructureContentGenerator[status](
+		"Report status.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("test-key"),
+		model.WithStructuredRepairAttempts(2),
+	)
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Require().Len(requests, 2)
+	s.Equal(model.StructuredRepairSystemPrompt, requests[1].System)
+	s.Require().Len(requests[1].Messages, 1)
+	s.Contains(requests[1].Messages[0].Content[0].Text, "Output:\nStatus is ok.")
+	s.Equal("1", meta[model.MetadataKeyStructuredRepairs])
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal("30", meta[model.MetadataKeyInputTokens])
+	s.Equal("msg_1", meta[model.MetadataKeyResponseID])
+}
+
+func (s *ContractSuite) TestCacheCreationBreakdownAndServiceTier() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"tool_use","id":"toolu_1","name":"noop","input":{}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":2,"cache_read_input_tokens":100,"cache_creation_input_tokens":50,"cache_creation":{"ephemeral_5m_input_tokens":20,"ephemeral_1h_input_tokens":30},"service_tier":"standard"}}`))
+			return
+		}
+		// No breakdown: older responses count every cache write as 5m.
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"text","text":"done"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2,"cache_read_input_tokens":150,"cache_creation_input_tokens":7,"service_tier":"priority"}}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "noop", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+	_, meta, err := s.newGenerator(server.URL, model.WithTools([]model.Tool{tool})).Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal("307", meta[model.MetadataKeyCachedInputTokens])
+	s.Equal("250", meta[model.MetadataKeyCacheReadInputTokens])
+	s.Equal("57", meta[model.MetadataKeyCacheCreationInputTokens])
+	s.Equal("27", meta[model.MetadataKeyCacheCreation5mInputTokens])
+	s.Equal("30", meta[model.MetadataKeyCacheCreation1hInputTokens])
+	s.Equal("priority", meta[model.MetadataKeyServiceTier])
+}
+
+func (s *ContractSuite) TestProviderParamsAreMergedIntoBody() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-test","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	_, _, err := s.newGenerator(server.URL,
+		model.WithMaxTokens(64),
+		model.WithProviderParams(map[string]any{"top_k": 5, "metadata": map[string]any{"user_id": "u-1"}}),
+		model.WithProviderParams(map[string]any{"max_tokens": 128}),
+	).Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal(float64(5), body["top_k"])
+	s.Equal(map[string]any{"user_id": "u-1"}, body["metadata"])
+	s.Equal(float64(128), body["max_tokens"])
+	s.NotEmpty(body["messages"])
+}
+
+func (s *ContractSuite) TestSamplingOptionsAreSent() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-test","content":[{"type":"text","text":"hi"}],"stop_reason":"stop_sequence","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	_, _, err := s.newGenerator(server.URL,
+		model.WithTopP(0.9),
+		model.WithTopK(40),
+		model.WithStopSequences("\n\nHuman:", ""),
+		model.WithStopSequences("END"),
+	).Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal(0.9, body["top_p"])
+	s.Equal(float64(40), body["top_k"])
+	s.Equal([]any{"\n\nHuman:", "END"}, body["stop_sequences"])
+
+	body = nil
+	_, _, err = s.newGenerator(server.URL).Generate(context.Background())
+	s.Require().NoError(err)
+	s.NotContains(body, "top_p")
+	s.NotContains(body, "top_k")
+	s.NotContains(body, "stop_sequences")
+}
+
+func (s *ContractSuite) TestThinkingRequestAndBlocksResentInToolRound() {
+	var requests []anthropicMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request anthropicMessageRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"thinking","thinking":"Need the record first.","signature":"sig_1"},{"type":"redacted_thinking","data":"enc_1"},{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":40}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"thinking","thinking":"Done.","signature":"sig_2"},{"type":"text","text":"Found it."}],"stop_reason":"end_turn","usage":{"input_tokens":60,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "Ada", nil
+	}}
+	out, meta, err := s.newGenerator(server.URL,
+		model.WithReasoningLevel(model.ReasoningLevelMed),
+		model.WithTools([]model.Tool{tool}),
+	).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Found it.", out)
+
+	s.Require().Len(requests, 2)
+	s.Require().NotNil(requests[0].Thinking)
+	s.Equal("enabled", requests[0].Thinking.Type)
+	s.Equal(medThinkingBudgetTokens, requests[0].Thinking.BudgetTokens)
+	s.Equal(medThinkingBudgetTokens+defaultMaxTokens, requests[0].MaxTokens)
+	s.Nil(requests[0].Temperature)
+
+	assistant := requests[1].Messages[1]
+	s.Require().Len(assistant.Content, 3)
+	s.Equal("thinking", assistant.Content[0].Type)
+	s.Equal("Need the record first.", assistant.Content[0].Thinking)
+	s.Equal("sig_1", assistant.Content[0].Signature)
+	s.Equal("redacted_thinking", assistant.Content[1].Type)
+	s.Equal("enc_1", assistant.Content[1].Data)
+
+	// "Need the record first." is 22 chars (6 tokens), the second round's
+	// "Done." estimate is capped by its single output token.
+	s.Equal("7", meta[model.MetadataKeyReasoningTokens])
+	s.Equal("41", meta[model.MetadataKeyOutputTokens])
+}
+
+func (s *ContractSuite) TestPromptCachingBreakpoints() {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		if len(bodies) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":2,"cache_creation_input_tokens":2048}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"text","text":"done"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2,"cache_read_input_tokens":2048,"cache_creation_input_tokens":30}}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+	gen := s.newGenerator(server.URL, model.WithPromptCaching(true), model.WithTools([]model.Tool{tool}))
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeHuman, "Long reference document.")
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	ephemeral := map[string]any{"type": "ephemeral"}
+	cacheControl := func(value any) any {
+		return value.(map[string]any)["cache_control"]
+	}
+	lastBlock := func(message any) any {
+		content := message.(map[string]any)["content"].([]any)
+		return content[len(content)-1]
+	}
+
+	s.Require().Len(bodies, 2)
+	first := bodies[0]
+	s.Equal(ephemeral, cacheControl(first["tools"].([]any)[0]))
+	s.Equal([]any{map[string]any{"type": "text", "text": "Be brief.", "cache_control": ephemeral}}, first["system"])
+	messages := first["messages"].([]any)
+	s.Require().Len(messages, 2)
+	s.Equal(ephemeral, cacheControl(lastBlock(messages[0])))
+	s.Nil(cacheControl(lastBlock(messages[1])), "the prompt itself is not a breakpoint")
+
+	// The tool round also marks the newest message, without leaking the
+	// marker into the assistant turn that precedes it.
+	messages = bodies[1]["messages"].([]any)
+	s.Require().Len(messages, 4)
+	s.Equal(ephemeral, cacheControl(messages[0].(map[string]any)["content"].([]any)[0]))
+	s.Nil(cacheControl(lastBlock(messages[2])))
+	s.Equal(ephemeral, cacheControl(lastBlock(messages[3])))
+
+	s.Equal("2048", meta[model.MetadataKeyCacheReadInputTokens])
+	s.Equal("2078", meta[model.MetadataKeyCacheCreationInputTokens])
+}
+
+func (s *ContractSuite) TestPromptCachingOffKeepsPlainSystem() {
+	var raw []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	gen := s.newGenerator(server.URL)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+	_, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Contains(string(raw), `"system":"Be brief."`)
+	s.NotContains(string(raw), "cache_control")
+}
+
+func (s *ContractSuite) TestGatewayHeadersAndCostMetadata() {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		w.Header().Set("x-litellm-response-cost", "0.25")
+		w.Header().Set("x-litellm-call-id", "call-"+strconv.Itoa(len(headers)))
+		if len(headers) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","content":[{"type":"tool_use","id":"toolu_1","name":"noop","input":{}}],"stop_reason":"tool_use"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","content":[{"type":"text","text":"done"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "noop", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+	_, meta, err := s.newGenerator(server.URL,
+		model.WithTools([]model.Tool{tool}),
+		model.WithGateway(model.GatewayProfile{Kind: model.GatewayLiteLLM, VirtualKey: "sk-virtual", Metadata: map[string]string{"team": "renal"}}),
+	).Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Require().Len(headers, 2)
+	for _, header := range headers {
+		s.Equal("Bearer sk-virtual", header.Get("x-litellm-api-key"))
+		s.Equal("team:renal", header.Get("x-litellm-tags"))
+		s.Equal("test-key", header.Get("x-api-key"))
+	}
+	s.Equal("litellm", meta[model.MetadataKeyGateway])
+	s.Equal("0.5", meta[model.MetadataKeyGatewayCost])
+	s.Equal("call-2", meta[model.MetadataKeyGatewayRequestID])
+}
+
+func (s *ContractSuite) TestUnknownGatewayKindIsRejected() {
+	_, err := NewStringContentGenerator("Hi.", model.WithAuthToken("test-key"), model.WithGateway(model.GatewayProfile{Kind: "envoy"}))
+	s.Error(err)
+}
@@ -1,19 +1,244 @@
 package anthropic
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 )
 
-const unsupportedEmbeddingsMessage = "anthropic provider does not currently support embeddings in this library; use another provider"
+// Anthropic has no embedding models; its recommended embedding provider is
+// Voyage AI, which is called directly.
+const (
+	defaultEmbeddingModelName = "voyage-3.5"
+	defaultVoyageBaseURL      = "https://api.voyageai.com/v1"
+	envVoyageAPIKey           = "VOYAGE_API_KEY"
+	envVoyageBaseURL          = "VOYAGE_BASE_URL"
+	// voyageProviderName keys Voyage models in the embedding dimension
+	// registry, apart from Claude models.
+	voyageProviderName = "voyage"
+	// voyageMaxBatchSize is the most inputs Voyage accepts per request.
+	voyageMaxBatchSize = 1000
+)
+
+type embeddingGenerator struct {
+	cfg        model.GeneratorConfig
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+type voyageEmbeddingRequest struct {
+	Input           []string `json:"input"`
+	Model           string   `json:"model"`
+	OutputDimension *int     `json:"output_dimension,omitempty"`
+}
+
+type voyageEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		TotalTokens int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
 
+// NewEmbeddingGenerator embeds through the Voyage AI API. The API key comes
+// from WithAuthToken or VOYAGE_API_KEY and the base URL from WithURL,
+// VOYAGE_BASE_URL, or https://api.voyageai.com/v1. The model defaults to
+// voyage-3.5; WithEmbeddingDimensions sets output_dimension.
 func NewEmbeddingGenerator(opts ...model.GeneratorOption) (model.EmbeddingGenerator, error) {
-	_ = opts
-	return nil, utils.WrapIfNotNil(errors.New(unsupportedEmbeddingsMessage))
+	cfg := model.ResolveGeneratorOpts(opts...)
+
+	apiKey := strings.TrimSpace(cfg.AuthToken)
+	if apiKey == "" {
+		apiKey = strings.TrimSpace(os.Getenv(envVoyageAPIKey))
+	}
+	if apiKey == "" {
+		return nil, utils.WrapIfNotNil(errors.New("auth token is required (set WithAuthToken or VOYAGE_API_KEY)"))
+	}
+
+	baseURL := strings.TrimSpace(cfg.URL)
+	if baseURL == "" {
+		baseURL = strings.TrimSpace(os.Getenv(envVoyageBaseURL))
+	}
+	if baseURL == "" {
+		baseURL = defaultVoyageBaseURL
+	}
+
+	return &embeddingGenerator{
+		cfg:        cfg,
+		httpClient: model.NewHTTPClient(cfg, defaultHTTPTimeout),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+	}, nil
+}
+
+func (g *embeddingGenerator) Generate(
+	ctx context.Context,
+	input string,
+) (model.EmbeddingVector, model.GenerationMetadata, error) {
+	vectors, meta, err := g.GenerateBatch(ctx, []string{input})
+	if err != nil {
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+	if len(vectors) != 1 {
+		return nil, meta, utils.WrapIfNotNil(fmt.Errorf("expected exactly 1 embedding vector, got %d", len(vectors)))
+	}
+	return vectors[0], meta, nil
+}
+
+// GenerateBatch splits inputs into requests of at most voyageMaxBatchSize
+// and returns the vectors in input order.
+func (g *embeddingGenerator) GenerateBatch(
+	ctx context.Context,
+	inputs []string,
+) (model.EmbeddingVectors, model.GenerationMetadata, error) {
+	start := time.Now()
+	modelName := resolveEmbeddingModelName(g.cfg)
+	meta := initMetadata(modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log := logging.NewLogger(ctx)
+	err := validateEmbeddingInputs(inputs)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+	dimensions, err := model.ResolveEmbeddingDimensions(g.cfg, voyageProviderName, modelName, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+
+	log.Infof(
+		"embedding_request inputs=%d model=%q dimensions=%v",
+		len(inputs),
+		modelName,
+		dimensions,
+	)
+
+	vectors := make(model.EmbeddingVectors, 0, len(inputs))
+	apiCalls := 0
+	var totalTokens int64
+	for batchStart := 0; batchStart < len(inputs); batchStart += voyageMaxBatchSize {
+		batchEnd := min(batchStart+voyageMaxBatchSize, len(inputs))
+		response, err := g.embed(ctx, voyageEmbeddingRequest{
+			Input:           inputs[batchStart:batchEnd],
+			Model:           modelName,
+			OutputDimension: dimensions,
+		})
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return nil, meta, utils.WrapIfNotNil(err)
+		}
+		batch, err := convertVoyageEmbeddingResponse(response, batchEnd-batchStart)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return nil, meta, utils.WrapIfNotNil(err)
+		}
+		vectors = append(vectors, batch...)
+		apiCalls++
+		totalTokens += response.Usage.TotalTokens
+		if responseModel := strings.TrimSpace(response.Model); responseModel != "" {
+			meta[model.MetadataKeyModel] = responseModel
+		}
+	}
+
+	meta[model.MetadataKeyEmbeddingCount] = strconv.Itoa(len(vectors))
+	if len(vectors) > 0 {
+		meta[model.MetadataKeyEmbeddingDims] = strconv.Itoa(len(vectors[0]))
+	}
+	meta[model.MetadataKeyAPICalls] = strconv.Itoa(apiCalls)
+	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(totalTokens, 10)
+	meta[model.MetadataKeyOutputTokens] = "0"
+	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(totalTokens, 10)
+	return vectors, meta, nil
+}
+
+func (g *embeddingGenerator) embed(ctx context.Context, request voyageEmbeddingRequest) (*voyageEmbeddingResponse, error) {
+	requestBits, err := json.Marshal(request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/embeddings", bytes.NewReader(requestBits))
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	httpResponse, err := g.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	defer httpResponse.Body.Close()
+
+	responseBits, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		apiErr := struct {
+			Detail string `json:"detail"`
+		}{}
+		message := strings.TrimSpace(string(responseBits))
+		if unmarshalErr := json.Unmarshal(responseBits, &apiErr); unmarshalErr == nil && strings.TrimSpace(apiErr.Detail) != "" {
+			message = strings.TrimSpace(apiErr.Detail)
+		}
+		return nil, utils.WrapIfNotNil(fmt.Errorf("voyage embedding API error (%d): %s", httpResponse.StatusCode, message))
+	}
+
+	response := voyageEmbeddingResponse{}
+	if err = json.Unmarshal(responseBits, &response); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return &response, nil
+}
+
+// convertVoyageEmbeddingResponse orders the vectors by their reported index.
+func convertVoyageEmbeddingResponse(response *voyageEmbeddingResponse, expected int) (model.EmbeddingVectors, error) {
+	if response == nil || len(response.Data) == 0 {
+		return nil, utils.WrapIfNotNil(errors.New("embedding response has no data"))
+	}
+	if len(response.Data) != expected {
+		return nil, utils.WrapIfNotNil(
+			fmt.Errorf("embedding response size mismatch: expected %d, got %d", expected, len(response.Data)),
+		)
+	}
+
+	vectors := make(model.EmbeddingVectors, expected)
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= expected || vectors[item.Index] != nil {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("embedding response has invalid index %d", item.Index))
+		}
+		vectors[item.Index] = append(model.EmbeddingVector(nil), item.Embedding...)
+	}
+	return vectors, nil
+}
+
+func resolveEmbeddingModelName(cfg model.GeneratorConfig) string {
+	if cfg.Model != nil {
+		name := strings.TrimSpace(*cfg.Model)
+		if name != "" {
+			return name
+		}
+	}
+	return defaultEmbeddingModelName
 }
 
 func validateEmbeddingInputs(inputs []string) error {
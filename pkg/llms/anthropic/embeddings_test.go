@@ -1,8 +1,14 @@
 package anthropic
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -14,11 +20,145 @@ func TestEmbeddingsSuite(t *testing.T) {
 	suite.Run(t, new(EmbeddingsSuite))
 }
 
-func (s *EmbeddingsSuite) TestNewEmbeddingGeneratorReturnsUnsupported() {
+func (s *EmbeddingsSuite) TestNewEmbeddingGeneratorRequiresAuthToken() {
+	s.T().Setenv(envVoyageAPIKey, "")
 	generator, err := NewEmbeddingGenerator()
 	s.Nil(generator)
 	s.Error(err)
-	s.Contains(err.Error(), unsupportedEmbeddingsMessage)
+	s.Contains(err.Error(), "VOYAGE_API_KEY")
+}
+
+func (s *EmbeddingsSuite) TestGenerateBatchCallsVoyage() {
+	var requests []voyageEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/v1/embeddings", r.URL.Path)
+		s.Equal("Bearer voyage-key", r.Header.Get("Authorization"))
+
+		var request voyageEmbeddingRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+
+		// Reply out of order to check vectors follow the reported index.
+		data := make([]map[string]any, 0, len(request.Input))
+		for i := len(request.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": []float64{float64(i), 0.5}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data":  data,
+			"model": "voyage-3.5-lite",
+			"usage": map[string]any{"total_tokens": 7},
+		})
+	}))
+	defer server.Close()
+
+	generator, err := NewEmbeddingGenerator(
+		model.WithURL(server.URL+"/v1"),
+		model.WithAuthToken("voyage-key"),
+		model.WithModel("voyage-3.5-lite"),
+		model.WithEmbeddingDimensions(256),
+	)
+	s.Require().NoError(err)
+
+	vectors, meta, err := generator.GenerateBatch(context.Background(), []string{"first", "second"})
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVectors{{0, 0.5}, {1, 0.5}}, vectors)
+	s.Require().Len(requests, 1)
+	s.Equal([]string{"first", "second"}, requests[0].Input)
+	s.Equal("voyage-3.5-lite", requests[0].Model)
+	s.Require().NotNil(requests[0].OutputDimension)
+	s.Equal(256, *requests[0].OutputDimension)
+	s.Equal("2", meta[model.MetadataKeyEmbeddingCount])
+	s.Equal("2", meta[model.MetadataKeyEmbeddingDims])
+	s.Equal("7", meta[model.MetadataKeyInputTokens])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+}
+
+func (s *EmbeddingsSuite) TestGenerateBatchSplitsLargeBatches() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var request voyageEmbeddingRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		data := make([]map[string]any, 0, len(request.Input))
+		for i := range request.Input {
+			data = append(data, map[string]any{"index": i, "embedding": []float64{1}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data, "usage": map[string]any{"total_tokens": 1}})
+	}))
+	defer server.Close()
+
+	generator, err := NewEmbeddingGenerator(model.WithURL(server.URL), model.WithAuthToken("voyage-key"))
+	s.Require().NoError(err)
+
+	inputs := make([]string, voyageMaxBatchSize+1)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("input %d", i)
+	}
+	vectors, meta, err := generator.GenerateBatch(context.Background(), inputs)
+	s.Require().NoError(err)
+	s.Len(vectors, len(inputs))
+	s.Equal(2, calls)
+	s.Equal("2", meta[model.MetadataKeyInputTokens])
+}
+
+func (s *EmbeddingsSuite) TestGenerateBatchReturnsAPIError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{"detail":"output_dimension 7 is not supported"}`)
+	}))
+	defer server.Close()
+
+	generator, err := NewEmbeddingGenerator(model.WithURL(server.URL), model.WithAuthToken("voyage-key"))
+	s.Require().NoError(err)
+
+	_, _, err = generator.Generate(context.Background(), "hello")
+	s.Error(err)
+	s.Contains(err.Error(), "output_dimension 7 is not supported")
+}
+
+func (s *EmbeddingsSuite) TestGenerateBatchRejectsInvalidDimensions() {
+	generator, err := NewEmbeddingGenerator(model.WithAuthToken("voyage-key"), model.WithEmbeddingDimensions(0))
+	s.Require().NoError(err)
+
+	_, _, err = generator.Generate(context.Background(), "hello")
+	s.Error(err)
+	s.Contains(err.Error(), "embedding dimensions must be greater than zero")
+}
+
+func (s *EmbeddingsSuite) TestGenerateBatchChecksModelDimensions() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var request voyageEmbeddingRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		s.Nil(request.OutputDimension)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]any{{"index": 0, "embedding": []float64{1}}},
+		})
+	}))
+	defer server.Close()
+
+	generator, err := NewEmbeddingGenerator(
+		model.WithURL(server.URL),
+		model.WithAuthToken("voyage-key"),
+		model.WithEmbeddingDimensions(768),
+	)
+	s.Require().NoError(err)
+	_, _, err = generator.Generate(context.Background(), "hello")
+	s.Require().Error(err)
+	s.Contains(err.Error(), `embedding dimensions 768 are not supported by voyage model "voyage-3.5"`)
+	s.Zero(calls)
+
+	generator, err = NewEmbeddingGenerator(
+		model.WithURL(server.URL),
+		model.WithAuthToken("voyage-key"),
+		model.WithEmbeddingDimensions(768),
+		model.WithIgnoreInvalidGeneratorOptions(true),
+	)
+	s.Require().NoError(err)
+	_, _, err = generator.Generate(context.Background(), "hello")
+	s.Require().NoError(err)
+	s.Equal(1, calls)
 }
 
 func (s *EmbeddingsSuite) TestValidateEmbeddingInputsEmptyInputReturnsError() {
@@ -0,0 +1,71 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+// buildConversationHistory flattens Anthropic content blocks into the
+// provider-neutral format: tool_use blocks become tool calls on the assistant
+// turn and each tool_result block becomes its own tool message.
+func buildConversationHistory(modelName string, system string, messages []anthropicMessage) model.ConversationHistory {
+	history := model.ConversationHistory{
+		Version:  model.ConversationHistoryVersion,
+		Provider: providerName,
+		Model:    modelName,
+		Messages: make([]model.HistoryMessage, 0, len(messages)+1),
+	}
+	if strings.TrimSpace(system) != "" {
+		history.Messages = append(history.Messages, model.HistoryMessage{Role: model.HistoryRoleSystem, Content: system})
+	}
+
+	toolNames := map[string]string{}
+	for _, message := range messages {
+		entry := model.HistoryMessage{Role: model.HistoryRole(message.Role)}
+		texts := make([]string, 0, len(message.Content))
+		for _, block := range message.Content {
+			switch block.Type {
+			case "text":
+				texts = append(texts, block.Text)
+			case "tool_use", "mcp_tool_use":
+				toolNames[block.ID] = block.Name
+				entry.ToolCalls = append(entry.ToolCalls, model.HistoryToolCall{
+					ID:        block.ID,
+					Name:      block.Name,
+					Arguments: append(json.RawMessage(nil), block.Input...),
+				})
+			case "tool_result", "mcp_tool_result":
+				history.Messages = append(history.Messages, model.HistoryMessage{
+					Role:       model.HistoryRoleTool,
+					Content:    toolResultText(block.Content),
+					ToolCallID: block.ToolUseID,
+					ToolName:   toolNames[block.ToolUseID],
+				})
+			}
+		}
+
+		entry.Content = strings.Join(texts, "\n")
+		if entry.Content == "" && len(entry.ToolCalls) == 0 {
+			continue
+		}
+		history.Messages = append(history.Messages, entry)
+	}
+	return history
+}
+
+// toolResultText unwraps tool_result content, which is either a JSON string or
+// a list of text blocks.
+func toolResultText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+
+	var blocks []anthropicContentBlock
+	if err := json.Unmarshal(content, &blocks); err == nil {
+		return extractTextFromContentBlocks(blocks)
+	}
+	return string(content)
+}
@@ -1,6 +1,7 @@
 package anthropic
 
 import (
+	"context"
 	"testing"
 
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
@@ -15,28 +16,149 @@ func TestOptionsSuite(t *testing.T) {
 	suite.Run(t, new(OptionsSuite))
 }
 
-func (s *OptionsSuite) TestReasoningLevelStrictReturnsError() {
-	_, err := normalizeGeneratorOptionsForProvider(
-		model.ResolveGeneratorOpts(
-			model.WithIgnoreInvalidGeneratorOptions(false),
-			model.WithReasoningLevel(model.ReasoningLevelLow),
-		),
+func (s *OptionsSuite) TestReasoningLevelMapsToThinkingBudget() {
+	cases := map[model.ReasoningLevel]int{
+		model.ReasoningLevelNone: 0,
+		model.ReasoningLevelLow:  lowThinkingBudgetTokens,
+		model.ReasoningLevelMed:  medThinkingBudgetTokens,
+		model.ReasoningLevelHigh: highThinkingBudgetTokens,
+	}
+	for level, budget := range cases {
+		cfg, err := normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(model.WithReasoningLevel(level)), nil)
+		s.Require().NoError(err)
+
+		thinking := resolveThinking(cfg)
+		if budget == 0 {
+			s.Nil(thinking, level)
+			s.Equal(defaultMaxTokens, resolveMaxTokens(cfg), level)
+			continue
+		}
+		s.Require().NotNil(thinking, level)
+		s.Equal("enabled", thinking.Type)
+		s.Equal(budget, thinking.BudgetTokens)
+		s.Equal(budget+defaultMaxTokens, resolveMaxTokens(cfg))
+	}
+}
+
+func (s *OptionsSuite) TestThinkingBudgetShrinksToFitMaxTokens() {
+	cfg := model.ResolveGeneratorOpts(model.WithReasoningLevel(model.ReasoningLevelHigh), model.WithMaxTokens(8000))
+
+	thinking := resolveThinking(cfg)
+	s.Require().NotNil(thinking)
+	s.Equal(4000, thinking.BudgetTokens)
+	s.Equal(8000, resolveMaxTokens(cfg))
+}
+
+func (s *OptionsSuite) TestReasoningLevelWithSmallMaxTokens() {
+	opts := []model.GeneratorOption{model.WithReasoningLevel(model.ReasoningLevelLow), model.WithMaxTokens(512)}
+
+	_, err := normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(opts...), nil)
+	s.Error(err)
+	s.Contains(err.Error(), "max tokens must exceed")
+
+	normalized, err := normalizeGeneratorOptionsForProvider(
+		model.ResolveGeneratorOpts(append(opts, model.WithIgnoreInvalidGeneratorOptions(true))...),
 		nil,
 	)
+	s.NoError(err)
+	s.Nil(normalized.ReasoningLevel)
+}
+
+func (s *OptionsSuite) TestReasoningLevelWithTemperature() {
+	opts := []model.GeneratorOption{model.WithReasoningLevel(model.ReasoningLevelMed), model.WithTemperature(0.3)}
 
+	_, err := normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(opts...), nil)
 	s.Error(err)
-	s.Contains(err.Error(), "reasoning level is not supported")
+	s.Contains(err.Error(), "temperature is not supported")
+
+	normalized, err := normalizeGeneratorOptionsForProvider(
+		model.ResolveGeneratorOpts(append(opts, model.WithIgnoreInvalidGeneratorOptions(true))...),
+		nil,
+	)
+	s.NoError(err)
+	s.Nil(normalized.Temperature)
+	s.NotNil(normalized.ReasoningLevel)
+
+	_, err = normalizeGeneratorOptionsForProvider(
+		model.ResolveGeneratorOpts(model.WithReasoningLevel(model.ReasoningLevelNone), model.WithTemperature(0.3)),
+		nil,
+	)
+	s.NoError(err)
 }
 
-func (s *OptionsSuite) TestReasoningLevelIgnoredWhenConfigured() {
+func (s *OptionsSuite) TestReasoningLevelWithSamplingOptions() {
+	opts := []model.GeneratorOption{model.WithReasoningLevel(model.ReasoningLevelLow), model.WithTopK(10)}
+	_, err := normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(opts...), nil)
+	s.Error(err)
+	s.Contains(err.Error(), "top_k is not supported")
+
+	opts = []model.GeneratorOption{model.WithReasoningLevel(model.ReasoningLevelLow), model.WithTopP(0.5)}
+	_, err = normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(opts...), nil)
+	s.Error(err)
+	s.Contains(err.Error(), "top_p must be at least")
+
 	normalized, err := normalizeGeneratorOptionsForProvider(
-		model.ResolveGeneratorOpts(
-			model.WithIgnoreInvalidGeneratorOptions(true),
-			model.WithReasoningLevel(model.ReasoningLevelLow),
-		),
+		model.ResolveGeneratorOpts(append(opts, model.WithTopK(10), model.WithIgnoreInvalidGeneratorOptions(true))...),
 		nil,
 	)
+	s.NoError(err)
+	s.Nil(normalized.TopP)
+	s.Nil(normalized.TopK)
 
+	normalized, err = normalizeGeneratorOptionsForProvider(
+		model.ResolveGeneratorOpts(model.WithReasoningLevel(model.ReasoningLevelLow), model.WithTopP(0.97)),
+		nil,
+	)
 	s.NoError(err)
-	s.Nil(normalized.ReasoningLevel)
+	s.Require().NotNil(normalized.TopP)
+	s.InDelta(0.97, *normalized.TopP, 1e-9)
+}
+
+func (s *OptionsSuite) TestEffectiveConfigReportsDroppedOptions() {
+	s.T().Setenv(envAnthropicModel, "")
+	gen, err := NewStringContentGenerator("hi",
+		model.WithAuthToken("key"),
+		model.WithURL("https://gateway.example.com/"),
+		model.WithReasoningLevel(model.ReasoningLevelLow),
+		model.WithTemperature(0.2),
+		model.WithIgnoreInvalidGeneratorOptions(true),
+	)
+	s.Require().NoError(err)
+
+	cfg, err := gen.(model.EffectiveConfigReporter).EffectiveConfig(context.Background())
+	s.Require().NoError(err)
+	s.Nil(cfg.Temperature)
+	s.Require().NotNil(cfg.ReasoningLevel)
+	s.Require().NotNil(cfg.Model)
+	s.Equal(defaultModelName, *cfg.Model)
+	s.Equal("https://gateway.example.com", cfg.URL)
+	s.Equal(model.HostingPlatformDirect, *cfg.HostingPlatform)
+
+	strict, err := NewStructureContentGenerator[struct{}]("hi",
+		model.WithAuthToken("key"),
+		model.WithReasoningLevel(model.ReasoningLevelLow),
+		model.WithTemperature(0.2),
+	)
+	s.Require().NoError(err)
+	_, err = strict.(model.EffectiveConfigReporter).EffectiveConfig(context.Background())
+	s.ErrorContains(err, "temperature is not supported")
+}
+
+func (s *OptionsSuite) TestSamplingRangesAreValidated() {
+	_, err := normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(model.WithTemperature(1.2)), nil)
+	s.ErrorContains(err, "temperature 1.2 is out of range [0, 1] for anthropic provider")
+
+	bedrockModel := model.WithModel("us.anthropic.claude-opus-4-1-20250805-v1:0")
+	_, err = normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(bedrockModel, model.WithMaxTokens(40000)), nil)
+	s.ErrorContains(err, "exceeds the 32000 output token limit")
+
+	cfg, err := normalizeGeneratorOptionsForProvider(model.ResolveGeneratorOpts(
+		bedrockModel,
+		model.WithTemperature(1.2),
+		model.WithMaxTokens(40000),
+		model.WithIgnoreInvalidGeneratorOptions(true),
+	), nil)
+	s.Require().NoError(err)
+	s.Equal(1.0, *cfg.Temperature)
+	s.Equal(32000, *cfg.MaxTokens)
 }
@@ -0,0 +1,265 @@
+package anthropic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/auth/credentials"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/llms/internal/awsconfig"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+	vertexAnthropicVersion  = "vertex-2023-10-16"
+	defaultBedrockModelName = "anthropic.claude-3-7-sonnet-20250219-v1:0"
+	defaultVertexModelName  = "claude-3-7-sonnet@20250219"
+	defaultVertexLocation   = "us-east5"
+	bedrockSigningService   = "bedrock"
+	vertexAuthScope         = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// resolveHostingPlatform returns the configured platform. Setting a GCP project
+// or location without an explicit platform selects Vertex AI, matching the
+// Gemini provider.
+func resolveHostingPlatform(cfg model.GeneratorConfig) model.HostingPlatform {
+	if cfg.HostingPlatform != nil {
+		platform := model.HostingPlatform(strings.ToLower(strings.TrimSpace(string(*cfg.HostingPlatform))))
+		if platform != "" {
+			return platform
+		}
+	}
+	if strings.TrimSpace(cfg.GCPProject) != "" || strings.TrimSpace(cfg.GCPLocation) != "" {
+		return model.HostingPlatformVertex
+	}
+	return model.HostingPlatformDirect
+}
+
+func newBedrockAPIClient(cfg model.GeneratorConfig) (*apiClient, error) {
+	region := awsconfig.Region()
+
+	baseURL := strings.TrimSpace(cfg.URL)
+	if baseURL == "" {
+		baseURL = "https://bedrock-runtime." + region + ".amazonaws.com"
+	}
+
+	client := &apiClient{
+		httpClient: model.NewHTTPClient(cfg, defaultHTTPTimeout),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		// An auth token is sent as a Bedrock API key; otherwise requests are SigV4-signed.
+		apiKey:   strings.TrimSpace(cfg.AuthToken),
+		platform: model.HostingPlatformBedrock,
+		region:   region,
+	}
+	if client.apiKey == "" {
+		awsCfg, err := awsconfig.Load(context.Background(), region)
+		if errors.Is(err, awsconfig.ErrMissingCredentials) {
+			return nil, utils.WrapIfNotNil(err, "set WithAuthToken (Bedrock API key) or AWS credentials")
+		}
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		client.awsCredentials = awsCfg.Credentials
+	}
+	return client, nil
+}
+
+func newVertexAPIClient(cfg model.GeneratorConfig) (*apiClient, error) {
+	log := logging.NewLogger(context.Background())
+	project := model.EnvFallback(log, providerName, "gcp_project", cfg.GCPProject, "GOOGLE_CLOUD_PROJECT")
+	if project == "" {
+		return nil, utils.WrapIfNotNil(errors.New("gcp project is required for anthropic on vertex ai (set WithGCPProject or GOOGLE_CLOUD_PROJECT)"))
+	}
+
+	location := model.EnvFallback(log, providerName, "gcp_location", cfg.GCPLocation, "GOOGLE_CLOUD_LOCATION")
+	if location == "" {
+		location = defaultVertexLocation
+	}
+
+	baseURL := strings.TrimSpace(cfg.URL)
+	if baseURL == "" {
+		baseURL = "https://" + location + "-aiplatform.googleapis.com"
+		if location == "global" {
+			baseURL = "https://aiplatform.googleapis.com"
+		}
+	}
+
+	return &apiClient{
+		httpClient: model.NewHTTPClient(cfg, defaultHTTPTimeout),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		// An auth token is sent as an OAuth access token; otherwise Application Default Credentials are used.
+		apiKey:   strings.TrimSpace(cfg.AuthToken),
+		platform: model.HostingPlatformVertex,
+		project:  project,
+		location: location,
+	}, nil
+}
+
+// buildMessageHTTPRequest maps an anthropicMessageRequest onto the endpoint and
+// body shape of the client's hosting platform. The returned body is the one
+// sent, gzipped when it reaches compressMinBytes, so signing covers it.
+func (c *apiClient) buildMessageHTTPRequest(
+	ctx context.Context,
+	request anthropicMessageRequest,
+	includeMCPBeta bool,
+) (*http.Request, []byte, error) {
+	var (
+		endpoint string
+		body     []byte
+		err      error
+	)
+
+	switch c.platform {
+	case model.HostingPlatformBedrock:
+		endpoint = c.baseURL + "/model/" + url.PathEscape(request.Model) + "/invoke"
+		var betas []string
+		if includeMCPBeta {
+			betas = []string{anthropicMCPBeta}
+		}
+		body, err = platformRequestBody(request, bedrockAnthropicVersion, betas)
+	case model.HostingPlatformVertex:
+		endpoint = fmt.Sprintf(
+			"%s/v1/projects/%s/locations/%s/publishers/anthropic/models/%s:rawPredict",
+			c.baseURL,
+			url.PathEscape(c.project),
+			url.PathEscape(c.location),
+			url.PathEscape(request.Model),
+		)
+		body, err = platformRequestBody(request, vertexAnthropicVersion, nil)
+	default:
+		endpoint = c.baseURL + "/v1/messages"
+		body, err = json.Marshal(request)
+	}
+	if err != nil {
+		return nil, nil, utils.WrapIfNotNil(err)
+	}
+	body, err = model.MergeProviderParams(body, request.ProviderParams)
+	if err != nil {
+		return nil, nil, utils.WrapIfNotNil(err)
+	}
+
+	httpRequest, body, err := model.NewCompressedRequest(ctx, http.MethodPost, endpoint, body, c.compressMinBytes)
+	if err != nil {
+		return nil, nil, utils.WrapIfNotNil(err)
+	}
+	httpRequest.Header.Set("content-type", "application/json")
+	for name, value := range c.gatewayHeaders {
+		httpRequest.Header.Set(name, value)
+	}
+	return httpRequest, body, nil
+}
+
+func (c *apiClient) authorizeRequest(ctx context.Context, httpRequest *http.Request, body []byte, includeMCPBeta bool) error {
+	switch c.platform {
+	case model.HostingPlatformBedrock:
+		if c.apiKey != "" {
+			httpRequest.Header.Set("authorization", "Bearer "+c.apiKey)
+			return nil
+		}
+		return utils.WrapIfNotNil(c.signBedrockRequest(ctx, httpRequest, body))
+	case model.HostingPlatformVertex:
+		token, err := c.vertexAccessToken(ctx)
+		if err != nil {
+			return utils.WrapIfNotNil(err)
+		}
+		httpRequest.Header.Set("authorization", "Bearer "+token)
+		if includeMCPBeta {
+			httpRequest.Header.Set("anthropic-beta", anthropicMCPBeta)
+		}
+		return nil
+	default:
+		httpRequest.Header.Set("x-api-key", c.apiKey)
+		httpRequest.Header.Set("anthropic-version", anthropicVersion)
+		if includeMCPBeta {
+			httpRequest.Header.Set("anthropic-beta", anthropicMCPBeta)
+		}
+		return nil
+	}
+}
+
+// platformRequestBody drops the model (it is part of the URL on Bedrock and
+// Vertex AI) and adds the platform's anthropic_version.
+func platformRequestBody(request anthropicMessageRequest, version string, betas []string) ([]byte, error) {
+	requestBits, err := json.Marshal(request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	body := map[string]json.RawMessage{}
+	if err := json.Unmarshal(requestBits, &body); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	delete(body, "model")
+
+	versionBits, err := json.Marshal(version)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	body["anthropic_version"] = versionBits
+
+	if len(betas) > 0 {
+		betaBits, err := json.Marshal(betas)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		body["anthropic_beta"] = betaBits
+	}
+
+	out, err := json.Marshal(body)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return out, nil
+}
+
+func (c *apiClient) signBedrockRequest(ctx context.Context, httpRequest *http.Request, body []byte) error {
+	if c.awsCredentials == nil {
+		return utils.WrapIfNotNil(awsconfig.ErrMissingCredentials)
+	}
+	creds, err := c.awsCredentials.Retrieve(ctx)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+
+	payloadHash := sha256.Sum256(body)
+	signer := v4.NewSigner()
+	err = signer.SignHTTP(ctx, creds, httpRequest, hex.EncodeToString(payloadHash[:]), bedrockSigningService, c.region, time.Now())
+	return utils.WrapIfNotNil(err)
+}
+
+func (c *apiClient) vertexAccessToken(ctx context.Context) (string, error) {
+	if c.apiKey != "" {
+		return c.apiKey, nil
+	}
+
+	c.gcpCredentialsMu.Lock()
+	if c.gcpCredentials == nil {
+		detected, err := credentials.DetectDefault(&credentials.DetectOptions{
+			Scopes: []string{vertexAuthScope},
+		})
+		if err != nil {
+			c.gcpCredentialsMu.Unlock()
+			return "", utils.WrapIfNotNil(err)
+		}
+		c.gcpCredentials = detected
+	}
+	creds := c.gcpCredentials
+	c.gcpCredentialsMu.Unlock()
+
+	token, err := creds.Token(ctx)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return token.Value, nil
+}
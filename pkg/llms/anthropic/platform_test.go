@@ -0,0 +1,189 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/llms/internal/awsconfig"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/suite"
+)
+
+type PlatformSuite struct {
+	suite.Suite
+}
+
+func TestPlatformSuite(t *testing.T) {
+	suite.Run(t, new(PlatformSuite))
+}
+
+type capturedRequest struct {
+	path    string
+	headers http.Header
+	body    map[string]any
+}
+
+func (s *PlatformSuite) newServer(captured *capturedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.path = r.URL.EscapedPath()
+		captured.headers = r.Header.Clone()
+		bodyBits, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(bodyBits, &captured.body)
+
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`))
+	}))
+}
+
+func (s *PlatformSuite) TestResolveHostingPlatform() {
+	s.Equal(model.HostingPlatformDirect, resolveHostingPlatform(model.GeneratorConfig{}))
+	s.Equal(model.HostingPlatformVertex, resolveHostingPlatform(model.ResolveGeneratorOpts(model.WithGCPProject("p"))))
+	s.Equal(model.HostingPlatformBedrock, resolveHostingPlatform(model.ResolveGeneratorOpts(model.WithHostingPlatform("Bedrock"))))
+}
+
+func (s *PlatformSuite) TestUnsupportedPlatformReturnsError() {
+	_, err := newAPIClient(model.ResolveGeneratorOpts(model.WithHostingPlatform("azure")))
+	s.Error(err)
+	s.Contains(err.Error(), "unsupported hosting platform")
+}
+
+func (s *PlatformSuite) TestDirectRequestShape() {
+	captured := &capturedRequest{}
+	server := s.newServer(captured)
+	defer server.Close()
+
+	client, err := newAPIClient(model.ResolveGeneratorOpts(model.WithURL(server.URL), model.WithAuthToken("key")))
+	s.Require().NoError(err)
+
+	_, err = client.createMessage(context.Background(), anthropicMessageRequest{Model: "claude-test", MaxTokens: 10}, false)
+	s.Require().NoError(err)
+	s.Equal("/v1/messages", captured.path)
+	s.Equal("key", captured.headers.Get("x-api-key"))
+	s.Equal(anthropicVersion, captured.headers.Get("anthropic-version"))
+	s.Equal("claude-test", captured.body["model"])
+}
+
+func (s *PlatformSuite) TestBedrockRequestShapeWithAPIKey() {
+	captured := &capturedRequest{}
+	server := s.newServer(captured)
+	defer server.Close()
+
+	client, err := newAPIClient(model.ResolveGeneratorOpts(
+		model.WithHostingPlatform(model.HostingPlatformBedrock),
+		model.WithURL(server.URL),
+		model.WithAuthToken("bedrock-key"),
+	))
+	s.Require().NoError(err)
+
+	response, err := client.createMessage(context.Background(), anthropicMessageRequest{Model: "anthropic.claude-test-v1:0", MaxTokens: 10}, true)
+	s.Require().NoError(err)
+	s.Equal("msg_1", response.ID)
+	s.Equal("/model/anthropic.claude-test-v1:0/invoke", captured.path)
+	s.Equal("Bearer bedrock-key", captured.headers.Get("authorization"))
+	s.Empty(captured.headers.Get("x-api-key"))
+	s.NotContains(captured.body, "model")
+	s.Equal(bedrockAnthropicVersion, captured.body["anthropic_version"])
+	s.Equal([]any{anthropicMCPBeta}, captured.body["anthropic_beta"])
+	s.EqualValues(10, captured.body["max_tokens"])
+}
+
+func (s *PlatformSuite) TestBedrockRequestIsSigV4Signed() {
+	s.T().Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	s.T().Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	s.T().Setenv("AWS_REGION", "us-west-2")
+
+	captured := &capturedRequest{}
+	server := s.newServer(captured)
+	defer server.Close()
+
+	client, err := newAPIClient(model.ResolveGeneratorOpts(
+		model.WithHostingPlatform(model.HostingPlatformBedrock),
+		model.WithURL(server.URL),
+	))
+	s.Require().NoError(err)
+
+	_, err = client.createMessage(context.Background(), anthropicMessageRequest{Model: "anthropic.claude-test-v1:0", MaxTokens: 10}, false)
+	s.Require().NoError(err)
+	authorization := captured.headers.Get("authorization")
+	s.True(strings.HasPrefix(authorization, "AWS4-HMAC-SHA256"))
+	s.Contains(authorization, "/us-west-2/bedrock/aws4_request")
+}
+
+func (s *PlatformSuite) TestBedrockCredentialsAreLoadedOnce() {
+	s.T().Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	s.T().Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	s.T().Setenv("AWS_REGION", "us-west-2")
+
+	captured := &capturedRequest{}
+	server := s.newServer(captured)
+	defer server.Close()
+
+	client, err := newAPIClient(model.ResolveGeneratorOpts(
+		model.WithHostingPlatform(model.HostingPlatformBedrock),
+		model.WithURL(server.URL),
+	))
+	s.Require().NoError(err)
+	s.IsType(&aws.CredentialsCache{}, client.awsCredentials)
+
+	// Requests sign with the credentials loaded by the constructor, not by
+	// rereading the environment.
+	s.T().Setenv("AWS_ACCESS_KEY_ID", "")
+	s.T().Setenv("AWS_SECRET_ACCESS_KEY", "")
+	for i := 0; i < 2; i++ {
+		_, err = client.createMessage(context.Background(), anthropicMessageRequest{Model: "anthropic.claude-test-v1:0", MaxTokens: 10}, false)
+		s.Require().NoError(err)
+		s.Contains(captured.headers.Get("authorization"), "Credential=AKIDEXAMPLE/")
+	}
+}
+
+func (s *PlatformSuite) TestBedrockWithoutCredentialsFailsAtConstruction() {
+	s.T().Setenv("AWS_ACCESS_KEY_ID", "")
+	s.T().Setenv("AWS_SECRET_ACCESS_KEY", "")
+	s.T().Setenv("AWS_PROFILE", "")
+
+	_, err := newAPIClient(model.ResolveGeneratorOpts(model.WithHostingPlatform(model.HostingPlatformBedrock)))
+	s.Require().Error(err)
+	s.ErrorIs(err, awsconfig.ErrMissingCredentials)
+	s.Contains(err.Error(), "WithAuthToken")
+}
+
+func (s *PlatformSuite) TestVertexRequestShapeWithAccessToken() {
+	captured := &capturedRequest{}
+	server := s.newServer(captured)
+	defer server.Close()
+
+	client, err := newAPIClient(model.ResolveGeneratorOpts(
+		model.WithGCPProject("my-project"),
+		model.WithGCPLocation("us-east5"),
+		model.WithURL(server.URL),
+		model.WithAuthToken("access-token"),
+	))
+	s.Require().NoError(err)
+
+	_, err = client.createMessage(context.Background(), anthropicMessageRequest{Model: "claude-test@20250101", MaxTokens: 10}, false)
+	s.Require().NoError(err)
+	s.Equal("/v1/projects/my-project/locations/us-east5/publishers/anthropic/models/claude-test@20250101:rawPredict", captured.path)
+	s.Equal("Bearer access-token", captured.headers.Get("authorization"))
+	s.NotContains(captured.body, "model")
+	s.Equal(vertexAnthropicVersion, captured.body["anthropic_version"])
+}
+
+func (s *PlatformSuite) TestVertexRequiresProject() {
+	s.T().Setenv("GOOGLE_CLOUD_PROJECT", "")
+	_, err := newAPIClient(model.ResolveGeneratorOpts(model.WithHostingPlatform(model.HostingPlatformVertex)))
+	s.Error(err)
+	s.Contains(err.Error(), "gcp project is required")
+}
+
+func (s *PlatformSuite) TestResolveModelNamePlatformDefaults() {
+	s.T().Setenv(envAnthropicModel, "")
+	s.Equal(defaultModelName, resolveModelName(model.GeneratorConfig{}))
+	s.Equal(defaultBedrockModelName, resolveModelName(model.ResolveGeneratorOpts(model.WithHostingPlatform(model.HostingPlatformBedrock))))
+	s.Equal(defaultVertexModelName, resolveModelName(model.ResolveGeneratorOpts(model.WithGCPProject("p"))))
+}
@@ -5,39 +5,136 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/mcp"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 )
 
 type toolHandler func(ctx context.Context, args json.RawMessage) (any, error)
 
+// buildAllTools maps local tools and MCP servers. Servers the native MCP
+// connector can express go to mcp_servers with an mcp_toolset each; the
+// others are bridged through pkg/mcp as local tools (see splitMCPServers) and
+// cleanup disconnects them.
 func buildAllTools(
 	ctx context.Context,
 	cfg model.GeneratorConfig,
 ) ([]anthropicTool, map[string]toolHandler, []anthropicMCPServer, func(), error) {
-	localTools, handlers, err := mapLocalTools(cfg.Tools)
+	native, bridged, err := splitMCPServers(ctx, cfg.MCPTools, model.ResolveMCPBridgeMode(cfg))
 	if err != nil {
 		return nil, nil, nil, func() {}, utils.WrapIfNotNil(err)
 	}
 
-	mcpServers, err := mapMCPServers(ctx, cfg.MCPTools)
+	mcpServers, err := mapMCPServers(ctx, native)
 	if err != nil {
 		return nil, nil, nil, func() {}, utils.WrapIfNotNil(err)
 	}
 
-	mcpToolsets, err := mapMCPToolsets(cfg.MCPTools)
+	mcpToolsets, err := mapMCPToolsets(native)
 	if err != nil {
 		return nil, nil, nil, func() {}, utils.WrapIfNotNil(err)
 	}
 
+	bridgedTools, cleanup, err := mcp.ModelTools(ctx, bridged, cfg.MCPAdapterPool)
+	if err != nil {
+		return nil, nil, nil, func() {}, utils.WrapIfNotNil(err)
+	}
+
+	combined := append(append([]model.Tool(nil), cfg.Tools...), bridgedTools...)
+	localTools, handlers, err := mapLocalTools(model.InterceptTools(combined, cfg.ToolInterceptors))
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, func() {}, utils.WrapIfNotNil(err)
+	}
+
 	tools := make([]anthropicTool, 0, len(localTools)+len(mcpToolsets))
 	tools = append(tools, localTools...)
 	tools = append(tools, mcpToolsets...)
 
-	return tools, handlers, mcpServers, func() {}, nil
+	return tools, handlers, mcpServers, cleanup, nil
+}
+
+// splitMCPServers separates the servers the native MCP connector can express
+// from those bridged through the local tool adapter. The connector reaches
+// public HTTPS servers with at most a bearer authorization_token, so servers
+// with another URL scheme, custom headers or a non-bearer Authorization
+// header are bridged. MCPBridgeModeLocal bridges every server.
+func splitMCPServers(ctx context.Context, mcpTools []model.MCPTool, mode model.MCPBridgeMode) ([]model.MCPTool, []model.MCPTool, error) {
+	log := logging.NewLogger(ctx)
+	var native, bridged []model.MCPTool
+	for _, mcpTool := range mcpTools {
+		name := strings.TrimSpace(mcpTool.Name)
+		if name == "" {
+			return nil, nil, utils.WrapIfNotNil(errors.New("mcp tool name is required"))
+		}
+		if strings.TrimSpace(mcpTool.URL) == "" {
+			return nil, nil, utils.WrapIfNotNil(fmt.Errorf("mcp tool URL is required for %q", name))
+		}
+
+		reason := nativeMCPUnsupportedReason(mcpTool)
+		if mode == model.MCPBridgeModeLocal {
+			reason = "mcp bridge mode is local"
+		}
+		if reason != "" {
+			log.Infof("mcp tool %q is bridged through local tools: %s", name, reason)
+			bridged = append(bridged, mcpTool)
+			continue
+		}
+		native = append(native, mcpTool)
+	}
+	return native, bridged, nil
+}
+
+// nativeMCPUnsupportedReason explains why the native connector cannot
+// express mcpTool, or returns "" when it can.
+func nativeMCPUnsupportedReason(mcpTool model.MCPTool) string {
+	parsed, err := url.Parse(strings.TrimSpace(mcpTool.URL))
+	if err != nil || !strings.EqualFold(parsed.Scheme, "https") {
+		return "the native connector only reaches https servers"
+	}
+
+	custom := make([]string, 0)
+	for key, value := range mcpTool.HTTPHeaders {
+		if !strings.EqualFold(strings.TrimSpace(key), "Authorization") {
+			custom = append(custom, key)
+			continue
+		}
+		if strings.TrimSpace(mcpTool.AuthToken) == "" && bearerToken(value) == "" && strings.TrimSpace(value) != "" {
+			return "the native connector only sends bearer authorization tokens"
+		}
+	}
+	if len(custom) > 0 {
+		sort.Strings(custom)
+		return fmt.Sprintf("the native connector cannot send custom headers (%s)", strings.Join(custom, ","))
+	}
+	return ""
+}
+
+// nativeMCPAuthorizationToken returns MCPTool.AuthToken, or else the token of
+// a bearer Authorization header.
+func nativeMCPAuthorizationToken(mcpTool model.MCPTool) string {
+	if token := strings.TrimSpace(mcpTool.AuthToken); token != "" {
+		return token
+	}
+	for key, value := range mcpTool.HTTPHeaders {
+		if strings.EqualFold(strings.TrimSpace(key), "Authorization") {
+			return bearerToken(value)
+		}
+	}
+	return ""
+}
+
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
 }
 
 func mapLocalTools(tools []model.Tool) ([]anthropicTool, map[string]toolHandler, error) {
@@ -71,14 +168,13 @@ func mapLocalTools(tools []model.Tool) ([]anthropicTool, map[string]toolHandler,
 			InputSchema: inputSchema,
 		}
 		mapped = append(mapped, mappedTool)
-		handlers[name] = tool.Handler
+		handlers[name] = tool.Call
 	}
 
 	return mapped, handlers, nil
 }
 
 func mapMCPServers(ctx context.Context, mcpTools []model.MCPTool) ([]anthropicMCPServer, error) {
-	log := logging.NewLogger(ctx)
 	servers := make([]anthropicMCPServer, 0, len(mcpTools))
 
 	for _, mcpTool := range mcpTools {
@@ -87,18 +183,17 @@ func mapMCPServers(ctx context.Context, mcpTools []model.MCPTool) ([]anthropicMC
 			return nil, utils.WrapIfNotNil(errors.New("mcp tool name is required"))
 		}
 
-		url := strings.TrimSpace(mcpTool.URL)
-		if url == "" {
+		serverURL := strings.TrimSpace(mcpTool.URL)
+		if serverURL == "" {
 			return nil, utils.WrapIfNotNil(fmt.Errorf("mcp tool URL is required for %q", name))
 		}
 
-		authorizationToken := strings.TrimSpace(mcpTool.AuthToken)
-		warnOnUnsupportedMCPHeaders(log, mcpTool.Name, mcpTool.HTTPHeaders)
+		authorizationToken := nativeMCPAuthorizationToken(mcpTool)
 
 		server := anthropicMCPServer{
 			Type: "url",
 			Name: name,
-			URL:  url,
+			URL:  serverURL,
 		}
 		if authorizationToken != "" {
 			server.AuthorizationToken = authorizationToken
@@ -117,8 +212,8 @@ func mapMCPToolsets(mcpTools []model.MCPTool) ([]anthropicTool, error) {
 		if name == "" {
 			return nil, utils.WrapIfNotNil(errors.New("mcp tool name is required"))
 		}
-		url := strings.TrimSpace(mcpTool.URL)
-		if url == "" {
+		serverURL := strings.TrimSpace(mcpTool.URL)
+		if serverURL == "" {
 			return nil, utils.WrapIfNotNil(fmt.Errorf("mcp tool URL is required for %q", name))
 		}
 
@@ -140,31 +235,6 @@ func mapMCPToolsets(mcpTools []model.MCPTool) ([]anthropicTool, error) {
 	return toolsets, nil
 }
 
-func warnOnUnsupportedMCPHeaders(log logging.Logger, toolName string, headers map[string]string) {
-	if log == nil || len(headers) == 0 {
-		return
-	}
-
-	unsupported := make([]string, 0)
-	for key := range headers {
-		if strings.EqualFold(strings.TrimSpace(key), "Authorization") {
-			continue
-		}
-		unsupported = append(unsupported, key)
-	}
-	if len(unsupported) == 0 {
-		return
-	}
-
-	// NOTE: Anthropic MCP uses MCPTool.AuthToken for authorization_token forwarding.
-	// Arbitrary custom headers are not supported for remote MCP servers.
-	log.Warnf(
-		"mcp tool %q has unsupported custom headers (%s); anthropic MCP uses MCPTool.AuthToken instead of HTTPHeaders",
-		toolName,
-		strings.Join(unsupported, ","),
-	)
-}
-
 func normalizeAllowedTools(names []string) []string {
 	if len(names) == 0 {
 		return nil
@@ -66,7 +66,7 @@ func (s *ToolsSuite) TestMapMCPServersAuthTokenAndAllowedTools() {
 	s.Equal("Bearer abc123", servers[0].AuthorizationToken)
 }
 
-func (s *ToolsSuite) TestMapMCPServersIgnoresAuthorizationHeaderWithoutAuthToken() {
+func (s *ToolsSuite) TestMapMCPServersUsesBearerAuthorizationHeaderWithoutAuthToken() {
 	servers, err := mapMCPServers(context.Background(), []model.MCPTool{
 		{
 			Name: "mcp-a",
@@ -79,7 +79,113 @@ func (s *ToolsSuite) TestMapMCPServersIgnoresAuthorizationHeaderWithoutAuthToken
 
 	s.Require().NoError(err)
 	s.Len(servers, 1)
-	s.Empty(servers[0].AuthorizationToken)
+	s.Equal("from-header", servers[0].AuthorizationToken)
+}
+
+func (s *ToolsSuite) TestSplitMCPServersBridgesWhatTheConnectorCannotExpress() {
+	native, bridged, err := splitMCPServers(context.Background(), []model.MCPTool{
+		{Name: "plain", URL: "https://a.example", AllowedTools: []string{"x"}},
+		{Name: "bearer", URL: "https://b.example", HTTPHeaders: map[string]string{"authorization": "Bearer t"}},
+		{Name: "token", URL: "https://c.example", AuthToken: "t", HTTPHeaders: map[string]string{"Authorization": "Basic abc"}},
+		{Name: "custom", URL: "https://d.example", HTTPHeaders: map[string]string{"X-Tenant": "a"}},
+		{Name: "basic", URL: "https://e.example", HTTPHeaders: map[string]string{"Authorization": "Basic abc"}},
+		{Name: "local", URL: "http://localhost:8080/mcp"},
+	}, model.MCPBridgeModeNative)
+
+	s.Require().NoError(err)
+	s.Equal([]string{"plain", "bearer", "token"}, mcpToolNames(native))
+	s.Equal([]string{"custom", "basic", "local"}, mcpToolNames(bridged))
+
+	_, _, err = splitMCPServers(context.Background(), []model.MCPTool{{Name: "x"}}, model.MCPBridgeModeNative)
+	s.ErrorContains(err, "mcp tool URL is required")
+
+	native, bridged, err = splitMCPServers(context.Background(), []model.MCPTool{
+		{Name: "plain", URL: "https://a.example"},
+		{Name: "local", URL: "http://localhost:8080/mcp"},
+	}, model.MCPBridgeModeLocal)
+	s.Require().NoError(err)
+	s.Empty(native)
+	s.Equal([]string{"plain", "local"}, mcpToolNames(bridged))
+}
+
+func (s *ToolsSuite) TestNativeMCPUnsupportedReason() {
+	s.Empty(nativeMCPUnsupportedReason(model.MCPTool{URL: "https://a.example"}))
+	s.Contains(nativeMCPUnsupportedReason(model.MCPTool{URL: "https://a.example", HTTPHeaders: map[string]string{"X-B": "1", "X-A": "2"}}), "(X-A,X-B)")
+	s.Contains(nativeMCPUnsupportedReason(model.MCPTool{URL: "http://a.example"}), "https")
+}
+
+func (s *ToolsSuite) TestBuildAllToolsWithoutBridgedServersNeedsNoConnection() {
+	tools, handlers, servers, cleanup, err := buildAllTools(context.Background(), model.ResolveGeneratorOpts(
+		model.WithMCPTools([]model.MCPTool{{Name: "labs", URL: "https://labs.example", AllowedTools: []string{"get_egfr"}}}),
+	))
+	s.Require().NoError(err)
+	defer cleanup()
+	s.Empty(handlers)
+	s.Require().Len(servers, 1)
+	s.Require().Len(tools, 1)
+	s.Equal("mcp_toolset", tools[0].Type)
+}
+
+type fakeMCPPool struct {
+	servers []model.MCPTool
+}
+
+func (p *fakeMCPPool) MCPServerTools(ctx context.Context, server model.MCPTool) ([]model.Tool, error) {
+	p.servers = append(p.servers, server)
+	return []model.Tool{{
+		Name: "get_egfr",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return 48, nil
+		},
+	}}, nil
+}
+
+func (s *ToolsSuite) TestBuildAllToolsBridgesServersThroughThePool() {
+	pool := &fakeMCPPool{}
+	tools, handlers, servers, cleanup, err := buildAllTools(context.Background(), model.ResolveGeneratorOpts(
+		model.WithMCPTools([]model.MCPTool{
+			{Name: "native", URL: "https://native.example"},
+			{Name: "labs", URL: "https://labs.example", HTTPHeaders: map[string]string{"X-Tenant": "a"}},
+		}),
+		model.WithMCPAdapterPool(pool),
+	))
+	s.Require().NoError(err)
+	defer cleanup()
+
+	s.Require().Len(pool.servers, 1)
+	s.Equal("labs", pool.servers[0].Name)
+	s.Require().Len(servers, 1)
+	s.Equal("native", servers[0].Name)
+	s.Require().Len(tools, 2)
+	s.Equal("get_egfr", tools[0].Name)
+	s.Equal("mcp_toolset", tools[1].Type)
+	s.Contains(handlers, "get_egfr")
+}
+
+func (s *ToolsSuite) TestBuildAllToolsLocalBridgeModeSendsNoMCPServers() {
+	pool := &fakeMCPPool{}
+	tools, handlers, servers, cleanup, err := buildAllTools(context.Background(), model.ResolveGeneratorOpts(
+		model.WithMCPTools([]model.MCPTool{{Name: "labs", URL: "https://labs.example", AuthToken: "t"}}),
+		model.WithMCPAdapterPool(pool),
+		model.WithMCPBridgeMode(model.MCPBridgeModeLocal),
+	))
+	s.Require().NoError(err)
+	defer cleanup()
+
+	s.Empty(servers)
+	s.Require().Len(pool.servers, 1)
+	s.Equal("labs", pool.servers[0].Name)
+	s.Require().Len(tools, 1)
+	s.Equal("get_egfr", tools[0].Name)
+	s.Contains(handlers, "get_egfr")
+}
+
+func mcpToolNames(tools []model.MCPTool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
 }
 
 func (s *ToolsSuite) TestMapMCPToolsetsCreatesServerReferences() {
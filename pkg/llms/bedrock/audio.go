@@ -0,0 +1,201 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// defaultAudioTranscriptionModelName is a multimodal Nova model that accepts
+// Converse audio blocks. Amazon Transcribe is not used: it needs the audio
+// staged in S3 and polls an asynchronous job.
+const defaultAudioTranscriptionModelName = "us.amazon.nova-2-omni-v1:0"
+
+var audioFormatsByExtension = map[string]bedrocktypes.AudioFormat{
+	".mp3":  bedrocktypes.AudioFormatMp3,
+	".wav":  bedrocktypes.AudioFormatWav,
+	".flac": bedrocktypes.AudioFormatFlac,
+	".ogg":  bedrocktypes.AudioFormatOgg,
+	".opus": bedrocktypes.AudioFormatOpus,
+	".aac":  bedrocktypes.AudioFormatAac,
+	".m4a":  bedrocktypes.AudioFormatMp4,
+	".mp4":  bedrocktypes.AudioFormatMp4,
+	".mkv":  bedrocktypes.AudioFormatMkv,
+}
+
+type audioTranscriptionGenerator struct {
+	filePath string
+	opts     model.AudioOptions
+	cfg      model.GeneratorConfig
+}
+
+// NewAudioTranscriptionGenerator transcribes with a multimodal model through
+// the Converse API, using the same AWS credentials as content generation.
+func NewAudioTranscriptionGenerator(
+	filePath string,
+	opts model.AudioOptions,
+) (model.AudioTranscriptionGenerator, error) {
+	if strings.TrimSpace(filePath) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("file path is required"))
+	}
+
+	cfg := model.GeneratorConfig{
+		IgnoreInvalidGeneratorOptions: opts.IgnoreInvalidGeneratorOptions,
+		URL:                           opts.URL,
+		TLSConfig:                     opts.TLSConfig,
+	}
+	modelName := strings.TrimSpace(opts.Model)
+	if modelName == "" {
+		modelName = defaultAudioTranscriptionModelName
+	}
+	cfg.Model = &modelName
+
+	opts.Keywords = append([]model.AudioKeyword(nil), opts.Keywords...)
+	return &audioTranscriptionGenerator{
+		filePath: filePath,
+		opts:     opts,
+		cfg:      cfg,
+	}, nil
+}
+
+func (g *audioTranscriptionGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	modelName := resolveModelName(g.cfg)
+	meta := initMetadata(modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log := logging.NewLogger(ctx)
+	format, err := model.ResolveTranscriptFormat(g.opts)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	if format == model.TranscriptFormatSRT || format == model.TranscriptFormatVTT {
+		err = errors.New(string(format) + " output is not supported by bedrock transcription")
+		if !g.opts.IgnoreInvalidGeneratorOptions {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		log.Warnf("returning plain transcript: %v", err)
+		format = model.TranscriptFormatText
+	}
+
+	audioBlock, err := loadAudioBlock(g.filePath)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	prompt, err := buildAudioTranscriptionPrompt(g.opts)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	client, err := newClient(ctx, g.cfg)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	log.Infof("audio_transcription_request model=%q", modelName)
+	messages := []bedrocktypes.Message{{
+		Role: bedrocktypes.ConversationRoleUser,
+		Content: []bedrocktypes.ContentBlock{
+			&bedrocktypes.ContentBlockMemberText{Value: prompt},
+			audioBlock,
+		},
+	}}
+	finalMessage, totals, _, stopReason, responseLatencyMs, err := runConverseFlow(
+		ctx,
+		client,
+		modelName,
+		nil,
+		messages,
+		nil,
+		nil,
+		nil,
+		g.cfg,
+	)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	applyBedrockMetadata(meta, totals, stopReason, responseLatencyMs)
+
+	transcript := strings.TrimSpace(extractTextFromMessage(finalMessage))
+	if transcript == "" {
+		err = errors.New("transcription response is empty")
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	model.SetTranscriptLanguage(meta, g.opts, transcript)
+	transcript = model.RedactTranscript(meta, g.opts, transcript)
+	if format == model.TranscriptFormatJSON {
+		output, err := model.FormatTranscript(model.TranscriptionResult{Text: transcript, Language: meta[model.MetadataKeyLanguage]}, format)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		return output, meta, nil
+	}
+	return transcript, meta, nil
+}
+
+// loadAudioBlock reads filePath into an audio block typed by its extension.
+func loadAudioBlock(filePath string) (*bedrocktypes.ContentBlockMemberAudio, error) {
+	extension := strings.ToLower(filepath.Ext(filePath))
+	format, ok := audioFormatsByExtension[extension]
+	if !ok {
+		return nil, utils.WrapIfNotNil(errors.New("unsupported audio file extension " + extension))
+	}
+	audio, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return &bedrocktypes.ContentBlockMemberAudio{Value: bedrocktypes.AudioBlock{
+		Format: format,
+		Source: &bedrocktypes.AudioSourceMemberBytes{Value: audio},
+	}}, nil
+}
+
+func buildAudioTranscriptionPrompt(opts model.AudioOptions) (string, error) {
+	if prompt := strings.TrimSpace(opts.Prompt); prompt != "" {
+		return prompt, nil
+	}
+
+	base := "Transcribe this audio accurately. Return only the transcript text."
+	if opts.Diarize {
+		base = "Transcribe this audio accurately and identify the speakers. Return only the transcript, one line per speaker turn formatted as \"Speaker 1: text\", numbering speakers in order of first appearance."
+	}
+	if language := strings.TrimSpace(opts.Language); language != "" {
+		base += " The audio is in the language with ISO 639-1 code \"" + strings.ToLower(language) + "\"."
+	}
+
+	keywords := make([]model.AudioKeyword, 0, len(opts.Keywords))
+	for _, keyword := range opts.Keywords {
+		keyword.Word = strings.TrimSpace(keyword.Word)
+		keyword.Definition = strings.TrimSpace(keyword.Definition)
+		if keyword.Word == "" && keyword.Definition == "" && len(keyword.CommonMistypes) == 0 {
+			continue
+		}
+		keywords = append(keywords, keyword)
+	}
+	if len(keywords) == 0 {
+		return base, nil
+	}
+	keywordsJSON, err := json.Marshal(keywords)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return base + "\nCommon missed words: " + string(keywordsJSON), nil
+}
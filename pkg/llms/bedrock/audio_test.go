@@ -0,0 +1,243 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type AudioSuite struct {
+	suite.Suite
+}
+
+func TestAudioSuite(t *testing.T) {
+	suite.Run(t, new(AudioSuite))
+}
+
+func (s *AudioSuite) SetupSuite() {
+	setFakeAWSCredentials(s.T())
+}
+
+// writeAudio stores a short fake recording in a temporary file named name.
+func (s *AudioSuite) writeAudio(name string) (string, []byte) {
+	audio := []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+	path := filepath.Join(s.T().TempDir(), name)
+	s.Require().NoError(os.WriteFile(path, audio, 0o600))
+	return path, audio
+}
+
+// transcriptionServer answers every Converse call with transcript and keeps
+// the last request path and body.
+type transcriptionServer struct {
+	transcript string
+	path       string
+	body       converseAudioRequest
+}
+
+type converseAudioRequest struct {
+	Messages []struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Text  string `json:"text"`
+			Audio *struct {
+				Format string `json:"format"`
+				Source struct {
+					Bytes string `json:"bytes"`
+				} `json:"source"`
+			} `json:"audio"`
+		} `json:"content"`
+	} `json:"messages"`
+}
+
+func (f *transcriptionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.path = r.URL.Path
+	_ = json.NewDecoder(r.Body).Decode(&f.body)
+	w.Header().Set("content-type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"output": map[string]any{"message": map[string]any{
+			"role":    "assistant",
+			"content": []map[string]any{{"text": f.transcript}},
+		}},
+		"stopReason": "end_turn",
+		"usage":      map[string]any{"inputTokens": 120, "outputTokens": 18, "totalTokens": 138},
+		"metrics":    map[string]any{"latencyMs": 42},
+	})
+}
+
+func (s *AudioSuite) transcribe(fake *transcriptionServer, filePath string, opts model.AudioOptions) (string, model.GenerationMetadata, error) {
+	server := httptest.NewServer(fake)
+	s.T().Cleanup(server.Close)
+
+	opts.URL = server.URL
+	gen, err := NewAudioTranscriptionGenerator(filePath, opts)
+	s.Require().NoError(err)
+	return gen.Generate(context.Background())
+}
+
+func (s *AudioSuite) TestNewAudioTranscriptionGeneratorRequiresFilePath() {
+	gen, err := NewAudioTranscriptionGenerator("  ", model.AudioOptions{})
+	s.ErrorContains(err, "file path is required")
+	s.Nil(gen)
+}
+
+func (s *AudioSuite) TestNewAudioTranscriptionGeneratorResolvesModel() {
+	gen, err := NewAudioTranscriptionGenerator("visit.wav", model.AudioOptions{})
+	s.Require().NoError(err)
+	s.Equal(defaultAudioTranscriptionModelName, resolveModelName(gen.(*audioTranscriptionGenerator).cfg))
+
+	gen, err = NewAudioTranscriptionGenerator("visit.wav", model.AudioOptions{Model: " us.amazon.nova-lite-v1:0 "})
+	s.Require().NoError(err)
+	s.Equal("us.amazon.nova-lite-v1:0", resolveModelName(gen.(*audioTranscriptionGenerator).cfg))
+}
+
+func (s *AudioSuite) TestLoadAudioBlock() {
+	cases := []struct {
+		name       string
+		file       string
+		wantFormat bedrocktypes.AudioFormat
+	}{
+		{name: "wav", file: "visit.wav", wantFormat: bedrocktypes.AudioFormatWav},
+		{name: "mp3", file: "visit.mp3", wantFormat: bedrocktypes.AudioFormatMp3},
+		{name: "m4a is mp4", file: "visit.m4a", wantFormat: bedrocktypes.AudioFormatMp4},
+		{name: "extension case is ignored", file: "VISIT.FLAC", wantFormat: bedrocktypes.AudioFormatFlac},
+	}
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			path, audio := s.writeAudio(tc.file)
+			block, err := loadAudioBlock(path)
+			s.Require().NoError(err)
+			s.Equal(tc.wantFormat, block.Value.Format)
+			s.Equal(&bedrocktypes.AudioSourceMemberBytes{Value: audio}, block.Value.Source)
+		})
+	}
+}
+
+func (s *AudioSuite) TestLoadAudioBlockErrors() {
+	path, _ := s.writeAudio("notes.txt")
+	_, err := loadAudioBlock(path)
+	s.ErrorContains(err, "unsupported audio file extension .txt")
+
+	_, err = loadAudioBlock(filepath.Join(s.T().TempDir(), "missing.wav"))
+	s.ErrorIs(err, os.ErrNotExist)
+}
+
+func (s *AudioSuite) TestBuildAudioTranscriptionPrompt() {
+	plain := "Transcribe this audio accurately. Return only the transcript text."
+	cases := []struct {
+		name string
+		opts model.AudioOptions
+		want string
+	}{
+		{name: "default", want: plain},
+		{
+			name: "custom prompt wins over hints",
+			opts: model.AudioOptions{Prompt: " Transcribe the nephrology visit. ", Language: "es", Keywords: []model.AudioKeyword{{Word: "egfr"}}},
+			want: "Transcribe the nephrology visit.",
+		},
+		{
+			name: "language",
+			opts: model.AudioOptions{Language: " ES "},
+			want: plain + ` The audio is in the language with ISO 639-1 code "es".`,
+		},
+		{
+			name: "diarize",
+			opts: model.AudioOptions{Diarize: true},
+			want: `Transcribe this audio accurately and identify the speakers. Return only the transcript, one line per speaker turn formatted as "Speaker 1: text", numbering speakers in order of first appearance.`,
+		},
+		{
+			name: "keywords skip empty entries",
+			opts: model.AudioOptions{Keywords: []model.AudioKeyword{
+				{},
+				{Word: " losartan ", CommonMistypes: []string{"losarton"}, Definition: " An ARB. "},
+			}},
+			want: plain + "\n" + `Common missed words: [{"word":"losartan","common_mistypes":["losarton"],"definition":"An ARB."}]`,
+		},
+		{
+			name: "only empty keywords",
+			opts: model.AudioOptions{Keywords: []model.AudioKeyword{{Word: " "}}},
+			want: plain,
+		},
+	}
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			prompt, err := buildAudioTranscriptionPrompt(tc.opts)
+			s.Require().NoError(err)
+			s.Equal(tc.want, prompt)
+		})
+	}
+}
+
+func (s *AudioSuite) TestGenerateSendsPromptAndAudio() {
+	path, audio := s.writeAudio("visit.wav")
+	fake := &transcriptionServer{transcript: " The patient reports less swelling in the evening. "}
+
+	transcript, meta, err := s.transcribe(fake, path, model.AudioOptions{Language: "EN"})
+	s.Require().NoError(err)
+	s.Equal("The patient reports less swelling in the evening.", transcript)
+
+	s.Equal("/model/"+defaultAudioTranscriptionModelName+"/converse", fake.path)
+	s.Require().Len(fake.body.Messages, 1)
+	message := fake.body.Messages[0]
+	s.Equal("user", message.Role)
+	s.Require().Len(message.Content, 2)
+	s.Contains(message.Content[0].Text, `ISO 639-1 code "en"`)
+	s.Require().NotNil(message.Content[1].Audio)
+	s.Equal("wav", message.Content[1].Audio.Format)
+	s.Equal(base64.StdEncoding.EncodeToString(audio), message.Content[1].Audio.Source.Bytes)
+
+	s.Equal(providerName, meta[model.MetadataKeyProvider])
+	s.Equal(defaultAudioTranscriptionModelName, meta[model.MetadataKeyModel])
+	s.Equal("120", meta[model.MetadataKeyInputTokens])
+	s.Equal("18", meta[model.MetadataKeyOutputTokens])
+	s.Equal("end_turn", meta[model.MetadataKeyResponseStatus])
+	s.Equal("en", meta[model.MetadataKeyLanguage])
+	s.NotContains(meta, model.MetadataKeyRedactedEntities)
+}
+
+func (s *AudioSuite) TestGenerateDetectsLanguageAndRedactsPII() {
+	path, _ := s.writeAudio("visit.mp3")
+	fake := &transcriptionServer{transcript: "The patient can be reached at 555-123-4567 after the visit, and the results will be sent by email to pat@example.com."}
+
+	transcript, meta, err := s.transcribe(fake, path, model.AudioOptions{RedactPII: true})
+	s.Require().NoError(err)
+	s.Equal("The patient can be reached at [PHONE] after the visit, and the results will be sent by email to [EMAIL].", transcript)
+	s.Equal("EMAIL:1,PHONE:1", meta[model.MetadataKeyRedactedEntities])
+	s.Equal("en", meta[model.MetadataKeyLanguage])
+	s.NotEmpty(meta[model.MetadataKeyLanguageConfidence])
+}
+
+func (s *AudioSuite) TestGenerateOutputFormats() {
+	path, _ := s.writeAudio("visit.ogg")
+	transcript := "Creatinine is stable."
+
+	out, _, err := s.transcribe(&transcriptionServer{transcript: transcript}, path, model.AudioOptions{Language: "en", OutputFormat: model.TranscriptFormatJSON})
+	s.Require().NoError(err)
+	s.JSONEq(`{"text":"Creatinine is stable.","language":"en"}`, out)
+
+	fake := &transcriptionServer{transcript: transcript}
+	_, _, err = s.transcribe(fake, path, model.AudioOptions{OutputFormat: model.TranscriptFormatSRT})
+	s.ErrorContains(err, "srt output is not supported by bedrock transcription")
+	s.Empty(fake.path, "no request is sent for an unsupported format")
+
+	out, _, err = s.transcribe(&transcriptionServer{transcript: transcript}, path, model.AudioOptions{
+		OutputFormat:                  model.TranscriptFormatVTT,
+		IgnoreInvalidGeneratorOptions: true,
+	})
+	s.Require().NoError(err)
+	s.Equal(transcript, out)
+}
+
+func (s *AudioSuite) TestGenerateRejectsEmptyTranscript() {
+	path, _ := s.writeAudio("visit.wav")
+	_, _, err := s.transcribe(&transcriptionServer{transcript: "  "}, path, model.AudioOptions{})
+	s.ErrorContains(err, "transcription response is empty")
+}
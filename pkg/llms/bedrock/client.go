@@ -2,30 +2,31 @@ package bedrock
 
 import (
 	"context"
-	"errors"
-	"os"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/llms/internal/awsconfig"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 )
 
 const (
 	defaultModelName = "us.anthropic.claude-3-5-sonnet-20241022-v2:0"
-	maxToolRounds    = 12
 	providerName     = "bedrock"
-	defaultRegion    = "us-east-1"
+	// maxTemperature is the Converse API range shared by the model families.
+	maxTemperature = 1.0
 )
 
 type flowUsageTotals struct {
 	APICalls          int
 	ToolRounds        int
+	ToolCalls         []model.ToolCallRecord
 	InputTokens       int64
 	OutputTokens      int64
 	TotalTokens       int64
@@ -33,7 +34,7 @@ type flowUsageTotals struct {
 }
 
 func newClient(ctx context.Context, cfg model.GeneratorConfig) (*bedrockruntime.Client, error) {
-	awsCfg, err := loadAWSConfig(ctx)
+	awsCfg, err := awsconfig.Load(ctx, awsconfig.Region())
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
@@ -42,49 +43,33 @@ func newClient(ctx context.Context, cfg model.GeneratorConfig) (*bedrockruntime.
 		if strings.TrimSpace(cfg.URL) != "" {
 			o.BaseEndpoint = aws.String(strings.TrimSpace(cfg.URL))
 		}
+		if transport := model.NewHTTPTransport(cfg); transport != nil {
+			o.HTTPClient = awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+				t.TLSClientConfig = transport.TLSClientConfig
+			})
+		}
+		retryer := o.Retryer
+		if retryer == nil {
+			retryer = retry.NewStandard()
+		}
+		if retryerV2, ok := retryer.(aws.RetryerV2); ok {
+			o.Retryer = budgetRetryer{RetryerV2: retryerV2}
+		}
 	})
 	return client, nil
 }
 
-func loadAWSConfig(ctx context.Context) (aws.Config, error) {
-	region := strings.TrimSpace(os.Getenv("AWS_REGION"))
-	if region == "" {
-		region = defaultRegion
-	}
-
-	accessKeyID := strings.TrimSpace(os.Getenv("AWS_ACCESS_KEY_ID"))
-	secretAccessKey := strings.TrimSpace(os.Getenv("AWS_SECRET_ACCESS_KEY"))
-	profile := strings.TrimSpace(os.Getenv("AWS_PROFILE"))
-
-	loadOpts := []func(*config.LoadOptions) error{
-		config.WithRegion(region),
-	}
-
-	switch {
-	case accessKeyID != "" || secretAccessKey != "":
-		if accessKeyID == "" || secretAccessKey == "" {
-			return aws.Config{}, utils.WrapIfNotNil(
-				errors.New("both AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when using key-based auth"),
-			)
-		}
-
-		sessionToken := strings.TrimSpace(os.Getenv("AWS_SESSION_TOKEN"))
-		loadOpts = append(loadOpts, config.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, sessionToken),
-		))
-	case profile != "":
-		loadOpts = append(loadOpts, config.WithSharedConfigProfile(profile))
-	default:
-		return aws.Config{}, utils.WrapIfNotNil(
-			errors.New("missing AWS credentials: set AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or AWS_PROFILE"),
-		)
-	}
+// budgetRetryer refuses SDK retries once the model.RetryBudget on the request
+// context is spent; the SDK then returns the error of the last attempt.
+type budgetRetryer struct {
+	aws.RetryerV2
+}
 
-	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
-	if err != nil {
-		return aws.Config{}, utils.WrapIfNotNil(err)
+func (r budgetRetryer) GetRetryToken(ctx context.Context, opErr error) (func(error) error, error) {
+	if !model.RetryBudgetFromContext(ctx).TryRetry() {
+		return nil, utils.WrapIfNotNil(model.ErrRetryBudgetExhausted)
 	}
-	return cfg, nil
+	return r.RetryerV2.GetRetryToken(ctx, opErr)
 }
 
 func resolveModelName(cfg model.GeneratorConfig) string {
@@ -127,6 +112,7 @@ func applyBedrockMetadata(
 
 	meta[model.MetadataKeyAPICalls] = strconv.Itoa(totals.APICalls)
 	meta[model.MetadataKeyToolRounds] = strconv.Itoa(totals.ToolRounds)
+	model.SetToolCalls(meta, totals.ToolCalls)
 	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(totals.InputTokens, 10)
 	meta[model.MetadataKeyOutputTokens] = strconv.FormatInt(totals.OutputTokens, 10)
 	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(totals.TotalTokens, 10)
@@ -0,0 +1,131 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/testsupport"
+)
+
+type conformanceWire struct{}
+
+type conformanceBlock struct {
+	Text       string `json:"text,omitempty"`
+	ToolResult *struct {
+		ToolUseID string `json:"toolUseId"`
+		Content   []struct {
+			Text string          `json:"text"`
+			JSON json.RawMessage `json:"json"`
+		} `json:"content"`
+	} `json:"toolResult,omitempty"`
+}
+
+func (conformanceWire) DecodeRequest(r *http.Request) (testsupport.Request, error) {
+	var request struct {
+		Messages []struct {
+			Role    string             `json:"role"`
+			Content []conformanceBlock `json:"content"`
+		} `json:"messages"`
+		System     []conformanceBlock `json:"system"`
+		ToolConfig *struct {
+			Tools []struct {
+				ToolSpec struct {
+					Name string `json:"name"`
+				} `json:"toolSpec"`
+			} `json:"tools"`
+		} `json:"toolConfig"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return testsupport.Request{}, err
+	}
+
+	// The model is part of the path: /model/{modelId}/converse.
+	out := testsupport.Request{Model: strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/model/"), "/converse")}
+	for _, block := range request.System {
+		out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleSystem, Content: block.Text})
+	}
+	for _, message := range request.Messages {
+		var text []string
+		for _, block := range message.Content {
+			if block.Text != "" {
+				text = append(text, block.Text)
+			}
+			if block.ToolResult == nil {
+				continue
+			}
+			var content []string
+			for _, part := range block.ToolResult.Content {
+				if len(part.JSON) > 0 {
+					content = append(content, string(part.JSON))
+					continue
+				}
+				content = append(content, part.Text)
+			}
+			out.Messages = append(out.Messages, testsupport.Message{
+				Role:       testsupport.RoleTool,
+				Content:    strings.Join(content, "\n"),
+				ToolCallID: block.ToolResult.ToolUseID,
+			})
+		}
+		if len(text) > 0 {
+			out.Messages = append(out.Messages, testsupport.Message{Role: message.Role, Content: strings.Join(text, "\n")})
+		}
+	}
+	if request.ToolConfig != nil {
+		for _, tool := range request.ToolConfig.Tools {
+			out.Tools = append(out.Tools, tool.ToolSpec.Name)
+		}
+	}
+	return out, nil
+}
+
+func (conformanceWire) WriteResponse(w http.ResponseWriter, turn testsupport.Turn) {
+	w.Header().Set("content-type", "application/json")
+	if turn.StatusCode >= http.StatusMultipleChoices {
+		w.Header().Set("X-Amzn-ErrorType", "InternalServerException")
+		w.WriteHeader(turn.StatusCode)
+		_ = json.NewEncoder(w).Encode(map[string]any{"message": turn.ErrorMessage})
+		return
+	}
+
+	content := make([]map[string]any, 0, len(turn.ToolCalls)+1)
+	if turn.Text != "" {
+		content = append(content, map[string]any{"text": turn.Text})
+	}
+	stopReason := "end_turn"
+	for _, call := range turn.ToolCalls {
+		content = append(content, map[string]any{
+			"toolUse": map[string]any{"toolUseId": call.ID, "name": call.Name, "input": call.Arguments},
+		})
+		stopReason = "tool_use"
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"output":     map[string]any{"message": map[string]any{"role": "assistant", "content": content}},
+		"stopReason": stopReason,
+		"usage": map[string]any{
+			"inputTokens":  turn.InputTokens,
+			"outputTokens": turn.OutputTokens,
+			"totalTokens":  turn.InputTokens + turn.OutputTokens,
+		},
+		"metrics": map[string]any{"latencyMs": 1},
+	})
+}
+
+func TestConformance(t *testing.T) {
+	setFakeAWSCredentials(t)
+	testsupport.RunConformance(t, testsupport.Harness{
+		Provider:  providerName,
+		Wire:      conformanceWire{},
+		Options:   []model.GeneratorOption{model.WithRetryPolicy(model.RetryPolicy{GenerationBudget: -1})},
+		NewString: NewStringContentGenerator,
+		// The suite scripts JSON answers as text, so ask in the prompt
+		// rather than through the structured output tool.
+		NewStructured: func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[testsupport.Record], error) {
+			opts = append(opts, model.WithStructuredOutputMode(model.StructuredOutputModePrompt))
+			return NewStructureContentGenerator[testsupport.Record](prompt, opts...)
+		},
+	})
+}
@@ -11,12 +11,12 @@ import (
 
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	bedrockdocument "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
 	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
-	"github.com/invopop/jsonschema"
 )
 
 type structuredGenerator[T any] struct {
@@ -25,6 +25,8 @@ type structuredGenerator[T any] struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 type textGenerator struct {
@@ -33,6 +35,8 @@ type textGenerator struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
@@ -112,40 +116,49 @@ func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider m
 }
 
 func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *structuredGenerator[T]) generate(ctx context.Context) (T, model.GenerationMetadata, error) {
 	start := time.Now()
 	modelName := resolveModelName(g.cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
+	ctx, retries := model.StartRetryBudget(ctx, g.cfg)
+	defer model.SetRetryMetadata(meta, retries)
 
 	log := logging.NewLogger(ctx)
-	system, messages, contextCount, err := g.messagesWithContext(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(g.cfg, log)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	schema, err := generateSchema[T]()
+	system, messages, contextCount, err := g.messagesWithContext(ctx, meta)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
-	schemaJSON, err := json.Marshal(schema)
+
+	schema, err := model.StructuredOutputSchema[T](cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
-
-	messages[len(messages)-1].Content = []bedrocktypes.ContentBlock{
-		&bedrocktypes.ContentBlockMemberText{
-			Value: messages[len(messages)-1].Content[0].(*bedrocktypes.ContentBlockMemberText).Value +
-				"\n\nReturn ONLY valid JSON that matches this schema:\n" + string(schemaJSON),
-		},
+	mode, err := resolveStructuredOutputMode(cfg, modelName, schema)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	allTools, cleanup, err := buildAllTools(ctx, g.cfg)
+	allTools, cleanup, err := buildAllTools(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -159,8 +172,14 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
+	if _, exists := handlers[structuredOutputToolName]; exists && mode != model.StructuredOutputModePrompt {
+		err = fmt.Errorf("tool name %q is reserved for structured output", structuredOutputToolName)
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
 
-	client, err := newClient(ctx, g.cfg)
+	client, err := newClient(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -168,27 +187,34 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	}
 
 	log.Infof(
-		"prompt=%q context_count=%d model=%q temperature=%v max_tokens=%v tools=%d mcp_tools=%d",
+		"prompt=%q context_count=%d model=%q temperature=%v max_tokens=%v tools=%d mcp_tools=%d structured_output_mode=%s",
 		g.prompt,
 		contextCount,
 		modelName,
-		g.cfg.Temperature,
-		g.cfg.MaxTokens,
-		len(g.cfg.Tools),
-		len(g.cfg.MCPTools),
+		cfg.Temperature,
+		cfg.MaxTokens,
+		len(cfg.Tools),
+		len(cfg.MCPTools),
+		mode,
 	)
 
-	inference := buildInferenceConfig(g.cfg)
-	finalMessage, totals, stopReason, responseLatencyMs, err := runConverseFlow(
-		ctx,
-		client,
-		modelName,
-		system,
-		messages,
-		inference,
-		toolConfig,
-		handlers,
-	)
+	inference := buildInferenceConfig(cfg)
+	converse := func(mode model.StructuredOutputMode) (bedrocktypes.Message, flowUsageTotals, string, int64, error) {
+		requestMessages, requestTools, err := structuredOutputRequest(mode, modelName, schema, messages, toolConfig)
+		if err != nil {
+			return bedrocktypes.Message{}, flowUsageTotals{}, "", 0, utils.WrapIfNotNil(err)
+		}
+		message, totals, history, stopReason, latencyMs, err := runConverseFlow(ctx, client, modelName, system, requestMessages, inference, requestTools, handlers, cfg)
+		g.recordHistory(modelName, system, history)
+		return message, totals, stopReason, latencyMs, err
+	}
+	finalMessage, totals, stopReason, responseLatencyMs, err := converse(mode)
+	if err != nil && mode == model.StructuredOutputModeAuto && converseUnsupported(modelName) {
+		// InvokeModel has no tools, so the output tool cannot be used.
+		log.Warnf("model %q has no Converse tool use, falling back to prompt schema instructions", modelName)
+		mode = model.StructuredOutputModePrompt
+		finalMessage, totals, stopReason, responseLatencyMs, err = converse(mode)
+	}
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -196,7 +222,21 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	}
 	applyBedrockMetadata(meta, totals, stopReason, responseLatencyMs)
 
-	text := strings.TrimSpace(extractTextFromMessage(finalMessage))
+	text, fromTool, err := structuredOutputToolInput(finalMessage)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+	if fromTool {
+		mode = model.StructuredOutputModeNative
+	} else {
+		// The model answered in text, either in prompt mode or by not calling
+		// the output tool; parse it the same way.
+		text = strings.TrimSpace(extractTextFromMessage(finalMessage))
+		mode = model.StructuredOutputModePrompt
+	}
+	meta[model.MetadataKeyStructuredOutputMode] = string(mode)
 	if text == "" {
 		err = errors.New("response output is empty")
 		log.Errorf("error: %v", err)
@@ -204,9 +244,19 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	payload := extractJSONPayload(text)
-	var out T
-	err = json.Unmarshal([]byte(payload), &out)
+	model.SetRawOutput(meta, cfg, text)
+	out, err := model.DecodeStructuredOutputWithRepair[T](
+		ctx,
+		cfg,
+		meta,
+		schema,
+		text,
+		model.ResolveStructuredRepairAttempts(cfg, 0),
+		extractJSONPayload,
+		func(ctx context.Context, prompt string) (string, model.GenerationMetadata, error) {
+			return repairStructuredOutput(ctx, client, modelName, inference, cfg, prompt)
+		},
+	)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -216,19 +266,34 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 }
 
 func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generate(ctx context.Context) (string, model.GenerationMetadata, error) {
 	start := time.Now()
 	modelName := resolveModelName(g.cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
+	ctx, retries := model.StartRetryBudget(ctx, g.cfg)
+	defer model.SetRetryMetadata(meta, retries)
 
 	log := logging.NewLogger(ctx)
-	system, messages, contextCount, err := g.messagesWithContext(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(g.cfg, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	system, messages, contextCount, err := g.messagesWithContext(ctx, meta)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 
-	allTools, cleanup, err := buildAllTools(ctx, g.cfg)
+	allTools, cleanup, err := buildAllTools(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -241,7 +306,7 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 
-	client, err := newClient(ctx, g.cfg)
+	client, err := newClient(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -252,14 +317,14 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		g.prompt,
 		contextCount,
 		modelName,
-		g.cfg.Temperature,
-		g.cfg.MaxTokens,
-		len(g.cfg.Tools),
-		len(g.cfg.MCPTools),
+		cfg.Temperature,
+		cfg.MaxTokens,
+		len(cfg.Tools),
+		len(cfg.MCPTools),
 	)
 
-	inference := buildInferenceConfig(g.cfg)
-	finalMessage, totals, stopReason, responseLatencyMs, err := runConverseFlow(
+	inference := buildInferenceConfig(cfg)
+	finalMessage, totals, history, stopReason, responseLatencyMs, err := runConverseFlow(
 		ctx,
 		client,
 		modelName,
@@ -268,23 +333,73 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		inference,
 		toolConfig,
 		handlers,
+		cfg,
 	)
+	g.recordHistory(modelName, system, history)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	applyBedrockMetadata(meta, totals, stopReason, responseLatencyMs)
 
-	text := strings.TrimSpace(extractTextFromMessage(finalMessage))
-	if text == "" {
-		err = errors.New("response output is empty")
+	text, err := model.FinishTextOutput(ctx, cfg, extractTextFromMessage(finalMessage))
+	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	return text, meta, nil
 }
 
-func (g *structuredGenerator[T]) messagesWithContext(ctx context.Context) ([]bedrocktypes.SystemContentBlock, []bedrocktypes.Message, int, error) {
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *structuredGenerator[T]) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg)
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *textGenerator) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg)
+}
+
+// effectiveConfig applies the option normalization and model resolution of a
+// generation to cfg. The endpoint comes from the AWS configuration, so URL is
+// left as configured.
+func effectiveConfig(ctx context.Context, cfg model.GeneratorConfig) (model.GeneratorConfig, error) {
+	cfg, err := normalizeGeneratorOptionsForProvider(cfg, logging.NewLogger(ctx))
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	modelName := resolveModelName(cfg)
+	cfg.Model = &modelName
+	return cfg, nil
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *structuredGenerator[T]) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *structuredGenerator[T]) recordHistory(modelID string, system []bedrocktypes.SystemContentBlock, messages []bedrocktypes.Message) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelID, system, messages)
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *textGenerator) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *textGenerator) recordHistory(modelID string, system []bedrocktypes.SystemContentBlock, messages []bedrocktypes.Message) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelID, system, messages)
+}
+
+func (g *structuredGenerator[T]) messagesWithContext(ctx context.Context, meta model.GenerationMetadata) ([]bedrocktypes.SystemContentBlock, []bedrocktypes.Message, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -298,10 +413,22 @@ func (g *structuredGenerator[T]) messagesWithContext(ctx context.Context) ([]bed
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
 	return buildMessagesWithContext(g.prompt, contexts)
 }
 
-func (g *textGenerator) messagesWithContext(ctx context.Context) ([]bedrocktypes.SystemContentBlock, []bedrocktypes.Message, int, error) {
+func (g *textGenerator) messagesWithContext(ctx context.Context, meta model.GenerationMetadata) ([]bedrocktypes.SystemContentBlock, []bedrocktypes.Message, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -315,6 +442,18 @@ func (g *textGenerator) messagesWithContext(ctx context.Context) ([]bedrocktypes
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
 	return buildMessagesWithContext(g.prompt, contexts)
 }
 
@@ -332,11 +471,27 @@ func buildMessagesWithContext(
 		}
 
 		content := strings.TrimSpace(contextItem.Content)
-		if content == "" {
+		if !contextItem.HasContent() {
 			continue
 		}
 
+		images, err := model.ResolveImageParts(contextItem)
+		if err != nil {
+			return nil, nil, 0, utils.WrapIfNotNil(err)
+		}
+
 		contextCount++
+		if len(images) > 0 {
+			blocks, err := mapImageContentBlocks(content, images)
+			if err != nil {
+				return nil, nil, 0, utils.WrapIfNotNil(err)
+			}
+			messages = append(messages, bedrocktypes.Message{
+				Role:    bedrocktypes.ConversationRoleUser,
+				Content: blocks,
+			})
+			continue
+		}
 		switch contextItem.MessageType {
 		case model.ContextMessageTypeSystem:
 			system = append(system, &bedrocktypes.SystemContentBlockMemberText{Value: content})
@@ -374,6 +529,16 @@ func buildMessagesWithContext(
 	return system, messages, contextCount, nil
 }
 
+func normalizeGeneratorOptionsForProvider(cfg model.GeneratorConfig, log logging.Logger) (model.GeneratorConfig, error) {
+	cfg, err := model.ApplyGenerationLimits(cfg, providerName, resolveModelName(cfg), model.GenerationLimits{
+		MaxTemperature: maxTemperature,
+	}, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	return cfg, nil
+}
+
 func buildInferenceConfig(cfg model.GeneratorConfig) *bedrocktypes.InferenceConfiguration {
 	if cfg.MaxTokens == nil && cfg.Temperature == nil {
 		return nil
@@ -389,6 +554,49 @@ func buildInferenceConfig(cfg model.GeneratorConfig) *bedrocktypes.InferenceConf
 	return inference
 }
 
+// repairStructuredOutput sends a structured output repair prompt as a new
+// single-turn Converse request without tools.
+func repairStructuredOutput(
+	ctx context.Context,
+	client *bedrockruntime.Client,
+	modelID string,
+	inference *bedrocktypes.InferenceConfiguration,
+	cfg model.GeneratorConfig,
+	prompt string,
+) (string, model.GenerationMetadata, error) {
+	meta := initMetadata(modelID)
+	system := []bedrocktypes.SystemContentBlock{
+		&bedrocktypes.SystemContentBlockMemberText{Value: model.StructuredRepairSystemPrompt},
+	}
+	messages := []bedrocktypes.Message{
+		{
+			Role: bedrocktypes.ConversationRoleUser,
+			Content: []bedrocktypes.ContentBlock{
+				&bedrocktypes.ContentBlockMemberText{Value: prompt},
+			},
+		},
+	}
+
+	finalMessage, totals, _, stopReason, responseLatencyMs, err := runConverseFlow(
+		ctx,
+		client,
+		modelID,
+		system,
+		messages,
+		inference,
+		nil,
+		nil,
+		cfg,
+	)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	applyBedrockMetadata(meta, totals, stopReason, responseLatencyMs)
+	return strings.TrimSpace(extractTextFromMessage(finalMessage)), meta, nil
+}
+
+// runConverseFlow runs the Converse tool loop. Models that Bedrock rejects
+// for Converse are answered through runInvokeModelFlow instead.
 func runConverseFlow(
 	ctx context.Context,
 	client *bedrockruntime.Client,
@@ -398,21 +606,41 @@ func runConverseFlow(
 	inference *bedrocktypes.InferenceConfiguration,
 	toolConfig *bedrocktypes.ToolConfiguration,
 	handlers map[string]toolHandler,
-) (bedrocktypes.Message, flowUsageTotals, string, int64, error) {
+	cfg model.GeneratorConfig,
+) (bedrocktypes.Message, flowUsageTotals, []bedrocktypes.Message, string, int64, error) {
+	if converseUnsupported(modelID) {
+		return runInvokeModelFlow(ctx, client, modelID, system, initialMessages, inference, toolConfig, cfg)
+	}
+
 	totals := flowUsageTotals{}
 	history := append([]bedrocktypes.Message(nil), initialMessages...)
 	var responseLatencyMs int64
 
-	for round := 0; round < maxToolRounds; round++ {
+	maxRounds := model.ResolveMaxToolRounds(cfg)
+	toolErrors := model.NewToolErrorPolicy(cfg)
+	log := logging.NewLogger(ctx)
+	var additionalFields bedrockdocument.Interface
+	if len(cfg.ProviderParams) > 0 {
+		// Converse has no raw body; model-specific fields travel in
+		// additionalModelRequestFields.
+		additionalFields = bedrockdocument.NewLazyDocument(cfg.ProviderParams)
+	}
+	for round := 0; round < maxRounds; round++ {
 		output, err := client.Converse(ctx, &bedrockruntime.ConverseInput{
-			ModelId:         aws.String(modelID),
-			Messages:        history,
-			System:          system,
-			InferenceConfig: inference,
-			ToolConfig:      toolConfig,
+			ModelId:                      aws.String(modelID),
+			Messages:                     history,
+			System:                       system,
+			InferenceConfig:              inference,
+			ToolConfig:                   toolConfig,
+			AdditionalModelRequestFields: additionalFields,
 		})
+		if err != nil && round == 0 && isConverseUnsupportedError(err) && resolveInvokeModelFamily(modelID) != invokeFamilyUnsupported {
+			log.Warnf("model %q does not support Converse, falling back to InvokeModel: %v", modelID, err)
+			converseUnsupportedModels.Store(modelID, struct{}{})
+			return runInvokeModelFlow(ctx, client, modelID, system, initialMessages, inference, toolConfig, cfg)
+		}
 		if err != nil {
-			return bedrocktypes.Message{}, totals, "", 0, utils.WrapIfNotNil(err)
+			return bedrocktypes.Message{}, totals, history, "", 0, utils.WrapIfNotNil(err)
 		}
 
 		totals.APICalls++
@@ -428,37 +656,59 @@ func runConverseFlow(
 
 		message, err := extractOutputMessage(output.Output)
 		if err != nil {
-			return bedrocktypes.Message{}, totals, "", responseLatencyMs, utils.WrapIfNotNil(err)
+			return bedrocktypes.Message{}, totals, history, "", responseLatencyMs, utils.WrapIfNotNil(err)
 		}
 		history = append(history, message)
 
 		toolUses := extractToolUses(message)
 		if len(toolUses) == 0 {
-			return message, totals, string(output.StopReason), responseLatencyMs, nil
+			return message, totals, history, string(output.StopReason), responseLatencyMs, nil
+		}
+		if _, found, _ := structuredOutputToolInput(message); found && handlers[structuredOutputToolName] == nil {
+			// The structured output tool ends the flow; its input is the result.
+			return message, totals, history, string(output.StopReason), responseLatencyMs, nil
 		}
 
 		totals.ToolRounds = round + 1
-		resultBlocks := make([]bedrocktypes.ContentBlock, 0, len(toolUses))
+		callHandlers := make([]toolHandler, 0, len(toolUses))
+		callArgs := make([][]byte, 0, len(toolUses))
 		for _, toolUse := range toolUses {
 			name := strings.TrimSpace(aws.ToString(toolUse.Name))
 			handler, ok := handlers[name]
 			if !ok {
-				return bedrocktypes.Message{}, totals, "", responseLatencyMs, utils.WrapIfNotNil(
+				return bedrocktypes.Message{}, totals, history, "", responseLatencyMs, utils.WrapIfNotNil(
 					fmt.Errorf("no tool handler configured for function %q", name),
 				)
 			}
 
 			argsBytes, marshalErr := toolUse.Input.MarshalSmithyDocument()
 			if marshalErr != nil {
-				return bedrocktypes.Message{}, totals, "", responseLatencyMs, utils.WrapIfNotNil(marshalErr)
+				return bedrocktypes.Message{}, totals, history, "", responseLatencyMs, utils.WrapIfNotNil(marshalErr)
 			}
+			callHandlers = append(callHandlers, handler)
+			callArgs = append(callArgs, argsBytes)
+		}
 
-			result, callErr := handler(ctx, argsBytes)
+		results := toolexec.Run(ctx, len(toolUses), model.ResolveToolParallelism(cfg), func(ctx context.Context, i int) (any, error) {
+			return callHandlers[i](ctx, callArgs[i])
+		})
+		totals.ToolCalls = append(totals.ToolCalls, toolexec.Transcript(round+1, results, func(i int) (string, string) {
+			return aws.ToString(toolUses[i].Name), aws.ToString(toolUses[i].ToolUseId)
+		})...)
+
+		resultBlocks := make([]bedrocktypes.ContentBlock, 0, len(toolUses))
+		for i, toolUse := range toolUses {
+			callErr := results[i].Err
 			resultStatus := bedrocktypes.ToolResultStatusSuccess
-			resultPayload := any(result)
+			resultPayload := results[i].Value
 			if callErr != nil {
+				name := aws.ToString(toolUse.Name)
+				if abortErr := toolErrors.Handle(name, callErr); abortErr != nil {
+					return bedrocktypes.Message{}, totals, history, "", responseLatencyMs, utils.WrapIfNotNil(abortErr)
+				}
+				log.Warnf("tool %q failed, reporting to model: %v", name, callErr)
 				resultStatus = bedrocktypes.ToolResultStatusError
-				resultPayload = map[string]any{"error": callErr.Error()}
+				resultPayload = model.ToolErrorResult(callErr)
 			}
 
 			resultBlocks = append(resultBlocks, &bedrocktypes.ContentBlockMemberToolResult{
@@ -480,9 +730,128 @@ func runConverseFlow(
 		})
 	}
 
-	return bedrocktypes.Message{}, totals, "", responseLatencyMs, utils.WrapIfNotNil(
-		fmt.Errorf("exceeded tool call loop limit (%d)", maxToolRounds),
+	return bedrocktypes.Message{}, totals, history, "", responseLatencyMs, utils.WrapIfNotNil(
+		&model.MaxToolRoundsError{Limit: maxRounds},
+	)
+}
+
+// structuredOutputToolName is the tool whose input carries the structured
+// result in native mode.
+const structuredOutputToolName = "structured_output"
+
+// resolveStructuredOutputMode picks how the structured generator asks for
+// JSON. Native mode defines a tool whose input schema is the output schema,
+// which needs an object schema and Converse tool use; auto mode falls back
+// to schema instructions in the prompt otherwise.
+func resolveStructuredOutputMode(cfg model.GeneratorConfig, modelID string, schema map[string]any) (model.StructuredOutputMode, error) {
+	mode := model.ResolveStructuredOutputMode(cfg)
+	if mode == model.StructuredOutputModePrompt {
+		return mode, nil
+	}
+
+	var reason string
+	switch {
+	case schema["type"] != "object":
+		reason = "tool input schemas must be objects"
+	case converseUnsupported(modelID):
+		reason = fmt.Sprintf("model %q is served through InvokeModel, which has no tools", modelID)
+	default:
+		return mode, nil
+	}
+	if mode == model.StructuredOutputModeNative {
+		return "", fmt.Errorf("native structured output is unavailable: %s", reason)
+	}
+	return model.StructuredOutputModePrompt, nil
+}
+
+// converseUnsupported reports whether modelID was found to need InvokeModel.
+func converseUnsupported(modelID string) bool {
+	_, unsupported := converseUnsupportedModels.Load(modelID)
+	return unsupported
+}
+
+// supportsSpecificToolChoice reports whether modelID accepts a tool choice
+// other than auto; on Bedrock only Anthropic Claude and Mistral Large do.
+func supportsSpecificToolChoice(modelID string) bool {
+	id := strings.ToLower(modelID)
+	return strings.Contains(id, "anthropic.claude") || strings.Contains(id, "mistral.mistral-large")
+}
+
+// structuredOutputRequest returns the messages and tool configuration of a
+// structured request. Prompt mode appends the schema to the prompt. Native
+// mode adds the structured output tool and forces it where the model allows,
+// letting the model call any tool first when others are configured; other
+// models are asked in the prompt to call it.
+func structuredOutputRequest(
+	mode model.StructuredOutputMode,
+	modelID string,
+	schema map[string]any,
+	messages []bedrocktypes.Message,
+	toolConfig *bedrocktypes.ToolConfiguration,
+) ([]bedrocktypes.Message, *bedrocktypes.ToolConfiguration, error) {
+	if mode == model.StructuredOutputModePrompt {
+		schemaJSON, err := json.Marshal(schema)
+		if err != nil {
+			return nil, nil, utils.WrapIfNotNil(err)
+		}
+		return appendToPrompt(messages, "Return ONLY valid JSON that matches this schema:\n"+string(schemaJSON)), toolConfig, nil
+	}
+
+	outputTool := &bedrocktypes.ToolMemberToolSpec{
+		Value: bedrocktypes.ToolSpecification{
+			Name:        aws.String(structuredOutputToolName),
+			Description: aws.String("Return the final answer. Call this tool exactly once with the complete result."),
+			InputSchema: &bedrocktypes.ToolInputSchemaMemberJson{
+				Value: bedrockdocument.NewLazyDocument(schema),
+			},
+		},
+	}
+	config := &bedrocktypes.ToolConfiguration{}
+	if toolConfig != nil {
+		config.Tools = append(config.Tools, toolConfig.Tools...)
+	}
+	otherTools := len(config.Tools) > 0
+	config.Tools = append(config.Tools, outputTool)
+
+	switch {
+	case !supportsSpecificToolChoice(modelID):
+		messages = appendToPrompt(messages, "Give the final answer by calling the "+structuredOutputToolName+" tool.")
+	case otherTools:
+		config.ToolChoice = &bedrocktypes.ToolChoiceMemberAny{Value: bedrocktypes.AnyToolChoice{}}
+	default:
+		config.ToolChoice = &bedrocktypes.ToolChoiceMemberTool{
+			Value: bedrocktypes.SpecificToolChoice{Name: aws.String(structuredOutputToolName)},
+		}
+	}
+	return messages, config, nil
+}
+
+// appendToPrompt returns a copy of messages with text added as a block of
+// the last (prompt) message, after any images it carries.
+func appendToPrompt(messages []bedrocktypes.Message, text string) []bedrocktypes.Message {
+	out := append([]bedrocktypes.Message(nil), messages...)
+	last := &out[len(out)-1]
+	last.Content = append(
+		append([]bedrocktypes.ContentBlock(nil), last.Content...),
+		&bedrocktypes.ContentBlockMemberText{Value: text},
 	)
+	return out
+}
+
+// structuredOutputToolInput returns the input of the structured output tool
+// call in message as JSON, if there is one.
+func structuredOutputToolInput(message bedrocktypes.Message) (string, bool, error) {
+	for _, toolUse := range extractToolUses(message) {
+		if aws.ToString(toolUse.Name) != structuredOutputToolName {
+			continue
+		}
+		input, err := toolUse.Input.MarshalSmithyDocument()
+		if err != nil {
+			return "", true, utils.WrapIfNotNil(err)
+		}
+		return strings.TrimSpace(string(input)), true, nil
+	}
+	return "", false, nil
 }
 
 func extractOutputMessage(output bedrocktypes.ConverseOutput) (bedrocktypes.Message, error) {
@@ -540,25 +909,21 @@ func extractJSONPayload(text string) string {
 	return trimmed
 }
 
-func generateSchema[T any]() (map[string]any, error) {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
-
-	var value T
-	schema := reflector.Reflect(value)
-
-	schemaJSON, err := json.Marshal(schema)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+// mapImageContentBlocks builds image blocks followed by the text. Converse
+// takes image bytes only, so URL images are rejected; pass Data or a data URL.
+func mapImageContentBlocks(content string, images []model.ImagePart) ([]bedrocktypes.ContentBlock, error) {
+	blocks := make([]bedrocktypes.ContentBlock, 0, len(images)+1)
+	for _, image := range images {
+		if len(image.Data) == 0 {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("image url %q is not supported for bedrock provider; pass image bytes", image.URL))
+		}
+		blocks = append(blocks, &bedrocktypes.ContentBlockMemberImage{Value: bedrocktypes.ImageBlock{
+			Format: bedrocktypes.ImageFormat(strings.TrimPrefix(image.MIMEType, "image/")),
+			Source: &bedrocktypes.ImageSourceMemberBytes{Value: image.Data},
+		}})
 	}
-
-	var schemaMap map[string]any
-	err = json.Unmarshal(schemaJSON, &schemaMap)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+	if content != "" {
+		blocks = append(blocks, &bedrocktypes.ContentBlockMemberText{Value: content})
 	}
-
-	return schemaMap, nil
+	return blocks, nil
 }
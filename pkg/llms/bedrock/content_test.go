@@ -0,0 +1,373 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockdocument "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// StructuredOutputSuite checks how the structured generator asks Bedrock for
+// JSON: through the structured output tool, forced or asked for in the
+// prompt, or through schema instructions when tools are unavailable.
+type StructuredOutputSuite struct {
+	suite.Suite
+}
+
+func TestStructuredOutputSuite(t *testing.T) {
+	suite.Run(t, new(StructuredOutputSuite))
+}
+
+func (s *StructuredOutputSuite) SetupSuite() {
+	setFakeAWSCredentials(s.T())
+}
+
+type stagingResult struct {
+	Stage string `json:"stage"`
+	EGFR  int    `json:"egfr"`
+}
+
+var stagingSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"stage": map[string]any{"type": "string"},
+		"egfr":  map[string]any{"type": "integer"},
+	},
+}
+
+func stagingPrompt() []bedrocktypes.Message {
+	return []bedrocktypes.Message{{
+		Role:    bedrocktypes.ConversationRoleUser,
+		Content: []bedrocktypes.ContentBlock{&bedrocktypes.ContentBlockMemberText{Value: "Stage this patient."}},
+	}}
+}
+
+func lookupToolConfig() *bedrocktypes.ToolConfiguration {
+	return &bedrocktypes.ToolConfiguration{Tools: []bedrocktypes.Tool{
+		&bedrocktypes.ToolMemberToolSpec{Value: bedrocktypes.ToolSpecification{Name: aws.String("lookup")}},
+	}}
+}
+
+// markConverseUnsupported records modelID as InvokeModel-only for one test.
+func (s *StructuredOutputSuite) markConverseUnsupported(modelID string) {
+	converseUnsupportedModels.Store(modelID, struct{}{})
+	s.T().Cleanup(func() { converseUnsupportedModels.Delete(modelID) })
+}
+
+func (s *StructuredOutputSuite) TestResolveStructuredOutputMode() {
+	invokeOnly := "meta.llama3-8b-instruct-v1:0-resolve-test"
+	s.markConverseUnsupported(invokeOnly)
+	arraySchema := map[string]any{"type": "array"}
+
+	cases := []struct {
+		name    string
+		mode    *model.StructuredOutputMode
+		modelID string
+		schema  map[string]any
+		want    model.StructuredOutputMode
+		wantErr string
+	}{
+		{name: "auto keeps the tool", modelID: defaultModelName, schema: stagingSchema, want: model.StructuredOutputModeAuto},
+		{name: "native keeps the tool", mode: ptr(model.StructuredOutputModeNative), modelID: defaultModelName, schema: stagingSchema, want: model.StructuredOutputModeNative},
+		{name: "prompt", mode: ptr(model.StructuredOutputModePrompt), modelID: invokeOnly, schema: arraySchema, want: model.StructuredOutputModePrompt},
+		{name: "auto with array schema", modelID: defaultModelName, schema: arraySchema, want: model.StructuredOutputModePrompt},
+		{name: "native with array schema", mode: ptr(model.StructuredOutputModeNative), modelID: defaultModelName, schema: arraySchema, wantErr: "tool input schemas must be objects"},
+		{name: "auto on InvokeModel", modelID: invokeOnly, schema: stagingSchema, want: model.StructuredOutputModePrompt},
+		{name: "native on InvokeModel", mode: ptr(model.StructuredOutputModeNative), modelID: invokeOnly, schema: stagingSchema, wantErr: "served through InvokeModel"},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			cfg := model.GeneratorConfig{StructuredOutputMode: tc.mode}
+			mode, err := resolveStructuredOutputMode(cfg, tc.modelID, tc.schema)
+			if tc.wantErr != "" {
+				s.ErrorContains(err, tc.wantErr)
+				return
+			}
+			s.Require().NoError(err)
+			s.Equal(tc.want, mode)
+		})
+	}
+}
+
+func (s *StructuredOutputSuite) TestStructuredOutputRequest() {
+	askInPrompt := "Give the final answer by calling the structured_output tool."
+	cases := []struct {
+		name       string
+		modelID    string
+		toolConfig *bedrocktypes.ToolConfiguration
+		wantTools  []string
+		wantChoice bedrocktypes.ToolChoice
+		wantPrompt []string
+	}{
+		{
+			name:       "claude is forced to the output tool",
+			modelID:    "us.anthropic.claude-3-5-haiku-20241022-v1:0",
+			wantTools:  []string{structuredOutputToolName},
+			wantChoice: &bedrocktypes.ToolChoiceMemberTool{Value: bedrocktypes.SpecificToolChoice{Name: aws.String(structuredOutputToolName)}},
+			wantPrompt: []string{"Stage this patient."},
+		},
+		{
+			name:       "mistral large is forced to the output tool",
+			modelID:    "mistral.mistral-large-2407-v1:0",
+			wantTools:  []string{structuredOutputToolName},
+			wantChoice: &bedrocktypes.ToolChoiceMemberTool{Value: bedrocktypes.SpecificToolChoice{Name: aws.String(structuredOutputToolName)}},
+			wantPrompt: []string{"Stage this patient."},
+		},
+		{
+			name:       "claude with other tools must call some tool",
+			modelID:    "us.anthropic.claude-3-5-haiku-20241022-v1:0",
+			toolConfig: lookupToolConfig(),
+			wantTools:  []string{"lookup", structuredOutputToolName},
+			wantChoice: &bedrocktypes.ToolChoiceMemberAny{Value: bedrocktypes.AnyToolChoice{}},
+			wantPrompt: []string{"Stage this patient."},
+		},
+		{
+			name:       "other models are asked in the prompt",
+			modelID:    "amazon.nova-pro-v1:0",
+			wantTools:  []string{structuredOutputToolName},
+			wantPrompt: []string{"Stage this patient.", askInPrompt},
+		},
+		{
+			name:       "other models with other tools are asked in the prompt",
+			modelID:    "meta.llama3-1-70b-instruct-v1:0",
+			toolConfig: lookupToolConfig(),
+			wantTools:  []string{"lookup", structuredOutputToolName},
+			wantPrompt: []string{"Stage this patient.", askInPrompt},
+		},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			prompt := stagingPrompt()
+			messages, config, err := structuredOutputRequest(model.StructuredOutputModeAuto, tc.modelID, stagingSchema, prompt, tc.toolConfig)
+			s.Require().NoError(err)
+
+			s.Require().NotNil(config)
+			names := make([]string, 0, len(config.Tools))
+			for _, tool := range config.Tools {
+				spec, ok := tool.(*bedrocktypes.ToolMemberToolSpec)
+				s.Require().True(ok)
+				names = append(names, aws.ToString(spec.Value.Name))
+			}
+			s.Equal(tc.wantTools, names)
+			s.Equal(tc.wantChoice, config.ToolChoice)
+			s.Equal(tc.wantPrompt, messageTexts(messages[len(messages)-1]))
+			s.Len(prompt[0].Content, 1, "the caller's messages must not be modified")
+			if tc.toolConfig != nil {
+				s.Len(tc.toolConfig.Tools, 1, "the caller's tool configuration must not be modified")
+			}
+		})
+	}
+}
+
+func (s *StructuredOutputSuite) TestStructuredOutputRequestInPromptMode() {
+	toolConfig := lookupToolConfig()
+	messages, config, err := structuredOutputRequest(model.StructuredOutputModePrompt, defaultModelName, stagingSchema, stagingPrompt(), toolConfig)
+	s.Require().NoError(err)
+
+	s.Same(toolConfig, config, "prompt mode keeps the configured tools as they are")
+	texts := messageTexts(messages[0])
+	s.Require().Len(texts, 2)
+	s.Equal("Stage this patient.", texts[0])
+	s.True(strings.HasPrefix(texts[1], "Return ONLY valid JSON that matches this schema:\n"))
+	s.Contains(texts[1], `"egfr":{"type":"integer"}`)
+}
+
+func (s *StructuredOutputSuite) TestStructuredOutputToolInput() {
+	toolUse := func(name string, input any) bedrocktypes.ContentBlock {
+		return &bedrocktypes.ContentBlockMemberToolUse{Value: bedrocktypes.ToolUseBlock{
+			ToolUseId: aws.String("tool_1"),
+			Name:      aws.String(name),
+			Input:     bedrockdocument.NewLazyDocument(input),
+		}}
+	}
+	cases := []struct {
+		name      string
+		content   []bedrocktypes.ContentBlock
+		wantInput string
+		wantFound bool
+	}{
+		{
+			name: "output tool after text",
+			content: []bedrocktypes.ContentBlock{
+				&bedrocktypes.ContentBlockMemberText{Value: "Here is the result."},
+				toolUse(structuredOutputToolName, map[string]any{"stage": "3a", "egfr": 52}),
+			},
+			wantInput: `{"egfr":52,"stage":"3a"}`,
+			wantFound: true,
+		},
+		{
+			name:    "other tool only",
+			content: []bedrocktypes.ContentBlock{toolUse("lookup", map[string]any{"id": 1})},
+		},
+		{
+			name:    "text only",
+			content: []bedrocktypes.ContentBlock{&bedrocktypes.ContentBlockMemberText{Value: `{"stage":"3a"}`}},
+		},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			input, found, err := structuredOutputToolInput(bedrocktypes.Message{Role: bedrocktypes.ConversationRoleAssistant, Content: tc.content})
+			s.Require().NoError(err)
+			s.Equal(tc.wantFound, found)
+			if tc.wantFound {
+				s.JSONEq(tc.wantInput, input)
+				return
+			}
+			s.Empty(input)
+		})
+	}
+}
+
+// structuredFakeServer answers Converse with scripted bodies, repeating the
+// last one, and InvokeModel with invokeReply; Converse is rejected for
+// models in rejectConverse.
+type structuredFakeServer struct {
+	mu              sync.Mutex
+	converseReplies []string
+	rejectConverse  bool
+	invokeReply     string
+	paths           []string
+	bodies          []map[string]any
+}
+
+func (f *structuredFakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.paths = append(f.paths, r.URL.Path)
+	f.bodies = append(f.bodies, body)
+
+	w.Header().Set("content-type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/invoke"):
+		_, _ = w.Write([]byte(f.invokeReply))
+	case f.rejectConverse:
+		w.Header().Set("X-Amzn-ErrorType", "ValidationException")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"This action doesn't support the model that you provided."}`))
+	default:
+		converseCalls := 0
+		for _, path := range f.paths {
+			if strings.HasSuffix(path, "/converse") {
+				converseCalls++
+			}
+		}
+		reply := f.converseReplies[min(converseCalls, len(f.converseReplies))-1]
+		_, _ = w.Write([]byte(reply))
+	}
+}
+
+func converseToolUseReply(name string, input string) string {
+	return `{"output":{"message":{"role":"assistant","content":[{"toolUse":{"toolUseId":"tool_` + name + `","name":"` + name + `","input":` + input + `}}]}},` +
+		`"stopReason":"tool_use","usage":{"inputTokens":10,"outputTokens":5,"totalTokens":15}}`
+}
+
+func (s *StructuredOutputSuite) generate(fake *structuredFakeServer, modelID string, opts ...model.GeneratorOption) (stagingResult, model.GenerationMetadata, error) {
+	server := httptest.NewServer(fake)
+	s.T().Cleanup(server.Close)
+
+	opts = append([]model.GeneratorOption{model.WithURL(server.URL), model.WithModel(modelID)}, opts...)
+	gen, err := NewStructureContentGenerator[stagingResult]("Stage this patient.", opts...)
+	s.Require().NoError(err)
+	return gen.Generate(context.Background())
+}
+
+func (s *StructuredOutputSuite) TestForcedOutputToolIsDecoded() {
+	fake := &structuredFakeServer{converseReplies: []string{converseToolUseReply(structuredOutputToolName, `{"stage":"3a","egfr":52}`)}}
+
+	out, meta, err := s.generate(fake, "us.anthropic.claude-3-5-haiku-20241022-v1:0")
+	s.Require().NoError(err)
+	s.Equal(stagingResult{Stage: "3a", EGFR: 52}, out)
+	s.Equal(string(model.StructuredOutputModeNative), meta[model.MetadataKeyStructuredOutputMode])
+	s.Equal("0", meta[model.MetadataKeyToolRounds])
+
+	s.Require().Len(fake.bodies, 1)
+	toolConfig := fake.bodies[0]["toolConfig"].(map[string]any)
+	s.Equal(map[string]any{"tool": map[string]any{"name": structuredOutputToolName}}, toolConfig["toolChoice"])
+}
+
+func (s *StructuredOutputSuite) TestPromptAskedModelRunsOtherToolsFirst() {
+	fake := &structuredFakeServer{converseReplies: []string{
+		converseToolUseReply("lookup", `{"id":7}`),
+		converseToolUseReply(structuredOutputToolName, `{"stage":"3b","egfr":38}`),
+	}}
+	var calls []string
+	tool := model.Tool{Name: "lookup", Description: "Look up a patient.", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		calls = append(calls, string(args))
+		return map[string]any{"creatinine": 1.7}, nil
+	}}
+
+	out, meta, err := s.generate(fake, "amazon.nova-pro-v1:0", model.WithTools([]model.Tool{tool}))
+	s.Require().NoError(err)
+	s.Equal(stagingResult{Stage: "3b", EGFR: 38}, out)
+	s.Equal([]string{`{"id":7}`}, calls)
+	s.Equal(string(model.StructuredOutputModeNative), meta[model.MetadataKeyStructuredOutputMode])
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+
+	s.Require().Len(fake.bodies, 2)
+	for _, body := range fake.bodies {
+		toolConfig := body["toolConfig"].(map[string]any)
+		s.NotContains(toolConfig, "toolChoice", "this model only accepts the auto tool choice")
+		s.Len(toolConfig["tools"], 2)
+	}
+	first, err := json.Marshal(fake.bodies[0]["messages"])
+	s.Require().NoError(err)
+	s.Contains(string(first), "Give the final answer by calling the structured_output tool.")
+}
+
+func (s *StructuredOutputSuite) TestAutoModeFallsBackToPromptOnInvokeModel() {
+	modelID := "meta.llama3-8b-instruct-v1:0-structured-test"
+	s.T().Cleanup(func() { converseUnsupportedModels.Delete(modelID) })
+	fake := &structuredFakeServer{
+		rejectConverse: true,
+		invokeReply:    `{"generation":"{\"stage\":\"2\",\"egfr\":75}","prompt_token_count":30,"generation_token_count":9,"stop_reason":"stop"}`,
+	}
+
+	out, meta, err := s.generate(fake, modelID)
+	s.Require().NoError(err)
+	s.Equal(stagingResult{Stage: "2", EGFR: 75}, out)
+	s.Equal(string(model.StructuredOutputModePrompt), meta[model.MetadataKeyStructuredOutputMode])
+
+	// Converse is rejected once; the tool request cannot go through
+	// InvokeModel, so only the prompt-mode retry reaches it.
+	s.Equal([]string{"/model/" + modelID + "/converse", "/model/" + modelID + "/invoke"}, fake.paths)
+	s.Contains(fake.bodies[1]["prompt"], "Return ONLY valid JSON that matches this schema:")
+	s.NotContains(fake.bodies[1]["prompt"], structuredOutputToolName)
+}
+
+func (s *StructuredOutputSuite) TestNativeModeFailsOnInvokeModel() {
+	modelID := "meta.llama3-8b-instruct-v1:0-native-test"
+	s.T().Cleanup(func() { converseUnsupportedModels.Delete(modelID) })
+	fake := &structuredFakeServer{rejectConverse: true}
+
+	_, _, err := s.generate(fake, modelID, model.WithStructuredOutputMode(model.StructuredOutputModeNative))
+	s.ErrorContains(err, "tools are not available through InvokeModel")
+	s.Equal([]string{"/model/" + modelID + "/converse"}, fake.paths)
+}
+
+func messageTexts(message bedrocktypes.Message) []string {
+	texts := make([]string, 0, len(message.Content))
+	for _, block := range message.Content {
+		if text, ok := block.(*bedrocktypes.ContentBlockMemberText); ok {
+			texts = append(texts, text.Value)
+		}
+	}
+	return texts
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
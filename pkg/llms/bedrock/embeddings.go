@@ -0,0 +1,274 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+const (
+	defaultEmbeddingModelName = "amazon.titan-embed-text-v2:0"
+	// cohereMaxBatchSize is the most texts Cohere embed models accept per call.
+	cohereMaxBatchSize = 96
+	// cohereDefaultInputType marks inputs as documents to be stored; Cohere
+	// requires an input type on every request.
+	cohereDefaultInputType = "search_document"
+)
+
+type embeddingGenerator struct {
+	cfg model.GeneratorConfig
+}
+
+type titanEmbeddingRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions *int   `json:"dimensions,omitempty"`
+	Normalize  *bool  `json:"normalize,omitempty"`
+}
+
+type titanEmbeddingResponse struct {
+	Embedding           []float64 `json:"embedding"`
+	InputTextTokenCount int64     `json:"inputTextTokenCount"`
+}
+
+type cohereEmbeddingRequest struct {
+	Texts           []string `json:"texts"`
+	InputType       string   `json:"input_type"`
+	EmbeddingTypes  []string `json:"embedding_types"`
+	OutputDimension *int     `json:"output_dimension,omitempty"`
+}
+
+type cohereEmbeddingResponse struct {
+	Embeddings struct {
+		Float [][]float64 `json:"float"`
+	} `json:"embeddings"`
+}
+
+// NewEmbeddingGenerator embeds with Titan or Cohere models through
+// InvokeModel, using the same AWS credentials as content generation. The
+// model defaults to amazon.titan-embed-text-v2:0; model IDs containing
+// "cohere.embed" use the Cohere request format. WithEmbeddingDimensions is
+// supported by Titan v2 and Cohere v4.
+func NewEmbeddingGenerator(opts ...model.GeneratorOption) (model.EmbeddingGenerator, error) {
+	cfg := model.ResolveGeneratorOpts(opts...)
+	return &embeddingGenerator{
+		cfg: cfg,
+	}, nil
+}
+
+func (g *embeddingGenerator) Generate(
+	ctx context.Context,
+	input string,
+) (model.EmbeddingVector, model.GenerationMetadata, error) {
+	vectors, meta, err := g.GenerateBatch(ctx, []string{input})
+	if err != nil {
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+	if len(vectors) != 1 {
+		return nil, meta, utils.WrapIfNotNil(fmt.Errorf("expected exactly 1 embedding vector, got %d", len(vectors)))
+	}
+	return vectors[0], meta, nil
+}
+
+// GenerateBatch sends Cohere inputs in chunks of cohereMaxBatchSize. Titan
+// embeds one input per call, so a batch costs one call per input.
+func (g *embeddingGenerator) GenerateBatch(
+	ctx context.Context,
+	inputs []string,
+) (model.EmbeddingVectors, model.GenerationMetadata, error) {
+	start := time.Now()
+	modelName := resolveEmbeddingModelName(g.cfg)
+	meta := initMetadata(modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log := logging.NewLogger(ctx)
+	err := validateEmbeddingInputs(inputs)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+
+	dimensions, err := model.ResolveEmbeddingDimensions(g.cfg, providerName, embeddingModelFamily(modelName), log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+
+	client, err := newClient(ctx, g.cfg)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+
+	log.Infof(
+		"embedding_request inputs=%d model=%q dimensions=%v",
+		len(inputs),
+		modelName,
+		dimensions,
+	)
+
+	var (
+		vectors     model.EmbeddingVectors
+		apiCalls    int
+		inputTokens int64
+	)
+	if isCohereEmbeddingModel(modelName) {
+		vectors, apiCalls, err = embedWithCohere(ctx, client, modelName, inputs, dimensions)
+	} else {
+		vectors, apiCalls, inputTokens, err = embedWithTitan(ctx, client, modelName, inputs, dimensions)
+	}
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+
+	meta[model.MetadataKeyEmbeddingCount] = strconv.Itoa(len(vectors))
+	if len(vectors) > 0 {
+		meta[model.MetadataKeyEmbeddingDims] = strconv.Itoa(len(vectors[0]))
+	}
+	meta[model.MetadataKeyAPICalls] = strconv.Itoa(apiCalls)
+	if inputTokens > 0 {
+		meta[model.MetadataKeyInputTokens] = strconv.FormatInt(inputTokens, 10)
+		meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(inputTokens, 10)
+	}
+	meta[model.MetadataKeyOutputTokens] = "0"
+	return vectors, meta, nil
+}
+
+func embedWithTitan(
+	ctx context.Context,
+	client *bedrockruntime.Client,
+	modelName string,
+	inputs []string,
+	dimensions *int,
+) (model.EmbeddingVectors, int, int64, error) {
+	request := titanEmbeddingRequest{Dimensions: dimensions}
+	if supportsEmbeddingDimensions(modelName) {
+		// Titan v2 only; v1 rejects the field.
+		request.Normalize = aws.Bool(true)
+	}
+
+	vectors := make(model.EmbeddingVectors, 0, len(inputs))
+	var inputTokens int64
+	for i, input := range inputs {
+		request.InputText = input
+		response := titanEmbeddingResponse{}
+		if err := invokeEmbeddingModel(ctx, client, modelName, request, &response); err != nil {
+			return nil, 0, 0, utils.WrapIfNotNil(err)
+		}
+		if len(response.Embedding) == 0 {
+			return nil, 0, 0, utils.WrapIfNotNil(fmt.Errorf("missing embedding at index %d", i))
+		}
+		vectors = append(vectors, response.Embedding)
+		inputTokens += response.InputTextTokenCount
+	}
+	return vectors, len(inputs), inputTokens, nil
+}
+
+func embedWithCohere(
+	ctx context.Context,
+	client *bedrockruntime.Client,
+	modelName string,
+	inputs []string,
+	dimensions *int,
+) (model.EmbeddingVectors, int, error) {
+	vectors := make(model.EmbeddingVectors, 0, len(inputs))
+	apiCalls := 0
+	for batchStart := 0; batchStart < len(inputs); batchStart += cohereMaxBatchSize {
+		batchEnd := min(batchStart+cohereMaxBatchSize, len(inputs))
+		response := cohereEmbeddingResponse{}
+		err := invokeEmbeddingModel(ctx, client, modelName, cohereEmbeddingRequest{
+			Texts:           inputs[batchStart:batchEnd],
+			InputType:       cohereDefaultInputType,
+			EmbeddingTypes:  []string{"float"},
+			OutputDimension: dimensions,
+		}, &response)
+		if err != nil {
+			return nil, 0, utils.WrapIfNotNil(err)
+		}
+		if len(response.Embeddings.Float) != batchEnd-batchStart {
+			return nil, 0, utils.WrapIfNotNil(
+				fmt.Errorf("embedding response size mismatch: expected %d, got %d", batchEnd-batchStart, len(response.Embeddings.Float)),
+			)
+		}
+		vectors = append(vectors, response.Embeddings.Float...)
+		apiCalls++
+	}
+	return vectors, apiCalls, nil
+}
+
+func invokeEmbeddingModel(
+	ctx context.Context,
+	client *bedrockruntime.Client,
+	modelName string,
+	request any,
+	out any,
+) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	output, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelName),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	return utils.WrapIfNotNil(json.Unmarshal(output.Body, out))
+}
+
+func resolveEmbeddingModelName(cfg model.GeneratorConfig) string {
+	if cfg.Model != nil {
+		modelName := strings.TrimSpace(*cfg.Model)
+		if modelName != "" {
+			return modelName
+		}
+	}
+	return defaultEmbeddingModelName
+}
+
+// isCohereEmbeddingModel also matches cross-region inference profile IDs
+// such as "us.cohere.embed-v4:0".
+func isCohereEmbeddingModel(modelName string) bool {
+	return strings.Contains(strings.ToLower(modelName), "cohere.embed")
+}
+
+func supportsEmbeddingDimensions(modelName string) bool {
+	modelName = strings.ToLower(modelName)
+	return strings.Contains(modelName, "titan-embed-text-v2") || strings.Contains(modelName, "cohere.embed-v4")
+}
+
+// embeddingModelFamily strips a cross-region inference prefix, as in
+// "us.cohere.embed-v4:0", so the model matches its registered dimensions.
+func embeddingModelFamily(modelName string) string {
+	if strings.Count(modelName, ".") > 1 {
+		if _, rest, ok := strings.Cut(modelName, "."); ok {
+			return rest
+		}
+	}
+	return modelName
+}
+
+func validateEmbeddingInputs(inputs []string) error {
+	if len(inputs) == 0 {
+		return utils.WrapIfNotNil(errors.New("at least one input is required"))
+	}
+	for i, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			return utils.WrapIfNotNil(fmt.Errorf("input at index %d is empty", i))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbeddingsSuite struct {
+	suite.Suite
+}
+
+func TestEmbeddingsSuite(t *testing.T) {
+	suite.Run(t, new(EmbeddingsSuite))
+}
+
+func (s *EmbeddingsSuite) SetupSuite() {
+	setFakeAWSCredentials(s.T())
+}
+
+// embeddingServer answers InvokeModel with reply(body) and keeps every
+// request path and raw body.
+type embeddingServer struct {
+	mu     sync.Mutex
+	reply  func(body map[string]any) any
+	paths  []string
+	bodies []string
+}
+
+func (f *embeddingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var raw json.RawMessage
+	_ = json.NewDecoder(r.Body).Decode(&raw)
+	f.paths = append(f.paths, r.URL.Path)
+	f.bodies = append(f.bodies, string(raw))
+
+	var body map[string]any
+	_ = json.Unmarshal(raw, &body)
+	w.Header().Set("content-type", "application/json")
+	_ = json.NewEncoder(w).Encode(f.reply(body))
+}
+
+// embeddingReply answers Titan with a two-value vector and three input
+// tokens, and Cohere with one such vector per text.
+func embeddingReply(body map[string]any) any {
+	texts, ok := body["texts"].([]any)
+	if !ok {
+		return map[string]any{"embedding": []float64{0.1, 0.2}, "inputTextTokenCount": 3}
+	}
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = []float64{float64(i), 0.5}
+	}
+	return map[string]any{"id": "emb_1", "embeddings": map[string]any{"float": vectors}, "response_type": "embeddings_by_type"}
+}
+
+func (s *EmbeddingsSuite) embed(fake *embeddingServer, inputs []string, opts ...model.GeneratorOption) (model.EmbeddingVectors, model.GenerationMetadata, error) {
+	server := httptest.NewServer(fake)
+	s.T().Cleanup(server.Close)
+
+	opts = append([]model.GeneratorOption{model.WithURL(server.URL)}, opts...)
+	gen, err := NewEmbeddingGenerator(opts...)
+	s.Require().NoError(err)
+	return gen.GenerateBatch(context.Background(), inputs)
+}
+
+func (s *EmbeddingsSuite) TestRequestBodyPerModel() {
+	cases := []struct {
+		name     string
+		opts     []model.GeneratorOption
+		wantPath string
+		wantBody string
+	}{
+		{
+			name:     "titan v2 by default",
+			wantPath: "/model/amazon.titan-embed-text-v2:0/invoke",
+			wantBody: `{"inputText":"eGFR 52","normalize":true}`,
+		},
+		{
+			name:     "titan v2 with dimensions",
+			opts:     []model.GeneratorOption{model.WithEmbeddingDimensions(512)},
+			wantPath: "/model/amazon.titan-embed-text-v2:0/invoke",
+			wantBody: `{"inputText":"eGFR 52","dimensions":512,"normalize":true}`,
+		},
+		{
+			name:     "titan v1 has no normalize field",
+			opts:     []model.GeneratorOption{model.WithModel("amazon.titan-embed-text-v1")},
+			wantPath: "/model/amazon.titan-embed-text-v1/invoke",
+			wantBody: `{"inputText":"eGFR 52"}`,
+		},
+		{
+			name:     "cohere v3",
+			opts:     []model.GeneratorOption{model.WithModel("cohere.embed-english-v3")},
+			wantPath: "/model/cohere.embed-english-v3/invoke",
+			wantBody: `{"texts":["eGFR 52"],"input_type":"search_document","embedding_types":["float"]}`,
+		},
+		{
+			name:     "cohere v4 inference profile with dimensions",
+			opts:     []model.GeneratorOption{model.WithModel("us.cohere.embed-v4:0"), model.WithEmbeddingDimensions(1024)},
+			wantPath: "/model/us.cohere.embed-v4:0/invoke",
+			wantBody: `{"texts":["eGFR 52"],"input_type":"search_document","embedding_types":["float"],"output_dimension":1024}`,
+		},
+	}
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			fake := &embeddingServer{reply: embeddingReply}
+			_, _, err := s.embed(fake, []string{"eGFR 52"}, tc.opts...)
+			s.Require().NoError(err)
+			s.Equal([]string{tc.wantPath}, fake.paths)
+			s.Require().Len(fake.bodies, 1)
+			s.JSONEq(tc.wantBody, fake.bodies[0])
+		})
+	}
+}
+
+func (s *EmbeddingsSuite) TestTitanEmbedsOneInputPerCall() {
+	fake := &embeddingServer{reply: embeddingReply}
+	vectors, meta, err := s.embed(fake, []string{"creatinine 1.7", "potassium 5.1"})
+	s.Require().NoError(err)
+
+	s.Equal(model.EmbeddingVectors{{0.1, 0.2}, {0.1, 0.2}}, vectors)
+	s.Require().Len(fake.bodies, 2)
+	s.JSONEq(`{"inputText":"creatinine 1.7","normalize":true}`, fake.bodies[0])
+	s.JSONEq(`{"inputText":"potassium 5.1","normalize":true}`, fake.bodies[1])
+
+	s.Equal(providerName, meta[model.MetadataKeyProvider])
+	s.Equal(defaultEmbeddingModelName, meta[model.MetadataKeyModel])
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal("2", meta[model.MetadataKeyEmbeddingCount])
+	s.Equal("2", meta[model.MetadataKeyEmbeddingDims])
+	s.Equal("6", meta[model.MetadataKeyInputTokens])
+	s.Equal("6", meta[model.MetadataKeyTotalTokens])
+	s.Equal("0", meta[model.MetadataKeyOutputTokens])
+}
+
+func (s *EmbeddingsSuite) TestCohereBatchesInputs() {
+	inputs := make([]string, cohereMaxBatchSize+1)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("note %d", i)
+	}
+	fake := &embeddingServer{reply: embeddingReply}
+	vectors, meta, err := s.embed(fake, inputs, model.WithModel("cohere.embed-multilingual-v3"))
+	s.Require().NoError(err)
+
+	s.Require().Len(vectors, len(inputs))
+	s.Equal([]float64{float64(cohereMaxBatchSize - 1), 0.5}, vectors[cohereMaxBatchSize-1])
+	s.Equal([]float64{0, 0.5}, vectors[cohereMaxBatchSize], "the second batch starts again at index 0")
+
+	s.Require().Len(fake.bodies, 2)
+	var second cohereEmbeddingRequest
+	s.Require().NoError(json.Unmarshal([]byte(fake.bodies[1]), &second))
+	s.Equal([]string{inputs[cohereMaxBatchSize]}, second.Texts)
+
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal(fmt.Sprint(len(inputs)), meta[model.MetadataKeyEmbeddingCount])
+	s.NotContains(meta, model.MetadataKeyInputTokens, "Cohere reports no token counts")
+}
+
+func (s *EmbeddingsSuite) TestMalformedResponsesAreRejected() {
+	fake := &embeddingServer{reply: func(map[string]any) any {
+		return map[string]any{"embedding": []float64{}, "inputTextTokenCount": 1}
+	}}
+	_, _, err := s.embed(fake, []string{"eGFR 52"})
+	s.ErrorContains(err, "missing embedding at index 0")
+
+	fake = &embeddingServer{reply: func(map[string]any) any {
+		return map[string]any{"embeddings": map[string]any{"float": [][]float64{{0.1}}}}
+	}}
+	_, _, err = s.embed(fake, []string{"eGFR 52", "eGFR 38"}, model.WithModel("cohere.embed-english-v3"))
+	s.ErrorContains(err, "embedding response size mismatch: expected 2, got 1")
+}
+
+func (s *EmbeddingsSuite) TestInvalidRequestsAreRejectedBeforeCalling() {
+	cases := []struct {
+		name    string
+		inputs  []string
+		opts    []model.GeneratorOption
+		wantErr string
+	}{
+		{name: "no inputs", wantErr: "at least one input is required"},
+		{name: "blank input", inputs: []string{"eGFR 52", " "}, wantErr: "input at index 1 is empty"},
+		{
+			name:    "dimensions on titan v1",
+			inputs:  []string{"eGFR 52"},
+			opts:    []model.GeneratorOption{model.WithModel("amazon.titan-embed-text-v1"), model.WithEmbeddingDimensions(512)},
+			wantErr: "embedding dimensions are not supported",
+		},
+		{
+			name:    "unsupported cohere v4 size",
+			inputs:  []string{"eGFR 52"},
+			opts:    []model.GeneratorOption{model.WithModel("us.cohere.embed-v4:0"), model.WithEmbeddingDimensions(300)},
+			wantErr: "embedding dimensions 300 are not supported",
+		},
+	}
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			fake := &embeddingServer{reply: embeddingReply}
+			_, _, err := s.embed(fake, tc.inputs, tc.opts...)
+			s.ErrorContains(err, tc.wantErr)
+			s.Empty(fake.paths)
+		})
+	}
+}
+
+func (s *EmbeddingsSuite) TestEmbeddingModelHelpers() {
+	cases := []struct {
+		modelName      string
+		wantCohere     bool
+		wantDimensions bool
+		wantFamily     string
+	}{
+		{modelName: "amazon.titan-embed-text-v2:0", wantDimensions: true, wantFamily: "amazon.titan-embed-text-v2:0"},
+		{modelName: "amazon.titan-embed-text-v1", wantFamily: "amazon.titan-embed-text-v1"},
+		{modelName: "cohere.embed-english-v3", wantCohere: true, wantFamily: "cohere.embed-english-v3"},
+		{modelName: "us.cohere.embed-v4:0", wantCohere: true, wantDimensions: true, wantFamily: "cohere.embed-v4:0"},
+		{modelName: "eu.Cohere.Embed-v4:0", wantCohere: true, wantDimensions: true, wantFamily: "Cohere.Embed-v4:0"},
+	}
+	for _, tc := range cases {
+		s.Run(tc.modelName, func() {
+			s.Equal(tc.wantCohere, isCohereEmbeddingModel(tc.modelName))
+			s.Equal(tc.wantDimensions, supportsEmbeddingDimensions(tc.modelName))
+			s.Equal(tc.wantFamily, embeddingModelFamily(tc.modelName))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// buildConversationHistory flattens Converse content blocks into the
+// provider-neutral format: toolUse blocks become tool calls on the assistant
+// turn and each toolResult block becomes its own tool message.
+func buildConversationHistory(
+	modelID string,
+	system []bedrocktypes.SystemContentBlock,
+	messages []bedrocktypes.Message,
+) model.ConversationHistory {
+	history := model.ConversationHistory{
+		Version:  model.ConversationHistoryVersion,
+		Provider: providerName,
+		Model:    modelID,
+		Messages: make([]model.HistoryMessage, 0, len(messages)+1),
+	}
+	systemParts := make([]string, 0, len(system))
+	for _, block := range system {
+		if text, ok := block.(*bedrocktypes.SystemContentBlockMemberText); ok {
+			systemParts = append(systemParts, text.Value)
+		}
+	}
+	if systemText := strings.Join(systemParts, "\n\n"); strings.TrimSpace(systemText) != "" {
+		history.Messages = append(history.Messages, model.HistoryMessage{Role: model.HistoryRoleSystem, Content: systemText})
+	}
+
+	toolNames := map[string]string{}
+	for _, message := range messages {
+		entry := model.HistoryMessage{Role: model.HistoryRoleUser}
+		if message.Role == bedrocktypes.ConversationRoleAssistant {
+			entry.Role = model.HistoryRoleAssistant
+		}
+		texts := make([]string, 0, len(message.Content))
+		for _, block := range message.Content {
+			switch block := block.(type) {
+			case *bedrocktypes.ContentBlockMemberText:
+				texts = append(texts, block.Value)
+			case *bedrocktypes.ContentBlockMemberToolUse:
+				id := aws.ToString(block.Value.ToolUseId)
+				name := aws.ToString(block.Value.Name)
+				toolNames[id] = name
+				var arguments json.RawMessage
+				if block.Value.Input != nil {
+					arguments, _ = block.Value.Input.MarshalSmithyDocument()
+				}
+				entry.ToolCalls = append(entry.ToolCalls, model.HistoryToolCall{ID: id, Name: name, Arguments: arguments})
+			case *bedrocktypes.ContentBlockMemberToolResult:
+				id := aws.ToString(block.Value.ToolUseId)
+				history.Messages = append(history.Messages, model.HistoryMessage{
+					Role:       model.HistoryRoleTool,
+					Content:    toolResultText(block.Value.Content),
+					ToolCallID: id,
+					ToolName:   toolNames[id],
+				})
+			}
+		}
+
+		entry.Content = strings.Join(texts, "\n")
+		if entry.Content == "" && len(entry.ToolCalls) == 0 {
+			continue
+		}
+		history.Messages = append(history.Messages, entry)
+	}
+	return history
+}
+
+// toolResultText renders toolResult content; JSON blocks are encoded and
+// text blocks are kept as they are.
+func toolResultText(content []bedrocktypes.ToolResultContentBlock) string {
+	parts := make([]string, 0, len(content))
+	for _, block := range content {
+		switch block := block.(type) {
+		case *bedrocktypes.ToolResultContentBlockMemberText:
+			parts = append(parts, block.Value)
+		case *bedrocktypes.ToolResultContentBlockMemberJson:
+			if block.Value == nil {
+				continue
+			}
+			if encoded, err := block.Value.MarshalSmithyDocument(); err == nil {
+				parts = append(parts, string(encoded))
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
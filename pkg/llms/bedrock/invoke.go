@@ -0,0 +1,506 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// invokeModelFamily selects the native InvokeModel body format of a model.
+type invokeModelFamily string
+
+const (
+	invokeFamilyAnthropic   invokeModelFamily = "anthropic"
+	invokeFamilyTitan       invokeModelFamily = "titan"
+	invokeFamilyLlama2      invokeModelFamily = "llama2"
+	invokeFamilyLlama3      invokeModelFamily = "llama3"
+	invokeFamilyMistral     invokeModelFamily = "mistral"
+	invokeFamilyCohereChat  invokeModelFamily = "cohere-chat"
+	invokeFamilyCohereText  invokeModelFamily = "cohere-text"
+	invokeFamilyAI21        invokeModelFamily = "ai21"
+	invokeFamilyUnsupported invokeModelFamily = ""
+
+	// anthropicBedrockVersion is the Messages API version Bedrock requires in
+	// Anthropic InvokeModel bodies.
+	anthropicBedrockVersion = "bedrock-2023-05-31"
+	// defaultInvokeMaxTokens is sent to models that require a token limit
+	// when WithMaxTokens is not set.
+	defaultInvokeMaxTokens = 4096
+)
+
+// converseUnsupportedModels holds model IDs whose Converse requests were
+// rejected, so later generations go straight to InvokeModel.
+var converseUnsupportedModels sync.Map
+
+type invokeTurn struct {
+	role bedrocktypes.ConversationRole
+	text string
+}
+
+type invokeModelResult struct {
+	text         string
+	stopReason   string
+	inputTokens  int64
+	outputTokens int64
+}
+
+type anthropicInvokeMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicInvokeRequest struct {
+	AnthropicVersion string                   `json:"anthropic_version"`
+	MaxTokens        int32                    `json:"max_tokens"`
+	System           string                   `json:"system,omitempty"`
+	Messages         []anthropicInvokeMessage `json:"messages"`
+	Temperature      *float32                 `json:"temperature,omitempty"`
+}
+
+type anthropicInvokeResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type titanTextRequest struct {
+	InputText            string `json:"inputText"`
+	TextGenerationConfig struct {
+		MaxTokenCount *int32   `json:"maxTokenCount,omitempty"`
+		Temperature   *float32 `json:"temperature,omitempty"`
+	} `json:"textGenerationConfig"`
+}
+
+type titanTextResponse struct {
+	InputTextTokenCount int64 `json:"inputTextTokenCount"`
+	Results             []struct {
+		TokenCount       int64  `json:"tokenCount"`
+		OutputText       string `json:"outputText"`
+		CompletionReason string `json:"completionReason"`
+	} `json:"results"`
+}
+
+type llamaRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxGenLen   *int32   `json:"max_gen_len,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+type llamaResponse struct {
+	Generation           string `json:"generation"`
+	PromptTokenCount     int64  `json:"prompt_token_count"`
+	GenerationTokenCount int64  `json:"generation_token_count"`
+	StopReason           string `json:"stop_reason"`
+}
+
+type promptCompletionRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   *int32   `json:"max_tokens,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+type mistralResponse struct {
+	Outputs []struct {
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"outputs"`
+}
+
+type cohereTextResponse struct {
+	Generations []struct {
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"generations"`
+}
+
+type cohereChatTurn struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
+
+type cohereChatRequest struct {
+	Message     string           `json:"message"`
+	ChatHistory []cohereChatTurn `json:"chat_history,omitempty"`
+	Preamble    string           `json:"preamble,omitempty"`
+	MaxTokens   *int32           `json:"max_tokens,omitempty"`
+	Temperature *float32         `json:"temperature,omitempty"`
+}
+
+type cohereChatResponse struct {
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+}
+
+type ai21Request struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   *int32   `json:"maxTokens,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+type ai21Response struct {
+	Completions []struct {
+		Data struct {
+			Text string `json:"text"`
+		} `json:"data"`
+		FinishReason struct {
+			Reason string `json:"reason"`
+		} `json:"finishReason"`
+	} `json:"completions"`
+}
+
+// resolveInvokeModelFamily matches model IDs with or without a cross-region
+// inference profile prefix such as "us.".
+func resolveInvokeModelFamily(modelID string) invokeModelFamily {
+	modelID = strings.ToLower(modelID)
+	switch {
+	case strings.Contains(modelID, "anthropic.claude"):
+		return invokeFamilyAnthropic
+	case strings.Contains(modelID, "amazon.titan-text"):
+		return invokeFamilyTitan
+	case strings.Contains(modelID, "meta.llama2"):
+		return invokeFamilyLlama2
+	case strings.Contains(modelID, "meta.llama"):
+		return invokeFamilyLlama3
+	case strings.Contains(modelID, "mistral."):
+		return invokeFamilyMistral
+	case strings.Contains(modelID, "cohere.command-r"):
+		return invokeFamilyCohereChat
+	case strings.Contains(modelID, "cohere.command"):
+		return invokeFamilyCohereText
+	case strings.Contains(modelID, "ai21.j2"):
+		return invokeFamilyAI21
+	default:
+		return invokeFamilyUnsupported
+	}
+}
+
+// isConverseUnsupportedError reports whether err is Bedrock rejecting a
+// Converse request because the model or region does not offer Converse.
+func isConverseUnsupportedError(err error) bool {
+	var validationErr *bedrocktypes.ValidationException
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	message := strings.ToLower(validationErr.ErrorMessage())
+	return strings.Contains(message, "doesn't support the model") ||
+		strings.Contains(message, "does not support the model") ||
+		(strings.Contains(message, "converse") && strings.Contains(message, "support"))
+}
+
+// runInvokeModelFlow answers a Converse conversation through InvokeModel in
+// the model's native body format. It makes one call and has no tool loop,
+// so tools are rejected.
+func runInvokeModelFlow(
+	ctx context.Context,
+	client *bedrockruntime.Client,
+	modelID string,
+	system []bedrocktypes.SystemContentBlock,
+	messages []bedrocktypes.Message,
+	inference *bedrocktypes.InferenceConfiguration,
+	toolConfig *bedrocktypes.ToolConfiguration,
+	cfg model.GeneratorConfig,
+) (bedrocktypes.Message, flowUsageTotals, []bedrocktypes.Message, string, int64, error) {
+	totals := flowUsageTotals{}
+	family := resolveInvokeModelFamily(modelID)
+	if family == invokeFamilyUnsupported {
+		return bedrocktypes.Message{}, totals, messages, "", 0, utils.WrapIfNotNil(
+			fmt.Errorf("model %q does not support Converse and has no InvokeModel request format", modelID),
+		)
+	}
+	if toolConfig != nil && len(toolConfig.Tools) > 0 {
+		return bedrocktypes.Message{}, totals, messages, "", 0, utils.WrapIfNotNil(
+			fmt.Errorf("model %q does not support Converse; tools are not available through InvokeModel", modelID),
+		)
+	}
+
+	systemText, turns, err := flattenConverseMessages(system, messages)
+	if err != nil {
+		return bedrocktypes.Message{}, totals, messages, "", 0, utils.WrapIfNotNil(err)
+	}
+	request := buildInvokeModelRequest(family, systemText, turns, inference)
+	body, err := json.Marshal(request)
+	if err != nil {
+		return bedrocktypes.Message{}, totals, messages, "", 0, utils.WrapIfNotNil(err)
+	}
+	body, err = model.MergeProviderParams(body, cfg.ProviderParams)
+	if err != nil {
+		return bedrocktypes.Message{}, totals, messages, "", 0, utils.WrapIfNotNil(err)
+	}
+
+	logging.NewLogger(ctx).Debugf("bedrock invoke_model model=%q family=%s turns=%d", modelID, family, len(turns))
+	start := time.Now()
+	output, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return bedrocktypes.Message{}, totals, messages, "", 0, utils.WrapIfNotNil(err)
+	}
+	latencyMs := time.Since(start).Milliseconds()
+	totals.APICalls++
+
+	result, err := parseInvokeModelResponse(family, output.Body)
+	if err != nil {
+		return bedrocktypes.Message{}, totals, messages, "", latencyMs, utils.WrapIfNotNil(err)
+	}
+	totals.InputTokens = result.inputTokens
+	totals.OutputTokens = result.outputTokens
+	totals.TotalTokens = result.inputTokens + result.outputTokens
+
+	message := bedrocktypes.Message{
+		Role: bedrocktypes.ConversationRoleAssistant,
+		Content: []bedrocktypes.ContentBlock{
+			&bedrocktypes.ContentBlockMemberText{Value: result.text},
+		},
+	}
+	history := append(append([]bedrocktypes.Message(nil), messages...), message)
+	return message, totals, history, result.stopReason, latencyMs, nil
+}
+
+// flattenConverseMessages reduces a Converse conversation to its system text
+// and text turns; InvokeModel bodies built here carry text only.
+func flattenConverseMessages(
+	system []bedrocktypes.SystemContentBlock,
+	messages []bedrocktypes.Message,
+) (string, []invokeTurn, error) {
+	systemParts := make([]string, 0, len(system))
+	for _, block := range system {
+		if text, ok := block.(*bedrocktypes.SystemContentBlockMemberText); ok {
+			systemParts = append(systemParts, text.Value)
+		}
+	}
+
+	turns := make([]invokeTurn, 0, len(messages))
+	for _, message := range messages {
+		parts := make([]string, 0, len(message.Content))
+		for _, block := range message.Content {
+			text, ok := block.(*bedrocktypes.ContentBlockMemberText)
+			if !ok {
+				return "", nil, utils.WrapIfNotNil(errors.New("InvokeModel fallback supports text content only"))
+			}
+			parts = append(parts, text.Value)
+		}
+		turns = append(turns, invokeTurn{role: message.Role, text: strings.Join(parts, "\n")})
+	}
+	return strings.Join(systemParts, "\n\n"), turns, nil
+}
+
+func buildInvokeModelRequest(
+	family invokeModelFamily,
+	system string,
+	turns []invokeTurn,
+	inference *bedrocktypes.InferenceConfiguration,
+) any {
+	var maxTokens *int32
+	var temperature *float32
+	if inference != nil {
+		maxTokens = inference.MaxTokens
+		temperature = inference.Temperature
+	}
+
+	switch family {
+	case invokeFamilyAnthropic:
+		request := anthropicInvokeRequest{
+			AnthropicVersion: anthropicBedrockVersion,
+			MaxTokens:        defaultInvokeMaxTokens,
+			System:           system,
+			Messages:         make([]anthropicInvokeMessage, 0, len(turns)),
+			Temperature:      temperature,
+		}
+		if maxTokens != nil {
+			request.MaxTokens = *maxTokens
+		}
+		for _, turn := range turns {
+			request.Messages = append(request.Messages, anthropicInvokeMessage{Role: string(turn.role), Content: turn.text})
+		}
+		return request
+	case invokeFamilyTitan:
+		request := titanTextRequest{InputText: renderPlainTranscript(system, turns, "User", "Bot")}
+		request.TextGenerationConfig.MaxTokenCount = maxTokens
+		request.TextGenerationConfig.Temperature = temperature
+		return request
+	case invokeFamilyLlama2:
+		return llamaRequest{Prompt: renderInstTranscript(system, turns, true), MaxGenLen: maxTokens, Temperature: temperature}
+	case invokeFamilyLlama3:
+		return llamaRequest{Prompt: renderLlama3Transcript(system, turns), MaxGenLen: maxTokens, Temperature: temperature}
+	case invokeFamilyMistral:
+		return promptCompletionRequest{Prompt: renderInstTranscript(system, turns, false), MaxTokens: maxTokens, Temperature: temperature}
+	case invokeFamilyCohereChat:
+		request := cohereChatRequest{Preamble: system, MaxTokens: maxTokens, Temperature: temperature}
+		for i, turn := range turns {
+			if i == len(turns)-1 && turn.role == bedrocktypes.ConversationRoleUser {
+				request.Message = turn.text
+				break
+			}
+			role := "USER"
+			if turn.role == bedrocktypes.ConversationRoleAssistant {
+				role = "CHATBOT"
+			}
+			request.ChatHistory = append(request.ChatHistory, cohereChatTurn{Role: role, Message: turn.text})
+		}
+		return request
+	case invokeFamilyCohereText:
+		return promptCompletionRequest{Prompt: renderPlainTranscript(system, turns, "User", "Chatbot"), MaxTokens: maxTokens, Temperature: temperature}
+	default:
+		return ai21Request{Prompt: renderPlainTranscript(system, turns, "User", "Assistant"), MaxTokens: maxTokens, Temperature: temperature}
+	}
+}
+
+func parseInvokeModelResponse(family invokeModelFamily, body []byte) (invokeModelResult, error) {
+	var result invokeModelResult
+	switch family {
+	case invokeFamilyAnthropic:
+		var response anthropicInvokeResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		parts := make([]string, 0, len(response.Content))
+		for _, block := range response.Content {
+			if block.Type == "text" {
+				parts = append(parts, block.Text)
+			}
+		}
+		result = invokeModelResult{
+			text:         strings.Join(parts, "\n"),
+			stopReason:   response.StopReason,
+			inputTokens:  response.Usage.InputTokens,
+			outputTokens: response.Usage.OutputTokens,
+		}
+	case invokeFamilyTitan:
+		var response titanTextResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		result.inputTokens = response.InputTextTokenCount
+		if len(response.Results) > 0 {
+			result.text = response.Results[0].OutputText
+			result.stopReason = response.Results[0].CompletionReason
+			result.outputTokens = response.Results[0].TokenCount
+		}
+	case invokeFamilyLlama2, invokeFamilyLlama3:
+		var response llamaResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		result = invokeModelResult{
+			text:         response.Generation,
+			stopReason:   response.StopReason,
+			inputTokens:  response.PromptTokenCount,
+			outputTokens: response.GenerationTokenCount,
+		}
+	case invokeFamilyMistral:
+		var response mistralResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		if len(response.Outputs) > 0 {
+			result.text = response.Outputs[0].Text
+			result.stopReason = response.Outputs[0].StopReason
+		}
+	case invokeFamilyCohereChat:
+		var response cohereChatResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		result = invokeModelResult{text: response.Text, stopReason: response.FinishReason}
+	case invokeFamilyCohereText:
+		var response cohereTextResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		if len(response.Generations) > 0 {
+			result.text = response.Generations[0].Text
+			result.stopReason = response.Generations[0].FinishReason
+		}
+	default:
+		var response ai21Response
+		if err := json.Unmarshal(body, &response); err != nil {
+			return result, utils.WrapIfNotNil(err)
+		}
+		if len(response.Completions) > 0 {
+			result.text = response.Completions[0].Data.Text
+			result.stopReason = response.Completions[0].FinishReason.Reason
+		}
+	}
+	result.text = strings.TrimSpace(result.text)
+	return result, nil
+}
+
+// renderPlainTranscript renders turns as "Role: text" lines ending with an
+// open assistant line, for completion models without a chat template.
+func renderPlainTranscript(system string, turns []invokeTurn, userLabel string, assistantLabel string) string {
+	var prompt strings.Builder
+	if system != "" {
+		prompt.WriteString(system + "\n\n")
+	}
+	for _, turn := range turns {
+		label := userLabel
+		if turn.role == bedrocktypes.ConversationRoleAssistant {
+			label = assistantLabel
+		}
+		prompt.WriteString(label + ": " + turn.text + "\n")
+	}
+	prompt.WriteString(assistantLabel + ":")
+	return prompt.String()
+}
+
+// renderInstTranscript renders the [INST] template used by Llama 2 and
+// Mistral. Llama 2 starts every exchange with <s> and wraps the system prompt
+// in <<SYS>> tags; Mistral starts once and prefixes the system prompt to the
+// first instruction.
+func renderInstTranscript(system string, turns []invokeTurn, llama2 bool) string {
+	var prompt strings.Builder
+	pendingSystem := system
+	for i, turn := range turns {
+		if turn.role == bedrocktypes.ConversationRoleAssistant {
+			prompt.WriteString(" " + turn.text + "</s>")
+			continue
+		}
+		text := turn.text
+		if pendingSystem != "" {
+			if llama2 {
+				text = "<<SYS>>\n" + pendingSystem + "\n<</SYS>>\n\n" + text
+			} else {
+				text = pendingSystem + "\n\n" + text
+			}
+			pendingSystem = ""
+		}
+		if llama2 || i == 0 {
+			prompt.WriteString("<s>")
+		}
+		prompt.WriteString("[INST] " + text + " [/INST]")
+	}
+	return prompt.String()
+}
+
+func renderLlama3Transcript(system string, turns []invokeTurn) string {
+	var prompt strings.Builder
+	prompt.WriteString("<|begin_of_text|>")
+	if system != "" {
+		prompt.WriteString("<|start_header_id|>system<|end_header_id|>\n\n" + system + "<|eot_id|>")
+	}
+	for _, turn := range turns {
+		prompt.WriteString("<|start_header_id|>" + string(turn.role) + "<|end_header_id|>\n\n" + turn.text + "<|eot_id|>")
+	}
+	prompt.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+	return prompt.String()
+}
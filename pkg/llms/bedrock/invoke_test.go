@@ -0,0 +1,417 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// InvokeSuite checks the InvokeModel fallback: model family detection, the
+// native request and response bodies, and the switch from Converse.
+type InvokeSuite struct {
+	suite.Suite
+}
+
+func TestInvokeSuite(t *testing.T) {
+	suite.Run(t, new(InvokeSuite))
+}
+
+func (s *InvokeSuite) SetupSuite() {
+	setFakeAWSCredentials(s.T())
+}
+
+// setFakeAWSCredentials lets the client load a config for tests that talk
+// to a fake server.
+func setFakeAWSCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+}
+
+var invokeTestTurns = []invokeTurn{
+	{role: bedrocktypes.ConversationRoleUser, text: "Hi"},
+	{role: bedrocktypes.ConversationRoleAssistant, text: "Hello"},
+	{role: bedrocktypes.ConversationRoleUser, text: "eGFR?"},
+}
+
+func (s *InvokeSuite) TestResolveInvokeModelFamily() {
+	cases := []struct {
+		modelID string
+		want    invokeModelFamily
+	}{
+		{modelID: "anthropic.claude-v2:1", want: invokeFamilyAnthropic},
+		{modelID: "us.anthropic.claude-3-haiku-20240307-v1:0", want: invokeFamilyAnthropic},
+		{modelID: "Anthropic.Claude-Instant-V1", want: invokeFamilyAnthropic},
+		{modelID: "amazon.titan-text-express-v1", want: invokeFamilyTitan},
+		{modelID: "meta.llama2-70b-chat-v1", want: invokeFamilyLlama2},
+		{modelID: "us.meta.llama3-1-70b-instruct-v1:0", want: invokeFamilyLlama3},
+		{modelID: "mistral.mistral-7b-instruct-v0:2", want: invokeFamilyMistral},
+		{modelID: "cohere.command-r-plus-v1:0", want: invokeFamilyCohereChat},
+		{modelID: "cohere.command-text-v14", want: invokeFamilyCohereText},
+		{modelID: "ai21.j2-ultra-v1", want: invokeFamilyAI21},
+		{modelID: "amazon.titan-embed-text-v2:0", want: invokeFamilyUnsupported},
+		{modelID: "amazon.nova-pro-v1:0", want: invokeFamilyUnsupported},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.modelID, func() {
+			s.Equal(tc.want, resolveInvokeModelFamily(tc.modelID))
+		})
+	}
+}
+
+func (s *InvokeSuite) TestBuildInvokeModelRequest() {
+	inference := &bedrocktypes.InferenceConfiguration{MaxTokens: aws.Int32(256), Temperature: aws.Float32(0.5)}
+	cases := []struct {
+		family    invokeModelFamily
+		inference *bedrocktypes.InferenceConfiguration
+		want      string
+	}{
+		{
+			family:    invokeFamilyAnthropic,
+			inference: inference,
+			want: `{"anthropic_version":"bedrock-2023-05-31","max_tokens":256,"system":"Be brief.","temperature":0.5,
+				"messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello"},{"role":"user","content":"eGFR?"}]}`,
+		},
+		{
+			family: invokeFamilyAnthropic,
+			want: `{"anthropic_version":"bedrock-2023-05-31","max_tokens":4096,"system":"Be brief.",
+				"messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello"},{"role":"user","content":"eGFR?"}]}`,
+		},
+		{
+			family:    invokeFamilyTitan,
+			inference: inference,
+			want:      `{"inputText":"Be brief.\n\nUser: Hi\nBot: Hello\nUser: eGFR?\nBot:","textGenerationConfig":{"maxTokenCount":256,"temperature":0.5}}`,
+		},
+		{
+			family: invokeFamilyTitan,
+			want:   `{"inputText":"Be brief.\n\nUser: Hi\nBot: Hello\nUser: eGFR?\nBot:","textGenerationConfig":{}}`,
+		},
+		{
+			family:    invokeFamilyLlama2,
+			inference: inference,
+			want:      `{"prompt":"<s>[INST] <<SYS>>\nBe brief.\n<</SYS>>\n\nHi [/INST] Hello</s><s>[INST] eGFR? [/INST]","max_gen_len":256,"temperature":0.5}`,
+		},
+		{
+			family:    invokeFamilyLlama3,
+			inference: inference,
+			want: `{"prompt":"<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>` +
+				`<|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\nHello<|eot_id|>` +
+				`<|start_header_id|>user<|end_header_id|>\n\neGFR?<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n","max_gen_len":256,"temperature":0.5}`,
+		},
+		{
+			family:    invokeFamilyMistral,
+			inference: inference,
+			want:      `{"prompt":"<s>[INST] Be brief.\n\nHi [/INST] Hello</s>[INST] eGFR? [/INST]","max_tokens":256,"temperature":0.5}`,
+		},
+		{
+			family:    invokeFamilyCohereChat,
+			inference: inference,
+			want: `{"message":"eGFR?","preamble":"Be brief.","max_tokens":256,"temperature":0.5,
+				"chat_history":[{"role":"USER","message":"Hi"},{"role":"CHATBOT","message":"Hello"}]}`,
+		},
+		{
+			family:    invokeFamilyCohereText,
+			inference: inference,
+			want:      `{"prompt":"Be brief.\n\nUser: Hi\nChatbot: Hello\nUser: eGFR?\nChatbot:","max_tokens":256,"temperature":0.5}`,
+		},
+		{
+			family:    invokeFamilyAI21,
+			inference: inference,
+			want:      `{"prompt":"Be brief.\n\nUser: Hi\nAssistant: Hello\nUser: eGFR?\nAssistant:","maxTokens":256,"temperature":0.5}`,
+		},
+	}
+
+	for _, tc := range cases {
+		s.Run(fmt.Sprintf("%s inference=%t", tc.family, tc.inference != nil), func() {
+			body, err := json.Marshal(buildInvokeModelRequest(tc.family, "Be brief.", invokeTestTurns, tc.inference))
+			s.Require().NoError(err)
+			s.JSONEq(tc.want, string(body))
+		})
+	}
+}
+
+func (s *InvokeSuite) TestCohereChatKeepsTrailingAssistantTurnInHistory() {
+	turns := []invokeTurn{
+		{role: bedrocktypes.ConversationRoleUser, text: "Hi"},
+		{role: bedrocktypes.ConversationRoleAssistant, text: "Hello"},
+	}
+
+	request, ok := buildInvokeModelRequest(invokeFamilyCohereChat, "", turns, nil).(cohereChatRequest)
+	s.Require().True(ok)
+	s.Empty(request.Message)
+	s.Equal([]cohereChatTurn{{Role: "USER", Message: "Hi"}, {Role: "CHATBOT", Message: "Hello"}}, request.ChatHistory)
+}
+
+func (s *InvokeSuite) TestParseInvokeModelResponse() {
+	cases := []struct {
+		family invokeModelFamily
+		body   string
+		want   invokeModelResult
+	}{
+		{
+			family: invokeFamilyAnthropic,
+			body: `{"content":[{"type":"text","text":"Stage 3a."},{"type":"tool_use"},{"type":"text","text":"Recheck in 3 months. "}],
+				"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":7}}`,
+			want: invokeModelResult{text: "Stage 3a.\nRecheck in 3 months.", stopReason: "end_turn", inputTokens: 12, outputTokens: 7},
+		},
+		{
+			family: invokeFamilyTitan,
+			body:   `{"inputTextTokenCount":9,"results":[{"tokenCount":4,"outputText":" Stage 3a.","completionReason":"FINISH"}]}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "FINISH", inputTokens: 9, outputTokens: 4},
+		},
+		{
+			family: invokeFamilyTitan,
+			body:   `{"inputTextTokenCount":9,"results":[]}`,
+			want:   invokeModelResult{inputTokens: 9},
+		},
+		{
+			family: invokeFamilyLlama2,
+			body:   `{"generation":" Stage 3a.","prompt_token_count":11,"generation_token_count":5,"stop_reason":"stop"}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "stop", inputTokens: 11, outputTokens: 5},
+		},
+		{
+			family: invokeFamilyLlama3,
+			body:   `{"generation":"Stage 3a.\n","prompt_token_count":11,"generation_token_count":5,"stop_reason":"length"}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "length", inputTokens: 11, outputTokens: 5},
+		},
+		{
+			family: invokeFamilyMistral,
+			body:   `{"outputs":[{"text":" Stage 3a.","stop_reason":"stop"},{"text":"ignored"}]}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "stop"},
+		},
+		{
+			family: invokeFamilyCohereChat,
+			body:   `{"text":"Stage 3a.","finish_reason":"COMPLETE"}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "COMPLETE"},
+		},
+		{
+			family: invokeFamilyCohereText,
+			body:   `{"generations":[{"text":" Stage 3a.","finish_reason":"COMPLETE"}]}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "COMPLETE"},
+		},
+		{
+			family: invokeFamilyAI21,
+			body:   `{"completions":[{"data":{"text":" Stage 3a."},"finishReason":{"reason":"endoftext"}}]}`,
+			want:   invokeModelResult{text: "Stage 3a.", stopReason: "endoftext"},
+		},
+	}
+
+	for _, tc := range cases {
+		s.Run(string(tc.family), func() {
+			result, err := parseInvokeModelResponse(tc.family, []byte(tc.body))
+			s.Require().NoError(err)
+			s.Equal(tc.want, result)
+		})
+	}
+}
+
+func (s *InvokeSuite) TestParseInvokeModelResponseRejectsMalformedBodies() {
+	families := []invokeModelFamily{
+		invokeFamilyAnthropic,
+		invokeFamilyTitan,
+		invokeFamilyLlama3,
+		invokeFamilyMistral,
+		invokeFamilyCohereChat,
+		invokeFamilyCohereText,
+		invokeFamilyAI21,
+	}
+
+	for _, family := range families {
+		s.Run(string(family), func() {
+			_, err := parseInvokeModelResponse(family, []byte(`{"generation":`))
+			s.Error(err)
+		})
+	}
+}
+
+func (s *InvokeSuite) TestTranscriptRenderers() {
+	single := []invokeTurn{{role: bedrocktypes.ConversationRoleUser, text: "eGFR?"}}
+	cases := []struct {
+		name   string
+		render func() string
+		want   string
+	}{
+		{
+			name:   "plain without system",
+			render: func() string { return renderPlainTranscript("", single, "User", "Bot") },
+			want:   "User: eGFR?\nBot:",
+		},
+		{
+			name:   "llama2 without system",
+			render: func() string { return renderInstTranscript("", invokeTestTurns, true) },
+			want:   "<s>[INST] Hi [/INST] Hello</s><s>[INST] eGFR? [/INST]",
+		},
+		{
+			name:   "llama2 system goes to the first instruction only",
+			render: func() string { return renderInstTranscript("Be brief.", invokeTestTurns, true) },
+			want:   "<s>[INST] <<SYS>>\nBe brief.\n<</SYS>>\n\nHi [/INST] Hello</s><s>[INST] eGFR? [/INST]",
+		},
+		{
+			name:   "mistral starts once",
+			render: func() string { return renderInstTranscript("", invokeTestTurns, false) },
+			want:   "<s>[INST] Hi [/INST] Hello</s>[INST] eGFR? [/INST]",
+		},
+		{
+			name:   "mistral single turn with system",
+			render: func() string { return renderInstTranscript("Be brief.", single, false) },
+			want:   "<s>[INST] Be brief.\n\neGFR? [/INST]",
+		},
+		{
+			name:   "llama3 without system",
+			render: func() string { return renderLlama3Transcript("", single) },
+			want:   "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\neGFR?<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.want, tc.render())
+		})
+	}
+}
+
+func (s *InvokeSuite) TestFlattenConverseMessages() {
+	system := []bedrocktypes.SystemContentBlock{
+		&bedrocktypes.SystemContentBlockMemberText{Value: "Be brief."},
+		&bedrocktypes.SystemContentBlockMemberText{Value: "Use SI units."},
+	}
+	messages := []bedrocktypes.Message{{
+		Role: bedrocktypes.ConversationRoleUser,
+		Content: []bedrocktypes.ContentBlock{
+			&bedrocktypes.ContentBlockMemberText{Value: "Creatinine 1.4."},
+			&bedrocktypes.ContentBlockMemberText{Value: "eGFR?"},
+		},
+	}}
+
+	systemText, turns, err := flattenConverseMessages(system, messages)
+	s.Require().NoError(err)
+	s.Equal("Be brief.\n\nUse SI units.", systemText)
+	s.Equal([]invokeTurn{{role: bedrocktypes.ConversationRoleUser, text: "Creatinine 1.4.\neGFR?"}}, turns)
+
+	messages[0].Content = append(messages[0].Content, &bedrocktypes.ContentBlockMemberImage{})
+	_, _, err = flattenConverseMessages(system, messages)
+	s.ErrorContains(err, "text content only")
+}
+
+func (s *InvokeSuite) TestIsConverseUnsupportedError() {
+	validation := func(message string) error {
+		return &bedrocktypes.ValidationException{Message: aws.String(message)}
+	}
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "doesn't support the model", err: validation("This action doesn't support the model that you provided."), want: true},
+		{name: "does not support the model", err: validation("The operation does not support the model."), want: true},
+		{name: "converse not supported", err: validation("Converse is not supported for this model."), want: true},
+		{name: "wrapped", err: fmt.Errorf("converse: %w", validation("This action doesn't support the model that you provided.")), want: true},
+		{name: "other validation error", err: validation("max_tokens must be at least 1"), want: false},
+		{name: "not a validation error", err: errors.New("This action doesn't support the model that you provided."), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.want, isConverseUnsupportedError(tc.err))
+		})
+	}
+}
+
+// invokeFallbackServer rejects Converse for every model and answers
+// InvokeModel with a Llama 3 body, recording the paths and invoke bodies.
+type invokeFallbackServer struct {
+	mu          sync.Mutex
+	paths       []string
+	invokeBody  map[string]any
+	invokeReply string
+}
+
+func (f *invokeFallbackServer) handler(s *InvokeSuite) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.paths = append(f.paths, r.URL.Path)
+		w.Header().Set("content-type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/converse") {
+			w.Header().Set("X-Amzn-ErrorType", "ValidationException")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"This action doesn't support the model that you provided. Try again with a supported text or chat model."}`))
+			return
+		}
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&f.invokeBody))
+		_, _ = w.Write([]byte(f.invokeReply))
+	}
+}
+
+func (s *InvokeSuite) newFallbackGenerator(modelID string, opts ...model.GeneratorOption) (*invokeFallbackServer, model.ContentGenerator[string]) {
+	s.T().Cleanup(func() { converseUnsupportedModels.Delete(modelID) })
+	fake := &invokeFallbackServer{
+		invokeReply: `{"generation":" Stage 3a.","prompt_token_count":20,"generation_token_count":4,"stop_reason":"stop"}`,
+	}
+	server := httptest.NewServer(fake.handler(s))
+	s.T().Cleanup(server.Close)
+
+	opts = append([]model.GeneratorOption{model.WithURL(server.URL), model.WithModel(modelID)}, opts...)
+	gen, err := NewStringContentGenerator("eGFR?", opts...)
+	s.Require().NoError(err)
+	return fake, gen
+}
+
+func (s *InvokeSuite) TestConverseUnsupportedFallsBackToInvokeModel() {
+	modelID := "meta.llama3-8b-instruct-v1:0-fallback-test"
+	fake, gen := s.newFallbackGenerator(modelID, model.WithMaxTokens(128))
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Stage 3a.", out)
+	s.Equal([]string{"/model/" + modelID + "/converse", "/model/" + modelID + "/invoke"}, fake.paths)
+	s.Equal(float64(128), fake.invokeBody["max_gen_len"])
+	s.Contains(fake.invokeBody["prompt"], "Be brief.<|eot_id|>")
+	s.Contains(fake.invokeBody["prompt"], "eGFR?<|eot_id|>")
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+	s.Equal("20", meta[model.MetadataKeyInputTokens])
+	s.Equal("4", meta[model.MetadataKeyOutputTokens])
+	s.Equal("24", meta[model.MetadataKeyTotalTokens])
+
+	// The rejection is remembered, so the next generation skips Converse.
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("/model/"+modelID+"/invoke", fake.paths[len(fake.paths)-1])
+	s.Len(fake.paths, 3)
+}
+
+func (s *InvokeSuite) TestInvokeModelFallbackRejectsTools() {
+	modelID := "meta.llama3-8b-instruct-v1:0-tools-test"
+	tool := model.Tool{Name: "lookup", Description: "Look up a record.", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+	fake, gen := s.newFallbackGenerator(modelID, model.WithTools([]model.Tool{tool}))
+
+	_, _, err := gen.Generate(context.Background())
+	s.ErrorContains(err, "tools are not available through InvokeModel")
+	s.Equal([]string{"/model/" + modelID + "/converse"}, fake.paths)
+}
+
+func (s *InvokeSuite) TestConverseRejectionWithoutInvokeFormatIsReturned() {
+	modelID := "amazon.nova-pro-v1:0-unsupported-test"
+	fake, gen := s.newFallbackGenerator(modelID)
+
+	_, _, err := gen.Generate(context.Background())
+	s.ErrorContains(err, "doesn't support the model")
+	s.Equal([]string{"/model/" + modelID + "/converse"}, fake.paths)
+	s.False(converseUnsupported(modelID))
+}
@@ -45,13 +45,16 @@ func (g *audioTranscriptionGenerator) Generate(ctx context.Context) (string, mod
 	defer setLatencyMetadata(meta, start)
 
 	log := logging.NewLogger(ctx)
-	audioBytes, err := os.ReadFile(g.filePath)
+	format, err := model.ResolveTranscriptFormat(g.opts)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
+	if format != model.TranscriptFormatText {
+		return g.generateFormatted(ctx, format)
+	}
 
-	mimeType, err := resolveAudioMIMEType(g.filePath)
+	audioPart, err := loadAudioPart(g.filePath)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -72,7 +75,7 @@ func (g *audioTranscriptionGenerator) Generate(ctx context.Context) (string, mod
 		genai.NewContentFromParts(
 			[]*genai.Part{
 				genai.NewPartFromText(prompt),
-				genai.NewPartFromBytes(audioBytes, mimeType),
+				audioPart,
 			},
 			genai.RoleUser,
 		),
@@ -92,7 +95,40 @@ func (g *audioTranscriptionGenerator) Generate(ctx context.Context) (string, mod
 	}
 
 	applyAudioTranscriptionMetadata(meta, response)
-	return transcript, meta, nil
+	model.SetTranscriptLanguage(meta, g.opts, transcript)
+	return model.RedactTranscript(meta, g.opts, transcript), meta, nil
+}
+
+// generateFormatted renders JSON and subtitle output from a timed transcript,
+// since Gemini has no native subtitle formats.
+func (g *audioTranscriptionGenerator) generateFormatted(
+	ctx context.Context,
+	format model.TranscriptFormat,
+) (string, model.GenerationMetadata, error) {
+	verbose := &verboseTranscriptionGenerator{filePath: g.filePath, opts: g.opts, cfg: g.cfg}
+	result, meta, err := verbose.Generate(ctx)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	output, err := model.FormatTranscript(result, format)
+	if err != nil {
+		logging.NewLogger(ctx).Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	return output, meta, nil
+}
+
+// loadAudioPart reads filePath into an inline part typed by its extension.
+func loadAudioPart(filePath string) (*genai.Part, error) {
+	audioBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	mimeType, err := resolveAudioMIMEType(filePath)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return genai.NewPartFromBytes(audioBytes, mimeType), nil
 }
 
 func resolveAudioTranscriptionModelName(opts model.AudioOptions) string {
@@ -107,6 +143,9 @@ func audioGeneratorConfigFromOptions(opts model.AudioOptions) model.GeneratorCon
 		IgnoreInvalidGeneratorOptions: opts.IgnoreInvalidGeneratorOptions,
 		URL:                           opts.URL,
 		AuthToken:                     opts.AuthToken,
+		TLSConfig:                     opts.TLSConfig,
+		GCPProject:                    opts.GCPProject,
+		GCPLocation:                   opts.GCPLocation,
 	}
 	if modelName := strings.TrimSpace(opts.Model); modelName != "" {
 		cfg.Model = &modelName
@@ -116,6 +155,7 @@ func audioGeneratorConfigFromOptions(opts model.AudioOptions) model.GeneratorCon
 
 func cloneAudioOptions(opts model.AudioOptions) model.AudioOptions {
 	cloned := opts
+	cloned.TimestampGranularities = append([]model.TimestampGranularity(nil), opts.TimestampGranularities...)
 	if len(opts.Keywords) == 0 {
 		cloned.Keywords = nil
 		return cloned
@@ -141,6 +181,12 @@ func buildAudioTranscriptionPrompt(opts model.AudioOptions) (string, error) {
 	}
 
 	base := "Transcribe this audio accurately. Return only the transcript text."
+	if opts.Diarize {
+		base = "Transcribe this audio accurately and identify the speakers. Return only the transcript, one line per speaker turn formatted as \"Speaker 1: text\", numbering speakers in order of first appearance."
+	}
+	if language := strings.TrimSpace(opts.Language); language != "" {
+		base += " The audio is in the language with ISO 639-1 code \"" + strings.ToLower(language) + "\"."
+	}
 	keywordsPrompt, err := buildCommonMissedWordsPrompt(opts.Keywords)
 	if err != nil {
 		return "", err
@@ -242,11 +288,12 @@ func applyAudioTranscriptionMetadata(meta model.GenerationMetadata, response *ge
 		return
 	}
 
-	meta[model.MetadataKeyInputTokens] = strconv.Itoa(int(response.UsageMetadata.PromptTokenCount))
-	meta[model.MetadataKeyOutputTokens] = strconv.Itoa(int(response.UsageMetadata.CandidatesTokenCount))
-	meta[model.MetadataKeyTotalTokens] = strconv.Itoa(int(response.UsageMetadata.TotalTokenCount))
-	meta[model.MetadataKeyCachedInputTokens] = strconv.Itoa(int(response.UsageMetadata.CachedContentTokenCount))
-	meta[model.MetadataKeyReasoningTokens] = strconv.Itoa(int(response.UsageMetadata.ThoughtsTokenCount))
+	usage := roundUsageFromMetadata(response.UsageMetadata)
+	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(usage.InputTokens, 10)
+	meta[model.MetadataKeyOutputTokens] = strconv.FormatInt(usage.OutputTokens, 10)
+	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(usage.TotalTokens, 10)
+	meta[model.MetadataKeyCachedInputTokens] = strconv.FormatInt(usage.CachedInputTokens, 10)
+	meta[model.MetadataKeyReasoningTokens] = strconv.FormatInt(usage.ReasoningTokens, 10)
 	if strings.TrimSpace(response.ResponseID) != "" {
 		meta[model.MetadataKeyResponseID] = response.ResponseID
 	}
@@ -109,3 +109,9 @@ func (s *AudioTranscriptionGeneratorSuite) TestBuildCommonMissedWordsPromptSkips
 	s.Equal("creatinine", parsed[0].Word)
 	s.Equal([]string{"creatnine"}, parsed[0].CommonMistypes)
 }
+
+func (s *AudioTranscriptionGeneratorSuite) TestBuildAudioTranscriptionPromptIncludesLanguage() {
+	prompt, err := buildAudioTranscriptionPrompt(model.AudioOptions{Language: "ES"})
+	s.Require().NoError(err)
+	s.Contains(prompt, `ISO 639-1 code "es"`)
+}
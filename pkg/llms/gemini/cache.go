@@ -0,0 +1,138 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"google.golang.org/genai"
+)
+
+// CachedContentManager creates, lists and deletes Gemini cached content.
+// Generators reference a cache with model.WithCachedContent(name); cached
+// input tokens are then billed at the reduced cache rate and reported in
+// cached_input_tokens.
+type CachedContentManager struct {
+	cfg    model.GeneratorConfig
+	client *genai.Client
+}
+
+// CachedContentSpec describes the context to cache. Gemini requires a
+// minimum cached size (a few thousand tokens depending on the model).
+type CachedContentSpec struct {
+	// Model the cache is created for; generators must use the same model.
+	// Defaults to the model resolved from the manager options.
+	Model string
+	// DisplayName is a human-readable label shown by List.
+	DisplayName string
+	// Contexts are cached like generator prompt contexts: system contexts
+	// become the system instruction, the others become conversation turns.
+	Contexts []*model.PromptContext
+	// Tools are declared in the cache. Generators pass the same tools with
+	// model.WithTools so their calls can be handled.
+	Tools []model.Tool
+	// TTL is how long the cache lives; zero uses the API default (1 hour).
+	TTL time.Duration
+}
+
+// CachedContent describes a cache entry.
+type CachedContent struct {
+	Name        string
+	DisplayName string
+	Model       string
+	CreateTime  time.Time
+	ExpireTime  time.Time
+	TotalTokens int
+}
+
+// NewCachedContentManager creates a manager using the same client options as
+// the generators (auth token, URL, Vertex AI project and location, model).
+func NewCachedContentManager(ctx context.Context, opts ...model.GeneratorOption) (*CachedContentManager, error) {
+	cfg := model.ResolveGeneratorOpts(opts...)
+	client, err := newAPIClient(ctx, cfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return &CachedContentManager{cfg: cfg, client: client}, nil
+}
+
+// Create caches spec and returns the new entry; pass its Name to
+// model.WithCachedContent.
+func (m *CachedContentManager) Create(ctx context.Context, spec CachedContentSpec) (CachedContent, error) {
+	systemInstruction, contents, _, err := mapPromptContexts(spec.Contexts)
+	if err != nil {
+		return CachedContent{}, utils.WrapIfNotNil(err)
+	}
+	if systemInstruction == nil && len(contents) == 0 {
+		return CachedContent{}, utils.WrapIfNotNil(errors.New("cached content requires at least one non-empty context"))
+	}
+
+	tools, _, err := mapTools(spec.Tools)
+	if err != nil {
+		return CachedContent{}, utils.WrapIfNotNil(err)
+	}
+
+	modelName := strings.TrimSpace(spec.Model)
+	if modelName == "" {
+		modelName = resolveGenerationModelName(m.cfg)
+	}
+
+	created, err := m.client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+		DisplayName:       spec.DisplayName,
+		TTL:               spec.TTL,
+		Contents:          contents,
+		SystemInstruction: systemInstruction,
+		Tools:             tools,
+	})
+	if err != nil {
+		return CachedContent{}, utils.WrapIfNotNil(err)
+	}
+	return convertCachedContent(created), nil
+}
+
+// Get returns the cache entry called name.
+func (m *CachedContentManager) Get(ctx context.Context, name string) (CachedContent, error) {
+	found, err := m.client.Caches.Get(ctx, name, nil)
+	if err != nil {
+		return CachedContent{}, utils.WrapIfNotNil(err)
+	}
+	return convertCachedContent(found), nil
+}
+
+// List returns every cache entry visible to the credentials, across pages.
+func (m *CachedContentManager) List(ctx context.Context) ([]CachedContent, error) {
+	out := make([]CachedContent, 0)
+	for entry, err := range m.client.Caches.All(ctx) {
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		out = append(out, convertCachedContent(entry))
+	}
+	return out, nil
+}
+
+// Delete removes the cache entry called name.
+func (m *CachedContentManager) Delete(ctx context.Context, name string) error {
+	_, err := m.client.Caches.Delete(ctx, name, nil)
+	return utils.WrapIfNotNil(err)
+}
+
+func convertCachedContent(entry *genai.CachedContent) CachedContent {
+	if entry == nil {
+		return CachedContent{}
+	}
+	out := CachedContent{
+		Name:        entry.Name,
+		DisplayName: entry.DisplayName,
+		Model:       entry.Model,
+		CreateTime:  entry.CreateTime,
+		ExpireTime:  entry.ExpireTime,
+	}
+	if entry.UsageMetadata != nil {
+		out.TotalTokens = int(entry.UsageMetadata.TotalTokenCount)
+	}
+	return out
+}
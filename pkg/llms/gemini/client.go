@@ -2,11 +2,12 @@ package gemini
 
 import (
 	"context"
-	"os"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 	"google.golang.org/genai"
@@ -16,30 +17,75 @@ const (
 	providerName               = "gemini"
 	defaultGenerationModelName = "gemini-2.5-flash"
 	defaultEmbeddingModelName  = "gemini-embedding-001"
-	maxToolRounds              = 12
+	defaultVertexLocation      = "us-central1"
+	maxTemperature             = 2.0
 )
 
 type generationTotals struct {
 	APICalls        int
 	ToolRounds      int
+	ToolCalls       []model.ToolCallRecord
 	InputTokens     int64
 	OutputTokens    int64
 	TotalTokens     int64
 	CachedTokens    int64
 	ReasoningTokens int64
+	Rounds          []model.RoundUsage
+	WebQueries      []string
 }
 
 func newAPIClient(ctx context.Context, cfg model.GeneratorConfig) (*genai.Client, error) {
+	clientCfg, err := buildClientConfig(cfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	// genai only looks up Application Default Credentials when it builds the
+	// HTTP client itself, so add them to the TLS client for Vertex AI.
+	if clientCfg.HTTPClient != nil && clientCfg.Backend == genai.BackendVertexAI {
+		if err = clientCfg.UseDefaultCredentials(); err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+	}
+
+	client, err := genai.NewClient(ctx, clientCfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return client, nil
+}
+
+// buildClientConfig selects the Vertex AI backend when a GCP project or location
+// is configured, otherwise the Gemini API key backend.
+func buildClientConfig(cfg model.GeneratorConfig) (*genai.ClientConfig, error) {
+	log := logging.NewLogger(context.Background())
 	clientCfg := &genai.ClientConfig{
 		Backend: genai.BackendGeminiAPI,
 	}
 
-	token := strings.TrimSpace(cfg.AuthToken)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("GEMINI_KEY"))
-	}
-	if token != "" {
-		clientCfg.APIKey = token
+	if usesVertexAI(cfg) {
+		project := model.EnvFallback(log, providerName, "gcp_project", cfg.GCPProject, "GOOGLE_CLOUD_PROJECT")
+		if project == "" {
+			return nil, utils.WrapIfNotNil(errors.New("gcp project is required for the vertex ai backend"))
+		}
+
+		location := model.EnvFallback(log, providerName, "gcp_location", cfg.GCPLocation, "GOOGLE_CLOUD_LOCATION")
+		if location == "" {
+			location = defaultVertexLocation
+		}
+
+		// Vertex AI authenticates with Application Default Credentials; API keys are not used.
+		if strings.TrimSpace(cfg.AuthToken) != "" && !cfg.IgnoreInvalidGeneratorOptions {
+			return nil, utils.WrapIfNotNil(errors.New("auth token is not supported with the vertex ai backend; use application default credentials"))
+		}
+
+		clientCfg.Backend = genai.BackendVertexAI
+		clientCfg.Project = project
+		clientCfg.Location = location
+	} else {
+		token := model.EnvFallback(log, providerName, "auth_token", cfg.AuthToken, "GEMINI_KEY")
+		if token != "" {
+			clientCfg.APIKey = token
+		}
 	}
 
 	baseURL := strings.TrimSpace(cfg.URL)
@@ -48,12 +94,15 @@ func newAPIClient(ctx context.Context, cfg model.GeneratorConfig) (*genai.Client
 			BaseURL: baseURL,
 		}
 	}
-
-	client, err := genai.NewClient(ctx, clientCfg)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+	if cfg.TLSConfig != nil {
+		clientCfg.HTTPClient = model.NewHTTPClient(cfg, 0)
 	}
-	return client, nil
+
+	return clientCfg, nil
+}
+
+func usesVertexAI(cfg model.GeneratorConfig) bool {
+	return strings.TrimSpace(cfg.GCPProject) != "" || strings.TrimSpace(cfg.GCPLocation) != ""
 }
 
 func initMetadata(modelName string) model.GenerationMetadata {
@@ -95,17 +144,43 @@ func resolveEmbeddingModelName(cfg model.GeneratorConfig) string {
 }
 
 func accumulateGenerationTotals(totals *generationTotals, response *genai.GenerateContentResponse) {
-	if totals == nil || response == nil || response.UsageMetadata == nil {
+	if totals == nil || response == nil {
 		return
 	}
 
-	usage := response.UsageMetadata
 	totals.APICalls++
-	totals.InputTokens += int64(usage.PromptTokenCount)
-	totals.OutputTokens += int64(usage.CandidatesTokenCount)
-	totals.TotalTokens += int64(usage.TotalTokenCount)
-	totals.CachedTokens += int64(usage.CachedContentTokenCount)
-	totals.ReasoningTokens += int64(usage.ThoughtsTokenCount)
+	if len(response.Candidates) > 0 && response.Candidates[0] != nil && response.Candidates[0].GroundingMetadata != nil {
+		totals.WebQueries = append(totals.WebQueries, response.Candidates[0].GroundingMetadata.WebSearchQueries...)
+	}
+	round := roundUsageFromMetadata(response.UsageMetadata)
+	totals.InputTokens += round.InputTokens
+	totals.OutputTokens += round.OutputTokens
+	totals.TotalTokens += round.TotalTokens
+	totals.CachedTokens += round.CachedInputTokens
+	totals.ReasoningTokens += round.ReasoningTokens
+	totals.Rounds = append(totals.Rounds, round)
+}
+
+// roundUsageFromMetadata normalizes Gemini usage to the shared token
+// semantics: input includes tool-use prompt tokens, and output includes
+// thought tokens (Gemini reports them apart from candidates, while other
+// providers count reasoning as output).
+func roundUsageFromMetadata(usage *genai.GenerateContentResponseUsageMetadata) model.RoundUsage {
+	if usage == nil {
+		return model.RoundUsage{}
+	}
+
+	round := model.RoundUsage{
+		InputTokens:       int64(usage.PromptTokenCount) + int64(usage.ToolUsePromptTokenCount),
+		OutputTokens:      int64(usage.CandidatesTokenCount) + int64(usage.ThoughtsTokenCount),
+		TotalTokens:       int64(usage.TotalTokenCount),
+		CachedInputTokens: int64(usage.CachedContentTokenCount),
+		ReasoningTokens:   int64(usage.ThoughtsTokenCount),
+	}
+	if round.TotalTokens == 0 {
+		round.TotalTokens = round.InputTokens + round.OutputTokens
+	}
+	return round
 }
 
 func applyGenerateMetadata(meta model.GenerationMetadata, response *genai.GenerateContentResponse, totals generationTotals) {
@@ -115,11 +190,17 @@ func applyGenerateMetadata(meta model.GenerationMetadata, response *genai.Genera
 
 	meta[model.MetadataKeyAPICalls] = strconv.Itoa(totals.APICalls)
 	meta[model.MetadataKeyToolRounds] = strconv.Itoa(totals.ToolRounds)
+	model.SetToolCalls(meta, totals.ToolCalls)
 	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(totals.InputTokens, 10)
 	meta[model.MetadataKeyOutputTokens] = strconv.FormatInt(totals.OutputTokens, 10)
 	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(totals.TotalTokens, 10)
 	meta[model.MetadataKeyCachedInputTokens] = strconv.FormatInt(totals.CachedTokens, 10)
 	meta[model.MetadataKeyReasoningTokens] = strconv.FormatInt(totals.ReasoningTokens, 10)
+	model.SetRoundUsage(meta, totals.Rounds)
+	if len(totals.WebQueries) > 0 {
+		meta[model.MetadataKeyWebSearchCalls] = strconv.Itoa(len(totals.WebQueries))
+		model.SetWebSearchQueries(meta, totals.WebQueries)
+	}
 
 	if response == nil {
 		return
@@ -129,7 +210,75 @@ func applyGenerateMetadata(meta model.GenerationMetadata, response *genai.Genera
 	}
 	if len(response.Candidates) > 0 && response.Candidates[0] != nil {
 		meta[model.MetadataKeyResponseStatus] = string(response.Candidates[0].FinishReason)
+		model.SetCitations(meta, groundingCitations(response.Candidates[0]))
+	}
+}
+
+// groundingCitations maps Google Search grounding to citations. Each web
+// source gets one citation per supported text segment, with offsets shifted
+// from the segment's part into the concatenated response text; sources no
+// segment refers to are listed once without a span.
+func groundingCitations(candidate *genai.Candidate) []model.Citation {
+	grounding := candidate.GroundingMetadata
+	if grounding == nil || len(grounding.GroundingChunks) == 0 {
+		return nil
+	}
+
+	var partOffsets []int
+	if candidate.Content != nil {
+		offset := 0
+		for _, part := range candidate.Content.Parts {
+			partOffsets = append(partOffsets, offset)
+			if part != nil && part.Text != "" && !part.Thought {
+				offset += len(part.Text)
+			}
+		}
+	}
+
+	var citations []model.Citation
+	cited := make(map[int32]bool, len(grounding.GroundingChunks))
+	for _, support := range grounding.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		offset := 0
+		if index := int(support.Segment.PartIndex); index >= 0 && index < len(partOffsets) {
+			offset = partOffsets[index]
+		}
+		for _, chunkIndex := range support.GroundingChunkIndices {
+			web := groundingWebChunk(grounding.GroundingChunks, chunkIndex)
+			if web == nil {
+				continue
+			}
+			cited[chunkIndex] = true
+			citations = append(citations, model.Citation{
+				URL:        web.URI,
+				Title:      web.Title,
+				StartIndex: offset + int(support.Segment.StartIndex),
+				EndIndex:   offset + int(support.Segment.EndIndex),
+			})
+		}
+	}
+	for i := range grounding.GroundingChunks {
+		if cited[int32(i)] {
+			continue
+		}
+		if web := groundingWebChunk(grounding.GroundingChunks, int32(i)); web != nil {
+			citations = append(citations, model.Citation{URL: web.URI, Title: web.Title})
+		}
+	}
+	return citations
+}
+
+func groundingWebChunk(chunks []*genai.GroundingChunk, index int32) *genai.GroundingChunkWeb {
+	if index < 0 || int(index) >= len(chunks) || chunks[index] == nil {
+		return nil
+	}
+	web := chunks[index].Web
+	if web == nil || strings.TrimSpace(web.URI) == "" {
+		return nil
 	}
+	return web
 }
 
 func applyEmbeddingMetadata(meta model.GenerationMetadata, vectors model.EmbeddingVectors) {
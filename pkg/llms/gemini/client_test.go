@@ -0,0 +1,122 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genai"
+)
+
+type ClientSuite struct {
+	suite.Suite
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
+
+func (s *ClientSuite) TestBuildClientConfigGeminiAPI() {
+	clientCfg, err := buildClientConfig(model.ResolveGeneratorOpts(
+		model.WithAuthToken("key"),
+		model.WithURL("https://example.test"),
+	))
+
+	s.Require().NoError(err)
+	s.Equal(genai.BackendGeminiAPI, clientCfg.Backend)
+	s.Equal("key", clientCfg.APIKey)
+	s.Equal("https://example.test", clientCfg.HTTPOptions.BaseURL)
+}
+
+func (s *ClientSuite) TestBuildClientConfigVertexAI() {
+	s.T().Setenv("GOOGLE_CLOUD_LOCATION", "")
+	clientCfg, err := buildClientConfig(model.ResolveGeneratorOpts(
+		model.WithGCPProject("my-project"),
+	))
+
+	s.Require().NoError(err)
+	s.Equal(genai.BackendVertexAI, clientCfg.Backend)
+	s.Equal("my-project", clientCfg.Project)
+	s.Equal(defaultVertexLocation, clientCfg.Location)
+	s.Empty(clientCfg.APIKey)
+}
+
+func (s *ClientSuite) TestBuildClientConfigVertexAIProjectFromEnv() {
+	s.T().Setenv("GOOGLE_CLOUD_PROJECT", "env-project")
+	clientCfg, err := buildClientConfig(model.ResolveGeneratorOpts(
+		model.WithGCPLocation("europe-west4"),
+	))
+
+	s.Require().NoError(err)
+	s.Equal("env-project", clientCfg.Project)
+	s.Equal("europe-west4", clientCfg.Location)
+}
+
+func (s *ClientSuite) TestBuildClientConfigVertexAIRequiresProject() {
+	s.T().Setenv("GOOGLE_CLOUD_PROJECT", "")
+	_, err := buildClientConfig(model.ResolveGeneratorOpts(
+		model.WithGCPLocation("us-east1"),
+	))
+
+	s.Error(err)
+	s.Contains(err.Error(), "gcp project is required")
+}
+
+func (s *ClientSuite) TestBuildClientConfigVertexAIRejectsAuthToken() {
+	_, err := buildClientConfig(model.ResolveGeneratorOpts(
+		model.WithGCPProject("my-project"),
+		model.WithAuthToken("key"),
+	))
+	s.Error(err)
+
+	clientCfg, err := buildClientConfig(model.ResolveGeneratorOpts(
+		model.WithGCPProject("my-project"),
+		model.WithAuthToken("key"),
+		model.WithIgnoreInvalidGeneratorOptions(true),
+	))
+	s.Require().NoError(err)
+	s.Empty(clientCfg.APIKey)
+}
+
+func (s *ClientSuite) TestAccumulateGenerationTotalsPerRound() {
+	totals := generationTotals{}
+	accumulateGenerationTotals(&totals, &genai.GenerateContentResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:        100,
+			CachedContentTokenCount: 60,
+			CandidatesTokenCount:    10,
+			ThoughtsTokenCount:      25,
+			TotalTokenCount:         135,
+		},
+	})
+	accumulateGenerationTotals(&totals, &genai.GenerateContentResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:        150,
+			ToolUsePromptTokenCount: 5,
+			CandidatesTokenCount:    20,
+		},
+	})
+	accumulateGenerationTotals(&totals, &genai.GenerateContentResponse{})
+
+	s.Equal(3, totals.APICalls)
+	s.Equal(int64(255), totals.InputTokens)
+	s.Equal(int64(55), totals.OutputTokens)
+	s.Equal(int64(310), totals.TotalTokens)
+	s.Equal(int64(60), totals.CachedTokens)
+	s.Equal(int64(25), totals.ReasoningTokens)
+	s.Equal([]model.RoundUsage{
+		{InputTokens: 100, OutputTokens: 35, TotalTokens: 135, CachedInputTokens: 60, ReasoningTokens: 25},
+		{InputTokens: 155, OutputTokens: 20, TotalTokens: 175},
+		{},
+	}, totals.Rounds)
+
+	meta := model.GenerationMetadata{}
+	applyGenerateMetadata(meta, nil, totals)
+	s.Equal("3", meta[model.MetadataKeyAPICalls])
+	s.Equal("55", meta[model.MetadataKeyOutputTokens])
+	s.Equal("60", meta[model.MetadataKeyCachedInputTokens])
+	s.Equal("25", meta[model.MetadataKeyReasoningTokens])
+	rounds, err := model.ParseRoundUsage(meta)
+	s.Require().NoError(err)
+	s.Equal(totals.Rounds, rounds)
+}
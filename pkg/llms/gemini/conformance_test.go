@@ -0,0 +1,109 @@
+package gemini
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/testsupport"
+	"google.golang.org/genai"
+)
+
+type conformanceWire struct{}
+
+func (conformanceWire) DecodeRequest(r *http.Request) (testsupport.Request, error) {
+	var request struct {
+		Contents          []*genai.Content `json:"contents"`
+		SystemInstruction *genai.Content   `json:"systemInstruction"`
+		Tools             []*genai.Tool    `json:"tools"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return testsupport.Request{}, err
+	}
+
+	// The model is part of the path: .../models/{model}:generateContent.
+	modelName := r.URL.Path[strings.LastIndex(r.URL.Path, "/models/")+len("/models/"):]
+	out := testsupport.Request{Model: strings.TrimSuffix(modelName, ":generateContent")}
+	if system := contentText(request.SystemInstruction); system != "" {
+		out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleSystem, Content: system})
+	}
+	for _, content := range request.Contents {
+		for _, part := range content.Parts {
+			if part.FunctionResponse == nil {
+				continue
+			}
+			response := part.FunctionResponse.Response
+			callID, _ := response["id"].(string)
+			var result any = response
+			if output, ok := response["output"]; ok {
+				result = output
+			} else {
+				delete(response, "id")
+			}
+			encoded, err := json.Marshal(result)
+			if err != nil {
+				return testsupport.Request{}, err
+			}
+			out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleTool, Content: string(encoded), ToolCallID: callID})
+		}
+		if text := contentText(content); text != "" {
+			role := testsupport.RoleUser
+			if content.Role == genai.RoleModel {
+				role = testsupport.RoleAssistant
+			}
+			out.Messages = append(out.Messages, testsupport.Message{Role: role, Content: text})
+		}
+	}
+	for _, tool := range request.Tools {
+		for _, declaration := range tool.FunctionDeclarations {
+			out.Tools = append(out.Tools, declaration.Name)
+		}
+	}
+	return out, nil
+}
+
+func (conformanceWire) WriteResponse(w http.ResponseWriter, turn testsupport.Turn) {
+	w.Header().Set("content-type", "application/json")
+	if turn.StatusCode >= http.StatusMultipleChoices {
+		w.WriteHeader(turn.StatusCode)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]any{"code": turn.StatusCode, "message": turn.ErrorMessage, "status": "INTERNAL"},
+		})
+		return
+	}
+
+	parts := make([]map[string]any, 0, len(turn.ToolCalls)+1)
+	if turn.Text != "" {
+		parts = append(parts, map[string]any{"text": turn.Text})
+	}
+	for _, call := range turn.ToolCalls {
+		var args map[string]any
+		_ = json.Unmarshal(call.Arguments, &args)
+		parts = append(parts, map[string]any{"functionCall": map[string]any{"id": call.ID, "name": call.Name, "args": args}})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"responseId":   "resp_conformance",
+		"modelVersion": "gemini-conformance",
+		"candidates": []map[string]any{{
+			"content":      map[string]any{"role": "model", "parts": parts},
+			"finishReason": "STOP",
+		}},
+		"usageMetadata": map[string]any{
+			"promptTokenCount":     turn.InputTokens,
+			"candidatesTokenCount": turn.OutputTokens,
+			"totalTokenCount":      turn.InputTokens + turn.OutputTokens,
+		},
+	})
+}
+
+func TestConformance(t *testing.T) {
+	testsupport.RunConformance(t, testsupport.Harness{
+		Provider:      providerName,
+		Wire:          conformanceWire{},
+		Options:       []model.GeneratorOption{model.WithAuthToken("test-key")},
+		NewString:     NewStringContentGenerator,
+		NewStructured: NewStructureContentGenerator[testsupport.Record],
+	})
+}
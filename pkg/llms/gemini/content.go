@@ -5,14 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
-	"github.com/invopop/jsonschema"
 	"google.golang.org/genai"
 )
 
@@ -24,6 +25,8 @@ type structuredGenerator[T any] struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 type textGenerator struct {
@@ -32,6 +35,8 @@ type textGenerator struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
@@ -111,20 +116,40 @@ func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider m
 }
 
 func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *structuredGenerator[T]) generate(ctx context.Context) (T, model.GenerationMetadata, error) {
 	start := time.Now()
 	modelName := resolveGenerationModelName(g.cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
 
 	log := logging.NewLogger(ctx)
-	systemInstruction, contents, contextCount, err := g.contentsWithContext(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(g.cfg, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+
+	systemInstruction, contents, contextCount, err := g.contentsWithContext(ctx, meta)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+	systemInstruction, err = checkCachedContentSystemInstruction(cfg, systemInstruction, log)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	allTools, cleanup, err := buildAllTools(ctx, g.cfg)
+	allTools, cleanup, err := buildAllTools(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -138,9 +163,16 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
+	builtinTools, err := mapBuiltinTools(cfg, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+	genTools = append(genTools, builtinTools...)
 
-	config := buildGenerateContentConfig(g.cfg, systemInstruction, genTools)
-	schema, err := generateJSONSchema[T]()
+	config := buildGenerateContentConfig(cfg, systemInstruction, genTools)
+	schema, err := model.StructuredOutputSchema[T](cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -161,7 +193,7 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		contents = append(contents, genai.NewContentFromText(instruction, genai.RoleUser))
 	}
 
-	client, err := newAPIClient(ctx, g.cfg)
+	client, err := newAPIClient(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -173,14 +205,15 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		g.prompt,
 		contextCount,
 		modelName,
-		g.cfg.Temperature,
-		g.cfg.MaxTokens,
-		g.cfg.ReasoningLevel,
-		len(g.cfg.Tools),
-		len(g.cfg.MCPTools),
+		cfg.Temperature,
+		cfg.MaxTokens,
+		cfg.ReasoningLevel,
+		len(cfg.Tools),
+		len(cfg.MCPTools),
 	)
 
-	response, totals, err := runGenerateFlow(ctx, client, modelName, contents, config, handlers)
+	response, totals, history, err := runGenerateFlow(ctx, client, modelName, contents, config, handlers, cfg)
+	g.recordHistory(modelName, config.SystemInstruction, history)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -196,8 +229,19 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	var out T
-	err = json.Unmarshal([]byte(extractJSONPayload(text)), &out)
+	model.SetRawOutput(meta, cfg, text)
+	out, err := model.DecodeStructuredOutputWithRepair[T](
+		ctx,
+		cfg,
+		meta,
+		schema,
+		text,
+		model.ResolveStructuredRepairAttempts(cfg, 0),
+		extractJSONPayload,
+		func(ctx context.Context, prompt string) (string, model.GenerationMetadata, error) {
+			return repairStructuredOutput(ctx, client, modelName, schema, cfg, prompt)
+		},
+	)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -207,19 +251,37 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 }
 
 func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generate(ctx context.Context) (string, model.GenerationMetadata, error) {
 	start := time.Now()
 	modelName := resolveGenerationModelName(g.cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
 
 	log := logging.NewLogger(ctx)
-	systemInstruction, contents, contextCount, err := g.contentsWithContext(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(g.cfg, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	systemInstruction, contents, contextCount, err := g.contentsWithContext(ctx, meta)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	systemInstruction, err = checkCachedContentSystemInstruction(cfg, systemInstruction, log)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 
-	allTools, cleanup, err := buildAllTools(ctx, g.cfg)
+	allTools, cleanup, err := buildAllTools(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -231,9 +293,15 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
+	builtinTools, err := mapBuiltinTools(cfg, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	genTools = append(genTools, builtinTools...)
 
-	config := buildGenerateContentConfig(g.cfg, systemInstruction, genTools)
-	client, err := newAPIClient(ctx, g.cfg)
+	config := buildGenerateContentConfig(cfg, systemInstruction, genTools)
+	client, err := newAPIClient(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -244,30 +312,93 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		g.prompt,
 		contextCount,
 		modelName,
-		g.cfg.Temperature,
-		g.cfg.MaxTokens,
-		g.cfg.ReasoningLevel,
-		len(g.cfg.Tools),
-		len(g.cfg.MCPTools),
+		cfg.Temperature,
+		cfg.MaxTokens,
+		cfg.ReasoningLevel,
+		len(cfg.Tools),
+		len(cfg.MCPTools),
 	)
 
-	response, totals, err := runGenerateFlow(ctx, client, modelName, contents, config, handlers)
+	response, totals, history, err := runGenerateFlow(ctx, client, modelName, contents, config, handlers, cfg)
+	g.recordHistory(modelName, config.SystemInstruction, history)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	applyGenerateMetadata(meta, response, totals)
 
-	text := strings.TrimSpace(response.Text())
-	if text == "" {
-		err = errors.New("response output is empty")
+	text, err := model.FinishTextOutput(ctx, cfg, response.Text())
+	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	return text, meta, nil
 }
 
-func (g *structuredGenerator[T]) contentsWithContext(ctx context.Context) (*genai.Content, []*genai.Content, int, error) {
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *structuredGenerator[T]) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg)
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *textGenerator) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg)
+}
+
+// effectiveConfig applies the option normalization, model resolution,
+// built-in tool support and backend settings of a generation to cfg.
+func effectiveConfig(ctx context.Context, cfg model.GeneratorConfig) (model.GeneratorConfig, error) {
+	log := logging.NewLogger(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(cfg, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	if _, err := mapBuiltinTools(cfg, log); err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	cfg.BuiltinTools = slices.DeleteFunc(slices.Clone(cfg.BuiltinTools), func(tool model.BuiltinTool) bool {
+		return tool != model.BuiltinWebSearch
+	})
+	clientCfg, err := buildClientConfig(cfg)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	if clientCfg.Backend == genai.BackendVertexAI {
+		cfg.GCPProject = clientCfg.Project
+		cfg.GCPLocation = clientCfg.Location
+	}
+	modelName := resolveGenerationModelName(cfg)
+	cfg.Model = &modelName
+	return cfg, nil
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *structuredGenerator[T]) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *structuredGenerator[T]) recordHistory(modelName string, systemInstruction *genai.Content, contents []*genai.Content) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, systemInstruction, contents)
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *textGenerator) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *textGenerator) recordHistory(modelName string, systemInstruction *genai.Content, contents []*genai.Content) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, systemInstruction, contents)
+}
+
+func (g *structuredGenerator[T]) contentsWithContext(ctx context.Context, meta model.GenerationMetadata) (*genai.Content, []*genai.Content, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -281,10 +412,30 @@ func (g *structuredGenerator[T]) contentsWithContext(ctx context.Context) (*gena
 		contexts = append(contexts, provided...)
 	}
 
-	return buildContentsWithContext(g.prompt, contexts)
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+	systemInstruction, contents, contextCount, err := buildContentsWithContext(g.prompt, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contents, err = attachDocuments(contents, g.cfg.Documents)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	return systemInstruction, contents, contextCount, nil
 }
 
-func (g *textGenerator) contentsWithContext(ctx context.Context) (*genai.Content, []*genai.Content, int, error) {
+func (g *textGenerator) contentsWithContext(ctx context.Context, meta model.GenerationMetadata) (*genai.Content, []*genai.Content, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -298,10 +449,42 @@ func (g *textGenerator) contentsWithContext(ctx context.Context) (*genai.Content
 		contexts = append(contexts, provided...)
 	}
 
-	return buildContentsWithContext(g.prompt, contexts)
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+	systemInstruction, contents, contextCount, err := buildContentsWithContext(g.prompt, contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contents, err = attachDocuments(contents, g.cfg.Documents)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	return systemInstruction, contents, contextCount, nil
 }
 
 func buildContentsWithContext(prompt string, contexts []*model.PromptContext) (*genai.Content, []*genai.Content, int, error) {
+	systemInstruction, contents, contextCount, err := mapPromptContexts(contexts)
+	if err != nil {
+		return nil, nil, 0, utils.WrapIfNotNil(err)
+	}
+	contents = append(contents, genai.NewContentFromText(prompt, genai.RoleUser))
+	return systemInstruction, contents, contextCount, nil
+}
+
+// mapPromptContexts joins system contexts into one system instruction and
+// maps the others to conversation contents. Blank contexts are skipped and not
+// counted.
+func mapPromptContexts(contexts []*model.PromptContext) (*genai.Content, []*genai.Content, int, error) {
 	systemParts := make([]string, 0)
 	contents := make([]*genai.Content, 0, len(contexts)+1)
 	contextCount := 0
@@ -312,11 +495,24 @@ func buildContentsWithContext(prompt string, contexts []*model.PromptContext) (*
 		}
 
 		content := strings.TrimSpace(contextItem.Content)
-		if content == "" {
+		if !contextItem.HasContent() {
 			continue
 		}
 
+		images, err := model.ResolveImageParts(contextItem)
+		if err != nil {
+			return nil, nil, 0, utils.WrapIfNotNil(err)
+		}
+
 		contextCount++
+		if len(images) > 0 {
+			imageContent, err := mapImageContent(content, images)
+			if err != nil {
+				return nil, nil, 0, utils.WrapIfNotNil(err)
+			}
+			contents = append(contents, imageContent)
+			continue
+		}
 		switch contextItem.MessageType {
 		case model.ContextMessageTypeSystem:
 			systemParts = append(systemParts, content)
@@ -329,14 +525,66 @@ func buildContentsWithContext(prompt string, contexts []*model.PromptContext) (*
 		}
 	}
 
-	contents = append(contents, genai.NewContentFromText(prompt, genai.RoleUser))
-
 	if len(systemParts) == 0 {
 		return nil, contents, contextCount, nil
 	}
+	return genai.NewContentFromText(strings.Join(systemParts, "\n\n"), genai.RoleUser), contents, contextCount, nil
+}
 
-	systemInstruction := genai.NewContentFromText(strings.Join(systemParts, "\n\n"), genai.RoleUser)
-	return systemInstruction, contents, contextCount, nil
+// mapImageContent builds a user turn with inline image bytes or file URIs,
+// followed by the text. Gemini needs the MIME type of URL images.
+func mapImageContent(content string, images []model.ImagePart) (*genai.Content, error) {
+	parts := make([]*genai.Part, 0, len(images)+1)
+	for _, image := range images {
+		if len(image.Data) > 0 {
+			parts = append(parts, genai.NewPartFromBytes(image.Data, image.MIMEType))
+			continue
+		}
+		if image.MIMEType == "" {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("image url %q needs a mime type for gemini provider", image.URL))
+		}
+		parts = append(parts, genai.NewPartFromURI(image.URL, image.MIMEType))
+	}
+	if content != "" {
+		parts = append(parts, genai.NewPartFromText(content))
+	}
+	return genai.NewContentFromParts(parts, genai.RoleUser), nil
+}
+
+// geminiDocumentMaxBytes is the limit on inline data per request; larger
+// documents must be uploaded with the Files API and passed by URI.
+const geminiDocumentMaxBytes = 20 << 20
+
+var geminiDocumentMIMETypes = []string{
+	model.DocumentMIMETypePDF,
+	model.DocumentMIMETypeText,
+	model.DocumentMIMETypeMarkdown,
+	model.DocumentMIMETypeCSV,
+	model.DocumentMIMETypeHTML,
+}
+
+// attachDocuments puts file parts (inline bytes or file URIs) ahead of the
+// parts of the final prompt content.
+func attachDocuments(contents []*genai.Content, documents []model.DocumentPart) ([]*genai.Content, error) {
+	if len(documents) == 0 || len(contents) == 0 {
+		return contents, nil
+	}
+	resolved, err := model.ResolveDocumentParts(documents, geminiDocumentMIMETypes, geminiDocumentMaxBytes)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	last := contents[len(contents)-1]
+	parts := make([]*genai.Part, 0, len(resolved)+len(last.Parts))
+	for _, document := range resolved {
+		if len(document.Data) > 0 {
+			parts = append(parts, genai.NewPartFromBytes(document.Data, document.MIMEType))
+			continue
+		}
+		parts = append(parts, genai.NewPartFromURI(document.URL, document.MIMEType))
+	}
+	contents[len(contents)-1] = genai.NewContentFromParts(append(parts, last.Parts...), genai.RoleUser)
+	return contents, nil
 }
 
 func buildGenerateContentConfig(
@@ -345,10 +593,20 @@ func buildGenerateContentConfig(
 	tools []*genai.Tool,
 ) *genai.GenerateContentConfig {
 	config := &genai.GenerateContentConfig{}
+	if name := strings.TrimSpace(cfg.CachedContent); name != "" {
+		// The cache carries the system instruction and tool declarations, and
+		// the API rejects requests that set them again.
+		config.CachedContent = name
+		systemInstruction = nil
+		tools = nil
+	}
 
 	if systemInstruction != nil {
 		config.SystemInstruction = systemInstruction
 	}
+	if len(cfg.ProviderParams) > 0 {
+		config.HTTPOptions = &genai.HTTPOptions{ExtraBody: cfg.ProviderParams}
+	}
 	if cfg.Temperature != nil {
 		temp := float32(*cfg.Temperature)
 		config.Temperature = &temp
@@ -363,6 +621,8 @@ func buildGenerateContentConfig(
 	}
 	if len(tools) > 0 {
 		config.Tools = tools
+	}
+	if hasFunctionDeclarations(tools) {
 		config.ToolConfig = &genai.ToolConfig{
 			FunctionCallingConfig: &genai.FunctionCallingConfig{
 				Mode: genai.FunctionCallingConfigModeAuto,
@@ -373,6 +633,64 @@ func buildGenerateContentConfig(
 	return config
 }
 
+// checkCachedContentSystemInstruction rejects system prompt contexts when
+// WithCachedContent is set, since the system instruction must live in the
+// cache. With IgnoreInvalidGeneratorOptions the instruction is dropped.
+func checkCachedContentSystemInstruction(cfg model.GeneratorConfig, systemInstruction *genai.Content, log logging.Logger) (*genai.Content, error) {
+	if systemInstruction == nil || strings.TrimSpace(cfg.CachedContent) == "" {
+		return systemInstruction, nil
+	}
+	if !cfg.IgnoreInvalidGeneratorOptions {
+		return nil, utils.WrapIfNotNil(errors.New("system prompt context is not supported with cached content; put the system instruction in the cache"))
+	}
+	if log != nil {
+		log.Warnf("ignoring system prompt context with cached content %q", cfg.CachedContent)
+	}
+	model.LogOptionResolution(log, providerName, "system_prompt_context", model.OptionActionDropped, "not supported with cached content")
+	return nil, nil
+}
+
+func normalizeGeneratorOptionsForProvider(cfg model.GeneratorConfig, log logging.Logger) (model.GeneratorConfig, error) {
+	cfg, err := model.ApplyGenerationLimits(cfg, providerName, resolveGenerationModelName(cfg), model.GenerationLimits{
+		MaxTemperature:  maxTemperature,
+		MaxOutputTokens: model.MaxOutputTokens(providerName, resolveGenerationModelName(cfg)),
+	}, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	return cfg, nil
+}
+
+func hasFunctionDeclarations(tools []*genai.Tool) bool {
+	for _, tool := range tools {
+		if tool != nil && len(tool.FunctionDeclarations) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// mapBuiltinTools maps provider-executed tools. BuiltinWebSearch becomes
+// Google Search grounding; other built-in tools are unsupported.
+func mapBuiltinTools(cfg model.GeneratorConfig, log logging.Logger) ([]*genai.Tool, error) {
+	out := make([]*genai.Tool, 0, len(cfg.BuiltinTools))
+	for _, tool := range cfg.BuiltinTools {
+		switch tool {
+		case model.BuiltinWebSearch:
+			out = append(out, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+		default:
+			if !cfg.IgnoreInvalidGeneratorOptions {
+				return nil, utils.WrapIfNotNil(fmt.Errorf("built-in tool %q is not supported for gemini provider", tool))
+			}
+			if log != nil {
+				log.Warnf("ignoring built-in tool %q for gemini provider", tool)
+			}
+			model.LogOptionResolution(log, providerName, "builtin_tools", model.OptionActionDropped, fmt.Sprintf("built-in tool %q is not supported", tool))
+		}
+	}
+	return out, nil
+}
+
 func mapReasoningLevel(level model.ReasoningLevel) genai.ThinkingLevel {
 	switch level {
 	case model.ReasoningLevelNone:
@@ -388,6 +706,32 @@ func mapReasoningLevel(level model.ReasoningLevel) genai.ThinkingLevel {
 	}
 }
 
+// repairStructuredOutput sends a structured output repair prompt as a new
+// single-turn request without tools, so the response schema can be enforced.
+// The cached content is not used since it may declare tools.
+func repairStructuredOutput(
+	ctx context.Context,
+	client *genai.Client,
+	modelName string,
+	schema map[string]any,
+	cfg model.GeneratorConfig,
+	prompt string,
+) (string, model.GenerationMetadata, error) {
+	meta := initMetadata(modelName)
+	cfg.CachedContent = ""
+	config := buildGenerateContentConfig(cfg, genai.NewContentFromText(model.StructuredRepairSystemPrompt, genai.RoleUser), nil)
+	config.ResponseMIMEType = "application/json"
+	config.ResponseJsonSchema = schema
+	contents := []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)}
+
+	response, totals, _, err := runGenerateFlow(ctx, client, modelName, contents, config, nil, cfg)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	applyGenerateMetadata(meta, response, totals)
+	return strings.TrimSpace(response.Text()), meta, nil
+}
+
 func runGenerateFlow(
 	ctx context.Context,
 	client *genai.Client,
@@ -395,58 +739,86 @@ func runGenerateFlow(
 	initialContents []*genai.Content,
 	config *genai.GenerateContentConfig,
 	handlers map[string]toolHandler,
-) (*genai.GenerateContentResponse, generationTotals, error) {
+	cfg model.GeneratorConfig,
+) (*genai.GenerateContentResponse, generationTotals, []*genai.Content, error) {
 	totals := generationTotals{}
 	history := append([]*genai.Content(nil), initialContents...)
 
 	response, configToUse, err := generateWithThinkingFallback(ctx, client, modelName, history, config)
 	if err != nil {
-		return nil, totals, utils.WrapIfNotNil(err)
+		return nil, totals, history, utils.WrapIfNotNil(err)
 	}
 	accumulateGenerationTotals(&totals, response)
 
-	for round := 0; round < maxToolRounds; round++ {
+	maxRounds := model.ResolveMaxToolRounds(cfg)
+	toolErrors := model.NewToolErrorPolicy(cfg)
+	log := logging.NewLogger(ctx)
+	for round := 0; round < maxRounds; round++ {
 		functionCalls := response.FunctionCalls()
 		if len(functionCalls) == 0 {
-			return response, totals, nil
+			if len(response.Candidates) > 0 && response.Candidates[0].Content != nil {
+				history = append(history, response.Candidates[0].Content)
+			}
+			return response, totals, history, nil
 		}
 		totals.ToolRounds = round + 1
 
+		callHandlers := make([]toolHandler, 0, len(functionCalls))
+		callArgs := make([]json.RawMessage, 0, len(functionCalls))
 		for _, call := range functionCalls {
 			handler, ok := handlers[call.Name]
 			if !ok {
-				return nil, totals, utils.WrapIfNotNil(
+				return nil, totals, history, utils.WrapIfNotNil(
 					fmt.Errorf("no tool handler configured for function %q", call.Name),
 				)
 			}
 
 			argsBytes, marshalErr := json.Marshal(call.Args)
 			if marshalErr != nil {
-				return nil, totals, utils.WrapIfNotNil(marshalErr)
+				return nil, totals, history, utils.WrapIfNotNil(marshalErr)
 			}
+			callHandlers = append(callHandlers, handler)
+			callArgs = append(callArgs, argsBytes)
+		}
 
-			result, callErr := handler(ctx, argsBytes)
-			if callErr != nil {
-				return nil, totals, utils.WrapIfNotNil(callErr)
+		results := toolexec.Run(ctx, len(functionCalls), model.ResolveToolParallelism(cfg), func(ctx context.Context, i int) (any, error) {
+			return callHandlers[i](ctx, callArgs[i])
+		})
+		totals.ToolCalls = append(totals.ToolCalls, toolexec.Transcript(round+1, results, func(i int) (string, string) {
+			return functionCalls[i].Name, functionCalls[i].ID
+		})...)
+
+		for i, call := range functionCalls {
+			toolOutput := map[string]any{"output": results[i].Value}
+			if callErr := results[i].Err; callErr != nil {
+				if abortErr := toolErrors.Handle(call.Name, callErr); abortErr != nil {
+					return nil, totals, history, utils.WrapIfNotNil(abortErr)
+				}
+				log.Warnf("tool %q failed, reporting to model: %v", call.Name, callErr)
+				toolOutput = model.ToolErrorResult(callErr)
 			}
 
 			history = append(history, genai.NewContentFromFunctionCall(call.Name, call.Args, genai.RoleModel))
 
-			toolOutput := map[string]any{"output": result}
 			if strings.TrimSpace(call.ID) != "" {
 				toolOutput["id"] = call.ID
 			}
 			history = append(history, genai.NewContentFromFunctionResponse(call.Name, toolOutput, genai.RoleUser))
 		}
+		if round+1 == maxRounds {
+			// Stop without sending the last allowed round's results, so a
+			// limit of N makes at most N API calls like the other providers.
+			break
+		}
 
 		response, _, err = generateWithThinkingFallback(ctx, client, modelName, history, configToUse)
 		if err != nil {
-			return nil, totals, utils.WrapIfNotNil(err)
+			return nil, totals, history, utils.WrapIfNotNil(err)
 		}
 		accumulateGenerationTotals(&totals, response)
 	}
 
-	return nil, totals, utils.WrapIfNotNil(fmt.Errorf("exceeded tool call loop limit (%d)", maxToolRounds))
+	return nil, totals, history, utils.WrapIfNotNil(&model.MaxToolRoundsError{Limit: maxRounds})
 }
 
 func generateWithThinkingFallback(
@@ -513,7 +885,7 @@ func mapTools(tools []model.Tool) ([]*genai.Tool, map[string]toolHandler, error)
 			Description:          tool.Description,
 			ParametersJsonSchema: parameters,
 		})
-		handlers[tool.Name] = tool.Handler
+		handlers[tool.Name] = tool.Call
 	}
 
 	return []*genai.Tool{
@@ -523,29 +895,6 @@ func mapTools(tools []model.Tool) ([]*genai.Tool, map[string]toolHandler, error)
 	}, handlers, nil
 }
 
-func generateJSONSchema[T any]() (map[string]any, error) {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
-
-	var value T
-	schema := reflector.Reflect(value)
-
-	schemaJSON, err := json.Marshal(schema)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
-
-	var schemaMap map[string]any
-	err = json.Unmarshal(schemaJSON, &schemaMap)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
-
-	return schemaMap, nil
-}
-
 func buildStructuredOutputInstruction(schema map[string]any) (string, error) {
 	schemaBytes, err := json.Marshal(schema)
 	if err != nil {
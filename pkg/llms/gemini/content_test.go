@@ -0,0 +1,312 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type ContentSuite struct {
+	suite.Suite
+}
+
+func TestContentSuite(t *testing.T) {
+	suite.Run(t, new(ContentSuite))
+}
+
+const groundedResponseJSON = `{
+  "responseId": "resp_1",
+  "candidates": [{
+    "content": {"role": "model", "parts": [
+      {"text": "Thinking about guidelines.", "thought": true},
+      {"text": "Intro. "},
+      {"text": "KDIGO 2024 updated CKD staging."}
+    ]},
+    "finishReason": "STOP",
+    "groundingMetadata": {
+      "webSearchQueries": ["kdigo 2024 ckd guideline"],
+      "groundingChunks": [
+        {"web": {"uri": "https://kdigo.org/guidelines/ckd", "title": "kdigo.org"}},
+        {"web": {"uri": "https://example.org/review", "title": "example.org"}}
+      ],
+      "groundingSupports": [
+        {"segment": {"partIndex": 2, "startIndex": 0, "endIndex": 31, "text": "KDIGO 2024 updated CKD staging."}, "groundingChunkIndices": [0, 7]}
+      ]
+    }
+  }],
+  "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 8, "toolUsePromptTokenCount": 30, "totalTokenCount": 50}
+}`
+
+func (s *ContentSuite) TestWebSearchEnablesGoogleSearchGrounding() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.True(strings.HasSuffix(r.URL.Path, "/models/gemini-test:generateContent"), r.URL.Path)
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(groundedResponseJSON))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator(
+		"What changed in the latest CKD guideline?",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gemini-test"),
+		model.WithBuiltinTools(model.BuiltinWebSearch),
+	)
+	s.Require().NoError(err)
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Intro. KDIGO 2024 updated CKD staging.", text)
+
+	s.Equal([]any{map[string]any{"googleSearch": map[string]any{}}}, body["tools"])
+	s.NotContains(body, "toolConfig")
+
+	s.Equal("1", meta[model.MetadataKeyWebSearchCalls])
+	s.Equal("42", meta[model.MetadataKeyInputTokens])
+	queries, err := model.ParseWebSearchQueries(meta)
+	s.Require().NoError(err)
+	s.Equal([]string{"kdigo 2024 ckd guideline"}, queries)
+
+	citations, err := model.ParseCitations(meta)
+	s.Require().NoError(err)
+	s.Equal([]model.Citation{
+		{URL: "https://kdigo.org/guidelines/ckd", Title: "kdigo.org", StartIndex: 7, EndIndex: 38},
+		{URL: "https://example.org/review", Title: "example.org"},
+	}, citations)
+	s.Equal("KDIGO 2024 updated CKD staging.", text[citations[0].StartIndex:citations[0].EndIndex])
+}
+
+func (s *ContentSuite) TestUnsupportedBuiltinToolIsRejected() {
+	_, err := mapBuiltinTools(model.ResolveGeneratorOpts(model.WithBuiltinTools(model.BuiltinCodeInterpreter)), nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), `built-in tool "code_interpreter" is not supported for gemini provider`)
+
+	tools, err := mapBuiltinTools(model.ResolveGeneratorOpts(
+		model.WithIgnoreInvalidGeneratorOptions(true),
+		model.WithBuiltinTools(model.BuiltinCodeInterpreter, model.BuiltinWebSearch),
+	), nil)
+	s.Require().NoError(err)
+	s.Require().Len(tools, 1)
+	s.NotNil(tools[0].GoogleSearch)
+}
+
+func (s *ContentSuite) TestProviderParamsAreMergedIntoBody() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gemini-test"),
+		model.WithMaxTokens(16),
+		model.WithProviderParams(map[string]any{"generationConfig": map[string]any{"topK": 3}}),
+	)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal(map[string]any{"maxOutputTokens": float64(16), "topK": float64(3)}, body["generationConfig"])
+}
+
+func (s *ContentSuite) TestCachedContentReferencedInRequest() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":4100,"cachedContentTokenCount":4000,"candidatesTokenCount":2,"totalTokenCount":4102}}`))
+	}))
+	defer server.Close()
+
+	lookup := model.Tool{
+		Name:        "lookup_labs",
+		Description: "Look up recent labs",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return map[string]any{"egfr": 48}, nil
+		},
+	}
+	gen, err := NewStringContentGenerator("Summarize the chart.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gemini-test"),
+		model.WithTools([]model.Tool{lookup}),
+		model.WithCachedContent(" cachedContents/chart-1 "),
+	)
+	s.Require().NoError(err)
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal("cachedContents/chart-1", body["cachedContent"])
+	s.NotContains(body, "systemInstruction")
+	s.NotContains(body, "tools")
+	s.Equal("4000", meta[model.MetadataKeyCachedInputTokens])
+}
+
+func (s *ContentSuite) TestToolRoundsAreRecordedInToolCalls() {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("content-type", "application/json")
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[
+				{"functionCall":{"id":"fc_1","name":"lookup_labs","args":{}}},
+				{"functionCall":{"id":"fc_2","name":"lookup_meds","args":{}}}
+			]},"finishReason":"STOP"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"eGFR 48."}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	labs := model.Tool{Name: "lookup_labs", Description: "Look up recent labs", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return map[string]any{"egfr": 48}, nil
+	}}
+	meds := model.Tool{Name: "lookup_meds", Description: "Look up medications", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return nil, errors.New("pharmacy offline")
+	}}
+	gen, err := NewStringContentGenerator("Summarize the chart.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gemini-test"),
+		model.WithTools([]model.Tool{labs, meds}),
+	)
+	s.Require().NoError(err)
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	toolCalls, err := model.ParseToolCalls(meta)
+	s.Require().NoError(err)
+	s.Require().Len(toolCalls, 2)
+	s.Equal(model.ToolCallRecord{Round: 1, Index: 0, Name: "lookup_labs", CallID: "fc_1", DurationMs: toolCalls[0].DurationMs}, toolCalls[0])
+	s.Equal(1, toolCalls[1].Round)
+	s.Equal(1, toolCalls[1].Index)
+	s.Equal("lookup_meds", toolCalls[1].Name)
+	s.Equal("fc_2", toolCalls[1].CallID)
+	s.Contains(toolCalls[1].Error, "pharmacy offline")
+}
+
+func (s *ContentSuite) TestCachedContentRejectsSystemContext() {
+	gen, err := NewStringContentGenerator("Summarize the chart.",
+		model.WithAuthToken("key"),
+		model.WithModel("gemini-test"),
+		model.WithCachedContent("cachedContents/chart-1"),
+	)
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "You are a nephrologist.")
+
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "system prompt context is not supported with cached content")
+}
+
+func (s *ContentSuite) TestCachedContentManagerLifecycle() {
+	var created map[string]any
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("content-type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			s.Require().NoError(json.NewDecoder(r.Body).Decode(&created))
+			_, _ = w.Write([]byte(`{"name":"cachedContents/chart-1","displayName":"chart","model":"models/gemini-test",
+				"createTime":"2026-01-02T03:04:05Z","expireTime":"2026-01-02T04:04:05Z","usageMetadata":{"totalTokenCount":4000}}`))
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"cachedContents":[{"name":"cachedContents/chart-1","displayName":"chart"}]}`))
+		case http.MethodDelete:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewCachedContentManager(context.Background(),
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gemini-test"))
+	s.Require().NoError(err)
+
+	_, err = manager.Create(context.Background(), CachedContentSpec{})
+	s.Require().Error(err)
+
+	entry, err := manager.Create(context.Background(), CachedContentSpec{
+		DisplayName: "chart",
+		TTL:         time.Hour,
+		Contexts: []*model.PromptContext{
+			{MessageType: model.ContextMessageTypeSystem, Content: "You are a nephrologist."},
+			{MessageType: model.ContextMessageTypeHuman, Content: "Chart: eGFR 48."},
+		},
+	})
+	s.Require().NoError(err)
+	s.Equal("cachedContents/chart-1", entry.Name)
+	s.Equal(4000, entry.TotalTokens)
+	s.Equal(time.Date(2026, 1, 2, 4, 4, 5, 0, time.UTC), entry.ExpireTime.UTC())
+
+	s.Equal("models/gemini-test", created["model"])
+	s.Equal("3600s", created["ttl"])
+	s.Equal("chart", created["displayName"])
+	s.Contains(created, "systemInstruction")
+	s.Len(created["contents"], 1)
+
+	entries, err := manager.List(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]CachedContent{{Name: "cachedContents/chart-1", DisplayName: "chart"}}, entries)
+
+	s.Require().NoError(manager.Delete(context.Background(), "cachedContents/chart-1"))
+
+	s.Require().Len(paths, 3)
+	for _, path := range paths {
+		s.Contains(path, "cachedContents")
+	}
+}
+
+func (s *ContentSuite) TestMapPromptContextsWithImages() {
+	_, contents, contextCount, err := mapPromptContexts([]*model.PromptContext{
+		model.ImagePromptContext("", model.ImagePart{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")}, model.ImagePart{URL: "https://example.org/xray.jpg", MIMEType: "image/jpeg"}),
+	})
+	s.Require().NoError(err)
+	s.Equal(1, contextCount)
+	s.Require().Len(contents, 1)
+	s.Equal("user", contents[0].Role)
+	s.Require().Len(contents[0].Parts, 2)
+	s.Equal("image/png", contents[0].Parts[0].InlineData.MIMEType)
+	s.Equal([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), contents[0].Parts[0].InlineData.Data)
+	s.Equal("https://example.org/xray.jpg", contents[0].Parts[1].FileData.FileURI)
+
+	_, _, _, err = mapPromptContexts([]*model.PromptContext{
+		model.ImagePromptContext("", model.ImagePart{URL: "https://example.org/xray"}),
+	})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "needs a mime type for gemini provider")
+}
+
+func (s *ContentSuite) TestAttachDocumentsAddsFileParts() {
+	_, contents, _, err := buildContentsWithContext("Summarize the attachments.", nil)
+	s.Require().NoError(err)
+	contents, err = attachDocuments(contents, []model.DocumentPart{
+		{Name: "labs.pdf", Data: []byte("%PDF-1.7")},
+		{URL: "gs://bucket/discharge.pdf"},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(contents, 1)
+	s.Equal("user", contents[0].Role)
+	s.Require().Len(contents[0].Parts, 3)
+	s.Equal("application/pdf", contents[0].Parts[0].InlineData.MIMEType)
+	s.Equal([]byte("%PDF-1.7"), contents[0].Parts[0].InlineData.Data)
+	s.Equal("gs://bucket/discharge.pdf", contents[0].Parts[1].FileData.FileURI)
+	s.Equal("Summarize the attachments.", contents[0].Parts[2].Text)
+
+	_, err = attachDocuments(contents, []model.DocumentPart{{Name: "letter.docx", Data: []byte("PK\x03\x04")}})
+	s.Error(err)
+}
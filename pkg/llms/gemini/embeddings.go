@@ -54,8 +54,14 @@ func (g *embeddingGenerator) GenerateBatch(
 		return nil, meta, utils.WrapIfNotNil(err)
 	}
 
-	if g.cfg.EmbeddingDimensions != nil && *g.cfg.EmbeddingDimensions <= 0 {
-		err = errors.New("embedding dimensions must be greater than zero")
+	dimensions, err := model.ResolveEmbeddingDimensions(g.cfg, providerName, modelName, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
+
+	taskType, title, err := resolveEmbeddingTask(g.cfg, log)
+	if err != nil {
 		log.Errorf("error: %v", err)
 		return nil, meta, utils.WrapIfNotNil(err)
 	}
@@ -71,17 +77,21 @@ func (g *embeddingGenerator) GenerateBatch(
 		contents = append(contents, genai.NewContentFromText(input, genai.RoleUser))
 	}
 
-	config := &genai.EmbedContentConfig{}
-	if g.cfg.EmbeddingDimensions != nil {
-		dims := int32(*g.cfg.EmbeddingDimensions)
+	config := &genai.EmbedContentConfig{
+		TaskType: string(taskType),
+		Title:    title,
+	}
+	if dimensions != nil {
+		dims := int32(*dimensions)
 		config.OutputDimensionality = &dims
 	}
 
 	log.Infof(
-		"embedding_request inputs=%d model=%q dimensions=%v",
+		"embedding_request inputs=%d model=%q dimensions=%v task_type=%q",
 		len(inputs),
 		modelName,
-		g.cfg.EmbeddingDimensions,
+		dimensions,
+		taskType,
 	)
 
 	response, err := client.Models.EmbedContent(ctx, modelName, contents, config)
@@ -100,6 +110,28 @@ func (g *embeddingGenerator) GenerateBatch(
 	return vectors, meta, nil
 }
 
+// resolveEmbeddingTask defaults the task type to retrieval document when only
+// a title is set, as the API accepts a title for no other task type.
+func resolveEmbeddingTask(cfg model.GeneratorConfig, log logging.Logger) (model.EmbeddingTaskType, string, error) {
+	taskType := model.EmbeddingTaskType(strings.ToUpper(strings.TrimSpace(string(cfg.EmbeddingTaskType))))
+	title := strings.TrimSpace(cfg.EmbeddingTitle)
+	if title == "" {
+		return taskType, "", nil
+	}
+	if taskType == "" {
+		return model.EmbeddingTaskRetrievalDocument, title, nil
+	}
+	if taskType != model.EmbeddingTaskRetrievalDocument {
+		err := fmt.Errorf("embedding title requires task type %s, got %s", model.EmbeddingTaskRetrievalDocument, taskType)
+		if !cfg.IgnoreInvalidGeneratorOptions {
+			return "", "", utils.WrapIfNotNil(err)
+		}
+		log.Warnf("ignoring embedding title: %v", err)
+		return taskType, "", nil
+	}
+	return taskType, title, nil
+}
+
 func validateEmbeddingInputs(inputs []string) error {
 	if len(inputs) == 0 {
 		return utils.WrapIfNotNil(errors.New("at least one input is required"))
@@ -0,0 +1,74 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbeddingsSuite struct {
+	suite.Suite
+}
+
+func TestEmbeddingsSuite(t *testing.T) {
+	suite.Run(t, new(EmbeddingsSuite))
+}
+
+func (s *EmbeddingsSuite) TestTaskTypeAndTitleAreSent() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.True(strings.HasSuffix(r.URL.Path, "/models/embed-test:batchEmbedContents"), r.URL.Path)
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.5,0.25]}]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewEmbeddingGenerator(
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("embed-test"),
+		model.WithEmbeddingTitle("CKD staging guideline"),
+	)
+	s.Require().NoError(err)
+	vector, meta, err := gen.Generate(context.Background(), "eGFR categories G1 to G5")
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVector{0.5, 0.25}, vector)
+	s.Equal("2", meta[model.MetadataKeyEmbeddingDims])
+
+	requests, ok := body["requests"].([]any)
+	s.Require().True(ok, body)
+	s.Require().Len(requests, 1)
+	request := requests[0].(map[string]any)
+	s.Equal("RETRIEVAL_DOCUMENT", request["taskType"])
+	s.Equal("CKD staging guideline", request["title"])
+
+	gen, err = NewEmbeddingGenerator(
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("embed-test"),
+		model.WithEmbeddingTaskType(model.EmbeddingTaskRetrievalQuery),
+	)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background(), "what is stage G3a?")
+	s.Require().NoError(err)
+	request = body["requests"].([]any)[0].(map[string]any)
+	s.Equal("RETRIEVAL_QUERY", request["taskType"])
+	s.NotContains(request, "title")
+}
+
+func (s *EmbeddingsSuite) TestTitleRequiresRetrievalDocumentTask() {
+	opts := []model.GeneratorOption{
+		model.WithEmbeddingTaskType(model.EmbeddingTaskClassification),
+		model.WithEmbeddingTitle("Guideline"),
+	}
+	_, _, err := resolveEmbeddingTask(model.ResolveGeneratorOpts(opts...), nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "embedding title requires task type RETRIEVAL_DOCUMENT")
+}
@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"google.golang.org/genai"
+)
+
+// buildConversationHistory flattens Gemini contents into the provider-neutral
+// format: functionCall parts become tool calls on the assistant turn and each
+// functionResponse part becomes its own tool message. Thought parts are left
+// out.
+func buildConversationHistory(modelName string, systemInstruction *genai.Content, contents []*genai.Content) model.ConversationHistory {
+	history := model.ConversationHistory{
+		Version:  model.ConversationHistoryVersion,
+		Provider: providerName,
+		Model:    modelName,
+		Messages: make([]model.HistoryMessage, 0, len(contents)+1),
+	}
+	if system := contentText(systemInstruction); strings.TrimSpace(system) != "" {
+		history.Messages = append(history.Messages, model.HistoryMessage{Role: model.HistoryRoleSystem, Content: system})
+	}
+
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		entry := model.HistoryMessage{Role: model.HistoryRoleUser}
+		if content.Role == genai.RoleModel {
+			entry.Role = model.HistoryRoleAssistant
+		}
+		for _, part := range content.Parts {
+			if part == nil || part.FunctionCall == nil {
+				continue
+			}
+			arguments, _ := json.Marshal(part.FunctionCall.Args)
+			entry.ToolCalls = append(entry.ToolCalls, model.HistoryToolCall{
+				ID:        part.FunctionCall.ID,
+				Name:      part.FunctionCall.Name,
+				Arguments: arguments,
+			})
+		}
+		for _, part := range content.Parts {
+			if part == nil || part.FunctionResponse == nil {
+				continue
+			}
+			response := part.FunctionResponse
+			callID := response.ID
+			if callID == "" {
+				callID, _ = response.Response["id"].(string)
+			}
+			output, _ := json.Marshal(response.Response)
+			history.Messages = append(history.Messages, model.HistoryMessage{
+				Role:       model.HistoryRoleTool,
+				Content:    string(output),
+				ToolCallID: callID,
+				ToolName:   response.Name,
+			})
+		}
+
+		entry.Content = contentText(content)
+		if entry.Content == "" && len(entry.ToolCalls) == 0 {
+			continue
+		}
+		history.Messages = append(history.Messages, entry)
+	}
+	return history
+}
+
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	texts := make([]string, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		if part != nil && part.Text != "" && !part.Thought {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,206 @@
+package gemini
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"google.golang.org/genai"
+)
+
+const (
+	defaultSpeechModelName = "gemini-2.5-flash-preview-tts"
+	defaultSpeechVoice     = "Kore"
+	// defaultSpeechSampleRate is used when the response MIME type has no rate.
+	defaultSpeechSampleRate = 24000
+)
+
+type speechGenerator struct {
+	text string
+	opts model.SpeechOptions
+	cfg  model.GeneratorConfig
+}
+
+func NewSpeechGenerator(text string, opts model.SpeechOptions) (model.SpeechGenerator, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("text is required"))
+	}
+
+	cfg := model.GeneratorConfig{
+		IgnoreInvalidGeneratorOptions: opts.IgnoreInvalidGeneratorOptions,
+		URL:                           opts.URL,
+		AuthToken:                     opts.AuthToken,
+		TLSConfig:                     opts.TLSConfig,
+		GCPProject:                    opts.GCPProject,
+		GCPLocation:                   opts.GCPLocation,
+	}
+	return &speechGenerator{text: text, opts: opts, cfg: cfg}, nil
+}
+
+func (g *speechGenerator) Generate(ctx context.Context) (model.SpeechAudio, model.GenerationMetadata, error) {
+	start := time.Now()
+	log := logging.NewLogger(ctx)
+	modelName := resolveSpeechModelName(g.opts)
+	meta := initMetadata(modelName)
+	defer setLatencyMetadata(meta, start)
+
+	opts, err := normalizeSpeechOptions(g.opts, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	client, err := newAPIClient(ctx, g.cfg)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	response, err := client.Models.GenerateContent(ctx, modelName, genai.Text(buildSpeechPrompt(g.text, opts)), buildSpeechConfig(opts))
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+	applyAudioTranscriptionMetadata(meta, response)
+	meta[model.MetadataKeyAPICalls] = "1"
+	meta[model.MetadataKeyInputCharacters] = strconv.Itoa(len([]rune(g.text)))
+
+	samples, mimeType := extractSpeechAudio(response)
+	if len(samples) == 0 {
+		err = errors.New("speech response is empty")
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	format := resolveSpeechFormat(opts)
+	if format == model.SpeechFormatWAV {
+		samples = wrapPCMAsWAV(samples, speechSampleRate(mimeType))
+	}
+	return model.SpeechAudio{Data: samples, Format: format, MIMEType: format.MIMEType()}, meta, nil
+}
+
+func buildSpeechPrompt(text string, opts model.SpeechOptions) string {
+	if instructions := strings.TrimSpace(opts.Instructions); instructions != "" {
+		return strings.TrimSuffix(instructions, ":") + ": " + text
+	}
+	return text
+}
+
+func buildSpeechConfig(opts model.SpeechOptions) *genai.GenerateContentConfig {
+	return &genai.GenerateContentConfig{
+		ResponseModalities: []string{string(genai.ModalityAudio)},
+		SpeechConfig: &genai.SpeechConfig{
+			VoiceConfig: &genai.VoiceConfig{
+				PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{VoiceName: resolveSpeechVoice(opts)},
+			},
+		},
+	}
+}
+
+// normalizeSpeechOptions rejects options Gemini TTS does not support: speed
+// and formats other than wav and pcm.
+func normalizeSpeechOptions(opts model.SpeechOptions, log logging.Logger) (model.SpeechOptions, error) {
+	if opts.Speed != nil {
+		if !opts.IgnoreInvalidGeneratorOptions {
+			return opts, utils.WrapIfNotNil(errors.New("speech speed is not supported for gemini provider; describe the pace in instructions"))
+		}
+		if log != nil {
+			log.Warnf("ignoring speech speed for gemini provider")
+		}
+		opts.Speed = nil
+	}
+	switch resolveSpeechFormat(opts) {
+	case model.SpeechFormatWAV, model.SpeechFormatPCM:
+	default:
+		if !opts.IgnoreInvalidGeneratorOptions {
+			return opts, utils.WrapIfNotNil(errors.New("speech format " + string(opts.Format) + " is not supported for gemini provider; use wav or pcm"))
+		}
+		if log != nil {
+			log.Warnf("ignoring speech format %q for gemini provider", opts.Format)
+		}
+		opts.Format = ""
+	}
+	return opts, nil
+}
+
+func extractSpeechAudio(response *genai.GenerateContentResponse) ([]byte, string) {
+	if response == nil {
+		return nil, ""
+	}
+	for _, candidate := range response.Candidates {
+		if candidate == nil || candidate.Content == nil {
+			continue
+		}
+		for _, part := range candidate.Content.Parts {
+			if part != nil && part.InlineData != nil && len(part.InlineData.Data) > 0 {
+				return part.InlineData.Data, part.InlineData.MIMEType
+			}
+		}
+	}
+	return nil, ""
+}
+
+// speechSampleRate reads the rate parameter of a MIME type such as
+// "audio/L16;codec=pcm;rate=24000".
+func speechSampleRate(mimeType string) int {
+	for _, parameter := range strings.Split(mimeType, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(parameter), "=")
+		if !ok || !strings.EqualFold(key, "rate") {
+			continue
+		}
+		if rate, err := strconv.Atoi(value); err == nil && rate > 0 {
+			return rate
+		}
+	}
+	return defaultSpeechSampleRate
+}
+
+// wrapPCMAsWAV prefixes 16-bit mono little-endian samples with a WAV header.
+func wrapPCMAsWAV(samples []byte, sampleRate int) []byte {
+	const (
+		channels      = 1
+		bitsPerSample = 16
+	)
+	blockAlign := channels * bitsPerSample / 8
+	out := make([]byte, 44, 44+len(samples))
+	copy(out[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(out[4:8], uint32(36+len(samples)))
+	copy(out[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(out[16:20], 16)
+	binary.LittleEndian.PutUint16(out[20:22], 1)
+	binary.LittleEndian.PutUint16(out[22:24], channels)
+	binary.LittleEndian.PutUint32(out[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(out[28:32], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(out[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(out[34:36], bitsPerSample)
+	copy(out[36:40], "data")
+	binary.LittleEndian.PutUint32(out[40:44], uint32(len(samples)))
+	return append(out, samples...)
+}
+
+func resolveSpeechModelName(opts model.SpeechOptions) string {
+	if modelName := strings.TrimSpace(opts.Model); modelName != "" {
+		return modelName
+	}
+	return defaultSpeechModelName
+}
+
+func resolveSpeechVoice(opts model.SpeechOptions) string {
+	if voice := strings.TrimSpace(opts.Voice); voice != "" {
+		return voice
+	}
+	return defaultSpeechVoice
+}
+
+func resolveSpeechFormat(opts model.SpeechOptions) model.SpeechFormat {
+	if opts.Format != "" {
+		return opts.Format
+	}
+	return model.SpeechFormatWAV
+}
@@ -0,0 +1,76 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type SpeechGeneratorSuite struct {
+	suite.Suite
+}
+
+func TestSpeechGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(SpeechGeneratorSuite))
+}
+
+func (s *SpeechGeneratorSuite) TestGenerateRequestsAudioAndWrapsWAV() {
+	samples := []byte{0x01, 0x02, 0x03, 0x04}
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.True(strings.HasSuffix(r.URL.Path, "/models/gemini-tts:generateContent"), r.URL.Path)
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=16000","data":"` +
+			base64.StdEncoding.EncodeToString(samples) + `"}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":20,"totalTokenCount":25}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewSpeechGenerator("Your results are ready.", model.SpeechOptions{
+		URL: server.URL, AuthToken: "key", Model: "gemini-tts", Voice: "Puck", Instructions: "Say calmly:",
+	})
+	s.Require().NoError(err)
+
+	audio, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal(model.SpeechFormatWAV, audio.Format)
+	s.Equal("audio/wav", audio.MIMEType)
+	s.Require().Len(audio.Data, 44+len(samples))
+	s.Equal("RIFF", string(audio.Data[0:4]))
+	s.Equal(uint32(16000), binary.LittleEndian.Uint32(audio.Data[24:28]))
+	s.Equal(samples, audio.Data[44:])
+	s.Equal("25", meta[model.MetadataKeyTotalTokens])
+	s.Equal("23", meta[model.MetadataKeyInputCharacters])
+
+	config := body["generationConfig"].(map[string]any)
+	s.Equal([]any{"AUDIO"}, config["responseModalities"])
+	s.Equal("Puck", config["speechConfig"].(map[string]any)["voiceConfig"].(map[string]any)["prebuiltVoiceConfig"].(map[string]any)["voiceName"])
+	contents := body["contents"].([]any)
+	text := contents[0].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"]
+	s.Equal("Say calmly: Your results are ready.", text)
+}
+
+func (s *SpeechGeneratorSuite) TestUnsupportedOptionsAreRejectedUnlessIgnored() {
+	speed := 1.5
+	_, err := normalizeSpeechOptions(model.SpeechOptions{Format: model.SpeechFormatMP3}, nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "use wav or pcm")
+	_, err = normalizeSpeechOptions(model.SpeechOptions{Speed: &speed}, nil)
+	s.Error(err)
+
+	opts, err := normalizeSpeechOptions(model.SpeechOptions{IgnoreInvalidGeneratorOptions: true, Format: model.SpeechFormatMP3, Speed: &speed}, nil)
+	s.Require().NoError(err)
+	s.Nil(opts.Speed)
+	s.Equal(model.SpeechFormatWAV, resolveSpeechFormat(opts))
+
+	_, err = NewSpeechGenerator("  ", model.SpeechOptions{})
+	s.Error(err)
+}
@@ -0,0 +1,158 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/schema"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"google.golang.org/genai"
+)
+
+type verboseTranscriptionGenerator struct {
+	filePath string
+	opts     model.AudioOptions
+	cfg      model.GeneratorConfig
+}
+
+// geminiTranscript is the JSON shape requested from the model. Gemini has no
+// timestamp API, so timings are generated by the model and are approximate.
+type geminiTranscript struct {
+	Text     string                    `json:"text"`
+	Segments []geminiTranscriptSegment `json:"segments"`
+	Words    []geminiTranscriptWord    `json:"words,omitempty"`
+}
+
+type geminiTranscriptSegment struct {
+	Start   float64 `json:"start" jsonschema:"description=Start time in seconds"`
+	End     float64 `json:"end" jsonschema:"description=End time in seconds"`
+	Text    string  `json:"text"`
+	Speaker string  `json:"speaker,omitempty" jsonschema:"description=Speaker label such as Speaker 1 when speakers are requested"`
+}
+
+type geminiTranscriptWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start" jsonschema:"description=Start time in seconds"`
+	End   float64 `json:"end" jsonschema:"description=End time in seconds"`
+}
+
+// NewVerboseTranscriptionGenerator returns a generator for timed
+// transcripts. Timings are produced by the model and are approximate, and
+// segments carry no confidence.
+func NewVerboseTranscriptionGenerator(
+	filePath string,
+	opts model.AudioOptions,
+) (model.VerboseTranscriptionGenerator, error) {
+	if strings.TrimSpace(filePath) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("file path is required"))
+	}
+
+	return &verboseTranscriptionGenerator{
+		filePath: filePath,
+		opts:     cloneAudioOptions(opts),
+		cfg:      audioGeneratorConfigFromOptions(opts),
+	}, nil
+}
+
+func (g *verboseTranscriptionGenerator) Generate(ctx context.Context) (model.TranscriptionResult, model.GenerationMetadata, error) {
+	start := time.Now()
+	modelName := resolveAudioTranscriptionModelName(g.opts)
+	meta := initMetadata(modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log := logging.NewLogger(ctx)
+	audioPart, err := loadAudioPart(g.filePath)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	client, err := newAPIClient(ctx, g.cfg)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	prompt, err := buildVerboseTranscriptionPrompt(g.opts)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+	transcriptSchema, err := schema.Reflect[geminiTranscript](schema.Options{})
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+	contents := []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{genai.NewPartFromText(prompt), audioPart}, genai.RoleUser),
+	}
+
+	response, err := client.Models.GenerateContent(ctx, modelName, contents, &genai.GenerateContentConfig{
+		ResponseMIMEType:   "application/json",
+		ResponseJsonSchema: transcriptSchema,
+	})
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+	applyAudioTranscriptionMetadata(meta, response)
+
+	var transcript geminiTranscript
+	if err = json.Unmarshal([]byte(response.Text()), &transcript); err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+	if strings.TrimSpace(transcript.Text) == "" {
+		err = errors.New("transcription response is empty")
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	return model.FinishTranscriptionResult(meta, g.opts, toTranscriptionResult(transcript, g.opts)), meta, nil
+}
+
+func buildVerboseTranscriptionPrompt(opts model.AudioOptions) (string, error) {
+	prompt, err := buildAudioTranscriptionPrompt(opts)
+	if err != nil {
+		return "", err
+	}
+	prompt += "\nReturn the full transcript as text and split it into segments of one sentence or phrase, each with start and end times in seconds from the beginning of the audio."
+	if opts.Diarize {
+		prompt += " Start a new segment at every change of speaker and label each segment's speaker as \"Speaker 1\", \"Speaker 2\" and so on, in order of first appearance."
+	}
+	if opts.WantsTimestamps(model.TimestampGranularityWord) {
+		prompt += " Also list every spoken word in order with its start and end times in seconds."
+	}
+	return prompt, nil
+}
+
+func toTranscriptionResult(transcript geminiTranscript, opts model.AudioOptions) model.TranscriptionResult {
+	result := model.TranscriptionResult{Text: transcript.Text}
+	for i, segment := range transcript.Segments {
+		result.Segments = append(result.Segments, model.TranscriptSegment{
+			ID:    i,
+			Start: model.SecondsToDuration(segment.Start),
+			End:   model.SecondsToDuration(segment.End),
+			Text:  strings.TrimSpace(segment.Text),
+		})
+		if opts.Diarize {
+			result.Segments[i].Speaker = strings.TrimSpace(segment.Speaker)
+		}
+	}
+	if !opts.WantsTimestamps(model.TimestampGranularityWord) {
+		return result
+	}
+	for _, word := range transcript.Words {
+		result.Words = append(result.Words, model.TranscriptWord{
+			Word:  strings.TrimSpace(word.Word),
+			Start: model.SecondsToDuration(word.Start),
+			End:   model.SecondsToDuration(word.End),
+		})
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerboseTranscriptionGeneratorSuite struct {
+	suite.Suite
+}
+
+func TestVerboseTranscriptionGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(VerboseTranscriptionGeneratorSuite))
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestGenerateRequestsTimedJSON() {
+	transcript := `{"text":"Swelling is better. Continue furosemide.","segments":[{"start":0,"end":1.5,"text":"Swelling is better."},{"start":1.5,"end":3.2,"text":"Continue furosemide."}],"words":[{"word":"Swelling","start":0,"end":0.5}]}`
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":` + strconv.Quote(transcript) + `}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":30,"totalTokenCount":80}}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(s.T().TempDir(), "visit.mp3")
+	s.Require().NoError(os.WriteFile(path, []byte("ID3-audio"), 0o600))
+	gen, err := NewVerboseTranscriptionGenerator(path, model.AudioOptions{URL: server.URL, AuthToken: "key", Language: "en"})
+	s.Require().NoError(err)
+
+	result, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Swelling is better. Continue furosemide.", result.Text)
+	s.Equal("en", result.Language)
+	s.Equal(3200*time.Millisecond, result.Duration)
+	s.Equal([]model.TranscriptSegment{
+		{ID: 0, Start: 0, End: 1500 * time.Millisecond, Text: "Swelling is better."},
+		{ID: 1, Start: 1500 * time.Millisecond, End: 3200 * time.Millisecond, Text: "Continue furosemide."},
+	}, result.Segments)
+	s.Nil(result.Words, "words are only returned when requested")
+	s.Equal("80", meta[model.MetadataKeyTotalTokens])
+
+	config := body["generationConfig"].(map[string]any)
+	s.Equal("application/json", config["responseMimeType"])
+	s.NotNil(config["responseJsonSchema"])
+	prompt := body["contents"].([]any)[0].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"].(string)
+	s.Contains(prompt, "start and end times in seconds")
+	s.NotContains(prompt, "every spoken word")
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestWordTimestampsAreRequested() {
+	opts := model.AudioOptions{TimestampGranularities: []model.TimestampGranularity{"WORD"}}
+	prompt, err := buildVerboseTranscriptionPrompt(opts)
+	s.Require().NoError(err)
+	s.Contains(prompt, "every spoken word")
+
+	result := toTranscriptionResult(geminiTranscript{Text: "Hi", Words: []geminiTranscriptWord{{Word: " Hi ", Start: 0.25, End: 0.5}}}, opts)
+	s.Equal([]model.TranscriptWord{{Word: "Hi", Start: 250 * time.Millisecond, End: 500 * time.Millisecond}}, result.Words)
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestDiarizeLabelsSegments() {
+	opts := model.AudioOptions{Diarize: true}
+	prompt, err := buildVerboseTranscriptionPrompt(opts)
+	s.Require().NoError(err)
+	s.Contains(prompt, "identify the speakers")
+	s.Contains(prompt, "\"Speaker 1\"")
+
+	result := toTranscriptionResult(geminiTranscript{Text: "Hi. Hello.", Segments: []geminiTranscriptSegment{
+		{Start: 0, End: 1, Text: "Hi.", Speaker: "Speaker 1"},
+		{Start: 1, End: 2, Text: "Hello.", Speaker: " Speaker 2 "},
+	}}, opts)
+	s.Equal([]string{"Speaker 1", "Speaker 2"}, result.Speakers())
+
+	result = toTranscriptionResult(geminiTranscript{Segments: []geminiTranscriptSegment{{Text: "Hi.", Speaker: "Speaker 1"}}}, model.AudioOptions{})
+	s.Empty(result.Segments[0].Speaker, "speakers are only kept when requested")
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestSubtitleOutputIsRenderedFromSegments() {
+	transcript := `{"text":"Swelling is better.","segments":[{"start":0,"end":1.5,"text":"Swelling is better."}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":` + strconv.Quote(transcript) + `}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(s.T().TempDir(), "visit.mp3")
+	s.Require().NoError(os.WriteFile(path, []byte("ID3-audio"), 0o600))
+	gen, err := NewAudioTranscriptionGenerator(path, model.AudioOptions{URL: server.URL, AuthToken: "key", OutputFormat: model.TranscriptFormatSRT})
+	s.Require().NoError(err)
+
+	subtitles, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("1\n00:00:00,000 --> 00:00:01,500\nSwelling is better.\n\n", subtitles)
+}
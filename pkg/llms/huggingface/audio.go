@@ -14,3 +14,9 @@ func NewAudioTranscriptionGenerator(filePath string, opts model.AudioOptions) (m
 	_ = opts
 	return nil, utils.WrapIfNotNil(errors.New(unsupportedAudioMessage))
 }
+
+func NewVerboseTranscriptionGenerator(filePath string, opts model.AudioOptions) (model.VerboseTranscriptionGenerator, error) {
+	_ = filePath
+	_ = opts
+	return nil, utils.WrapIfNotNil(errors.New(unsupportedAudioMessage))
+}
@@ -1,14 +1,13 @@
 package huggingface
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -24,7 +23,7 @@ const (
 	defaultEmbeddingModelName = "BAAI/bge-base-en-v1.5"
 	defaultBaseURL            = "https://router.huggingface.co"
 	defaultMaxTokens          = 1024
-	maxToolRounds             = 12
+	maxTemperature            = 2.0
 	defaultHTTPTimeout        = 90 * time.Second
 	envHFToken                = "HF_TOKEN"
 	envHFBaseURL              = "HF_BASE_URL"
@@ -35,14 +34,23 @@ type apiClient struct {
 	httpClient *http.Client
 	baseURL    string
 	apiKey     string
+
+	// gatewayHeaders are added to every request (see model.WithGateway).
+	gatewayHeaders map[string]string
+	retryPolicy    model.RetryPolicy
+	// compressMinBytes is the body size from which chat requests are gzipped;
+	// 0 disables compression (see model.WithRequestCompression).
+	compressMinBytes int
 }
 
 type flowUsageTotals struct {
 	APICalls     int
 	ToolRounds   int
+	ToolCalls    []model.ToolCallRecord
 	InputTokens  int64
 	OutputTokens int64
 	TotalTokens  int64
+	Gateway      model.GatewayTotals
 }
 
 type chatMessage struct {
@@ -80,6 +88,12 @@ type chatCompletionRequest struct {
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	Temperature *float64      `json:"temperature,omitempty"`
 	Tools       []chatTool    `json:"tools,omitempty"`
+
+	// ProviderParams are merged into the encoded body (see model.WithProviderParams).
+	ProviderParams map[string]any `json:"-"`
+	// WaitForModel asks the router to hold the request while a cold model
+	// loads instead of answering 503.
+	WaitForModel bool `json:"-"`
 }
 
 type chatCompletionResponse struct {
@@ -87,6 +101,9 @@ type chatCompletionResponse struct {
 	Model   string                 `json:"model"`
 	Choices []chatCompletionChoice `json:"choices"`
 	Usage   *chatCompletionUsage   `json:"usage"`
+
+	// Header holds the HTTP response headers, for gateway metadata.
+	Header http.Header `json:"-"`
 }
 
 type chatCompletionChoice struct {
@@ -101,6 +118,23 @@ type chatCompletionUsage struct {
 	TotalTokens      int64 `json:"total_tokens"`
 }
 
+// apiError is a non-2xx chat completion response.
+type apiError struct {
+	StatusCode int
+	Message    string
+	// EstimatedTime is how long a loading model still needs, when reported.
+	EstimatedTime time.Duration
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("huggingface API error (%d): %s", e.StatusCode, e.Message)
+}
+
+func (e *apiError) modelLoading() bool {
+	return e.StatusCode == http.StatusServiceUnavailable &&
+		(e.EstimatedTime > 0 || strings.Contains(strings.ToLower(e.Message), "loading"))
+}
+
 type chatCompletionErrorResponse struct {
 	Error struct {
 		Message string `json:"message"`
@@ -109,41 +143,82 @@ type chatCompletionErrorResponse struct {
 }
 
 func newAPIClient(cfg model.GeneratorConfig) (*apiClient, error) {
-	apiKey := strings.TrimSpace(cfg.AuthToken)
-	if apiKey == "" {
-		apiKey = strings.TrimSpace(os.Getenv(envHFToken))
-	}
+	log := logging.NewLogger(context.Background())
+	apiKey := model.EnvFallback(log, providerName, "auth_token", cfg.AuthToken, envHFToken)
 	if apiKey == "" {
 		return nil, utils.WrapIfNotNil(errors.New("auth token is required (set WithAuthToken or HF_TOKEN)"))
 	}
 
-	baseURL := strings.TrimSpace(cfg.URL)
-	if baseURL == "" {
-		baseURL = strings.TrimSpace(os.Getenv(envHFBaseURL))
-	}
+	baseURL := model.EnvFallback(log, providerName, "url", cfg.URL, envHFBaseURL)
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 	baseURL = strings.TrimSuffix(baseURL, "/")
 
+	gatewayHeaders, err := model.GatewayRequestHeaders(cfg.Gateway)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
 	return &apiClient{
-		httpClient: &http.Client{Timeout: defaultHTTPTimeout},
-		baseURL:    baseURL,
-		apiKey:     apiKey,
+		httpClient:       model.NewHTTPClient(cfg, defaultHTTPTimeout),
+		baseURL:          baseURL,
+		apiKey:           apiKey,
+		gatewayHeaders:   gatewayHeaders,
+		retryPolicy:      model.ResolveRetryPolicy(cfg),
+		compressMinBytes: model.ResolveRequestCompressionMinBytes(cfg),
 	}, nil
 }
 
+// createChatCompletion sends one chat completion request. When the request
+// waits for its model, 503 "model is loading" responses are retried under the
+// retry policy, waiting for the server's estimated load time (capped by
+// RetryPolicy.MaxDelay) between attempts, while the generation's retry budget
+// on ctx lasts.
 func (c *apiClient) createChatCompletion(ctx context.Context, request chatCompletionRequest) (*chatCompletionResponse, error) {
 	requestBits, err := json.Marshal(request)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
+	requestBits, err = model.MergeProviderParams(requestBits, request.ProviderParams)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 
-	httpRequest, err := http.NewRequestWithContext(
+	for retry := 0; ; retry++ {
+		response, err := c.sendChatCompletion(ctx, requestBits, request.WaitForModel)
+		var apiErr *apiError
+		if err == nil || !request.WaitForModel || retry >= c.retryPolicy.MaxRetries ||
+			!errors.As(err, &apiErr) || !apiErr.modelLoading() {
+			return response, utils.WrapIfNotNil(err)
+		}
+
+		if !model.RetryBudgetFromContext(ctx).TryRetry() {
+			logging.NewLogger(ctx).Warnf("model %q is loading, not retrying: generation retry budget is spent", request.Model)
+			return response, utils.WrapIfNotNil(err)
+		}
+
+		delay := c.retryPolicy.Delay(retry, apiErr.EstimatedTime)
+		logging.NewLogger(ctx).Warnf(
+			"model %q is loading, retrying in %s (retry %d of %d)",
+			request.Model,
+			delay,
+			retry+1,
+			c.retryPolicy.MaxRetries,
+		)
+		if err = model.WaitForRetry(ctx, delay); err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+	}
+}
+
+func (c *apiClient) sendChatCompletion(ctx context.Context, requestBits []byte, waitForModel bool) (*chatCompletionResponse, error) {
+	httpRequest, _, err := model.NewCompressedRequest(
 		ctx,
 		http.MethodPost,
 		c.baseURL+"/v1/chat/completions",
-		bytes.NewReader(requestBits),
+		requestBits,
+		c.compressMinBytes,
 	)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
@@ -151,12 +226,21 @@ func (c *apiClient) createChatCompletion(ctx context.Context, request chatComple
 
 	httpRequest.Header.Set("Content-Type", "application/json")
 	httpRequest.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if waitForModel {
+		httpRequest.Header.Set("X-Wait-For-Model", "true")
+	}
+	for name, value := range c.gatewayHeaders {
+		httpRequest.Header.Set(name, value)
+	}
 
 	httpResponse, err := c.httpClient.Do(httpRequest)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
 	defer httpResponse.Body.Close()
+	if err := model.DecompressResponse(httpResponse); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 
 	responseBits, err := io.ReadAll(httpResponse.Body)
 	if err != nil {
@@ -164,18 +248,7 @@ func (c *apiClient) createChatCompletion(ctx context.Context, request chatComple
 	}
 
 	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
-		apiErr := chatCompletionErrorResponse{}
-		message := strings.TrimSpace(string(responseBits))
-		if unmarshalErr := json.Unmarshal(responseBits, &apiErr); unmarshalErr == nil {
-			candidate := strings.TrimSpace(apiErr.Error.Message)
-			if candidate != "" {
-				message = candidate
-			}
-		}
-		if message == "" {
-			message = "unknown huggingface error"
-		}
-		return nil, utils.WrapIfNotNil(fmt.Errorf("huggingface API error (%d): %s", httpResponse.StatusCode, message))
+		return nil, utils.WrapIfNotNil(parseAPIError(httpResponse.StatusCode, responseBits))
 	}
 
 	response := chatCompletionResponse{}
@@ -183,9 +256,94 @@ func (c *apiClient) createChatCompletion(ctx context.Context, request chatComple
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
+	response.Header = httpResponse.Header
 	return &response, nil
 }
 
+// parseAPIError reads both the OpenAI-style {"error": {"message": ...}} body
+// and the Inference API's {"error": "...", "estimated_time": 20.5} body.
+func parseAPIError(statusCode int, body []byte) *apiError {
+	apiErr := &apiError{StatusCode: statusCode, Message: strings.TrimSpace(string(body))}
+	chatErr := chatCompletionErrorResponse{}
+	if json.Unmarshal(body, &chatErr) == nil && strings.TrimSpace(chatErr.Error.Message) != "" {
+		apiErr.Message = strings.TrimSpace(chatErr.Error.Message)
+	}
+	loadingErr := struct {
+		Error         string  `json:"error"`
+		EstimatedTime float64 `json:"estimated_time"`
+	}{}
+	if json.Unmarshal(body, &loadingErr) == nil {
+		if strings.TrimSpace(loadingErr.Error) != "" {
+			apiErr.Message = strings.TrimSpace(loadingErr.Error)
+		}
+		if loadingErr.EstimatedTime > 0 {
+			apiErr.EstimatedTime = time.Duration(loadingErr.EstimatedTime * float64(time.Second))
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = "unknown huggingface error"
+	}
+	return apiErr
+}
+
+// createChatCompletionWithFallback sends request to models[*active:] in order,
+// moving to the next model while the current one is unavailable, and leaves
+// *active at the model that answered so later tool rounds stay on it. Only
+// the last model waits for a cold start, so fallbacks are tried instead of
+// waiting on a loading primary.
+func (c *apiClient) createChatCompletionWithFallback(
+	ctx context.Context,
+	request chatCompletionRequest,
+	models []string,
+	active *int,
+	log logging.Logger,
+) (*chatCompletionResponse, error) {
+	for {
+		request.Model = models[*active]
+		request.WaitForModel = *active == len(models)-1
+		response, err := c.createChatCompletion(ctx, request)
+		if err == nil {
+			if response != nil && strings.TrimSpace(response.Model) == "" {
+				response.Model = request.Model
+			}
+			return response, nil
+		}
+		if !isModelUnavailable(err) || *active == len(models)-1 || ctx.Err() != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		*active++
+		if log != nil {
+			log.Warnf("model %q is unavailable, falling back to %q: %v", request.Model, models[*active], err)
+		}
+	}
+}
+
+// isModelUnavailable reports responses that mean the model cannot serve
+// right now (loading, or no provider is up) rather than a bad request.
+func isModelUnavailable(err error) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// resolveModelCandidates is the configured model followed by the fallback
+// models, without duplicates.
+func resolveModelCandidates(cfg model.GeneratorConfig, modelName string) []string {
+	candidates := []string{modelName}
+	for _, name := range cfg.FallbackModels {
+		if !slices.Contains(candidates, name) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
 func resolveModelName(cfg model.GeneratorConfig) string {
 	if cfg.Model != nil {
 		name := strings.TrimSpace(*cfg.Model)
@@ -194,7 +352,7 @@ func resolveModelName(cfg model.GeneratorConfig) string {
 		}
 	}
 
-	fromEnv := strings.TrimSpace(os.Getenv(envHFModel))
+	fromEnv := model.EnvFallback(logging.NewLogger(context.Background()), providerName, "model", "", envHFModel)
 	if fromEnv != "" {
 		return fromEnv
 	}
@@ -242,6 +400,7 @@ func accumulateUsageTotals(totals *flowUsageTotals, response *chatCompletionResp
 	}
 
 	totals.APICalls++
+	totals.Gateway.Observe(response.Header)
 	if response.Usage == nil {
 		return
 	}
@@ -258,11 +417,13 @@ func applyHuggingFaceMetadata(meta model.GenerationMetadata, response *chatCompl
 
 	meta[model.MetadataKeyAPICalls] = strconv.Itoa(totals.APICalls)
 	meta[model.MetadataKeyToolRounds] = strconv.Itoa(totals.ToolRounds)
+	model.SetToolCalls(meta, totals.ToolCalls)
 	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(totals.InputTokens, 10)
 	meta[model.MetadataKeyOutputTokens] = strconv.FormatInt(totals.OutputTokens, 10)
 	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(totals.TotalTokens, 10)
 	meta[model.MetadataKeyCachedInputTokens] = "0"
 	meta[model.MetadataKeyReasoningTokens] = "0"
+	totals.Gateway.Apply(meta)
 
 	if response == nil {
 		return
@@ -279,15 +440,55 @@ func applyHuggingFaceMetadata(meta model.GenerationMetadata, response *chatCompl
 }
 
 func normalizeGeneratorOptionsForProvider(cfg model.GeneratorConfig, log logging.Logger) (model.GeneratorConfig, error) {
+	cfg, err := model.ApplyGenerationLimits(cfg, providerName, resolveModelName(cfg), model.GenerationLimits{
+		MaxTemperature: maxTemperature,
+	}, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
 	if cfg.ReasoningLevel != nil {
 		if cfg.IgnoreInvalidGeneratorOptions {
 			if log != nil {
 				log.Warnf("ignoring reasoning level for huggingface provider")
 			}
+			model.LogOptionResolution(log, providerName, "reasoning_level", model.OptionActionDropped, "not supported")
 			cfg.ReasoningLevel = nil
 		} else {
 			return cfg, utils.WrapIfNotNil(errors.New("reasoning level is not supported for huggingface provider"))
 		}
 	}
+	if len(cfg.BuiltinTools) > 0 {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring built-in tools for huggingface provider")
+			}
+			model.LogOptionResolution(log, providerName, "builtin_tools", model.OptionActionDropped, "not supported")
+			cfg.BuiltinTools = nil
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("built-in tools are not supported for huggingface provider"))
+		}
+	}
+	if cfg.CachedContent != "" {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring cached content for huggingface provider")
+			}
+			model.LogOptionResolution(log, providerName, "cached_content", model.OptionActionDropped, "not supported")
+			cfg.CachedContent = ""
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("cached content is not supported for huggingface provider"))
+		}
+	}
+	if len(cfg.Documents) > 0 {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring documents for huggingface provider")
+			}
+			model.LogOptionResolution(log, providerName, "documents", model.OptionActionDropped, "not supported")
+			cfg.Documents = nil
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("documents are not supported for huggingface provider"))
+		}
+	}
 	return cfg, nil
 }
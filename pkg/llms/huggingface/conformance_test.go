@@ -0,0 +1,76 @@
+package huggingface
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/testsupport"
+)
+
+type conformanceWire struct{}
+
+func (conformanceWire) DecodeRequest(r *http.Request) (testsupport.Request, error) {
+	var request chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return testsupport.Request{}, err
+	}
+
+	out := testsupport.Request{Model: request.Model}
+	for _, message := range request.Messages {
+		if message.Content == "" && message.Role != "tool" {
+			continue
+		}
+		out.Messages = append(out.Messages, testsupport.Message{Role: message.Role, Content: message.Content, ToolCallID: message.ToolCallID})
+	}
+	for _, tool := range request.Tools {
+		out.Tools = append(out.Tools, tool.Function.Name)
+	}
+	return out, nil
+}
+
+func (conformanceWire) WriteResponse(w http.ResponseWriter, turn testsupport.Turn) {
+	w.Header().Set("Content-Type", "application/json")
+	if turn.StatusCode >= http.StatusMultipleChoices {
+		w.WriteHeader(turn.StatusCode)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": turn.ErrorMessage}})
+		return
+	}
+
+	choice := chatCompletionChoice{Message: chatMessage{Role: "assistant", Content: turn.Text}, FinishReason: "stop"}
+	for _, call := range turn.ToolCalls {
+		choice.Message.ToolCalls = append(choice.Message.ToolCalls, chatToolCall{
+			ID:       call.ID,
+			Type:     "function",
+			Function: chatFunctionCall{Name: call.Name, Arguments: string(call.Arguments)},
+		})
+		choice.FinishReason = "tool_calls"
+	}
+	_ = json.NewEncoder(w).Encode(chatCompletionResponse{
+		ID:      "chatcmpl-conformance",
+		Model:   "hf-conformance",
+		Choices: []chatCompletionChoice{choice},
+		Usage: &chatCompletionUsage{
+			PromptTokens:     turn.InputTokens,
+			CompletionTokens: turn.OutputTokens,
+			TotalTokens:      turn.InputTokens + turn.OutputTokens,
+		},
+	})
+}
+
+func TestConformance(t *testing.T) {
+	testsupport.RunConformance(t, testsupport.Harness{
+		Provider:      providerName,
+		Wire:          conformanceWire{},
+		Options:       []model.GeneratorOption{model.WithAuthToken("hf_test")},
+		NewString:     NewStringContentGenerator,
+		NewStructured: NewStructureContentGenerator[testsupport.Record],
+		UnsupportedOptions: []model.GeneratorOption{
+			model.WithReasoningLevel(model.ReasoningLevelHigh),
+			model.WithBuiltinTools(model.BuiltinWebSearch),
+			model.WithCachedContent("cachedContents/abc"),
+			model.WithDocuments(model.DocumentPart{Name: "report.pdf", Data: []byte("%PDF-1.7")}),
+		},
+	})
+}
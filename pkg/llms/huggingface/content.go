@@ -4,15 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/emulation"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
-	"github.com/invopop/jsonschema"
 )
 
 type structuredGenerator[T any] struct {
@@ -22,6 +22,8 @@ type structuredGenerator[T any] struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 type textGenerator struct {
@@ -31,6 +33,8 @@ type textGenerator struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
@@ -122,6 +126,13 @@ func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider m
 }
 
 func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *structuredGenerator[T]) generate(ctx context.Context) (T, model.GenerationMetadata, error) {
 	start := time.Now()
 	log := logging.NewLogger(ctx)
 
@@ -134,8 +145,10 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	modelName := resolveModelName(cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
+	ctx, retries := model.StartRetryBudget(ctx, cfg)
+	defer model.SetRetryMetadata(meta, retries)
 
-	schema, err := generateJSONSchema[T]()
+	schema, err := model.StructuredOutputSchema[T](cfg)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
@@ -146,7 +159,7 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	messages, contextCount, err := g.messagesWithContext(ctx, schemaInstruction)
+	messages, contextCount, err := g.messagesWithContext(ctx, meta, schemaInstruction)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
@@ -159,6 +172,12 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 	}
 	defer cleanup()
 
+	emulateTools, err := resolveToolEmulation(cfg, modelName, len(tools) > 0, log)
+	if err != nil {
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+
 	log.Infof(
 		"prompt=%q context_count=%d model=%q temperature=%v max_tokens=%v tools=%d mcp_tools=%d",
 		g.prompt,
@@ -170,7 +189,8 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		len(cfg.MCPTools),
 	)
 
-	response, totals, err := runMessageFlow(ctx, g.client, cfg, modelName, messages, tools, handlers)
+	response, totals, history, err := runMessageFlow(ctx, g.client, cfg, modelName, messages, tools, handlers, emulateTools)
+	g.recordHistory(modelName, history)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
@@ -184,8 +204,19 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	var out T
-	err = json.Unmarshal([]byte(extractJSONPayload(text)), &out)
+	model.SetRawOutput(meta, g.cfg, text)
+	out, err := model.DecodeStructuredOutputWithRepair[T](
+		ctx,
+		g.cfg,
+		meta,
+		schema,
+		text,
+		model.ResolveStructuredRepairAttempts(cfg, 0),
+		extractJSONPayload,
+		func(ctx context.Context, prompt string) (string, model.GenerationMetadata, error) {
+			return repairStructuredOutput(ctx, g.client, cfg, modelName, prompt)
+		},
+	)
 	if err != nil {
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
@@ -194,6 +225,13 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 }
 
 func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generate(ctx context.Context) (string, model.GenerationMetadata, error) {
 	start := time.Now()
 	log := logging.NewLogger(ctx)
 
@@ -205,8 +243,10 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 	modelName := resolveModelName(cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
+	ctx, retries := model.StartRetryBudget(ctx, cfg)
+	defer model.SetRetryMetadata(meta, retries)
 
-	messages, contextCount, err := g.messagesWithContext(ctx, "")
+	messages, contextCount, err := g.messagesWithContext(ctx, meta, "")
 	if err != nil {
 		return "", meta, utils.WrapIfNotNil(err)
 	}
@@ -217,6 +257,11 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 	}
 	defer cleanup()
 
+	emulateTools, err := resolveToolEmulation(cfg, modelName, len(tools) > 0, log)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
 	log.Infof(
 		"prompt=%q context_count=%d model=%q temperature=%v max_tokens=%v tools=%d mcp_tools=%d",
 		g.prompt,
@@ -228,21 +273,44 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		len(cfg.MCPTools),
 	)
 
-	response, totals, err := runMessageFlow(ctx, g.client, cfg, modelName, messages, tools, handlers)
+	response, totals, history, err := runMessageFlow(ctx, g.client, cfg, modelName, messages, tools, handlers, emulateTools)
+	g.recordHistory(modelName, history)
 	if err != nil {
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	applyHuggingFaceMetadata(meta, response, totals)
 
-	text := extractTextFromResponse(response)
-	if text == "" {
-		err = errors.New("response output is empty")
+	text, err := model.FinishTextOutput(ctx, cfg, extractTextFromResponse(response))
+	if err != nil {
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 
 	return text, meta, nil
 }
 
+// repairStructuredOutput sends a structured output repair prompt as a new
+// single-turn chat completion without tools.
+func repairStructuredOutput(
+	ctx context.Context,
+	client *apiClient,
+	cfg model.GeneratorConfig,
+	modelName string,
+	prompt string,
+) (string, model.GenerationMetadata, error) {
+	meta := initMetadata(modelName)
+	messages := []chatMessage{
+		{Role: "system", Content: model.StructuredRepairSystemPrompt},
+		{Role: "user", Content: prompt},
+	}
+
+	response, totals, _, err := runMessageFlow(ctx, client, cfg, modelName, messages, nil, nil, false)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	applyHuggingFaceMetadata(meta, response, totals)
+	return extractTextFromResponse(response), meta, nil
+}
+
 func runMessageFlow(
 	ctx context.Context,
 	client *apiClient,
@@ -251,62 +319,104 @@ func runMessageFlow(
 	initialMessages []chatMessage,
 	tools []chatTool,
 	handlers map[string]toolHandler,
-) (*chatCompletionResponse, flowUsageTotals, error) {
+	emulateTools bool,
+) (*chatCompletionResponse, flowUsageTotals, []chatMessage, error) {
 	log := logging.NewLogger(ctx)
-	totals := flowUsageTotals{}
+	totals := flowUsageTotals{Gateway: model.NewGatewayTotals(cfg.Gateway)}
 	messages := append([]chatMessage(nil), initialMessages...)
+	if emulateTools {
+		// The model cannot accept native tool definitions; describe them in the prompt instead.
+		instructions, err := emulation.BuildToolInstructions(chatToolsAsModelTools(tools))
+		if err != nil {
+			return nil, totals, messages, utils.WrapIfNotNil(err)
+		}
+		messages = append([]chatMessage{{Role: "system", Content: instructions}}, messages...)
+	}
 
-	for round := 0; round < maxToolRounds; round++ {
+	models := resolveModelCandidates(cfg, modelName)
+	activeModel := 0
+	maxRounds := model.ResolveMaxToolRounds(cfg)
+	toolErrors := model.NewToolErrorPolicy(cfg)
+	for round := 0; round < maxRounds; round++ {
 		request := chatCompletionRequest{
-			Model:    modelName,
-			Messages: append([]chatMessage(nil), messages...),
+			Messages:       append([]chatMessage(nil), messages...),
+			ProviderParams: cfg.ProviderParams,
 		}
 		request.MaxTokens = resolveMaxTokens(cfg)
 		if cfg.Temperature != nil {
 			request.Temperature = cfg.Temperature
 		}
-		if len(tools) > 0 {
+		if len(tools) > 0 && !emulateTools {
 			request.Tools = append([]chatTool(nil), tools...)
 		}
 
-		response, err := client.createChatCompletion(ctx, request)
+		response, err := client.createChatCompletionWithFallback(ctx, request, models, &activeModel, log)
 		if err != nil {
-			return nil, totals, utils.WrapIfNotNil(err)
+			return nil, totals, messages, utils.WrapIfNotNil(err)
 		}
 		if response == nil {
-			return nil, totals, utils.WrapIfNotNil(errors.New("huggingface API returned nil response"))
+			return nil, totals, messages, utils.WrapIfNotNil(errors.New("huggingface API returned nil response"))
 		}
 
 		accumulateUsageTotals(&totals, response)
 
 		if len(response.Choices) == 0 {
-			return nil, totals, utils.WrapIfNotNil(errors.New("huggingface API returned no choices"))
+			return nil, totals, messages, utils.WrapIfNotNil(errors.New("huggingface API returned no choices"))
 		}
 
 		assistantMsg := response.Choices[0].Message
 		messages = append(messages, assistantMsg)
 
+		if emulateTools {
+			handled, records, err := runEmulatedToolCall(ctx, assistantMsg.Content, handlers, round+1)
+			totals.ToolCalls = append(totals.ToolCalls, records...)
+			if err != nil {
+				return nil, totals, messages, utils.WrapIfNotNil(err)
+			}
+			if handled == nil {
+				return response, totals, messages, nil
+			}
+			messages = append(messages, *handled)
+			totals.ToolRounds = round + 1
+			continue
+		}
+
 		if len(assistantMsg.ToolCalls) == 0 {
-			return response, totals, nil
+			return response, totals, messages, nil
 		}
 
-		localToolCalls := 0
+		localCalls := make([]chatToolCall, 0, len(assistantMsg.ToolCalls))
+		callHandlers := make([]toolHandler, 0, len(assistantMsg.ToolCalls))
 		for _, toolCall := range assistantMsg.ToolCalls {
 			handler, found := handlers[toolCall.Function.Name]
 			if !found {
 				log.Warnf("tool_call for %q has no handler; skipping", toolCall.Function.Name)
 				continue
 			}
+			localCalls = append(localCalls, toolCall)
+			callHandlers = append(callHandlers, handler)
+		}
 
-			localToolCalls++
-			result, callErr := handler(ctx, json.RawMessage(toolCall.Function.Arguments))
-			if callErr != nil {
-				return nil, totals, utils.WrapIfNotNil(callErr)
+		results := toolexec.Run(ctx, len(localCalls), model.ResolveToolParallelism(cfg), func(ctx context.Context, i int) (any, error) {
+			return callHandlers[i](ctx, json.RawMessage(localCalls[i].Function.Arguments))
+		})
+		totals.ToolCalls = append(totals.ToolCalls, toolexec.Transcript(round+1, results, func(i int) (string, string) {
+			return localCalls[i].Function.Name, localCalls[i].ID
+		})...)
+
+		for i, toolCall := range localCalls {
+			output := results[i].Value
+			if callErr := results[i].Err; callErr != nil {
+				if abortErr := toolErrors.Handle(toolCall.Function.Name, callErr); abortErr != nil {
+					return nil, totals, messages, utils.WrapIfNotNil(abortErr)
+				}
+				log.Warnf("tool %q failed, reporting to model: %v", toolCall.Function.Name, callErr)
+				output = model.ToolErrorResult(callErr)
 			}
 
-			resultJSON, marshalErr := json.Marshal(result)
+			resultJSON, marshalErr := json.Marshal(output)
 			if marshalErr != nil {
-				return nil, totals, utils.WrapIfNotNil(marshalErr)
+				return nil, totals, messages, utils.WrapIfNotNil(marshalErr)
 			}
 
 			messages = append(messages, chatMessage{
@@ -316,18 +426,99 @@ func runMessageFlow(
 			})
 		}
 
-		if localToolCalls == 0 {
-			return response, totals, nil
+		if len(localCalls) == 0 {
+			return response, totals, messages, nil
 		}
 
 		totals.ToolRounds = round + 1
 	}
 
-	return nil, totals, utils.WrapIfNotNil(fmt.Errorf("exceeded tool call loop limit (%d)", maxToolRounds))
+	return nil, totals, messages, utils.WrapIfNotNil(&model.MaxToolRoundsError{Limit: maxRounds})
+}
+
+// runEmulatedToolCall executes a prompt-protocol tool call found in assistant text.
+// It returns nil when the text is a final answer or names an unknown tool.
+func runEmulatedToolCall(ctx context.Context, content string, handlers map[string]toolHandler, round int) (*chatMessage, []model.ToolCallRecord, error) {
+	command, ok := emulation.ParseToolCall(content)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	handler, found := handlers[command.Name]
+	if !found {
+		logging.NewLogger(ctx).Warnf("tool_call for %q has no handler; skipping", command.Name)
+		return nil, nil, nil
+	}
+
+	results := toolexec.Run(ctx, 1, 1, func(ctx context.Context, i int) (any, error) {
+		return handler(ctx, command.Arguments)
+	})
+	records := toolexec.Transcript(round, results, func(i int) (string, string) {
+		return command.Name, ""
+	})
+	if err := results[0].Err; err != nil {
+		return nil, records, utils.WrapIfNotNil(err)
+	}
+
+	resultMessage, err := emulation.FormatToolResult(command.Name, results[0].Value)
+	if err != nil {
+		return nil, records, utils.WrapIfNotNil(err)
+	}
+	return &chatMessage{Role: "user", Content: resultMessage}, records, nil
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *structuredGenerator[T]) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg, g.client)
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *textGenerator) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg, g.client)
+}
+
+// effectiveConfig applies the option normalization, model resolution and
+// endpoint of a generation to cfg.
+func effectiveConfig(ctx context.Context, cfg model.GeneratorConfig, client *apiClient) (model.GeneratorConfig, error) {
+	cfg, err := normalizeGeneratorOptionsForProvider(cfg, logging.NewLogger(ctx))
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	modelName := resolveModelName(cfg)
+	cfg.Model = &modelName
+	cfg.URL = client.baseURL
+	return cfg, nil
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *structuredGenerator[T]) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *structuredGenerator[T]) recordHistory(modelName string, messages []chatMessage) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, messages)
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *textGenerator) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *textGenerator) recordHistory(modelName string, messages []chatMessage) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, messages)
 }
 
 func (g *structuredGenerator[T]) messagesWithContext(
 	ctx context.Context,
+	meta model.GenerationMetadata,
 	promptSuffix string,
 ) ([]chatMessage, int, error) {
 	g.promptContextMu.RLock()
@@ -343,15 +534,28 @@ func (g *structuredGenerator[T]) messagesWithContext(
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+
 	prompt := g.prompt
 	if strings.TrimSpace(promptSuffix) != "" {
 		prompt += "\n\n" + promptSuffix
 	}
+	model.SetContextTokens(meta, g.cfg, prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
 	return buildMessagesWithContext(prompt, contexts)
 }
 
 func (g *textGenerator) messagesWithContext(
 	ctx context.Context,
+	meta model.GenerationMetadata,
 	promptSuffix string,
 ) ([]chatMessage, int, error) {
 	g.promptContextMu.RLock()
@@ -367,10 +571,22 @@ func (g *textGenerator) messagesWithContext(
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+
 	prompt := g.prompt
 	if strings.TrimSpace(promptSuffix) != "" {
 		prompt += "\n\n" + promptSuffix
 	}
+	model.SetContextTokens(meta, g.cfg, prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
 	return buildMessagesWithContext(prompt, contexts)
 }
 
@@ -383,6 +599,10 @@ func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) ([
 			continue
 		}
 
+		if len(contextItem.Images) > 0 {
+			return nil, 0, utils.WrapIfNotNil(errors.New("image prompt contexts are not supported for huggingface provider"))
+		}
+
 		content := strings.TrimSpace(contextItem.Content)
 		if content == "" {
 			continue
@@ -412,29 +632,6 @@ func extractTextFromResponse(response *chatCompletionResponse) string {
 	return strings.TrimSpace(response.Choices[0].Message.Content)
 }
 
-func generateJSONSchema[T any]() (map[string]any, error) {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
-
-	var value T
-	schema := reflector.Reflect(value)
-
-	schemaJSON, err := json.Marshal(schema)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
-
-	var schemaMap map[string]any
-	err = json.Unmarshal(schemaJSON, &schemaMap)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
-
-	return schemaMap, nil
-}
-
 func buildStructuredOutputInstruction(schema map[string]any) (string, error) {
 	schemaBytes, err := json.Marshal(schema)
 	if err != nil {
@@ -99,7 +99,7 @@ func (s *ContentSuite) TestMessagesWithContextProviderError() {
 	g := &textGenerator{prompt: "hi"}
 	g.AddPromptContextProvider(context.Background(), &stubPromptContextProvider{err: errors.New("provider failed")})
 
-	_, _, err := g.messagesWithContext(context.Background(), "")
+	_, _, err := g.messagesWithContext(context.Background(), nil, "")
 	s.Error(err)
 	s.Contains(err.Error(), "provider failed")
 }
@@ -114,3 +114,30 @@ func (p *stubPromptContextProvider) GenerateContext(ctx context.Context) ([]*mod
 	}
 	return nil, nil
 }
+
+func (s *ContentSuite) TestBuildConversationHistory() {
+	history := buildConversationHistory("m", []chatMessage{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []chatToolCall{{ID: "call_1", Type: "function", Function: chatFunctionCall{Name: "weather", Arguments: `{"city":"Oslo"}`}}}},
+		{Role: "tool", Content: `{"temp":3}`, ToolCallID: "call_1"},
+		{Role: "assistant", Content: "3 degrees"},
+	})
+
+	s.Equal(providerName, history.Provider)
+	s.Equal("m", history.Model)
+	s.Require().Len(history.Messages, 4)
+	s.Require().Len(history.Messages[1].ToolCalls, 1)
+	s.Equal("weather", history.Messages[1].ToolCalls[0].Name)
+	s.JSONEq(`{"city":"Oslo"}`, string(history.Messages[1].ToolCalls[0].Arguments))
+	s.Equal(model.HistoryRoleTool, history.Messages[2].Role)
+	s.Equal("weather", history.Messages[2].ToolName)
+	s.Equal("call_1", history.Messages[2].ToolCallID)
+}
+
+func (s *ContentSuite) TestBuildMessagesRejectsImages() {
+	_, _, err := buildMessagesWithContext("final prompt", []*model.PromptContext{
+		model.ImagePromptContext("", model.ImagePart{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")}),
+	})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not supported for huggingface provider")
+}
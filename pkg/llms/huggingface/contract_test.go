@@ -0,0 +1,419 @@
+package huggingface
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+// ContractSuite checks the hand-rolled chat completion and feature extraction
+// clients against a fake server, so request shape, error handling and
+// metadata are verified without an HF token.
+type ContractSuite struct {
+	suite.Suite
+}
+
+func TestContractSuite(t *testing.T) {
+	suite.Run(t, new(ContractSuite))
+}
+
+func (s *ContractSuite) newGenerator(serverURL string, opts ...model.GeneratorOption) model.ContentGenerator[string] {
+	opts = append([]model.GeneratorOption{model.WithURL(serverURL), model.WithAuthToken("hf_test")}, opts...)
+	gen, err := NewStringContentGenerator("Say hello.", opts...)
+	s.Require().NoError(err)
+	return gen
+}
+
+func (s *ContractSuite) TestChatRequestShapeAndMetadata() {
+	var request chatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal(http.MethodPost, r.Method)
+		s.Equal("/v1/chat/completions", r.URL.Path)
+		s.Equal("Bearer hf_test", r.Header.Get("Authorization"))
+		s.Equal("application/json", r.Header.Get("Content-Type"))
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"org/model-served","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}`))
+	}))
+	defer server.Close()
+
+	gen := s.newGenerator(server.URL, model.WithModel("org/model"), model.WithMaxTokens(32))
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+
+	s.Equal("org/model", request.Model)
+	s.Equal(32, request.MaxTokens)
+	s.Nil(request.Temperature)
+	s.Require().Len(request.Messages, 2)
+	s.Equal("system", request.Messages[0].Role)
+	s.Equal("Be brief.", request.Messages[0].Content)
+	s.Equal("user", request.Messages[1].Role)
+	s.Equal("Say hello.", request.Messages[1].Content)
+
+	s.Equal(providerName, meta[model.MetadataKeyProvider])
+	s.Equal("org/model-served", meta[model.MetadataKeyModel])
+	s.Equal("chatcmpl-1", meta[model.MetadataKeyResponseID])
+	s.Equal("stop", meta[model.MetadataKeyResponseStatus])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+	s.Equal("9", meta[model.MetadataKeyInputTokens])
+	s.Equal("2", meta[model.MetadataKeyOutputTokens])
+	s.Equal("11", meta[model.MetadataKeyTotalTokens])
+}
+
+func (s *ContractSuite) TestFallbackModelsWhenPrimaryIsUnavailable() {
+	type attempt struct {
+		model string
+		wait  string
+	}
+	var attempts []attempt
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request chatCompletionRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		attempts = append(attempts, attempt{model: request.Model, wait: r.Header.Get("X-Wait-For-Model")})
+		switch request.Model {
+		case "org/cold":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":"Model org/cold is currently loading","estimated_time":40}`))
+		case "org/broken":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad request"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	out, meta, err := s.newGenerator(server.URL,
+		model.WithModel("org/cold"),
+		model.WithFallbackModels("org/warm", "org/cold", "org/other"),
+	).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+	s.Equal([]attempt{{model: "org/cold"}, {model: "org/warm"}}, attempts)
+	s.Equal("org/warm", meta[model.MetadataKeyModel])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+
+	attempts = nil
+	_, _, err = s.newGenerator(server.URL,
+		model.WithModel("org/cold"),
+		model.WithRetryPolicy(model.RetryPolicy{MaxRetries: -1}),
+	).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "huggingface API error (503)")
+	s.Equal([]attempt{{model: "org/cold", wait: "true"}}, attempts)
+
+	attempts = nil
+	_, _, err = s.newGenerator(server.URL,
+		model.WithModel("org/broken"),
+		model.WithFallbackModels("org/warm"),
+	).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "bad request")
+	s.Len(attempts, 1)
+}
+
+func (s *ContractSuite) TestLoadingModelIsRetried() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		s.Equal("true", r.Header.Get("X-Wait-For-Model"))
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":"Model org/cold is currently loading","estimated_time":40.5}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	policy := model.RetryPolicy{MaxRetries: 2, MaxDelay: time.Millisecond}
+	out, meta, err := s.newGenerator(server.URL, model.WithModel("org/cold"), model.WithRetryPolicy(policy)).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+	s.Equal(3, calls)
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+
+	calls = 0
+	policy.MaxRetries = 1
+	_, _, err = s.newGenerator(server.URL, model.WithModel("org/cold"), model.WithRetryPolicy(policy)).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "huggingface API error (503): Model org/cold is currently loading")
+	s.Equal(2, calls)
+}
+
+func (s *ContractSuite) TestRetryBudgetIsSharedAcrossCalls() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":"Model org/cold is currently loading","estimated_time":40.5}`))
+	}))
+	defer server.Close()
+
+	policy := model.RetryPolicy{MaxRetries: 3, MaxDelay: time.Millisecond}
+	budget := model.NewRetryBudget(2)
+	ctx := model.ContextWithRetryBudget(context.Background(), budget)
+	for range 2 {
+		_, meta, err := s.newGenerator(server.URL, model.WithModel("org/cold"), model.WithRetryPolicy(policy)).Generate(ctx)
+		s.Require().Error(err)
+		s.Equal("2", meta[model.MetadataKeyRetries])
+	}
+	// Three attempts on the first generation, one on the second.
+	s.Equal(4, calls)
+	s.Equal(2, budget.Used())
+}
+
+func (s *ContractSuite) TestParseAPIErrorReadsEstimatedTime() {
+	apiErr := parseAPIError(http.StatusServiceUnavailable, []byte(`{"error":"Model is currently loading","estimated_time":20.5}`))
+	s.True(apiErr.modelLoading())
+	s.Equal(20500*time.Millisecond, apiErr.EstimatedTime)
+
+	apiErr = parseAPIError(http.StatusServiceUnavailable, []byte(`{"error":{"message":"no healthy upstream"}}`))
+	s.False(apiErr.modelLoading())
+	s.Equal("no healthy upstream", apiErr.Message)
+}
+
+func (s *ContractSuite) TestChatErrorBodies() {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "openai style error", status: http.StatusUnauthorized, body: `{"error":{"message":"Invalid credentials","type":"auth"}}`, want: "huggingface API error (401): Invalid credentials"},
+		{name: "plain text", status: http.StatusServiceUnavailable, body: "Model is loading", want: "huggingface API error (503): Model is loading"},
+		{name: "empty body", status: http.StatusInternalServerError, body: "", want: "huggingface API error (500): unknown huggingface error"},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			// Loading responses are retried; see TestLoadingModelIsRetried.
+			_, _, err := s.newGenerator(server.URL, model.WithRetryPolicy(model.RetryPolicy{MaxRetries: -1})).Generate(context.Background())
+			s.Require().Error(err)
+			s.Contains(err.Error(), tc.want)
+		})
+	}
+}
+
+func (s *ContractSuite) TestChatMalformedJSONAndNoChoices() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":`))
+	}))
+	defer server.Close()
+
+	_, _, err := s.newGenerator(server.URL).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "unexpected end of JSON input")
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer empty.Close()
+
+	_, _, err = s.newGenerator(empty.URL).Generate(context.Background())
+	s.Error(err)
+}
+
+func (s *ContractSuite) TestSlowResponseHonoursContextDeadline() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := s.newGenerator(server.URL).Generate(ctx)
+	s.Require().Error(err)
+	s.True(errors.Is(err, context.DeadlineExceeded), err.Error())
+}
+
+func (s *ContractSuite) TestToolRoundTrip() {
+	var requests []chatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request chatCompletionRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"c1","choices":[{"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"id\":7}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"c2","choices":[{"message":{"role":"assistant","content":"Found it."},"finish_reason":"stop"}],"usage":{"prompt_tokens":20,"completion_tokens":4,"total_tokens":24}}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{
+		Name:        "lookup",
+		Description: "Look up a record",
+		InputSchema: model.JSONSchema{"type": "object"},
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			s.JSONEq(`{"id":7}`, string(args))
+			return map[string]any{"name": "Ada"}, nil
+		},
+	}
+	out, meta, err := s.newGenerator(server.URL, model.WithTools([]model.Tool{tool})).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Found it.", out)
+
+	s.Require().Len(requests, 2)
+	s.Require().Len(requests[0].Tools, 1)
+	s.Equal("function", requests[0].Tools[0].Type)
+	s.Equal("lookup", requests[0].Tools[0].Function.Name)
+
+	second := requests[1].Messages
+	s.Require().Len(second, 3)
+	s.Equal("assistant", second[1].Role)
+	s.Require().Len(second[1].ToolCalls, 1)
+	s.Equal("tool", second[2].Role)
+	s.Equal("call_1", second[2].ToolCallID)
+	s.JSONEq(`{"name":"Ada"}`, second[2].Content)
+
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	s.Equal("39", meta[model.MetadataKeyTotalTokens])
+}
+
+func (s *ContractSuite) TestFeatureExtraction() {
+	var request featureExtractionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/hf-inference/models/org/embedder", r.URL.Path)
+		s.Equal("Bearer hf_test", r.Header.Get("Authorization"))
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`[[0.1,0.2,0.3],[0.4,0.5,0.6]]`))
+	}))
+	defer server.Close()
+
+	gen, err := NewEmbeddingGenerator(model.WithURL(server.URL), model.WithAuthToken("hf_test"), model.WithModel("org/embedder"))
+	s.Require().NoError(err)
+
+	vectors, meta, err := gen.GenerateBatch(context.Background(), []string{"a", "b"})
+	s.Require().NoError(err)
+	s.Equal([]string{"a", "b"}, request.Inputs)
+	s.Require().NotNil(request.Options)
+	s.True(request.Options.WaitForModel)
+	s.Require().Len(vectors, 2)
+	s.Equal("2", meta[model.MetadataKeyEmbeddingCount])
+	s.Equal("3", meta[model.MetadataKeyEmbeddingDims])
+}
+
+func (s *ContractSuite) TestFeatureExtractionTruncatesDimensions() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[[3,4,12],[0,5,1]]`))
+	}))
+	defer server.Close()
+
+	opts := []model.GeneratorOption{model.WithURL(server.URL), model.WithAuthToken("hf_test"), model.WithEmbeddingDimensions(2)}
+	gen, err := NewEmbeddingGenerator(opts...)
+	s.Require().NoError(err)
+	vectors, meta, err := gen.GenerateBatch(context.Background(), []string{"a", "b"})
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVectors{{0.6, 0.8}, {0, 1}}, vectors)
+	s.Equal("2", meta[model.MetadataKeyEmbeddingDims])
+
+	gen, err = NewEmbeddingGenerator(append(opts, model.WithEmbeddingDimensions(4))...)
+	s.Require().NoError(err)
+	_, _, err = gen.GenerateBatch(context.Background(), []string{"a", "b"})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "exceed the model's 3 dimensions")
+
+	gen, err = NewEmbeddingGenerator(append(opts, model.WithEmbeddingDimensions(4), model.WithIgnoreInvalidGeneratorOptions(true))...)
+	s.Require().NoError(err)
+	vectors, _, err = gen.GenerateBatch(context.Background(), []string{"a", "b"})
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVectors{{3, 4, 12}, {0, 5, 1}}, vectors)
+}
+
+func (s *ContractSuite) TestFeatureExtractionErrors() {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "hf error", status: http.StatusServiceUnavailable, body: `{"error":"Model org/embedder is currently loading","estimated_time":20}`, want: "huggingface embedding API error (503): Model org/embedder is currently loading"},
+		{name: "empty body", status: http.StatusInternalServerError, body: "", want: "huggingface embedding API error (500): unknown huggingface embedding error"},
+		{name: "size mismatch", status: http.StatusOK, body: `[[0.1],[0.2],[0.3]]`, want: "embedding response size mismatch: expected 2, got 3"},
+		{name: "malformed", status: http.StatusOK, body: `[[0.1,`, want: ""},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			gen, err := NewEmbeddingGenerator(model.WithURL(server.URL), model.WithAuthToken("hf_test"), model.WithModel("org/embedder"))
+			s.Require().NoError(err)
+			_, _, err = gen.GenerateBatch(context.Background(), []string{"a", "b"})
+			s.Require().Error(err)
+			s.Contains(err.Error(), tc.want)
+		})
+	}
+}
+
+func (s *ContractSuite) TestProviderParamsAreMergedIntoBody() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"id":"c1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	_, _, err := s.newGenerator(server.URL,
+		model.WithProviderParams(map[string]any{"top_p": 0.9, "response_format": map[string]any{"type": "json_object"}}),
+	).Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal(0.9, body["top_p"])
+	s.Equal(map[string]any{"type": "json_object"}, body["response_format"])
+	s.NotEmpty(body["messages"])
+}
+
+func (s *ContractSuite) TestGatewayHeadersAndMetadata() {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("x-portkey-trace-id", "trace-1")
+		w.Header().Set("x-portkey-cache-status", "HIT")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	_, meta, err := s.newGenerator(server.URL, model.WithGateway(model.GatewayProfile{
+		Kind:       model.GatewayPortkey,
+		VirtualKey: "vk-1",
+		Metadata:   map[string]string{"_user": "u-1"},
+	})).Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal("vk-1", header.Get("x-portkey-virtual-key"))
+	s.JSONEq(`{"_user":"u-1"}`, header.Get("x-portkey-metadata"))
+	s.Equal("Bearer hf_test", header.Get("Authorization"))
+	s.Equal("portkey", meta[model.MetadataKeyGateway])
+	s.Equal("trace-1", meta[model.MetadataKeyGatewayRequestID])
+	s.Equal("HIT", meta[model.MetadataKeyGatewayCacheStatus])
+	s.NotContains(meta, model.MetadataKeyGatewayCost)
+}
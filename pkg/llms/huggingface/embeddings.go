@@ -74,12 +74,19 @@ func (g *embeddingGenerator) GenerateBatch(
 		log.Errorf("error: %v", err)
 		return nil, meta, utils.WrapIfNotNil(err)
 	}
+	cfg := g.cfg
+	cfg.EmbeddingDimensions, err = model.ResolveEmbeddingDimensions(g.cfg, providerName, modelName, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
 
 	log.Infof(
-		"embedding_request inputs=%d model=%q base_url=%q",
+		"embedding_request inputs=%d model=%q base_url=%q dimensions=%v",
 		len(inputs),
 		modelName,
 		g.client.baseURL,
+		cfg.EmbeddingDimensions,
 	)
 
 	vectors, err := g.client.featureExtraction(ctx, modelName, inputs)
@@ -96,6 +103,11 @@ func (g *embeddingGenerator) GenerateBatch(
 			fmt.Errorf("embedding response size mismatch: expected %d, got %d", len(inputs), len(vectors)),
 		)
 	}
+	vectors, err = truncateEmbeddings(log, cfg, vectors)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
 
 	meta[model.MetadataKeyEmbeddingCount] = fmt.Sprintf("%d", len(vectors))
 	if len(vectors) > 0 {
@@ -136,6 +148,9 @@ func (c *apiClient) featureExtraction(ctx context.Context, modelName string, inp
 
 	httpRequest.Header.Set("Content-Type", "application/json")
 	httpRequest.Header.Set("Authorization", "Bearer "+c.apiKey)
+	for name, value := range c.gatewayHeaders {
+		httpRequest.Header.Set(name, value)
+	}
 
 	httpResponse, err := c.httpClient.Do(httpRequest)
 	if err != nil {
@@ -227,6 +242,24 @@ func meanPool(tokenVectors [][]float64) model.EmbeddingVector {
 	return result
 }
 
+// truncateEmbeddings applies WithEmbeddingDimensions client-side, as the API
+// has no dimensions parameter. A size larger than the model's is an error, or
+// is ignored with a warning when invalid options are ignored.
+func truncateEmbeddings(log logging.Logger, cfg model.GeneratorConfig, vectors model.EmbeddingVectors) (model.EmbeddingVectors, error) {
+	if cfg.EmbeddingDimensions == nil {
+		return vectors, nil
+	}
+	truncated, err := model.TruncateEmbeddings(vectors, *cfg.EmbeddingDimensions)
+	if err != nil {
+		if !cfg.IgnoreInvalidGeneratorOptions {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		log.Warnf("ignoring embedding dimensions: %v", err)
+		return vectors, nil
+	}
+	return truncated, nil
+}
+
 func validateEmbeddingInputs(inputs []string) error {
 	if len(inputs) == 0 {
 		return utils.WrapIfNotNil(errors.New("at least one input is required"))
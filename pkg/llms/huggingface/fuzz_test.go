@@ -0,0 +1,39 @@
+package huggingface
+
+import (
+	"math"
+	"testing"
+)
+
+func FuzzParseFeatureExtractionResponse(f *testing.F) {
+	for _, seed := range []string{
+		`[0.1,0.2,0.3]`,
+		`[[0.1,0.2],[0.3,0.4]]`,
+		`[[[0.1,0.2],[0.3,0.4]],[[1,2]]]`,
+		`[[[0.1,0.2],[0.3]],[[]]]`,
+		`[[],[]]`,
+		`[]`,
+		`{"error":"loading"}`,
+		`null`,
+	} {
+		f.Add([]byte(seed), 1)
+		f.Add([]byte(seed), 2)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, expectedCount int) {
+		vectors, err := parseFeatureExtractionResponse(data, expectedCount)
+		if err != nil {
+			return
+		}
+		if len(vectors) == 0 {
+			t.Fatalf("successful parse of %q returned no vectors", data)
+		}
+		for _, vector := range vectors {
+			for _, value := range vector {
+				if math.IsNaN(value) {
+					t.Fatalf("parse of %q produced NaN", data)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package huggingface
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+func buildConversationHistory(modelName string, messages []chatMessage) model.ConversationHistory {
+	history := model.ConversationHistory{
+		Version:  model.ConversationHistoryVersion,
+		Provider: providerName,
+		Model:    modelName,
+		Messages: make([]model.HistoryMessage, 0, len(messages)),
+	}
+
+	// Tool result messages only carry the call ID, so remember which tool each ID named.
+	toolNames := map[string]string{}
+	for _, message := range messages {
+		entry := model.HistoryMessage{
+			Role:       model.HistoryRole(message.Role),
+			Content:    message.Content,
+			ToolCallID: message.ToolCallID,
+			ToolName:   toolNames[message.ToolCallID],
+		}
+		for _, call := range message.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+			var arguments json.RawMessage
+			if trimmed := strings.TrimSpace(call.Function.Arguments); json.Valid([]byte(trimmed)) {
+				arguments = json.RawMessage(trimmed)
+			}
+			entry.ToolCalls = append(entry.ToolCalls, model.HistoryToolCall{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: arguments,
+			})
+		}
+		history.Messages = append(history.Messages, entry)
+	}
+	return history
+}
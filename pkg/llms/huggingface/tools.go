@@ -16,46 +16,19 @@ import (
 type toolHandler func(ctx context.Context, args json.RawMessage) (any, error)
 
 func buildAllTools(ctx context.Context, cfg model.GeneratorConfig) ([]chatTool, map[string]toolHandler, func(), error) {
-	localTools, handlers, err := mapLocalTools(cfg.Tools)
+	localTools, handlers, err := mapLocalTools(model.InterceptTools(cfg.Tools, cfg.ToolInterceptors))
 	if err != nil {
 		return nil, nil, func() {}, utils.WrapIfNotNil(err)
 	}
 
-	adapters := make([]*mcp.ToolAdapter, 0, len(cfg.MCPTools))
-
-	cleanup := func() {
-		log := logging.NewLogger(ctx)
-		for _, adapter := range adapters {
-			if adapter == nil {
-				continue
-			}
-			if err := adapter.Disconnect(); err != nil {
-				log.Warnf("mcp adapter disconnect failed: %v", err)
-			}
-		}
+	mcpTools, cleanup, err := mcp.ModelTools(ctx, cfg.MCPTools, cfg.MCPAdapterPool)
+	if err != nil {
+		return nil, nil, func() {}, utils.WrapIfNotNil(err)
 	}
-
-	for _, mcpTool := range cfg.MCPTools {
-		authToken := extractAuthorizationHeader(mcpTool.HTTPHeaders)
-
-		adapter, err := mcp.NewToolAdapter(ctx, mcpTool.URL, authToken, mcpTool.AllowedTools)
-		if err != nil {
-			cleanup()
-			return nil, nil, func() {}, utils.WrapIfNotNil(err)
-		}
-		adapters = append(adapters, adapter)
-
-		adapterTools, err := adapter.AsModelTools()
-		if err != nil {
-			cleanup()
-			return nil, nil, func() {}, utils.WrapIfNotNil(err)
-		}
-
-		for _, modelTool := range adapterTools {
-			ct, handler := convertModelToolToChatTool(modelTool)
-			localTools = append(localTools, ct)
-			handlers[modelTool.Name] = handler
-		}
+	for _, modelTool := range model.InterceptTools(mcpTools, cfg.ToolInterceptors) {
+		ct, handler := convertModelToolToChatTool(modelTool)
+		localTools = append(localTools, ct)
+		handlers[modelTool.Name] = handler
 	}
 
 	return localTools, handlers, cleanup, nil
@@ -94,7 +67,7 @@ func mapLocalTools(tools []model.Tool) ([]chatTool, map[string]toolHandler, erro
 				Parameters:  parameters,
 			},
 		})
-		handlers[name] = tool.Handler
+		handlers[name] = tool.Call
 	}
 
 	return mapped, handlers, nil
@@ -119,14 +92,32 @@ func convertModelToolToChatTool(tool model.Tool) (chatTool, toolHandler) {
 		},
 	}
 
-	return ct, tool.Handler
+	return ct, tool.Call
 }
 
-func extractAuthorizationHeader(headers map[string]string) string {
-	for k, v := range headers {
-		if strings.EqualFold(k, "Authorization") {
-			return v
-		}
+// resolveToolEmulation reports whether tools must be described in the prompt
+// because the model is registered as not supporting native tool calling.
+func resolveToolEmulation(cfg model.GeneratorConfig, modelName string, hasTools bool, log logging.Logger) (bool, error) {
+	if !hasTools || model.SupportsToolCalling(providerName, modelName) {
+		return false, nil
+	}
+	if !cfg.IgnoreInvalidGeneratorOptions {
+		return false, utils.WrapIfNotNil(fmt.Errorf("%w: %s", model.ErrToolCallingNotSupported, modelName))
+	}
+	if log != nil {
+		log.Warnf("model %q does not support tool calling; falling back to prompt-described tools", modelName)
+	}
+	return true, nil
+}
+
+func chatToolsAsModelTools(tools []chatTool) []model.Tool {
+	out := make([]model.Tool, 0, len(tools))
+	for _, tool := range tools {
+		out = append(out, model.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: model.JSONSchema(tool.Function.Parameters),
+		})
 	}
-	return ""
+	return out
 }
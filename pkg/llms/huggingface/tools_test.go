@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/stretchr/testify/suite"
@@ -37,6 +38,23 @@ func (s *ToolsSuite) TestMapLocalToolsSuccess() {
 	s.NotNil(handlers["echo"])
 }
 
+func (s *ToolsSuite) TestMapLocalToolsEnforcesTimeout() {
+	_, handlers, err := mapLocalTools([]model.Tool{
+		{
+			Name:    "slow",
+			Timeout: 10 * time.Millisecond,
+			Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	})
+	s.Require().NoError(err)
+
+	_, err = handlers["slow"](context.Background(), json.RawMessage(`{}`))
+	s.ErrorIs(err, model.ErrToolTimeout)
+}
+
 func (s *ToolsSuite) TestMapLocalToolsDuplicateName() {
 	_, _, err := mapLocalTools([]model.Tool{
 		{Name: "dup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) { return nil, nil }},
@@ -78,18 +96,44 @@ func (s *ToolsSuite) TestMapLocalToolsDefaultSchema() {
 	s.Equal("object", tools[0].Function.Parameters["type"])
 }
 
-func (s *ToolsSuite) TestExtractAuthorizationHeader() {
-	s.Equal("Bearer tok", extractAuthorizationHeader(map[string]string{
-		"authorization": "Bearer tok",
-	}))
-}
+func (s *ToolsSuite) TestResolveToolEmulation() {
+	model.RegisterModelCapabilities(providerName, "text-only-model", model.ModelCapabilities{ToolCalling: false})
 
-func (s *ToolsSuite) TestExtractAuthorizationHeaderMissing() {
-	s.Equal("", extractAuthorizationHeader(map[string]string{
-		"X-Custom": "val",
-	}))
+	emulate, err := resolveToolEmulation(model.GeneratorConfig{}, "text-only-model", false, nil)
+	s.Require().NoError(err)
+	s.False(emulate)
+
+	emulate, err = resolveToolEmulation(model.GeneratorConfig{}, defaultModelName, true, nil)
+	s.Require().NoError(err)
+	s.False(emulate)
+
+	_, err = resolveToolEmulation(model.GeneratorConfig{}, "text-only-model", true, nil)
+	s.Error(err)
+	s.ErrorIs(err, model.ErrToolCallingNotSupported)
+
+	emulate, err = resolveToolEmulation(model.GeneratorConfig{IgnoreInvalidGeneratorOptions: true}, "text-only-model", true, nil)
+	s.Require().NoError(err)
+	s.True(emulate)
 }
 
-func (s *ToolsSuite) TestExtractAuthorizationHeaderEmpty() {
-	s.Equal("", extractAuthorizationHeader(nil))
+func (s *ToolsSuite) TestRunEmulatedToolCall() {
+	handlers := map[string]toolHandler{
+		"echo": func(ctx context.Context, args json.RawMessage) (any, error) {
+			return map[string]any{"args": string(args)}, nil
+		},
+	}
+
+	message, records, err := runEmulatedToolCall(context.Background(), `{"tool_call":{"name":"echo","arguments":{"x":1}}}`, handlers, 2)
+	s.Require().NoError(err)
+	s.Require().NotNil(message)
+	s.Equal("user", message.Role)
+	s.Contains(message.Content, "Tool result for echo:")
+	s.Require().Len(records, 1)
+	s.Equal(2, records[0].Round)
+	s.Equal("echo", records[0].Name)
+
+	message, records, err = runEmulatedToolCall(context.Background(), "final answer", handlers, 3)
+	s.Require().NoError(err)
+	s.Nil(message)
+	s.Empty(records)
 }
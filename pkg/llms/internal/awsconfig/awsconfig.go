@@ -0,0 +1,74 @@
+// Package awsconfig loads the AWS configuration shared by the Bedrock
+// provider and Anthropic models hosted on Bedrock from the standard AWS
+// environment variables.
+package awsconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// DefaultRegion is used when AWS_REGION is not set.
+const DefaultRegion = "us-east-1"
+
+// ErrMissingCredentials is returned by Load when neither access keys nor a
+// profile are configured.
+var ErrMissingCredentials = errors.New("missing AWS credentials: set AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or AWS_PROFILE")
+
+// Region returns AWS_REGION, or DefaultRegion when it is not set.
+func Region() string {
+	region := strings.TrimSpace(os.Getenv("AWS_REGION"))
+	if region == "" {
+		return DefaultRegion
+	}
+	return region
+}
+
+// Load builds the AWS config for region from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY (with the optional AWS_SESSION_TOKEN) or, failing
+// that, AWS_PROFILE. The credentials provider is wrapped in an
+// aws.CredentialsCache, so callers that keep the config retrieve credentials
+// once until they expire instead of on every request.
+func Load(ctx context.Context, region string) (aws.Config, error) {
+	accessKeyID := strings.TrimSpace(os.Getenv("AWS_ACCESS_KEY_ID"))
+	secretAccessKey := strings.TrimSpace(os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	profile := strings.TrimSpace(os.Getenv("AWS_PROFILE"))
+
+	loadOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+	}
+
+	switch {
+	case accessKeyID != "" || secretAccessKey != "":
+		if accessKeyID == "" || secretAccessKey == "" {
+			return aws.Config{}, utils.WrapIfNotNil(
+				errors.New("both AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when using key-based auth"),
+			)
+		}
+
+		sessionToken := strings.TrimSpace(os.Getenv("AWS_SESSION_TOKEN"))
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, sessionToken),
+		))
+	case profile != "":
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(profile))
+	default:
+		return aws.Config{}, utils.WrapIfNotNil(ErrMissingCredentials)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, utils.WrapIfNotNil(err)
+	}
+	if _, cached := cfg.Credentials.(*aws.CredentialsCache); !cached && cfg.Credentials != nil {
+		cfg.Credentials = aws.NewCredentialsCache(cfg.Credentials)
+	}
+	return cfg, nil
+}
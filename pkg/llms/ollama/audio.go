@@ -0,0 +1,308 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const (
+	// Ollama has no speech models, so transcription targets a local
+	// OpenAI-compatible whisper server (whisper.cpp, faster-whisper).
+	defaultWhisperBaseURL       = "http://localhost:8080"
+	defaultWhisperModelName     = "whisper-1"
+	whisperTranscriptionsPath   = "/v1/audio/transcriptions"
+	whisperTranscriptionTimeout = 10 * time.Minute
+)
+
+type audioTranscriptionGenerator struct {
+	client   *client
+	filePath string
+	opts     model.AudioOptions
+}
+
+// whisperTranscription is the json and verbose_json response of an
+// OpenAI-compatible transcription endpoint.
+type whisperTranscription struct {
+	Text     string  `json:"text"`
+	Duration float64 `json:"duration"`
+	Segments []struct {
+		ID         int     `json:"id"`
+		Start      float64 `json:"start"`
+		End        float64 `json:"end"`
+		Text       string  `json:"text"`
+		AvgLogprob float64 `json:"avg_logprob"`
+	} `json:"segments"`
+}
+
+// NewAudioTranscriptionGenerator transcribes through a local whisper server
+// at opts.URL, else WHISPER_BASE_URL, else http://localhost:8080 (unix://
+// socket URLs are supported). The model defaults to whisper-1, which
+// whisper.cpp ignores; faster-whisper servers expect their model name.
+func NewAudioTranscriptionGenerator(
+	filePath string,
+	opts model.AudioOptions,
+) (model.AudioTranscriptionGenerator, error) {
+	if strings.TrimSpace(filePath) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("file path is required"))
+	}
+
+	baseURL := strings.TrimSpace(opts.URL)
+	if baseURL == "" {
+		baseURL = strings.TrimSpace(os.Getenv("WHISPER_BASE_URL"))
+	}
+	if baseURL == "" {
+		baseURL = defaultWhisperBaseURL
+	}
+	c, err := newClientWithBaseURL(model.GeneratorConfig{TLSConfig: opts.TLSConfig}, baseURL)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	opts.Keywords = append([]model.AudioKeyword(nil), opts.Keywords...)
+	return &audioTranscriptionGenerator{
+		client:   c,
+		filePath: filePath,
+		opts:     opts,
+	}, nil
+}
+
+func (g *audioTranscriptionGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	log := logging.NewLogger(ctx)
+	modelName := resolveAudioTranscriptionModelName(g.opts)
+	meta := initMetadata(modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log.Infof("audio_transcription_request model=%q", modelName)
+
+	format, err := model.ResolveTranscriptFormat(g.opts)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	opts := g.opts
+	if opts.Diarize {
+		err = errors.New("diarization is not supported by whisper servers")
+		if !opts.IgnoreInvalidGeneratorOptions {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		log.Warnf("ignoring diarization: %v", err)
+		opts.Diarize = false
+	}
+
+	switch {
+	case format == model.TranscriptFormatText:
+		var response whisperTranscription
+		if err = g.client.transcribe(ctx, g.filePath, opts, "json", &response); err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		transcript := strings.TrimSpace(response.Text)
+		if transcript == "" {
+			err = errors.New("transcription response is empty")
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		model.SetTranscriptLanguage(meta, opts, transcript)
+		return model.RedactTranscript(meta, opts, transcript), meta, nil
+	case format != model.TranscriptFormatJSON && !opts.RedactPII:
+		// The server renders subtitles itself; with RedactPII they are
+		// rendered from the redacted segments so timecodes are never scrubbed.
+		var subtitles []byte
+		if err = g.client.transcribe(ctx, g.filePath, opts, string(format), &subtitles); err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		if len(bytes.TrimSpace(subtitles)) == 0 {
+			err = errors.New("transcription response is empty")
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		model.SetTranscriptLanguage(meta, opts, string(subtitles))
+		return strings.TrimSpace(string(subtitles)) + "\n", meta, nil
+	default:
+		var response whisperTranscription
+		if err = g.client.transcribe(ctx, g.filePath, opts, "verbose_json", &response); err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		result := model.FinishTranscriptionResult(meta, opts, toTranscriptionResult(response))
+		output, err := model.FormatTranscript(result, format)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		return output, meta, nil
+	}
+}
+
+// transcribe posts filePath to the transcription endpoint with
+// responseFormat and decodes the response into out, which is a *[]byte for
+// non-JSON formats.
+func (c *client) transcribe(
+	ctx context.Context,
+	filePath string,
+	opts model.AudioOptions,
+	responseFormat string,
+	out any,
+) error {
+	body, contentType, err := buildTranscriptionForm(filePath, opts, responseFormat)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		whisperTranscriptionsURL(c.baseURL),
+		body,
+	)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	httpRequest.Header.Set("Content-Type", contentType)
+	if token := strings.TrimSpace(opts.AuthToken); token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpResponse, err := c.httpClient(whisperTranscriptionTimeout).Do(httpRequest)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	defer httpResponse.Body.Close()
+
+	rawBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	if httpResponse.StatusCode < http.StatusOK || httpResponse.StatusCode >= http.StatusMultipleChoices {
+		return utils.WrapIfNotNil(
+			fmt.Errorf("whisper transcription request failed with status %d: %s", httpResponse.StatusCode, strings.TrimSpace(string(rawBody))),
+		)
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		*raw = rawBody
+		return nil
+	}
+	return utils.WrapIfNotNil(json.Unmarshal(rawBody, out))
+}
+
+func buildTranscriptionForm(filePath string, opts model.AudioOptions, responseFormat string) (io.Reader, string, error) {
+	audio, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", utils.WrapIfNotNil(err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return nil, "", utils.WrapIfNotNil(err)
+	}
+	if _, err = part.Write(audio); err != nil {
+		return nil, "", utils.WrapIfNotNil(err)
+	}
+
+	prompt, err := buildAudioTranscriptionPrompt(opts)
+	if err != nil {
+		return nil, "", utils.WrapIfNotNil(err)
+	}
+	fields := [][2]string{
+		{"model", resolveAudioTranscriptionModelName(opts)},
+		{"response_format", responseFormat},
+		{"prompt", prompt},
+		{"language", strings.ToLower(strings.TrimSpace(opts.Language))},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err = writer.WriteField(field[0], field[1]); err != nil {
+			return nil, "", utils.WrapIfNotNil(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return nil, "", utils.WrapIfNotNil(err)
+	}
+	return &body, writer.FormDataContentType(), nil
+}
+
+// whisperTranscriptionsURL accepts base URLs with or without the /v1 prefix.
+func whisperTranscriptionsURL(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + strings.TrimPrefix(whisperTranscriptionsPath, "/v1")
+	}
+	return baseURL + whisperTranscriptionsPath
+}
+
+// buildAudioTranscriptionPrompt uses opts.Prompt, else a "Common missed
+// words" hint built from opts.Keywords.
+func buildAudioTranscriptionPrompt(opts model.AudioOptions) (string, error) {
+	if prompt := strings.TrimSpace(opts.Prompt); prompt != "" {
+		return prompt, nil
+	}
+
+	keywords := make([]model.AudioKeyword, 0, len(opts.Keywords))
+	for _, keyword := range opts.Keywords {
+		keyword.Word = strings.TrimSpace(keyword.Word)
+		keyword.Definition = strings.TrimSpace(keyword.Definition)
+		if keyword.Word == "" && keyword.Definition == "" && len(keyword.CommonMistypes) == 0 {
+			continue
+		}
+		keywords = append(keywords, keyword)
+	}
+	if len(keywords) == 0 {
+		return "", nil
+	}
+	keywordsJSON, err := json.Marshal(keywords)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return "Common missed words: " + string(keywordsJSON), nil
+}
+
+func resolveAudioTranscriptionModelName(opts model.AudioOptions) string {
+	if modelName := strings.TrimSpace(opts.Model); modelName != "" {
+		return modelName
+	}
+	return defaultWhisperModelName
+}
+
+func toTranscriptionResult(response whisperTranscription) model.TranscriptionResult {
+	result := model.TranscriptionResult{
+		Text:     response.Text,
+		Duration: model.SecondsToDuration(response.Duration),
+	}
+	for _, segment := range response.Segments {
+		confidence := 0.0
+		if segment.AvgLogprob < 0 {
+			confidence = math.Exp(segment.AvgLogprob)
+		}
+		result.Segments = append(result.Segments, model.TranscriptSegment{
+			ID:         segment.ID,
+			Start:      model.SecondsToDuration(segment.Start),
+			End:        model.SecondsToDuration(segment.End),
+			Text:       strings.TrimSpace(segment.Text),
+			Confidence: confidence,
+		})
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type AudioSuite struct {
+	suite.Suite
+}
+
+func TestAudioSuite(t *testing.T) {
+	suite.Run(t, new(AudioSuite))
+}
+
+func (s *AudioSuite) writeAudio() string {
+	path := filepath.Join(s.T().TempDir(), "visit.wav")
+	s.Require().NoError(os.WriteFile(path, []byte("RIFF-audio"), 0o600))
+	return path
+}
+
+func (s *AudioSuite) TestTranscribeSendsOpenAICompatibleForm() {
+	var form map[string][]string
+	var path, authorization, fileName string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		form = r.MultipartForm.Value
+		fileName = r.MultipartForm.File["file"][0].Filename
+		_, _ = w.Write([]byte(`{"text":" Call 555-123-4567 about the furosemide dose. "}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{
+		URL:       server.URL + "/v1/",
+		AuthToken: "local-key",
+		Language:  "EN",
+		RedactPII: true,
+		Keywords:  []model.AudioKeyword{{Word: "furosemide"}},
+	})
+	s.Require().NoError(err)
+
+	transcript, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Call [PHONE] about the furosemide dose.", transcript)
+	s.Equal("/v1/audio/transcriptions", path)
+	s.Equal("Bearer local-key", authorization)
+	s.Equal("visit.wav", fileName)
+	s.Equal([]string{"whisper-1"}, form["model"])
+	s.Equal([]string{"json"}, form["response_format"])
+	s.Equal([]string{"en"}, form["language"])
+	s.Require().Len(form["prompt"], 1)
+	s.Contains(form["prompt"][0], `Common missed words: [{"word":"furosemide"`)
+	s.Equal("ollama", meta[model.MetadataKeyProvider])
+	s.Equal("en", meta[model.MetadataKeyLanguage])
+}
+
+func (s *AudioSuite) TestSubtitleAndJSONOutput() {
+	var responseFormat string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		responseFormat = r.FormValue("response_format")
+		if responseFormat == "vtt" {
+			_, _ = w.Write([]byte("WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nSwelling is better.\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"Swelling is better.","duration":1.5,"segments":[{"id":0,"start":0,"end":1.5,"text":" Swelling is better.","avg_logprob":-0.1}]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{URL: server.URL, OutputFormat: model.TranscriptFormatVTT})
+	s.Require().NoError(err)
+	subtitles, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("vtt", responseFormat)
+	s.Equal("WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nSwelling is better.\n", subtitles)
+
+	gen, err = NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{URL: server.URL, OutputFormat: model.TranscriptFormatSRT, RedactPII: true})
+	s.Require().NoError(err)
+	subtitles, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("verbose_json", responseFormat)
+	s.Equal("1\n00:00:00,000 --> 00:00:01,500\nSwelling is better.\n\n", subtitles)
+}
+
+func (s *AudioSuite) TestServerErrorsAndUnsupportedOptions() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	gen, err := NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{URL: server.URL})
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "status 503: model not loaded")
+
+	gen, err = NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{URL: server.URL, Diarize: true})
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "diarization")
+
+	_, err = NewAudioTranscriptionGenerator(" ", model.AudioOptions{})
+	s.Require().Error(err)
+}
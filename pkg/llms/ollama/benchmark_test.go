@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+type benchmarkSummary struct {
+	Summary  string   `json:"summary"`
+	Keywords []string `json:"keywords"`
+}
+
+func benchmarkServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.Contains(body, []byte(`"role":"tool"`)) {
+			_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"{\"summary\":\"stable\",\"keywords\":[\"ckd\"]}"},"done":true,"prompt_eval_count":80,"eval_count":20}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","tool_calls":[{"function":{"name":"lookup","arguments":{"id":1}}}]},"done":true,"prompt_eval_count":50,"eval_count":5}`))
+	}))
+}
+
+// BenchmarkStructuredGenerateToolRound measures client-side overhead of one
+// structured generation with a single tool round against a local mock server.
+func BenchmarkStructuredGenerateToolRound(b *testing.B) {
+	server := benchmarkServer()
+	defer server.Close()
+
+	tool := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return map[string]any{"egfr": 48.5}, nil
+	}}
+	opts := []model.GeneratorOption{
+		model.WithURL(server.URL),
+		model.WithModel("llama3.1"),
+		model.WithTools([]model.Tool{tool}),
+	}
+
+	previous := logging.GetLoggerFactory()
+	logging.SetLoggerFactory(logging.NopLoggerFactory{})
+	defer logging.SetLoggerFactory(previous)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gen, err := NewStructureContentGenerator[benchmarkSummary]("Summarize the chart.", opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := gen.Generate(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractJSONPayload(b *testing.B) {
+	text := "```json\n{\"summary\":\"stable\",\"keywords\":[\"ckd\",\"egfr\"]}\n```"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if extractJSONPayload(text) == "" {
+			b.Fatal("empty payload")
+		}
+	}
+}
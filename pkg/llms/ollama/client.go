@@ -1,13 +1,15 @@
 package ollama
 
 import (
-	"os"
+	"context"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
-	ollamasdk "github.com/rozoomcool/go-ollama-sdk"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 )
 
 const (
@@ -15,27 +17,46 @@ const (
 	defaultGenerationModelName = "llama3.1"
 	defaultEmbeddingModelName  = "nomic-embed-text"
 	defaultBaseURL             = "http://localhost:11434"
-	maxToolRounds              = 12
 )
 
 type client struct {
-	apiClient *ollamasdk.OllamaClient
-	baseURL   string
+	baseURL string
+	// transport is set for a TLS configuration (see model.WithTLSConfig) or a
+	// unix:// base URL; nil uses http.DefaultTransport.
+	transport http.RoundTripper
+	// compressMinBytes is the body size from which chat requests are gzipped;
+	// 0 disables compression (see model.WithRequestCompression).
+	compressMinBytes int
 }
 
-func newClient(cfg model.GeneratorConfig) *client {
-	baseURL := strings.TrimSpace(cfg.URL)
-	if baseURL == "" {
-		baseURL = strings.TrimSpace(os.Getenv("OLLAMA_BASE_URL"))
-	}
+func newClient(cfg model.GeneratorConfig) (*client, error) {
+	log := logging.NewLogger(context.Background())
+	baseURL := model.EnvFallback(log, providerName, "url", cfg.URL, "OLLAMA_BASE_URL")
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
+	return newClientWithBaseURL(cfg, baseURL)
+}
 
-	return &client{
-		apiClient: ollamasdk.NewClient(baseURL),
-		baseURL:   baseURL,
+// newClientWithBaseURL builds a client for baseURL, which may be a unix://
+// socket URL: the transport dials the socket and requests go to its HTTP
+// base URL.
+func newClientWithBaseURL(cfg model.GeneratorConfig, baseURL string) (*client, error) {
+	cfg.URL = baseURL
+	_, httpBaseURL, err := model.ParseUnixSocketURL(baseURL)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
 	}
+
+	c := &client{baseURL: httpBaseURL, compressMinBytes: model.ResolveRequestCompressionMinBytes(cfg)}
+	if transport := model.NewHTTPTransport(cfg); transport != nil {
+		c.transport = transport
+	}
+	return c, nil
+}
+
+func (c *client) httpClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: c.transport}
 }
 
 func resolveGenerationModelName(cfg model.GeneratorConfig) string {
@@ -0,0 +1,65 @@
+package ollama
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/testsupport"
+)
+
+type conformanceWire struct{}
+
+func (conformanceWire) DecodeRequest(r *http.Request) (testsupport.Request, error) {
+	var request ollamaChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return testsupport.Request{}, err
+	}
+
+	out := testsupport.Request{Model: request.Model}
+	for _, message := range request.Messages {
+		if message.Content == "" && message.Role != "tool" {
+			continue
+		}
+		out.Messages = append(out.Messages, testsupport.Message{Role: message.Role, Content: message.Content, ToolCallID: message.ToolCallID})
+	}
+	for _, tool := range request.Tools {
+		out.Tools = append(out.Tools, tool.Function.Name)
+	}
+	return out, nil
+}
+
+func (conformanceWire) WriteResponse(w http.ResponseWriter, turn testsupport.Turn) {
+	w.Header().Set("Content-Type", "application/json")
+	if turn.StatusCode >= http.StatusMultipleChoices {
+		w.WriteHeader(turn.StatusCode)
+		_ = json.NewEncoder(w).Encode(ollamaErrorResponse{Error: turn.ErrorMessage})
+		return
+	}
+
+	response := ollamaChatResponse{
+		Model:           "ollama-conformance",
+		Message:         ollamaChatMessage{Role: "assistant", Content: turn.Text},
+		Done:            true,
+		PromptEvalCount: turn.InputTokens,
+		EvalCount:       turn.OutputTokens,
+	}
+	for _, call := range turn.ToolCalls {
+		var arguments map[string]any
+		_ = json.Unmarshal(call.Arguments, &arguments)
+		response.Message.ToolCalls = append(response.Message.ToolCalls, ollamaToolCall{
+			ID:       call.ID,
+			Function: ollamaToolFunctionCall{Name: call.Name, Arguments: arguments},
+		})
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestConformance(t *testing.T) {
+	testsupport.RunConformance(t, testsupport.Harness{
+		Provider:      providerName,
+		Wire:          conformanceWire{},
+		NewString:     NewStringContentGenerator,
+		NewStructured: NewStructureContentGenerator[testsupport.Record],
+	})
+}
@@ -3,6 +3,7 @@ package ollama
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,15 +13,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/emulation"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
-	"github.com/invopop/jsonschema"
-	ollamasdk "github.com/rozoomcool/go-ollama-sdk"
 )
 
 type toolHandler func(ctx context.Context, args json.RawMessage) (any, error)
 
+var _ model.StreamingContentGenerator = (*textGenerator)(nil)
+
 type structuredGenerator[T any] struct {
 	client                 *client
 	prompt                 string
@@ -28,6 +31,8 @@ type structuredGenerator[T any] struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 type textGenerator struct {
@@ -37,6 +42,8 @@ type textGenerator struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
@@ -45,7 +52,10 @@ func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorO
 	}
 
 	cfg := model.ResolveGeneratorOpts(opts...)
-	c := newClient(cfg)
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 	return &structuredGenerator[T]{
 		client: c,
 		prompt: prompt,
@@ -59,7 +69,10 @@ func NewStringContentGenerator(prompt string, opts ...model.GeneratorOption) (mo
 	}
 
 	cfg := model.ResolveGeneratorOpts(opts...)
-	c := newClient(cfg)
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 	return &textGenerator{
 		client: c,
 		prompt: prompt,
@@ -120,33 +133,40 @@ func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider m
 }
 
 func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *structuredGenerator[T]) generate(ctx context.Context) (T, model.GenerationMetadata, error) {
 	start := time.Now()
 	modelName := resolveGenerationModelName(g.cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
 
 	log := logging.NewLogger(ctx)
-	messages, contextCount, err := g.messagesWithContext(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(g.cfg, log)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	schema, err := generateJSONSchema[T]()
+	messages, contextCount, err := g.messagesWithContext(ctx, meta)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
-	schemaInstruction, err := buildStructuredOutputInstruction(schema)
+
+	schema, err := model.StructuredOutputSchema[T](cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
-
-	allTools, cleanup, err := buildAllTools(ctx, g.cfg)
+	allTools, cleanup, err := buildAllTools(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -160,47 +180,95 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
+	emulateTools, err := resolveToolEmulation(cfg, modelName, len(modelTools) > 0, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
 
-	messages = append(messages, ollamasdk.ChatMessage{
-		Role:    "user",
-		Content: schemaInstruction,
-	})
+	mode, err := resolveStructuredOutputMode(cfg, len(modelTools) > 0)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		var zero T
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+	if mode == model.StructuredOutputModeAuto && formatSchemaUnsupported(g.client.baseURL) {
+		log.Debugf("format schema previously rejected by %q; using prompt schema instructions", g.client.baseURL)
+		mode = model.StructuredOutputModePrompt
+	}
 
 	log.Infof(
-		"prompt=%q context_count=%d model=%q tools=%d mcp_tools=%d base_url=%q",
+		"prompt=%q context_count=%d model=%q tools=%d mcp_tools=%d base_url=%q structured_output_mode=%s",
 		g.prompt,
 		contextCount,
 		modelName,
-		len(g.cfg.Tools),
-		len(g.cfg.MCPTools),
+		len(cfg.Tools),
+		len(cfg.MCPTools),
 		g.client.baseURL,
+		mode,
 	)
 
-	finalText, totals, err := runChatFlow(ctx, g.client, modelName, g.cfg, messages, modelTools, handlers)
-	if err != nil {
-		log.Errorf("error: %v", err)
-		var zero T
-		return zero, meta, utils.WrapIfNotNil(err)
+	var finalText string
+	var totals flowUsageTotals
+	var history []ollamaChatMessage
+	if mode != model.StructuredOutputModePrompt {
+		format, marshalErr := json.Marshal(schema)
+		if marshalErr != nil {
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(marshalErr)
+		}
+		finalText, totals, history, err = runChatFlow(ctx, g.client, modelName, cfg, messages, modelTools, handlers, emulateTools, format, nil)
+		switch {
+		case err == nil:
+			mode = model.StructuredOutputModeNative
+		case mode == model.StructuredOutputModeAuto && isFormatSchemaUnsupportedError(err):
+			log.Warnf("format schema rejected by %q, falling back to prompt schema instructions: %v", g.client.baseURL, err)
+			markFormatSchemaUnsupported(g.client.baseURL)
+			mode = model.StructuredOutputModePrompt
+		default:
+			g.recordHistory(modelName, history)
+			log.Errorf("error: %v", err)
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(err)
+		}
 	}
-	applyOllamaMetadata(meta, totals)
-
-	payload := extractJSONPayload(finalText)
-	var out T
-	err = json.Unmarshal([]byte(payload), &out)
-	if err == nil {
-		return out, meta, nil
+	if mode == model.StructuredOutputModePrompt {
+		schemaInstruction, instructionErr := buildStructuredOutputInstruction(schema)
+		if instructionErr != nil {
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(instructionErr)
+		}
+		messages = append(messages, ollamaChatMessage{
+			Role:    "user",
+			Content: schemaInstruction,
+		})
+		finalText, totals, history, err = runChatFlow(ctx, g.client, modelName, cfg, messages, modelTools, handlers, emulateTools, nil, nil)
 	}
-
-	// Ollama may return explanatory text after tool calls; do one repair round to force valid JSON.
-	log.Warnf("structured output parse failed, attempting repair: %v", err)
-	repaired, repairErr := g.repairStructuredJSON(ctx, modelName, schema, finalText)
-	if repairErr != nil {
+	g.recordHistory(modelName, history)
+	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
+	applyOllamaMetadata(meta, totals)
+	meta[model.MetadataKeyStructuredOutputMode] = string(mode)
 
-	err = json.Unmarshal([]byte(extractJSONPayload(repaired)), &out)
+	model.SetRawOutput(meta, cfg, finalText)
+	// Ollama may return explanatory text after tool calls, so one repair
+	// round is made by default.
+	out, err := model.DecodeStructuredOutputWithRepair[T](
+		ctx,
+		cfg,
+		meta,
+		schema,
+		finalText,
+		model.ResolveStructuredRepairAttempts(cfg, 1),
+		extractJSONPayload,
+		func(ctx context.Context, prompt string) (string, model.GenerationMetadata, error) {
+			return repairStructuredOutput(ctx, g.client, modelName, prompt)
+		},
+	)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -210,19 +278,41 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 }
 
 func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	return g.generate(ctx, nil)
+}
+
+// GenerateStream streams assistant text from /api/chat as it is produced.
+func (g *textGenerator) GenerateStream(ctx context.Context, onChunk model.StreamHandler) (string, model.GenerationMetadata, error) {
+	return g.generate(ctx, onChunk)
+}
+
+func (g *textGenerator) generate(ctx context.Context, onChunk model.StreamHandler) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generateText(ctx, onChunk)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generateText(ctx context.Context, onChunk model.StreamHandler) (string, model.GenerationMetadata, error) {
 	start := time.Now()
 	modelName := resolveGenerationModelName(g.cfg)
 	meta := initMetadata(modelName)
 	defer setLatencyMetadata(meta, start)
 
 	log := logging.NewLogger(ctx)
-	messages, contextCount, err := g.messagesWithContext(ctx)
+	cfg, err := normalizeGeneratorOptionsForProvider(g.cfg, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	messages, contextCount, err := g.messagesWithContext(ctx, meta)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 
-	allTools, cleanup, err := buildAllTools(ctx, g.cfg)
+	allTools, cleanup, err := buildAllTools(ctx, cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -234,34 +324,96 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
+	emulateTools, err := resolveToolEmulation(cfg, modelName, len(modelTools) > 0, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
 
 	log.Infof(
 		"prompt=%q context_count=%d model=%q tools=%d mcp_tools=%d base_url=%q",
 		g.prompt,
 		contextCount,
 		modelName,
-		len(g.cfg.Tools),
-		len(g.cfg.MCPTools),
+		len(cfg.Tools),
+		len(cfg.MCPTools),
 		g.client.baseURL,
 	)
 
-	finalText, totals, err := runChatFlow(ctx, g.client, modelName, g.cfg, messages, modelTools, handlers)
+	partial := &model.PartialStream{}
+	finalText, totals, history, err := runChatFlow(ctx, g.client, modelName, cfg, messages, modelTools, handlers, emulateTools, nil, partial.Wrap(onChunk))
+	g.recordHistory(modelName, history)
 	if err != nil {
+		if text, ok := partial.Salvage(ctx, cfg, meta, err); ok {
+			applyOllamaMetadata(meta, totals)
+			log.Warnf("deadline reached mid-stream, returning %d bytes of partial output: %v", len(text), err)
+			return text, meta, nil
+		}
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	applyOllamaMetadata(meta, totals)
 
-	finalText = strings.TrimSpace(finalText)
-	if finalText == "" {
-		err = errors.New("response output is empty")
+	finalText, err = model.FinishTextOutput(ctx, cfg, finalText)
+	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	return finalText, meta, nil
 }
 
-func (g *structuredGenerator[T]) messagesWithContext(ctx context.Context) ([]ollamasdk.ChatMessage, int, error) {
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *structuredGenerator[T]) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg, g.client)
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *textGenerator) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg, g.client)
+}
+
+// effectiveConfig applies the option normalization, model resolution and
+// endpoint of a generation to cfg. A unix:// URL is kept as configured.
+func effectiveConfig(ctx context.Context, cfg model.GeneratorConfig, client *client) (model.GeneratorConfig, error) {
+	cfg, err := normalizeGeneratorOptionsForProvider(cfg, logging.NewLogger(ctx))
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	modelName := resolveGenerationModelName(cfg)
+	cfg.Model = &modelName
+	if strings.TrimSpace(cfg.URL) == "" {
+		cfg.URL = client.baseURL
+	}
+	return cfg, nil
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *structuredGenerator[T]) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *structuredGenerator[T]) recordHistory(modelName string, history []ollamaChatMessage) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, history)
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *textGenerator) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *textGenerator) recordHistory(modelName string, history []ollamaChatMessage) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, history)
+}
+
+func (g *structuredGenerator[T]) messagesWithContext(ctx context.Context, meta model.GenerationMetadata) ([]ollamaChatMessage, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -275,10 +427,22 @@ func (g *structuredGenerator[T]) messagesWithContext(ctx context.Context) ([]oll
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
 	return buildMessagesWithContext(g.prompt, contexts)
 }
 
-func (g *textGenerator) messagesWithContext(ctx context.Context) ([]ollamasdk.ChatMessage, int, error) {
+func (g *textGenerator) messagesWithContext(ctx context.Context, meta model.GenerationMetadata) ([]ollamaChatMessage, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -292,11 +456,23 @@ func (g *textGenerator) messagesWithContext(ctx context.Context) ([]ollamasdk.Ch
 		contexts = append(contexts, provided...)
 	}
 
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
 	return buildMessagesWithContext(g.prompt, contexts)
 }
 
-func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) ([]ollamasdk.ChatMessage, int, error) {
-	messages := make([]ollamasdk.ChatMessage, 0, len(contexts)+1)
+func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) ([]ollamaChatMessage, int, error) {
+	messages := make([]ollamaChatMessage, 0, len(contexts)+1)
 	contextCount := 0
 
 	for _, contextItem := range contexts {
@@ -305,10 +481,19 @@ func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) ([
 		}
 
 		content := strings.TrimSpace(contextItem.Content)
-		if content == "" {
+		if !contextItem.HasContent() {
 			continue
 		}
 
+		images, err := model.ResolveImageParts(contextItem)
+		if err != nil {
+			return nil, 0, utils.WrapIfNotNil(err)
+		}
+		encodedImages, err := encodeOllamaImages(images)
+		if err != nil {
+			return nil, 0, utils.WrapIfNotNil(err)
+		}
+
 		contextCount++
 		role := "user"
 		switch contextItem.MessageType {
@@ -322,13 +507,14 @@ func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) ([
 			role = "user"
 		}
 
-		messages = append(messages, ollamasdk.ChatMessage{
+		messages = append(messages, ollamaChatMessage{
 			Role:    role,
 			Content: content,
+			Images:  encodedImages,
 		})
 	}
 
-	messages = append(messages, ollamasdk.ChatMessage{
+	messages = append(messages, ollamaChatMessage{
 		Role:    "user",
 		Content: prompt,
 	})
@@ -336,9 +522,26 @@ func buildMessagesWithContext(prompt string, contexts []*model.PromptContext) ([
 	return messages, contextCount, nil
 }
 
+// encodeOllamaImages base64-encodes image bytes for the message images
+// field. Ollama does not fetch URLs, so URL images are rejected.
+func encodeOllamaImages(images []model.ImagePart) ([]string, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+	encoded := make([]string, 0, len(images))
+	for _, image := range images {
+		if len(image.Data) == 0 {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("image url %q is not supported for ollama provider; pass image bytes", image.URL))
+		}
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(image.Data))
+	}
+	return encoded, nil
+}
+
 type flowUsageTotals struct {
 	APICalls     int
 	ToolRounds   int
+	ToolCalls    []model.ToolCallRecord
 	InputTokens  int64
 	OutputTokens int64
 	TotalTokens  int64
@@ -349,7 +552,11 @@ type ollamaChatRequest struct {
 	Messages []ollamaChatMessage `json:"messages"`
 	Stream   bool                `json:"stream"`
 	Tools    []ollamaToolDef     `json:"tools,omitempty"`
+	Format   json.RawMessage     `json:"format,omitempty"`
 	Options  *ollamaChatOptions  `json:"options,omitempty"`
+
+	// ProviderParams are merged into the encoded body (see model.WithProviderParams).
+	ProviderParams map[string]any `json:"-"`
 }
 
 type ollamaChatResponse struct {
@@ -368,6 +575,7 @@ type ollamaErrorResponse struct {
 type ollamaChatMessage struct {
 	Role       string           `json:"role"`
 	Content    string           `json:"content,omitempty"`
+	Images     []string         `json:"images,omitempty"`
 	ToolCalls  []ollamaToolCall `json:"tool_calls,omitempty"`
 	Name       string           `json:"name,omitempty"`
 	ToolName   string           `json:"tool_name,omitempty"`
@@ -406,32 +614,59 @@ func runChatFlow(
 	c *client,
 	modelName string,
 	cfg model.GeneratorConfig,
-	initialMessages []ollamasdk.ChatMessage,
+	initialMessages []ollamaChatMessage,
 	tools []model.Tool,
 	handlers map[string]toolHandler,
-) (string, flowUsageTotals, error) {
-	history := make([]ollamaChatMessage, 0, len(initialMessages)+2)
-	for _, message := range initialMessages {
+	emulateTools bool,
+	format json.RawMessage,
+	onChunk model.StreamHandler,
+) (string, flowUsageTotals, []ollamaChatMessage, error) {
+	history := make([]ollamaChatMessage, 0, len(initialMessages)+3)
+	toolDefs := buildOllamaToolDefs(tools)
+	if emulateTools {
+		// The model cannot accept native tool definitions; describe them in the prompt instead.
+		instructions, err := emulation.BuildToolInstructions(tools)
+		if err != nil {
+			return "", flowUsageTotals{}, nil, utils.WrapIfNotNil(err)
+		}
 		history = append(history, ollamaChatMessage{
-			Role:    message.Role,
-			Content: message.Content,
+			Role:    "system",
+			Content: instructions,
 		})
+		toolDefs = nil
 	}
+	history = append(history, initialMessages...)
 
-	toolDefs := buildOllamaToolDefs(tools)
 	options := buildOllamaChatOptions(cfg)
 	totals := flowUsageTotals{}
 
-	for round := 0; round < maxToolRounds; round++ {
-		response, err := c.chat(ctx, ollamaChatRequest{
+	// Emulated tool commands arrive as plain text, so they cannot be streamed safely.
+	streamDeltas := onChunk != nil && !emulateTools
+
+	maxRounds := model.ResolveMaxToolRounds(cfg)
+	toolErrors := model.NewToolErrorPolicy(cfg)
+	log := logging.NewLogger(ctx)
+	for round := 0; round < maxRounds; round++ {
+		request := ollamaChatRequest{
 			Model:    modelName,
 			Messages: history,
-			Stream:   false,
+			Stream:   streamDeltas,
 			Tools:    toolDefs,
+			Format:   format,
 			Options:  options,
-		})
+
+			ProviderParams: cfg.ProviderParams,
+		}
+
+		var response *ollamaChatResponse
+		var err error
+		if streamDeltas {
+			response, err = c.chatStream(ctx, request, onChunk)
+		} else {
+			response, err = c.chat(ctx, request)
+		}
 		if err != nil {
-			return "", totals, utils.WrapIfNotNil(err)
+			return "", totals, history, utils.WrapIfNotNil(err)
 		}
 
 		totals.APICalls++
@@ -446,37 +681,81 @@ func runChatFlow(
 		assistantMessage.Content = strings.TrimSpace(assistantMessage.Content)
 
 		toolCalls := assistantMessage.ToolCalls
-		if len(tools) == 0 {
-			return assistantMessage.Content, totals, nil
+		if emulateTools {
+			toolCalls = nil
+			if command, ok := emulation.ParseToolCall(assistantMessage.Content); ok {
+				toolCalls = []ollamaToolCall{{
+					Function: ollamaToolFunctionCall{
+						Name:      command.Name,
+						Arguments: command.Arguments,
+					},
+				}}
+			}
 		}
-		if len(toolCalls) == 0 {
-			return assistantMessage.Content, totals, nil
+		if len(tools) == 0 || len(toolCalls) == 0 {
+			if onChunk != nil && !streamDeltas && assistantMessage.Content != "" {
+				if err := onChunk(model.StreamChunk{Text: assistantMessage.Content}); err != nil {
+					return "", totals, history, utils.WrapIfNotNil(err)
+				}
+			}
+			history = append(history, assistantMessage)
+			return assistantMessage.Content, totals, history, nil
 		}
 
 		history = append(history, assistantMessage)
 		totals.ToolRounds = round + 1
 
+		handlerNames := make([]string, 0, len(toolCalls))
+		callHandlers := make([]toolHandler, 0, len(toolCalls))
+		callArgs := make([]json.RawMessage, 0, len(toolCalls))
 		for _, toolCall := range toolCalls {
 			handlerName, handler, err := resolveToolHandler(toolCall.Function.Name, handlers)
 			if err != nil {
-				return "", totals, utils.WrapIfNotNil(err)
+				return "", totals, history, utils.WrapIfNotNil(err)
 			}
 
 			argsBytes, err := normalizeToolArguments(toolCall.Function.Arguments)
 			if err != nil {
-				return "", totals, utils.WrapIfNotNil(err)
+				return "", totals, history, utils.WrapIfNotNil(err)
 			}
+			handlerNames = append(handlerNames, handlerName)
+			callHandlers = append(callHandlers, handler)
+			callArgs = append(callArgs, argsBytes)
+		}
 
-			result, callErr := handler(ctx, argsBytes)
-			resultPayload := any(result)
+		results := toolexec.Run(ctx, len(toolCalls), model.ResolveToolParallelism(cfg), func(ctx context.Context, i int) (any, error) {
+			return callHandlers[i](ctx, callArgs[i])
+		})
+		totals.ToolCalls = append(totals.ToolCalls, toolexec.Transcript(round+1, results, func(i int) (string, string) {
+			return handlerNames[i], toolCalls[i].ID
+		})...)
+
+		for i, toolCall := range toolCalls {
+			handlerName := handlerNames[i]
+			callErr := results[i].Err
+			resultPayload := results[i].Value
 			if callErr != nil {
-				resultPayload = map[string]any{
-					"error": callErr.Error(),
+				if abortErr := toolErrors.Handle(handlerName, callErr); abortErr != nil {
+					return "", totals, history, utils.WrapIfNotNil(abortErr)
+				}
+				log.Warnf("tool %q failed, reporting to model: %v", handlerName, callErr)
+				resultPayload = model.ToolErrorResult(callErr)
+			}
+			if emulateTools {
+				resultMessage, err := emulation.FormatToolResult(handlerName, resultPayload)
+				if err != nil {
+					return "", totals, history, utils.WrapIfNotNil(err)
 				}
+				history = append(history, ollamaChatMessage{
+					Role:    "user",
+					Content: resultMessage,
+				})
+				continue
 			}
+
 			resultBytes, err := json.Marshal(resultPayload)
 			if err != nil {
-				return "", totals, utils.WrapIfNotNil(err)
+				return "", totals, history, utils.WrapIfNotNil(err)
 			}
 
 			history = append(history, ollamaChatMessage{
@@ -489,40 +768,155 @@ func runChatFlow(
 		}
 	}
 
-	return "", totals, utils.WrapIfNotNil(fmt.Errorf("exceeded tool call loop limit (%d)", maxToolRounds))
+	return "", totals, history, utils.WrapIfNotNil(&model.MaxToolRoundsError{Limit: maxRounds})
 }
 
 func (c *client) chat(ctx context.Context, request ollamaChatRequest) (*ollamaChatResponse, error) {
+	httpResponse, err := c.postChat(ctx, request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	defer httpResponse.Body.Close()
+
+	rawBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	var response ollamaChatResponse
+	if err := json.Unmarshal(rawBody, &response); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if strings.TrimSpace(response.Error) != "" {
+		return nil, utils.WrapIfNotNil(errors.New(strings.TrimSpace(response.Error)))
+	}
+
+	return &response, nil
+}
+
+// chatStream reads the newline-delimited JSON stream from /api/chat, forwards
+// content deltas to onChunk, and returns the aggregated response. When the
+// request offers tools, a round's deltas are held until it ends and forwarded
+// only if it called no tools, so narration before a tool call never reaches
+// onChunk. A round that fails before calling a tool forwards its text before
+// returning the error.
+func (c *client) chatStream(ctx context.Context, request ollamaChatRequest, onChunk model.StreamHandler) (*ollamaChatResponse, error) {
+	request.Stream = true
+	httpResponse, err := c.postChat(ctx, request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	defer httpResponse.Body.Close()
+
+	holdDeltas := len(request.Tools) > 0
+	var held []string
+	aggregated := &ollamaChatResponse{}
+	forwardHeld := func() error {
+		if onChunk == nil || len(aggregated.Message.ToolCalls) > 0 {
+			return nil
+		}
+		for _, delta := range held {
+			if err := onChunk(model.StreamChunk{Text: delta}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var content strings.Builder
+	decoder := json.NewDecoder(httpResponse.Body)
+	for {
+		var chunk ollamaChatResponse
+		err := decoder.Decode(&chunk)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A round cut short before calling a tool (typically by the
+			// deadline) hands over its text so far, so
+			// model.WithPartialStreamOnDeadline can return the answer.
+			if forwardErr := forwardHeld(); forwardErr != nil {
+				return nil, utils.WrapIfNotNil(forwardErr)
+			}
+			return nil, utils.WrapIfNotNil(err)
+		}
+		if strings.TrimSpace(chunk.Error) != "" {
+			return nil, utils.WrapIfNotNil(errors.New(strings.TrimSpace(chunk.Error)))
+		}
+
+		if chunk.Message.Role != "" {
+			aggregated.Message.Role = chunk.Message.Role
+		}
+		aggregated.Message.ToolCalls = append(aggregated.Message.ToolCalls, chunk.Message.ToolCalls...)
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if holdDeltas {
+				held = append(held, chunk.Message.Content)
+			} else if onChunk != nil {
+				if err := onChunk(model.StreamChunk{Text: chunk.Message.Content}); err != nil {
+					return nil, utils.WrapIfNotNil(err)
+				}
+			}
+		}
+
+		if chunk.Done {
+			aggregated.Model = chunk.Model
+			aggregated.Done = true
+			aggregated.PromptEvalCount = chunk.PromptEvalCount
+			aggregated.EvalCount = chunk.EvalCount
+			break
+		}
+	}
+
+	if err := forwardHeld(); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	aggregated.Message.Content = content.String()
+	return aggregated, nil
+}
+
+func (c *client) postChat(ctx context.Context, request ollamaChatRequest) (*http.Response, error) {
 	body, err := json.Marshal(request)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
+	body, err = model.MergeProviderParams(body, request.ProviderParams)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 
-	httpRequest, err := http.NewRequestWithContext(
+	httpRequest, _, err := model.NewCompressedRequest(
 		ctx,
 		http.MethodPost,
 		strings.TrimRight(c.baseURL, "/")+"/api/chat",
-		bytes.NewReader(body),
+		body,
+		c.compressMinBytes,
 	)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
 	httpRequest.Header.Set("Content-Type", "application/json")
-	httpRequest.Header.Set("Accept", "application/json")
+	if request.Stream {
+		httpRequest.Header.Set("Accept", "application/x-ndjson")
+	} else {
+		httpRequest.Header.Set("Accept", "application/json")
+	}
 
-	httpClient := &http.Client{Timeout: 180 * time.Second}
-	httpResponse, err := httpClient.Do(httpRequest)
+	httpResponse, err := c.httpClient(180 * time.Second).Do(httpRequest)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
 	}
-	defer httpResponse.Body.Close()
-
-	rawBody, err := io.ReadAll(httpResponse.Body)
-	if err != nil {
+	if err := model.DecompressResponse(httpResponse); err != nil {
+		httpResponse.Body.Close()
 		return nil, utils.WrapIfNotNil(err)
 	}
 
 	if httpResponse.StatusCode < http.StatusOK || httpResponse.StatusCode >= http.StatusMultipleChoices {
+		defer httpResponse.Body.Close()
+		rawBody, err := io.ReadAll(httpResponse.Body)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+
 		var apiError ollamaErrorResponse
 		if unmarshalErr := json.Unmarshal(rawBody, &apiError); unmarshalErr == nil && strings.TrimSpace(apiError.Error) != "" {
 			return nil, utils.WrapIfNotNil(
@@ -534,15 +928,7 @@ func (c *client) chat(ctx context.Context, request ollamaChatRequest) (*ollamaCh
 		)
 	}
 
-	var response ollamaChatResponse
-	if err := json.Unmarshal(rawBody, &response); err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
-	if strings.TrimSpace(response.Error) != "" {
-		return nil, utils.WrapIfNotNil(errors.New(strings.TrimSpace(response.Error)))
-	}
-
-	return &response, nil
+	return httpResponse, nil
 }
 
 func applyOllamaMetadata(meta model.GenerationMetadata, totals flowUsageTotals) {
@@ -552,6 +938,7 @@ func applyOllamaMetadata(meta model.GenerationMetadata, totals flowUsageTotals)
 
 	meta[model.MetadataKeyAPICalls] = fmt.Sprintf("%d", totals.APICalls)
 	meta[model.MetadataKeyToolRounds] = fmt.Sprintf("%d", totals.ToolRounds)
+	model.SetToolCalls(meta, totals.ToolCalls)
 	meta[model.MetadataKeyInputTokens] = fmt.Sprintf("%d", totals.InputTokens)
 	meta[model.MetadataKeyOutputTokens] = fmt.Sprintf("%d", totals.OutputTokens)
 	meta[model.MetadataKeyTotalTokens] = fmt.Sprintf("%d", totals.TotalTokens)
@@ -585,6 +972,16 @@ func buildOllamaToolDefs(tools []model.Tool) []ollamaToolDef {
 	return out
 }
 
+// normalizeGeneratorOptionsForProvider validates sampling options. Ollama
+// accepts any non-negative temperature and no output limits are registered.
+func normalizeGeneratorOptionsForProvider(cfg model.GeneratorConfig, log logging.Logger) (model.GeneratorConfig, error) {
+	cfg, err := model.ApplyGenerationLimits(cfg, providerName, resolveGenerationModelName(cfg), model.GenerationLimits{}, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	return cfg, nil
+}
+
 func buildOllamaChatOptions(cfg model.GeneratorConfig) *ollamaChatOptions {
 	if cfg.Temperature == nil && cfg.MaxTokens == nil {
 		return nil
@@ -662,27 +1059,45 @@ func normalizeToolArguments(arguments any) (json.RawMessage, error) {
 	}
 }
 
-func generateJSONSchema[T any]() (map[string]any, error) {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
+// resolveStructuredOutputMode picks how the structured generator asks for
+// JSON. Native mode sends the schema as the chat format, which constrains
+// every answer and so cannot be combined with tool calls; auto mode falls
+// back to schema instructions when tools are configured.
+func resolveStructuredOutputMode(cfg model.GeneratorConfig, hasTools bool) (model.StructuredOutputMode, error) {
+	mode := model.ResolveStructuredOutputMode(cfg)
+	if !hasTools || mode == model.StructuredOutputModePrompt {
+		return mode, nil
+	}
+	if mode == model.StructuredOutputModeNative {
+		return "", utils.WrapIfNotNil(errors.New("native structured output cannot be combined with tools"))
 	}
+	return model.StructuredOutputModePrompt, nil
+}
 
-	var value T
-	schema := reflector.Reflect(value)
+// formatSchemaUnsupportedURLs remembers base URLs of servers that rejected a
+// schema as the chat format (Ollama before 0.5 only accepts "json"), so later
+// structured generations in StructuredOutputModeAuto skip straight to prompt
+// mode.
+var formatSchemaUnsupportedURLs sync.Map
 
-	schemaJSON, err := json.Marshal(schema)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
+func formatSchemaUnsupported(baseURL string) bool {
+	_, found := formatSchemaUnsupportedURLs.Load(strings.TrimSpace(baseURL))
+	return found
+}
 
-	var schemaMap map[string]any
-	err = json.Unmarshal(schemaJSON, &schemaMap)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
-	}
+func markFormatSchemaUnsupported(baseURL string) {
+	formatSchemaUnsupportedURLs.Store(strings.TrimSpace(baseURL), struct{}{})
+}
 
-	return schemaMap, nil
+// isFormatSchemaUnsupportedError reports whether err is a server refusing a
+// schema as the chat format. Older servers decode format as a string and
+// answer with a 400 naming the format field.
+func isFormatSchemaUnsupportedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, fmt.Sprintf("status %d", http.StatusBadRequest)) && strings.Contains(message, "format")
 }
 
 func buildStructuredOutputInstruction(schema map[string]any) (string, error) {
@@ -694,35 +1109,37 @@ func buildStructuredOutputInstruction(schema map[string]any) (string, error) {
 	return "Return ONLY valid JSON matching this schema. Do not include markdown fences.\n" + string(schemaBytes), nil
 }
 
-func (g *structuredGenerator[T]) repairStructuredJSON(
+// repairStructuredOutput sends a structured output repair prompt as a new
+// single-turn chat without tools.
+func repairStructuredOutput(
 	ctx context.Context,
+	c *client,
 	modelName string,
-	schema map[string]any,
-	rawOutput string,
-) (string, error) {
-	schemaBytes, err := json.Marshal(schema)
-	if err != nil {
-		return "", utils.WrapIfNotNil(err)
-	}
-
-	messages := []ollamasdk.ChatMessage{
+	prompt string,
+) (string, model.GenerationMetadata, error) {
+	meta := initMetadata(modelName)
+	messages := []ollamaChatMessage{
 		{
 			Role:    "system",
-			Content: "You are a strict JSON formatter.",
+			Content: model.StructuredRepairSystemPrompt,
 		},
 		{
-			Role: "user",
-			Content: "Reformat the following output into valid JSON matching this schema. Return only JSON.\n\n" +
-				"Schema:\n" + string(schemaBytes) + "\n\n" +
-				"Output:\n" + rawOutput,
+			Role:    "user",
+			Content: prompt,
 		},
 	}
 
-	text, err := g.client.apiClient.Chat(modelName, messages)
+	response, err := c.chat(ctx, ollamaChatRequest{Model: modelName, Messages: messages})
 	if err != nil {
-		return "", utils.WrapIfNotNil(err)
+		return "", meta, utils.WrapIfNotNil(err)
 	}
-	return strings.TrimSpace(text), nil
+	applyOllamaMetadata(meta, flowUsageTotals{
+		APICalls:     1,
+		InputTokens:  response.PromptEvalCount,
+		OutputTokens: response.EvalCount,
+		TotalTokens:  response.PromptEvalCount + response.EvalCount,
+	})
+	return strings.TrimSpace(response.Message.Content), meta, nil
 }
 
 func extractJSONPayload(text string) string {
@@ -0,0 +1,252 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type ContentSuite struct {
+	suite.Suite
+}
+
+func TestContentSuite(t *testing.T) {
+	suite.Run(t, new(ContentSuite))
+}
+
+func (s *ContentSuite) TestGenerateToStreamsChatDeltas() {
+	var streamRequested bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ollamaChatRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		streamRequested = request.Stream
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(
+			`{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}` + "\n" +
+				`{"model":"llama3.1","message":{"role":"assistant","content":"lo"},"done":false}` + "\n" +
+				`{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":4,"eval_count":2}` + "\n",
+		))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello", model.WithURL(server.URL))
+	s.Require().NoError(err)
+
+	var out strings.Builder
+	meta, err := model.GenerateTo(context.Background(), gen, &out)
+
+	s.Require().NoError(err)
+	s.True(streamRequested)
+	s.Equal("Hello", out.String())
+	s.Equal("4", meta[model.MetadataKeyInputTokens])
+	s.Equal("2", meta[model.MetadataKeyOutputTokens])
+}
+
+func (s *ContentSuite) TestGenerateToWritesDisclosure() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(
+			`{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}` + "\n" +
+				`{"model":"llama3.1","message":{"role":"assistant","content":"lo"},"done":true}` + "\n",
+		))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello", model.WithURL(server.URL), model.WithDisclosure(model.DisclosureConfig{}))
+	s.Require().NoError(err)
+
+	var out strings.Builder
+	meta, err := model.GenerateTo(context.Background(), gen, &out)
+
+	s.Require().NoError(err)
+	s.Equal("Hello\n\n"+model.DefaultDisclosureNotice, out.String())
+	s.Equal(model.DefaultDisclosureNotice, meta[model.MetadataKeyAIDisclosure])
+}
+
+func (s *ContentSuite) TestChatStreamReturnsStreamError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"error":"model not found"}` + "\n"))
+	}))
+	defer server.Close()
+
+	c, err := newClient(model.ResolveGeneratorOpts(model.WithURL(server.URL)))
+	s.Require().NoError(err)
+	_, err = c.chatStream(context.Background(), ollamaChatRequest{Model: "missing"}, nil)
+	s.Error(err)
+	s.Contains(err.Error(), "model not found")
+}
+
+func (s *ContentSuite) TestExportHistoryRecordsFlow() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"Hi there"},"done":true}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello", model.WithURL(server.URL), model.WithModel("llama3.1"))
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "be brief")
+
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	exporter, ok := gen.(model.HistoryExporter)
+	s.Require().True(ok)
+	history := exporter.ExportHistory()
+	s.Equal(model.ConversationHistoryVersion, history.Version)
+	s.Equal(providerName, history.Provider)
+	s.Equal("llama3.1", history.Model)
+	s.Require().Len(history.Messages, 3)
+	s.Equal(model.HistoryRoleSystem, history.Messages[0].Role)
+	s.Equal(model.HistoryRoleUser, history.Messages[1].Role)
+	s.Equal("Say hello", history.Messages[1].Content)
+	s.Equal(model.HistoryRoleAssistant, history.Messages[2].Role)
+	s.Equal("Hi there", history.Messages[2].Content)
+}
+
+func (s *ContentSuite) TestToolCallsInOneRoundRunConcurrently() {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","tool_calls":[` +
+				`{"function":{"name":"wait","arguments":{"id":1}}},` +
+				`{"function":{"name":"wait","arguments":{"id":2}}}]},"done":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"done"},"done":true}`))
+	}))
+	defer server.Close()
+
+	// Each handler blocks until the other has started, so sequential execution would time out.
+	var started sync.WaitGroup
+	started.Add(2)
+	bothStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(bothStarted)
+	}()
+	var sequential atomic.Int32
+	tool := model.Tool{
+		Name: "wait",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			started.Done()
+			select {
+			case <-bothStarted:
+				return "ok", nil
+			case <-time.After(time.Second):
+				sequential.Add(1)
+				return nil, errors.New("tool calls did not overlap")
+			}
+		},
+	}
+
+	gen, err := NewStringContentGenerator("go", model.WithURL(server.URL), model.WithModel("llama3.1"), model.WithTools([]model.Tool{tool}))
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("done", out)
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	s.Zero(sequential.Load())
+}
+
+func (s *ContentSuite) TestWithMaxToolRoundsLimitsFlow() {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","tool_calls":[{"function":{"name":"again","arguments":{}}}]},"done":true}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "again", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+	gen, err := NewStringContentGenerator("loop",
+		model.WithURL(server.URL),
+		model.WithModel("llama3.1"),
+		model.WithTools([]model.Tool{tool}),
+		model.WithMaxToolRounds(2),
+	)
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.ErrorIs(err, model.ErrMaxToolRoundsExceeded)
+	var roundsErr *model.MaxToolRoundsError
+	s.Require().ErrorAs(err, &roundsErr)
+	s.Equal(2, roundsErr.Limit)
+	s.Equal(2, requests)
+}
+
+func (s *ContentSuite) TestWithToolInterceptorWrapsHandlers() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.Contains(body, []byte(`"role":"tool"`)) {
+			s.Contains(string(body), "mocked-weather")
+			_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"done"},"done":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","tool_calls":[{"function":{"name":"weather","arguments":{"city":"Oslo"}}}]},"done":true}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "weather", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		s.Fail("handler should be mocked")
+		return nil, nil
+	}}
+	var seen []string
+	mock := model.ToolInterceptorFuncs{Before: func(ctx context.Context, call *model.ToolInvocation) (any, bool, error) {
+		seen = append(seen, call.Name+" "+string(call.Arguments))
+		return "mocked-weather", true, nil
+	}}
+	gen, err := NewStringContentGenerator("weather?",
+		model.WithURL(server.URL),
+		model.WithModel("llama3.1"),
+		model.WithTools([]model.Tool{tool}),
+		model.WithToolInterceptor(mock),
+	)
+	s.Require().NoError(err)
+
+	out, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("done", out)
+	s.Equal([]string{`weather {"city":"Oslo"}`}, seen)
+}
+
+func (s *ContentSuite) TestBuildMessagesWithImageContext() {
+	messages, contextCount, err := buildMessagesWithContext("final prompt", []*model.PromptContext{
+		model.ImagePromptContext("What does the scan show?", model.ImagePart{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")}),
+	})
+	s.Require().NoError(err)
+	s.Equal(1, contextCount)
+	s.Require().Len(messages, 2)
+	s.Equal([]string{"iVBORw0KGgoAAAANSUhEUg=="}, messages[0].Images)
+
+	_, _, err = buildMessagesWithContext("final prompt", []*model.PromptContext{
+		model.ImagePromptContext("", model.ImagePart{URL: "https://example.org/xray.jpg"}),
+	})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not supported for ollama provider")
+}
+
+func (s *ContentSuite) TestResolveStructuredOutputModeWithTools() {
+	mode, err := resolveStructuredOutputMode(model.GeneratorConfig{}, true)
+	s.Require().NoError(err)
+	s.Equal(model.StructuredOutputModePrompt, mode, "auto falls back to prompt mode with tools")
+
+	native := model.StructuredOutputModeNative
+	_, err = resolveStructuredOutputMode(model.GeneratorConfig{StructuredOutputMode: &native}, true)
+	s.ErrorContains(err, "native structured output cannot be combined with tools")
+}
@@ -0,0 +1,536 @@
+package ollama
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+// ContractSuite checks the /api/chat and /api/embed clients against a fake
+// server, so request shape, error handling, streaming and metadata are
+// verified without a running Ollama.
+type ContractSuite struct {
+	suite.Suite
+}
+
+func TestContractSuite(t *testing.T) {
+	suite.Run(t, new(ContractSuite))
+}
+
+func (s *ContractSuite) TestChatRequestShapeAndMetadata() {
+	var request ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal(http.MethodPost, r.Method)
+		s.Equal("/api/chat", r.URL.Path)
+		s.Equal("application/json", r.Header.Get("Accept"))
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"model":"llama3.1:8b","message":{"role":"assistant","content":"hello"},"done":true,"prompt_eval_count":7,"eval_count":2}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello.",
+		model.WithURL(server.URL+"/"),
+		model.WithModel("llama3.1"),
+		model.WithTemperature(0.1),
+		model.WithMaxTokens(16),
+	)
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+
+	s.Equal("llama3.1", request.Model)
+	s.False(request.Stream)
+	s.Require().NotNil(request.Options)
+	s.Require().NotNil(request.Options.NumPredict)
+	s.Equal(16, *request.Options.NumPredict)
+	s.Require().Len(request.Messages, 2)
+	s.Equal("system", request.Messages[0].Role)
+	s.Equal("user", request.Messages[1].Role)
+	s.Equal("Say hello.", request.Messages[1].Content)
+
+	s.Equal(providerName, meta[model.MetadataKeyProvider])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+	s.Equal("7", meta[model.MetadataKeyInputTokens])
+	s.Equal("2", meta[model.MetadataKeyOutputTokens])
+	s.Equal("9", meta[model.MetadataKeyTotalTokens])
+}
+
+func (s *ContractSuite) TestExperimentTagsAreRecorded() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"hello"},"done":true}`))
+	}))
+	defer server.Close()
+
+	var records []model.GenerationRecord
+	gen, err := NewStringContentGenerator("Say hello.",
+		model.WithURL(server.URL),
+		model.WithPromptVersion("greeting-v2"),
+		model.WithExperiment("greeting", "short"),
+		model.WithMetricsRecorder(model.MetricsRecorderFunc(func(ctx context.Context, record model.GenerationRecord) {
+			records = append(records, record)
+		})),
+	)
+	s.Require().NoError(err)
+
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("greeting-v2", meta[model.MetadataKeyPromptVersion])
+	s.Equal("greeting", meta[model.MetadataKeyExperiment])
+	s.Equal("short", meta[model.MetadataKeyExperimentVariant])
+	s.Require().Len(records, 1)
+	s.Equal(model.ExperimentLabels(meta), records[0].Labels)
+}
+
+func (s *ContractSuite) TestStructuredOutputUsesFormatSchema() {
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"{\"status\":\"ok\"}"},"done":true}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	gen, err := NewStructureContentGenerator[status]("Report status.", model.WithURL(server.URL))
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModeNative), meta[model.MetadataKeyStructuredOutputMode])
+	format, ok := request["format"].(map[string]any)
+	s.Require().True(ok, "format should carry the schema")
+	s.Equal("object", format["type"])
+	messages := request["messages"].([]any)
+	s.Require().Len(messages, 1)
+	s.Equal("Report status.", messages[0].(map[string]any)["content"])
+}
+
+func (s *ContractSuite) TestStructuredOutputFallsBackForOldServers() {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if _, ok := request["format"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"json: cannot unmarshal object into Go struct field ChatRequest.format of type string"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"{\"status\":\"ok\"}"},"done":true}`))
+	}))
+	defer server.Close()
+
+	type status struct {
+		Status string `json:"status"`
+	}
+	gen, err := NewStructureContentGenerator[status]("Report status.", model.WithURL(server.URL))
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModePrompt), meta[model.MetadataKeyStructuredOutputMode])
+	s.Require().Len(requests, 2)
+	messages := requests[1]["messages"].([]any)
+	s.Require().Len(messages, 2)
+	s.Contains(messages[1].(map[string]any)["content"], "Return ONLY valid JSON")
+
+	// The rejection is remembered, so the next generation skips the format.
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Require().Len(requests, 3)
+	s.NotContains(requests[2], "format")
+
+	native, err := NewStructureContentGenerator[status]("Report status.",
+		model.WithURL(server.URL),
+		model.WithStructuredOutputMode(model.StructuredOutputModeNative),
+	)
+	s.Require().NoError(err)
+	_, _, err = native.Generate(context.Background())
+	s.ErrorContains(err, "status 400")
+}
+
+func (s *ContractSuite) TestChatErrorBodies() {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "json error", status: http.StatusNotFound, body: `{"error":"model \"nope\" not found, try pulling it first"}`, want: `ollama chat request failed with status 404: model "nope" not found`},
+		{name: "plain text", status: http.StatusBadGateway, body: "bad gateway", want: "ollama chat request failed with status 502: bad gateway"},
+		{name: "error in 200 body", status: http.StatusOK, body: `{"error":"out of memory"}`, want: "out of memory"},
+		{name: "malformed json", status: http.StatusOK, body: `{"message":{"content":`, want: "unexpected end of JSON input"},
+	}
+
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			gen, err := NewStringContentGenerator("hi", model.WithURL(server.URL))
+			s.Require().NoError(err)
+			_, _, err = gen.Generate(context.Background())
+			s.Require().Error(err)
+			s.Contains(err.Error(), tc.want)
+		})
+	}
+}
+
+func (s *ContractSuite) TestStreamErrors() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"Hel"},"done":false}` + "\n" + `{"message":`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("hi", model.WithURL(server.URL))
+	s.Require().NoError(err)
+	streamer, ok := gen.(model.StreamingContentGenerator)
+	s.Require().True(ok)
+
+	var chunks []string
+	_, _, err = streamer.GenerateStream(context.Background(), func(chunk model.StreamChunk) error {
+		chunks = append(chunks, chunk.Text)
+		return nil
+	})
+	s.Require().Error(err)
+	s.Equal([]string{"Hel"}, chunks)
+
+	errStop := errors.New("client went away")
+	ok200 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"a"},"done":false}` + "\n" + `{"message":{"role":"assistant","content":"b"},"done":true}` + "\n"))
+	}))
+	defer ok200.Close()
+
+	gen, err = NewStringContentGenerator("hi", model.WithURL(ok200.URL))
+	s.Require().NoError(err)
+	_, _, err = gen.(model.StreamingContentGenerator).GenerateStream(context.Background(), func(chunk model.StreamChunk) error {
+		return errStop
+	})
+	s.ErrorIs(err, errStop)
+}
+
+func (s *ContractSuite) TestStreamSkipsToolRoundText() {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"Let me look that up. "},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","tool_calls":[{"function":{"name":"lookup","arguments":{"id":7}}}]},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","content":""},"done":true}` + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"eGFR "},"done":false}` + "\n" +
+			`{"message":{"role":"assistant","content":"is 48."},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return map[string]any{"egfr": 48}, nil
+	}}
+	gen, err := NewStringContentGenerator("eGFR?", model.WithURL(server.URL), model.WithTools([]model.Tool{tool}))
+	s.Require().NoError(err)
+
+	var chunks []string
+	out, _, err := gen.(model.StreamingContentGenerator).GenerateStream(context.Background(), func(chunk model.StreamChunk) error {
+		chunks = append(chunks, chunk.Text)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Equal("eGFR is 48.", out)
+	s.Equal([]string{"eGFR ", "is 48."}, chunks)
+	s.Equal(2, requests)
+}
+
+func (s *ContractSuite) TestSlowResponseHonoursContextDeadline() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	gen, err := NewStringContentGenerator("hi", model.WithURL(server.URL))
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(ctx)
+	s.Require().Error(err)
+	s.True(errors.Is(err, context.DeadlineExceeded), err.Error())
+}
+
+func (s *ContractSuite) TestDeadlineReturnsPartialStream() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"The patient "},"done":false}` + "\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	stream := func(opts ...model.GeneratorOption) (string, model.GenerationMetadata, []string, error) {
+		gen, err := NewStringContentGenerator("hi", append([]model.GeneratorOption{model.WithURL(server.URL)}, opts...)...)
+		s.Require().NoError(err)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		var chunks []string
+		out, meta, err := gen.(model.StreamingContentGenerator).GenerateStream(ctx, func(chunk model.StreamChunk) error {
+			chunks = append(chunks, chunk.Text)
+			return nil
+		})
+		return out, meta, chunks, err
+	}
+
+	out, meta, chunks, err := stream(model.WithPartialStreamOnDeadline(true))
+	s.Require().NoError(err)
+	s.Equal("The patient ", out)
+	s.Equal([]string{"The patient "}, chunks)
+	s.Equal("true", meta[model.MetadataKeyTruncated])
+
+	_, meta, _, err = stream()
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.NotContains(meta, model.MetadataKeyTruncated)
+}
+
+func (s *ContractSuite) TestDeadlineAfterToolRoundReturnsOnlyAnswerText() {
+	release := make(chan struct{})
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"Checking the labs. "},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","tool_calls":[{"function":{"name":"lookup","arguments":{"id":7}}}]},"done":true}` + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"The eGFR "},"done":false}` + "\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tool := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return map[string]any{"egfr": 48}, nil
+	}}
+	gen, err := NewStringContentGenerator("eGFR?",
+		model.WithURL(server.URL),
+		model.WithTools([]model.Tool{tool}),
+		model.WithPartialStreamOnDeadline(true),
+	)
+	s.Require().NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var chunks []string
+	out, meta, err := gen.(model.StreamingContentGenerator).GenerateStream(ctx, func(chunk model.StreamChunk) error {
+		chunks = append(chunks, chunk.Text)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Equal("The eGFR ", out)
+	s.Equal([]string{"The eGFR "}, chunks)
+	s.Equal("true", meta[model.MetadataKeyTruncated])
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+}
+
+func (s *ContractSuite) TestToolErrorIsReportedToModel() {
+	var requests []ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ollamaChatRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","tool_calls":[{"function":{"name":"lookup","arguments":{"id":7}}}]},"done":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"Not found."},"done":true}`))
+	}))
+	defer server.Close()
+
+	tool := model.Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return nil, errors.New("record 7 missing")
+	}}
+	gen, err := NewStringContentGenerator("find 7", model.WithURL(server.URL), model.WithTools([]model.Tool{tool}))
+	s.Require().NoError(err)
+
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Not found.", out)
+	s.Require().Len(requests, 2)
+	s.Require().Len(requests[0].Tools, 1)
+	s.Equal("lookup", requests[0].Tools[0].Function.Name)
+
+	last := requests[1].Messages[len(requests[1].Messages)-1]
+	s.Equal("tool", last.Role)
+	s.JSONEq(`{"error":"record 7 missing"}`, last.Content)
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+}
+
+func (s *ContractSuite) TestEmbed() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/api/embed", r.URL.Path)
+		var request embedRequest
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		s.Equal("nomic-embed-text", request.Model)
+		_, _ = w.Write([]byte(`{"embeddings":[[0.1,0.2],[0.3,0.4]]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewEmbeddingGenerator(model.WithURL(server.URL))
+	s.Require().NoError(err)
+	vectors, meta, err := gen.GenerateBatch(context.Background(), []string{"a", "b"})
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVectors{{0.1, 0.2}, {0.3, 0.4}}, vectors)
+	s.Equal("2", meta[model.MetadataKeyEmbeddingCount])
+	s.Equal("2", meta[model.MetadataKeyEmbeddingDims])
+}
+
+func (s *ContractSuite) TestEmbedTruncatesDimensions() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"embeddings":[[3,4,12]]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewEmbeddingGenerator(model.WithURL(server.URL), model.WithEmbeddingDimensions(2))
+	s.Require().NoError(err)
+	vector, meta, err := gen.Generate(context.Background(), "a")
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVector{0.6, 0.8}, vector)
+	s.Equal("2", meta[model.MetadataKeyEmbeddingDims])
+
+	gen, err = NewEmbeddingGenerator(model.WithURL(server.URL), model.WithEmbeddingDimensions(0))
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background(), "a")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "must be greater than zero")
+	s.Equal(1, calls)
+}
+
+func (s *ContractSuite) TestEmbedFallsBackToLegacyEndpoint() {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/api/embed" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"embedding":[0.5,0.6,0.7]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewEmbeddingGenerator(model.WithURL(server.URL))
+	s.Require().NoError(err)
+	vector, _, err := gen.Generate(context.Background(), "a")
+	s.Require().NoError(err)
+	s.Equal(model.EmbeddingVector{0.5, 0.6, 0.7}, vector)
+	s.Equal([]string{"/api/embed", "/api/embeddings"}, paths)
+
+	_, _, err = gen.GenerateBatch(context.Background(), []string{"a", "b"})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "ollama embedding request failed with status 404")
+}
+
+func (s *ContractSuite) TestProviderParamsAreMergedIntoBody() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"hi"},"done":true}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello.",
+		model.WithURL(server.URL),
+		model.WithModel("llama3.1"),
+		model.WithMaxTokens(16),
+		model.WithProviderParams(map[string]any{"keep_alive": "10m", "options": map[string]any{"num_ctx": 8192}}),
+	)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal("10m", body["keep_alive"])
+	s.Equal(map[string]any{"num_predict": float64(16), "num_ctx": float64(8192)}, body["options"])
+}
+
+func (s *ContractSuite) TestTLSConfigReachesSelfSignedServer() {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"secure"},"done":true}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello.", model.WithURL(server.URL))
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	gen, err = NewStringContentGenerator("Say hello.", model.WithURL(server.URL), model.WithRootCAs(pool))
+	s.Require().NoError(err)
+	out, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("secure", out)
+}
+
+func (s *ContractSuite) TestUnixSocketBaseURL() {
+	dir, err := os.MkdirTemp("", "ollama")
+	s.Require().NoError(err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	socketPath := filepath.Join(dir, "ollama.sock")
+	listener, err := net.Listen("unix", socketPath)
+	s.Require().NoError(err)
+
+	var path string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"local"},"done":true}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Say hello.", model.WithURL("unix://"+socketPath))
+	s.Require().NoError(err)
+	out, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("local", out)
+	s.Equal("/api/chat", path)
+
+	_, err = NewStringContentGenerator("Say hello.", model.WithURL("unix://"))
+	s.Require().Error(err)
+}
@@ -22,7 +22,10 @@ type embeddingGenerator struct {
 
 func NewEmbeddingGenerator(opts ...model.GeneratorOption) (model.EmbeddingGenerator, error) {
 	cfg := model.ResolveGeneratorOpts(opts...)
-	c := newClient(cfg)
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 	return &embeddingGenerator{
 		client: c,
 		cfg:    cfg,
@@ -60,12 +63,19 @@ func (g *embeddingGenerator) GenerateBatch(
 		log.Errorf("error: %v", err)
 		return nil, meta, utils.WrapIfNotNil(err)
 	}
+	cfg := g.cfg
+	cfg.EmbeddingDimensions, err = model.ResolveEmbeddingDimensions(g.cfg, providerName, modelName, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
 
 	log.Infof(
-		"embedding_request inputs=%d model=%q base_url=%q",
+		"embedding_request inputs=%d model=%q base_url=%q dimensions=%v",
 		len(inputs),
 		modelName,
 		g.client.baseURL,
+		cfg.EmbeddingDimensions,
 	)
 
 	vectors, err := g.client.embed(ctx, modelName, inputs)
@@ -73,6 +83,11 @@ func (g *embeddingGenerator) GenerateBatch(
 		log.Errorf("error: %v", err)
 		return nil, meta, utils.WrapIfNotNil(err)
 	}
+	vectors, err = truncateEmbeddings(log, cfg, vectors)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return nil, meta, utils.WrapIfNotNil(err)
+	}
 
 	meta[model.MetadataKeyEmbeddingCount] = fmt.Sprintf("%d", len(vectors))
 	if len(vectors) > 0 {
@@ -121,7 +136,7 @@ func (c *client) embed(ctx context.Context, modelName string, inputs []string) (
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	httpClient := &http.Client{Timeout: 120 * time.Second}
+	httpClient := c.httpClient(120 * time.Second)
 	httpResp, err := httpClient.Do(httpReq)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
@@ -193,6 +208,24 @@ func (c *client) embed(ctx context.Context, modelName string, inputs []string) (
 	return nil, utils.WrapIfNotNil(fmt.Errorf("ollama embedding request failed with status %d", httpResp.StatusCode))
 }
 
+// truncateEmbeddings applies WithEmbeddingDimensions client-side, as the API
+// has no dimensions parameter. A size larger than the model's is an error, or
+// is ignored with a warning when invalid options are ignored.
+func truncateEmbeddings(log logging.Logger, cfg model.GeneratorConfig, vectors model.EmbeddingVectors) (model.EmbeddingVectors, error) {
+	if cfg.EmbeddingDimensions == nil {
+		return vectors, nil
+	}
+	truncated, err := model.TruncateEmbeddings(vectors, *cfg.EmbeddingDimensions)
+	if err != nil {
+		if !cfg.IgnoreInvalidGeneratorOptions {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		log.Warnf("ignoring embedding dimensions: %v", err)
+		return vectors, nil
+	}
+	return truncated, nil
+}
+
 func validateEmbeddingInputs(inputs []string) error {
 	if len(inputs) == 0 {
 		return utils.WrapIfNotNil(errors.New("at least one input is required"))
@@ -0,0 +1,75 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func FuzzExtractJSONPayload(f *testing.F) {
+	for _, seed := range []string{
+		`{"a":1}`,
+		"```json\n{\"a\":1}\n```",
+		"Sure! Here you go: {\"a\":{\"b\":[1,2]}} Hope that helps.",
+		"```\n[1,2,3]\n```",
+		"} {",
+		"```json",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		out := extractJSONPayload(text)
+
+		if !strings.Contains(text, out) {
+			t.Fatalf("payload %q is not part of input %q", out, text)
+		}
+		if out != strings.TrimSpace(out) {
+			t.Fatalf("payload %q has surrounding whitespace", out)
+		}
+
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) && out != trimmed {
+			t.Fatalf("valid object %q was altered to %q", trimmed, out)
+		}
+	})
+}
+
+func FuzzNormalizeToolArguments(f *testing.F) {
+	for _, seed := range []string{`{"id":1}`, ` {"a":[1,2]} `, `"quoted"`, `{`, ``, `   `, `null`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		fromString, stringErr := normalizeToolArguments(raw)
+		fromRaw, rawErr := normalizeToolArguments(json.RawMessage(raw))
+
+		if (stringErr == nil) != (rawErr == nil) {
+			t.Fatalf("string and raw inputs disagree for %q: %v vs %v", raw, stringErr, rawErr)
+		}
+		if stringErr != nil {
+			return
+		}
+		if !json.Valid(fromString) || !json.Valid(fromRaw) {
+			t.Fatalf("normalized arguments for %q are not valid JSON", raw)
+		}
+		if !bytes.Equal(fromString, fromRaw) {
+			t.Fatalf("string and raw inputs normalized differently: %q vs %q", fromString, fromRaw)
+		}
+
+		// Strings are treated as already-encoded JSON, so only structured values
+		// must survive a decode/normalize round trip.
+		var decoded any
+		if err := json.Unmarshal([]byte(raw), &decoded); err == nil {
+			if _, isString := decoded.(string); isString {
+				return
+			}
+			reencoded, err := normalizeToolArguments(decoded)
+			if err != nil || !json.Valid(reencoded) {
+				t.Fatalf("decoded arguments for %q did not re-normalize: %v", raw, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package ollama
+
+import (
+	"encoding/json"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+func buildConversationHistory(modelName string, messages []ollamaChatMessage) model.ConversationHistory {
+	history := model.ConversationHistory{
+		Version:  model.ConversationHistoryVersion,
+		Provider: providerName,
+		Model:    modelName,
+		Messages: make([]model.HistoryMessage, 0, len(messages)),
+	}
+
+	for _, message := range messages {
+		entry := model.HistoryMessage{
+			Role:       model.HistoryRole(message.Role),
+			Content:    message.Content,
+			ToolCallID: message.ToolCallID,
+			ToolName:   message.ToolName,
+		}
+		if entry.ToolName == "" {
+			entry.ToolName = message.Name
+		}
+		for _, call := range message.ToolCalls {
+			arguments, err := json.Marshal(call.Function.Arguments)
+			if err != nil || call.Function.Arguments == nil {
+				arguments = nil
+			}
+			entry.ToolCalls = append(entry.ToolCalls, model.HistoryToolCall{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: arguments,
+			})
+		}
+		history.Messages = append(history.Messages, entry)
+	}
+	return history
+}
@@ -14,40 +14,13 @@ import (
 )
 
 func buildAllTools(ctx context.Context, cfg model.GeneratorConfig) ([]model.Tool, func(), error) {
-	combined := append([]model.Tool(nil), cfg.Tools...)
-	adapters := make([]*mcp.ToolAdapter, 0, len(cfg.MCPTools))
-
-	cleanup := func() {
-		log := logging.NewLogger(ctx)
-		for _, adapter := range adapters {
-			if adapter == nil {
-				continue
-			}
-			if err := adapter.Disconnect(); err != nil {
-				log.Warnf("mcp adapter disconnect failed: %v", err)
-			}
-		}
+	mcpTools, cleanup, err := mcp.ModelTools(ctx, cfg.MCPTools, cfg.MCPAdapterPool)
+	if err != nil {
+		return nil, func() {}, utils.WrapIfNotNil(err)
 	}
+	combined := append(append([]model.Tool(nil), cfg.Tools...), mcpTools...)
 
-	for _, mcpTool := range cfg.MCPTools {
-		authToken := extractAuthorizationHeader(mcpTool.HTTPHeaders)
-
-		adapter, err := mcp.NewToolAdapter(ctx, mcpTool.URL, authToken, mcpTool.AllowedTools)
-		if err != nil {
-			cleanup()
-			return nil, func() {}, utils.WrapIfNotNil(err)
-		}
-		adapters = append(adapters, adapter)
-
-		adapterTools, err := adapter.AsModelTools()
-		if err != nil {
-			cleanup()
-			return nil, func() {}, utils.WrapIfNotNil(err)
-		}
-		combined = append(combined, adapterTools...)
-	}
-
-	return combined, cleanup, nil
+	return model.InterceptTools(combined, cfg.ToolInterceptors), cleanup, nil
 }
 
 func mapTools(tools []model.Tool) ([]model.Tool, map[string]toolHandler, error) {
@@ -70,7 +43,7 @@ func mapTools(tools []model.Tool) ([]model.Tool, map[string]toolHandler, error)
 			return nil, nil, utils.WrapIfNotNil(fmt.Errorf("duplicate tool name %q", name))
 		}
 
-		handler := tool.Handler
+		handler := tool.Call
 		handlers[name] = func(ctx context.Context, args json.RawMessage) (any, error) {
 			return handler(ctx, args)
 		}
@@ -81,11 +54,17 @@ func mapTools(tools []model.Tool) ([]model.Tool, map[string]toolHandler, error)
 	return out, handlers, nil
 }
 
-func extractAuthorizationHeader(headers map[string]string) string {
-	for k, v := range headers {
-		if strings.EqualFold(k, "Authorization") {
-			return v
-		}
+// resolveToolEmulation reports whether tools must be described in the prompt
+// because the model is registered as not supporting native tool calling.
+func resolveToolEmulation(cfg model.GeneratorConfig, modelName string, hasTools bool, log logging.Logger) (bool, error) {
+	if !hasTools || model.SupportsToolCalling(providerName, modelName) {
+		return false, nil
+	}
+	if !cfg.IgnoreInvalidGeneratorOptions {
+		return false, utils.WrapIfNotNil(fmt.Errorf("%w: %s", model.ErrToolCallingNotSupported, modelName))
+	}
+	if log != nil {
+		log.Warnf("model %q does not support tool calling; falling back to prompt-described tools", modelName)
 	}
-	return ""
+	return true, nil
 }
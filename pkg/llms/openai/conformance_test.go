@@ -0,0 +1,141 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/testsupport"
+)
+
+type conformanceWire struct{}
+
+type conformanceInputItem struct {
+	Type    string          `json:"type"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+	CallID  string          `json:"call_id"`
+	Output  string          `json:"output"`
+}
+
+func (conformanceWire) DecodeRequest(r *http.Request) (testsupport.Request, error) {
+	var request struct {
+		Model        string                 `json:"model"`
+		Instructions string                 `json:"instructions"`
+		Input        []conformanceInputItem `json:"input"`
+		Tools        []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return testsupport.Request{}, err
+	}
+
+	out := testsupport.Request{Model: request.Model}
+	if request.Instructions != "" {
+		out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleSystem, Content: request.Instructions})
+	}
+	for _, item := range request.Input {
+		switch item.Type {
+		case "function_call_output":
+			out.Messages = append(out.Messages, testsupport.Message{Role: testsupport.RoleTool, Content: item.Output, ToolCallID: item.CallID})
+			continue
+		case "", "message":
+		default:
+			continue
+		}
+
+		var text string
+		if err := json.Unmarshal(item.Content, &text); err != nil {
+			var parts []struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(item.Content, &parts); err != nil {
+				return testsupport.Request{}, err
+			}
+			texts := make([]string, 0, len(parts))
+			for _, part := range parts {
+				texts = append(texts, part.Text)
+			}
+			text = strings.Join(texts, "\n")
+		}
+		role := item.Role
+		if role == "developer" {
+			role = testsupport.RoleSystem
+		}
+		if text != "" {
+			out.Messages = append(out.Messages, testsupport.Message{Role: role, Content: text})
+		}
+	}
+	for _, tool := range request.Tools {
+		out.Tools = append(out.Tools, tool.Name)
+	}
+	return out, nil
+}
+
+func (conformanceWire) WriteResponse(w http.ResponseWriter, turn testsupport.Turn) {
+	w.Header().Set("content-type", "application/json")
+	if turn.StatusCode >= http.StatusMultipleChoices {
+		w.WriteHeader(turn.StatusCode)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]any{"type": "server_error", "message": turn.ErrorMessage},
+		})
+		return
+	}
+
+	output := make([]map[string]any, 0, len(turn.ToolCalls)+1)
+	if turn.Text != "" {
+		output = append(output, map[string]any{
+			"type":    "message",
+			"id":      "msg_conformance",
+			"role":    "assistant",
+			"status":  "completed",
+			"content": []map[string]any{{"type": "output_text", "text": turn.Text, "annotations": []any{}}},
+		})
+	}
+	for _, call := range turn.ToolCalls {
+		output = append(output, map[string]any{
+			"type":      "function_call",
+			"id":        "fc_" + call.ID,
+			"call_id":   call.ID,
+			"name":      call.Name,
+			"arguments": string(call.Arguments),
+			"status":    "completed",
+		})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":     "resp_conformance",
+		"object": "response",
+		"model":  "gpt-conformance",
+		"status": "completed",
+		"output": output,
+		"usage": map[string]any{
+			"input_tokens":  turn.InputTokens,
+			"output_tokens": turn.OutputTokens,
+			"total_tokens":  turn.InputTokens + turn.OutputTokens,
+		},
+	})
+}
+
+func TestConformance(t *testing.T) {
+	testsupport.RunConformance(t, testsupport.Harness{
+		Provider: providerName,
+		Wire:     conformanceWire{},
+		Options: []model.GeneratorOption{
+			model.WithAuthToken("test-key"),
+			model.WithRetryPolicy(model.RetryPolicy{GenerationBudget: -1}),
+		},
+		NewString: NewStringContentGenerator,
+		// The suite scripts JSON answers as text, so ask in the prompt
+		// rather than through a json_schema response format.
+		NewStructured: func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[testsupport.Record], error) {
+			opts = append(opts, model.WithStructuredOutputMode(model.StructuredOutputModePrompt))
+			return NewStructureContentGenerator[testsupport.Record](prompt, opts...)
+		},
+		UnsupportedOptions: []model.GeneratorOption{
+			model.WithCachedContent("cachedContents/abc"),
+		},
+	})
+}
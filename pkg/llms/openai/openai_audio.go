@@ -13,10 +13,16 @@ import (
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/param"
 )
 
-const defaultAudioTranscriptionModelName = "whisper-1"
+const (
+	defaultAudioTranscriptionModelName = "whisper-1"
+	// defaultDiarizationModelName is used when AudioOptions.Diarize is set
+	// without a model.
+	defaultDiarizationModelName = "gpt-4o-transcribe-diarize"
+)
 
 type audioTranscriptionGenerator struct {
 	client   *client
@@ -47,28 +53,111 @@ func NewAudioTranscriptionGenerator(
 
 func (g *audioTranscriptionGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
 	start := time.Now()
-	meta := initMetadata(providerName, resolveAudioTranscriptionModelName(g.opts))
+	log := logging.NewLogger(ctx)
+	modelName := resolveAudioTranscriptionModelName(g.opts)
+	meta := initMetadata(providerName, modelName)
 	defer setLatencyMetadata(meta, start)
 
-	logging.NewLogger(ctx).Infof(
-		"audio_transcription_request model=%q",
-		resolveAudioTranscriptionModelName(g.opts),
-	)
+	log.Infof("audio_transcription_request model=%q", modelName)
 
-	transcript, response, err := g.client.runAudioTranscription(ctx, g.filePath, g.opts)
+	format, err := model.ResolveTranscriptFormat(g.opts)
 	if err != nil {
-		logging.NewLogger(ctx).Errorf("error: %v", err)
+		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
+	opts, err := normalizeAudioDiarization(g.opts, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	if opts.Diarize {
+		result, response, err := g.client.diarizeAudio(ctx, g.filePath, opts, log)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		applyOpenAIAudioTranscriptionMetadata(meta, response)
+		if format != model.TranscriptFormatText {
+			return formatTranscriptionResult(meta, opts, result, format, log)
+		}
+		model.SetTranscriptLanguage(meta, g.opts, result.Text)
+		return model.RedactTranscript(meta, g.opts, model.FormatSpeakerTranscript(result.Segments)), meta, nil
+	}
+
+	timed := supportsTimedTranscription(modelName)
+	if (format == model.TranscriptFormatSRT || format == model.TranscriptFormatVTT) && !timed {
+		err = errors.New(string(format) + " output is not supported by model " + modelName + "; use whisper-1")
+		if !opts.IgnoreInvalidGeneratorOptions {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		log.Warnf("returning plain transcript: %v", err)
+		format = model.TranscriptFormatText
+	}
 
-	applyOpenAIAudioTranscriptionMetadata(meta, response)
-	return transcript, meta, nil
+	switch {
+	case format == model.TranscriptFormatText:
+		transcript, response, err := g.client.runAudioTranscription(ctx, g.filePath, opts)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		applyOpenAIAudioTranscriptionMetadata(meta, response)
+		model.SetTranscriptLanguage(meta, opts, transcript)
+		return model.RedactTranscript(meta, opts, transcript), meta, nil
+	case format != model.TranscriptFormatJSON && !opts.RedactPII:
+		// The API renders subtitles itself; with RedactPII they are rendered
+		// from the redacted segments instead, so timecodes are never scrubbed.
+		subtitles, err := g.client.transcribeAudioSubtitles(ctx, g.filePath, opts, format)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		model.SetTranscriptLanguage(meta, opts, subtitles)
+		return subtitles, meta, nil
+	default:
+		result, response, err := g.client.transcribeTimed(ctx, g.filePath, opts, timed)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", meta, utils.WrapIfNotNil(err)
+		}
+		applyOpenAIAudioTranscriptionMetadata(meta, response)
+		return formatTranscriptionResult(meta, opts, result, format, log)
+	}
+}
+
+// formatTranscriptionResult post-processes result like the verbose generator
+// and renders it in format.
+func formatTranscriptionResult(
+	meta model.GenerationMetadata,
+	opts model.AudioOptions,
+	result model.TranscriptionResult,
+	format model.TranscriptFormat,
+	log logging.Logger,
+) (string, model.GenerationMetadata, error) {
+	output, err := model.FormatTranscript(model.FinishTranscriptionResult(meta, opts, result), format)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	return output, meta, nil
 }
 
 func (c *client) runAudioTranscription(
 	ctx context.Context,
 	filePath string,
 	opts model.AudioOptions,
+) (string, *openai.AudioTranscriptionNewResponseUnion, error) {
+	return c.transcribeAudio(ctx, filePath, opts, func(params *openai.AudioTranscriptionNewParams) {})
+}
+
+// transcribeAudio sends filePath with the prompt, language and model of opts;
+// configure adjusts the request, for example to ask for verbose output.
+func (c *client) transcribeAudio(
+	ctx context.Context,
+	filePath string,
+	opts model.AudioOptions,
+	configure func(params *openai.AudioTranscriptionNewParams),
 ) (string, *openai.AudioTranscriptionNewResponseUnion, error) {
 	if strings.TrimSpace(filePath) == "" {
 		return "", nil, utils.WrapIfNotNil(errors.New("file path is required"))
@@ -82,18 +171,11 @@ func (c *client) runAudioTranscription(
 		_ = file.Close()
 	}()
 
-	params := openai.AudioTranscriptionNewParams{
-		File:           file,
-		Model:          openai.AudioModel(resolveAudioTranscriptionModelName(opts)),
-		ResponseFormat: openai.AudioResponseFormatJSON,
-	}
-	prompt, err := buildAudioTranscriptionPrompt(opts)
+	params, err := newAudioTranscriptionParams(file, opts)
 	if err != nil {
 		return "", nil, utils.WrapIfNotNil(err)
 	}
-	if prompt != "" {
-		params.Prompt = param.NewOpt(prompt)
-	}
+	configure(&params)
 
 	response, err := c.apiClient.Audio.Transcriptions.New(ctx, params)
 	if err != nil {
@@ -111,6 +193,61 @@ func (c *client) runAudioTranscription(
 	return transcript, response, nil
 }
 
+// transcribeAudioSubtitles asks a whisper model for SRT or VTT output, which
+// the API returns as plain text rather than JSON.
+func (c *client) transcribeAudioSubtitles(
+	ctx context.Context,
+	filePath string,
+	opts model.AudioOptions,
+	format model.TranscriptFormat,
+) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	params, err := newAudioTranscriptionParams(file, opts)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	params.ResponseFormat = openai.AudioResponseFormatSRT
+	if format == model.TranscriptFormatVTT {
+		params.ResponseFormat = openai.AudioResponseFormatVTT
+	}
+
+	var body []byte
+	if _, err = c.apiClient.Audio.Transcriptions.New(ctx, params, option.WithResponseBodyInto(&body)); err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	subtitles := strings.TrimSpace(string(body))
+	if subtitles == "" {
+		return "", utils.WrapIfNotNil(errors.New("transcription response is empty"))
+	}
+	return subtitles + "\n", nil
+}
+
+func newAudioTranscriptionParams(file *os.File, opts model.AudioOptions) (openai.AudioTranscriptionNewParams, error) {
+	params := openai.AudioTranscriptionNewParams{
+		File:           file,
+		Model:          openai.AudioModel(resolveAudioTranscriptionModelName(opts)),
+		ResponseFormat: openai.AudioResponseFormatJSON,
+	}
+	prompt, err := buildAudioTranscriptionPrompt(opts)
+	if err != nil {
+		return params, err
+	}
+	if prompt != "" {
+		params.Prompt = param.NewOpt(prompt)
+	}
+	if language := strings.TrimSpace(opts.Language); language != "" {
+		params.Language = param.NewOpt(strings.ToLower(language))
+	}
+	return params, nil
+}
+
 func buildAudioTranscriptionPrompt(opts model.AudioOptions) (string, error) {
 	customPrompt := strings.TrimSpace(opts.Prompt)
 	if customPrompt != "" {
@@ -175,6 +312,9 @@ func resolveAudioTranscriptionModelName(opts model.AudioOptions) string {
 	if modelName != "" {
 		return modelName
 	}
+	if opts.Diarize {
+		return defaultDiarizationModelName
+	}
 
 	return defaultAudioTranscriptionModelName
 }
@@ -184,6 +324,7 @@ func audioGeneratorConfigFromOptions(opts model.AudioOptions) model.GeneratorCon
 		IgnoreInvalidGeneratorOptions: opts.IgnoreInvalidGeneratorOptions,
 		URL:                           opts.URL,
 		AuthToken:                     opts.AuthToken,
+		TLSConfig:                     opts.TLSConfig,
 	}
 
 	modelName := strings.TrimSpace(opts.Model)
@@ -196,6 +337,7 @@ func audioGeneratorConfigFromOptions(opts model.AudioOptions) model.GeneratorCon
 
 func cloneAudioOptions(opts model.AudioOptions) model.AudioOptions {
 	cloned := opts
+	cloned.TimestampGranularities = append([]model.TimestampGranularity(nil), opts.TimestampGranularities...)
 	if len(opts.Keywords) == 0 {
 		cloned.Keywords = nil
 		return cloned
@@ -1,10 +1,13 @@
 package openai
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,8 +16,8 @@ import (
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/mcp"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/toolexec"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
-	"github.com/invopop/jsonschema"
 	openai "github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
@@ -23,20 +26,26 @@ import (
 
 const (
 	defaultModelName = "gpt-5-mini"
-	maxToolRounds    = 12
 	providerName     = "openai"
+	maxTemperature   = 2.0
 )
 
 type toolHandler func(ctx context.Context, args json.RawMessage) (any, error)
 
 type flowUsageTotals struct {
-	APICalls          int
-	ToolRounds        int
-	InputTokens       int64
-	OutputTokens      int64
-	TotalTokens       int64
-	CachedInputTokens int64
-	ReasoningTokens   int64
+	APICalls             int
+	ToolRounds           int
+	ToolCalls            []model.ToolCallRecord
+	InputTokens          int64
+	OutputTokens         int64
+	TotalTokens          int64
+	CachedInputTokens    int64
+	ReasoningTokens      int64
+	WebSearchCalls       int
+	CodeInterpreterCalls int
+	FileSearchCalls      int
+	WebSearchQueries     []string
+	Gateway              model.GatewayTotals
 }
 
 type client struct {
@@ -44,7 +53,7 @@ type client struct {
 }
 
 func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
-	if prompt == "" {
+	if strings.TrimSpace(prompt) == "" {
 		return nil, utils.WrapIfNotNil(errors.New("prompt is required"))
 	}
 
@@ -57,7 +66,7 @@ func NewStructureContentGenerator[T any](prompt string, opts ...model.GeneratorO
 }
 
 func NewStringContentGenerator(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
-	if prompt == "" {
+	if strings.TrimSpace(prompt) == "" {
 		return nil, utils.WrapIfNotNil(errors.New("prompt is required"))
 	}
 
@@ -70,13 +79,28 @@ func NewStringContentGenerator(prompt string, opts ...model.GeneratorOption) (mo
 }
 
 func newClient(cfg model.GeneratorConfig) (*client, error) {
-	requestOpts := make([]option.RequestOption, 0, 2)
-	if cfg.URL != "" {
-		requestOpts = append(requestOpts, option.WithBaseURL(cfg.URL))
+	socketPath, baseURL, err := model.ParseUnixSocketURL(cfg.URL)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	requestOpts := make([]option.RequestOption, 0, 3)
+	requestOpts = append(requestOpts, retryBudgetOption())
+	if baseURL != "" {
+		requestOpts = append(requestOpts, option.WithBaseURL(baseURL))
 	}
 	if cfg.AuthToken != "" {
 		requestOpts = append(requestOpts, option.WithAPIKey(cfg.AuthToken))
 	}
+	gatewayHeaders, err := model.GatewayRequestHeaders(cfg.Gateway)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	for name, value := range gatewayHeaders {
+		requestOpts = append(requestOpts, option.WithHeader(name, value))
+	}
+	if cfg.TLSConfig != nil || socketPath != "" {
+		requestOpts = append(requestOpts, option.WithHTTPClient(model.NewHTTPClient(cfg, 0)))
+	}
 
 	apiClient := openai.NewClient(requestOpts...)
 	return &client{apiClient: apiClient}, nil
@@ -89,6 +113,8 @@ type structuredGenerator[T any] struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func (g *structuredGenerator[T]) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
@@ -121,12 +147,21 @@ func (g *structuredGenerator[T]) AddPromptContextProvider(ctx context.Context, p
 }
 
 func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *structuredGenerator[T]) generate(ctx context.Context) (T, model.GenerationMetadata, error) {
 	start := time.Now()
 	meta := initMetadata(providerName, resolveModelName(g.cfg))
 	defer setLatencyMetadata(meta, start)
+	ctx, retries := model.StartRetryBudget(ctx, g.cfg)
+	defer model.SetRetryMetadata(meta, retries)
 
 	log := logging.NewLogger(ctx)
-	inputItems, contextCount, err := g.inputItemsWithContext(ctx)
+	inputItems, contextCount, err := g.inputItemsWithContext(ctx, meta)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -145,37 +180,84 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		len(g.cfg.MCPTools),
 	)
 
-	schema, err := generateSchema[T]()
+	schema, err := model.StructuredOutputSchema[T](g.cfg)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
 
-	textCfg := responses.ResponseTextConfigParam{
-		Format: responses.ResponseFormatTextConfigUnionParam{
-			OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
-				Name:   "structured_output",
-				Schema: schema,
-				Strict: openai.Bool(true),
+	mode := model.ResolveStructuredOutputMode(g.cfg)
+	if mode == model.StructuredOutputModeAuto && jsonSchemaUnsupported(g.cfg.URL) {
+		log.Debugf("json_schema previously rejected by %q; using prompt schema instructions", g.cfg.URL)
+		mode = model.StructuredOutputModePrompt
+	}
+
+	var response *responses.Response
+	var totals flowUsageTotals
+	var history responses.ResponseInputParam
+	if mode != model.StructuredOutputModePrompt {
+		textCfg := responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{
+				OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+					Name:   "structured_output",
+					Schema: schema,
+					Strict: openai.Bool(true),
+				},
 			},
-		},
+		}
+
+		response, totals, history, err = g.client.runResponsesFlow(
+			ctx,
+			responses.ResponseNewParamsInputUnion{
+				OfInputItemList: inputItems,
+			},
+			g.cfg,
+			&textCfg,
+		)
+		g.recordHistory(resolveModelName(g.cfg), history)
+		switch {
+		case err == nil:
+			mode = model.StructuredOutputModeNative
+		case mode == model.StructuredOutputModeAuto && totals.ToolRounds == 0 && isJSONSchemaUnsupportedError(err):
+			log.Warnf("json_schema output rejected by %q, falling back to prompt schema instructions: %v", g.cfg.URL, err)
+			markJSONSchemaUnsupported(g.cfg.URL)
+			mode = model.StructuredOutputModePrompt
+		default:
+			log.Errorf("error: %v", err)
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(err)
+		}
 	}
 
-	response, totals, err := g.client.runResponsesFlow(
-		ctx,
-		responses.ResponseNewParamsInputUnion{
-			OfInputItemList: inputItems,
-		},
-		g.cfg,
-		&textCfg,
-	)
-	if err != nil {
-		log.Errorf("error: %v", err)
-		var zero T
-		return zero, meta, utils.WrapIfNotNil(err)
+	if mode == model.StructuredOutputModePrompt {
+		instruction, err := buildStructuredOutputInstruction(schema)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(err)
+		}
+		promptItems := make(responses.ResponseInputParam, 0, len(inputItems)+1)
+		promptItems = append(promptItems, responses.ResponseInputItemParamOfMessage(instruction, responses.EasyInputMessageRoleSystem))
+		promptItems = append(promptItems, inputItems...)
+
+		response, totals, history, err = g.client.runResponsesFlow(
+			ctx,
+			responses.ResponseNewParamsInputUnion{
+				OfInputItemList: promptItems,
+			},
+			g.cfg,
+			nil,
+		)
+		g.recordHistory(resolveModelName(g.cfg), history)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			var zero T
+			return zero, meta, utils.WrapIfNotNil(err)
+		}
 	}
 	applyOpenAIResponseMetadata(meta, response, totals)
+	meta[model.MetadataKeyStructuredOutputMode] = string(mode)
 
 	output := strings.TrimSpace(response.OutputText())
 	if output == "" {
@@ -184,9 +266,26 @@ func (g *structuredGenerator[T]) Generate(ctx context.Context) (T, model.Generat
 		var zero T
 		return zero, meta, utils.WrapIfNotNil(err)
 	}
+	model.SetRawOutput(meta, g.cfg, output)
+	extract := func(text string) string { return text }
+	repairAttempts := 0
+	if mode == model.StructuredOutputModePrompt {
+		extract = extractJSONPayload
+		repairAttempts = model.ResolveStructuredRepairAttempts(g.cfg, 0)
+	}
 
-	var result T
-	err = json.Unmarshal([]byte(output), &result)
+	result, err := model.DecodeStructuredOutputWithRepair[T](
+		ctx,
+		g.cfg,
+		meta,
+		schema,
+		output,
+		repairAttempts,
+		extract,
+		func(ctx context.Context, prompt string) (string, model.GenerationMetadata, error) {
+			return g.client.repairStructuredOutput(ctx, g.cfg, prompt)
+		},
+	)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		var zero T
@@ -203,6 +302,8 @@ type textGenerator struct {
 	promptContextMu        sync.RWMutex
 	promptContexts         []*model.PromptContext
 	promptContextProviders []model.PromptContextProvider
+	historyMu              sync.RWMutex
+	lastHistory            model.ConversationHistory
 }
 
 func (g *textGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
@@ -235,12 +336,21 @@ func (g *textGenerator) AddPromptContextProvider(ctx context.Context, provider m
 }
 
 func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	start := time.Now()
+	out, meta, err := g.generate(ctx)
+	model.RecordGeneration(ctx, g.cfg, providerName, start, meta, err)
+	return out, meta, err
+}
+
+func (g *textGenerator) generate(ctx context.Context) (string, model.GenerationMetadata, error) {
 	start := time.Now()
 	meta := initMetadata(providerName, resolveModelName(g.cfg))
 	defer setLatencyMetadata(meta, start)
+	ctx, retries := model.StartRetryBudget(ctx, g.cfg)
+	defer model.SetRetryMetadata(meta, retries)
 
 	log := logging.NewLogger(ctx)
-	inputItems, contextCount, err := g.inputItemsWithContext(ctx)
+	inputItems, contextCount, err := g.inputItemsWithContext(ctx, meta)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
@@ -258,7 +368,7 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		len(g.cfg.MCPTools),
 	)
 
-	response, totals, err := g.client.runResponsesFlow(
+	response, totals, history, err := g.client.runResponsesFlow(
 		ctx,
 		responses.ResponseNewParamsInputUnion{
 			OfInputItemList: inputItems,
@@ -266,16 +376,76 @@ func (g *textGenerator) Generate(ctx context.Context) (string, model.GenerationM
 		g.cfg,
 		nil,
 	)
+	g.recordHistory(resolveModelName(g.cfg), history)
 	if err != nil {
 		log.Errorf("error: %v", err)
 		return "", meta, utils.WrapIfNotNil(err)
 	}
 	applyOpenAIResponseMetadata(meta, response, totals)
 
-	return response.OutputText(), meta, nil
+	text, err := model.TransformOutput(ctx, g.cfg, response.OutputText())
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	text, err = model.ApplyPostProcessors(g.cfg, text)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	return model.ApplyDisclosure(g.cfg, text), meta, nil
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *structuredGenerator[T]) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg)
+}
+
+// EffectiveConfig returns the configuration Generate would run with (see model.EffectiveConfigReporter).
+func (g *textGenerator) EffectiveConfig(ctx context.Context) (model.GeneratorConfig, error) {
+	return effectiveConfig(ctx, g.cfg)
+}
+
+// effectiveConfig applies the model resolution and per-model option
+// normalization of a generation to cfg. The endpoint is resolved by the SDK,
+// so URL is left as configured.
+func effectiveConfig(ctx context.Context, cfg model.GeneratorConfig) (model.GeneratorConfig, error) {
+	modelName := resolveModelName(cfg)
+	cfg, err := normalizeGeneratorOptionsForModel(modelName, cfg, logging.NewLogger(ctx))
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+	cfg.Model = &modelName
+	return cfg, nil
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *structuredGenerator[T]) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *structuredGenerator[T]) recordHistory(modelName string, items responses.ResponseInputParam) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, items)
+}
+
+// ExportHistory returns the messages exchanged during the most recent Generate call.
+func (g *textGenerator) ExportHistory() model.ConversationHistory {
+	g.historyMu.RLock()
+	defer g.historyMu.RUnlock()
+	return g.lastHistory.Clone()
+}
+
+func (g *textGenerator) recordHistory(modelName string, items responses.ResponseInputParam) {
+	g.historyMu.Lock()
+	defer g.historyMu.Unlock()
+	g.lastHistory = buildConversationHistory(modelName, items)
 }
 
-func (g *structuredGenerator[T]) inputItemsWithContext(ctx context.Context) (responses.ResponseInputParam, int, error) {
+func (g *structuredGenerator[T]) inputItemsWithContext(ctx context.Context, meta model.GenerationMetadata) (responses.ResponseInputParam, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -289,10 +459,30 @@ func (g *structuredGenerator[T]) inputItemsWithContext(ctx context.Context) (res
 		contexts = append(contexts, provided...)
 	}
 
-	return buildInputItemsWithContext(g.prompt, contexts)
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+	items, contextCount, err := buildInputItemsWithContext(g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	items, err = attachDocuments(items, g.prompt, g.cfg.Documents)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	return items, contextCount, nil
 }
 
-func (g *textGenerator) inputItemsWithContext(ctx context.Context) (responses.ResponseInputParam, int, error) {
+func (g *textGenerator) inputItemsWithContext(ctx context.Context, meta model.GenerationMetadata) (responses.ResponseInputParam, int, error) {
 	g.promptContextMu.RLock()
 	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
 	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
@@ -306,7 +496,27 @@ func (g *textGenerator) inputItemsWithContext(ctx context.Context) (responses.Re
 		contexts = append(contexts, provided...)
 	}
 
-	return buildInputItemsWithContext(g.prompt, contexts)
+	contexts, err := model.DedupPromptContexts(ctx, meta, g.cfg, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	contexts, err = model.CompressPromptContexts(ctx, meta, g.cfg, g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+
+	model.SetContextTokens(meta, g.cfg, g.prompt, contexts)
+	model.SetExperimentMetadata(meta, g.cfg)
+	model.SetDisclosureMetadata(meta, g.cfg)
+	items, contextCount, err := buildInputItemsWithContext(g.prompt, contexts)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	items, err = attachDocuments(items, g.prompt, g.cfg.Documents)
+	if err != nil {
+		return nil, 0, utils.WrapIfNotNil(err)
+	}
+	return items, contextCount, nil
 }
 
 func buildInputItemsWithContext(prompt string, contexts []*model.PromptContext) (responses.ResponseInputParam, int, error) {
@@ -318,11 +528,23 @@ func buildInputItemsWithContext(prompt string, contexts []*model.PromptContext)
 		}
 
 		content := strings.TrimSpace(contextItem.Content)
-		if content == "" {
+		if !contextItem.HasContent() {
 			continue
 		}
 
+		images, err := model.ResolveImageParts(contextItem)
+		if err != nil {
+			return nil, 0, utils.WrapIfNotNil(err)
+		}
+
 		contextCount++
+		if len(images) > 0 {
+			items = append(items, responses.ResponseInputItemParamOfMessage(
+				mapImageMessageContent(content, images),
+				responses.EasyInputMessageRoleUser,
+			))
+			continue
+		}
 		items = append(
 			items,
 			responses.ResponseInputItemParamOfMessage(
@@ -342,94 +564,216 @@ func buildInputItemsWithContext(prompt string, contexts []*model.PromptContext)
 	return items, contextCount, nil
 }
 
+// repairStructuredOutput sends a structured output repair prompt as a new
+// single-turn request without tools.
+func (c *client) repairStructuredOutput(
+	ctx context.Context,
+	cfg model.GeneratorConfig,
+	prompt string,
+) (string, model.GenerationMetadata, error) {
+	meta := initMetadata(providerName, resolveModelName(cfg))
+	cfg.Tools = nil
+	cfg.MCPTools = nil
+	input := responses.ResponseInputParam{
+		responses.ResponseInputItemParamOfMessage(model.StructuredRepairSystemPrompt, responses.EasyInputMessageRoleSystem),
+		responses.ResponseInputItemParamOfMessage(prompt, responses.EasyInputMessageRoleUser),
+	}
+
+	response, totals, _, err := c.runResponsesFlow(ctx, responses.ResponseNewParamsInputUnion{OfInputItemList: input}, cfg, nil)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+	applyOpenAIResponseMetadata(meta, response, totals)
+	return strings.TrimSpace(response.OutputText()), meta, nil
+}
+
 func (c *client) runResponsesFlow(
 	ctx context.Context,
 	input responses.ResponseNewParamsInputUnion,
 	cfg model.GeneratorConfig,
 	textCfg *responses.ResponseTextConfigParam,
-) (*responses.Response, flowUsageTotals, error) {
+) (*responses.Response, flowUsageTotals, responses.ResponseInputParam, error) {
 	log := logging.NewLogger(ctx)
-	totals := flowUsageTotals{}
+	totals := flowUsageTotals{Gateway: model.NewGatewayTotals(cfg.Gateway)}
 
-	initialParams, handlers, err := c.buildInitialParams(ctx, input, cfg, textCfg)
+	initialParams, handlers, cleanup, err := c.buildInitialParams(ctx, input, cfg, textCfg)
 	if err != nil {
-		return nil, totals, utils.WrapIfNotNil(err)
+		return nil, totals, nil, utils.WrapIfNotNil(err)
 	}
+	defer cleanup()
 	history, err := seedInputHistory(initialParams.Input)
 	if err != nil {
-		return nil, totals, utils.WrapIfNotNil(err)
+		return nil, totals, nil, utils.WrapIfNotNil(err)
 	}
 
-	response, err := c.apiClient.Responses.New(ctx, initialParams)
+	requestOpts := providerParamsOptions(cfg.ProviderParams)
+	if cfg.Gateway != nil {
+		requestOpts = append(requestOpts, gatewayResponseOption(&totals.Gateway))
+	}
+	response, err := c.apiClient.Responses.New(ctx, initialParams, requestOpts...)
 	if err != nil {
 		log.Errorf("error: %v", err)
-		return nil, totals, utils.WrapIfNotNil(err)
+		return nil, totals, history, utils.WrapIfNotNil(err)
 	}
 	if response == nil {
 		err = errors.New("responses API returned nil response")
 		log.Errorf("error: %v", err)
-		return nil, totals, utils.WrapIfNotNil(err)
+		return nil, totals, history, utils.WrapIfNotNil(err)
 	}
 	accumulateFlowUsage(&totals, response)
 
-	for round := 0; round < maxToolRounds; round++ {
+	maxRounds := model.ResolveMaxToolRounds(cfg)
+	toolErrors := model.NewToolErrorPolicy(cfg)
+	for round := 0; round < maxRounds; round++ {
 		priorItems, err := responseOutputToInputItems(response.Output)
 		if err != nil {
 			log.Errorf("error: %v", err)
-			return nil, totals, utils.WrapIfNotNil(err)
+			return nil, totals, history, utils.WrapIfNotNil(err)
 		}
 		history = append(history, priorItems...)
 
 		calls := extractFunctionCalls(response)
 		if len(calls) == 0 {
-			return response, totals, nil
+			return response, totals, history, nil
 		}
 		totals.ToolRounds = round + 1
+		if missing := countReasoningWithoutEncryptedContent(response.Output); missing > 0 {
+			log.Warnf("reasoning_items_without_encrypted_content=%d; reasoning state is resent by id only", missing)
+		}
 
 		log.Infof("tool_round=%d function_calls=%d history_items=%d", round+1, len(calls), len(history))
 		outputItems := make([]responses.ResponseInputItemUnionParam, 0, len(calls))
 
+		callHandlers := make([]toolHandler, 0, len(calls))
 		for _, call := range calls {
 			handler, ok := handlers[call.Name]
 			if !ok {
 				err = fmt.Errorf("no tool handler configured for function %q", call.Name)
 				log.Errorf("error: %v", err)
-				return nil, totals, utils.WrapIfNotNil(err)
+				return nil, totals, history, utils.WrapIfNotNil(err)
 			}
+			callHandlers = append(callHandlers, handler)
+		}
 
-			result, callErr := handler(ctx, json.RawMessage(call.Arguments))
-			if callErr != nil {
-				log.Errorf("error: %v", callErr)
-				return nil, totals, utils.WrapIfNotNil(callErr)
+		results := toolexec.Run(ctx, len(calls), model.ResolveToolParallelism(cfg), func(ctx context.Context, i int) (any, error) {
+			return callHandlers[i](ctx, json.RawMessage(calls[i].Arguments))
+		})
+		totals.ToolCalls = append(totals.ToolCalls, toolexec.Transcript(round+1, results, func(i int) (string, string) {
+			return calls[i].Name, calls[i].CallID
+		})...)
+
+		for i, call := range calls {
+			output := results[i].Value
+			if callErr := results[i].Err; callErr != nil {
+				if abortErr := toolErrors.Handle(call.Name, callErr); abortErr != nil {
+					log.Errorf("error: %v", abortErr)
+					return nil, totals, history, utils.WrapIfNotNil(abortErr)
+				}
+				log.Warnf("tool %q failed, reporting to model: %v", call.Name, callErr)
+				output = model.ToolErrorResult(callErr)
 			}
 
-			outputJSON, marshalErr := json.Marshal(result)
+			outputJSON, marshalErr := json.Marshal(output)
 			if marshalErr != nil {
 				log.Errorf("error: %v", marshalErr)
-				return nil, totals, utils.WrapIfNotNil(marshalErr)
+				return nil, totals, history, utils.WrapIfNotNil(marshalErr)
 			}
 
 			outputItems = append(outputItems, responses.ResponseInputItemParamOfFunctionCallOutput(call.CallID, string(outputJSON)))
 		}
 
 		history = append(history, outputItems...)
-		nextParams := buildStatelessFollowupParams(initialParams, history, textCfg)
-		response, err = c.apiClient.Responses.New(ctx, nextParams)
+		if round+1 == maxRounds {
+			// Stop without sending the last allowed round's results, so a
+			// limit of N makes at most N API calls like the other providers.
+			break
+		}
+		var nextParams responses.ResponseNewParams
+		if cfg.ServerSideState && response.ID != "" {
+			nextParams = buildStatefulFollowupParams(initialParams, response.ID, outputItems, textCfg)
+		} else {
+			nextParams = buildStatelessFollowupParams(initialParams, history, textCfg)
+		}
+		response, err = c.apiClient.Responses.New(ctx, nextParams, requestOpts...)
 		if err != nil {
 			log.Errorf("error: %v", err)
-			return nil, totals, utils.WrapIfNotNil(err)
+			return nil, totals, history, utils.WrapIfNotNil(err)
 		}
 		if response == nil {
 			err = errors.New("responses API returned nil follow-up response")
 			log.Errorf("error: %v", err)
-			return nil, totals, utils.WrapIfNotNil(err)
+			return nil, totals, history, utils.WrapIfNotNil(err)
 		}
 		accumulateFlowUsage(&totals, response)
 	}
 
-	err = fmt.Errorf("exceeded tool call loop limit (%d)", maxToolRounds)
+	err = &model.MaxToolRoundsError{Limit: maxRounds}
 	log.Errorf("error: %v", err)
-	return nil, totals, utils.WrapIfNotNil(err)
+	return nil, totals, history, utils.WrapIfNotNil(err)
+}
+
+// providerParamsOptions merges model.WithProviderParams into the encoded
+// request body, so nested objects merge the same way as for the hand-rolled
+// providers.
+func providerParamsOptions(params map[string]any) []option.RequestOption {
+	if len(params) == 0 {
+		return nil
+	}
+	return []option.RequestOption{
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			if req.Body == nil {
+				return next(req)
+			}
+			body, err := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			if err != nil {
+				return nil, utils.WrapIfNotNil(err)
+			}
+			body, err = model.MergeProviderParams(body, params)
+			if err != nil {
+				return nil, utils.WrapIfNotNil(err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.ContentLength = int64(len(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+			return next(req)
+		}),
+	}
+}
+
+// retryBudgetOption draws the SDK's retries from the model.RetryBudget on the
+// request context. The SDK retries by calling the middleware again, marked by
+// its retry count header; once the budget is spent, responses carry
+// x-should-retry: false so the SDK returns their error instead of retrying.
+func retryBudgetOption() option.RequestOption {
+	return option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		budget := model.RetryBudgetFromContext(req.Context())
+		if retry := req.Header.Get("X-Stainless-Retry-Count"); retry != "" && retry != "0" && !budget.TryRetry() {
+			return nil, utils.WrapIfNotNil(model.ErrRetryBudgetExhausted)
+		}
+		res, err := next(req)
+		if res != nil && budget.Exhausted() {
+			if res.Header == nil {
+				res.Header = http.Header{}
+			}
+			res.Header.Set("x-should-retry", "false")
+		}
+		return res, err
+	})
+}
+
+// gatewayResponseOption records the gateway headers of every response of a
+// flow into gateway.
+func gatewayResponseOption(gateway *model.GatewayTotals) option.RequestOption {
+	return option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		res, err := next(req)
+		if res != nil {
+			gateway.Observe(res.Header)
+		}
+		return res, err
+	})
 }
 
 func (c *client) buildInitialParams(
@@ -437,29 +781,44 @@ func (c *client) buildInitialParams(
 	input responses.ResponseNewParamsInputUnion,
 	cfg model.GeneratorConfig,
 	textCfg *responses.ResponseTextConfigParam,
-) (responses.ResponseNewParams, map[string]toolHandler, error) {
+) (responses.ResponseNewParams, map[string]toolHandler, func(), error) {
 	log := logging.NewLogger(ctx)
+	noop := func() {}
 
 	modelName := resolveModelName(cfg)
 	reasoningModel := isReasoningModel(modelName)
 	cfg, err := normalizeGeneratorOptionsForModel(modelName, cfg, log)
 	if err != nil {
-		return responses.ResponseNewParams{}, nil, utils.WrapIfNotNil(err)
+		return responses.ResponseNewParams{}, nil, noop, utils.WrapIfNotNil(err)
+	}
+
+	localTools, nativeMCP, cleanup, err := resolveMCPBridge(ctx, cfg)
+	if err != nil {
+		return responses.ResponseNewParams{}, nil, noop, utils.WrapIfNotNil(err)
 	}
 
-	tools, handlers, err := mapLocalTools(cfg.Tools)
+	tools, handlers, err := mapLocalTools(model.InterceptTools(localTools, cfg.ToolInterceptors))
 	if err != nil {
-		return responses.ResponseNewParams{}, nil, utils.WrapIfNotNil(err)
+		cleanup()
+		return responses.ResponseNewParams{}, nil, noop, utils.WrapIfNotNil(err)
 	}
 
-	mcpTools, err := mapMCPTools(ctx, cfg.MCPTools)
+	mcpTools, err := mapMCPTools(ctx, nativeMCP)
 	if err != nil {
-		return responses.ResponseNewParams{}, nil, utils.WrapIfNotNil(err)
+		cleanup()
+		return responses.ResponseNewParams{}, nil, noop, utils.WrapIfNotNil(err)
 	}
 
-	allTools := make([]responses.ToolUnionParam, 0, len(tools)+len(mcpTools))
+	builtinTools, err := mapBuiltinTools(cfg)
+	if err != nil {
+		cleanup()
+		return responses.ResponseNewParams{}, nil, noop, utils.WrapIfNotNil(err)
+	}
+
+	allTools := make([]responses.ToolUnionParam, 0, len(tools)+len(mcpTools)+len(builtinTools))
 	allTools = append(allTools, tools...)
 	allTools = append(allTools, mcpTools...)
+	allTools = append(allTools, builtinTools...)
 
 	params := responses.ResponseNewParams{
 		Input: input,
@@ -484,8 +843,86 @@ func (c *client) buildInitialParams(
 	if textCfg != nil {
 		params.Text = *textCfg
 	}
+	if cfg.ServerSideState {
+		params.Store = openai.Bool(true)
+	}
+	if cfg.ParallelToolCalls != nil && len(allTools) > 0 {
+		params.ParallelToolCalls = openai.Bool(*cfg.ParallelToolCalls)
+	}
+
+	return params, handlers, cleanup, nil
+}
+
+// resolveMCPBridge returns the local tools and the MCP servers left to the
+// native connector. Under MCPBridgeModeLocal every MCP server is connected
+// through pkg/mcp and its tools join the local tools; cleanup releases those
+// connections.
+func resolveMCPBridge(ctx context.Context, cfg model.GeneratorConfig) ([]model.Tool, []model.MCPTool, func(), error) {
+	if model.ResolveMCPBridgeMode(cfg) != model.MCPBridgeModeLocal || len(cfg.MCPTools) == 0 {
+		return cfg.Tools, cfg.MCPTools, func() {}, nil
+	}
+	bridged, cleanup, err := mcp.ModelTools(ctx, cfg.MCPTools, cfg.MCPAdapterPool)
+	if err != nil {
+		return nil, nil, func() {}, utils.WrapIfNotNil(err)
+	}
+	return append(append([]model.Tool(nil), cfg.Tools...), bridged...), nil, cleanup, nil
+}
+
+// mapImageMessageContent builds input_text and input_image parts; bytes are
+// sent as base64 data URLs.
+func mapImageMessageContent(content string, images []model.ImagePart) responses.ResponseInputMessageContentListParam {
+	parts := make(responses.ResponseInputMessageContentListParam, 0, len(images)+1)
+	if content != "" {
+		parts = append(parts, responses.ResponseInputContentParamOfInputText(content))
+	}
+	for _, image := range images {
+		part := responses.ResponseInputContentParamOfInputImage(responses.ResponseInputImageDetailAuto)
+		part.OfInputImage.ImageURL = openai.String(image.DataURL())
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// openAIDocumentMaxBytes is the Responses API limit on inline file data per
+// request.
+const openAIDocumentMaxBytes = 50 << 20
+
+var openAIDocumentMIMETypes = []string{
+	model.DocumentMIMETypePDF,
+	model.DocumentMIMETypeText,
+	model.DocumentMIMETypeMarkdown,
+	model.DocumentMIMETypeCSV,
+	model.DocumentMIMETypeHTML,
+	model.DocumentMIMETypeDOCX,
+	model.DocumentMIMETypeXLSX,
+	model.DocumentMIMETypePPTX,
+}
+
+// attachDocuments replaces the final prompt message with input_file parts
+// followed by the prompt text. Bytes are sent as base64 data URLs.
+func attachDocuments(items responses.ResponseInputParam, prompt string, documents []model.DocumentPart) (responses.ResponseInputParam, error) {
+	if len(documents) == 0 || len(items) == 0 {
+		return items, nil
+	}
+	resolved, err := model.ResolveDocumentParts(documents, openAIDocumentMIMETypes, openAIDocumentMaxBytes)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
 
-	return params, handlers, nil
+	parts := make(responses.ResponseInputMessageContentListParam, 0, len(resolved)+1)
+	for _, document := range resolved {
+		file := responses.ResponseInputFileParam{Filename: openai.String(document.Name)}
+		if len(document.Data) > 0 {
+			file.FileData = openai.String(document.DataURL())
+		} else {
+			file.FileURL = openai.String(document.URL)
+		}
+		parts = append(parts, responses.ResponseInputContentUnionParam{OfInputFile: &file})
+	}
+	parts = append(parts, responses.ResponseInputContentParamOfInputText(prompt))
+
+	items[len(items)-1] = responses.ResponseInputItemParamOfMessage(parts, responses.EasyInputMessageRoleUser)
+	return items, nil
 }
 
 func mapContextMessageRole(messageType model.ContextMessageType) responses.EasyInputMessageRole {
@@ -527,11 +964,13 @@ func applyOpenAIResponseMetadata(meta model.GenerationMetadata, response *respon
 
 	meta[model.MetadataKeyAPICalls] = strconv.Itoa(totals.APICalls)
 	meta[model.MetadataKeyToolRounds] = strconv.Itoa(totals.ToolRounds)
+	model.SetToolCalls(meta, totals.ToolCalls)
 	meta[model.MetadataKeyInputTokens] = strconv.FormatInt(totals.InputTokens, 10)
 	meta[model.MetadataKeyOutputTokens] = strconv.FormatInt(totals.OutputTokens, 10)
 	meta[model.MetadataKeyTotalTokens] = strconv.FormatInt(totals.TotalTokens, 10)
 	meta[model.MetadataKeyCachedInputTokens] = strconv.FormatInt(totals.CachedInputTokens, 10)
 	meta[model.MetadataKeyReasoningTokens] = strconv.FormatInt(totals.ReasoningTokens, 10)
+	totals.Gateway.Apply(meta)
 	if response != nil {
 		if response.ID != "" {
 			meta[model.MetadataKeyResponseID] = response.ID
@@ -539,9 +978,72 @@ func applyOpenAIResponseMetadata(meta model.GenerationMetadata, response *respon
 		if response.Status != "" {
 			meta[model.MetadataKeyResponseStatus] = string(response.Status)
 		}
+		citations, files := extractAnnotations(response)
+		model.SetCitations(meta, citations)
+		model.SetFileAnnotations(meta, files)
+	}
+	if totals.WebSearchCalls > 0 {
+		meta[model.MetadataKeyWebSearchCalls] = strconv.Itoa(totals.WebSearchCalls)
+	}
+	model.SetWebSearchQueries(meta, totals.WebSearchQueries)
+	if totals.CodeInterpreterCalls > 0 {
+		meta[model.MetadataKeyCodeInterpreterCalls] = strconv.Itoa(totals.CodeInterpreterCalls)
+	}
+	if totals.FileSearchCalls > 0 {
+		meta[model.MetadataKeyFileSearchCalls] = strconv.Itoa(totals.FileSearchCalls)
 	}
 }
 
+// extractAnnotations collects url_citation annotations as citations and file
+// annotations from the response text. Indices are shifted so they point into
+// Response.OutputText, which concatenates every output_text part.
+func extractAnnotations(response *responses.Response) ([]model.Citation, []model.FileAnnotation) {
+	var (
+		citations []model.Citation
+		files     []model.FileAnnotation
+	)
+	offset := 0
+	for _, item := range response.Output {
+		for _, content := range item.Content {
+			if content.Type != "output_text" {
+				continue
+			}
+			for _, annotation := range content.Annotations {
+				switch annotation.Type {
+				case "url_citation":
+					if annotation.URL == "" {
+						continue
+					}
+					citations = append(citations, model.Citation{
+						URL:        annotation.URL,
+						Title:      annotation.Title,
+						StartIndex: offset + int(annotation.StartIndex),
+						EndIndex:   offset + int(annotation.EndIndex),
+					})
+				case "container_file_citation":
+					files = append(files, model.FileAnnotation{
+						Type:        annotation.Type,
+						FileID:      annotation.FileID,
+						Filename:    annotation.Filename,
+						ContainerID: annotation.ContainerID,
+						StartIndex:  offset + int(annotation.StartIndex),
+						EndIndex:    offset + int(annotation.EndIndex),
+					})
+				case "file_citation", "file_path":
+					files = append(files, model.FileAnnotation{
+						Type:     annotation.Type,
+						FileID:   annotation.FileID,
+						Filename: annotation.Filename,
+						Index:    offset + int(annotation.Index),
+					})
+				}
+			}
+			offset += len(content.Text)
+		}
+	}
+	return citations, files
+}
+
 func accumulateFlowUsage(totals *flowUsageTotals, response *responses.Response) {
 	if totals == nil || response == nil {
 		return
@@ -553,6 +1055,19 @@ func accumulateFlowUsage(totals *flowUsageTotals, response *responses.Response)
 	totals.TotalTokens += response.Usage.TotalTokens
 	totals.CachedInputTokens += response.Usage.InputTokensDetails.CachedTokens
 	totals.ReasoningTokens += response.Usage.OutputTokensDetails.ReasoningTokens
+	for _, item := range response.Output {
+		switch item.Type {
+		case "web_search_call":
+			totals.WebSearchCalls++
+			if query := strings.TrimSpace(item.Action.Query); item.Action.Type == "search" && query != "" {
+				totals.WebSearchQueries = append(totals.WebSearchQueries, query)
+			}
+		case "code_interpreter_call":
+			totals.CodeInterpreterCalls++
+		case "file_search_call":
+			totals.FileSearchCalls++
+		}
+	}
 }
 
 func normalizeGeneratorOptionsForModel(
@@ -567,6 +1082,7 @@ func normalizeGeneratorOptionsForModel(
 			if log != nil {
 				log.Warnf("ignoring temperature for reasoning model %q", modelName)
 			}
+			model.LogOptionResolution(log, providerName, "temperature", model.OptionActionDropped, "not supported for reasoning model "+modelName)
 			cfg.Temperature = nil
 		} else {
 			return cfg, utils.WrapIfNotNil(
@@ -580,6 +1096,7 @@ func normalizeGeneratorOptionsForModel(
 			if log != nil {
 				log.Warnf("ignoring reasoning effort for non-reasoning model %q", modelName)
 			}
+			model.LogOptionResolution(log, providerName, "reasoning_level", model.OptionActionDropped, "not supported for non-reasoning model "+modelName)
 			cfg.ReasoningLevel = nil
 		} else {
 			return cfg, utils.WrapIfNotNil(
@@ -588,6 +1105,26 @@ func normalizeGeneratorOptionsForModel(
 		}
 	}
 
+	if cfg.CachedContent != "" {
+		if cfg.IgnoreInvalidGeneratorOptions {
+			if log != nil {
+				log.Warnf("ignoring cached content for openai provider")
+			}
+			model.LogOptionResolution(log, providerName, "cached_content", model.OptionActionDropped, "not supported")
+			cfg.CachedContent = ""
+		} else {
+			return cfg, utils.WrapIfNotNil(errors.New("cached content is not supported for openai provider"))
+		}
+	}
+
+	cfg, err := model.ApplyGenerationLimits(cfg, providerName, modelName, model.GenerationLimits{
+		MaxTemperature:  maxTemperature,
+		MaxOutputTokens: model.MaxOutputTokens(providerName, modelName),
+	}, log)
+	if err != nil {
+		return cfg, utils.WrapIfNotNil(err)
+	}
+
 	return cfg, nil
 }
 
@@ -609,12 +1146,13 @@ func buildStatelessFollowupParams(
 	textCfg *responses.ResponseTextConfigParam,
 ) responses.ResponseNewParams {
 	followup := responses.ResponseNewParams{
-		Model:           initial.Model,
-		Temperature:     initial.Temperature,
-		MaxOutputTokens: initial.MaxOutputTokens,
-		Reasoning:       initial.Reasoning,
-		Tools:           initial.Tools,
-		Include:         append([]responses.ResponseIncludable(nil), initial.Include...),
+		Model:             initial.Model,
+		Temperature:       initial.Temperature,
+		MaxOutputTokens:   initial.MaxOutputTokens,
+		Reasoning:         initial.Reasoning,
+		Tools:             initial.Tools,
+		ParallelToolCalls: initial.ParallelToolCalls,
+		Include:           append([]responses.ResponseIncludable(nil), initial.Include...),
 		Input: responses.ResponseNewParamsInputUnion{
 			OfInputItemList: append(responses.ResponseInputParam(nil), history...),
 		},
@@ -627,6 +1165,20 @@ func buildStatelessFollowupParams(
 	return followup
 }
 
+// buildStatefulFollowupParams chains a tool round to the stored previous
+// response, so only the new function call outputs are sent.
+func buildStatefulFollowupParams(
+	initial responses.ResponseNewParams,
+	previousResponseID string,
+	outputItems responses.ResponseInputParam,
+	textCfg *responses.ResponseTextConfigParam,
+) responses.ResponseNewParams {
+	followup := buildStatelessFollowupParams(initial, outputItems, textCfg)
+	followup.PreviousResponseID = openai.String(previousResponseID)
+	followup.Store = openai.Bool(true)
+	return followup
+}
+
 func seedInputHistory(input responses.ResponseNewParamsInputUnion) (responses.ResponseInputParam, error) {
 	if len(input.OfInputItemList) > 0 {
 		return append(responses.ResponseInputParam(nil), input.OfInputItemList...), nil
@@ -642,6 +1194,11 @@ func seedInputHistory(input responses.ResponseNewParamsInputUnion) (responses.Re
 func responseOutputToInputItems(output []responses.ResponseOutputItemUnion) (responses.ResponseInputParam, error) {
 	items := make(responses.ResponseInputParam, 0, len(output))
 	for _, outputItem := range output {
+		if outputItem.Type == "reasoning" {
+			items = append(items, reasoningInputItem(outputItem.AsReasoning()))
+			continue
+		}
+
 		var inputItem responses.ResponseInputItemUnion
 		err := json.Unmarshal([]byte(outputItem.RawJSON()), &inputItem)
 		if err != nil {
@@ -652,6 +1209,83 @@ func responseOutputToInputItems(output []responses.ResponseOutputItemUnion) (res
 	return items, nil
 }
 
+// reasoningInputItem rebuilds a reasoning output item for the next stateless
+// request. The encrypted content is what carries the model's reasoning state
+// across rounds; status is output-only and is dropped.
+func reasoningInputItem(item responses.ResponseReasoningItem) responses.ResponseInputItemUnionParam {
+	param := responses.ResponseReasoningItemParam{
+		ID:      item.ID,
+		Summary: make([]responses.ResponseReasoningItemSummaryParam, 0, len(item.Summary)),
+	}
+	for _, summary := range item.Summary {
+		param.Summary = append(param.Summary, responses.ResponseReasoningItemSummaryParam{Text: summary.Text})
+	}
+	for _, content := range item.Content {
+		param.Content = append(param.Content, responses.ResponseReasoningItemContentParam{Text: content.Text})
+	}
+	if item.EncryptedContent != "" {
+		param.EncryptedContent = openai.String(item.EncryptedContent)
+	}
+	return responses.ResponseInputItemUnionParam{OfReasoning: &param}
+}
+
+// countReasoningWithoutEncryptedContent returns how many reasoning items lack
+// encrypted content. Those can only be resent by ID, which fails when the
+// response was not stored, so the model loses its reasoning state.
+func countReasoningWithoutEncryptedContent(output []responses.ResponseOutputItemUnion) int {
+	missing := 0
+	for _, item := range output {
+		if item.Type == "reasoning" && item.EncryptedContent == "" {
+			missing++
+		}
+	}
+	return missing
+}
+
+func mapBuiltinTools(cfg model.GeneratorConfig) ([]responses.ToolUnionParam, error) {
+	out := make([]responses.ToolUnionParam, 0, len(cfg.BuiltinTools))
+	for _, tool := range cfg.BuiltinTools {
+		switch tool {
+		case model.BuiltinWebSearch:
+			out = append(out, responses.ToolParamOfWebSearch(responses.WebSearchToolTypeWebSearch))
+		case model.BuiltinCodeInterpreter:
+			out = append(out, mapCodeInterpreterTool(cfg.CodeInterpreter))
+		case model.BuiltinFileSearch:
+			fileSearch, err := mapFileSearchTool(cfg.FileSearch)
+			if err != nil {
+				return nil, utils.WrapIfNotNil(err)
+			}
+			out = append(out, fileSearch)
+		default:
+			return nil, utils.WrapIfNotNil(fmt.Errorf("built-in tool %q is not supported for openai provider", tool))
+		}
+	}
+	return out, nil
+}
+
+func mapCodeInterpreterTool(config *model.CodeInterpreterConfig) responses.ToolUnionParam {
+	if config != nil && strings.TrimSpace(config.ContainerID) != "" {
+		return responses.ToolParamOfCodeInterpreter(strings.TrimSpace(config.ContainerID))
+	}
+	container := responses.ToolCodeInterpreterContainerCodeInterpreterContainerAutoParam{}
+	if config != nil {
+		container.FileIDs = config.FileIDs
+		container.MemoryLimit = config.MemoryLimit
+	}
+	return responses.ToolParamOfCodeInterpreter(container)
+}
+
+func mapFileSearchTool(config *model.FileSearchConfig) (responses.ToolUnionParam, error) {
+	if config == nil || len(config.VectorStoreIDs) == 0 {
+		return responses.ToolUnionParam{}, utils.WrapIfNotNil(errors.New("file search requires at least one vector store id"))
+	}
+	fileSearch := responses.ToolParamOfFileSearch(config.VectorStoreIDs)
+	if config.MaxResults > 0 {
+		fileSearch.OfFileSearch.MaxNumResults = openai.Int(int64(config.MaxResults))
+	}
+	return fileSearch, nil
+}
+
 func mapLocalTools(tools []model.Tool) ([]responses.ToolUnionParam, map[string]toolHandler, error) {
 	responseTools := make([]responses.ToolUnionParam, 0, len(tools))
 	handlers := make(map[string]toolHandler, len(tools))
@@ -687,7 +1321,7 @@ func mapLocalTools(tools []model.Tool) ([]responses.ToolUnionParam, map[string]t
 		responseTools = append(responseTools, responses.ToolUnionParam{
 			OfFunction: &param,
 		})
-		handlers[tool.Name] = tool.Handler
+		handlers[tool.Name] = tool.Call
 	}
 
 	return responseTools, handlers, nil
@@ -831,25 +1465,71 @@ func extractFunctionCalls(response *responses.Response) []responses.ResponseFunc
 	return calls
 }
 
-func generateSchema[T any]() (map[string]any, error) {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
+// jsonSchemaUnsupportedURLs remembers base URLs (keyed as configured, "" for
+// the default endpoint) that rejected json_schema output, so later structured
+// generations in StructuredOutputModeAuto skip straight to prompt mode.
+var jsonSchemaUnsupportedURLs sync.Map
 
-	var value T
-	schema := reflector.Reflect(value)
+func jsonSchemaUnsupported(baseURL string) bool {
+	_, found := jsonSchemaUnsupportedURLs.Load(strings.TrimSpace(baseURL))
+	return found
+}
 
-	schemaJSON, err := json.Marshal(schema)
-	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+func markJSONSchemaUnsupported(baseURL string) {
+	jsonSchemaUnsupportedURLs.Store(strings.TrimSpace(baseURL), struct{}{})
+}
+
+// jsonSchemaRejectionMarkers are fragments of the code, param or message of an
+// API error that point at the text format rather than the request content.
+var jsonSchemaRejectionMarkers = []string{"json_schema", "text.format", "response_format", "strict"}
+
+// isJSONSchemaUnsupportedError reports whether err is an endpoint refusing
+// the json_schema text format, which gateways that only proxy part of the
+// Responses API do with a 400, 422 or 501. invalid_json_schema errors are the
+// caller's schema being rejected and are not treated as unsupported.
+func isJSONSchemaUnsupportedError(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusNotImplemented:
+	default:
+		return false
+	}
+	if apiErr.Code == "invalid_json_schema" {
+		return false
 	}
 
-	var schemaMap map[string]any
-	err = json.Unmarshal(schemaJSON, &schemaMap)
+	text := strings.ToLower(strings.Join([]string{apiErr.Code, apiErr.Param, apiErr.Message}, " "))
+	for _, marker := range jsonSchemaRejectionMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildStructuredOutputInstruction(schema map[string]any) (string, error) {
+	schemaBytes, err := json.Marshal(schema)
 	if err != nil {
-		return nil, utils.WrapIfNotNil(err)
+		return "", utils.WrapIfNotNil(err)
 	}
 
-	return schemaMap, nil
+	return "Return ONLY valid JSON matching this schema. Do not include markdown fences.\n" + string(schemaBytes), nil
+}
+
+func extractJSONPayload(text string) string {
+	trimmed := strings.TrimSpace(text)
+	trimmed = strings.TrimPrefix(trimmed, "```json")
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	trimmed = strings.TrimSuffix(trimmed, "```")
+	trimmed = strings.TrimSpace(trimmed)
+
+	start := strings.Index(trimmed, "{")
+	end := strings.LastIndex(trimmed, "}")
+	if start >= 0 && end > start {
+		return strings.TrimSpace(trimmed[start : end+1])
+	}
+	return trimmed
 }
@@ -0,0 +1,845 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/suite"
+)
+
+type ResponsesFlowSuite struct {
+	suite.Suite
+}
+
+func TestResponsesFlowSuite(t *testing.T) {
+	suite.Run(t, new(ResponsesFlowSuite))
+}
+
+const webSearchResponseJSON = `{
+  "id": "resp_1",
+  "object": "response",
+  "status": "completed",
+  "model": "gpt-4.1-mini",
+  "output": [
+    {"type": "web_search_call", "id": "ws_1", "status": "completed", "action": {"type": "search", "query": "kdigo ckd guideline"}},
+    {"type": "message", "id": "msg_1", "role": "assistant", "status": "completed", "content": [
+      {"type": "output_text", "text": "Intro. ", "annotations": []},
+      {"type": "output_text", "text": "KDIGO 2024 updated CKD staging.", "annotations": [
+        {"type": "url_citation", "url": "https://kdigo.org/guidelines/ckd-evaluation-and-management/", "title": "KDIGO CKD Guideline", "start_index": 0, "end_index": 31},
+        {"type": "file_citation", "file_id": "file_1", "filename": "notes.pdf", "index": 3}
+      ]}
+    ]}
+  ],
+  "usage": {"input_tokens": 20, "output_tokens": 10, "total_tokens": 30}
+}`
+
+func (s *ResponsesFlowSuite) TestWebSearchSendsToolAndReportsCitations() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/responses", r.URL.Path)
+		raw, err := io.ReadAll(r.Body)
+		s.Require().NoError(err)
+		s.Require().NoError(json.Unmarshal(raw, &body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(webSearchResponseJSON))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator(
+		"What changed in the latest CKD guideline?",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithBuiltinTools(model.BuiltinWebSearch, model.BuiltinWebSearch),
+	)
+	s.Require().NoError(err)
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Intro. KDIGO 2024 updated CKD staging.", text)
+
+	tools, ok := body["tools"].([]any)
+	s.Require().True(ok, "request must carry tools")
+	s.Require().Len(tools, 1)
+	s.Equal("web_search", tools[0].(map[string]any)["type"])
+
+	s.Equal("1", meta[model.MetadataKeyWebSearchCalls])
+	queries, err := model.ParseWebSearchQueries(meta)
+	s.Require().NoError(err)
+	s.Equal([]string{"kdigo ckd guideline"}, queries)
+	citations, err := model.ParseCitations(meta)
+	s.Require().NoError(err)
+	s.Equal([]model.Citation{{
+		URL:        "https://kdigo.org/guidelines/ckd-evaluation-and-management/",
+		Title:      "KDIGO CKD Guideline",
+		StartIndex: 7,
+		EndIndex:   38,
+	}}, citations)
+	s.Equal("KDIGO 2024 updated CKD staging.", text[citations[0].StartIndex:citations[0].EndIndex])
+}
+
+func (s *ResponsesFlowSuite) TestNoBuiltinToolsOmitsCitationMetadata() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_2","object":"response","status":"completed","model":"gpt-4.1-mini","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Hello.","annotations":[]}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.", model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"))
+	s.Require().NoError(err)
+
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.NotContains(meta, model.MetadataKeyCitations)
+	s.NotContains(meta, model.MetadataKeyWebSearchCalls)
+	s.NotContains(meta, model.MetadataKeyWebSearchQueries)
+}
+
+func (s *ResponsesFlowSuite) TestRetryBudgetBoundsSDKRetries() {
+	calls := 0
+	failUntil := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("content-type", "application/json")
+		if calls <= failUntil {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"message":"upstream overloaded","type":"server_error"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"completed","model":"gpt-4.1-mini","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Hello.","annotations":[]}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	newGenerator := func(budget int) model.ContentGenerator[string] {
+		gen, err := NewStringContentGenerator("Hi.",
+			model.WithURL(server.URL),
+			model.WithAuthToken("key"),
+			model.WithModel("gpt-4.1-mini"),
+			model.WithRetryPolicy(model.RetryPolicy{GenerationBudget: budget}),
+		)
+		s.Require().NoError(err)
+		return gen
+	}
+
+	text, meta, err := newGenerator(1).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Hello.", text)
+	s.Equal(2, calls)
+	s.Equal("1", meta[model.MetadataKeyRetries])
+
+	// The SDK would retry twice; the budget stops it after one retry.
+	calls = 0
+	failUntil = 10
+	_, _, err = newGenerator(1).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "upstream overloaded")
+	s.Equal(2, calls)
+
+	calls = 0
+	_, _, err = newGenerator(-1).Generate(context.Background())
+	s.Require().Error(err)
+	s.Equal(1, calls)
+}
+
+func (s *ResponsesFlowSuite) TestPostProcessorsRewriteTextOutput() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_2","object":"response","status":"completed","model":"gpt-4.1-mini","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Creatinine   is\n\n\n\nstable today.","annotations":[]}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithPostProcessors(model.CollapseWhitespace, model.MaxLength(22)),
+	)
+	s.Require().NoError(err)
+
+	text, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Creatinine is\n\nstable", text)
+}
+
+func (s *ResponsesFlowSuite) TestOutputTransformerRunsBeforePostProcessors() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_2","object":"response","status":"completed","model":"gpt-4.1-mini","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"<reply>Stable   today.</reply>","annotations":[]}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithOutputTransformer(func(ctx context.Context, raw string) (string, error) {
+			return strings.TrimSuffix(strings.TrimPrefix(raw, "<reply>"), "</reply>"), nil
+		}),
+		model.WithPostProcessors(model.CollapseWhitespace),
+	)
+	s.Require().NoError(err)
+
+	text, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Stable today.", text)
+}
+
+func (s *ResponsesFlowSuite) TestEncryptedReasoningIsResentAcrossToolRounds() {
+	replies := []string{
+		`{"id":"resp_1","object":"response","status":"completed","model":"gpt-5-mini","output":[
+			{"type":"reasoning","id":"rs_1","summary":[{"type":"summary_text","text":"Need labs."}],"encrypted_content":"enc-1","status":"completed"},
+			{"type":"function_call","id":"fc_1","call_id":"call_1","name":"lookup_labs","arguments":"{}","status":"completed"}],
+			"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15,"output_tokens_details":{"reasoning_tokens":4}}}`,
+		`{"id":"resp_2","object":"response","status":"completed","model":"gpt-5-mini","output":[
+			{"type":"reasoning","id":"rs_2","summary":[],"encrypted_content":"enc-2"},
+			{"type":"function_call","id":"fc_2","call_id":"call_2","name":"lookup_labs","arguments":"{}","status":"completed"}],
+			"usage":{"input_tokens":20,"output_tokens":5,"total_tokens":25,"output_tokens_details":{"reasoning_tokens":3}}}`,
+		`{"id":"resp_3","object":"response","status":"completed","model":"gpt-5-mini","output":[
+			{"type":"reasoning","id":"rs_3","summary":[],"encrypted_content":"enc-3"},
+			{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"eGFR is 48.","annotations":[]}]}],
+			"usage":{"input_tokens":30,"output_tokens":5,"total_tokens":35}}`,
+	}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(replies[len(requests)-1]))
+	}))
+	defer server.Close()
+
+	lookup := model.Tool{
+		Name:        "lookup_labs",
+		Description: "Look up recent labs",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return map[string]any{"egfr": 48}, nil
+		},
+	}
+	gen, err := NewStringContentGenerator(
+		"What is the eGFR?",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-5-mini"),
+		model.WithReasoningLevel(model.ReasoningLevelLow),
+		model.WithTools([]model.Tool{lookup}),
+	)
+	s.Require().NoError(err)
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("eGFR is 48.", text)
+	s.Equal("2", meta[model.MetadataKeyToolRounds])
+	s.Equal("7", meta[model.MetadataKeyReasoningTokens])
+	toolCalls, err := model.ParseToolCalls(meta)
+	s.Require().NoError(err)
+	s.Require().Len(toolCalls, 2)
+	s.Equal(model.ToolCallRecord{Round: 2, Index: 0, Name: "lookup_labs", CallID: "call_2", DurationMs: toolCalls[1].DurationMs}, toolCalls[1])
+	s.Require().Len(requests, 3)
+
+	for _, request := range requests {
+		s.Equal([]any{"reasoning.encrypted_content"}, request["include"])
+	}
+
+	last := requests[2]["input"].([]any)
+	var types []string
+	var reasoning []map[string]any
+	for _, raw := range last {
+		item := raw.(map[string]any)
+		itemType, _ := item["type"].(string)
+		if itemType == "" {
+			itemType = "message"
+		}
+		types = append(types, itemType)
+		if itemType == "reasoning" {
+			reasoning = append(reasoning, item)
+		}
+	}
+	s.Equal([]string{
+		"message",
+		"reasoning", "function_call", "function_call_output",
+		"reasoning", "function_call", "function_call_output",
+	}, types)
+
+	s.Require().Len(reasoning, 2)
+	s.Equal("rs_1", reasoning[0]["id"])
+	s.Equal("enc-1", reasoning[0]["encrypted_content"])
+	s.Equal([]any{map[string]any{"type": "summary_text", "text": "Need labs."}}, reasoning[0]["summary"])
+	s.NotContains(reasoning[0], "status")
+	s.Equal("rs_2", reasoning[1]["id"])
+	s.Equal("enc-2", reasoning[1]["encrypted_content"])
+	s.Equal([]any{}, reasoning[1]["summary"])
+}
+
+func (s *ResponsesFlowSuite) TestParallelToolCallsDisabledSerializesHandlers() {
+	replies := []string{
+		`{"id":"resp_1","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"function_call","id":"fc_1","call_id":"call_1","name":"record_dose","arguments":"{}","status":"completed"},
+			{"type":"function_call","id":"fc_2","call_id":"call_2","name":"record_dose","arguments":"{}","status":"completed"}],
+			"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`,
+		`{"id":"resp_2","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Recorded.","annotations":[]}]}],
+			"usage":{"input_tokens":20,"output_tokens":5,"total_tokens":25}}`,
+	}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(replies[len(requests)-1]))
+	}))
+	defer server.Close()
+
+	var active, maxActive atomic.Int32
+	record := model.Tool{
+		Name:        "record_dose",
+		Description: "Record a dose in the chart",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			current := active.Add(1)
+			defer active.Add(-1)
+			if current > maxActive.Load() {
+				maxActive.Store(current)
+			}
+			time.Sleep(10 * time.Millisecond)
+			return "ok", nil
+		},
+	}
+	gen, err := NewStringContentGenerator(
+		"Record both doses.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithTools([]model.Tool{record}),
+		model.WithToolParallelism(4),
+		model.WithParallelToolCalls(false),
+	)
+	s.Require().NoError(err)
+
+	text, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Recorded.", text)
+	s.Equal(int32(1), maxActive.Load())
+	s.Require().Len(requests, 2)
+	for _, request := range requests {
+		s.Equal(false, request["parallel_tool_calls"])
+	}
+}
+
+func (s *ResponsesFlowSuite) TestParallelToolCallsOmittedByDefault() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(webSearchResponseJSON))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithBuiltinTools(model.BuiltinWebSearch),
+	)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.NotContains(body, "parallel_tool_calls")
+
+	gen, err = NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithParallelToolCalls(false),
+	)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.NotContains(body, "parallel_tool_calls", "without tools the flag is not sent")
+}
+
+type fakeMCPPool struct {
+	servers []model.MCPTool
+}
+
+func (p *fakeMCPPool) MCPServerTools(ctx context.Context, server model.MCPTool) ([]model.Tool, error) {
+	p.servers = append(p.servers, server)
+	return []model.Tool{{
+		Name: "get_egfr",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return 48, nil
+		},
+	}}, nil
+}
+
+func (s *ResponsesFlowSuite) TestLocalMCPBridgeModeRunsMCPToolsClientSide() {
+	replies := []string{
+		`{"id":"resp_1","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"function_call","id":"fc_1","call_id":"call_1","name":"get_egfr","arguments":"{}","status":"completed"}],
+			"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`,
+		`{"id":"resp_2","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"eGFR is 48.","annotations":[]}]}],
+			"usage":{"input_tokens":20,"output_tokens":5,"total_tokens":25}}`,
+	}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(replies[len(requests)-1]))
+	}))
+	defer server.Close()
+
+	pool := &fakeMCPPool{}
+	gen, err := NewStringContentGenerator(
+		"What is the eGFR?",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithMCPTools([]model.MCPTool{{Name: "labs", URL: "https://labs.internal/mcp", AuthToken: "t"}}),
+		model.WithMCPAdapterPool(pool),
+		model.WithMCPBridgeMode(model.MCPBridgeModeLocal),
+	)
+	s.Require().NoError(err)
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("eGFR is 48.", text)
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	s.Require().Len(pool.servers, 1)
+	s.Equal("labs", pool.servers[0].Name)
+
+	s.Require().Len(requests, 2)
+	tools := requests[0]["tools"].([]any)
+	s.Require().Len(tools, 1)
+	s.Equal("function", tools[0].(map[string]any)["type"])
+	s.Equal("get_egfr", tools[0].(map[string]any)["name"])
+
+	last := requests[1]["input"].([]any)
+	output := last[len(last)-1].(map[string]any)
+	s.Equal("function_call_output", output["type"])
+	s.Equal("48", output["output"])
+}
+
+func (s *ResponsesFlowSuite) TestReasoningWithoutEncryptedContentIsResentByID() {
+	var response responses.Response
+	s.Require().NoError(json.Unmarshal([]byte(`{"id":"resp_1","output":[
+		{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":null,"status":null},
+		{"type":"function_call","id":"fc_1","call_id":"call_1","name":"lookup_labs","arguments":"{}"}]}`), &response))
+
+	items, err := responseOutputToInputItems(response.Output)
+	s.Require().NoError(err)
+	s.Require().Len(items, 2)
+	s.Require().NotNil(items[0].OfReasoning)
+	s.Equal("rs_1", items[0].OfReasoning.ID)
+	s.False(items[0].OfReasoning.EncryptedContent.Valid())
+	s.Equal(1, countReasoningWithoutEncryptedContent(response.Output))
+
+	encoded, err := json.Marshal(items[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"type":"reasoning","id":"rs_1","summary":[]}`, string(encoded))
+}
+
+func (s *ResponsesFlowSuite) TestCodeInterpreterAndFileSearchReportFiles() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"file_search_call","id":"fs_1","status":"completed","queries":["egfr trend"]},
+			{"type":"code_interpreter_call","id":"ci_1","status":"completed","code":"plot()","container_id":"cntr_1","outputs":null},
+			{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[
+				{"type":"output_text","text":"Plot saved to egfr.png per labs.pdf.","annotations":[
+					{"type":"container_file_citation","container_id":"cntr_1","file_id":"cfile_1","filename":"egfr.png","start_index":14,"end_index":22},
+					{"type":"file_citation","file_id":"file_9","filename":"labs.pdf","index":35}
+				]}
+			]}],
+			"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator(
+		"Plot the eGFR trend from the uploaded labs.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-4.1-mini"),
+		model.WithCodeInterpreter(model.CodeInterpreterConfig{FileIDs: []string{"file_1"}, MemoryLimit: "4g"}),
+		model.WithFileSearch(model.FileSearchConfig{VectorStoreIDs: []string{"vs_1"}, MaxResults: 5}),
+	)
+	s.Require().NoError(err)
+
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	tools := body["tools"].([]any)
+	s.Require().Len(tools, 2)
+	codeInterpreter, _ := json.Marshal(tools[0])
+	s.JSONEq(`{"type":"code_interpreter","container":{"type":"auto","file_ids":["file_1"],"memory_limit":"4g"}}`, string(codeInterpreter))
+	fileSearch, _ := json.Marshal(tools[1])
+	s.JSONEq(`{"type":"file_search","vector_store_ids":["vs_1"],"max_num_results":5}`, string(fileSearch))
+
+	s.Equal("1", meta[model.MetadataKeyCodeInterpreterCalls])
+	s.Equal("1", meta[model.MetadataKeyFileSearchCalls])
+	s.NotContains(meta, model.MetadataKeyCitations)
+	files, err := model.ParseFileAnnotations(meta)
+	s.Require().NoError(err)
+	s.Equal([]model.FileAnnotation{
+		{Type: "container_file_citation", FileID: "cfile_1", Filename: "egfr.png", ContainerID: "cntr_1", StartIndex: 14, EndIndex: 22},
+		{Type: "file_citation", FileID: "file_9", Filename: "labs.pdf", Index: 35},
+	}, files)
+}
+
+func (s *ResponsesFlowSuite) TestMapBuiltinToolsContainerAndValidation() {
+	tools, err := mapBuiltinTools(model.ResolveGeneratorOpts(
+		model.WithCodeInterpreter(model.CodeInterpreterConfig{ContainerID: "cntr_1", FileIDs: []string{"ignored"}}),
+	))
+	s.Require().NoError(err)
+	s.Require().Len(tools, 1)
+	encoded, err := json.Marshal(tools[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"type":"code_interpreter","container":"cntr_1"}`, string(encoded))
+
+	tools, err = mapBuiltinTools(model.ResolveGeneratorOpts(model.WithBuiltinTools(model.BuiltinCodeInterpreter)))
+	s.Require().NoError(err)
+	encoded, err = json.Marshal(tools[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"type":"code_interpreter","container":{"type":"auto"}}`, string(encoded))
+
+	_, err = mapBuiltinTools(model.ResolveGeneratorOpts(model.WithBuiltinTools(model.BuiltinFileSearch)))
+	s.Require().Error(err)
+	s.Contains(err.Error(), "vector store")
+
+	_, err = mapBuiltinTools(model.ResolveGeneratorOpts(model.WithBuiltinTools("image_generation")))
+	s.Require().Error(err)
+	s.Contains(err.Error(), `built-in tool "image_generation" is not supported`)
+}
+
+func (s *ResponsesFlowSuite) TestProviderParamsAreMergedIntoBody() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"completed","model":"gpt-5-mini","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Hello.","annotations":[]}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL),
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-5-mini"),
+		model.WithReasoningLevel(model.ReasoningLevelLow),
+		model.WithProviderParams(map[string]any{"reasoning": map[string]any{"summary": "auto"}, "service_tier": "flex"}),
+	)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal(map[string]any{"effort": "low", "summary": "auto"}, body["reasoning"])
+	s.Equal("flex", body["service_tier"])
+	s.Equal("gpt-5-mini", body["model"])
+}
+
+type structuredStatus struct {
+	Status string `json:"status"`
+}
+
+func structuredResponseJSON(text string) string {
+	encoded, _ := json.Marshal(text)
+	return `{"id":"resp_1","object":"response","status":"completed","model":"gpt-4.1-mini","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":` + string(encoded) + `,"annotations":[]}]}]}`
+}
+
+func (s *ResponsesFlowSuite) TestStructuredFallsBackToPromptWhenGatewayRejectsJSONSchema() {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.Header().Set("content-type", "application/json")
+		if _, native := body["text"]; native {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Unsupported parameter: text.format json_schema","type":"invalid_request_error","param":"text.format","code":"unsupported_parameter"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(structuredResponseJSON("```json\n{\"status\":\"ok\"}\n```")))
+	}))
+	defer server.Close()
+
+	newGen := func() model.ContentGenerator[structuredStatus] {
+		gen, err := NewStructureContentGenerator[structuredStatus]("Report status.",
+			model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"))
+		s.Require().NoError(err)
+		return gen
+	}
+
+	out, meta, err := newGen().Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModePrompt), meta[model.MetadataKeyStructuredOutputMode])
+
+	s.Require().Len(bodies, 2)
+	input := bodies[1]["input"].([]any)
+	instruction := input[0].(map[string]any)
+	s.Equal("system", instruction["role"])
+	s.Contains(instruction["content"], `"status"`)
+
+	// The rejection is remembered for the endpoint, so the next generation
+	// goes straight to prompt mode.
+	_, _, err = newGen().Generate(context.Background())
+	s.Require().NoError(err)
+	s.Len(bodies, 3)
+	s.NotContains(bodies[2], "text")
+}
+
+func (s *ResponsesFlowSuite) TestStructuredNativeModeDoesNotFallBack() {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"response_format json_schema is not supported","type":"invalid_request_error","param":null,"code":null}}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewStructureContentGenerator[structuredStatus]("Report status.",
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"),
+		model.WithStructuredOutputMode(model.StructuredOutputModeNative))
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.Error(err)
+	s.Equal(1, calls)
+}
+
+func (s *ResponsesFlowSuite) TestStructuredNativeSuccessAndSchemaErrors() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(structuredResponseJSON(`{"status":"ok"}`)))
+	}))
+	defer server.Close()
+
+	gen, err := NewStructureContentGenerator[structuredStatus]("Report status.",
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"))
+	s.Require().NoError(err)
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	s.Equal(string(model.StructuredOutputModeNative), meta[model.MetadataKeyStructuredOutputMode])
+	format := body["text"].(map[string]any)["format"].(map[string]any)
+	s.Equal("json_schema", format["type"])
+	s.Equal(true, format["strict"])
+
+	s.False(isJSONSchemaUnsupportedError(&openai.Error{StatusCode: http.StatusBadRequest, Code: "invalid_json_schema", Param: "text.format.schema"}))
+	s.False(isJSONSchemaUnsupportedError(&openai.Error{StatusCode: http.StatusBadRequest, Message: "prompt is too long"}))
+	s.False(isJSONSchemaUnsupportedError(&openai.Error{StatusCode: http.StatusInternalServerError, Message: "json_schema"}))
+	s.True(isJSONSchemaUnsupportedError(&openai.Error{StatusCode: http.StatusUnprocessableEntity, Message: "strict mode is not supported"}))
+}
+
+func (s *ResponsesFlowSuite) TestGatewayHeadersAndMetadata() {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("content-type", "application/json")
+		w.Header().Set("x-kong-request-id", "kong-1")
+		w.Header().Set("x-kong-llm-model", "gpt-4.1-mini-2025")
+		_, _ = w.Write([]byte(structuredResponseJSON("hi")))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.",
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"),
+		model.WithGateway(model.GatewayProfile{Kind: model.GatewayKong, VirtualKey: "kong-key", Headers: map[string]string{"x-route": "renal"}}),
+	)
+	s.Require().NoError(err)
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	s.Equal("kong-key", header.Get("apikey"))
+	s.Equal("renal", header.Get("x-route"))
+	s.Equal("Bearer key", header.Get("Authorization"))
+	s.Equal("kong", meta[model.MetadataKeyGateway])
+	s.Equal("kong-1", meta[model.MetadataKeyGatewayRequestID])
+	s.Equal("gpt-4.1-mini-2025", meta[model.MetadataKeyGatewayModel])
+}
+
+func (s *ResponsesFlowSuite) TestContextTokenAccountingReportsEachContext() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(structuredResponseJSON("ok")))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Summarize.",
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"),
+		model.WithContextTokenAccounting(true))
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "Be brief.")
+	gen.AddPromptContextProvider(context.Background(), &stubPromptContextProvider{contexts: []*model.PromptContext{
+		{MessageType: model.ContextMessageTypeHuman, Content: "Chunk: eGFR 48 on 2026-01-02."},
+	}})
+
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	entries, err := model.ParseContextTokens(meta)
+	s.Require().NoError(err)
+	s.Equal([]model.ContextTokens{
+		{Index: 0, MessageType: model.ContextMessageTypeSystem, Tokens: 3},
+		{Index: 1, MessageType: model.ContextMessageTypeHuman, Tokens: 8},
+		{Index: -1, MessageType: model.ContextMessageTypeHuman, Tokens: 3},
+	}, entries)
+}
+
+func (s *ResponsesFlowSuite) TestContextDedupDropsRepeatedChunks() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(structuredResponseJSON("ok")))
+	}))
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Summarize.",
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"),
+		model.WithContextDedup(model.ContextDedupConfig{}))
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeHuman, "Chunk: eGFR 48.")
+	gen.AddPromptContextProvider(context.Background(), &stubPromptContextProvider{contexts: []*model.PromptContext{
+		{MessageType: model.ContextMessageTypeHuman, Content: "Chunk:  eGFR 48."},
+	}})
+
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Len(body["input"], 2)
+	s.Equal("1", meta[model.MetadataKeyDedupedContexts])
+}
+
+func (s *ResponsesFlowSuite) TestServerSideStateChainsToolRoundsByResponseID() {
+	replies := []string{
+		`{"id":"resp_1","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"function_call","id":"fc_1","call_id":"call_1","name":"lookup_labs","arguments":"{}","status":"completed"}],
+			"usage":{"input_tokens":500,"output_tokens":5,"total_tokens":505}}`,
+		`{"id":"resp_2","object":"response","status":"completed","model":"gpt-4.1-mini","output":[
+			{"type":"function_call","id":"fc_2","call_id":"call_2","name":"lookup_labs","arguments":"{}","status":"completed"}],
+			"usage":{"input_tokens":520,"output_tokens":5,"total_tokens":525}}`,
+		structuredResponseJSON("eGFR is 48."),
+	}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(replies[len(requests)-1]))
+	}))
+	defer server.Close()
+
+	lookup := model.Tool{
+		Name:        "lookup_labs",
+		Description: "Look up recent labs",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return map[string]any{"egfr": 48}, nil
+		},
+	}
+	gen, err := NewStringContentGenerator("What is the eGFR?",
+		model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"),
+		model.WithTools([]model.Tool{lookup}),
+		model.WithServerSideState(true),
+	)
+	s.Require().NoError(err)
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("eGFR is 48.", text)
+	s.Equal("2", meta[model.MetadataKeyToolRounds])
+	s.Require().Len(requests, 3)
+
+	s.Equal(true, requests[0]["store"])
+	s.NotContains(requests[0], "previous_response_id")
+	for i, previous := range []string{"resp_1", "resp_2"} {
+		request := requests[i+1]
+		s.Equal(previous, request["previous_response_id"])
+		s.Equal(true, request["store"])
+		s.NotEmpty(request["tools"])
+		input := request["input"].([]any)
+		s.Require().Len(input, 1, "only the new function call output is sent")
+		s.Equal("function_call_output", input[0].(map[string]any)["type"])
+	}
+}
+
+func (s *ResponsesFlowSuite) TestRawOutputIsKeptAlongsideParsedValue() {
+	output := "```json\n{\"status\":\"ok\"}\n```"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(structuredResponseJSON(output)))
+	}))
+	defer server.Close()
+
+	newGen := func(opts ...model.GeneratorOption) model.ContentGenerator[structuredStatus] {
+		opts = append(opts, model.WithURL(server.URL), model.WithAuthToken("key"), model.WithModel("gpt-4.1-mini"),
+			model.WithStructuredOutputMode(model.StructuredOutputModePrompt))
+		gen, err := NewStructureContentGenerator[structuredStatus]("Report status.", opts...)
+		s.Require().NoError(err)
+		return gen
+	}
+
+	out, meta, err := newGen(model.WithRawOutput(true)).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("ok", out.Status)
+	raw, ok := model.RawOutput(meta)
+	s.True(ok)
+	s.Equal(output, raw)
+
+	_, meta, err = newGen().Generate(context.Background())
+	s.Require().NoError(err)
+	s.NotContains(meta, model.MetadataKeyRawOutput)
+
+	output = "The status is fine."
+	_, meta, err = newGen(model.WithRawOutput(true)).Generate(context.Background())
+	s.Require().Error(err)
+	s.Equal("The status is fine.", meta[model.MetadataKeyRawOutput])
+}
+
+func (s *ResponsesFlowSuite) TestUnixSocketGatewayURL() {
+	dir, err := os.MkdirTemp("", "gateway")
+	s.Require().NoError(err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	socketPath := filepath.Join(dir, "gateway.sock")
+	listener, err := net.Listen("unix", socketPath)
+	s.Require().NoError(err)
+
+	var path string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_3","object":"response","status":"completed","model":"local","output":[{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Hello.","annotations":[]}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	gen, err := NewStringContentGenerator("Hi.", model.WithURL("unix://"+socketPath+"?path=/v1"), model.WithAuthToken("key"), model.WithModel("local"))
+	s.Require().NoError(err)
+	text, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Hello.", text)
+	s.Equal("/v1/responses", path)
+}
@@ -81,18 +81,20 @@ func (c *client) runEmbeddings(
 	if err != nil {
 		return nil, nil, utils.WrapIfNotNil(err)
 	}
-	if cfg.EmbeddingDimensions != nil && *cfg.EmbeddingDimensions <= 0 {
-		return nil, nil, utils.WrapIfNotNil(errors.New("embedding dimensions must be greater than zero"))
+	modelName := resolveEmbeddingModelName(cfg)
+	dimensions, err := model.ResolveEmbeddingDimensions(cfg, providerName, modelName, logging.NewLogger(ctx))
+	if err != nil {
+		return nil, nil, utils.WrapIfNotNil(err)
 	}
 
 	params := openai.EmbeddingNewParams{
 		Input: openai.EmbeddingNewParamsInputUnion{
 			OfArrayOfStrings: append([]string(nil), inputs...),
 		},
-		Model: openai.EmbeddingModel(resolveEmbeddingModelName(cfg)),
+		Model: openai.EmbeddingModel(modelName),
 	}
-	if cfg.EmbeddingDimensions != nil {
-		params.Dimensions = openai.Int(int64(*cfg.EmbeddingDimensions))
+	if dimensions != nil {
+		params.Dimensions = openai.Int(int64(*dimensions))
 	}
 
 	response, err := c.apiClient.Embeddings.New(ctx, params)
@@ -0,0 +1,117 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// historyItem is the JSON shape of the Responses API input items the flow
+// records. Items copied from a response are raw JSON overrides without typed
+// fields, so every item is read back from its encoding.
+type historyItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	CallID    string          `json:"call_id"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Output    json.RawMessage `json:"output"`
+}
+
+// buildConversationHistory flattens Responses API input items into the
+// provider-neutral format: function_call items become tool calls on an
+// assistant turn and function_call_output items become tool messages.
+// Reasoning and built-in tool items are left out.
+func buildConversationHistory(modelName string, items responses.ResponseInputParam) model.ConversationHistory {
+	history := model.ConversationHistory{
+		Version:  model.ConversationHistoryVersion,
+		Provider: providerName,
+		Model:    modelName,
+		Messages: make([]model.HistoryMessage, 0, len(items)),
+	}
+
+	// Output items only carry the call ID, so remember which tool each ID named.
+	toolNames := map[string]string{}
+	for _, param := range items {
+		encoded, err := json.Marshal(param)
+		if err != nil {
+			continue
+		}
+		var item historyItem
+		if err := json.Unmarshal(encoded, &item); err != nil {
+			continue
+		}
+
+		switch item.Type {
+		case "", "message":
+			content := historyText(item.Content)
+			if content == "" {
+				continue
+			}
+			history.Messages = append(history.Messages, model.HistoryMessage{Role: historyRole(item.Role), Content: content})
+		case "function_call":
+			toolNames[item.CallID] = item.Name
+			var arguments json.RawMessage
+			if trimmed := strings.TrimSpace(item.Arguments); json.Valid([]byte(trimmed)) {
+				arguments = json.RawMessage(trimmed)
+			}
+			call := model.HistoryToolCall{ID: item.CallID, Name: item.Name, Arguments: arguments}
+			// A turn's text and its parallel calls are separate items.
+			if last := len(history.Messages) - 1; last >= 0 && history.Messages[last].Role == model.HistoryRoleAssistant {
+				history.Messages[last].ToolCalls = append(history.Messages[last].ToolCalls, call)
+				continue
+			}
+			history.Messages = append(history.Messages, model.HistoryMessage{
+				Role:      model.HistoryRoleAssistant,
+				ToolCalls: []model.HistoryToolCall{call},
+			})
+		case "function_call_output":
+			history.Messages = append(history.Messages, model.HistoryMessage{
+				Role:       model.HistoryRoleTool,
+				Content:    historyText(item.Output),
+				ToolCallID: item.CallID,
+				ToolName:   toolNames[item.CallID],
+			})
+		}
+	}
+	return history
+}
+
+// historyRole maps Responses API roles to history roles; developer messages
+// are system instructions.
+func historyRole(role string) model.HistoryRole {
+	switch role {
+	case "system", "developer":
+		return model.HistoryRoleSystem
+	case "assistant":
+		return model.HistoryRoleAssistant
+	default:
+		return model.HistoryRoleUser
+	}
+}
+
+// historyText reads content that is either a string or a list of content
+// parts, keeping the text parts.
+func historyText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
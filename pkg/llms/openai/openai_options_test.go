@@ -2,6 +2,7 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -78,6 +79,27 @@ func (s *GeneratorOptionValidationSuite) TestReasoningOnNonReasoningModelIsIgnor
 	s.Assert().Nil(normalized.ReasoningLevel)
 }
 
+func (s *GeneratorOptionValidationSuite) TestCachedContentIsRejectedUnlessIgnored() {
+	_, err := normalizeGeneratorOptionsForModel(
+		"gpt-4.1-mini",
+		model.ResolveGeneratorOpts(model.WithCachedContent("cachedContents/abc")),
+		nil,
+	)
+	s.Require().Error(err)
+	s.Assert().Contains(err.Error(), "cached content is not supported for openai provider")
+
+	normalized, err := normalizeGeneratorOptionsForModel(
+		"gpt-4.1-mini",
+		model.ResolveGeneratorOpts(
+			model.WithIgnoreInvalidGeneratorOptions(true),
+			model.WithCachedContent("cachedContents/abc"),
+		),
+		nil,
+	)
+	s.Require().NoError(err)
+	s.Assert().Empty(normalized.CachedContent)
+}
+
 func (s *GeneratorOptionValidationSuite) TestBuildInputItemsWithContextIncludesPromptContexts() {
 	items, contextCount, err := buildInputItemsWithContext("final prompt", []*model.PromptContext{
 		{
@@ -102,7 +124,7 @@ func (s *GeneratorOptionValidationSuite) TestAddPromptContextIsUsedByGeneratorIn
 	g := &textGenerator{prompt: "main prompt"}
 	g.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "be concise")
 
-	items, contextCount, err := g.inputItemsWithContext(context.Background())
+	items, contextCount, err := g.inputItemsWithContext(context.Background(), nil)
 
 	s.Require().NoError(err)
 	s.Assert().Equal(1, contextCount)
@@ -124,7 +146,7 @@ func (s *GeneratorOptionValidationSuite) TestAddPromptContextProviderIsCalledDur
 	g := &textGenerator{prompt: "main prompt"}
 	g.AddPromptContextProvider(context.Background(), provider)
 
-	items, contextCount, err := g.inputItemsWithContext(context.Background())
+	items, contextCount, err := g.inputItemsWithContext(context.Background(), nil)
 
 	s.Require().NoError(err)
 	s.Assert().Equal(1, provider.calls)
@@ -142,7 +164,7 @@ func (s *GeneratorOptionValidationSuite) TestInputBuildReturnsProviderError() {
 	g := &textGenerator{prompt: "main prompt"}
 	g.AddPromptContextProvider(context.Background(), provider)
 
-	_, _, err := g.inputItemsWithContext(context.Background())
+	_, _, err := g.inputItemsWithContext(context.Background(), nil)
 
 	s.Require().Error(err)
 	s.Assert().Contains(err.Error(), "provider failed")
@@ -199,3 +221,72 @@ func assertMessageItem(s *GeneratorOptionValidationSuite, item responses.Respons
 	s.Assert().Equal(expectedRole, item.OfMessage.Role)
 	s.Assert().Equal(expectedContent, item.OfMessage.Content.OfString.Value)
 }
+
+func (s *GeneratorOptionValidationSuite) TestBuildInputItemsWithImageContext() {
+	items, contextCount, err := buildInputItemsWithContext("final prompt", []*model.PromptContext{
+		model.ImagePromptContext("What does the scan show?", model.ImagePart{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")}),
+	})
+	s.Require().NoError(err)
+	s.Equal(1, contextCount)
+	s.Require().Len(items, 2)
+
+	encoded, err := json.Marshal(items[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"role":"user","content":[
+		{"type":"input_text","text":"What does the scan show?"},
+		{"type":"input_image","detail":"auto","image_url":"data:image/png;base64,iVBORw0KGgoAAAANSUhEUg=="}]}`, string(encoded))
+}
+
+func (s *GeneratorOptionValidationSuite) TestAttachDocumentsAddsInputFiles() {
+	items, _, err := buildInputItemsWithContext("Summarize the attachments.", nil)
+	s.Require().NoError(err)
+	items, err = attachDocuments(items, "Summarize the attachments.", []model.DocumentPart{
+		{Name: "labs.pdf", Data: []byte("%PDF-1.7")},
+		{URL: "https://example.org/letter.docx"},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+
+	encoded, err := json.Marshal(items[0])
+	s.Require().NoError(err)
+	s.JSONEq(`{"role":"user","content":[
+		{"type":"input_file","filename":"labs.pdf","file_data":"data:application/pdf;base64,JVBERi0xLjc="},
+		{"type":"input_file","filename":"letter.docx","file_url":"https://example.org/letter.docx"},
+		{"type":"input_text","text":"Summarize the attachments."}]}`, string(encoded))
+
+	_, err = attachDocuments(items, "prompt", []model.DocumentPart{{Name: "scan.png", Data: []byte("\x89PNG\r\n\x1a\n")}})
+	s.Error(err)
+}
+
+func (s *GeneratorOptionValidationSuite) TestEffectiveConfigAppliesModelNormalization() {
+	gen, err := NewStringContentGenerator("hi",
+		model.WithAuthToken("key"),
+		model.WithModel("gpt-5-mini"),
+		model.WithTemperature(0.2),
+		model.WithIgnoreInvalidGeneratorOptions(true),
+	)
+	s.Require().NoError(err)
+
+	cfg, err := gen.(model.EffectiveConfigReporter).EffectiveConfig(context.Background())
+	s.Require().NoError(err)
+	s.Nil(cfg.Temperature)
+	s.Require().NotNil(cfg.Model)
+	s.Equal("gpt-5-mini", *cfg.Model)
+}
+
+func (s *GeneratorOptionValidationSuite) TestSamplingRangesAreValidated() {
+	_, err := normalizeGeneratorOptionsForModel("gpt-4.1-mini", model.ResolveGeneratorOpts(model.WithTemperature(2.5)), nil)
+	s.ErrorContains(err, "temperature 2.5 is out of range [0, 2] for openai provider")
+
+	_, err = normalizeGeneratorOptionsForModel("gpt-4.1-mini", model.ResolveGeneratorOpts(model.WithMaxTokens(50000)), nil)
+	s.ErrorContains(err, `exceeds the 32768 output token limit of model "gpt-4.1-mini"`)
+
+	normalized, err := normalizeGeneratorOptionsForModel("gpt-4.1-mini", model.ResolveGeneratorOpts(
+		model.WithTemperature(2.5),
+		model.WithMaxTokens(50000),
+		model.WithIgnoreInvalidGeneratorOptions(true),
+	), nil)
+	s.Require().NoError(err)
+	s.Equal(2.0, *normalized.Temperature)
+	s.Equal(32768, *normalized.MaxTokens)
+}
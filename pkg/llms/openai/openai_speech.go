@@ -0,0 +1,157 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+const (
+	defaultSpeechModelName = "gpt-4o-mini-tts"
+	defaultSpeechVoice     = "alloy"
+)
+
+type speechGenerator struct {
+	client *client
+	text   string
+	opts   model.SpeechOptions
+}
+
+func NewSpeechGenerator(text string, opts model.SpeechOptions) (model.SpeechGenerator, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("text is required"))
+	}
+
+	c, err := newClient(model.GeneratorConfig{
+		IgnoreInvalidGeneratorOptions: opts.IgnoreInvalidGeneratorOptions,
+		URL:                           opts.URL,
+		AuthToken:                     opts.AuthToken,
+		TLSConfig:                     opts.TLSConfig,
+	})
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	return &speechGenerator{client: c, text: text, opts: opts}, nil
+}
+
+func (g *speechGenerator) Generate(ctx context.Context) (model.SpeechAudio, model.GenerationMetadata, error) {
+	start := time.Now()
+	log := logging.NewLogger(ctx)
+	modelName := resolveSpeechModelName(g.opts)
+	meta := initMetadata(providerName, modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log.Infof("speech_request model=%q", modelName)
+
+	opts, err := normalizeSpeechOptions(g.opts, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+	format := resolveSpeechFormat(opts)
+	response, err := g.client.apiClient.Audio.Speech.New(ctx, buildSpeechParams(g.text, opts))
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+	if len(data) == 0 {
+		err = errors.New("speech response is empty")
+		log.Errorf("error: %v", err)
+		return model.SpeechAudio{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	meta[model.MetadataKeyAPICalls] = "1"
+	meta[model.MetadataKeyInputCharacters] = strconv.Itoa(len([]rune(g.text)))
+	return model.SpeechAudio{Data: data, Format: format, MIMEType: format.MIMEType()}, meta, nil
+}
+
+func buildSpeechParams(text string, opts model.SpeechOptions) openai.AudioSpeechNewParams {
+	params := openai.AudioSpeechNewParams{
+		Input:          text,
+		Model:          openai.SpeechModel(resolveSpeechModelName(opts)),
+		Voice:          openai.AudioSpeechNewParamsVoice(resolveSpeechVoice(opts)),
+		ResponseFormat: openai.AudioSpeechNewParamsResponseFormat(resolveSpeechFormat(opts)),
+	}
+	if opts.Speed != nil {
+		params.Speed = param.NewOpt(*opts.Speed)
+	}
+	if instructions := strings.TrimSpace(opts.Instructions); instructions != "" {
+		params.Instructions = param.NewOpt(instructions)
+	}
+	return params
+}
+
+// normalizeSpeechOptions rejects options the selected model does not support:
+// instructions on tts-1 models and speeds outside 0.25 to 4.
+func normalizeSpeechOptions(opts model.SpeechOptions, log logging.Logger) (model.SpeechOptions, error) {
+	if strings.TrimSpace(opts.Instructions) != "" && strings.HasPrefix(resolveSpeechModelName(opts), "tts-1") {
+		if !opts.IgnoreInvalidGeneratorOptions {
+			return opts, utils.WrapIfNotNil(errors.New("speech instructions are not supported by tts-1 models"))
+		}
+		if log != nil {
+			log.Warnf("ignoring speech instructions for tts-1 model")
+		}
+		opts.Instructions = ""
+	}
+	if opts.Speed != nil && (*opts.Speed < 0.25 || *opts.Speed > 4) {
+		if !opts.IgnoreInvalidGeneratorOptions {
+			return opts, utils.WrapIfNotNil(errors.New("speech speed must be between 0.25 and 4"))
+		}
+		if log != nil {
+			log.Warnf("ignoring speech speed %v outside 0.25 to 4", *opts.Speed)
+		}
+		opts.Speed = nil
+	}
+	switch resolveSpeechFormat(opts) {
+	case model.SpeechFormatMP3, model.SpeechFormatOpus, model.SpeechFormatAAC, model.SpeechFormatFLAC, model.SpeechFormatWAV, model.SpeechFormatPCM:
+	default:
+		if !opts.IgnoreInvalidGeneratorOptions {
+			return opts, utils.WrapIfNotNil(errors.New("unsupported speech format: " + string(opts.Format)))
+		}
+		if log != nil {
+			log.Warnf("ignoring unsupported speech format %q", opts.Format)
+		}
+		opts.Format = ""
+	}
+	return opts, nil
+}
+
+func resolveSpeechModelName(opts model.SpeechOptions) string {
+	if modelName := strings.TrimSpace(opts.Model); modelName != "" {
+		return modelName
+	}
+	return defaultSpeechModelName
+}
+
+func resolveSpeechVoice(opts model.SpeechOptions) string {
+	if voice := strings.TrimSpace(opts.Voice); voice != "" {
+		return strings.ToLower(voice)
+	}
+	return defaultSpeechVoice
+}
+
+func resolveSpeechFormat(opts model.SpeechOptions) model.SpeechFormat {
+	if opts.Format != "" {
+		return opts.Format
+	}
+	return model.SpeechFormatMP3
+}
@@ -0,0 +1,72 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type SpeechGeneratorSuite struct {
+	suite.Suite
+}
+
+func TestSpeechGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(SpeechGeneratorSuite))
+}
+
+func (s *SpeechGeneratorSuite) TestGenerateSendsVoiceAndFormat() {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/audio/speech", r.URL.Path)
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("content-type", "audio/flac")
+		_, _ = w.Write([]byte("fLaC-audio"))
+	}))
+	defer server.Close()
+
+	speed := 1.25
+	gen, err := NewSpeechGenerator("Your results are ready.", model.SpeechOptions{
+		URL: server.URL, AuthToken: "key", Voice: "Coral", Format: model.SpeechFormatFLAC, Speed: &speed, Instructions: "Speak calmly.",
+	})
+	s.Require().NoError(err)
+
+	audio, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal(model.SpeechAudio{Data: []byte("fLaC-audio"), Format: model.SpeechFormatFLAC, MIMEType: "audio/flac"}, audio)
+	s.Equal(map[string]any{
+		"input":           "Your results are ready.",
+		"model":           defaultSpeechModelName,
+		"voice":           "coral",
+		"response_format": "flac",
+		"speed":           1.25,
+		"instructions":    "Speak calmly.",
+	}, body)
+	s.Equal(defaultSpeechModelName, meta[model.MetadataKeyModel])
+	s.Equal("23", meta[model.MetadataKeyInputCharacters])
+
+	path := s.T().TempDir() + "/speech.flac"
+	s.Require().NoError(audio.WriteFile(path))
+}
+
+func (s *SpeechGeneratorSuite) TestUnsupportedOptionsAreRejectedUnlessIgnored() {
+	_, err := normalizeSpeechOptions(model.SpeechOptions{Model: "tts-1", Instructions: "Speak calmly."}, nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "tts-1")
+
+	speed := 9.0
+	opts, err := normalizeSpeechOptions(model.SpeechOptions{
+		IgnoreInvalidGeneratorOptions: true, Model: "tts-1-hd", Instructions: "Speak calmly.", Speed: &speed, Format: "midi",
+	}, nil)
+	s.Require().NoError(err)
+	s.Empty(opts.Instructions)
+	s.Nil(opts.Speed)
+	s.Equal(model.SpeechFormatMP3, resolveSpeechFormat(opts))
+
+	_, err = NewSpeechGenerator("", model.SpeechOptions{})
+	s.Error(err)
+}
@@ -0,0 +1,223 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared/constant"
+)
+
+type verboseTranscriptionGenerator struct {
+	client   *client
+	filePath string
+	opts     model.AudioOptions
+}
+
+// NewVerboseTranscriptionGenerator returns a generator for timed
+// transcripts. Only whisper models support the verbose response format.
+func NewVerboseTranscriptionGenerator(
+	filePath string,
+	opts model.AudioOptions,
+) (model.VerboseTranscriptionGenerator, error) {
+	if strings.TrimSpace(filePath) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("file path is required"))
+	}
+
+	c, err := newClient(audioGeneratorConfigFromOptions(opts))
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	return &verboseTranscriptionGenerator{
+		client:   c,
+		filePath: filePath,
+		opts:     cloneAudioOptions(opts),
+	}, nil
+}
+
+func (g *verboseTranscriptionGenerator) Generate(ctx context.Context) (model.TranscriptionResult, model.GenerationMetadata, error) {
+	start := time.Now()
+	log := logging.NewLogger(ctx)
+	modelName := resolveAudioTranscriptionModelName(g.opts)
+	meta := initMetadata(providerName, modelName)
+	defer setLatencyMetadata(meta, start)
+
+	log.Infof("verbose_transcription_request model=%q", modelName)
+
+	opts, err := normalizeAudioDiarization(g.opts, log)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+	if opts.Diarize {
+		result, response, err := g.client.diarizeAudio(ctx, g.filePath, opts, log)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+		}
+		applyOpenAIAudioTranscriptionMetadata(meta, response)
+		return model.FinishTranscriptionResult(meta, opts, result), meta, nil
+	}
+
+	verbose := supportsTimedTranscription(modelName)
+	if !verbose {
+		err := errors.New("verbose transcription is not supported by model " + modelName + "; use whisper-1")
+		if !opts.IgnoreInvalidGeneratorOptions {
+			log.Errorf("error: %v", err)
+			return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+		}
+		log.Warnf("returning transcript without timestamps: %v", err)
+	}
+
+	result, response, err := g.client.transcribeTimed(ctx, g.filePath, opts, verbose)
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return model.TranscriptionResult{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	applyOpenAIAudioTranscriptionMetadata(meta, response)
+	return model.FinishTranscriptionResult(meta, opts, result), meta, nil
+}
+
+// supportsTimedTranscription reports whether modelName has the verbose, SRT
+// and VTT response formats; only whisper models do.
+func supportsTimedTranscription(modelName string) bool {
+	return strings.HasPrefix(modelName, "whisper")
+}
+
+// transcribeTimed transcribes filePath with segment timings, and word
+// timings when opts asks for them. Without verbose the result has only text.
+func (c *client) transcribeTimed(
+	ctx context.Context,
+	filePath string,
+	opts model.AudioOptions,
+	verbose bool,
+) (model.TranscriptionResult, *openai.AudioTranscriptionNewResponseUnion, error) {
+	_, response, err := c.transcribeAudio(ctx, filePath, opts, func(params *openai.AudioTranscriptionNewParams) {
+		if !verbose {
+			return
+		}
+		params.ResponseFormat = openai.AudioResponseFormatVerboseJSON
+		params.TimestampGranularities = []string{string(model.TimestampGranularitySegment)}
+		if opts.WantsTimestamps(model.TimestampGranularityWord) {
+			params.TimestampGranularities = append(params.TimestampGranularities, string(model.TimestampGranularityWord))
+		}
+	})
+	if err != nil {
+		return model.TranscriptionResult{}, response, utils.WrapIfNotNil(err)
+	}
+	return toTranscriptionResult(response), response, nil
+}
+
+func toTranscriptionResult(response *openai.AudioTranscriptionNewResponseUnion) model.TranscriptionResult {
+	result := model.TranscriptionResult{
+		Text:     response.Text,
+		Duration: model.SecondsToDuration(response.Duration),
+	}
+	for _, segment := range response.Segments {
+		result.Segments = append(result.Segments, model.TranscriptSegment{
+			ID:         int(segment.ID),
+			Start:      model.SecondsToDuration(segment.Start),
+			End:        model.SecondsToDuration(segment.End),
+			Text:       strings.TrimSpace(segment.Text),
+			Confidence: segmentConfidence(segment.AvgLogprob),
+		})
+	}
+	for _, word := range response.Words {
+		result.Words = append(result.Words, model.TranscriptWord{
+			Word:  strings.TrimSpace(word.Word),
+			Start: model.SecondsToDuration(word.Start),
+			End:   model.SecondsToDuration(word.End),
+		})
+	}
+	return result
+}
+
+// segmentConfidence converts an average token log probability to a 0..1
+// confidence.
+func segmentConfidence(avgLogprob float64) float64 {
+	if avgLogprob > 0 || math.IsNaN(avgLogprob) {
+		return 0
+	}
+	return math.Exp(avgLogprob)
+}
+
+// normalizeAudioDiarization rejects Diarize for models without diarization,
+// or drops it with a warning when invalid options are ignored.
+func normalizeAudioDiarization(opts model.AudioOptions, log logging.Logger) (model.AudioOptions, error) {
+	modelName := resolveAudioTranscriptionModelName(opts)
+	if !opts.Diarize || strings.Contains(modelName, "diarize") {
+		return opts, nil
+	}
+	err := errors.New("diarization is not supported by model " + modelName + "; use " + defaultDiarizationModelName)
+	if !opts.IgnoreInvalidGeneratorOptions {
+		return opts, utils.WrapIfNotNil(err)
+	}
+	log.Warnf("ignoring diarization: %v", err)
+	opts.Diarize = false
+	return opts, nil
+}
+
+// diarizedTranscription is the diarized_json response. The SDK union only
+// decodes the verbose segment shape, which has no speaker.
+type diarizedTranscription struct {
+	Text     string  `json:"text"`
+	Duration float64 `json:"duration"`
+	Segments []struct {
+		Start   float64 `json:"start"`
+		End     float64 `json:"end"`
+		Text    string  `json:"text"`
+		Speaker string  `json:"speaker"`
+	} `json:"segments"`
+}
+
+// diarizeAudio transcribes with a diarization model. These models take no
+// prompt, so keyword hints and custom prompts are dropped with a warning.
+func (c *client) diarizeAudio(
+	ctx context.Context,
+	filePath string,
+	opts model.AudioOptions,
+	log logging.Logger,
+) (model.TranscriptionResult, *openai.AudioTranscriptionNewResponseUnion, error) {
+	modelName := resolveAudioTranscriptionModelName(opts)
+	if opts.Prompt != "" || len(opts.Keywords) > 0 {
+		log.Warnf("ignoring audio prompt and keywords: %s does not accept a prompt", modelName)
+		opts.Prompt = ""
+		opts.Keywords = nil
+	}
+
+	_, response, err := c.transcribeAudio(ctx, filePath, opts, func(params *openai.AudioTranscriptionNewParams) {
+		params.ResponseFormat = openai.AudioResponseFormatDiarizedJSON
+		params.ChunkingStrategy = openai.AudioTranscriptionNewParamsChunkingStrategyUnion{OfAuto: constant.ValueOf[constant.Auto]()}
+	})
+	if err != nil {
+		return model.TranscriptionResult{}, response, utils.WrapIfNotNil(err)
+	}
+
+	var diarized diarizedTranscription
+	if err = json.Unmarshal([]byte(response.RawJSON()), &diarized); err != nil {
+		return model.TranscriptionResult{}, response, utils.WrapIfNotNil(err)
+	}
+	result := model.TranscriptionResult{
+		Text:     diarized.Text,
+		Duration: model.SecondsToDuration(diarized.Duration),
+	}
+	for i, segment := range diarized.Segments {
+		result.Segments = append(result.Segments, model.TranscriptSegment{
+			ID:      i,
+			Start:   model.SecondsToDuration(segment.Start),
+			End:     model.SecondsToDuration(segment.End),
+			Text:    strings.TrimSpace(segment.Text),
+			Speaker: strings.TrimSpace(segment.Speaker),
+		})
+	}
+	return result, response, nil
+}
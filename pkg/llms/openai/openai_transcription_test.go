@@ -0,0 +1,237 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerboseTranscriptionGeneratorSuite struct {
+	suite.Suite
+}
+
+func TestVerboseTranscriptionGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(VerboseTranscriptionGeneratorSuite))
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) writeAudio() string {
+	path := filepath.Join(s.T().TempDir(), "visit.wav")
+	s.Require().NoError(os.WriteFile(path, []byte("RIFF-audio"), 0o600))
+	return path
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestGenerateReturnsSegmentsAndWords() {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/audio/transcriptions", r.URL.Path)
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		form = r.MultipartForm.Value
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"text":" Call me at 555-123-4567. Thanks.","language":"english","duration":3.5,` +
+			`"segments":[{"id":0,"start":0,"end":2.25,"text":" Call me at 555-123-4567.","avg_logprob":-0.1},{"id":1,"start":2.25,"end":3.5,"text":" Thanks.","avg_logprob":-0.5}],` +
+			`"words":[{"word":"Call","start":0,"end":0.4},{"word":"me","start":0.4,"end":0.6}]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewVerboseTranscriptionGenerator(s.writeAudio(), model.AudioOptions{
+		URL: server.URL, AuthToken: "key", Language: "EN",
+		TimestampGranularities: []model.TimestampGranularity{model.TimestampGranularityWord},
+	})
+	s.Require().NoError(err)
+
+	result, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]string{"verbose_json"}, form["response_format"])
+	s.Equal([]string{"segment", "word"}, form["timestamp_granularities[]"])
+	s.Equal([]string{"en"}, form["language"])
+
+	s.Equal("Call me at 555-123-4567. Thanks.", result.Text)
+	s.Equal("en", result.Language)
+	s.Equal(3500*time.Millisecond, result.Duration)
+	s.Require().Len(result.Segments, 2)
+	s.Equal(model.TranscriptSegment{ID: 1, Start: 2250 * time.Millisecond, End: 3500 * time.Millisecond, Text: "Thanks.", Confidence: result.Segments[1].Confidence}, result.Segments[1])
+	s.InDelta(0.905, result.Segments[0].Confidence, 0.001)
+	s.InDelta(0.607, result.Segments[1].Confidence, 0.001)
+	s.Equal([]model.TranscriptWord{
+		{Word: "Call", Start: 0, End: 400 * time.Millisecond},
+		{Word: "me", Start: 400 * time.Millisecond, End: 600 * time.Millisecond},
+	}, result.Words)
+	s.Equal("en", meta[model.MetadataKeyLanguage])
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestRedactPIIScrubsSegmentsAndDropsWords() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"Call 555-123-4567.","duration":2,` +
+			`"segments":[{"id":0,"start":0,"end":2,"text":"Call 555-123-4567."}],"words":[{"word":"Call","start":0,"end":0.4}]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewVerboseTranscriptionGenerator(s.writeAudio(), model.AudioOptions{
+		URL: server.URL, AuthToken: "key", RedactPII: true,
+		TimestampGranularities: []model.TimestampGranularity{model.TimestampGranularityWord},
+	})
+	s.Require().NoError(err)
+
+	result, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Call [PHONE].", result.Text)
+	s.Equal("Call [PHONE].", result.Segments[0].Text)
+	s.Nil(result.Words)
+	s.Equal("PHONE:1", meta[model.MetadataKeyRedactedEntities])
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestNonWhisperModelIsRejectedUnlessIgnored() {
+	var responseFormat string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		responseFormat = r.FormValue("response_format")
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"Stable today."}`))
+	}))
+	defer server.Close()
+
+	opts := model.AudioOptions{URL: server.URL, AuthToken: "key", Model: "gpt-4o-transcribe"}
+	gen, err := NewVerboseTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "whisper-1")
+
+	opts.IgnoreInvalidGeneratorOptions = true
+	gen, err = NewVerboseTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+	result, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("json", responseFormat)
+	s.Equal("Stable today.", result.Text)
+	s.Empty(result.Segments)
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestDiarizeUsesDiarizedJSON() {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		form = r.MultipartForm.Value
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"task":"transcribe","text":"How are you? Better today.","duration":4,` +
+			`"segments":[{"type":"transcript.text.segment","id":"seg_0","start":0,"end":1.5,"text":" How are you?","speaker":"A"},` +
+			`{"type":"transcript.text.segment","id":"seg_1","start":1.5,"end":4,"text":" Better today.","speaker":"B"}],` +
+			`"usage":{"type":"tokens","input_tokens":20,"output_tokens":8,"total_tokens":28}}`))
+	}))
+	defer server.Close()
+
+	opts := model.AudioOptions{URL: server.URL, AuthToken: "key", Diarize: true, Keywords: []model.AudioKeyword{{Word: "eGFR"}}}
+	gen, err := NewVerboseTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+
+	result, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]string{defaultDiarizationModelName}, form["model"])
+	s.Equal([]string{"diarized_json"}, form["response_format"])
+	s.Equal([]string{"auto"}, form["chunking_strategy"])
+	s.Empty(form["prompt"], "diarization models take no prompt")
+	s.Equal(defaultDiarizationModelName, meta[model.MetadataKeyModel])
+	s.Equal("28", meta[model.MetadataKeyTotalTokens])
+	s.Equal([]model.TranscriptSegment{
+		{ID: 0, Start: 0, End: 1500 * time.Millisecond, Text: "How are you?", Speaker: "A"},
+		{ID: 1, Start: 1500 * time.Millisecond, End: 4 * time.Second, Text: "Better today.", Speaker: "B"},
+	}, result.Segments)
+	s.Equal([]string{"A", "B"}, result.Speakers())
+
+	plain, err := NewAudioTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+	transcript, _, err := plain.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("A: How are you?\nB: Better today.", transcript)
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestDiarizeWithUnsupportedModel() {
+	_, err := normalizeAudioDiarization(model.AudioOptions{Model: "whisper-1", Diarize: true}, nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), defaultDiarizationModelName)
+
+	opts, err := normalizeAudioDiarization(model.AudioOptions{Model: "whisper-1", Diarize: true, IgnoreInvalidGeneratorOptions: true}, logging.NewLogger(context.Background()))
+	s.Require().NoError(err)
+	s.False(opts.Diarize)
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestSubtitleOutputUsesNativeFormat() {
+	var responseFormat string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		responseFormat = r.FormValue("response_format")
+		w.Header().Set("content-type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("1\n00:00:00,000 --> 00:00:01,500\nSwelling is better.\n\n"))
+	}))
+	defer server.Close()
+
+	gen, err := NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{
+		URL: server.URL, AuthToken: "key", OutputFormat: model.TranscriptFormatSRT,
+	})
+	s.Require().NoError(err)
+
+	subtitles, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("srt", responseFormat)
+	s.Equal("1\n00:00:00,000 --> 00:00:01,500\nSwelling is better.\n", subtitles)
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestSubtitleOutputWithRedactionRendersSegments() {
+	var responseFormat string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().NoError(r.ParseMultipartForm(1 << 20))
+		responseFormat = r.FormValue("response_format")
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"Call 555-123-4567.","duration":2,"segments":[{"id":0,"start":0,"end":2,"text":" Call 555-123-4567."}]}`))
+	}))
+	defer server.Close()
+
+	gen, err := NewAudioTranscriptionGenerator(s.writeAudio(), model.AudioOptions{
+		URL: server.URL, AuthToken: "key", OutputFormat: model.TranscriptFormatVTT, RedactPII: true,
+	})
+	s.Require().NoError(err)
+
+	subtitles, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("verbose_json", responseFormat)
+	s.Equal("WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nCall [PHONE].\n\n", subtitles)
+	s.Equal("PHONE:1", meta[model.MetadataKeyRedactedEntities])
+}
+
+func (s *VerboseTranscriptionGeneratorSuite) TestSubtitleOutputWithUnsupportedModel() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"Stable today."}`))
+	}))
+	defer server.Close()
+
+	opts := model.AudioOptions{URL: server.URL, AuthToken: "key", Model: "gpt-4o-transcribe", OutputFormat: model.TranscriptFormatSRT}
+	gen, err := NewAudioTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "whisper-1")
+
+	opts.IgnoreInvalidGeneratorOptions = true
+	gen, err = NewAudioTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+	transcript, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Stable today.", transcript)
+
+	opts.OutputFormat = model.TranscriptFormatJSON
+	gen, err = NewAudioTranscriptionGenerator(s.writeAudio(), opts)
+	s.Require().NoError(err)
+	encoded, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.JSONEq(`{"text":"Stable today."}`, encoded)
+}
@@ -0,0 +1,24 @@
+package logging
+
+import "context"
+
+// NopLoggerFactory creates loggers that discard everything. It is useful for
+// benchmarks and for callers that want the library to stay silent.
+type NopLoggerFactory struct{}
+
+func (NopLoggerFactory) CreateLogger(ctx context.Context) Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...any)                 {}
+func (nopLogger) Debugf(format string, args ...any) {}
+func (nopLogger) Info(args ...any)                  {}
+func (nopLogger) Infof(format string, args ...any)  {}
+func (nopLogger) Error(args ...any)                 {}
+func (nopLogger) Errorf(format string, args ...any) {}
+func (nopLogger) Warn(args ...any)                  {}
+func (nopLogger) Warnf(format string, args ...any)  {}
+func (nopLogger) Fatal(args ...any)                 {}
+func (nopLogger) Fatalf(format string, args ...any) {}
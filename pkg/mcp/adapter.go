@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,27 +21,71 @@ type toolClient interface {
 	Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error)
 	ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error)
 	CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
+	ListResources(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error)
+	ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error)
+	ListPrompts(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error)
+	GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error)
+	Ping(ctx context.Context) error
 	Close() error
 }
 
 // ToolAdapter bridges MCP tools into local model.Tool definitions for providers
-// that do not support MCP natively.
+// that do not support MCP natively. It also exposes the server's resources
+// and prompts as model.PromptContextProviders (see ResourceContextProvider
+// and PromptContextProvider). Tool calls whose context carries a
+// model.ToolProgressFunc (see model.ContextWithToolProgress) ask the server
+// for progress notifications and forward them while the call runs.
 type ToolAdapter struct {
 	serverURL       string
 	serverAuthToken string
+	httpHeaders     map[string]string
+	tlsConfig       *tls.Config
 	allowedTools    map[string]struct{}
 
 	mu     sync.RWMutex
 	client toolClient
 	tools  []mcp.Tool
+
+	progressMu        sync.Mutex
+	progressSeq       uint64
+	progressListeners map[string]progressListener
 }
 
-func NewToolAdapter(ctx context.Context, serverURL string, authToken string, allowedTools []string) (*ToolAdapter, error) {
+// ToolAdapterOption configures a ToolAdapter.
+type ToolAdapterOption func(*ToolAdapter)
+
+// WithAdapterHeaders sends headers on every request to the server, for
+// servers that need more than the Authorization token. An Authorization
+// entry is ignored; pass the token to NewToolAdapter instead.
+func WithAdapterHeaders(headers map[string]string) ToolAdapterOption {
+	return func(a *ToolAdapter) {
+		a.httpHeaders = nonAuthorizationHeaders(headers)
+	}
+}
+
+// WithAdapterTLSConfig sets the TLS configuration for a server behind a
+// private CA or requiring client certificates, as model.WithTLSConfig does
+// for providers. The config is cloned.
+func WithAdapterTLSConfig(config *tls.Config) ToolAdapterOption {
+	return func(a *ToolAdapter) {
+		a.tlsConfig = nil
+		if config != nil {
+			a.tlsConfig = config.Clone()
+		}
+	}
+}
+
+func NewToolAdapter(ctx context.Context, serverURL string, authToken string, allowedTools []string, opts ...ToolAdapterOption) (*ToolAdapter, error) {
 	a := &ToolAdapter{
 		serverURL:       serverURL,
 		serverAuthToken: authToken,
 		allowedTools:    normalizeAllowedTools(allowedTools),
 	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(a)
+		}
+	}
 	err := a.Connect(ctx)
 	if err != nil {
 		return nil, utils.WrapIfNotNil(err)
@@ -54,6 +99,9 @@ func (a *ToolAdapter) Connect(ctx context.Context) error {
 	}
 
 	headers := map[string]string{}
+	for key, value := range a.httpHeaders {
+		headers[key] = value
+	}
 	if a.serverAuthToken != "" {
 		headers["Authorization"] = a.serverAuthToken
 	}
@@ -61,12 +109,20 @@ func (a *ToolAdapter) Connect(ctx context.Context) error {
 	httpTransport, err := transport.NewStreamableHTTP(
 		a.serverURL,
 		transport.WithHTTPHeaders(headers),
+		transport.WithHTTPBasicClient(newToolListValidationClient(model.GeneratorConfig{URL: a.serverURL, TLSConfig: a.tlsConfig})),
 	)
 	if err != nil {
 		return utils.WrapIfNotNil(err)
 	}
 
 	c := client.NewClient(httpTransport)
+	c.OnNotification(a.handleNotification)
+	// Start wires the notification handlers into the transport; it opens no
+	// connection of its own for streamable HTTP.
+	if err := c.Start(ctx); err != nil {
+		_ = c.Close()
+		return utils.WrapIfNotNil(err)
+	}
 	tools, initErr := initializeAndListTools(ctx, c)
 	if initErr != nil {
 		_ = c.Close()
@@ -85,6 +141,9 @@ func (a *ToolAdapter) Connect(ctx context.Context) error {
 	return nil
 }
 
+// RefreshTools lists the server's tools again on the current connection. A
+// server that validates listings with ETag or Last-Modified can answer 304
+// Not Modified, and the cached list is reused.
 func (a *ToolAdapter) RefreshTools(ctx context.Context) error {
 	a.mu.RLock()
 	c := a.client
@@ -110,6 +169,18 @@ func (a *ToolAdapter) RefreshTools(ctx context.Context) error {
 	return nil
 }
 
+// Ping checks that the server still answers on the current connection.
+func (a *ToolAdapter) Ping(ctx context.Context) error {
+	a.mu.RLock()
+	c := a.client
+	a.mu.RUnlock()
+
+	if c == nil {
+		return utils.WrapIfNotNil(errors.New("mcp client is not connected"))
+	}
+	return utils.WrapIfNotNil(c.Ping(ctx))
+}
+
 func (a *ToolAdapter) Disconnect() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -178,11 +249,15 @@ func (a *ToolAdapter) ExecuteTool(ctx context.Context, toolName string, rawArgs
 		}
 	}
 
+	progressMeta, stopProgress := a.watchProgress(ctx, toolName)
+	defer stopProgress()
+
 	request := mcp.CallToolRequest{
 		Header: http.Header{},
 		Params: mcp.CallToolParams{
 			Name:      toolName,
 			Arguments: args,
+			Meta:      progressMeta,
 		},
 	}
 	if authToken != "" {
@@ -18,9 +18,19 @@ type fakeToolClient struct {
 	listToolsErr     error
 	callToolResult   *mcp.CallToolResult
 	callToolErr      error
+	callToolHook     func(request mcp.CallToolRequest)
 	closeErr         error
 
-	lastCallRequest *mcp.CallToolRequest
+	listResourcesResult *mcp.ListResourcesResult
+	readResourceResults map[string]*mcp.ReadResourceResult
+	listPromptsResult   *mcp.ListPromptsResult
+	getPromptResult     *mcp.GetPromptResult
+	getPromptErr        error
+	pingErr             error
+	closed              bool
+
+	lastCallRequest      *mcp.CallToolRequest
+	lastGetPromptRequest *mcp.GetPromptRequest
 }
 
 func (f *fakeToolClient) Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error) {
@@ -34,10 +44,40 @@ func (f *fakeToolClient) ListTools(ctx context.Context, request mcp.ListToolsReq
 func (f *fakeToolClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	reqCopy := request
 	f.lastCallRequest = &reqCopy
+	if f.callToolHook != nil {
+		f.callToolHook(request)
+	}
 	return f.callToolResult, f.callToolErr
 }
 
+func (f *fakeToolClient) ListResources(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	return f.listResourcesResult, nil
+}
+
+func (f *fakeToolClient) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	result, ok := f.readResourceResults[request.Params.URI]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	return result, nil
+}
+
+func (f *fakeToolClient) ListPrompts(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error) {
+	return f.listPromptsResult, nil
+}
+
+func (f *fakeToolClient) GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	reqCopy := request
+	f.lastGetPromptRequest = &reqCopy
+	return f.getPromptResult, f.getPromptErr
+}
+
+func (f *fakeToolClient) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
 func (f *fakeToolClient) Close() error {
+	f.closed = true
 	return f.closeErr
 }
 
@@ -0,0 +1,264 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// DefaultHealthCheckInterval is how long a pooled connection is trusted
+// before Acquire pings the server again.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// ErrPoolClosed is returned by Acquire after Close.
+var ErrPoolClosed = errors.New("mcp adapter pool is closed")
+
+// PoolOption configures an AdapterPool.
+type PoolOption func(*AdapterPool)
+
+// WithHealthCheckInterval sets how long a connection is used without a ping
+// (default DefaultHealthCheckInterval). Values below zero disable health
+// checks; zero pings on every Acquire.
+func WithHealthCheckInterval(interval time.Duration) PoolOption {
+	return func(p *AdapterPool) {
+		p.healthCheckInterval = interval
+	}
+}
+
+// AdapterPool shares connected ToolAdapters across generations, one per
+// server URL, auth token and tool allow-list. Connections are opened on first
+// use, pinged when they have been idle longer than the health check interval
+// and reopened when the ping fails. It implements model.MCPAdapterPool (see
+// model.WithMCPAdapterPool) and is safe for concurrent use.
+type AdapterPool struct {
+	healthCheckInterval time.Duration
+	now                 func() time.Time
+	dial                func(ctx context.Context, serverURL string, authToken string, allowedTools []string, opts ...ToolAdapterOption) (*ToolAdapter, error)
+
+	mu      sync.Mutex
+	closed  bool
+	entries map[string]*pooledAdapter
+}
+
+type pooledAdapter struct {
+	mu        sync.Mutex
+	adapter   *ToolAdapter
+	checkedAt time.Time
+}
+
+// NewAdapterPool creates an empty pool.
+func NewAdapterPool(opts ...PoolOption) *AdapterPool {
+	p := &AdapterPool{
+		healthCheckInterval: DefaultHealthCheckInterval,
+		now:                 time.Now,
+		dial:                NewToolAdapter,
+		entries:             map[string]*pooledAdapter{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+	return p
+}
+
+// Acquire returns the pooled adapter for the server, connecting it when
+// needed. The adapter stays owned by the pool: do not disconnect it.
+func (p *AdapterPool) Acquire(ctx context.Context, serverURL string, authToken string, allowedTools []string) (*ToolAdapter, error) {
+	return p.acquire(ctx, poolKey(serverURL, authToken, allowedTools, nil), serverURL, authToken, allowedTools)
+}
+
+func (p *AdapterPool) acquire(ctx context.Context, key string, serverURL string, authToken string, allowedTools []string, opts ...ToolAdapterOption) (*ToolAdapter, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, utils.WrapIfNotNil(ErrPoolClosed)
+	}
+	entry, ok := p.entries[key]
+	if !ok {
+		entry = &pooledAdapter{}
+		p.entries[key] = entry
+	}
+	p.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.adapter != nil && p.healthCheckInterval >= 0 && p.now().Sub(entry.checkedAt) >= p.healthCheckInterval {
+		if err := entry.adapter.Ping(ctx); err != nil {
+			logging.NewLogger(ctx).Warnf("mcp server %q failed health check; reconnecting: %v", serverURL, err)
+			_ = entry.adapter.Disconnect()
+			entry.adapter = nil
+		} else {
+			entry.checkedAt = p.now()
+		}
+	}
+	if entry.adapter == nil {
+		adapter, err := p.dial(ctx, serverURL, authToken, allowedTools, opts...)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			// Close ran while connecting; do not leak the connection.
+			_ = adapter.Disconnect()
+			return nil, utils.WrapIfNotNil(ErrPoolClosed)
+		}
+		entry.adapter, entry.checkedAt = adapter, p.now()
+	}
+	return entry.adapter, nil
+}
+
+// MCPServerTools returns the tools of server from its pooled adapter,
+// authorizing with the server's Authorization header, or its AuthToken as a
+// bearer token, sending its other headers and using its TLSConfig. Servers
+// with different headers or TLS configs get their own connection.
+func (p *AdapterPool) MCPServerTools(ctx context.Context, server model.MCPTool) ([]model.Tool, error) {
+	authToken := serverAuthorization(server)
+	headers := nonAuthorizationHeaders(server.HTTPHeaders)
+	key := poolKey(server.URL, authToken, server.AllowedTools, headers)
+	if server.TLSConfig != nil {
+		key += fmt.Sprintf("\x00%p", server.TLSConfig)
+	}
+	adapter, err := p.acquire(ctx, key, server.URL, authToken, server.AllowedTools, WithAdapterHeaders(headers), WithAdapterTLSConfig(server.TLSConfig))
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	tools, err := adapter.AsModelTools()
+	return tools, utils.WrapIfNotNil(err)
+}
+
+// Close disconnects every pooled adapter. Later Acquire calls fail with
+// ErrPoolClosed; generations still using an adapter see its calls fail.
+func (p *AdapterPool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	entries := p.entries
+	p.entries = map[string]*pooledAdapter{}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, entry := range entries {
+		entry.mu.Lock()
+		if entry.adapter != nil {
+			if err := entry.adapter.Disconnect(); err != nil {
+				errs = append(errs, err)
+			}
+			entry.adapter = nil
+		}
+		entry.mu.Unlock()
+	}
+	return utils.WrapIfNotNil(errors.Join(errs...))
+}
+
+// ModelTools returns the tools of every server as model.Tools. With a pool
+// the connections are taken from it and the returned cleanup does nothing;
+// without one each server is connected now and cleanup disconnects it. Each
+// server is authorized with its Authorization header, or its AuthToken as a
+// bearer token, its other headers are sent as they are and its TLSConfig
+// secures the connection.
+func ModelTools(ctx context.Context, servers []model.MCPTool, pool model.MCPAdapterPool) ([]model.Tool, func(), error) {
+	var tools []model.Tool
+	if pool != nil {
+		for _, server := range servers {
+			serverTools, err := pool.MCPServerTools(ctx, server)
+			if err != nil {
+				return nil, func() {}, utils.WrapIfNotNil(err)
+			}
+			tools = append(tools, serverTools...)
+		}
+		return tools, func() {}, nil
+	}
+
+	adapters := make([]*ToolAdapter, 0, len(servers))
+	cleanup := func() {
+		log := logging.NewLogger(ctx)
+		for _, adapter := range adapters {
+			if err := adapter.Disconnect(); err != nil {
+				log.Warnf("mcp adapter disconnect failed: %v", err)
+			}
+		}
+	}
+	for _, server := range servers {
+		adapter, err := NewToolAdapter(
+			ctx,
+			server.URL,
+			serverAuthorization(server),
+			server.AllowedTools,
+			WithAdapterHeaders(server.HTTPHeaders),
+			WithAdapterTLSConfig(server.TLSConfig),
+		)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, utils.WrapIfNotNil(err)
+		}
+		adapters = append(adapters, adapter)
+
+		serverTools, err := adapter.AsModelTools()
+		if err != nil {
+			cleanup()
+			return nil, func() {}, utils.WrapIfNotNil(err)
+		}
+		tools = append(tools, serverTools...)
+	}
+	return tools, cleanup, nil
+}
+
+func poolKey(serverURL string, authToken string, allowedTools []string, headers map[string]string) string {
+	allowed := make([]string, 0, len(allowedTools))
+	for name := range normalizeAllowedTools(allowedTools) {
+		allowed = append(allowed, name)
+	}
+	slices.Sort(allowed)
+	headerPairs := make([]string, 0, len(headers))
+	for key, value := range headers {
+		headerPairs = append(headerPairs, strings.ToLower(key)+":"+value)
+	}
+	slices.Sort(headerPairs)
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s", strings.TrimSpace(serverURL), authToken, strings.Join(allowed, ","), strings.Join(headerPairs, "\x00"))
+}
+
+func authorizationHeader(headers map[string]string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, "Authorization") {
+			return value
+		}
+	}
+	return ""
+}
+
+// serverAuthorization returns the Authorization header of server, or its
+// AuthToken as a bearer token.
+func serverAuthorization(server model.MCPTool) string {
+	if authorization := authorizationHeader(server.HTTPHeaders); authorization != "" {
+		return authorization
+	}
+	if token := strings.TrimSpace(server.AuthToken); token != "" {
+		return "Bearer " + token
+	}
+	return ""
+}
+
+func nonAuthorizationHeaders(headers map[string]string) map[string]string {
+	var out map[string]string
+	for key, value := range headers {
+		if strings.EqualFold(key, "Authorization") {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(headers))
+		}
+		out[key] = value
+	}
+	return out
+}
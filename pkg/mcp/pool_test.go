@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDialer struct {
+	clients []*fakeToolClient
+	dials   []string
+	err     error
+}
+
+func (d *fakeDialer) dial(ctx context.Context, serverURL string, authToken string, allowedTools []string, opts ...ToolAdapterOption) (*ToolAdapter, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	client := &fakeToolClient{}
+	d.clients = append(d.clients, client)
+	d.dials = append(d.dials, serverURL+" "+authToken)
+	adapter := &ToolAdapter{
+		serverURL:       serverURL,
+		serverAuthToken: authToken,
+		client:          client,
+		tools:           []mcp.Tool{{Name: "lookup", RawInputSchema: []byte(`{"type":"object"}`)}},
+	}
+	for _, opt := range opts {
+		opt(adapter)
+	}
+	return adapter, nil
+}
+
+func newTestPool(dialer *fakeDialer, now *time.Time, opts ...PoolOption) *AdapterPool {
+	pool := NewAdapterPool(opts...)
+	pool.dial = dialer.dial
+	pool.now = func() time.Time { return *now }
+	return pool
+}
+
+func TestAdapterPoolReusesConnections(t *testing.T) {
+	dialer := &fakeDialer{}
+	now := time.Unix(0, 0)
+	pool := newTestPool(dialer, &now)
+
+	first, err := pool.Acquire(context.Background(), "https://mcp.example.com", "Bearer a", []string{"b", "a"})
+	require.NoError(t, err)
+	second, err := pool.Acquire(context.Background(), " https://mcp.example.com ", "Bearer a", []string{"a", "b", " "})
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	other, err := pool.Acquire(context.Background(), "https://mcp.example.com", "Bearer b", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.NotSame(t, first, other, "different credentials get their own connection")
+	assert.Len(t, dialer.dials, 2)
+
+	tools, err := pool.MCPServerTools(context.Background(), model.MCPTool{
+		URL:          "https://mcp.example.com",
+		HTTPHeaders:  map[string]string{"authorization": "Bearer a"},
+		AllowedTools: []string{"a", "b"},
+	})
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "lookup", tools[0].Name)
+	assert.Len(t, dialer.dials, 2)
+
+	require.NoError(t, pool.Close())
+	assert.True(t, dialer.clients[0].closed)
+	assert.True(t, dialer.clients[1].closed)
+	_, err = pool.Acquire(context.Background(), "https://mcp.example.com", "Bearer a", nil)
+	assert.ErrorIs(t, err, ErrPoolClosed)
+}
+
+func TestAdapterPoolHealthCheckReconnects(t *testing.T) {
+	dialer := &fakeDialer{}
+	now := time.Unix(0, 0)
+	pool := newTestPool(dialer, &now, WithHealthCheckInterval(time.Minute))
+
+	first, err := pool.Acquire(context.Background(), "https://mcp.example.com", "", nil)
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	dialer.clients[0].pingErr = errors.New("connection reset")
+	again, err := pool.Acquire(context.Background(), "https://mcp.example.com", "", nil)
+	require.NoError(t, err)
+	assert.Same(t, first, again, "connections are not pinged within the interval")
+
+	now = now.Add(time.Minute)
+	reconnected, err := pool.Acquire(context.Background(), "https://mcp.example.com", "", nil)
+	require.NoError(t, err)
+	assert.NotSame(t, first, reconnected)
+	assert.True(t, dialer.clients[0].closed)
+	assert.Len(t, dialer.dials, 2)
+
+	dialer.err = errors.New("refused")
+	dialer.clients[1].pingErr = errors.New("gone")
+	now = now.Add(time.Minute)
+	_, err = pool.Acquire(context.Background(), "https://mcp.example.com", "", nil)
+	require.Error(t, err)
+}
+
+type fakePool struct {
+	servers []string
+}
+
+func (p *fakePool) MCPServerTools(ctx context.Context, server model.MCPTool) ([]model.Tool, error) {
+	p.servers = append(p.servers, server.URL)
+	return []model.Tool{{Name: server.Name}}, nil
+}
+
+func TestModelToolsUsesPool(t *testing.T) {
+	pool := &fakePool{}
+	tools, cleanup, err := ModelTools(context.Background(), []model.MCPTool{
+		{Name: "labs", URL: "https://labs.example.com"},
+		{Name: "notes", URL: "https://notes.example.com"},
+	}, pool)
+	require.NoError(t, err)
+	cleanup()
+	assert.Equal(t, []string{"https://labs.example.com", "https://notes.example.com"}, pool.servers)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "notes", tools[1].Name)
+
+	tools, cleanup, err = ModelTools(context.Background(), nil, nil)
+	require.NoError(t, err)
+	cleanup()
+	assert.Empty(t, tools)
+}
+
+func TestAuthorizationHeader(t *testing.T) {
+	assert.Equal(t, "Bearer tok", authorizationHeader(map[string]string{"authorization": "Bearer tok"}))
+	assert.Equal(t, "", authorizationHeader(map[string]string{"X-Custom": "val"}))
+	assert.Equal(t, "", authorizationHeader(nil))
+}
+
+func TestAdapterPoolKeysServersByHeaders(t *testing.T) {
+	dialer := &fakeDialer{}
+	now := time.Unix(0, 0)
+	pool := newTestPool(dialer, &now)
+
+	server := model.MCPTool{URL: "https://mcp.example.com", AuthToken: "tok", HTTPHeaders: map[string]string{"X-Tenant": "a"}}
+	_, err := pool.MCPServerTools(context.Background(), server)
+	require.NoError(t, err)
+	_, err = pool.MCPServerTools(context.Background(), server)
+	require.NoError(t, err)
+	server.HTTPHeaders = map[string]string{"X-Tenant": "b"}
+	_, err = pool.MCPServerTools(context.Background(), server)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"https://mcp.example.com Bearer tok", "https://mcp.example.com Bearer tok"}, dialer.dials)
+	assert.Equal(t, map[string]string{"X-Tenant": "b"}, pool.entries[poolKey(server.URL, "Bearer tok", nil, server.HTTPHeaders)].adapter.httpHeaders)
+}
+
+func TestAdapterPoolKeysServersByTLSConfig(t *testing.T) {
+	dialer := &fakeDialer{}
+	now := time.Unix(0, 0)
+	pool := newTestPool(dialer, &now)
+
+	tlsConfig := &tls.Config{ServerName: "mcp.internal"}
+	server := model.MCPTool{URL: "https://mcp.example.com", TLSConfig: tlsConfig}
+	_, err := pool.MCPServerTools(context.Background(), server)
+	require.NoError(t, err)
+	_, err = pool.MCPServerTools(context.Background(), server)
+	require.NoError(t, err)
+	server.TLSConfig = nil
+	_, err = pool.MCPServerTools(context.Background(), server)
+	require.NoError(t, err)
+
+	assert.Len(t, dialer.dials, 2, "servers with a TLS config get their own connection")
+	entry := pool.entries[poolKey(server.URL, "", nil, nil)+fmt.Sprintf("\x00%p", tlsConfig)]
+	require.NotNil(t, entry)
+	require.NotNil(t, entry.adapter.tlsConfig)
+	assert.Equal(t, "mcp.internal", entry.adapter.tlsConfig.ServerName)
+	assert.NotSame(t, tlsConfig, entry.adapter.tlsConfig, "the adapter keeps a clone")
+	assert.Nil(t, pool.entries[poolKey(server.URL, "", nil, nil)].adapter.tlsConfig)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	assert.Equal(t, "Bearer header", serverAuthorization(model.MCPTool{AuthToken: "tok", HTTPHeaders: map[string]string{"Authorization": "Bearer header"}}))
+	assert.Equal(t, "Bearer tok", serverAuthorization(model.MCPTool{AuthToken: " tok "}))
+	assert.Equal(t, "", serverAuthorization(model.MCPTool{}))
+	assert.Equal(t, map[string]string{"X-Tenant": "a"}, nonAuthorizationHeaders(map[string]string{"authorization": "x", "X-Tenant": "a"}))
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ServerProbe describes an MCP server as reported by ProbeServer.
+type ServerProbe struct {
+	ServerName      string
+	ServerVersion   string
+	ProtocolVersion string
+	Instructions    string
+	Capabilities    mcp.ServerCapabilities
+	// ToolCount is the number of tools the server lists, and ToolNames their
+	// names. Both are empty when the server has no tools capability.
+	ToolCount int
+	ToolNames []string
+	// Latency is how long the initialize handshake and tool listing took.
+	Latency time.Duration
+}
+
+// SupportsTools, SupportsResources and SupportsPrompts report whether the
+// server advertised the capability.
+func (p ServerProbe) SupportsTools() bool     { return p.Capabilities.Tools != nil }
+func (p ServerProbe) SupportsResources() bool { return p.Capabilities.Resources != nil }
+func (p ServerProbe) SupportsPrompts() bool   { return p.Capabilities.Prompts != nil }
+
+// ProbeServer connects to the MCP server at serverURL, runs the initialize
+// handshake, lists its tools and disconnects. Use it at startup to validate
+// MCP configuration (URL, auth token, expected tools) instead of failing in
+// the middle of a generation. The tool list is not cached.
+func ProbeServer(ctx context.Context, serverURL string, authToken string) (*ServerProbe, error) {
+	if strings.TrimSpace(serverURL) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("serverURL is required"))
+	}
+
+	headers := map[string]string{}
+	if authToken != "" {
+		headers["Authorization"] = authToken
+	}
+	httpTransport, err := transport.NewStreamableHTTP(serverURL, transport.WithHTTPHeaders(headers))
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	c := client.NewClient(httpTransport)
+	defer c.Close()
+
+	probe, err := probeClient(ctx, c)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err, "probing mcp server "+serverURL)
+	}
+	return probe, nil
+}
+
+func probeClient(ctx context.Context, c toolClient) (*ServerProbe, error) {
+	start := time.Now()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "Polyglot LLM MCP Probe",
+		Version: "1.0.0",
+	}
+	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+
+	serverInfo, err := c.Initialize(ctx, initRequest)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if serverInfo == nil {
+		return nil, utils.WrapIfNotNil(errors.New("mcp server returned no initialize result"))
+	}
+
+	probe := &ServerProbe{
+		ServerName:      serverInfo.ServerInfo.Name,
+		ServerVersion:   serverInfo.ServerInfo.Version,
+		ProtocolVersion: serverInfo.ProtocolVersion,
+		Instructions:    serverInfo.Instructions,
+		Capabilities:    serverInfo.Capabilities,
+	}
+	if probe.SupportsTools() {
+		toolsResult, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		if toolsResult != nil {
+			for _, tool := range toolsResult.Tools {
+				probe.ToolNames = append(probe.ToolNames, tool.Name)
+			}
+		}
+		probe.ToolCount = len(probe.ToolNames)
+	}
+	probe.Latency = time.Since(start)
+	return probe, nil
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeClientReportsServerInfoAndTools(t *testing.T) {
+	c := &fakeToolClient{
+		initializeResult: &mcp.InitializeResult{
+			ProtocolVersion: "2025-06-18",
+			ServerInfo:      mcp.Implementation{Name: "labs", Version: "2.1.0"},
+			Instructions:    "Use for lab lookups.",
+			Capabilities: mcp.ServerCapabilities{
+				Tools: &struct {
+					ListChanged bool `json:"listChanged,omitempty"`
+				}{},
+				Resources: &struct {
+					Subscribe   bool `json:"subscribe,omitempty"`
+					ListChanged bool `json:"listChanged,omitempty"`
+				}{},
+			},
+		},
+		listToolsResult: &mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "get_egfr"}, {Name: "get_potassium"}}},
+	}
+
+	probe, err := probeClient(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, "labs", probe.ServerName)
+	assert.Equal(t, "2.1.0", probe.ServerVersion)
+	assert.Equal(t, "2025-06-18", probe.ProtocolVersion)
+	assert.Equal(t, "Use for lab lookups.", probe.Instructions)
+	assert.Equal(t, 2, probe.ToolCount)
+	assert.Equal(t, []string{"get_egfr", "get_potassium"}, probe.ToolNames)
+	assert.True(t, probe.SupportsTools())
+	assert.True(t, probe.SupportsResources())
+	assert.False(t, probe.SupportsPrompts())
+}
+
+func TestProbeClientWithoutToolsCapabilitySkipsListing(t *testing.T) {
+	c := &fakeToolClient{
+		initializeResult: &mcp.InitializeResult{ServerInfo: mcp.Implementation{Name: "prompts-only"}},
+		listToolsErr:     errors.New("must not be called"),
+	}
+
+	probe, err := probeClient(context.Background(), c)
+	require.NoError(t, err)
+	assert.Zero(t, probe.ToolCount)
+	assert.False(t, probe.SupportsTools())
+}
+
+func TestProbeClientErrors(t *testing.T) {
+	_, err := probeClient(context.Background(), &fakeToolClient{initializeErr: errors.New("unauthorized")})
+	require.ErrorContains(t, err, "unauthorized")
+
+	_, err = probeClient(context.Background(), &fakeToolClient{})
+	require.ErrorContains(t, err, "no initialize result")
+
+	_, err = ProbeServer(context.Background(), " ", "")
+	require.ErrorContains(t, err, "serverURL is required")
+}
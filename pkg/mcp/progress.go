@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const progressNotificationMethod = "notifications/progress"
+
+type progressListener struct {
+	ctx  context.Context
+	tool string
+	fn   model.ToolProgressFunc
+}
+
+// watchProgress registers the context's progress function for one call of
+// tool and returns the request meta that asks the server for progress, plus
+// a func that unregisters it. Without a progress function it returns nil
+// meta and a no-op.
+func (a *ToolAdapter) watchProgress(ctx context.Context, tool string) (*mcp.Meta, func()) {
+	fn := model.ToolProgressFromContext(ctx)
+	if fn == nil {
+		return nil, func() {}
+	}
+
+	a.progressMu.Lock()
+	a.progressSeq++
+	token := fmt.Sprintf("polyglot-%d", a.progressSeq)
+	if a.progressListeners == nil {
+		a.progressListeners = map[string]progressListener{}
+	}
+	a.progressListeners[token] = progressListener{ctx: ctx, tool: tool, fn: fn}
+	a.progressMu.Unlock()
+
+	return &mcp.Meta{ProgressToken: token}, func() {
+		a.progressMu.Lock()
+		delete(a.progressListeners, token)
+		a.progressMu.Unlock()
+	}
+}
+
+// handleNotification forwards progress notifications to the listener of the
+// call they belong to. Other notifications and unknown tokens are ignored.
+func (a *ToolAdapter) handleNotification(notification mcp.JSONRPCNotification) {
+	if notification.Method != progressNotificationMethod {
+		return
+	}
+	fields := notification.Params.AdditionalFields
+	token, ok := fields["progressToken"]
+	if !ok || token == nil {
+		return
+	}
+
+	a.progressMu.Lock()
+	listener, ok := a.progressListeners[fmt.Sprint(token)]
+	a.progressMu.Unlock()
+	if !ok {
+		return
+	}
+
+	message, _ := fields["message"].(string)
+	listener.fn(listener.ctx, model.ToolProgress{
+		Tool:     listener.tool,
+		Progress: notificationNumber(fields["progress"]),
+		Total:    notificationNumber(fields["total"]),
+		Message:  message,
+	})
+}
+
+func notificationNumber(value any) float64 {
+	switch typed := value.(type) {
+	case float64:
+		return typed
+	case int:
+		return float64(typed)
+	case int64:
+		return float64(typed)
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func progressNotification(token any, progress float64, total float64, message string) mcp.JSONRPCNotification {
+	return mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: progressNotificationMethod,
+			Params: mcp.NotificationParams{AdditionalFields: map[string]any{
+				"progressToken": token,
+				"progress":      progress,
+				"total":         total,
+				"message":       message,
+			}},
+		},
+	}
+}
+
+func TestExecuteToolForwardsProgress(t *testing.T) {
+	fake := &fakeToolClient{callToolResult: &mcp.CallToolResult{}}
+	adapter := &ToolAdapter{client: fake}
+	fake.callToolHook = func(request mcp.CallToolRequest) {
+		require.NotNil(t, request.Params.Meta)
+		token := request.Params.Meta.ProgressToken
+		adapter.handleNotification(progressNotification(token, 1, 4, "querying"))
+		adapter.handleNotification(progressNotification("someone-else", 2, 4, "ignored"))
+		adapter.handleNotification(progressNotification(token, 4, 4, "done"))
+	}
+
+	var updates []model.ToolProgress
+	ctx := model.ContextWithToolProgress(context.Background(), func(ctx context.Context, progress model.ToolProgress) {
+		updates = append(updates, progress)
+	})
+	_, err := adapter.ExecuteTool(ctx, "build_report", json.RawMessage(`{}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.ToolProgress{
+		{Tool: "build_report", Progress: 1, Total: 4, Message: "querying"},
+		{Tool: "build_report", Progress: 4, Total: 4, Message: "done"},
+	}, updates)
+	assert.Empty(t, adapter.progressListeners)
+}
+
+func TestExecuteToolWithoutProgressFuncRequestsNoProgress(t *testing.T) {
+	fake := &fakeToolClient{callToolResult: &mcp.CallToolResult{}}
+	adapter := &ToolAdapter{client: fake}
+
+	_, err := adapter.ExecuteTool(context.Background(), "build_report", nil)
+	require.NoError(t, err)
+	require.NotNil(t, fake.lastCallRequest)
+	assert.Nil(t, fake.lastCallRequest.Params.Meta)
+
+	// Late notifications for finished calls are dropped.
+	adapter.handleNotification(progressNotification("polyglot-1", 1, 0, ""))
+}
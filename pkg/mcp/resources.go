@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ListResources returns the resources the server offers, following
+// pagination.
+func (a *ToolAdapter) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	c, authToken, err := a.connectedClient()
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	request := mcp.ListResourcesRequest{Header: authHeader(authToken)}
+	result, err := c.ListResources(ctx, request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result.Resources, nil
+}
+
+// ReadResource returns the contents of the resource at uri.
+func (a *ToolAdapter) ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	c, authToken, err := a.connectedClient()
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if strings.TrimSpace(uri) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("uri is required"))
+	}
+
+	request := mcp.ReadResourceRequest{Header: authHeader(authToken)}
+	request.Params.URI = uri
+	result, err := c.ReadResource(ctx, request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result.Contents, nil
+}
+
+// ListPrompts returns the prompts and prompt templates the server offers,
+// following pagination.
+func (a *ToolAdapter) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	c, authToken, err := a.connectedClient()
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	request := mcp.ListPromptsRequest{Header: authHeader(authToken)}
+	result, err := c.ListPrompts(ctx, request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result.Prompts, nil
+}
+
+// GetPrompt renders the server prompt name with args.
+func (a *ToolAdapter) GetPrompt(ctx context.Context, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	c, authToken, err := a.connectedClient()
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("prompt name is required"))
+	}
+
+	request := mcp.GetPromptRequest{Header: authHeader(authToken)}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := c.GetPrompt(ctx, request)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if result == nil {
+		return nil, utils.WrapIfNotNil(fmt.Errorf("prompt %q returned no result", name))
+	}
+	return result, nil
+}
+
+// ResourceContextProvider returns a PromptContextProvider that reads the
+// resources at uris on every generation and adds each as a human context,
+// for example RAG content served by the MCP server. Text contents become the
+// context text, prefixed with their URI; image blobs are attached as images
+// and other binary contents are skipped.
+func (a *ToolAdapter) ResourceContextProvider(uris ...string) model.PromptContextProvider {
+	return resourceContextProvider{adapter: a, uris: append([]string(nil), uris...)}
+}
+
+// PromptContextProvider returns a PromptContextProvider that renders the
+// server prompt name with args on every generation and adds its messages as
+// contexts: user messages as human contexts and assistant messages as
+// assistant contexts. Text, images and embedded text resources are kept;
+// audio and resource links are skipped.
+func (a *ToolAdapter) PromptContextProvider(name string, args map[string]string) model.PromptContextProvider {
+	copied := make(map[string]string, len(args))
+	for key, value := range args {
+		copied[key] = value
+	}
+	return promptContextProvider{adapter: a, name: name, args: copied}
+}
+
+type resourceContextProvider struct {
+	adapter *ToolAdapter
+	uris    []string
+}
+
+func (p resourceContextProvider) GenerateContext(ctx context.Context) ([]*model.PromptContext, error) {
+	out := make([]*model.PromptContext, 0, len(p.uris))
+	for _, uri := range p.uris {
+		contents, err := p.adapter.ReadResource(ctx, uri)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("read resource %q: %w", uri, err))
+		}
+		promptContext := &model.PromptContext{MessageType: model.ContextMessageTypeHuman}
+		var texts []string
+		for _, content := range contents {
+			text, image := resourceContentParts(content)
+			if text != "" {
+				texts = append(texts, text)
+			}
+			if image != nil {
+				promptContext.Images = append(promptContext.Images, *image)
+			}
+		}
+		if len(texts) > 0 {
+			promptContext.Content = fmt.Sprintf("Resource %s:\n%s", uri, strings.Join(texts, "\n\n"))
+		}
+		if promptContext.HasContent() {
+			out = append(out, promptContext)
+		}
+	}
+	return out, nil
+}
+
+type promptContextProvider struct {
+	adapter *ToolAdapter
+	name    string
+	args    map[string]string
+}
+
+func (p promptContextProvider) GenerateContext(ctx context.Context) ([]*model.PromptContext, error) {
+	result, err := p.adapter.GetPrompt(ctx, p.name, p.args)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	out := make([]*model.PromptContext, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		promptContext := &model.PromptContext{MessageType: model.ContextMessageTypeHuman}
+		if message.Role == mcp.RoleAssistant {
+			promptContext.MessageType = model.ContextMessageTypeAssistant
+		}
+		switch content := message.Content.(type) {
+		case mcp.TextContent:
+			promptContext.Content = content.Text
+		case mcp.ImageContent:
+			image, err := decodeImage(content.MIMEType, content.Data)
+			if err != nil {
+				return nil, utils.WrapIfNotNil(fmt.Errorf("prompt %q image: %w", p.name, err))
+			}
+			promptContext.Images = []model.ImagePart{image}
+		case mcp.EmbeddedResource:
+			text, image := resourceContentParts(content.Resource)
+			promptContext.Content = text
+			if image != nil {
+				promptContext.Images = []model.ImagePart{*image}
+			}
+		}
+		if promptContext.HasContent() {
+			out = append(out, promptContext)
+		}
+	}
+	return out, nil
+}
+
+// resourceContentParts returns the text or image of one resource content.
+// Non-image blobs and undecodable images yield neither.
+func resourceContentParts(content mcp.ResourceContents) (string, *model.ImagePart) {
+	switch typed := content.(type) {
+	case mcp.TextResourceContents:
+		return typed.Text, nil
+	case mcp.BlobResourceContents:
+		if !strings.HasPrefix(typed.MIMEType, "image/") {
+			return "", nil
+		}
+		image, err := decodeImage(typed.MIMEType, typed.Blob)
+		if err != nil {
+			return "", nil
+		}
+		return "", &image
+	}
+	return "", nil
+}
+
+func decodeImage(mimeType string, data string) (model.ImagePart, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return model.ImagePart{}, utils.WrapIfNotNil(err)
+	}
+	return model.ImagePart{MIMEType: mimeType, Data: decoded}, nil
+}
+
+// connectedClient returns the current client and auth token, or an error
+// when the adapter is not connected.
+func (a *ToolAdapter) connectedClient() (toolClient, string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.client == nil {
+		return nil, "", errors.New("mcp client is not connected")
+	}
+	return a.client, a.serverAuthToken, nil
+}
+
+func authHeader(authToken string) http.Header {
+	header := http.Header{}
+	if authToken != "" {
+		header.Set("Authorization", authToken)
+	}
+	return header
+}
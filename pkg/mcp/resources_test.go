@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListResourcesAndPrompts(t *testing.T) {
+	adapter := &ToolAdapter{
+		client: &fakeToolClient{
+			listResourcesResult: &mcp.ListResourcesResult{Resources: []mcp.Resource{{URI: "kb://guidelines", Name: "guidelines"}}},
+			listPromptsResult:   &mcp.ListPromptsResult{Prompts: []mcp.Prompt{{Name: "summarize_labs"}}},
+		},
+	}
+
+	resources, err := adapter.ListResources(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "kb://guidelines", resources[0].URI)
+
+	prompts, err := adapter.ListPrompts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "summarize_labs", prompts[0].Name)
+
+	_, err = (&ToolAdapter{}).ListResources(context.Background())
+	require.Error(t, err)
+}
+
+func TestResourceContextProvider(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	adapter := &ToolAdapter{
+		client: &fakeToolClient{
+			readResourceResults: map[string]*mcp.ReadResourceResult{
+				"kb://guidelines": {Contents: []mcp.ResourceContents{
+					mcp.TextResourceContents{URI: "kb://guidelines", Text: "eGFR below 60 is CKD stage 3."},
+					mcp.BlobResourceContents{URI: "kb://guidelines", MIMEType: "image/png", Blob: base64.StdEncoding.EncodeToString(png)},
+					mcp.BlobResourceContents{URI: "kb://guidelines", MIMEType: "application/pdf", Blob: "JVBERi0="},
+				}},
+				"kb://empty": {},
+			},
+		},
+	}
+
+	contexts, err := adapter.ResourceContextProvider("kb://guidelines", "kb://empty").GenerateContext(context.Background())
+	require.NoError(t, err)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, model.ContextMessageTypeHuman, contexts[0].MessageType)
+	assert.Equal(t, "Resource kb://guidelines:\neGFR below 60 is CKD stage 3.", contexts[0].Content)
+	assert.Equal(t, []model.ImagePart{{MIMEType: "image/png", Data: png}}, contexts[0].Images)
+
+	_, err = adapter.ResourceContextProvider("kb://missing").GenerateContext(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `read resource "kb://missing"`)
+}
+
+func TestPromptContextProvider(t *testing.T) {
+	fake := &fakeToolClient{
+		getPromptResult: &mcp.GetPromptResult{Messages: []mcp.PromptMessage{
+			{Role: mcp.RoleUser, Content: mcp.NewTextContent("Summarize the labs for patient 42.")},
+			{Role: mcp.RoleAssistant, Content: mcp.NewTextContent("Which panel?")},
+			{Role: mcp.RoleUser, Content: mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "labs://42", Text: "Creatinine 1.9"})},
+			{Role: mcp.RoleUser, Content: mcp.NewAudioContent("AAAA", "audio/wav")},
+		}},
+	}
+	adapter := &ToolAdapter{serverAuthToken: "Bearer token123", client: fake}
+
+	args := map[string]string{"patient_id": "42"}
+	provider := adapter.PromptContextProvider("summarize_labs", args)
+	args["patient_id"] = "changed"
+
+	contexts, err := provider.GenerateContext(context.Background())
+	require.NoError(t, err)
+	require.Len(t, contexts, 3)
+	assert.Equal(t, model.ContextMessageTypeHuman, contexts[0].MessageType)
+	assert.Equal(t, "Summarize the labs for patient 42.", contexts[0].Content)
+	assert.Equal(t, model.ContextMessageTypeAssistant, contexts[1].MessageType)
+	assert.Equal(t, "Creatinine 1.9", contexts[2].Content)
+
+	require.NotNil(t, fake.lastGetPromptRequest)
+	assert.Equal(t, "summarize_labs", fake.lastGetPromptRequest.Params.Name)
+	assert.Equal(t, map[string]string{"patient_id": "42"}, fake.lastGetPromptRequest.Params.Arguments)
+	assert.Equal(t, "Bearer token123", fake.lastGetPromptRequest.Header.Get("Authorization"))
+}
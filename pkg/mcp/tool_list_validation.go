@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Servers that send an ETag or Last-Modified header with their tools/list
+// responses let clients skip the catalog on later listings: the request
+// carries If-None-Match / If-Modified-Since and a 304 Not Modified answer is
+// replaced with the cached result. Only JSON responses are cached; results
+// streamed as server-sent events always go to the server.
+
+// defaultHTTPTimeout bounds every request to an MCP server, tool calls
+// included, so a server that stops answering cannot hang a generation.
+const defaultHTTPTimeout = 90 * time.Second
+
+// unkeyedToolListHeaders change between connections or requests without
+// changing what a server lists, so they are left out of the cache key.
+var unkeyedToolListHeaders = map[string]bool{
+	"Content-Length":    true,
+	"If-Modified-Since": true,
+	"If-None-Match":     true,
+	"Last-Event-Id":     true,
+	"Mcp-Session-Id":    true,
+}
+
+type validatedToolList struct {
+	etag         string
+	lastModified string
+	result       json.RawMessage
+}
+
+var (
+	validatedToolLists      = map[string]validatedToolList{}
+	validatedToolListsMutex sync.Mutex
+)
+
+// toolListValidationTransport adds the validators of a cached tools/list
+// result to repeated listings and answers 304 Not Modified from the cache.
+type toolListValidationTransport struct {
+	base http.RoundTripper
+}
+
+// newToolListValidationClient returns the HTTP client for a server: the
+// transport of model.NewHTTPTransport for cfg.URL and cfg.TLSConfig, wrapped
+// in toolListValidationTransport, with defaultHTTPTimeout.
+func newToolListValidationClient(cfg model.GeneratorConfig) *http.Client {
+	client := model.NewHTTPClient(cfg, defaultHTTPTimeout)
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &toolListValidationTransport{base: base}
+	return client
+}
+
+func (t *toolListValidationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Body == nil || req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return t.base.RoundTrip(req)
+	}
+	raw, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return t.base.RoundTrip(req)
+	}
+	var call struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Cursor string `json:"cursor"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(raw, &call) != nil || call.Method != string(mcp.MethodToolsList) || len(call.ID) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	key := validatedToolListKey(req, call.Params.Cursor)
+	validatedToolListsMutex.Lock()
+	cached, found := validatedToolLists[key]
+	validatedToolListsMutex.Unlock()
+
+	if found {
+		req = req.Clone(req.Context())
+		req.Body, _ = req.GetBody()
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && found {
+		_ = resp.Body.Close()
+		return notModifiedToolListResponse(req, resp, call.ID, cached.result), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if (etag == "" && lastModified == "") || mediaType != "application/json" {
+		if found {
+			forgetValidatedToolList(key)
+		}
+		return resp, nil
+	}
+
+	payload, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(payload))
+
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if json.Unmarshal(payload, &reply) != nil || len(reply.Result) == 0 {
+		forgetValidatedToolList(key)
+		return resp, nil
+	}
+	validatedToolListsMutex.Lock()
+	validatedToolLists[key] = validatedToolList{etag: etag, lastModified: lastModified, result: reply.Result}
+	validatedToolListsMutex.Unlock()
+	return resp, nil
+}
+
+// notModifiedToolListResponse builds the 200 JSON-RPC response the transport
+// expects from the cached result, answering the request id.
+func notModifiedToolListResponse(req *http.Request, notModified *http.Response, id json.RawMessage, result json.RawMessage) *http.Response {
+	payload, _ := json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  json.RawMessage `json:"result"`
+	}{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Result: result})
+
+	header := notModified.Header.Clone()
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(payload)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+		Request:       req,
+	}
+}
+
+// validatedToolListKey keys cached results by normalized server URL, a hash
+// of the request headers (Authorization and tenant headers included) and page
+// cursor.
+func validatedToolListKey(req *http.Request, cursor string) string {
+	return normalizedServerURL(req.URL) + "\x00" + toolListHeadersHash(req.Header) + "\x00" + cursor
+}
+
+// normalizedServerURL writes u with a lower-case scheme and host, without a
+// default port, fragment or trailing slash and with sorted query parameters,
+// so every spelling of a server URL gives the same key.
+func normalizedServerURL(u *url.URL) string {
+	normalized := *u
+	normalized.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (normalized.Scheme == "http" && port == "80") || (normalized.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		normalized.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		normalized.Host = "[" + host + "]"
+	default:
+		normalized.Host = host
+	}
+	normalized.Path = strings.TrimRight(u.Path, "/")
+	normalized.RawPath = ""
+	normalized.RawQuery = u.Query().Encode()
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	return normalized.String()
+}
+
+// toolListHeadersHash hashes the request headers except
+// unkeyedToolListHeaders, in name order.
+func toolListHeadersHash(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if !unkeyedToolListHeaders[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return http.CanonicalHeaderKey(names[i]) < http.CanonicalHeaderKey(names[j])
+	})
+	hash := sha256.New()
+	for _, name := range names {
+		_, _ = io.WriteString(hash, http.CanonicalHeaderKey(name)+"\x00"+strings.Join(header[name], "\x00")+"\n")
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func forgetValidatedToolList(key string) {
+	validatedToolListsMutex.Lock()
+	defer validatedToolListsMutex.Unlock()
+	delete(validatedToolLists, key)
+}
+
+func invalidateValidatedToolLists(serverURL string) {
+	serverURL = strings.TrimSpace(serverURL)
+	if parsed, err := url.Parse(serverURL); err == nil {
+		serverURL = normalizedServerURL(parsed)
+	}
+
+	validatedToolListsMutex.Lock()
+	defer validatedToolListsMutex.Unlock()
+	prefix := serverURL + "\x00"
+	for key := range validatedToolLists {
+		if strings.HasPrefix(key, prefix) {
+			delete(validatedToolLists, key)
+		}
+	}
+}
+
+func clearValidatedToolLists() {
+	validatedToolListsMutex.Lock()
+	defer validatedToolListsMutex.Unlock()
+	validatedToolLists = map[string]validatedToolList{}
+}
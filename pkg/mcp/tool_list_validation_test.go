@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etagToolServer is a minimal streamable HTTP MCP server whose tools/list
+// responses carry an ETag and honour If-None-Match.
+type etagToolServer struct {
+	mu          sync.Mutex
+	etag        string
+	tools       []string
+	listings    int
+	notModified int
+}
+
+func (s *etagToolServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var call struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(body, &call)
+	if len(call.ID) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result any
+	switch call.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": "2025-06-18",
+			"serverInfo":      map[string]any{"name": "labs", "version": "1.0.0"},
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+		}
+	case "tools/list":
+		s.listings++
+		if s.etag != "" && r.Header.Get("If-None-Match") == s.etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		tools := []map[string]any{}
+		for _, name := range s.tools {
+			tools = append(tools, map[string]any{"name": name, "inputSchema": map[string]any{"type": "object"}})
+		}
+		result = map[string]any{"tools": tools}
+		if s.etag != "" {
+			w.Header().Set("ETag", s.etag)
+		}
+	default:
+		result = map[string]any{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": call.ID, "result": result})
+}
+
+func (s *etagToolServer) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listings, s.notModified
+}
+
+func toolNames(a *ToolAdapter) []string {
+	names := []string{}
+	for _, tool := range a.Tools() {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestToolListingsAreRevalidatedWithETag(t *testing.T) {
+	ClearToolListCache()
+	t.Cleanup(ClearToolListCache)
+	fake := &etagToolServer{etag: `"v1"`, tools: []string{"get_egfr", "get_potassium"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	first, err := NewToolAdapter(ctx, server.URL, "Bearer token", nil)
+	require.NoError(t, err)
+	defer first.Disconnect()
+	second, err := NewToolAdapter(ctx, server.URL, "Bearer token", nil)
+	require.NoError(t, err)
+	defer second.Disconnect()
+
+	listings, notModified := fake.counts()
+	assert.Equal(t, 2, listings)
+	assert.Equal(t, 1, notModified)
+	assert.Equal(t, []string{"get_egfr", "get_potassium"}, toolNames(second))
+
+	fake.mu.Lock()
+	fake.etag = `"v2"`
+	fake.tools = []string{"get_egfr"}
+	fake.mu.Unlock()
+	require.NoError(t, second.RefreshTools(ctx))
+	assert.Equal(t, []string{"get_egfr"}, toolNames(second))
+
+	require.NoError(t, first.RefreshTools(ctx))
+	assert.Equal(t, []string{"get_egfr"}, toolNames(first))
+	listings, notModified = fake.counts()
+	assert.Equal(t, 4, listings)
+	assert.Equal(t, 2, notModified)
+}
+
+func TestToolListingsWithoutValidatorsAreNotCached(t *testing.T) {
+	ClearToolListCache()
+	t.Cleanup(ClearToolListCache)
+	fake := &etagToolServer{tools: []string{"get_egfr"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	adapter, err := NewToolAdapter(context.Background(), server.URL, "", nil)
+	require.NoError(t, err)
+	defer adapter.Disconnect()
+	require.NoError(t, adapter.RefreshTools(context.Background()))
+
+	_, notModified := fake.counts()
+	assert.Equal(t, 0, notModified)
+	assert.Empty(t, validatedToolLists)
+}
+
+func TestInvalidateToolListForgetsValidatedListings(t *testing.T) {
+	ClearToolListCache()
+	t.Cleanup(ClearToolListCache)
+	fake := &etagToolServer{etag: `"v1"`, tools: []string{"get_egfr"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	adapter, err := NewToolAdapter(ctx, server.URL, "", nil)
+	require.NoError(t, err)
+	defer adapter.Disconnect()
+
+	InvalidateToolList(server.URL)
+	require.NoError(t, adapter.RefreshTools(ctx))
+	_, notModified := fake.counts()
+	assert.Equal(t, 0, notModified)
+	assert.Equal(t, []string{"get_egfr"}, toolNames(adapter))
+}
+
+func TestInvalidateToolListMatchesNormalizedURL(t *testing.T) {
+	ClearToolListCache()
+	t.Cleanup(ClearToolListCache)
+	fake := &etagToolServer{etag: `"v1"`, tools: []string{"get_egfr"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	adapter, err := NewToolAdapter(ctx, server.URL+"/", "", nil)
+	require.NoError(t, err)
+	defer adapter.Disconnect()
+
+	InvalidateToolList(strings.ToUpper(server.URL[:4]) + server.URL[4:])
+	require.NoError(t, adapter.RefreshTools(ctx))
+	_, notModified := fake.counts()
+	assert.Equal(t, 0, notModified, "the listing is forgotten however the URL is spelled")
+}
+
+func TestToolListingsAreKeyedByHeaders(t *testing.T) {
+	ClearToolListCache()
+	t.Cleanup(ClearToolListCache)
+	fake := &etagToolServer{etag: `"v1"`, tools: []string{"get_egfr"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	for _, tenant := range []string{"clinic-a", "clinic-b", "clinic-a"} {
+		adapter, err := NewToolAdapter(ctx, server.URL, "Bearer token", nil, WithAdapterHeaders(map[string]string{"X-Tenant": tenant}))
+		require.NoError(t, err)
+		require.NoError(t, adapter.Disconnect())
+	}
+
+	listings, notModified := fake.counts()
+	assert.Equal(t, 3, listings)
+	assert.Equal(t, 1, notModified, "only the repeated tenant revalidates")
+}
+
+func TestToolListValidationClientUsesTLSConfig(t *testing.T) {
+	ClearToolListCache()
+	t.Cleanup(ClearToolListCache)
+	server := httptest.NewTLSServer(&etagToolServer{tools: []string{"get_egfr"}})
+	defer server.Close()
+	ctx := context.Background()
+
+	_, err := NewToolAdapter(ctx, server.URL, "", nil)
+	require.Error(t, err, "the test server's certificate is not trusted by default")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	adapter, err := NewToolAdapter(ctx, server.URL, "", nil, WithAdapterTLSConfig(&tls.Config{RootCAs: roots}))
+	require.NoError(t, err)
+	defer adapter.Disconnect()
+	assert.Equal(t, []string{"get_egfr"}, toolNames(adapter))
+
+	client := newToolListValidationClient(model.GeneratorConfig{URL: server.URL})
+	assert.Equal(t, defaultHTTPTimeout, client.Timeout)
+}
+
+func TestNormalizedServerURL(t *testing.T) {
+	cases := map[string]string{
+		"https://mcp.example.com/tools":           "https://mcp.example.com/tools",
+		"HTTPS://MCP.Example.com:443/tools/":      "https://mcp.example.com/tools",
+		"http://mcp.example.com:80/tools#top":     "http://mcp.example.com/tools",
+		"http://mcp.example.com:8080/":            "http://mcp.example.com:8080",
+		"https://mcp.example.com/t%6Fols?b=2&a=1": "https://mcp.example.com/tools?a=1&b=2",
+		"http://[::1]:80/mcp":                     "http://[::1]/mcp",
+	}
+	for raw, want := range cases {
+		parsed, err := url.Parse(raw)
+		require.NoError(t, err)
+		assert.Equal(t, want, normalizedServerURL(parsed), raw)
+	}
+}
@@ -2,46 +2,150 @@ package mcp
 
 import (
 	"context"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
 	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-var cachedToolsByURL = map[string][]string{}
-var cachedToolsMutex sync.RWMutex
+// DefaultToolListCacheTTL is how long FetchListOfTools reuses a discovered
+// tool list before asking the server again.
+const DefaultToolListCacheTTL = 5 * time.Minute
 
+type cachedToolList struct {
+	names     []string
+	fetchedAt time.Time
+}
+
+var (
+	cachedToolsByKey = map[string]cachedToolList{}
+	cachedToolsTTL   = DefaultToolListCacheTTL
+	cachedToolsMutex sync.RWMutex
+	// cachedToolsGeneration changes on every invalidation, so a fetch that
+	// was running meanwhile does not cache a list that may be stale.
+	cachedToolsGeneration uint64
+	// toolListFetchLocks serialize fetches per key, so concurrent callers
+	// share one discovery while other servers are fetched in parallel.
+	toolListFetchLocks = map[string]*sync.Mutex{}
+
+	toolListNow   = time.Now
+	fetchToolList = actuallyFetchListOfTools
+)
+
+// SetToolListCacheTTL sets how long discovered tool lists are reused
+// (default DefaultToolListCacheTTL). Zero disables the cache; a negative TTL
+// keeps entries until they are invalidated. Existing entries are judged by
+// the new TTL.
+func SetToolListCacheTTL(ttl time.Duration) {
+	cachedToolsMutex.Lock()
+	defer cachedToolsMutex.Unlock()
+	cachedToolsTTL = ttl
+}
+
+// InvalidateToolList drops the cached tool lists of serverURL, for every auth
+// token, so the next FetchListOfTools discovers them again, and forgets the
+// ETag / Last-Modified validated listings of ToolAdapter connects and
+// refreshes. Call it when the server's tools are known to have changed.
+func InvalidateToolList(serverURL string) {
+	invalidateValidatedToolLists(serverURL)
+	cachedToolsMutex.Lock()
+	defer cachedToolsMutex.Unlock()
+	cachedToolsGeneration++
+	prefix := serverURL + "\x00"
+	for key := range cachedToolsByKey {
+		if strings.HasPrefix(key, prefix) {
+			delete(cachedToolsByKey, key)
+		}
+	}
+}
+
+// ClearToolListCache drops every cached tool list, validated listings
+// included.
+func ClearToolListCache() {
+	clearValidatedToolLists()
+	cachedToolsMutex.Lock()
+	defer cachedToolsMutex.Unlock()
+	cachedToolsGeneration++
+	cachedToolsByKey = map[string]cachedToolList{}
+}
+
+// FetchListOfTools returns the names of the tools serverURL offers. Lists are
+// cached per server URL and auth token for the cache TTL (see
+// SetToolListCacheTTL), so repeated generations do not rediscover them. Once
+// an entry expires, servers that send ETag or Last-Modified validators can
+// answer 304 Not Modified instead of resending the list. Concurrent calls for
+// the same server and token share one fetch; the cache is not locked while
+// the server is asked, so other servers and invalidations are not held up.
 func FetchListOfTools(ctx context.Context, serverURL string, authToken string) ([]string, error) {
+	key := serverURL + "\x00" + authToken
+
 	cachedToolsMutex.RLock()
-	tmpTools, found := cachedToolsByURL[serverURL]
+	tmpTools, found := freshToolList(key)
 	cachedToolsMutex.RUnlock()
 	if found {
-		return append([]string(nil), tmpTools...), nil
+		return tmpTools, nil
 	}
 
-	cachedToolsMutex.Lock()
-	defer cachedToolsMutex.Unlock()
+	fetchLock := toolListFetchLock(key)
+	fetchLock.Lock()
+	defer fetchLock.Unlock()
 
-	tmpTools, found = cachedToolsByURL[serverURL]
+	cachedToolsMutex.RLock()
+	tmpTools, found = freshToolList(key)
+	generation := cachedToolsGeneration
+	cachedToolsMutex.RUnlock()
 	if found {
-		return append([]string(nil), tmpTools...), nil
+		return tmpTools, nil
 	}
 
-	tmpTools, err := actuallyFetchListOfTools(ctx, serverURL, authToken)
+	tmpTools, err := fetchToolList(ctx, serverURL, authToken)
 	if err != nil {
 		return nil, err
 	}
 
-	cachedToolsByURL[serverURL] = append([]string(nil), tmpTools...)
+	cachedToolsMutex.Lock()
+	if cachedToolsTTL != 0 && cachedToolsGeneration == generation {
+		cachedToolsByKey[key] = cachedToolList{names: append([]string(nil), tmpTools...), fetchedAt: toolListNow()}
+	}
+	cachedToolsMutex.Unlock()
 	return append([]string(nil), tmpTools...), nil
 }
+
+// toolListFetchLock returns the mutex that serializes fetches for key.
+func toolListFetchLock(key string) *sync.Mutex {
+	cachedToolsMutex.Lock()
+	defer cachedToolsMutex.Unlock()
+	fetchLock, ok := toolListFetchLocks[key]
+	if !ok {
+		fetchLock = &sync.Mutex{}
+		toolListFetchLocks[key] = fetchLock
+	}
+	return fetchLock
+}
+
+// freshToolList returns a copy of the cached list for key when it has not
+// expired. The caller holds cachedToolsMutex.
+func freshToolList(key string) ([]string, bool) {
+	entry, found := cachedToolsByKey[key]
+	if !found || cachedToolsTTL == 0 {
+		return nil, false
+	}
+	if cachedToolsTTL > 0 && toolListNow().Sub(entry.fetchedAt) >= cachedToolsTTL {
+		return nil, false
+	}
+	return append([]string(nil), entry.names...), true
+}
+
 func actuallyFetchListOfTools(ctx context.Context, serverURL string, authToken string) ([]string, error) {
 
 	headers := make(map[string]string)
 	headers["Authorization"] = authToken
-	httpTransport, err := transport.NewStreamableHTTP(serverURL, transport.WithHTTPHeaders(headers))
+	httpTransport, err := transport.NewStreamableHTTP(serverURL, transport.WithHTTPHeaders(headers), transport.WithHTTPBasicClient(newToolListValidationClient(model.GeneratorConfig{URL: serverURL})))
 
 	// Create client with the transport
 	c := client.NewClient(httpTransport)
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubToolList replaces discovery and the clock for one test and returns the
+// fetch counter and a func that advances the clock.
+func stubToolList(t *testing.T, ttl time.Duration) (*int, func(time.Duration)) {
+	t.Helper()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fetches := 0
+
+	originalFetch, originalNow := fetchToolList, toolListNow
+	fetchToolList = func(ctx context.Context, serverURL string, authToken string) ([]string, error) {
+		fetches++
+		return []string{serverURL + "/" + authToken}, nil
+	}
+	toolListNow = func() time.Time { return now }
+	ClearToolListCache()
+	SetToolListCacheTTL(ttl)
+	t.Cleanup(func() {
+		fetchToolList, toolListNow = originalFetch, originalNow
+		ClearToolListCache()
+		SetToolListCacheTTL(DefaultToolListCacheTTL)
+	})
+	return &fetches, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestFetchListOfToolsCachesUntilTTL(t *testing.T) {
+	fetches, advance := stubToolList(t, time.Minute)
+	ctx := context.Background()
+
+	names, err := FetchListOfTools(ctx, "https://mcp.example", "a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://mcp.example/a"}, names)
+	_, err = FetchListOfTools(ctx, "https://mcp.example", "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, *fetches)
+
+	// Another token may see other tools, so it is cached separately.
+	_, err = FetchListOfTools(ctx, "https://mcp.example", "b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, *fetches)
+
+	advance(time.Minute)
+	_, err = FetchListOfTools(ctx, "https://mcp.example", "a")
+	require.NoError(t, err)
+	assert.Equal(t, 3, *fetches)
+}
+
+func TestInvalidateToolList(t *testing.T) {
+	fetches, _ := stubToolList(t, -1)
+	ctx := context.Background()
+
+	for _, token := range []string{"a", "b"} {
+		_, err := FetchListOfTools(ctx, "https://mcp.example", token)
+		require.NoError(t, err)
+	}
+	_, err := FetchListOfTools(ctx, "https://other.example", "a")
+	require.NoError(t, err)
+	require.Equal(t, 3, *fetches)
+
+	InvalidateToolList("https://mcp.example")
+	for _, token := range []string{"a", "b"} {
+		_, err := FetchListOfTools(ctx, "https://mcp.example", token)
+		require.NoError(t, err)
+	}
+	_, err = FetchListOfTools(ctx, "https://other.example", "a")
+	require.NoError(t, err)
+	assert.Equal(t, 5, *fetches)
+}
+
+func TestFetchListOfToolsZeroTTLDisablesCache(t *testing.T) {
+	fetches, _ := stubToolList(t, 0)
+
+	for range 2 {
+		_, err := FetchListOfTools(context.Background(), "https://mcp.example", "a")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, *fetches)
+}
+
+// blockToolList makes fetches for blockedURL wait until release is closed and
+// counts every fetch.
+func blockToolList(t *testing.T, blockedURL string) (*atomic.Int32, chan struct{}, chan struct{}) {
+	t.Helper()
+	stubToolList(t, time.Minute)
+	var fetches atomic.Int32
+	started, release := make(chan struct{}, 8), make(chan struct{})
+	fetchToolList = func(ctx context.Context, serverURL string, authToken string) ([]string, error) {
+		fetches.Add(1)
+		if serverURL == blockedURL {
+			started <- struct{}{}
+			<-release
+		}
+		return []string{serverURL + "/" + authToken}, nil
+	}
+	return &fetches, started, release
+}
+
+func TestFetchListOfToolsDoesNotHoldCacheWhileFetching(t *testing.T) {
+	fetches, started, release := blockToolList(t, "https://slow.example")
+	ctx := context.Background()
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := FetchListOfTools(ctx, "https://slow.example", "a")
+		slow <- err
+	}()
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		names, err := FetchListOfTools(ctx, "https://fast.example", "a")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://fast.example/a"}, names)
+		InvalidateToolList("https://fast.example")
+		SetToolListCacheTTL(time.Minute)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow server blocked other servers and invalidation")
+	}
+
+	close(release)
+	require.NoError(t, <-slow)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestFetchListOfToolsSharesConcurrentFetches(t *testing.T) {
+	fetches, started, release := blockToolList(t, "https://mcp.example")
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			names, err := FetchListOfTools(ctx, "https://mcp.example", "a")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"https://mcp.example/a"}, names)
+		})
+	}
+	<-started
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestFetchListOfToolsDropsListInvalidatedWhileFetching(t *testing.T) {
+	fetches, started, release := blockToolList(t, "https://mcp.example")
+	ctx := context.Background()
+
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := FetchListOfTools(ctx, "https://mcp.example", "a")
+		fetched <- err
+	}()
+	<-started
+	InvalidateToolList("https://mcp.example")
+	close(release)
+	require.NoError(t, <-fetched)
+
+	_, err := FetchListOfTools(ctx, "https://mcp.example", "a")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load(), "the list fetched before the invalidation is not cached")
+}
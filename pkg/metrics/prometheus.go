@@ -0,0 +1,178 @@
+// Package metrics reports generations to Prometheus. A PrometheusRecorder is a
+// model.MetricsRecorder: pass it to providers with model.WithMetricsRecorder
+// and it counts requests, errors and tokens and observes latency and tool
+// rounds, labelled by provider and model and, with WithRecordLabels, by prompt
+// version or experiment. It is a separate module so the core module does not
+// depend on the Prometheus client.
+package metrics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace prefixes every metric name when WithNamespace is not set.
+const DefaultNamespace = "polyglot_llm"
+
+// DefaultToolRoundBuckets are the tool round histogram buckets used when
+// WithToolRoundBuckets is not set.
+var DefaultToolRoundBuckets = []float64{0, 1, 2, 3, 5, 8, 12}
+
+// PrometheusRecorder exposes these metrics, all labelled provider and model
+// plus any WithRecordLabels names:
+//   - <namespace>_generation_requests_total: generations, successful or not.
+//   - <namespace>_generation_errors_total: generations that returned an error.
+//   - <namespace>_generation_duration_seconds: generation latency histogram.
+//   - <namespace>_generation_input_tokens_total and
+//     <namespace>_generation_output_tokens_total: token usage.
+//   - <namespace>_generation_tool_rounds: tool rounds per generation histogram.
+type PrometheusRecorder struct {
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inputTokens  *prometheus.CounterVec
+	outputTokens *prometheus.CounterVec
+	toolRounds   *prometheus.HistogramVec
+	recordLabels []string
+}
+
+type prometheusConfig struct {
+	namespace        string
+	constLabels      prometheus.Labels
+	durationBuckets  []float64
+	toolRoundBuckets []float64
+	recordLabels     []string
+}
+
+// Option configures NewPrometheusRecorder.
+type Option func(*prometheusConfig)
+
+// WithNamespace replaces DefaultNamespace as the metric name prefix.
+func WithNamespace(namespace string) Option {
+	return func(cfg *prometheusConfig) {
+		cfg.namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric, such as the
+// service name.
+func WithConstLabels(labels map[string]string) Option {
+	return func(cfg *prometheusConfig) {
+		cfg.constLabels = prometheus.Labels(labels)
+	}
+}
+
+// WithRecordLabels adds a label per name to every metric, valued from
+// model.GenerationRecord.Labels and empty when a generation has no value,
+// for example model.MetadataKeyPromptVersion, model.MetadataKeyExperiment
+// and model.MetadataKeyExperimentVariant. Each distinct value starts new
+// series, so only add labels with few values.
+func WithRecordLabels(names ...string) Option {
+	return func(cfg *prometheusConfig) {
+		cfg.recordLabels = append(cfg.recordLabels, names...)
+	}
+}
+
+// WithDurationBuckets sets the latency histogram buckets in seconds (default
+// prometheus.DefBuckets).
+func WithDurationBuckets(buckets []float64) Option {
+	return func(cfg *prometheusConfig) {
+		cfg.durationBuckets = append([]float64(nil), buckets...)
+	}
+}
+
+// WithToolRoundBuckets sets the tool round histogram buckets (default
+// DefaultToolRoundBuckets).
+func WithToolRoundBuckets(buckets []float64) Option {
+	return func(cfg *prometheusConfig) {
+		cfg.toolRoundBuckets = append([]float64(nil), buckets...)
+	}
+}
+
+// NewPrometheusRecorder creates the metrics and registers them with
+// registerer (prometheus.DefaultRegisterer when nil). Registering twice with
+// the same registerer and namespace fails.
+func NewPrometheusRecorder(registerer prometheus.Registerer, opts ...Option) (*PrometheusRecorder, error) {
+	cfg := prometheusConfig{
+		namespace:        DefaultNamespace,
+		durationBuckets:  prometheus.DefBuckets,
+		toolRoundBuckets: DefaultToolRoundBuckets,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	labels := append([]string{"provider", "model"}, cfg.recordLabels...)
+	counter := func(name string, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: cfg.constLabels,
+		}, labels)
+	}
+	histogram := func(name string, help string, buckets []float64) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: cfg.constLabels,
+			Buckets:     buckets,
+		}, labels)
+	}
+
+	recorder := &PrometheusRecorder{
+		requests:     counter("generation_requests_total", "Content generations, successful or not."),
+		errors:       counter("generation_errors_total", "Content generations that returned an error."),
+		duration:     histogram("generation_duration_seconds", "Content generation latency in seconds.", cfg.durationBuckets),
+		inputTokens:  counter("generation_input_tokens_total", "Input tokens used by content generations."),
+		outputTokens: counter("generation_output_tokens_total", "Output tokens used by content generations."),
+		toolRounds:   histogram("generation_tool_rounds", "Tool rounds per content generation.", cfg.toolRoundBuckets),
+		recordLabels: cfg.recordLabels,
+	}
+
+	var errs []error
+	for _, collector := range []prometheus.Collector{
+		recorder.requests,
+		recorder.errors,
+		recorder.duration,
+		recorder.inputTokens,
+		recorder.outputTokens,
+		recorder.toolRounds,
+	} {
+		errs = append(errs, registerer.Register(collector))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return recorder, nil
+}
+
+// RecordGeneration implements model.MetricsRecorder.
+func (r *PrometheusRecorder) RecordGeneration(ctx context.Context, record model.GenerationRecord) {
+	labels := prometheus.Labels{"provider": record.Provider, "model": record.Model}
+	for _, name := range r.recordLabels {
+		labels[name] = record.Labels[name]
+	}
+	r.requests.With(labels).Inc()
+	if record.Err != nil {
+		r.errors.With(labels).Inc()
+	}
+	r.duration.With(labels).Observe(record.Duration.Seconds())
+	if record.InputTokens > 0 {
+		r.inputTokens.With(labels).Add(float64(record.InputTokens))
+	}
+	if record.OutputTokens > 0 {
+		r.outputTokens.With(labels).Add(float64(record.OutputTokens))
+	}
+	r.toolRounds.With(labels).Observe(float64(record.ToolRounds))
+}
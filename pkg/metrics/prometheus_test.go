@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/suite"
+)
+
+type PrometheusSuite struct {
+	suite.Suite
+}
+
+func TestPrometheusSuite(t *testing.T) {
+	suite.Run(t, new(PrometheusSuite))
+}
+
+func gatherFamilies(s *PrometheusSuite, registry *prometheus.Registry) map[string]*dto.MetricFamily {
+	families, err := registry.Gather()
+	s.Require().NoError(err)
+	out := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		out[family.GetName()] = family
+	}
+	return out
+}
+
+func metricFor(s *PrometheusSuite, family *dto.MetricFamily, provider string, modelName string) *dto.Metric {
+	s.Require().NotNil(family)
+	for _, metric := range family.GetMetric() {
+		labels := map[string]string{}
+		for _, pair := range metric.GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		if labels["provider"] == provider && labels["model"] == modelName {
+			return metric
+		}
+	}
+	s.FailNow("metric not found", "%s{provider=%q,model=%q}", family.GetName(), provider, modelName)
+	return nil
+}
+
+func (s *PrometheusSuite) TestRecordsGenerationsPerProviderAndModel() {
+	registry := prometheus.NewRegistry()
+	recorder, err := NewPrometheusRecorder(registry)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	recorder.RecordGeneration(ctx, model.GenerationRecord{
+		Provider: "openai", Model: "gpt-4o", Duration: 2 * time.Second,
+		InputTokens: 100, OutputTokens: 20, ToolRounds: 1,
+	})
+	recorder.RecordGeneration(ctx, model.GenerationRecord{
+		Provider: "openai", Model: "gpt-4o", Duration: time.Second,
+		InputTokens: 50, Err: errors.New("rate limited"),
+	})
+	recorder.RecordGeneration(ctx, model.GenerationRecord{
+		Provider: "anthropic", Model: "claude", Duration: time.Second, OutputTokens: 7,
+	})
+
+	families := gatherFamilies(s, registry)
+	s.Equal(2.0, metricFor(s, families["polyglot_llm_generation_requests_total"], "openai", "gpt-4o").GetCounter().GetValue())
+	s.Equal(1.0, metricFor(s, families["polyglot_llm_generation_errors_total"], "openai", "gpt-4o").GetCounter().GetValue())
+	s.Equal(150.0, metricFor(s, families["polyglot_llm_generation_input_tokens_total"], "openai", "gpt-4o").GetCounter().GetValue())
+	s.Equal(20.0, metricFor(s, families["polyglot_llm_generation_output_tokens_total"], "openai", "gpt-4o").GetCounter().GetValue())
+	s.Equal(7.0, metricFor(s, families["polyglot_llm_generation_output_tokens_total"], "anthropic", "claude").GetCounter().GetValue())
+
+	duration := metricFor(s, families["polyglot_llm_generation_duration_seconds"], "openai", "gpt-4o").GetHistogram()
+	s.Equal(uint64(2), duration.GetSampleCount())
+	s.InDelta(3.0, duration.GetSampleSum(), 1e-9)
+	rounds := metricFor(s, families["polyglot_llm_generation_tool_rounds"], "openai", "gpt-4o").GetHistogram()
+	s.Equal(uint64(2), rounds.GetSampleCount())
+	s.InDelta(1.0, rounds.GetSampleSum(), 1e-9)
+}
+
+func (s *PrometheusSuite) TestOptions() {
+	registry := prometheus.NewRegistry()
+	recorder, err := NewPrometheusRecorder(registry,
+		WithNamespace("clinic"),
+		WithConstLabels(map[string]string{"service": "notes"}),
+		WithDurationBuckets([]float64{0.5, 5}),
+	)
+	s.Require().NoError(err)
+	recorder.RecordGeneration(context.Background(), model.GenerationRecord{Provider: "gemini", Model: "flash", Duration: time.Second})
+
+	families := gatherFamilies(s, registry)
+	s.NotContains(families, "polyglot_llm_generation_requests_total")
+	requests := metricFor(s, families["clinic_generation_requests_total"], "gemini", "flash")
+	s.Contains(requests.GetLabel(), &dto.LabelPair{Name: strPtr("service"), Value: strPtr("notes")})
+	duration := metricFor(s, families["clinic_generation_duration_seconds"], "gemini", "flash").GetHistogram()
+	s.Len(duration.GetBucket(), 2)
+}
+
+func (s *PrometheusSuite) TestRecordLabelsCarryExperiments() {
+	registry := prometheus.NewRegistry()
+	recorder, err := NewPrometheusRecorder(registry,
+		WithRecordLabels(model.MetadataKeyPromptVersion, model.MetadataKeyExperiment, model.MetadataKeyExperimentVariant),
+	)
+	s.Require().NoError(err)
+
+	// Labels reach the recorder through model.RecordGeneration, as providers
+	// report them.
+	ctx := context.Background()
+	for _, opts := range [][]model.GeneratorOption{
+		{model.WithPromptVersion("summary-v3"), model.WithExperiment("summary-length", "short")},
+		{model.WithPromptVersion("summary-v3"), model.WithExperiment("summary-length", "long")},
+		{model.WithPromptVersion("summary-v3"), model.WithExperiment("summary-length", "long")},
+		nil,
+	} {
+		cfg := model.ResolveGeneratorOpts(append(opts, model.WithMetricsRecorder(recorder))...)
+		meta := model.GenerationMetadata{model.MetadataKeyProvider: "openai", model.MetadataKeyModel: "gpt-4o"}
+		model.SetExperimentMetadata(meta, cfg)
+		model.RecordGeneration(ctx, cfg, "openai", time.Now(), meta, nil)
+	}
+
+	requests := map[string]float64{}
+	for _, metric := range gatherFamilies(s, registry)["polyglot_llm_generation_requests_total"].GetMetric() {
+		labels := map[string]string{}
+		for _, pair := range metric.GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		s.Equal("openai", labels["provider"])
+		key := labels[model.MetadataKeyPromptVersion] + "/" + labels[model.MetadataKeyExperiment] + "/" + labels[model.MetadataKeyExperimentVariant]
+		requests[key] = metric.GetCounter().GetValue()
+	}
+	s.Equal(map[string]float64{
+		"summary-v3/summary-length/short": 1,
+		"summary-v3/summary-length/long":  2,
+		"//":                              1,
+	}, requests)
+}
+
+func (s *PrometheusSuite) TestRecordLabelsMustNotRepeatBuiltInLabels() {
+	_, err := NewPrometheusRecorder(prometheus.NewRegistry(), WithRecordLabels("model"))
+	s.Error(err)
+}
+
+func (s *PrometheusSuite) TestRegisteringTwiceFails() {
+	registry := prometheus.NewRegistry()
+	_, err := NewPrometheusRecorder(registry)
+	s.Require().NoError(err)
+
+	_, err = NewPrometheusRecorder(registry)
+	s.Require().Error(err)
+	var already prometheus.AlreadyRegisteredError
+	s.ErrorAs(err, &already)
+}
+
+func (s *PrometheusSuite) TestImplementsMetricsRecorderOption() {
+	recorder, err := NewPrometheusRecorder(prometheus.NewRegistry())
+	s.Require().NoError(err)
+	cfg := model.ResolveGeneratorOpts(model.WithMetricsRecorder(recorder))
+	s.Same(recorder, cfg.MetricsRecorder)
+}
+
+func strPtr(value string) *string {
+	return &value
+}
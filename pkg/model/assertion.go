@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrAssertionFailed matches any *AssertionError with errors.Is.
+var ErrAssertionFailed = errors.New("structured output failed assertions")
+
+// Assertion checks a decoded structured output. Assertions registered for
+// another output type are skipped.
+type Assertion func(value any) error
+
+// AssertionError is returned by structured generators when the decoded output
+// fails one or more WithAssertion checks. errors.Is and errors.As reach the
+// errors the checks returned.
+type AssertionError struct {
+	Failures []error
+}
+
+func (e *AssertionError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		parts = append(parts, failure.Error())
+	}
+	return ErrAssertionFailed.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is reports whether target is ErrAssertionFailed.
+func (e *AssertionError) Is(target error) bool {
+	return target == ErrAssertionFailed
+}
+
+// Unwrap returns the errors of the failed checks.
+func (e *AssertionError) Unwrap() []error {
+	return e.Failures
+}
+
+// WithAssertion adds a business check that structured generators run on the
+// decoded output of type T, after schema validation (for example totals add
+// up or dates are in range). A failure is treated like a schema violation:
+// its message goes into the repair prompt, and when repair is exhausted the
+// generation returns an *AssertionError. Unless WithStructuredRepairAttempts
+// is set, generators with assertions make at least one repair attempt.
+// Checks accumulate across calls; string generators ignore them.
+func WithAssertion[T any](check func(T) error) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		if check == nil {
+			return
+		}
+		cfg.Assertions = append(cfg.Assertions, func(value any) error {
+			typed, ok := value.(T)
+			if !ok {
+				return nil
+			}
+			return check(typed)
+		})
+	})
+}
+
+// CheckAssertions runs cfg.Assertions on value and returns an
+// *AssertionError listing every failure, or nil.
+func CheckAssertions(cfg GeneratorConfig, value any) error {
+	var failures []error
+	for _, assertion := range cfg.Assertions {
+		if err := assertion(value); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &AssertionError{Failures: failures}
+}
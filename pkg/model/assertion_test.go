@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AssertionSuite struct {
+	suite.Suite
+}
+
+func TestAssertionSuite(t *testing.T) {
+	suite.Run(t, new(AssertionSuite))
+}
+
+var errEmptyName = errors.New("name must not be empty")
+
+func nameNotEmpty(target repairTarget) error {
+	if target.Name == "" {
+		return errEmptyName
+	}
+	return nil
+}
+
+func (s *AssertionSuite) TestCheckAssertionsCollectsFailures() {
+	cfg := ResolveGeneratorOpts(
+		WithAssertion(nameNotEmpty),
+		WithAssertion(func(target repairTarget) error {
+			if strings.ToLower(target.Name) != target.Name {
+				return errors.New("name must be lower case")
+			}
+			return nil
+		}),
+		WithAssertion(func(count int) error {
+			return errors.New("never runs for repairTarget")
+		}),
+		WithAssertion[repairTarget](nil),
+	)
+	s.Len(cfg.Assertions, 3)
+
+	s.NoError(CheckAssertions(cfg, repairTarget{Name: "ok"}))
+
+	err := CheckAssertions(cfg, repairTarget{Name: "OK"})
+	var assertionErr *AssertionError
+	s.Require().ErrorAs(err, &assertionErr)
+	s.Len(assertionErr.Failures, 1)
+	s.ErrorIs(err, ErrAssertionFailed)
+	s.Equal("structured output failed assertions: name must be lower case", err.Error())
+
+	err = CheckAssertions(cfg, repairTarget{})
+	s.ErrorIs(err, errEmptyName)
+}
+
+func (s *AssertionSuite) TestAssertionsEnableOneRepairByDefault() {
+	s.Equal(0, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(), 0))
+	s.Equal(1, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(WithAssertion(nameNotEmpty)), 0))
+	s.Equal(2, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(WithAssertion(nameNotEmpty)), 2))
+	s.Equal(0, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(WithAssertion(nameNotEmpty), WithStructuredRepairAttempts(0)), 1))
+}
+
+func (s *AssertionSuite) TestFailuresAreRepaired() {
+	var prompts []string
+	repair := func(ctx context.Context, prompt string) (string, GenerationMetadata, error) {
+		prompts = append(prompts, prompt)
+		return `{"name":"fixed"}`, nil, nil
+	}
+	cfg := ResolveGeneratorOpts(WithAssertion(nameNotEmpty))
+	meta := GenerationMetadata{}
+
+	out, err := DecodeStructuredOutputWithRepair[repairTarget](context.Background(), cfg, meta, repairSchema, `{"name":""}`, 1, strings.TrimSpace, repair)
+	s.Require().NoError(err)
+	s.Equal("fixed", out.Name)
+	s.Require().Len(prompts, 1)
+	s.Contains(prompts[0], "name must not be empty")
+	s.Equal("1", meta[MetadataKeyStructuredRepairs])
+}
+
+func (s *AssertionSuite) TestExhaustedRepairReturnsAssertionError() {
+	cfg := ResolveGeneratorOpts(WithAssertion(nameNotEmpty))
+
+	out, err := DecodeStructuredOutputWithRepair[repairTarget](context.Background(), cfg, GenerationMetadata{}, repairSchema, `{"name":""}`, 0, strings.TrimSpace, nil)
+	s.Require().Error(err)
+	s.ErrorIs(err, ErrAssertionFailed)
+	s.ErrorIs(err, errEmptyName)
+	s.Equal(repairTarget{}, out)
+}
@@ -1,5 +1,7 @@
 package model
 
+import "crypto/tls"
+
 type AudioKeyword struct {
 	Word           string   `json:"word"`
 	CommonMistypes []string `json:"common_mistypes"`
@@ -11,6 +13,11 @@ type AudioOptions struct {
 	URL                           string
 	AuthToken                     string
 	Model                         string
+	// TLSConfig configures TLS for self-hosted endpoints (see WithTLSConfig).
+	TLSConfig *tls.Config
+	// GCPProject and GCPLocation select the Vertex AI backend for providers that support it.
+	GCPProject  string
+	GCPLocation string
 	// Prompt optionally overrides the provider's default audio prompt behavior.
 	// When Prompt is set, keyword hints are not appended.
 	Prompt string
@@ -18,4 +25,24 @@ type AudioOptions struct {
 	// Providers may convert this into: "Common missed words: <json>"
 	// when Prompt is empty.
 	Keywords []AudioKeyword
+	// Language is the ISO 639-1 code of the spoken language. Empty lets the
+	// provider detect it; the transcript language is then guessed with
+	// DetectLanguage and reported in MetadataKeyLanguage.
+	Language string
+	// RedactPII replaces personal data in the transcript with entity
+	// placeholders such as "[PHONE]" before it is returned (see RedactPII).
+	RedactPII bool
+	// TimestampGranularities selects the timings returned by
+	// VerboseTranscriptionGenerator: segments are always included, add
+	// TimestampGranularityWord for word timings. Plain transcription
+	// generators ignore it.
+	TimestampGranularities []TimestampGranularity
+	// Diarize labels who is speaking: VerboseTranscriptionGenerator sets
+	// TranscriptSegment.Speaker, and plain generators return one
+	// "Speaker: text" line per turn (see FormatSpeakerTranscript).
+	Diarize bool
+	// OutputFormat selects what AudioTranscriptionGenerator returns: plain
+	// text (the default), a JSON TranscriptionResult, or SRT/VTT subtitles
+	// (see FormatTranscript). Verbose generators ignore it.
+	OutputFormat TranscriptFormat
 }
@@ -0,0 +1,227 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Defaults for AudioBatchOptions.
+const (
+	DefaultAudioBatchConcurrency = 4
+	DefaultAudioBatchAttempts    = 3
+	DefaultAudioBatchRetryDelay  = time.Second
+)
+
+// DefaultAudioBatchExtensions are the file extensions TranscribeAudioDir
+// picks up when AudioBatchOptions.Extensions is empty.
+var DefaultAudioBatchExtensions = []string{".aac", ".flac", ".m4a", ".mp3", ".mp4", ".mpeg", ".mpga", ".ogg", ".wav", ".webm"}
+
+// AudioBatchSource is one input of TranscribeAudioBatch: a file path or a
+// reader. Readers are copied to a temporary file because transcription
+// generators read from a path.
+type AudioBatchSource struct {
+	// Name identifies the source in results. It defaults to Path. For
+	// readers, its extension selects the audio format.
+	Name   string
+	Path   string
+	Reader io.Reader
+}
+
+// AudioBatchOptions configures TranscribeAudioDir and TranscribeAudioBatch.
+type AudioBatchOptions struct {
+	// Concurrency is the number of files transcribed at once (default
+	// DefaultAudioBatchConcurrency).
+	Concurrency int
+	// MaxAttempts is the number of tries per file (default
+	// DefaultAudioBatchAttempts). Generator construction errors are not
+	// retried.
+	MaxAttempts int
+	// RetryDelay is the wait before the second try, doubled for each later
+	// try (default DefaultAudioBatchRetryDelay).
+	RetryDelay time.Duration
+	// Extensions filters the files of TranscribeAudioDir (default
+	// DefaultAudioBatchExtensions). Matching is case-insensitive.
+	Extensions []string
+}
+
+// AudioBatchResult is the outcome of one source.
+type AudioBatchResult struct {
+	Name       string
+	Transcript string
+	// Metadata is the metadata of the last attempt.
+	Metadata GenerationMetadata
+	Attempts int
+	Err      error
+}
+
+// AudioBatchReport holds the results in source order and the usage summed
+// over all successful files.
+type AudioBatchReport struct {
+	Results []AudioBatchResult
+	// Metadata sums the usage of successful files (see MergeUsageMetadata).
+	// latency_ms is the wall-clock time of the whole batch.
+	Metadata  GenerationMetadata
+	Succeeded int
+	Failed    int
+}
+
+// TranscribeAudioDir transcribes the audio files directly inside dir, in
+// name order (see TranscribeAudioBatch).
+func TranscribeAudioDir(ctx context.Context, factory NewAudioTranscriptionGeneratorFunc, dir string, opts AudioOptions, batch AudioBatchOptions) (AudioBatchReport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return AudioBatchReport{}, utils.WrapIfNotNil(err)
+	}
+
+	extensions := batch.Extensions
+	if len(extensions) == 0 {
+		extensions = DefaultAudioBatchExtensions
+	}
+	sources := make([]AudioBatchSource, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !hasAudioExtension(entry.Name(), extensions) {
+			continue
+		}
+		sources = append(sources, AudioBatchSource{Name: entry.Name(), Path: filepath.Join(dir, entry.Name())})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	return TranscribeAudioBatch(ctx, factory, sources, opts, batch)
+}
+
+// TranscribeAudioBatch transcribes sources concurrently with generators from
+// factory and opts, retrying failed files. Per-file failures are reported in
+// the results; the error is only set when the batch cannot run.
+func TranscribeAudioBatch(ctx context.Context, factory NewAudioTranscriptionGeneratorFunc, sources []AudioBatchSource, opts AudioOptions, batch AudioBatchOptions) (AudioBatchReport, error) {
+	if factory == nil {
+		return AudioBatchReport{}, utils.WrapIfNotNil(errors.New("audio transcription generator factory is required"))
+	}
+
+	start := time.Now()
+	concurrency := batch.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultAudioBatchConcurrency
+	}
+
+	report := AudioBatchReport{
+		Results:  make([]AudioBatchResult, len(sources)),
+		Metadata: GenerationMetadata{},
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			report.Results[i] = transcribeAudioSource(ctx, factory, source, opts, batch)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Err != nil {
+			report.Failed++
+			continue
+		}
+		report.Succeeded++
+		MergeUsageMetadata(report.Metadata, result.Metadata)
+	}
+	report.Metadata[MetadataKeyLatencyMs] = strconv.FormatInt(time.Since(start).Milliseconds(), 10)
+	return report, nil
+}
+
+func transcribeAudioSource(ctx context.Context, factory NewAudioTranscriptionGeneratorFunc, source AudioBatchSource, opts AudioOptions, batch AudioBatchOptions) AudioBatchResult {
+	result := AudioBatchResult{Name: source.Name}
+	if result.Name == "" {
+		result.Name = source.Path
+	}
+
+	path := source.Path
+	if source.Reader != nil {
+		tempPath, err := copyAudioToTempFile(source)
+		if err != nil {
+			result.Err = utils.WrapIfNotNil(err)
+			return result
+		}
+		defer func() {
+			_ = os.Remove(tempPath)
+		}()
+		path = tempPath
+	}
+	if strings.TrimSpace(path) == "" {
+		result.Err = utils.WrapIfNotNil(errors.New("audio source needs a path or a reader"))
+		return result
+	}
+
+	attempts := batch.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultAudioBatchAttempts
+	}
+	delay := batch.RetryDelay
+	if delay <= 0 {
+		delay = DefaultAudioBatchRetryDelay
+	}
+
+	for result.Attempts < attempts {
+		if result.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				result.Err = utils.WrapIfNotNil(ctx.Err())
+				return result
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		result.Attempts++
+
+		gen, err := factory(path, opts)
+		if err != nil {
+			result.Err = utils.WrapIfNotNil(err)
+			return result
+		}
+		result.Transcript, result.Metadata, err = gen.Generate(ctx)
+		result.Err = utils.WrapIfNotNil(err)
+		if err == nil || ctx.Err() != nil {
+			return result
+		}
+	}
+	return result
+}
+
+func copyAudioToTempFile(source AudioBatchSource) (string, error) {
+	file, err := os.CreateTemp("", "audio-batch-*"+filepath.Ext(source.Name))
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	_, err = io.Copy(file, source.Reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", utils.WrapIfNotNil(fmt.Errorf("copy audio %q: %w", source.Name, err))
+	}
+	return file.Name(), nil
+}
+
+func hasAudioExtension(name string, extensions []string) bool {
+	extension := strings.ToLower(filepath.Ext(name))
+	for _, candidate := range extensions {
+		if strings.ToLower(candidate) == extension {
+			return true
+		}
+	}
+	return false
+}
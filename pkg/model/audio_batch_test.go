@@ -0,0 +1,143 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AudioBatchSuite struct {
+	suite.Suite
+}
+
+func TestAudioBatchSuite(t *testing.T) {
+	suite.Run(t, new(AudioBatchSuite))
+}
+
+type fileTranscriber struct {
+	path string
+	fail func(path string) bool
+}
+
+func (g *fileTranscriber) Generate(ctx context.Context) (string, GenerationMetadata, error) {
+	if g.fail != nil && g.fail(g.path) {
+		return "", GenerationMetadata{MetadataKeyProvider: "fake"}, errors.New("transient failure")
+	}
+	data, err := os.ReadFile(g.path)
+	if err != nil {
+		return "", nil, err
+	}
+	return "transcript of " + string(data), GenerationMetadata{
+		MetadataKeyProvider:    "fake",
+		MetadataKeyModel:       "whisper",
+		MetadataKeyInputTokens: "10",
+		MetadataKeyTotalTokens: "15",
+	}, nil
+}
+
+func (s *AudioBatchSuite) TestTranscribeAudioDirRetriesAndSumsUsage() {
+	dir := s.T().TempDir()
+	for name, content := range map[string]string{"b.wav": "b", "a.MP3": "a", "c.wav": "c", "notes.txt": "skip"} {
+		s.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	s.Require().NoError(os.Mkdir(filepath.Join(dir, "nested.wav"), 0o700))
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	var active, peak int32
+	factory := func(path string, opts AudioOptions) (AudioTranscriptionGenerator, error) {
+		s.Equal("es", opts.Language)
+		return &fileTranscriber{path: path, fail: func(path string) bool {
+			current := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			calls[filepath.Base(path)]++
+			// b fails once, c always fails.
+			return (strings.HasSuffix(path, "b.wav") && calls["b.wav"] == 1) || strings.HasSuffix(path, "c.wav")
+		}}, nil
+	}
+
+	report, err := TranscribeAudioDir(context.Background(), factory, dir, AudioOptions{Language: "es"},
+		AudioBatchOptions{Concurrency: 2, MaxAttempts: 2, RetryDelay: time.Millisecond})
+	s.Require().NoError(err)
+	s.Require().Len(report.Results, 3)
+
+	s.Equal("a.MP3", report.Results[0].Name)
+	s.Equal("transcript of a", report.Results[0].Transcript)
+	s.Equal(1, report.Results[0].Attempts)
+
+	s.Equal("b.wav", report.Results[1].Name)
+	s.Equal("transcript of b", report.Results[1].Transcript)
+	s.Equal(2, report.Results[1].Attempts)
+	s.NoError(report.Results[1].Err)
+
+	s.Equal("c.wav", report.Results[2].Name)
+	s.Equal(2, report.Results[2].Attempts)
+	s.Error(report.Results[2].Err)
+
+	s.Equal(2, report.Succeeded)
+	s.Equal(1, report.Failed)
+	s.Equal("20", report.Metadata[MetadataKeyInputTokens])
+	s.Equal("30", report.Metadata[MetadataKeyTotalTokens])
+	s.Equal("whisper", report.Metadata[MetadataKeyModel])
+	s.Contains(report.Metadata, MetadataKeyLatencyMs)
+	s.LessOrEqual(atomic.LoadInt32(&peak), int32(2))
+}
+
+func (s *AudioBatchSuite) TestTranscribeAudioBatchCopiesReaders() {
+	var paths []string
+	var mu sync.Mutex
+	factory := func(path string, opts AudioOptions) (AudioTranscriptionGenerator, error) {
+		mu.Lock()
+		paths = append(paths, path)
+		mu.Unlock()
+		return &fileTranscriber{path: path}, nil
+	}
+
+	report, err := TranscribeAudioBatch(context.Background(), factory, []AudioBatchSource{
+		{Name: "visit.m4a", Reader: strings.NewReader("visit")},
+		{Name: "empty"},
+	}, AudioOptions{}, AudioBatchOptions{})
+	s.Require().NoError(err)
+	s.Equal("transcript of visit", report.Results[0].Transcript)
+	s.Require().Len(paths, 1)
+	s.Equal(".m4a", filepath.Ext(paths[0]))
+	_, statErr := os.Stat(paths[0])
+	s.True(os.IsNotExist(statErr), "temporary file is removed")
+
+	s.Error(report.Results[1].Err)
+	s.Equal(1, report.Failed)
+
+	_, err = TranscribeAudioBatch(context.Background(), nil, nil, AudioOptions{}, AudioBatchOptions{})
+	s.Error(err)
+}
+
+func (s *AudioBatchSuite) TestConstructorErrorsAreNotRetried() {
+	calls := 0
+	factory := func(path string, opts AudioOptions) (AudioTranscriptionGenerator, error) {
+		calls++
+		return nil, errors.New("bad options")
+	}
+	report, err := TranscribeAudioBatch(context.Background(), factory, []AudioBatchSource{{Path: "a.wav"}}, AudioOptions{}, AudioBatchOptions{})
+	s.Require().NoError(err)
+	s.Equal(1, calls)
+	s.Equal("a.wav", report.Results[0].Name)
+	s.Error(report.Results[0].Err)
+}
@@ -0,0 +1,199 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// BuiltinTool names a tool that the provider runs server side, such as web
+// search. Unlike Tool there is no local handler; the provider executes the
+// call and folds the result into its answer.
+type BuiltinTool string
+
+const (
+	// BuiltinWebSearch lets the model search the web. Providers that support
+	// it report the sources used in MetadataKeyCitations.
+	BuiltinWebSearch BuiltinTool = "web_search"
+	// BuiltinCodeInterpreter lets the model run code in a provider sandbox
+	// (see WithCodeInterpreter). Files it produces are reported in
+	// MetadataKeyFileAnnotations.
+	BuiltinCodeInterpreter BuiltinTool = "code_interpreter"
+	// BuiltinFileSearch lets the model search provider-hosted vector stores
+	// (see WithFileSearch). Cited files are reported in
+	// MetadataKeyFileAnnotations.
+	BuiltinFileSearch BuiltinTool = "file_search"
+)
+
+// CodeInterpreterConfig configures the sandbox for BuiltinCodeInterpreter.
+// With ContainerID empty the provider creates a container per request.
+type CodeInterpreterConfig struct {
+	// ContainerID reuses an existing container; FileIDs and MemoryLimit are
+	// ignored when it is set.
+	ContainerID string
+	// FileIDs are uploaded files made available to the code.
+	FileIDs []string
+	// MemoryLimit is the container memory size, for example "1g" or "4g".
+	MemoryLimit string
+}
+
+// FileSearchConfig configures BuiltinFileSearch.
+type FileSearchConfig struct {
+	// VectorStoreIDs lists the vector stores to search; at least one is
+	// required.
+	VectorStoreIDs []string
+	// MaxResults caps the results per search; zero uses the provider default.
+	MaxResults int
+}
+
+// WithBuiltinTools enables provider-executed tools. It can be passed several
+// times; tools accumulate and duplicates are dropped. Providers that do not
+// support a requested tool return an error unless
+// IgnoreInvalidGeneratorOptions is set.
+func WithBuiltinTools(tools ...BuiltinTool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		for _, tool := range tools {
+			tool = BuiltinTool(strings.TrimSpace(string(tool)))
+			if tool == "" || HasBuiltinTool(*cfg, tool) {
+				continue
+			}
+			cfg.BuiltinTools = append(cfg.BuiltinTools, tool)
+		}
+	})
+}
+
+// WithCodeInterpreter enables BuiltinCodeInterpreter with the given sandbox
+// configuration.
+func WithCodeInterpreter(config CodeInterpreterConfig) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		WithBuiltinTools(BuiltinCodeInterpreter).apply(cfg)
+		cfg.CodeInterpreter = &config
+	})
+}
+
+// WithFileSearch enables BuiltinFileSearch over the given vector stores.
+func WithFileSearch(config FileSearchConfig) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		WithBuiltinTools(BuiltinFileSearch).apply(cfg)
+		cfg.FileSearch = &config
+	})
+}
+
+// HasBuiltinTool reports whether tool is enabled in cfg.
+func HasBuiltinTool(cfg GeneratorConfig, tool BuiltinTool) bool {
+	for _, enabled := range cfg.BuiltinTools {
+		if enabled == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// Citation is a source the model cited in its answer. StartIndex and
+// EndIndex are byte offsets of the cited span in the returned text; both are
+// zero when the provider does not report a span.
+type Citation struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	StartIndex int    `json:"start_index,omitempty"`
+	EndIndex   int    `json:"end_index,omitempty"`
+}
+
+// SetCitations stores citations in meta under MetadataKeyCitations as a JSON
+// array. Nothing is stored when citations is empty.
+func SetCitations(meta GenerationMetadata, citations []Citation) {
+	if meta == nil || len(citations) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(citations)
+	if err != nil {
+		return
+	}
+	meta[MetadataKeyCitations] = string(encoded)
+}
+
+// SetWebSearchQueries stores queries in meta under
+// MetadataKeyWebSearchQueries as a JSON array. Nothing is stored when queries
+// is empty.
+func SetWebSearchQueries(meta GenerationMetadata, queries []string) {
+	if meta == nil || len(queries) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(queries)
+	if err != nil {
+		return
+	}
+	meta[MetadataKeyWebSearchQueries] = string(encoded)
+}
+
+// ParseWebSearchQueries decodes MetadataKeyWebSearchQueries from meta. It
+// returns nil when the key is absent.
+func ParseWebSearchQueries(meta GenerationMetadata) ([]string, error) {
+	raw, ok := meta[MetadataKeyWebSearchQueries]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var queries []string
+	if err := json.Unmarshal([]byte(raw), &queries); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return queries, nil
+}
+
+// ParseCitations decodes MetadataKeyCitations from meta. It returns nil when
+// the key is absent.
+func ParseCitations(meta GenerationMetadata) ([]Citation, error) {
+	raw, ok := meta[MetadataKeyCitations]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var citations []Citation
+	if err := json.Unmarshal([]byte(raw), &citations); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return citations, nil
+}
+
+// FileAnnotation is a file referenced in the answer: a file produced by the
+// code interpreter or a file cited from file search. Type is the provider
+// annotation type (for OpenAI "container_file_citation", "file_citation" or
+// "file_path"). Offsets follow Citation; Index is the position for
+// annotations that mark a point instead of a span.
+type FileAnnotation struct {
+	Type        string `json:"type"`
+	FileID      string `json:"file_id"`
+	Filename    string `json:"filename,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+	StartIndex  int    `json:"start_index,omitempty"`
+	EndIndex    int    `json:"end_index,omitempty"`
+	Index       int    `json:"index,omitempty"`
+}
+
+// SetFileAnnotations stores annotations in meta under
+// MetadataKeyFileAnnotations as a JSON array. Nothing is stored when
+// annotations is empty.
+func SetFileAnnotations(meta GenerationMetadata, annotations []FileAnnotation) {
+	if meta == nil || len(annotations) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(annotations)
+	if err != nil {
+		return
+	}
+	meta[MetadataKeyFileAnnotations] = string(encoded)
+}
+
+// ParseFileAnnotations decodes MetadataKeyFileAnnotations from meta. It
+// returns nil when the key is absent.
+func ParseFileAnnotations(meta GenerationMetadata) ([]FileAnnotation, error) {
+	raw, ok := meta[MetadataKeyFileAnnotations]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var annotations []FileAnnotation
+	if err := json.Unmarshal([]byte(raw), &annotations); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return annotations, nil
+}
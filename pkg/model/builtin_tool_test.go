@@ -0,0 +1,92 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BuiltinToolSuite struct {
+	suite.Suite
+}
+
+func TestBuiltinToolSuite(t *testing.T) {
+	suite.Run(t, new(BuiltinToolSuite))
+}
+
+func (s *BuiltinToolSuite) TestWithBuiltinToolsAccumulatesWithoutDuplicates() {
+	cfg := ResolveGeneratorOpts(
+		WithBuiltinTools(BuiltinWebSearch, " "),
+		WithBuiltinTools(BuiltinWebSearch),
+	)
+
+	s.Equal([]BuiltinTool{BuiltinWebSearch}, cfg.BuiltinTools)
+	s.True(HasBuiltinTool(cfg, BuiltinWebSearch))
+	s.False(HasBuiltinTool(ResolveGeneratorOpts(), BuiltinWebSearch))
+}
+
+func (s *BuiltinToolSuite) TestCitationsRoundTrip() {
+	meta := GenerationMetadata{}
+	SetCitations(meta, nil)
+	s.NotContains(meta, MetadataKeyCitations)
+
+	citations, err := ParseCitations(meta)
+	s.NoError(err)
+	s.Nil(citations)
+
+	want := []Citation{
+		{URL: "https://example.org/a", Title: "A", StartIndex: 3, EndIndex: 9},
+		{URL: "https://example.org/b"},
+	}
+	SetCitations(meta, want)
+	s.JSONEq(`[{"url":"https://example.org/a","title":"A","start_index":3,"end_index":9},{"url":"https://example.org/b"}]`, meta[MetadataKeyCitations])
+
+	citations, err = ParseCitations(meta)
+	s.NoError(err)
+	s.Equal(want, citations)
+
+	meta[MetadataKeyCitations] = "not json"
+	_, err = ParseCitations(meta)
+	s.Error(err)
+}
+
+func (s *BuiltinToolSuite) TestConfiguredToolsEnableBuiltin() {
+	cfg := ResolveGeneratorOpts(
+		WithCodeInterpreter(CodeInterpreterConfig{MemoryLimit: "1g"}),
+		WithFileSearch(FileSearchConfig{VectorStoreIDs: []string{"vs_1"}}),
+		WithBuiltinTools(BuiltinCodeInterpreter),
+	)
+
+	s.Equal([]BuiltinTool{BuiltinCodeInterpreter, BuiltinFileSearch}, cfg.BuiltinTools)
+	s.Require().NotNil(cfg.CodeInterpreter)
+	s.Equal("1g", cfg.CodeInterpreter.MemoryLimit)
+	s.Require().NotNil(cfg.FileSearch)
+	s.Equal([]string{"vs_1"}, cfg.FileSearch.VectorStoreIDs)
+}
+
+func (s *BuiltinToolSuite) TestFileAnnotationsRoundTrip() {
+	meta := GenerationMetadata{}
+	SetFileAnnotations(meta, nil)
+	s.NotContains(meta, MetadataKeyFileAnnotations)
+
+	want := []FileAnnotation{{Type: "file_path", FileID: "file_1", Index: 4}}
+	SetFileAnnotations(meta, want)
+	s.JSONEq(`[{"type":"file_path","file_id":"file_1","index":4}]`, meta[MetadataKeyFileAnnotations])
+
+	annotations, err := ParseFileAnnotations(meta)
+	s.NoError(err)
+	s.Equal(want, annotations)
+}
+
+func (s *BuiltinToolSuite) TestWebSearchQueriesRoundTrip() {
+	meta := GenerationMetadata{}
+	SetWebSearchQueries(meta, nil)
+	s.NotContains(meta, MetadataKeyWebSearchQueries)
+
+	SetWebSearchQueries(meta, []string{"egfr formula", "ckd-epi 2021"})
+	s.JSONEq(`["egfr formula","ckd-epi 2021"]`, meta[MetadataKeyWebSearchQueries])
+
+	queries, err := ParseWebSearchQueries(meta)
+	s.NoError(err)
+	s.Equal([]string{"egfr formula", "ckd-epi 2021"}, queries)
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrToolCallingNotSupported is returned when tools are configured for a model
+// that is registered as not supporting native tool calling.
+var ErrToolCallingNotSupported = errors.New("tool calling is not supported by model")
+
+// ModelCapabilities describes the features a provider model supports natively.
+type ModelCapabilities struct {
+	ToolCalling bool
+}
+
+type capabilityEntry struct {
+	pattern      string
+	capabilities ModelCapabilities
+}
+
+var (
+	capabilityRegistryMu sync.RWMutex
+	capabilityRegistry   = map[string][]capabilityEntry{}
+)
+
+// RegisterModelCapabilities registers capabilities for a provider model.
+// The model may be an exact name or a prefix pattern ending in "*"
+// (for example "gemma*"). Matching is case-insensitive; exact names win
+// over patterns and longer patterns win over shorter ones.
+func RegisterModelCapabilities(provider string, modelPattern string, capabilities ModelCapabilities) {
+	provider = normalizeCapabilityKey(provider)
+	modelPattern = normalizeCapabilityKey(modelPattern)
+	if provider == "" || modelPattern == "" {
+		return
+	}
+
+	capabilityRegistryMu.Lock()
+	defer capabilityRegistryMu.Unlock()
+
+	entries := capabilityRegistry[provider]
+	for i := range entries {
+		if entries[i].pattern == modelPattern {
+			entries[i].capabilities = capabilities
+			return
+		}
+	}
+	capabilityRegistry[provider] = append(entries, capabilityEntry{
+		pattern:      modelPattern,
+		capabilities: capabilities,
+	})
+}
+
+// LookupModelCapabilities returns the registered capabilities for a provider model.
+// The boolean is false when nothing is registered, in which case callers should
+// assume the model supports the feature and let the provider API decide.
+func LookupModelCapabilities(provider string, modelName string) (ModelCapabilities, bool) {
+	provider = normalizeCapabilityKey(provider)
+	modelName = normalizeCapabilityKey(modelName)
+	if provider == "" || modelName == "" {
+		return ModelCapabilities{}, false
+	}
+
+	capabilityRegistryMu.RLock()
+	defer capabilityRegistryMu.RUnlock()
+
+	entries := capabilityRegistry[provider]
+	patterns := make([]string, len(entries))
+	for i, entry := range entries {
+		patterns[i] = entry.pattern
+	}
+	best := matchModelPattern(patterns, modelName)
+	if best < 0 {
+		return ModelCapabilities{}, false
+	}
+	return entries[best].capabilities, true
+}
+
+// matchModelPattern returns the index of the pattern that best matches the
+// normalized modelName, or -1: an exact name wins, then the longest "*"
+// prefix pattern.
+func matchModelPattern(patterns []string, modelName string) int {
+	best := -1
+	bestLength := -1
+	for i, pattern := range patterns {
+		if pattern == modelName {
+			return i
+		}
+
+		prefix, isPattern := strings.CutSuffix(pattern, "*")
+		if !isPattern || !strings.HasPrefix(modelName, prefix) {
+			continue
+		}
+		if len(prefix) > bestLength {
+			best = i
+			bestLength = len(prefix)
+		}
+	}
+	return best
+}
+
+// SupportsToolCalling reports whether a provider model can be sent native tool definitions.
+// Unregistered models are assumed to support tool calling.
+func SupportsToolCalling(provider string, modelName string) bool {
+	capabilities, found := LookupModelCapabilities(provider, modelName)
+	if !found {
+		return true
+	}
+	return capabilities.ToolCalling
+}
+
+func normalizeCapabilityKey(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+func init() {
+	// Local Ollama model families that do not accept the "tools" field on /api/chat.
+	for _, pattern := range []string{
+		"llama2*",
+		"gemma*",
+		"phi",
+		"phi:*",
+		"phi2*",
+		"phi3*",
+		"tinyllama*",
+		"codellama*",
+		"deepseek-r1*",
+		"llava*",
+		"orca-mini*",
+		"vicuna*",
+	} {
+		RegisterModelCapabilities("ollama", pattern, ModelCapabilities{ToolCalling: false})
+	}
+}
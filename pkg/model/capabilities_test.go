@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CapabilitiesSuite struct {
+	suite.Suite
+}
+
+func TestCapabilitiesSuite(t *testing.T) {
+	suite.Run(t, new(CapabilitiesSuite))
+}
+
+func (s *CapabilitiesSuite) TestUnregisteredModelSupportsToolCalling() {
+	_, found := LookupModelCapabilities("unit-test-provider", "some-model")
+	s.False(found)
+	s.True(SupportsToolCalling("unit-test-provider", "some-model"))
+}
+
+func (s *CapabilitiesSuite) TestPrefixPatternMatchesCaseInsensitive() {
+	RegisterModelCapabilities("unit-test-provider", "tiny*", ModelCapabilities{ToolCalling: false})
+
+	s.False(SupportsToolCalling("Unit-Test-Provider", "TINY-model:1b"))
+	s.True(SupportsToolCalling("unit-test-provider", "large-model"))
+}
+
+func (s *CapabilitiesSuite) TestExactAndLongerPatternsWin() {
+	RegisterModelCapabilities("unit-test-provider-2", "family*", ModelCapabilities{ToolCalling: false})
+	RegisterModelCapabilities("unit-test-provider-2", "family-tools*", ModelCapabilities{ToolCalling: true})
+	RegisterModelCapabilities("unit-test-provider-2", "family-tools-mini", ModelCapabilities{ToolCalling: false})
+
+	s.False(SupportsToolCalling("unit-test-provider-2", "family-base"))
+	s.True(SupportsToolCalling("unit-test-provider-2", "family-tools-large"))
+	s.False(SupportsToolCalling("unit-test-provider-2", "family-tools-mini"))
+}
+
+func (s *CapabilitiesSuite) TestRegisterReplacesExistingPattern() {
+	RegisterModelCapabilities("unit-test-provider-3", "model*", ModelCapabilities{ToolCalling: false})
+	RegisterModelCapabilities("unit-test-provider-3", "model*", ModelCapabilities{ToolCalling: true})
+
+	s.True(SupportsToolCalling("unit-test-provider-3", "model-a"))
+}
+
+func (s *CapabilitiesSuite) TestSeededOllamaModels() {
+	s.False(SupportsToolCalling("ollama", "gemma2:2b"))
+	s.False(SupportsToolCalling("ollama", "phi3:mini"))
+	s.True(SupportsToolCalling("ollama", "phi4"))
+	s.True(SupportsToolCalling("ollama", "llama3.1"))
+}
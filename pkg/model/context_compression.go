@@ -0,0 +1,279 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const (
+	// DefaultContextCompressionRatio is the share of a context's estimated
+	// tokens kept when ContextCompressionConfig.TargetRatio is unset.
+	DefaultContextCompressionRatio = 0.5
+	// DefaultContextCompressionMinTokens is the context size, in estimated
+	// tokens, at or below which contexts are left alone when
+	// ContextCompressionConfig.MinTokens is unset.
+	DefaultContextCompressionMinTokens = 100
+)
+
+// ContextCompressionConfig configures WithContextCompression.
+type ContextCompressionConfig struct {
+	// TargetRatio is the share of each context's estimated tokens to keep
+	// (default DefaultContextCompressionRatio). 1 or more disables
+	// compression.
+	TargetRatio float64
+	// MinTokens leaves contexts of at most this many estimated tokens
+	// unchanged (default DefaultContextCompressionMinTokens).
+	MinTokens int
+	// Compressor, when set, asks a model to compress each context instead of
+	// using the extractive heuristic, typically a small, cheap model. Its
+	// usage is not added to the generation metadata.
+	Compressor NewStringContentGeneratorFunc
+	// CompressorOptions are passed to Compressor.
+	CompressorOptions []GeneratorOption
+}
+
+// WithContextCompression shrinks human prompt contexts (typically RAG
+// content) during context assembly, after WithContextDedup. By default an
+// LLMLingua-style heuristic keeps the sentences most relevant to the prompt:
+// those sharing its terms, rare terms of the context and numbers score
+// highest, and the best ones that fit the target are kept in their original
+// order. With a Compressor a model rewrites each context instead. System and
+// assistant contexts, and images, are never changed. The estimated tokens
+// removed are reported in MetadataKeyContextTokensSaved.
+func WithContextCompression(config ContextCompressionConfig) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ContextCompression = &config
+	})
+}
+
+// CompressPromptContexts applies WithContextCompression to contexts, using
+// prompt to judge relevance, and records the estimated tokens saved in meta.
+// Without the option it returns contexts unchanged. Compressed contexts are
+// copies; the inputs are not modified.
+func CompressPromptContexts(ctx context.Context, meta GenerationMetadata, cfg GeneratorConfig, prompt string, contexts []*PromptContext) ([]*PromptContext, error) {
+	if cfg.ContextCompression == nil {
+		return contexts, nil
+	}
+	config := *cfg.ContextCompression
+	ratio := config.TargetRatio
+	if ratio <= 0 {
+		ratio = DefaultContextCompressionRatio
+	}
+	if ratio >= 1 {
+		return contexts, nil
+	}
+	minTokens := config.MinTokens
+	if minTokens <= 0 {
+		minTokens = DefaultContextCompressionMinTokens
+	}
+
+	saved := 0
+	out := make([]*PromptContext, len(contexts))
+	for i, contextItem := range contexts {
+		out[i] = contextItem
+		if contextItem == nil || contextItem.MessageType != ContextMessageTypeHuman {
+			continue
+		}
+		tokens := EstimateTokens(contextItem.Content)
+		if tokens <= minTokens {
+			continue
+		}
+		budget := max(1, int(math.Ceil(float64(tokens)*ratio)))
+
+		var compressed string
+		if config.Compressor != nil {
+			var err error
+			compressed, err = compressWithModel(ctx, config, prompt, contextItem.Content, budget)
+			if err != nil {
+				return nil, utils.WrapIfNotNil(fmt.Errorf("compress context %d: %w", i, err))
+			}
+		} else {
+			compressed = compressExtractive(prompt, contextItem.Content, budget)
+		}
+		compressedTokens := EstimateTokens(compressed)
+		if strings.TrimSpace(compressed) == "" || compressedTokens >= tokens {
+			continue
+		}
+
+		copied := *contextItem
+		copied.Content = compressed
+		out[i] = &copied
+		saved += tokens - compressedTokens
+	}
+
+	if meta != nil {
+		meta[MetadataKeyContextTokensSaved] = strconv.Itoa(saved)
+	}
+	return out, nil
+}
+
+func compressWithModel(ctx context.Context, config ContextCompressionConfig, prompt string, content string, budget int) (string, error) {
+	instruction := fmt.Sprintf(
+		"Compress the context above to at most %d words for answering the question below. "+
+			"Keep every fact, number, name, date and unit that could matter; drop filler and repetition. "+
+			"Reply with the compressed context only.\n\nQuestion: %s",
+		max(1, budget*3/4), prompt,
+	)
+	gen, err := config.Compressor(instruction, config.CompressorOptions...)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	gen.AddPromptContext(ctx, ContextMessageTypeHuman, content)
+	compressed, _, err := gen.Generate(ctx)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return strings.TrimSpace(compressed), nil
+}
+
+type compressionSentence struct {
+	paragraph int
+	text      string
+	tokens    int
+	score     float64
+}
+
+// compressExtractive keeps the highest-scoring sentences of content that fit
+// in budget estimated tokens, in their original order. The best sentence is
+// always kept, cut to budget when it is too long on its own.
+func compressExtractive(prompt string, content string, budget int) string {
+	sentences := splitCompressionSentences(content)
+	if len(sentences) == 0 {
+		return ""
+	}
+
+	queryTerms := map[string]bool{}
+	for _, term := range compressionTerms(prompt) {
+		queryTerms[term] = true
+	}
+	sentenceTerms := make([][]string, len(sentences))
+	documentFrequency := map[string]int{}
+	for i, sentence := range sentences {
+		seen := map[string]bool{}
+		for _, term := range compressionTerms(sentence.text) {
+			if !seen[term] {
+				seen[term] = true
+				sentenceTerms[i] = append(sentenceTerms[i], term)
+				documentFrequency[term]++
+			}
+		}
+	}
+	for i := range sentences {
+		score := 0.0
+		for _, term := range sentenceTerms[i] {
+			score += math.Log(1 + float64(len(sentences))/float64(documentFrequency[term]))
+			if queryTerms[term] {
+				score += 2
+			}
+			if strings.IndexFunc(term, unicode.IsDigit) >= 0 {
+				score++
+			}
+		}
+		sentences[i].score = score / math.Sqrt(float64(len(strings.Fields(sentences[i].text))))
+	}
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sentences[order[a]].score > sentences[order[b]].score
+	})
+
+	kept := make([]bool, len(sentences))
+	used := 0
+	for _, index := range order {
+		if used+sentences[index].tokens <= budget {
+			kept[index] = true
+			used += sentences[index].tokens
+		}
+	}
+	if used == 0 {
+		best := sentences[order[0]]
+		return truncateToTokens(best.text, budget)
+	}
+
+	var b strings.Builder
+	paragraph := -1
+	for i, sentence := range sentences {
+		if !kept[i] {
+			continue
+		}
+		switch {
+		case paragraph == -1:
+		case sentence.paragraph != paragraph:
+			b.WriteString("\n\n")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(sentence.text)
+		paragraph = sentence.paragraph
+	}
+	return b.String()
+}
+
+// splitCompressionSentences splits content into sentences, remembering the
+// paragraph (blank-line separated block) of each.
+func splitCompressionSentences(content string) []compressionSentence {
+	var out []compressionSentence
+	for paragraph, block := range strings.Split(content, "\n\n") {
+		text := strings.Join(strings.Fields(block), " ")
+		start := 0
+		for i := 0; i < len(text); i++ {
+			end := i + 1
+			if !strings.ContainsRune(".!?", rune(text[i])) || (end < len(text) && text[end] != ' ') {
+				if end < len(text) {
+					continue
+				}
+			}
+			sentence := strings.TrimSpace(text[start:end])
+			if sentence != "" {
+				out = append(out, compressionSentence{paragraph: paragraph, text: sentence, tokens: EstimateTokens(sentence)})
+			}
+			start = end
+		}
+	}
+	return out
+}
+
+// compressionTerms returns the lower-cased words and numbers of text, minus
+// common function words.
+func compressionTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+	out := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.Trim(word, ".")
+		if word != "" && !compressionStopWords[word] {
+			out = append(out, word)
+		}
+	}
+	return out
+}
+
+func truncateToTokens(text string, tokens int) string {
+	words := strings.Fields(text)
+	for len(words) > 1 && EstimateTokens(strings.Join(words, " ")) > tokens {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+var compressionStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "been": true,
+	"but": true, "by": true, "can": true, "did": true, "do": true, "does": true, "for": true, "from": true,
+	"had": true, "has": true, "have": true, "he": true, "her": true, "his": true, "how": true, "i": true,
+	"if": true, "in": true, "into": true, "is": true, "it": true, "its": true, "of": true, "on": true,
+	"or": true, "she": true, "so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "to": true, "was": true, "we": true,
+	"were": true, "what": true, "when": true, "which": true, "who": true, "will": true, "with": true,
+	"would": true, "you": true,
+}
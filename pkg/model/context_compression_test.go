@@ -0,0 +1,127 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextCompressionSuite struct {
+	suite.Suite
+}
+
+func TestContextCompressionSuite(t *testing.T) {
+	suite.Run(t, new(ContextCompressionSuite))
+}
+
+const compressionLabNote = "The patient attended clinic on Monday morning with a relative. " +
+	"Parking at the clinic was difficult and the waiting room was busy as usual. " +
+	"Latest creatinine was 1.6 mg/dL and eGFR was 48, down from 55 in March. " +
+	"The relative asked about the cafeteria opening hours and was given a leaflet. " +
+	"Potassium was 5.1 mmol/L on the same panel. " +
+	"The weather was mild and the patient walked to the bus stop afterwards.\n\n" +
+	"Plan: repeat renal panel in four weeks and review lisinopril dose."
+
+func (s *ContextCompressionSuite) TestDisabledReturnsContextsUnchanged() {
+	contexts := []*PromptContext{{MessageType: ContextMessageTypeHuman, Content: compressionLabNote}}
+	meta := GenerationMetadata{}
+	out, err := CompressPromptContexts(context.Background(), meta, ResolveGeneratorOpts(), "What was the eGFR?", contexts)
+	s.Require().NoError(err)
+	s.Equal(contexts, out)
+	s.NotContains(meta, MetadataKeyContextTokensSaved)
+
+	cfg := ResolveGeneratorOpts(WithContextCompression(ContextCompressionConfig{TargetRatio: 1, MinTokens: 1}))
+	out, err = CompressPromptContexts(context.Background(), meta, cfg, "What was the eGFR?", contexts)
+	s.Require().NoError(err)
+	s.Equal(contexts, out)
+}
+
+func (s *ContextCompressionSuite) TestExtractiveKeepsRelevantSentencesInOrder() {
+	original := &PromptContext{MessageType: ContextMessageTypeHuman, Content: compressionLabNote}
+	contexts := []*PromptContext{original}
+	meta := GenerationMetadata{}
+	cfg := ResolveGeneratorOpts(WithContextCompression(ContextCompressionConfig{TargetRatio: 0.4, MinTokens: 10}))
+
+	out, err := CompressPromptContexts(context.Background(), meta, cfg, "What were the creatinine and eGFR results?", contexts)
+	s.Require().NoError(err)
+	s.Require().Len(out, 1)
+	s.Equal(compressionLabNote, original.Content)
+
+	compressed := out[0].Content
+	s.Contains(compressed, "Latest creatinine was 1.6 mg/dL and eGFR was 48")
+	s.NotContains(compressed, "cafeteria")
+	s.NotContains(compressed, "weather")
+	s.LessOrEqual(EstimateTokens(compressed), EstimateTokens(compressionLabNote)*4/10+1)
+	saved := EstimateTokens(compressionLabNote) - EstimateTokens(compressed)
+	s.Equal(ContextMessageTypeHuman, out[0].MessageType)
+	s.Equal(strconv.Itoa(saved), meta[MetadataKeyContextTokensSaved])
+}
+
+func (s *ContextCompressionSuite) TestSkipsShortAndNonHumanContexts() {
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeSystem, Content: compressionLabNote},
+		{MessageType: ContextMessageTypeAssistant, Content: compressionLabNote},
+		{MessageType: ContextMessageTypeHuman, Content: "eGFR 48."},
+		nil,
+	}
+	meta := GenerationMetadata{}
+	cfg := ResolveGeneratorOpts(WithContextCompression(ContextCompressionConfig{}))
+
+	out, err := CompressPromptContexts(context.Background(), meta, cfg, "eGFR?", contexts)
+	s.Require().NoError(err)
+	s.Equal(contexts, out)
+	s.Equal("0", meta[MetadataKeyContextTokensSaved])
+}
+
+func (s *ContextCompressionSuite) TestLongSingleSentenceIsTruncated() {
+	content := "eGFR " + strings.Repeat("stable ", 200) + "today."
+	cfg := ResolveGeneratorOpts(WithContextCompression(ContextCompressionConfig{TargetRatio: 0.1, MinTokens: 10}))
+
+	out, err := CompressPromptContexts(context.Background(), nil, cfg, "eGFR?", []*PromptContext{{MessageType: ContextMessageTypeHuman, Content: content}})
+	s.Require().NoError(err)
+	s.True(strings.HasPrefix(out[0].Content, "eGFR stable"))
+	s.LessOrEqual(EstimateTokens(out[0].Content), EstimateTokens(content)/10+1)
+}
+
+func (s *ContextCompressionSuite) TestCompressorModel() {
+	var gen *recordingGenerator
+	var gotOpts []GeneratorOption
+	var instruction string
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		instruction = prompt
+		gotOpts = opts
+		gen = &recordingGenerator{reply: "  creatinine 1.6, eGFR 48 (55 in March)  "}
+		return gen, nil
+	}
+	cfg := ResolveGeneratorOpts(WithContextCompression(ContextCompressionConfig{
+		TargetRatio:       0.2,
+		MinTokens:         10,
+		Compressor:        factory,
+		CompressorOptions: []GeneratorOption{WithModel("small")},
+	}))
+	meta := GenerationMetadata{}
+
+	out, err := CompressPromptContexts(context.Background(), meta, cfg, "What was the eGFR?", []*PromptContext{{MessageType: ContextMessageTypeHuman, Content: compressionLabNote}})
+	s.Require().NoError(err)
+	s.Equal("creatinine 1.6, eGFR 48 (55 in March)", out[0].Content)
+	s.Contains(instruction, "Question: What was the eGFR?")
+	s.Len(gotOpts, 1)
+	s.Require().Len(gen.contexts, 1)
+	s.Equal(compressionLabNote, gen.contexts[0].Content)
+	s.NotEqual("0", meta[MetadataKeyContextTokensSaved])
+}
+
+func (s *ContextCompressionSuite) TestCompressorErrorIsReturned() {
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{err: errors.New("boom")}, nil
+	}
+	cfg := ResolveGeneratorOpts(WithContextCompression(ContextCompressionConfig{MinTokens: 10, Compressor: factory}))
+
+	_, err := CompressPromptContexts(context.Background(), nil, cfg, "eGFR?", []*PromptContext{{MessageType: ContextMessageTypeHuman, Content: compressionLabNote}})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "boom")
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// DefaultContextDedupSimilarity is the cosine similarity at or above which
+// two contexts count as near-identical when ContextDedupConfig.Embedder is set.
+const DefaultContextDedupSimilarity = 0.95
+
+// ContextDedupConfig configures WithContextDedup.
+type ContextDedupConfig struct {
+	// Embedder, when set, also drops contexts whose embedding is
+	// near-identical to an earlier kept context of the same message type.
+	// Its usage is not added to the generation metadata.
+	Embedder EmbeddingGenerator
+	// SimilarityThreshold is the cosine similarity treated as near-identical
+	// (default DefaultContextDedupSimilarity).
+	SimilarityThreshold float64
+}
+
+// WithContextDedup drops repeated prompt contexts during context assembly,
+// keeping the first occurrence. Contexts are duplicates when they have the
+// same message type and the same content after collapsing whitespace, or,
+// with an Embedder, when their embeddings are near-identical. The number of
+// dropped contexts is reported in MetadataKeyDedupedContexts.
+func WithContextDedup(config ContextDedupConfig) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ContextDedup = &config
+	})
+}
+
+// DedupPromptContexts applies WithContextDedup to contexts and records the
+// number of dropped contexts in meta. Without the option it returns contexts
+// unchanged. Empty contexts are kept for the provider to skip.
+func DedupPromptContexts(ctx context.Context, meta GenerationMetadata, cfg GeneratorConfig, contexts []*PromptContext) ([]*PromptContext, error) {
+	if cfg.ContextDedup == nil {
+		return contexts, nil
+	}
+
+	seen := make(map[[sha256.Size]byte]struct{}, len(contexts))
+	kept := make([]*PromptContext, 0, len(contexts))
+	for _, contextItem := range contexts {
+		if !contextItem.HasContent() {
+			kept = append(kept, contextItem)
+			continue
+		}
+		key := contextDedupKey(contextItem)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		kept = append(kept, contextItem)
+	}
+
+	if cfg.ContextDedup.Embedder != nil {
+		var err error
+		kept, err = dedupSimilarContexts(ctx, *cfg.ContextDedup, kept)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+	}
+
+	if meta != nil {
+		meta[MetadataKeyDedupedContexts] = strconv.Itoa(len(contexts) - len(kept))
+	}
+	return kept, nil
+}
+
+// contextDedupKey hashes the message type, the whitespace-collapsed content
+// and any images of contextItem.
+func contextDedupKey(contextItem *PromptContext) [sha256.Size]byte {
+	hash := sha256.New()
+	hash.Write([]byte(string(contextItem.MessageType) + "\x00" + strings.Join(strings.Fields(contextItem.Content), " ")))
+	for _, image := range contextItem.Images {
+		hash.Write([]byte("\x00" + image.MIMEType + "\x00" + image.URL + "\x00"))
+		hash.Write(image.Data)
+	}
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+	return key
+}
+
+func dedupSimilarContexts(ctx context.Context, config ContextDedupConfig, contexts []*PromptContext) ([]*PromptContext, error) {
+	threshold := config.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = DefaultContextDedupSimilarity
+	}
+
+	positions := make([]int, 0, len(contexts))
+	inputs := make([]string, 0, len(contexts))
+	for i, contextItem := range contexts {
+		if contextItem == nil || strings.TrimSpace(contextItem.Content) == "" || len(contextItem.Images) > 0 {
+			continue
+		}
+		positions = append(positions, i)
+		inputs = append(inputs, contextItem.Content)
+	}
+	if len(inputs) < 2 {
+		return contexts, nil
+	}
+
+	vectors, _, err := config.Embedder.GenerateBatch(ctx, inputs)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if len(vectors) != len(inputs) {
+		return nil, utils.WrapIfNotNil(fmt.Errorf("context dedup embedder returned %d vectors for %d contexts", len(vectors), len(inputs)))
+	}
+
+	dropped := make(map[int]bool)
+	for i := range positions {
+		for j := 0; j < i; j++ {
+			if dropped[positions[j]] || contexts[positions[i]].MessageType != contexts[positions[j]].MessageType {
+				continue
+			}
+			similarity, err := cosineSimilarity(vectors[i], vectors[j])
+			if err != nil {
+				return nil, utils.WrapIfNotNil(err)
+			}
+			if similarity >= threshold {
+				dropped[positions[i]] = true
+				break
+			}
+		}
+	}
+
+	kept := make([]*PromptContext, 0, len(contexts)-len(dropped))
+	for i, contextItem := range contexts {
+		if !dropped[i] {
+			kept = append(kept, contextItem)
+		}
+	}
+	return kept, nil
+}
+
+func cosineSimilarity(a, b EmbeddingVector) (float64, error) {
+	if len(a) != len(b) {
+		return 0, utils.WrapIfNotNil(errors.New("context dedup embeddings have different dimensions"))
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0, nil
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextDedupSuite struct {
+	suite.Suite
+}
+
+func TestContextDedupSuite(t *testing.T) {
+	suite.Run(t, new(ContextDedupSuite))
+}
+
+type fakeDedupEmbedder struct {
+	vectors map[string]EmbeddingVector
+	inputs  []string
+	err     error
+}
+
+func (e *fakeDedupEmbedder) Generate(ctx context.Context, input string) (EmbeddingVector, GenerationMetadata, error) {
+	return e.vectors[input], GenerationMetadata{}, e.err
+}
+
+func (e *fakeDedupEmbedder) GenerateBatch(ctx context.Context, inputs []string) (EmbeddingVectors, GenerationMetadata, error) {
+	e.inputs = append(e.inputs, inputs...)
+	if e.err != nil {
+		return nil, nil, e.err
+	}
+	out := make(EmbeddingVectors, 0, len(inputs))
+	for _, input := range inputs {
+		out = append(out, e.vectors[input])
+	}
+	return out, GenerationMetadata{}, nil
+}
+
+func (s *ContextDedupSuite) TestDisabledReturnsContextsUnchanged() {
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeHuman, Content: "chunk"},
+		{MessageType: ContextMessageTypeHuman, Content: "chunk"},
+	}
+	meta := GenerationMetadata{}
+	out, err := DedupPromptContexts(context.Background(), meta, ResolveGeneratorOpts(), contexts)
+	s.Require().NoError(err)
+	s.Equal(contexts, out)
+	s.NotContains(meta, MetadataKeyDedupedContexts)
+}
+
+func (s *ContextDedupSuite) TestExactDuplicatesIgnoreWhitespaceButNotMessageType() {
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeHuman, Content: "eGFR 48\non 2026-01-02"},
+		{MessageType: ContextMessageTypeSystem, Content: "eGFR 48 on 2026-01-02"},
+		{MessageType: ContextMessageTypeHuman, Content: "  eGFR 48   on 2026-01-02 "},
+		{MessageType: ContextMessageTypeHuman, Content: "Creatinine 1.6"},
+	}
+	meta := GenerationMetadata{}
+	out, err := DedupPromptContexts(context.Background(), meta, ResolveGeneratorOpts(WithContextDedup(ContextDedupConfig{})), contexts)
+	s.Require().NoError(err)
+	s.Equal([]*PromptContext{contexts[0], contexts[1], contexts[3]}, out)
+	s.Equal("1", meta[MetadataKeyDedupedContexts])
+}
+
+func (s *ContextDedupSuite) TestEmbedderDropsNearIdenticalContexts() {
+	embedder := &fakeDedupEmbedder{vectors: map[string]EmbeddingVector{
+		"eGFR was 48.":         {1, 0, 0},
+		"The eGFR was 48.":     {0.99, 0.05, 0},
+		"Creatinine rose.":     {0, 1, 0},
+		"Be brief about eGFR.": {1, 0, 0},
+	}}
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeHuman, Content: "eGFR was 48."},
+		{MessageType: ContextMessageTypeHuman, Content: "eGFR was 48."},
+		{MessageType: ContextMessageTypeHuman, Content: "The eGFR was 48."},
+		{MessageType: ContextMessageTypeHuman, Content: "Creatinine rose."},
+		{MessageType: ContextMessageTypeSystem, Content: "Be brief about eGFR."},
+	}
+	meta := GenerationMetadata{}
+	out, err := DedupPromptContexts(context.Background(), meta,
+		ResolveGeneratorOpts(WithContextDedup(ContextDedupConfig{Embedder: embedder})), contexts)
+	s.Require().NoError(err)
+	s.Equal([]*PromptContext{contexts[0], contexts[3], contexts[4]}, out)
+	s.Equal("2", meta[MetadataKeyDedupedContexts])
+	s.Len(embedder.inputs, 4, "exact duplicates are dropped before embedding")
+
+	out, err = DedupPromptContexts(context.Background(), GenerationMetadata{},
+		ResolveGeneratorOpts(WithContextDedup(ContextDedupConfig{Embedder: embedder, SimilarityThreshold: 0.9999})), contexts)
+	s.Require().NoError(err)
+	s.Len(out, 4)
+}
+
+func (s *ContextDedupSuite) TestEmbedderErrors() {
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeHuman, Content: "a"},
+		{MessageType: ContextMessageTypeHuman, Content: "b"},
+	}
+
+	_, err := DedupPromptContexts(context.Background(), nil,
+		ResolveGeneratorOpts(WithContextDedup(ContextDedupConfig{Embedder: &fakeDedupEmbedder{err: errors.New("embed failed")}})), contexts)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "embed failed")
+
+	_, err = DedupPromptContexts(context.Background(), nil,
+		ResolveGeneratorOpts(WithContextDedup(ContextDedupConfig{Embedder: &fakeDedupEmbedder{vectors: map[string]EmbeddingVector{"a": {1}, "b": {1, 0}}}})), contexts)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "different dimensions")
+}
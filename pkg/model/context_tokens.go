@@ -0,0 +1,88 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// estimatedCharsPerToken is the characters-per-token ratio used by
+// EstimateTokens. It matches common BPE tokenizers on English prose.
+const estimatedCharsPerToken = 4
+
+// ContextTokens is the estimated size of one message in the assembled
+// prompt. Index is the position among the prompt contexts (after providers
+// ran, WithContextDedup and WithContextCompression) and is -1 for the final
+// prompt.
+type ContextTokens struct {
+	Index       int                `json:"index"`
+	MessageType ContextMessageType `json:"message_type"`
+	Tokens      int                `json:"tokens"`
+}
+
+// WithContextTokenAccounting reports the estimated tokens of each prompt
+// context and of the prompt in MetadataKeyContextTokens, to show which
+// context (for example which RAG chunk) uses the input budget.
+func WithContextTokenAccounting(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ContextTokenAccounting = enabled
+	})
+}
+
+// EstimateTokens approximates the tokens text uses, at about four characters
+// per token. Provider tokenizers differ, so use it to compare messages rather
+// than to predict billed usage.
+func EstimateTokens(text string) int {
+	chars := utf8.RuneCountInString(text)
+	return (chars + estimatedCharsPerToken - 1) / estimatedCharsPerToken
+}
+
+// EstimateContextTokens estimates each non-empty context and the prompt. The
+// prompt is the last entry, with Index -1 and MessageType human.
+func EstimateContextTokens(prompt string, contexts []*PromptContext) []ContextTokens {
+	out := make([]ContextTokens, 0, len(contexts)+1)
+	for i, contextItem := range contexts {
+		if !contextItem.HasContent() {
+			continue
+		}
+		out = append(out, ContextTokens{
+			Index:       i,
+			MessageType: contextItem.MessageType,
+			Tokens:      EstimateTokens(contextItem.Content),
+		})
+	}
+	return append(out, ContextTokens{
+		Index:       -1,
+		MessageType: ContextMessageTypeHuman,
+		Tokens:      EstimateTokens(prompt),
+	})
+}
+
+// SetContextTokens stores the EstimateContextTokens breakdown in meta under
+// MetadataKeyContextTokens when cfg enables WithContextTokenAccounting.
+func SetContextTokens(meta GenerationMetadata, cfg GeneratorConfig, prompt string, contexts []*PromptContext) {
+	if meta == nil || !cfg.ContextTokenAccounting {
+		return
+	}
+	encoded, err := json.Marshal(EstimateContextTokens(prompt, contexts))
+	if err != nil {
+		return
+	}
+	meta[MetadataKeyContextTokens] = string(encoded)
+}
+
+// ParseContextTokens decodes MetadataKeyContextTokens from meta. It returns
+// nil when the key is absent.
+func ParseContextTokens(meta GenerationMetadata) ([]ContextTokens, error) {
+	raw, ok := meta[MetadataKeyContextTokens]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var entries []ContextTokens
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return entries, nil
+}
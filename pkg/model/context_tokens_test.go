@@ -0,0 +1,59 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextTokensSuite struct {
+	suite.Suite
+}
+
+func TestContextTokensSuite(t *testing.T) {
+	suite.Run(t, new(ContextTokensSuite))
+}
+
+func (s *ContextTokensSuite) TestEstimateTokens() {
+	s.Equal(0, EstimateTokens(""))
+	s.Equal(1, EstimateTokens("abc"))
+	s.Equal(2, EstimateTokens("abcde"))
+	s.Equal(1, EstimateTokens("ééé"))
+}
+
+func (s *ContextTokensSuite) TestBreakdownSkipsEmptyContextsAndEndsWithPrompt() {
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeSystem, Content: "Be brief."},
+		nil,
+		{MessageType: ContextMessageTypeHuman, Content: "  "},
+		{MessageType: ContextMessageTypeHuman, Content: strings.Repeat("x", 400)},
+	}
+
+	s.Equal([]ContextTokens{
+		{Index: 0, MessageType: ContextMessageTypeSystem, Tokens: 3},
+		{Index: 3, MessageType: ContextMessageTypeHuman, Tokens: 100},
+		{Index: -1, MessageType: ContextMessageTypeHuman, Tokens: 2},
+	}, EstimateContextTokens("Summary?", contexts))
+}
+
+func (s *ContextTokensSuite) TestSetOnlyWhenEnabled() {
+	contexts := []*PromptContext{{MessageType: ContextMessageTypeHuman, Content: "chunk"}}
+
+	meta := GenerationMetadata{}
+	SetContextTokens(meta, ResolveGeneratorOpts(), "prompt", contexts)
+	s.NotContains(meta, MetadataKeyContextTokens)
+	entries, err := ParseContextTokens(meta)
+	s.NoError(err)
+	s.Nil(entries)
+
+	SetContextTokens(meta, ResolveGeneratorOpts(WithContextTokenAccounting(true)), "prompt", contexts)
+	s.JSONEq(`[{"index":0,"message_type":"human","tokens":2},{"index":-1,"message_type":"human","tokens":2}]`, meta[MetadataKeyContextTokens])
+	entries, err = ParseContextTokens(meta)
+	s.NoError(err)
+	s.Len(entries, 2)
+
+	meta[MetadataKeyContextTokens] = "["
+	_, err = ParseContextTokens(meta)
+	s.Error(err)
+}
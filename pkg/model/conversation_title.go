@@ -0,0 +1,123 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const (
+	// maxConversationTitleRunes caps titles from models that ignore the
+	// requested length.
+	maxConversationTitleRunes = 80
+	// maxTitleMessageRunes clips each message in the title prompt so long
+	// conversations stay cheap to title.
+	maxTitleMessageRunes = 2000
+)
+
+// ConversationTitle is a short title and summary of a conversation, for
+// example for a chat application's conversation list.
+type ConversationTitle struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+// GenerateConversationTitle asks a model from factory and opts for a title of
+// at most a few words and a one or two sentence summary of messages. System
+// messages are left out and long messages are clipped. Pass WithModel to use
+// a cheaper model than the conversation itself.
+func GenerateConversationTitle(
+	ctx context.Context,
+	factory NewStringContentGeneratorFunc,
+	messages []ChatMessage,
+	opts ...GeneratorOption,
+) (ConversationTitle, GenerationMetadata, error) {
+	if factory == nil {
+		return ConversationTitle{}, nil, utils.WrapIfNotNil(errors.New("generator factory is required"))
+	}
+
+	var transcript strings.Builder
+	for _, message := range messages {
+		content := strings.TrimSpace(message.Content)
+		if message.Role == ContextMessageTypeSystem || content == "" {
+			continue
+		}
+		if utf8.RuneCountInString(content) > maxTitleMessageRunes {
+			content = string([]rune(content)[:maxTitleMessageRunes]) + "..."
+		}
+		transcript.WriteString(string(message.Role) + ": " + content + "\n")
+	}
+	if transcript.Len() == 0 {
+		return ConversationTitle{}, nil, utils.WrapIfNotNil(errors.New("conversation has no messages to title"))
+	}
+
+	prompt := "Write a title of at most six words and a summary of one or two sentences for the conversation below. " +
+		"Answer with JSON only, in the form {\"title\": \"...\", \"summary\": \"...\"}.\n\nConversation:\n" + transcript.String()
+	gen, err := factory(prompt, opts...)
+	if err != nil {
+		return ConversationTitle{}, nil, utils.WrapIfNotNil(err)
+	}
+	reply, meta, err := gen.Generate(ctx)
+	if err != nil {
+		return ConversationTitle{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	title := parseConversationTitle(reply)
+	if title.Title == "" {
+		return ConversationTitle{}, meta, utils.WrapIfNotNil(errors.New("model returned no conversation title"))
+	}
+	return title, meta, nil
+}
+
+// GenerateTitle titles the session history with the session's factory. opts
+// are applied after the session options, so WithModel selects the model used
+// for the title.
+func (s *ChatSession) GenerateTitle(ctx context.Context, opts ...GeneratorOption) (ConversationTitle, GenerationMetadata, error) {
+	s.mu.Lock()
+	factory := s.factory
+	titleOpts := append(append([]GeneratorOption(nil), s.opts...), opts...)
+	history := append([]ChatMessage(nil), s.history...)
+	s.mu.Unlock()
+
+	title, meta, err := GenerateConversationTitle(ctx, factory, history, titleOpts...)
+	return title, meta, utils.WrapIfNotNil(err)
+}
+
+// ChatMessagesFromHistory converts an exported ConversationHistory to chat
+// messages, rendering tool calls and results as text like ImportHistory.
+func ChatMessagesFromHistory(history ConversationHistory) []ChatMessage {
+	messages := make([]ChatMessage, 0, len(history.Messages))
+	for _, message := range history.Messages {
+		role, content := historyMessageAsContext(message)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		messages = append(messages, ChatMessage{Role: role, Content: content})
+	}
+	return messages
+}
+
+// parseConversationTitle reads the requested JSON, falling back to the first
+// line as the title and the rest as the summary for models that answer in
+// plain text.
+func parseConversationTitle(reply string) ConversationTitle {
+	var title ConversationTitle
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end <= start || json.Unmarshal([]byte(reply[start:end+1]), &title) != nil {
+		lines := strings.SplitN(strings.TrimSpace(reply), "\n", 2)
+		title = ConversationTitle{Title: strings.TrimPrefix(lines[0], "Title:")}
+		if len(lines) > 1 {
+			title.Summary = strings.TrimPrefix(strings.TrimSpace(lines[1]), "Summary:")
+		}
+	}
+
+	title.Title = strings.Trim(strings.TrimSpace(title.Title), "\"'*#")
+	title.Title = strings.TrimSuffix(strings.TrimSpace(title.Title), ".")
+	title.Title, _ = MaxLength(maxConversationTitleRunes)(title.Title)
+	title.Summary = strings.TrimSpace(title.Summary)
+	return title
+}
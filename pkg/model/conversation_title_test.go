@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConversationTitleSuite struct {
+	suite.Suite
+}
+
+func TestConversationTitleSuite(t *testing.T) {
+	suite.Run(t, new(ConversationTitleSuite))
+}
+
+func (s *ConversationTitleSuite) TestGeneratesTitleFromJSONReply() {
+	var prompt string
+	factory := func(p string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		prompt = p
+		return &recordingGenerator{reply: "```json\n{\"title\": \"Lowering sodium intake.\", \"summary\": \" Tips for a low-salt diet. \"}\n```"}, nil
+	}
+
+	title, meta, err := GenerateConversationTitle(context.Background(), factory, []ChatMessage{
+		{Role: ContextMessageTypeSystem, Content: "You are a dietitian."},
+		{Role: ContextMessageTypeHuman, Content: "How do I eat less salt?"},
+		{Role: ContextMessageTypeAssistant, Content: "Cook at home and read labels."},
+	})
+	s.Require().NoError(err)
+	s.Equal(ConversationTitle{Title: "Lowering sodium intake", Summary: "Tips for a low-salt diet."}, title)
+	s.Equal("recording", meta[MetadataKeyProvider])
+	s.Contains(prompt, "human: How do I eat less salt?\nassistant: Cook at home and read labels.\n")
+	s.NotContains(prompt, "dietitian")
+}
+
+func (s *ConversationTitleSuite) TestFallsBackToPlainTextReply() {
+	factory := func(p string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{reply: "Title: \"Dialysis schedule\"\nSummary: Moving sessions to mornings."}, nil
+	}
+
+	title, _, err := GenerateConversationTitle(context.Background(), factory, []ChatMessage{{Role: ContextMessageTypeHuman, Content: "Can I dialyze mornings?"}})
+	s.Require().NoError(err)
+	s.Equal(ConversationTitle{Title: "Dialysis schedule", Summary: "Moving sessions to mornings."}, title)
+}
+
+func (s *ConversationTitleSuite) TestClipsLongMessages() {
+	var prompt string
+	factory := func(p string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		prompt = p
+		return &recordingGenerator{reply: `{"title":"Long","summary":""}`}, nil
+	}
+
+	_, _, err := GenerateConversationTitle(context.Background(), factory, []ChatMessage{{Role: ContextMessageTypeHuman, Content: strings.Repeat("a", maxTitleMessageRunes+50)}})
+	s.Require().NoError(err)
+	s.Contains(prompt, strings.Repeat("a", maxTitleMessageRunes)+"...")
+	s.NotContains(prompt, strings.Repeat("a", maxTitleMessageRunes+1))
+}
+
+func (s *ConversationTitleSuite) TestErrors() {
+	_, _, err := GenerateConversationTitle(context.Background(), nil, []ChatMessage{{Role: ContextMessageTypeHuman, Content: "hi"}})
+	s.Error(err)
+
+	factory := func(p string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{reply: "   "}, nil
+	}
+	_, _, err = GenerateConversationTitle(context.Background(), factory, []ChatMessage{{Role: ContextMessageTypeSystem, Content: "only instructions"}})
+	s.Error(err)
+	s.Contains(err.Error(), "no messages to title")
+
+	_, _, err = GenerateConversationTitle(context.Background(), factory, []ChatMessage{{Role: ContextMessageTypeHuman, Content: "hi"}})
+	s.Error(err)
+	s.Contains(err.Error(), "no conversation title")
+
+	failing := func(p string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{err: errors.New("model down")}, nil
+	}
+	_, _, err = GenerateConversationTitle(context.Background(), failing, []ChatMessage{{Role: ContextMessageTypeHuman, Content: "hi"}})
+	s.Error(err)
+	s.Contains(err.Error(), "model down")
+}
+
+func (s *ConversationTitleSuite) TestChatSessionGenerateTitleAppliesOptionsAfterSessionOptions() {
+	var resolved GeneratorConfig
+	factory := func(p string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		resolved = ResolveGeneratorOpts(opts...)
+		return &recordingGenerator{reply: `{"title":"Potassium levels","summary":"Discussed a high potassium result."}`}, nil
+	}
+	session, err := NewChatSession(factory, WithModel("large-model"), WithAuthToken("key"))
+	s.Require().NoError(err)
+	session.SetHistory([]ChatMessage{{Role: ContextMessageTypeHuman, Content: "My potassium is 5.8"}})
+
+	title, _, err := session.GenerateTitle(context.Background(), WithModel("small-model"))
+	s.Require().NoError(err)
+	s.Equal("Potassium levels", title.Title)
+	s.Require().NotNil(resolved.Model)
+	s.Equal("small-model", *resolved.Model)
+	s.Equal("key", resolved.AuthToken)
+}
+
+func (s *ConversationTitleSuite) TestChatMessagesFromHistory() {
+	messages := ChatMessagesFromHistory(ConversationHistory{Messages: []HistoryMessage{
+		{Role: HistoryRoleUser, Content: "What is my eGFR?"},
+		{Role: HistoryRoleAssistant, ToolCalls: []HistoryToolCall{{Name: "lookup_labs"}}},
+		{Role: HistoryRoleTool, ToolName: "lookup_labs", Content: "48"},
+		{Role: HistoryRoleAssistant, Content: ""},
+	}})
+	s.Equal([]ChatMessage{
+		{Role: ContextMessageTypeHuman, Content: "What is my eGFR?"},
+		{Role: ContextMessageTypeAssistant, Content: "Called tool lookup_labs with arguments {}"},
+		{Role: ContextMessageTypeHuman, Content: "Tool result for lookup_labs:\n48"},
+	}, messages)
+}
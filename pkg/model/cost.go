@@ -0,0 +1,162 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// ErrPricingUnknown is returned by EstimateCost for models without registered
+// pricing.
+var ErrPricingUnknown = errors.New("model pricing is unknown")
+
+// ModelPricing is the list price of a provider model in US dollars per
+// million tokens.
+type ModelPricing struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// CostEstimate is the predicted size and price of a generation. OutputTokens
+// is the output budget (WithMaxTokens, else the model's output token limit),
+// so OutputCost and TotalCost are upper bounds; OutputTokens is zero when
+// neither is known.
+type CostEstimate struct {
+	Provider     string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	InputCost    float64
+	OutputCost   float64
+	TotalCost    float64
+}
+
+type pricingEntry struct {
+	pattern string
+	pricing ModelPricing
+}
+
+var (
+	modelPricingMu sync.RWMutex
+	modelPricing   = map[string][]pricingEntry{}
+)
+
+// RegisterModelPricing registers the price of a provider model, replacing
+// any built-in price. Patterns match like RegisterModelCapabilities.
+func RegisterModelPricing(provider string, modelPattern string, pricing ModelPricing) {
+	provider = normalizeCapabilityKey(provider)
+	modelPattern = normalizeCapabilityKey(modelPattern)
+	if provider == "" || modelPattern == "" {
+		return
+	}
+
+	modelPricingMu.Lock()
+	defer modelPricingMu.Unlock()
+
+	entries := modelPricing[provider]
+	for i := range entries {
+		if entries[i].pattern == modelPattern {
+			entries[i].pricing = pricing
+			return
+		}
+	}
+	modelPricing[provider] = append(entries, pricingEntry{pattern: modelPattern, pricing: pricing})
+}
+
+// LookupModelPricing returns the registered price of a provider model.
+func LookupModelPricing(provider string, modelName string) (ModelPricing, bool) {
+	provider = normalizeCapabilityKey(provider)
+	modelName = normalizeCapabilityKey(modelName)
+	if provider == "" || modelName == "" {
+		return ModelPricing{}, false
+	}
+
+	modelPricingMu.RLock()
+	defer modelPricingMu.RUnlock()
+
+	entries := modelPricing[provider]
+	patterns := make([]string, len(entries))
+	for i, entry := range entries {
+		patterns[i] = entry.pattern
+	}
+	best := matchModelPattern(patterns, modelName)
+	if best < 0 {
+		return ModelPricing{}, false
+	}
+	return entries[best].pricing, true
+}
+
+// EstimateCost predicts what generating from prompt and contexts with cfg
+// costs on provider, before calling Generate, so callers can pick the
+// cheapest provider for a request. Input tokens come from
+// EstimateContextTokens, so the estimate is approximate. cfg must name the
+// model with WithModel; models without registered pricing return
+// ErrPricingUnknown.
+func EstimateCost(provider string, prompt string, contexts []*PromptContext, cfg GeneratorConfig) (CostEstimate, error) {
+	if cfg.Model == nil || strings.TrimSpace(*cfg.Model) == "" {
+		return CostEstimate{}, utils.WrapIfNotNil(errors.New("model is required to estimate cost"))
+	}
+	modelName := strings.TrimSpace(*cfg.Model)
+	estimate := CostEstimate{Provider: provider, Model: modelName}
+
+	for _, entry := range EstimateContextTokens(prompt, contexts) {
+		estimate.InputTokens += entry.Tokens
+	}
+	if cfg.MaxTokens != nil && *cfg.MaxTokens > 0 {
+		estimate.OutputTokens = *cfg.MaxTokens
+	} else {
+		estimate.OutputTokens = MaxOutputTokens(provider, modelName)
+	}
+
+	pricing, found := LookupModelPricing(provider, modelName)
+	if !found {
+		return estimate, utils.WrapIfNotNil(fmt.Errorf("%w: %s model %q", ErrPricingUnknown, provider, modelName))
+	}
+	estimate.InputCost = float64(estimate.InputTokens) * pricing.InputPerMillion / 1e6
+	estimate.OutputCost = float64(estimate.OutputTokens) * pricing.OutputPerMillion / 1e6
+	estimate.TotalCost = estimate.InputCost + estimate.OutputCost
+	return estimate, nil
+}
+
+// Built-in list prices for common models; register your own to keep them
+// current or to add negotiated rates.
+func init() {
+	for pattern, pricing := range map[string]ModelPricing{
+		"gpt-4o*":       {InputPerMillion: 2.5, OutputPerMillion: 10},
+		"gpt-4o-mini*":  {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+		"gpt-4.1*":      {InputPerMillion: 2, OutputPerMillion: 8},
+		"gpt-4.1-mini*": {InputPerMillion: 0.4, OutputPerMillion: 1.6},
+		"gpt-4.1-nano*": {InputPerMillion: 0.1, OutputPerMillion: 0.4},
+		"gpt-5*":        {InputPerMillion: 1.25, OutputPerMillion: 10},
+		"gpt-5-mini*":   {InputPerMillion: 0.25, OutputPerMillion: 2},
+		"gpt-5-nano*":   {InputPerMillion: 0.05, OutputPerMillion: 0.4},
+		"o3*":           {InputPerMillion: 2, OutputPerMillion: 8},
+		"o4-mini*":      {InputPerMillion: 1.1, OutputPerMillion: 4.4},
+	} {
+		RegisterModelPricing("openai", pattern, pricing)
+	}
+	for pattern, pricing := range map[string]ModelPricing{
+		"claude-3-haiku*":    {InputPerMillion: 0.25, OutputPerMillion: 1.25},
+		"claude-3-5-haiku*":  {InputPerMillion: 0.8, OutputPerMillion: 4},
+		"claude-haiku-4-5*":  {InputPerMillion: 1, OutputPerMillion: 5},
+		"claude-3-5-sonnet*": {InputPerMillion: 3, OutputPerMillion: 15},
+		"claude-3-7-sonnet*": {InputPerMillion: 3, OutputPerMillion: 15},
+		"claude-sonnet-4*":   {InputPerMillion: 3, OutputPerMillion: 15},
+		"claude-opus-4*":     {InputPerMillion: 15, OutputPerMillion: 75},
+		"claude-opus-4-5*":   {InputPerMillion: 5, OutputPerMillion: 25},
+	} {
+		RegisterModelPricing("anthropic", pattern, pricing)
+	}
+	for pattern, pricing := range map[string]ModelPricing{
+		"gemini-2.0-flash*":      {InputPerMillion: 0.1, OutputPerMillion: 0.4},
+		"gemini-2.0-flash-lite*": {InputPerMillion: 0.075, OutputPerMillion: 0.3},
+		"gemini-2.5-pro*":        {InputPerMillion: 1.25, OutputPerMillion: 10},
+		"gemini-2.5-flash*":      {InputPerMillion: 0.3, OutputPerMillion: 2.5},
+		"gemini-2.5-flash-lite*": {InputPerMillion: 0.1, OutputPerMillion: 0.4},
+	} {
+		RegisterModelPricing("gemini", pattern, pricing)
+	}
+}
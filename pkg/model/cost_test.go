@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CostSuite struct {
+	suite.Suite
+}
+
+func TestCostSuite(t *testing.T) {
+	suite.Run(t, new(CostSuite))
+}
+
+func (s *CostSuite) TestEstimateCostUsesTokensAndPricing() {
+	contexts := []*PromptContext{
+		{MessageType: ContextMessageTypeSystem, Content: strings.Repeat("a", 400)},
+		{MessageType: ContextMessageTypeHuman, Content: "   "},
+	}
+	cfg := ResolveGeneratorOpts(WithModel("gpt-4o-mini-2024-07-18"), WithMaxTokens(1000))
+
+	estimate, err := EstimateCost("openai", strings.Repeat("b", 100), contexts, cfg)
+	s.Require().NoError(err)
+	s.Equal("openai", estimate.Provider)
+	s.Equal("gpt-4o-mini-2024-07-18", estimate.Model)
+	s.Equal(125, estimate.InputTokens)
+	s.Equal(1000, estimate.OutputTokens)
+	s.InDelta(125*0.15/1e6, estimate.InputCost, 1e-12)
+	s.InDelta(1000*0.6/1e6, estimate.OutputCost, 1e-12)
+	s.InDelta(estimate.InputCost+estimate.OutputCost, estimate.TotalCost, 1e-12)
+}
+
+func (s *CostSuite) TestEstimateCostDefaultsToOutputLimit() {
+	estimate, err := EstimateCost("anthropic", "hi", nil, ResolveGeneratorOpts(WithModel("claude-sonnet-4-5")))
+	s.Require().NoError(err)
+	s.Equal(64000, estimate.OutputTokens)
+	s.InDelta(64000*15/1e6, estimate.OutputCost, 1e-9)
+}
+
+func (s *CostSuite) TestRegisteredPricingOverridesBuiltIn() {
+	RegisterModelPricing("test-cost", "custom-*", ModelPricing{InputPerMillion: 1, OutputPerMillion: 2})
+	RegisterModelPricing("test-cost", "custom-*", ModelPricing{InputPerMillion: 3, OutputPerMillion: 4})
+
+	pricing, found := LookupModelPricing("test-cost", "custom-large")
+	s.Require().True(found)
+	s.Equal(ModelPricing{InputPerMillion: 3, OutputPerMillion: 4}, pricing)
+}
+
+func (s *CostSuite) TestEstimateCostErrors() {
+	_, err := EstimateCost("openai", "hi", nil, ResolveGeneratorOpts())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "model is required")
+
+	estimate, err := EstimateCost("ollama", "hello there", nil, ResolveGeneratorOpts(WithModel("llama3")))
+	s.ErrorIs(err, ErrPricingUnknown)
+	s.Equal(3, estimate.InputTokens)
+}
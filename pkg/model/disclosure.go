@@ -0,0 +1,155 @@
+package model
+
+import (
+	"strings"
+)
+
+// DefaultDisclosureNotice is the notice used when DisclosureConfig.Notice is
+// empty.
+const DefaultDisclosureNotice = "This content was generated by AI."
+
+// DisclosurePlacement selects where WithDisclosure puts the notice.
+type DisclosurePlacement string
+
+const (
+	// DisclosurePlacementAppend adds the notice after the text, separated by
+	// a blank line. It is the default.
+	DisclosurePlacementAppend DisclosurePlacement = "append"
+	// DisclosurePlacementPrepend adds the notice before the text, separated
+	// by a blank line.
+	DisclosurePlacementPrepend DisclosurePlacement = "prepend"
+	// DisclosurePlacementEmbed appends the notice as an invisible watermark
+	// of zero-width characters; read it back with ExtractDisclosure.
+	DisclosurePlacementEmbed DisclosurePlacement = "embed"
+	// DisclosurePlacementMetadata leaves the text unchanged and only sets
+	// MetadataKeyAIDisclosure, for consumers that render the notice
+	// themselves.
+	DisclosurePlacementMetadata DisclosurePlacement = "metadata"
+)
+
+// DisclosureConfig configures WithDisclosure.
+type DisclosureConfig struct {
+	// Notice is the disclosure text (default DefaultDisclosureNotice).
+	Notice string
+	// Placement is where the notice goes (default DisclosurePlacementAppend).
+	Placement DisclosurePlacement
+}
+
+// WithDisclosure adds an AI-disclosure notice to the final text of string
+// generators, after output transformers and post-processors so they cannot
+// cut it, and sets MetadataKeyAIDisclosure to the notice on every content
+// generation, structured ones included. Streamed chunks do not carry the
+// notice; the full text GenerateStream returns does.
+func WithDisclosure(config DisclosureConfig) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.Disclosure = &config
+	})
+}
+
+// SetDisclosureMetadata records the notice of WithDisclosure in meta.
+// Providers call it for every content generation.
+func SetDisclosureMetadata(meta GenerationMetadata, cfg GeneratorConfig) {
+	if meta == nil || cfg.Disclosure == nil {
+		return
+	}
+	meta[MetadataKeyAIDisclosure] = disclosureNotice(*cfg.Disclosure)
+}
+
+// ApplyDisclosure adds the notice of WithDisclosure to text. Without the
+// option it returns text unchanged.
+func ApplyDisclosure(cfg GeneratorConfig, text string) string {
+	prefix, suffix := disclosureAffixes(cfg)
+	return prefix + text + suffix
+}
+
+// disclosureAffixes returns what ApplyDisclosure writes before and after the
+// text, so GenerateTo can add the notice around a stream.
+func disclosureAffixes(cfg GeneratorConfig) (string, string) {
+	if cfg.Disclosure == nil {
+		return "", ""
+	}
+	notice := disclosureNotice(*cfg.Disclosure)
+	switch cfg.Disclosure.Placement {
+	case DisclosurePlacementPrepend:
+		return notice + "\n\n", ""
+	case DisclosurePlacementEmbed:
+		return "", encodeDisclosureWatermark(notice)
+	case DisclosurePlacementMetadata:
+		return "", ""
+	default:
+		return "", "\n\n" + notice
+	}
+}
+
+// ExtractDisclosure finds a notice embedded with DisclosurePlacementEmbed. It
+// returns the notice, text without the watermark and whether one was found.
+func ExtractDisclosure(text string) (string, string, bool) {
+	start := strings.Index(text, disclosureWatermarkMark)
+	if start < 0 {
+		return "", text, false
+	}
+	rest := text[start+len(disclosureWatermarkMark):]
+	end := strings.Index(rest, disclosureWatermarkMark)
+	if end < 0 {
+		return "", text, false
+	}
+	notice, ok := decodeDisclosureWatermark(rest[:end])
+	if !ok {
+		return "", text, false
+	}
+	return notice, text[:start] + rest[end+len(disclosureWatermarkMark):], true
+}
+
+func disclosureNotice(config DisclosureConfig) string {
+	if notice := strings.TrimSpace(config.Notice); notice != "" {
+		return notice
+	}
+	return DefaultDisclosureNotice
+}
+
+// The watermark is the notice's bytes, most significant bit first, as
+// zero-width space (0) and zero-width non-joiner (1), between word joiners.
+const (
+	disclosureWatermarkMark = "\u2060"
+	disclosureWatermarkZero = '\u200b'
+	disclosureWatermarkOne  = '\u200c'
+)
+
+func encodeDisclosureWatermark(notice string) string {
+	var b strings.Builder
+	b.WriteString(disclosureWatermarkMark)
+	for i := 0; i < len(notice); i++ {
+		for bit := 7; bit >= 0; bit-- {
+			if notice[i]&(1<<bit) != 0 {
+				b.WriteRune(disclosureWatermarkOne)
+			} else {
+				b.WriteRune(disclosureWatermarkZero)
+			}
+		}
+	}
+	b.WriteString(disclosureWatermarkMark)
+	return b.String()
+}
+
+func decodeDisclosureWatermark(encoded string) (string, bool) {
+	runes := []rune(encoded)
+	if len(runes) == 0 || len(runes)%8 != 0 {
+		return "", false
+	}
+	out := make([]byte, 0, len(runes)/8)
+	for i := 0; i < len(runes); i += 8 {
+		var c byte
+		for _, r := range runes[i : i+8] {
+			c <<= 1
+			switch r {
+			case disclosureWatermarkOne:
+				c |= 1
+			case disclosureWatermarkZero:
+			default:
+				return "", false
+			}
+		}
+		out = append(out, c)
+	}
+	return string(out), true
+}
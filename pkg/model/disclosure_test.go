@@ -0,0 +1,71 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DisclosureSuite struct {
+	suite.Suite
+}
+
+func TestDisclosureSuite(t *testing.T) {
+	suite.Run(t, new(DisclosureSuite))
+}
+
+func (s *DisclosureSuite) TestDisabledLeavesTextAndMetadataAlone() {
+	cfg := ResolveGeneratorOpts()
+	meta := GenerationMetadata{}
+	SetDisclosureMetadata(meta, cfg)
+	s.Equal("answer", ApplyDisclosure(cfg, "answer"))
+	s.NotContains(meta, MetadataKeyAIDisclosure)
+}
+
+func (s *DisclosureSuite) TestPlacements() {
+	cases := []struct {
+		placement DisclosurePlacement
+		want      string
+	}{
+		{"", "answer\n\n" + DefaultDisclosureNotice},
+		{DisclosurePlacementAppend, "answer\n\n" + DefaultDisclosureNotice},
+		{DisclosurePlacementPrepend, DefaultDisclosureNotice + "\n\nanswer"},
+		{DisclosurePlacementMetadata, "answer"},
+	}
+	for _, tc := range cases {
+		cfg := ResolveGeneratorOpts(WithDisclosure(DisclosureConfig{Placement: tc.placement}))
+		meta := GenerationMetadata{}
+		SetDisclosureMetadata(meta, cfg)
+		s.Equal(tc.want, ApplyDisclosure(cfg, "answer"), tc.placement)
+		s.Equal(DefaultDisclosureNotice, meta[MetadataKeyAIDisclosure], tc.placement)
+	}
+}
+
+func (s *DisclosureSuite) TestEmbeddedWatermarkRoundTrips() {
+	notice := "Generated by AI — review before use."
+	cfg := ResolveGeneratorOpts(WithDisclosure(DisclosureConfig{Notice: notice, Placement: DisclosurePlacementEmbed}))
+
+	text := ApplyDisclosure(cfg, "eGFR is 48.")
+	s.NotContains(text, "Generated")
+	s.Contains(text, "eGFR is 48.")
+
+	found, clean, ok := ExtractDisclosure(text)
+	s.True(ok)
+	s.Equal(notice, found)
+	s.Equal("eGFR is 48.", clean)
+
+	_, clean, ok = ExtractDisclosure("plain text")
+	s.False(ok)
+	s.Equal("plain text", clean)
+}
+
+func (s *DisclosureSuite) TestFinishTextOutputAddsNoticeAfterPostProcessors() {
+	cfg := ResolveGeneratorOpts(
+		WithPostProcessors(MaxLength(6)),
+		WithDisclosure(DisclosureConfig{Notice: " AI generated "}),
+	)
+	text, err := FinishTextOutput(context.Background(), cfg, "  answer text  ")
+	s.Require().NoError(err)
+	s.Equal("answer\n\nAI generated", text)
+}
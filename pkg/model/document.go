@@ -0,0 +1,151 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Document MIME types accepted by WithDocuments. Providers support a subset
+// (see DocumentPart).
+const (
+	DocumentMIMETypePDF      = "application/pdf"
+	DocumentMIMETypeText     = "text/plain"
+	DocumentMIMETypeMarkdown = "text/markdown"
+	DocumentMIMETypeCSV      = "text/csv"
+	DocumentMIMETypeHTML     = "text/html"
+	DocumentMIMETypeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	DocumentMIMETypeXLSX     = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	DocumentMIMETypePPTX     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+var documentMIMETypesByExtension = map[string]string{
+	".pdf":  DocumentMIMETypePDF,
+	".txt":  DocumentMIMETypeText,
+	".md":   DocumentMIMETypeMarkdown,
+	".csv":  DocumentMIMETypeCSV,
+	".html": DocumentMIMETypeHTML,
+	".htm":  DocumentMIMETypeHTML,
+	".docx": DocumentMIMETypeDOCX,
+	".xlsx": DocumentMIMETypeXLSX,
+	".pptx": DocumentMIMETypePPTX,
+}
+
+// DocumentPart is a document attached to a generation with WithDocuments.
+// Set either Data or URL. Providers accept different types: OpenAI takes
+// every type above, Anthropic takes PDF and plain text, Gemini takes PDF and
+// the text types.
+type DocumentPart struct {
+	// Name is the filename shown to the model. It defaults to "document"
+	// with the extension of MIMEType.
+	Name string
+	// MIMEType is detected from Name's extension or from Data when empty.
+	MIMEType string
+	// Data is the raw document; providers receive it base64 encoded.
+	Data []byte
+	// URL is an http(s) URL the provider fetches (Gemini also accepts
+	// gs:// and Files API URIs).
+	URL string
+}
+
+// WithDocuments attaches documents (PDFs, office documents, text files) to
+// the prompt. Documents are sent ahead of the prompt text in the final user
+// message.
+func WithDocuments(documents ...DocumentPart) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.Documents = append(cfg.Documents, documents...)
+	})
+}
+
+// ResolveDocumentParts validates documents for a provider: each must have
+// data or a URL, a MIME type in supported and at most maxBytes of data
+// (0 means no limit, and the limit applies to the sum of all documents).
+// Missing names and MIME types are filled in.
+func ResolveDocumentParts(documents []DocumentPart, supported []string, maxBytes int) ([]DocumentPart, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	out := make([]DocumentPart, 0, len(documents))
+	total := 0
+	for i, document := range documents {
+		resolved, err := resolveDocumentPart(document)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("document %d: %w", i, err))
+		}
+		if !containsString(supported, resolved.MIMEType) {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("document %d: mime type %q is not supported; supported: %s", i, resolved.MIMEType, strings.Join(supported, ", ")))
+		}
+		total += len(resolved.Data)
+		if maxBytes > 0 && total > maxBytes {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("documents exceed %d bytes", maxBytes))
+		}
+		out = append(out, resolved)
+	}
+	return out, nil
+}
+
+func resolveDocumentPart(document DocumentPart) (DocumentPart, error) {
+	document.URL = strings.TrimSpace(document.URL)
+	if len(document.Data) > 0 && document.URL != "" {
+		return DocumentPart{}, utils.WrapIfNotNil(errors.New("set either data or url, not both"))
+	}
+	if len(document.Data) == 0 && document.URL == "" {
+		return DocumentPart{}, utils.WrapIfNotNil(errors.New("document data or url is required"))
+	}
+
+	document.Name = strings.TrimSpace(document.Name)
+	if document.Name == "" && document.URL != "" {
+		document.Name = path.Base(strings.SplitN(document.URL, "?", 2)[0])
+	}
+
+	document.MIMEType = strings.ToLower(strings.TrimSpace(document.MIMEType))
+	if document.MIMEType == "" {
+		document.MIMEType = documentMIMETypesByExtension[strings.ToLower(path.Ext(document.Name))]
+	}
+	if document.MIMEType == "" && len(document.Data) > 0 {
+		document.MIMEType, _, _ = strings.Cut(http.DetectContentType(document.Data), ";")
+	}
+	if document.MIMEType == "" {
+		return DocumentPart{}, utils.WrapIfNotNil(errors.New("document mime type is required"))
+	}
+
+	if document.Name == "" || document.Name == "." || document.Name == "/" {
+		document.Name = "document"
+		for extension, mimeType := range documentMIMETypesByExtension {
+			if mimeType == document.MIMEType && extension != ".htm" {
+				document.Name += extension
+				break
+			}
+		}
+	}
+	return document, nil
+}
+
+// Base64Data returns the document data base64 encoded.
+func (d DocumentPart) Base64Data() string {
+	return base64.StdEncoding.EncodeToString(d.Data)
+}
+
+// DataURL returns the document as a base64 data URL, or URL for URL
+// documents.
+func (d DocumentPart) DataURL() string {
+	if len(d.Data) == 0 {
+		return d.URL
+	}
+	return "data:" + d.MIMEType + ";base64," + d.Base64Data()
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DocumentPartSuite struct {
+	suite.Suite
+}
+
+func TestDocumentPartSuite(t *testing.T) {
+	suite.Run(t, new(DocumentPartSuite))
+}
+
+var testPDF = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+func (s *DocumentPartSuite) TestResolveFillsNamesAndMIMETypes() {
+	documents, err := ResolveDocumentParts([]DocumentPart{
+		{Data: testPDF},
+		{Name: "labs.CSV", Data: []byte("a,b\n1,2\n")},
+		{URL: " https://example.org/files/discharge.pdf?sig=abc "},
+		{Name: "notes", MIMEType: "Text/Plain", Data: []byte("note")},
+	}, []string{DocumentMIMETypePDF, DocumentMIMETypeCSV, DocumentMIMETypeText}, 0)
+	s.Require().NoError(err)
+	s.Equal([]DocumentPart{
+		{Name: "document.pdf", MIMEType: DocumentMIMETypePDF, Data: testPDF},
+		{Name: "labs.CSV", MIMEType: DocumentMIMETypeCSV, Data: []byte("a,b\n1,2\n")},
+		{Name: "discharge.pdf", MIMEType: DocumentMIMETypePDF, URL: "https://example.org/files/discharge.pdf?sig=abc"},
+		{Name: "notes", MIMEType: DocumentMIMETypeText, Data: []byte("note")},
+	}, documents)
+
+	s.Equal("data:application/pdf;base64,JVBERi0xLjcKJeLjz9MK", documents[0].DataURL())
+	s.Equal("https://example.org/files/discharge.pdf?sig=abc", documents[2].DataURL())
+
+	documents, err = ResolveDocumentParts(nil, []string{DocumentMIMETypePDF}, 0)
+	s.NoError(err)
+	s.Nil(documents)
+}
+
+func (s *DocumentPartSuite) TestResolveRejectsInvalidDocuments() {
+	supported := []string{DocumentMIMETypePDF}
+	cases := map[string][]DocumentPart{
+		"either data or url":          {{Data: testPDF, URL: "https://example.org/a.pdf"}},
+		"data or url is required":     {{Name: "a.pdf"}},
+		"mime type is required":       {{URL: "https://example.org/download"}},
+		`"application/vnd.openxmlfor`: {{Name: "letter.docx", Data: []byte("PK\x03\x04")}},
+		"documents exceed 12 bytes":   {{Data: testPDF}, {Name: "b.pdf", Data: testPDF}},
+	}
+	for want, documents := range cases {
+		_, err := ResolveDocumentParts(documents, supported, 12)
+		s.Require().Error(err, want)
+		s.Contains(err.Error(), want)
+	}
+}
+
+func (s *DocumentPartSuite) TestWithDocumentsAppends() {
+	cfg := ResolveGeneratorOpts(
+		WithDocuments(DocumentPart{Name: "a.pdf", Data: testPDF}),
+		WithDocuments(DocumentPart{Name: "b.txt", Data: []byte("b")}),
+	)
+	s.Require().Len(cfg.Documents, 2)
+	s.Equal("b.txt", cfg.Documents[1].Name)
+}
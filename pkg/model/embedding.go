@@ -1,5 +1,13 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
 type EmbeddingVector = []float64
 type EmbeddingVectors = [][]float64
 
@@ -8,8 +16,81 @@ const (
 	MetadataKeyEmbeddingDims  = "embedding_dims"
 )
 
+// EmbeddingTaskType tells the embedding model how the vectors will be used,
+// so it can optimise them for that task.
+type EmbeddingTaskType string
+
+const (
+	EmbeddingTaskRetrievalQuery     EmbeddingTaskType = "RETRIEVAL_QUERY"
+	EmbeddingTaskRetrievalDocument  EmbeddingTaskType = "RETRIEVAL_DOCUMENT"
+	EmbeddingTaskSemanticSimilarity EmbeddingTaskType = "SEMANTIC_SIMILARITY"
+	EmbeddingTaskClassification     EmbeddingTaskType = "CLASSIFICATION"
+	EmbeddingTaskClustering         EmbeddingTaskType = "CLUSTERING"
+	EmbeddingTaskQuestionAnswering  EmbeddingTaskType = "QUESTION_ANSWERING"
+	EmbeddingTaskFactVerification   EmbeddingTaskType = "FACT_VERIFICATION"
+	EmbeddingTaskCodeRetrievalQuery EmbeddingTaskType = "CODE_RETRIEVAL_QUERY"
+)
+
 func WithEmbeddingDimensions(value int) GeneratorOption {
 	return generatorOptionFunc(func(cfg *GeneratorConfig) {
 		cfg.EmbeddingDimensions = &value
 	})
 }
+
+// WithEmbeddingTaskType sets the task the embeddings are for. Queries and the
+// documents they search should be embedded with the matching retrieval task
+// types. Supported by Gemini.
+func WithEmbeddingTaskType(taskType EmbeddingTaskType) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.EmbeddingTaskType = taskType
+	})
+}
+
+// WithEmbeddingTitle sets the title of the document being embedded, which
+// improves retrieval quality. It applies to the EmbeddingTaskRetrievalDocument
+// task type, which is used when no task type is set. Supported by Gemini.
+func WithEmbeddingTitle(title string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.EmbeddingTitle = title
+	})
+}
+
+// ValidateEmbeddingDimensions rejects a configured size of zero or less.
+func ValidateEmbeddingDimensions(cfg GeneratorConfig) error {
+	if cfg.EmbeddingDimensions != nil && *cfg.EmbeddingDimensions <= 0 {
+		return utils.WrapIfNotNil(errors.New("embedding dimensions must be greater than zero"))
+	}
+	return nil
+}
+
+// TruncateEmbeddings shortens each vector to its first dims values and
+// rescales it to unit length, for providers whose APIs have no dimensions
+// parameter. This matches server-side shortening of Matryoshka-trained models
+// (such as nomic-embed-text or mxbai-embed-large); for other models the
+// shortened vectors lose more quality. Vectors shorter than dims are an
+// error, as they cannot be extended.
+func TruncateEmbeddings(vectors EmbeddingVectors, dims int) (EmbeddingVectors, error) {
+	if dims <= 0 {
+		return nil, utils.WrapIfNotNil(errors.New("embedding dimensions must be greater than zero"))
+	}
+	truncated := make(EmbeddingVectors, len(vectors))
+	for i, vector := range vectors {
+		if len(vector) < dims {
+			return nil, utils.WrapIfNotNil(
+				fmt.Errorf("embedding dimensions %d exceed the model's %d dimensions", dims, len(vector)),
+			)
+		}
+		shortened := append(EmbeddingVector(nil), vector[:dims]...)
+		var sumSquares float64
+		for _, value := range shortened {
+			sumSquares += value * value
+		}
+		if norm := math.Sqrt(sumSquares); norm > 0 {
+			for j := range shortened {
+				shortened[j] /= norm
+			}
+		}
+		truncated[i] = shortened
+	}
+	return truncated, nil
+}
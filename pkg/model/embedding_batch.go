@@ -0,0 +1,148 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Defaults for EmbeddingBatchOptions.
+const (
+	DefaultEmbeddingBatchSize        = 96
+	DefaultEmbeddingBatchConcurrency = 4
+)
+
+// EmbeddingBatchSizes are the inputs per GenerateBatch call used for each
+// provider when EmbeddingBatchOptions.BatchSize is not set. They stay below
+// each API's input-count limit while keeping requests small enough for
+// typical document lengths. Unknown providers use DefaultEmbeddingBatchSize.
+var EmbeddingBatchSizes = map[string]int{
+	"openai":      2048,
+	"anthropic":   128,
+	"bedrock":     96,
+	"gemini":      100,
+	"ollama":      256,
+	"huggingface": 32,
+}
+
+// EmbeddingBatchOptions configures GenerateEmbeddingsInBatches.
+type EmbeddingBatchOptions struct {
+	// Provider selects the default BatchSize from EmbeddingBatchSizes.
+	Provider string
+	// BatchSize is the number of inputs per GenerateBatch call.
+	BatchSize int
+	// Concurrency is the number of chunks embedded at once (default
+	// DefaultEmbeddingBatchConcurrency).
+	Concurrency int
+}
+
+// GenerateEmbeddingsInBatches splits inputs into chunks, embeds them
+// concurrently with gen and returns the vectors in input order. The first
+// failing chunk cancels the chunks that have not finished and its error is
+// returned. The metadata sums the usage of all chunks (see
+// MergeUsageMetadata); latency_ms is the wall-clock time of the whole batch.
+func GenerateEmbeddingsInBatches(
+	ctx context.Context,
+	gen EmbeddingGenerator,
+	inputs []string,
+	opts EmbeddingBatchOptions,
+) (EmbeddingVectors, GenerationMetadata, error) {
+	start := time.Now()
+	meta := GenerationMetadata{}
+	if gen == nil {
+		return nil, meta, utils.WrapIfNotNil(errors.New("embedding generator is required"))
+	}
+	if len(inputs) == 0 {
+		return nil, meta, utils.WrapIfNotNil(errors.New("at least one input is required"))
+	}
+
+	batchSize := resolveEmbeddingBatchSize(opts)
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultEmbeddingBatchConcurrency
+	}
+
+	chunkCount := (len(inputs) + batchSize - 1) / batchSize
+	chunkMetas := make([]GenerationMetadata, chunkCount)
+	chunkDone := make([]bool, chunkCount)
+	vectors := make(EmbeddingVectors, len(inputs))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	semaphore := make(chan struct{}, concurrency)
+	for chunk := 0; chunk < chunkCount; chunk++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			from := chunk * batchSize
+			to := min(from+batchSize, len(inputs))
+			chunkVectors, chunkMeta, err := gen.GenerateBatch(ctx, inputs[from:to])
+			if err == nil && len(chunkVectors) != to-from {
+				err = fmt.Errorf("embedding response size mismatch: expected %d, got %d", to-from, len(chunkVectors))
+			}
+			if err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("embedding chunk %d (inputs %d-%d): %w", chunk, from, to-1, err)
+					cancel()
+				}
+				errMu.Unlock()
+				return
+			}
+			copy(vectors[from:to], chunkVectors)
+			chunkMetas[chunk] = chunkMeta
+			chunkDone[chunk] = true
+		}()
+	}
+	wg.Wait()
+	for _, done := range chunkDone {
+		if !done && firstErr == nil {
+			// The caller's context ended before every chunk started.
+			firstErr = ctx.Err()
+		}
+	}
+
+	for _, chunkMeta := range chunkMetas {
+		MergeUsageMetadata(meta, chunkMeta)
+	}
+	meta[MetadataKeyLatencyMs] = strconv.FormatInt(time.Since(start).Milliseconds(), 10)
+	if firstErr != nil {
+		return nil, meta, utils.WrapIfNotNil(firstErr)
+	}
+
+	meta[MetadataKeyEmbeddingCount] = strconv.Itoa(len(vectors))
+	meta[MetadataKeyEmbeddingDims] = strconv.Itoa(len(vectors[0]))
+	return vectors, meta, nil
+}
+
+func resolveEmbeddingBatchSize(opts EmbeddingBatchOptions) int {
+	if opts.BatchSize > 0 {
+		return opts.BatchSize
+	}
+	if size, ok := EmbeddingBatchSizes[strings.ToLower(strings.TrimSpace(opts.Provider))]; ok && size > 0 {
+		return size
+	}
+	return DefaultEmbeddingBatchSize
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbeddingBatchSuite struct {
+	suite.Suite
+}
+
+func TestEmbeddingBatchSuite(t *testing.T) {
+	suite.Run(t, new(EmbeddingBatchSuite))
+}
+
+// fakeEmbeddingGenerator embeds each input as its index in the input slice
+// and records the chunk sizes it receives.
+type fakeEmbeddingGenerator struct {
+	mu        sync.Mutex
+	chunks    []int
+	inFlight  atomic.Int32
+	maxFlight atomic.Int32
+	failAt    string
+	release   chan struct{}
+}
+
+func (g *fakeEmbeddingGenerator) Generate(ctx context.Context, input string) (EmbeddingVector, GenerationMetadata, error) {
+	vectors, meta, err := g.GenerateBatch(ctx, []string{input})
+	if err != nil {
+		return nil, meta, err
+	}
+	return vectors[0], meta, nil
+}
+
+func (g *fakeEmbeddingGenerator) GenerateBatch(ctx context.Context, inputs []string) (EmbeddingVectors, GenerationMetadata, error) {
+	current := g.inFlight.Add(1)
+	defer g.inFlight.Add(-1)
+	for {
+		seen := g.maxFlight.Load()
+		if current <= seen || g.maxFlight.CompareAndSwap(seen, current) {
+			break
+		}
+	}
+	if g.release != nil {
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	g.mu.Lock()
+	g.chunks = append(g.chunks, len(inputs))
+	g.mu.Unlock()
+
+	vectors := make(EmbeddingVectors, 0, len(inputs))
+	for _, input := range inputs {
+		if input == g.failAt {
+			return nil, nil, errors.New("provider rejected input")
+		}
+		value, _ := strconv.Atoi(input)
+		vectors = append(vectors, EmbeddingVector{float64(value), 1})
+	}
+	return vectors, GenerationMetadata{
+		MetadataKeyProvider:    "fake",
+		MetadataKeyModel:       "fake-embed",
+		MetadataKeyInputTokens: strconv.Itoa(len(inputs)),
+		MetadataKeyAPICalls:    "1",
+	}, nil
+}
+
+func numberedInputs(count int) []string {
+	inputs := make([]string, count)
+	for i := range inputs {
+		inputs[i] = strconv.Itoa(i)
+	}
+	return inputs
+}
+
+func (s *EmbeddingBatchSuite) TestSplitsAndMergesInInputOrder() {
+	gen := &fakeEmbeddingGenerator{}
+
+	vectors, meta, err := GenerateEmbeddingsInBatches(context.Background(), gen, numberedInputs(25), EmbeddingBatchOptions{BatchSize: 10, Concurrency: 3})
+	s.Require().NoError(err)
+	s.Require().Len(vectors, 25)
+	for i, vector := range vectors {
+		s.Equal(float64(i), vector[0])
+	}
+	s.ElementsMatch([]int{10, 10, 5}, gen.chunks)
+	s.Equal("25", meta[MetadataKeyInputTokens])
+	s.Equal("3", meta[MetadataKeyAPICalls])
+	s.Equal("25", meta[MetadataKeyEmbeddingCount])
+	s.Equal("2", meta[MetadataKeyEmbeddingDims])
+	s.Equal("fake-embed", meta[MetadataKeyModel])
+	s.Contains(meta, MetadataKeyLatencyMs)
+}
+
+func (s *EmbeddingBatchSuite) TestProviderSelectsDefaultBatchSize() {
+	gen := &fakeEmbeddingGenerator{}
+
+	_, _, err := GenerateEmbeddingsInBatches(context.Background(), gen, numberedInputs(40), EmbeddingBatchOptions{Provider: "HuggingFace"})
+	s.Require().NoError(err)
+	s.ElementsMatch([]int{32, 8}, gen.chunks)
+
+	s.Equal(DefaultEmbeddingBatchSize, resolveEmbeddingBatchSize(EmbeddingBatchOptions{Provider: "unknown"}))
+}
+
+func (s *EmbeddingBatchSuite) TestBoundsConcurrency() {
+	gen := &fakeEmbeddingGenerator{release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := GenerateEmbeddingsInBatches(context.Background(), gen, numberedInputs(10), EmbeddingBatchOptions{BatchSize: 1, Concurrency: 2})
+		done <- err
+	}()
+	s.Eventually(func() bool { return gen.inFlight.Load() == 2 }, time.Second, time.Millisecond)
+	for i := 0; i < 10; i++ {
+		gen.release <- struct{}{}
+	}
+	s.Require().NoError(<-done)
+	s.Equal(int32(2), gen.maxFlight.Load())
+}
+
+func (s *EmbeddingBatchSuite) TestReturnsFirstChunkError() {
+	gen := &fakeEmbeddingGenerator{failAt: "12"}
+
+	vectors, _, err := GenerateEmbeddingsInBatches(context.Background(), gen, numberedInputs(30), EmbeddingBatchOptions{BatchSize: 10, Concurrency: 1})
+	s.Nil(vectors)
+	s.Error(err)
+	s.Contains(err.Error(), "embedding chunk 1 (inputs 10-19)")
+	s.Contains(err.Error(), "provider rejected input")
+}
+
+func (s *EmbeddingBatchSuite) TestCancelledContextFails() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := GenerateEmbeddingsInBatches(ctx, &fakeEmbeddingGenerator{release: make(chan struct{})}, numberedInputs(3), EmbeddingBatchOptions{BatchSize: 1})
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *EmbeddingBatchSuite) TestRequiresGeneratorAndInputs() {
+	_, _, err := GenerateEmbeddingsInBatches(context.Background(), nil, []string{"a"}, EmbeddingBatchOptions{})
+	s.Error(err)
+
+	_, _, err = GenerateEmbeddingsInBatches(context.Background(), &fakeEmbeddingGenerator{}, nil, EmbeddingBatchOptions{})
+	s.Error(err)
+}
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// EmbeddingDimensionSupport describes the output sizes an embedding model
+// accepts through WithEmbeddingDimensions. The zero value means the model
+// has a fixed size and takes no dimensions parameter.
+type EmbeddingDimensionSupport struct {
+	// Min and Max bound the accepted sizes.
+	Min int
+	Max int
+	// Allowed lists the only accepted sizes, for models that take a fixed
+	// set; when empty any size from Min to Max is accepted.
+	Allowed []int
+}
+
+func (s EmbeddingDimensionSupport) supported() bool {
+	return s.Max > 0 || len(s.Allowed) > 0
+}
+
+func (s EmbeddingDimensionSupport) accepts(dims int) bool {
+	if len(s.Allowed) > 0 {
+		return slices.Contains(s.Allowed, dims)
+	}
+	return dims >= s.Min && dims <= s.Max
+}
+
+func (s EmbeddingDimensionSupport) String() string {
+	if len(s.Allowed) > 0 {
+		values := make([]string, len(s.Allowed))
+		for i, value := range s.Allowed {
+			values[i] = strconv.Itoa(value)
+		}
+		return "one of " + fmt.Sprint(values)
+	}
+	return fmt.Sprintf("between %d and %d", s.Min, s.Max)
+}
+
+type embeddingDimensionEntry struct {
+	pattern string
+	support EmbeddingDimensionSupport
+}
+
+var (
+	embeddingDimensionRegistryMu sync.RWMutex
+	embeddingDimensionRegistry   = map[string][]embeddingDimensionEntry{}
+)
+
+// RegisterEmbeddingDimensions registers the output sizes a provider's
+// embedding model accepts. Patterns match like RegisterModelCapabilities.
+func RegisterEmbeddingDimensions(provider string, modelPattern string, support EmbeddingDimensionSupport) {
+	provider = normalizeCapabilityKey(provider)
+	modelPattern = normalizeCapabilityKey(modelPattern)
+	if provider == "" || modelPattern == "" {
+		return
+	}
+
+	embeddingDimensionRegistryMu.Lock()
+	defer embeddingDimensionRegistryMu.Unlock()
+
+	entries := embeddingDimensionRegistry[provider]
+	for i := range entries {
+		if entries[i].pattern == modelPattern {
+			entries[i].support = support
+			return
+		}
+	}
+	embeddingDimensionRegistry[provider] = append(entries, embeddingDimensionEntry{pattern: modelPattern, support: support})
+}
+
+// LookupEmbeddingDimensions returns the registered dimension support of a
+// provider's embedding model. The boolean is false when nothing is
+// registered, in which case any positive size is passed to the provider.
+func LookupEmbeddingDimensions(provider string, modelName string) (EmbeddingDimensionSupport, bool) {
+	provider = normalizeCapabilityKey(provider)
+	modelName = normalizeCapabilityKey(modelName)
+	if provider == "" || modelName == "" {
+		return EmbeddingDimensionSupport{}, false
+	}
+
+	embeddingDimensionRegistryMu.RLock()
+	defer embeddingDimensionRegistryMu.RUnlock()
+
+	entries := embeddingDimensionRegistry[provider]
+	patterns := make([]string, len(entries))
+	for i, entry := range entries {
+		patterns[i] = entry.pattern
+	}
+	best := matchModelPattern(patterns, modelName)
+	if best < 0 {
+		return EmbeddingDimensionSupport{}, false
+	}
+	return entries[best].support, true
+}
+
+// ResolveEmbeddingDimensions returns the embedding size to request from a
+// provider model, or nil when none is configured. A size of zero or less is
+// always an error. A size the registered model does not accept, or any size
+// for a model that takes none, is an error too, or is dropped with a warning
+// when IgnoreInvalidGeneratorOptions is set, so the model's full size is
+// returned. Every embedding generator calls it.
+func ResolveEmbeddingDimensions(cfg GeneratorConfig, provider string, modelName string, log logging.Logger) (*int, error) {
+	if err := ValidateEmbeddingDimensions(cfg); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if cfg.EmbeddingDimensions == nil {
+		return nil, nil
+	}
+	dims := *cfg.EmbeddingDimensions
+
+	support, found := LookupEmbeddingDimensions(provider, modelName)
+	if !found {
+		return &dims, nil
+	}
+	var err error
+	switch {
+	case !support.supported():
+		err = fmt.Errorf("embedding dimensions are not supported by %s model %q", provider, modelName)
+	case !support.accepts(dims):
+		err = fmt.Errorf("embedding dimensions %d are not supported by %s model %q; use %s", dims, provider, modelName, support)
+	default:
+		return &dims, nil
+	}
+	if !cfg.IgnoreInvalidGeneratorOptions {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if log != nil {
+		log.Warnf("ignoring embedding dimensions: %v", err)
+	}
+	LogOptionResolution(log, provider, "embedding_dimensions", OptionActionDropped, err.Error())
+	return nil, nil
+}
+
+func init() {
+	RegisterEmbeddingDimensions("openai", "text-embedding-3-small", EmbeddingDimensionSupport{Min: 1, Max: 1536})
+	RegisterEmbeddingDimensions("openai", "text-embedding-3-large", EmbeddingDimensionSupport{Min: 1, Max: 3072})
+	RegisterEmbeddingDimensions("openai", "text-embedding-ada-002", EmbeddingDimensionSupport{})
+
+	RegisterEmbeddingDimensions("gemini", "gemini-embedding-001", EmbeddingDimensionSupport{Min: 128, Max: 3072})
+	RegisterEmbeddingDimensions("gemini", "text-embedding-004", EmbeddingDimensionSupport{Min: 1, Max: 768})
+
+	voyageFlexible := EmbeddingDimensionSupport{Allowed: []int{256, 512, 1024, 2048}}
+	for _, pattern := range []string{"voyage-3.5*", "voyage-3-large", "voyage-code-3"} {
+		RegisterEmbeddingDimensions("voyage", pattern, voyageFlexible)
+	}
+	for _, pattern := range []string{"voyage-3", "voyage-3-lite", "voyage-code-2", "voyage-law-2", "voyage-finance-2"} {
+		RegisterEmbeddingDimensions("voyage", pattern, EmbeddingDimensionSupport{})
+	}
+
+	// Bedrock model IDs without a cross-region inference prefix such as "us.".
+	RegisterEmbeddingDimensions("bedrock", "amazon.titan-embed-text-v2*", EmbeddingDimensionSupport{Allowed: []int{256, 512, 1024}})
+	RegisterEmbeddingDimensions("bedrock", "cohere.embed-v4*", EmbeddingDimensionSupport{Allowed: []int{256, 512, 1024, 1536}})
+	for _, pattern := range []string{"amazon.titan-embed-text-v1*", "amazon.titan-embed-image-v1*", "cohere.embed-english-v3*", "cohere.embed-multilingual-v3*"} {
+		RegisterEmbeddingDimensions("bedrock", pattern, EmbeddingDimensionSupport{})
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbeddingDimensionsSuite struct {
+	suite.Suite
+}
+
+func TestEmbeddingDimensionsSuite(t *testing.T) {
+	suite.Run(t, new(EmbeddingDimensionsSuite))
+}
+
+func (s *EmbeddingDimensionsSuite) TestNonPositiveDimensionsAreRejected() {
+	for _, dims := range []int{0, -8} {
+		cfg := ResolveGeneratorOpts(WithEmbeddingDimensions(dims), WithIgnoreInvalidGeneratorOptions(true))
+		_, err := ResolveEmbeddingDimensions(cfg, "ollama", "nomic-embed-text", nil)
+		s.Require().Error(err)
+		s.Contains(err.Error(), "must be greater than zero")
+	}
+
+	dims, err := ResolveEmbeddingDimensions(ResolveGeneratorOpts(), "openai", "text-embedding-3-small", nil)
+	s.Require().NoError(err)
+	s.Nil(dims)
+}
+
+func (s *EmbeddingDimensionsSuite) TestRegisteredRangesAreChecked() {
+	cfg := ResolveGeneratorOpts(WithEmbeddingDimensions(3072))
+	dims, err := ResolveEmbeddingDimensions(cfg, "openai", "text-embedding-3-large", nil)
+	s.Require().NoError(err)
+	s.Equal(3072, *dims)
+
+	_, err = ResolveEmbeddingDimensions(cfg, "openai", "text-embedding-3-small", nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "between 1 and 1536")
+
+	_, err = ResolveEmbeddingDimensions(cfg, "openai", "text-embedding-ada-002", nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not supported by openai model")
+
+	_, err = ResolveEmbeddingDimensions(ResolveGeneratorOpts(WithEmbeddingDimensions(768)), "voyage", "voyage-3.5-lite", nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "one of [256 512 1024 2048]")
+
+	dims, err = ResolveEmbeddingDimensions(ResolveGeneratorOpts(WithEmbeddingDimensions(512)), "bedrock", "amazon.titan-embed-text-v2:0", nil)
+	s.Require().NoError(err)
+	s.Equal(512, *dims)
+}
+
+func (s *EmbeddingDimensionsSuite) TestUnsupportedDimensionsAreDroppedWhenIgnored() {
+	log := &debugRecorder{}
+	cfg := ResolveGeneratorOpts(WithEmbeddingDimensions(4096), WithIgnoreInvalidGeneratorOptions(true))
+	dims, err := ResolveEmbeddingDimensions(cfg, "gemini", "gemini-embedding-001", log)
+	s.Require().NoError(err)
+	s.Nil(dims)
+	s.Require().Len(log.lines, 1)
+	s.Contains(log.lines[0], `option="embedding_dimensions" action="dropped"`)
+}
+
+func (s *EmbeddingDimensionsSuite) TestUnregisteredModelsPassThrough() {
+	cfg := ResolveGeneratorOpts(WithEmbeddingDimensions(4096))
+	dims, err := ResolveEmbeddingDimensions(cfg, "ollama", "nomic-embed-text", nil)
+	s.Require().NoError(err)
+	s.Equal(4096, *dims)
+
+	RegisterEmbeddingDimensions("ollama", "nomic-embed-text*", EmbeddingDimensionSupport{Min: 64, Max: 768})
+	defer func() {
+		embeddingDimensionRegistryMu.Lock()
+		delete(embeddingDimensionRegistry, "ollama")
+		embeddingDimensionRegistryMu.Unlock()
+	}()
+	_, err = ResolveEmbeddingDimensions(cfg, "ollama", "nomic-embed-text:v1.5", nil)
+	s.Error(err)
+}
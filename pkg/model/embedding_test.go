@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbeddingSuite struct {
+	suite.Suite
+}
+
+func TestEmbeddingSuite(t *testing.T) {
+	suite.Run(t, new(EmbeddingSuite))
+}
+
+func (s *EmbeddingSuite) TestTruncateEmbeddingsRenormalizes() {
+	original := EmbeddingVectors{{3, 4, 12}, {0, 0, 1}}
+	truncated, err := TruncateEmbeddings(original, 2)
+	s.Require().NoError(err)
+	s.Equal(EmbeddingVectors{{0.6, 0.8}, {0, 0}}, truncated)
+	s.Equal(EmbeddingVector{3, 4, 12}, original[0])
+}
+
+func (s *EmbeddingSuite) TestTruncateEmbeddingsRejectsInvalidSizes() {
+	_, err := TruncateEmbeddings(EmbeddingVectors{{1, 2}}, 3)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "exceed the model's 2 dimensions")
+
+	_, err = TruncateEmbeddings(EmbeddingVectors{{1, 2}}, 0)
+	s.Require().Error(err)
+
+	s.Error(ValidateEmbeddingDimensions(ResolveGeneratorOpts(WithEmbeddingDimensions(-1))))
+	s.NoError(ValidateEmbeddingDimensions(ResolveGeneratorOpts()))
+}
@@ -0,0 +1,60 @@
+package model
+
+import "strings"
+
+// Metadata keys set from WithPromptVersion and WithExperiment.
+const (
+	MetadataKeyPromptVersion     = "prompt_version"
+	MetadataKeyExperiment        = "experiment"
+	MetadataKeyExperimentVariant = "experiment_variant"
+)
+
+// WithPromptVersion tags generations with the version of the prompt that
+// produced them, for example a content hash or a release name. The version is
+// only recorded; it does not change the request.
+func WithPromptVersion(id string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.PromptVersion = strings.TrimSpace(id)
+	})
+}
+
+// WithExperiment tags generations with an A/B experiment and the variant this
+// generator serves. Like WithPromptVersion it is only recorded.
+func WithExperiment(name string, variant string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.Experiment = strings.TrimSpace(name)
+		cfg.ExperimentVariant = strings.TrimSpace(variant)
+	})
+}
+
+// SetExperimentMetadata records the prompt version and experiment from cfg in
+// meta. Providers call it for every content generation.
+func SetExperimentMetadata(meta GenerationMetadata, cfg GeneratorConfig) {
+	if meta == nil {
+		return
+	}
+	for key, value := range experimentValues(cfg.PromptVersion, cfg.Experiment, cfg.ExperimentVariant) {
+		meta[key] = value
+	}
+}
+
+// ExperimentLabels returns the prompt version and experiment recorded in
+// meta, keyed by their metadata keys, for use as metric dimensions. Unset
+// values are left out.
+func ExperimentLabels(meta GenerationMetadata) map[string]string {
+	return experimentValues(meta[MetadataKeyPromptVersion], meta[MetadataKeyExperiment], meta[MetadataKeyExperimentVariant])
+}
+
+func experimentValues(promptVersion string, experiment string, variant string) map[string]string {
+	values := map[string]string{}
+	if promptVersion != "" {
+		values[MetadataKeyPromptVersion] = promptVersion
+	}
+	if experiment != "" {
+		values[MetadataKeyExperiment] = experiment
+	}
+	if variant != "" {
+		values[MetadataKeyExperimentVariant] = variant
+	}
+	return values
+}
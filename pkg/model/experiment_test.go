@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExperimentSuite struct {
+	suite.Suite
+}
+
+func TestExperimentSuite(t *testing.T) {
+	suite.Run(t, new(ExperimentSuite))
+}
+
+func (s *ExperimentSuite) TestMetadataAndLabels() {
+	cfg := ResolveGeneratorOpts(WithPromptVersion(" v3 "), WithExperiment("tone", "friendly"))
+	meta := GenerationMetadata{MetadataKeyModel: "m"}
+	SetExperimentMetadata(meta, cfg)
+
+	s.Equal("v3", meta[MetadataKeyPromptVersion])
+	s.Equal("tone", meta[MetadataKeyExperiment])
+	s.Equal("friendly", meta[MetadataKeyExperimentVariant])
+	s.Equal(map[string]string{
+		MetadataKeyPromptVersion:     "v3",
+		MetadataKeyExperiment:        "tone",
+		MetadataKeyExperimentVariant: "friendly",
+	}, ExperimentLabels(meta))
+}
+
+func (s *ExperimentSuite) TestUnsetValuesAreLeftOut() {
+	meta := GenerationMetadata{}
+	SetExperimentMetadata(meta, ResolveGeneratorOpts(WithPromptVersion("v1")))
+
+	s.Equal(GenerationMetadata{MetadataKeyPromptVersion: "v1"}, meta)
+	s.Equal(map[string]string{MetadataKeyPromptVersion: "v1"}, ExperimentLabels(meta))
+	s.Empty(ExperimentLabels(GenerationMetadata{}))
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// GatewayKind names an AI gateway whose header conventions a provider should
+// follow when WithURL points at it.
+type GatewayKind string
+
+const (
+	// GatewayLiteLLM is a LiteLLM proxy.
+	GatewayLiteLLM GatewayKind = "litellm"
+	// GatewayPortkey is the Portkey AI gateway.
+	GatewayPortkey GatewayKind = "portkey"
+	// GatewayKong is Kong AI Gateway (ai-proxy plugin behind key-auth).
+	GatewayKong GatewayKind = "kong"
+)
+
+// GatewayProfile describes the gateway in front of a provider endpoint.
+type GatewayProfile struct {
+	Kind GatewayKind
+	// VirtualKey is the gateway-issued key, sent in the gateway's key header:
+	// x-litellm-api-key (as a bearer value), x-portkey-virtual-key or Kong's
+	// key-auth apikey.
+	VirtualKey string
+	// Metadata carries routing and tracing tags: JSON in x-portkey-metadata
+	// for Portkey, key:value tags in x-litellm-tags for LiteLLM. Kong has no
+	// standard metadata header; use Headers instead.
+	Metadata map[string]string
+	// Headers are extra request headers sent as is; they win over the
+	// headers derived from the fields above.
+	Headers map[string]string
+}
+
+// gatewayHeaderSet lists the header names a gateway kind uses.
+type gatewayHeaderSet struct {
+	virtualKey       string
+	virtualKeyPrefix string
+	metadata         string
+	metadataAsTags   bool
+
+	// Response headers read back into metadata; empty when not reported.
+	cost        string
+	requestID   string
+	model       string
+	cacheStatus string
+}
+
+var gatewayHeaderSets = map[GatewayKind]gatewayHeaderSet{
+	GatewayLiteLLM: {
+		virtualKey:       "x-litellm-api-key",
+		virtualKeyPrefix: "Bearer ",
+		metadata:         "x-litellm-tags",
+		metadataAsTags:   true,
+		cost:             "x-litellm-response-cost",
+		requestID:        "x-litellm-call-id",
+		model:            "x-litellm-model-id",
+	},
+	GatewayPortkey: {
+		virtualKey:  "x-portkey-virtual-key",
+		metadata:    "x-portkey-metadata",
+		requestID:   "x-portkey-trace-id",
+		cacheStatus: "x-portkey-cache-status",
+	},
+	GatewayKong: {
+		virtualKey: "apikey",
+		requestID:  "x-kong-request-id",
+		model:      "x-kong-llm-model",
+	},
+}
+
+// WithGateway routes requests through an AI gateway: the profile's virtual
+// key, metadata and headers are added to every request, and the gateway's
+// cost and routing response headers are reported in the gateway metadata
+// keys. Combine it with WithURL pointing at the gateway.
+func WithGateway(profile GatewayProfile) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.Gateway = &profile
+	})
+}
+
+// GatewayRequestHeaders returns the request headers for profile, or nil when
+// profile is nil. Unknown gateway kinds are an error; an empty Kind sends
+// only Headers.
+func GatewayRequestHeaders(profile *GatewayProfile) (map[string]string, error) {
+	if profile == nil {
+		return nil, nil
+	}
+
+	headers := map[string]string{}
+	if profile.Kind != "" {
+		set, ok := gatewayHeaderSets[profile.Kind]
+		if !ok {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("unsupported gateway kind %q", profile.Kind))
+		}
+		if key := strings.TrimSpace(profile.VirtualKey); key != "" {
+			headers[set.virtualKey] = set.virtualKeyPrefix + key
+		}
+		if len(profile.Metadata) > 0 && set.metadata != "" {
+			value, err := encodeGatewayMetadata(profile.Metadata, set.metadataAsTags)
+			if err != nil {
+				return nil, utils.WrapIfNotNil(err)
+			}
+			headers[set.metadata] = value
+		}
+	}
+	for name, value := range profile.Headers {
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+func encodeGatewayMetadata(metadata map[string]string, asTags bool) (string, error) {
+	if !asTags {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return "", utils.WrapIfNotNil(err)
+		}
+		return string(encoded), nil
+	}
+
+	tags := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		tags = append(tags, key+":"+value)
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ","), nil
+}
+
+// GatewayTotals accumulates gateway response headers across the API calls of
+// one generation. The zero value records nothing.
+type GatewayTotals struct {
+	kind        GatewayKind
+	costs       int
+	cost        float64
+	requestID   string
+	model       string
+	cacheStatus string
+}
+
+// NewGatewayTotals starts the totals for a generation using profile, which
+// may be nil.
+func NewGatewayTotals(profile *GatewayProfile) GatewayTotals {
+	if profile == nil {
+		return GatewayTotals{}
+	}
+	return GatewayTotals{kind: profile.Kind}
+}
+
+// Observe records the gateway headers of one response. Costs are summed;
+// the other values keep the latest response.
+func (t *GatewayTotals) Observe(header http.Header) {
+	set, ok := gatewayHeaderSets[t.kind]
+	if !ok || header == nil {
+		return
+	}
+
+	if set.cost != "" {
+		if cost, err := strconv.ParseFloat(strings.TrimSpace(header.Get(set.cost)), 64); err == nil {
+			t.cost += cost
+			t.costs++
+		}
+	}
+	observeGatewayHeader(header, set.requestID, &t.requestID)
+	observeGatewayHeader(header, set.model, &t.model)
+	observeGatewayHeader(header, set.cacheStatus, &t.cacheStatus)
+}
+
+func observeGatewayHeader(header http.Header, name string, target *string) {
+	if name == "" {
+		return
+	}
+	if value := strings.TrimSpace(header.Get(name)); value != "" {
+		*target = value
+	}
+}
+
+// Apply writes the recorded values into meta. Nothing is written when no
+// gateway is configured.
+func (t GatewayTotals) Apply(meta GenerationMetadata) {
+	if meta == nil || t.kind == "" {
+		return
+	}
+
+	meta[MetadataKeyGateway] = string(t.kind)
+	if t.costs > 0 {
+		meta[MetadataKeyGatewayCost] = strconv.FormatFloat(t.cost, 'f', -1, 64)
+	}
+	if t.requestID != "" {
+		meta[MetadataKeyGatewayRequestID] = t.requestID
+	}
+	if t.model != "" {
+		meta[MetadataKeyGatewayModel] = t.model
+	}
+	if t.cacheStatus != "" {
+		meta[MetadataKeyGatewayCacheStatus] = t.cacheStatus
+	}
+}
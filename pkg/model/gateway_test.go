@@ -0,0 +1,82 @@
+package model
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type GatewaySuite struct {
+	suite.Suite
+}
+
+func TestGatewaySuite(t *testing.T) {
+	suite.Run(t, new(GatewaySuite))
+}
+
+func (s *GatewaySuite) TestRequestHeadersPerKind() {
+	headers, err := GatewayRequestHeaders(nil)
+	s.NoError(err)
+	s.Nil(headers)
+
+	headers, err = GatewayRequestHeaders(&GatewayProfile{
+		Kind:       GatewayLiteLLM,
+		VirtualKey: "sk-virtual",
+		Metadata:   map[string]string{"team": "renal", "env": "prod"},
+	})
+	s.Require().NoError(err)
+	s.Equal(map[string]string{
+		"x-litellm-api-key": "Bearer sk-virtual",
+		"x-litellm-tags":    "env:prod,team:renal",
+	}, headers)
+
+	headers, err = GatewayRequestHeaders(&GatewayProfile{
+		Kind:       GatewayPortkey,
+		VirtualKey: "vk-1",
+		Metadata:   map[string]string{"_user": "u-1"},
+		Headers:    map[string]string{"x-portkey-api-key": "pk-1"},
+	})
+	s.Require().NoError(err)
+	s.Equal(map[string]string{
+		"x-portkey-virtual-key": "vk-1",
+		"x-portkey-metadata":    `{"_user":"u-1"}`,
+		"x-portkey-api-key":     "pk-1",
+	}, headers)
+
+	headers, err = GatewayRequestHeaders(&GatewayProfile{Kind: GatewayKong, VirtualKey: "kong-key", Metadata: map[string]string{"ignored": "x"}})
+	s.Require().NoError(err)
+	s.Equal(map[string]string{"apikey": "kong-key"}, headers)
+
+	_, err = GatewayRequestHeaders(&GatewayProfile{Kind: "envoy"})
+	s.Error(err)
+}
+
+func (s *GatewaySuite) TestTotalsSumCostAndKeepLatestValues() {
+	totals := NewGatewayTotals(&GatewayProfile{Kind: GatewayLiteLLM})
+	first := http.Header{}
+	first.Set("x-litellm-response-cost", "0.0012")
+	first.Set("x-litellm-call-id", "call-1")
+	first.Set("x-litellm-model-id", "deploy-a")
+	totals.Observe(first)
+	second := http.Header{}
+	second.Set("x-litellm-response-cost", "0.0008")
+	second.Set("x-litellm-call-id", "call-2")
+	totals.Observe(second)
+	totals.Observe(nil)
+
+	meta := GenerationMetadata{}
+	totals.Apply(meta)
+	s.Equal(GenerationMetadata{
+		MetadataKeyGateway:          "litellm",
+		MetadataKeyGatewayCost:      "0.002",
+		MetadataKeyGatewayRequestID: "call-2",
+		MetadataKeyGatewayModel:     "deploy-a",
+	}, meta)
+
+	meta = GenerationMetadata{}
+	var none GatewayTotals
+	none.Observe(first)
+	none.Apply(meta)
+	s.Empty(meta)
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// GenerationLimits bounds the sampling options a provider model accepts.
+type GenerationLimits struct {
+	// MaxTemperature is the highest accepted temperature; zero means no upper
+	// bound. Negative temperatures are always rejected.
+	MaxTemperature float64
+	// MaxOutputTokens is the model's output token limit; zero means unknown.
+	MaxOutputTokens int
+}
+
+type outputTokenLimit struct {
+	pattern string
+	limit   int
+}
+
+var (
+	outputTokenLimitsMu sync.RWMutex
+	outputTokenLimits   = map[string][]outputTokenLimit{}
+)
+
+// RegisterMaxOutputTokens registers the output token limit of a provider
+// model. Patterns match like RegisterModelCapabilities.
+func RegisterMaxOutputTokens(provider string, modelPattern string, limit int) {
+	provider = normalizeCapabilityKey(provider)
+	modelPattern = normalizeCapabilityKey(modelPattern)
+	if provider == "" || modelPattern == "" || limit <= 0 {
+		return
+	}
+
+	outputTokenLimitsMu.Lock()
+	defer outputTokenLimitsMu.Unlock()
+
+	entries := outputTokenLimits[provider]
+	for i := range entries {
+		if entries[i].pattern == modelPattern {
+			entries[i].limit = limit
+			return
+		}
+	}
+	outputTokenLimits[provider] = append(entries, outputTokenLimit{pattern: modelPattern, limit: limit})
+}
+
+// MaxOutputTokens returns the registered output token limit of a provider
+// model, or zero when none is registered.
+func MaxOutputTokens(provider string, modelName string) int {
+	provider = normalizeCapabilityKey(provider)
+	modelName = normalizeCapabilityKey(modelName)
+	if provider == "" || modelName == "" {
+		return 0
+	}
+
+	outputTokenLimitsMu.RLock()
+	defer outputTokenLimitsMu.RUnlock()
+
+	entries := outputTokenLimits[provider]
+	patterns := make([]string, len(entries))
+	for i, entry := range entries {
+		patterns[i] = entry.pattern
+	}
+	best := matchModelPattern(patterns, modelName)
+	if best < 0 {
+		return 0
+	}
+	return entries[best].limit
+}
+
+// ApplyGenerationLimits checks cfg.Temperature and cfg.MaxTokens against
+// limits and returns an error for values out of range. With
+// IgnoreInvalidGeneratorOptions they are clamped into range instead, and a
+// MaxTokens below one is dropped so the provider default applies; each change
+// is logged as a warning and with LogOptionResolution.
+func ApplyGenerationLimits(cfg GeneratorConfig, provider string, modelName string, limits GenerationLimits, log logging.Logger) (GeneratorConfig, error) {
+	if cfg.Temperature != nil {
+		temperature := *cfg.Temperature
+		clamped := max(temperature, 0)
+		if limits.MaxTemperature > 0 {
+			clamped = min(clamped, limits.MaxTemperature)
+		}
+		if clamped != temperature {
+			if !cfg.IgnoreInvalidGeneratorOptions {
+				return cfg, utils.WrapIfNotNil(fmt.Errorf("temperature %v is out of range %s for %s provider", temperature, temperatureRange(limits), provider))
+			}
+			if log != nil {
+				log.Warnf("clamping temperature %v to %v for %s provider", temperature, clamped, provider)
+			}
+			LogOptionResolution(log, provider, "temperature", OptionActionClamped, strconv.FormatFloat(clamped, 'g', -1, 64))
+			cfg.Temperature = &clamped
+		}
+	}
+
+	if cfg.MaxTokens != nil {
+		maxTokens := *cfg.MaxTokens
+		switch {
+		case maxTokens < 1:
+			if !cfg.IgnoreInvalidGeneratorOptions {
+				return cfg, utils.WrapIfNotNil(fmt.Errorf("max tokens must be positive, got %d", maxTokens))
+			}
+			if log != nil {
+				log.Warnf("ignoring max tokens %d for %s provider: must be positive", maxTokens, provider)
+			}
+			LogOptionResolution(log, provider, "max_tokens", OptionActionDropped, "must be positive")
+			cfg.MaxTokens = nil
+		case limits.MaxOutputTokens > 0 && maxTokens > limits.MaxOutputTokens:
+			if !cfg.IgnoreInvalidGeneratorOptions {
+				return cfg, utils.WrapIfNotNil(fmt.Errorf("max tokens %d exceeds the %d output token limit of model %q", maxTokens, limits.MaxOutputTokens, modelName))
+			}
+			if log != nil {
+				log.Warnf("clamping max tokens %d to the %d output token limit of model %q", maxTokens, limits.MaxOutputTokens, modelName)
+			}
+			LogOptionResolution(log, provider, "max_tokens", OptionActionClamped, strconv.Itoa(limits.MaxOutputTokens))
+			clamped := limits.MaxOutputTokens
+			cfg.MaxTokens = &clamped
+		}
+	}
+	return cfg, nil
+}
+
+func temperatureRange(limits GenerationLimits) string {
+	if limits.MaxTemperature > 0 {
+		return fmt.Sprintf("[0, %v]", limits.MaxTemperature)
+	}
+	return "[0, inf)"
+}
+
+func init() {
+	for pattern, limit := range map[string]int{
+		"gpt-4o*":      16384,
+		"gpt-4.1*":     32768,
+		"gpt-5*":       128000,
+		"o1*":          100000,
+		"o1-mini*":     65536,
+		"o3*":          100000,
+		"o4-mini*":     100000,
+		"gpt-4-turbo*": 4096,
+	} {
+		RegisterMaxOutputTokens("openai", pattern, limit)
+	}
+	for pattern, limit := range map[string]int{
+		"claude-3-haiku*":    4096,
+		"claude-3-opus*":     4096,
+		"claude-3-5-sonnet*": 8192,
+		"claude-3-5-haiku*":  8192,
+		"claude-3-7-sonnet*": 64000,
+		"claude-sonnet-4*":   64000,
+		"claude-opus-4*":     32000,
+		"claude-opus-4-5*":   64000,
+		"claude-haiku-4-5*":  64000,
+	} {
+		RegisterMaxOutputTokens("anthropic", pattern, limit)
+	}
+	for pattern, limit := range map[string]int{
+		"gemini-1.5*": 8192,
+		"gemini-2.0*": 8192,
+		"gemini-2.5*": 65536,
+	} {
+		RegisterMaxOutputTokens("gemini", pattern, limit)
+	}
+}
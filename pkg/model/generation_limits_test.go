@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type GenerationLimitsSuite struct {
+	suite.Suite
+}
+
+func TestGenerationLimitsSuite(t *testing.T) {
+	suite.Run(t, new(GenerationLimitsSuite))
+}
+
+func (s *GenerationLimitsSuite) TestMaxOutputTokensMatchesLongestPattern() {
+	s.Equal(65536, MaxOutputTokens("openai", "o1-mini-2024-09-12"))
+	s.Equal(100000, MaxOutputTokens("openai", "o1"))
+	s.Equal(64000, MaxOutputTokens("anthropic", "claude-opus-4-5-20251101"))
+	s.Equal(32000, MaxOutputTokens("anthropic", "claude-opus-4-1-20250805"))
+	s.Zero(MaxOutputTokens("openai", "unknown-model"))
+
+	RegisterMaxOutputTokens("unit-test-provider", "local*", 2048)
+	s.Equal(2048, MaxOutputTokens("unit-test-provider", "Local-Model"))
+}
+
+func (s *GenerationLimitsSuite) TestOutOfRangeValuesAreRejected() {
+	limits := GenerationLimits{MaxTemperature: 1, MaxOutputTokens: 4096}
+
+	_, err := ApplyGenerationLimits(ResolveGeneratorOpts(WithTemperature(1.5)), "test", "m", limits, nil)
+	s.ErrorContains(err, "temperature 1.5 is out of range [0, 1] for test provider")
+
+	_, err = ApplyGenerationLimits(ResolveGeneratorOpts(WithTemperature(-0.1)), "test", "m", GenerationLimits{}, nil)
+	s.ErrorContains(err, "out of range [0, inf)")
+
+	_, err = ApplyGenerationLimits(ResolveGeneratorOpts(WithMaxTokens(0)), "test", "m", limits, nil)
+	s.ErrorContains(err, "max tokens must be positive")
+
+	_, err = ApplyGenerationLimits(ResolveGeneratorOpts(WithMaxTokens(5000)), "test", "m", limits, nil)
+	s.ErrorContains(err, `max tokens 5000 exceeds the 4096 output token limit of model "m"`)
+
+	cfg, err := ApplyGenerationLimits(ResolveGeneratorOpts(WithTemperature(1), WithMaxTokens(4096)), "test", "m", limits, nil)
+	s.Require().NoError(err)
+	s.Equal(1.0, *cfg.Temperature)
+	s.Equal(4096, *cfg.MaxTokens)
+}
+
+func (s *GenerationLimitsSuite) TestOutOfRangeValuesAreClampedWhenIgnored() {
+	limits := GenerationLimits{MaxTemperature: 1, MaxOutputTokens: 4096}
+	temperature := 1.5
+	input := ResolveGeneratorOpts(WithIgnoreInvalidGeneratorOptions(true), WithMaxTokens(5000))
+	input.Temperature = &temperature
+	log := &debugRecorder{}
+
+	cfg, err := ApplyGenerationLimits(input, "test", "m", limits, log)
+	s.Require().NoError(err)
+	s.Equal(1.0, *cfg.Temperature)
+	s.Equal(4096, *cfg.MaxTokens)
+	s.Equal(1.5, temperature, "the caller's value is not modified")
+	s.Contains(log.lines, `option resolution provider="test" option="max_tokens" action="clamped" detail="4096"`)
+
+	cfg, err = ApplyGenerationLimits(ResolveGeneratorOpts(WithIgnoreInvalidGeneratorOptions(true), WithMaxTokens(-1)), "test", "m", limits, nil)
+	s.Require().NoError(err)
+	s.Nil(cfg.MaxTokens)
+}
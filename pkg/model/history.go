@@ -0,0 +1,143 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// ConversationHistoryVersion is the current provider-neutral history format version.
+const ConversationHistoryVersion = 1
+
+type HistoryRole string
+
+const (
+	HistoryRoleSystem    HistoryRole = "system"
+	HistoryRoleUser      HistoryRole = "user"
+	HistoryRoleAssistant HistoryRole = "assistant"
+	HistoryRoleTool      HistoryRole = "tool"
+)
+
+// ConversationHistory is a provider-neutral record of a generation flow:
+// contexts, the prompt, assistant turns, tool calls and tool results.
+type ConversationHistory struct {
+	Version  int              `json:"version"`
+	Provider string           `json:"provider,omitempty"`
+	Model    string           `json:"model,omitempty"`
+	Messages []HistoryMessage `json:"messages"`
+}
+
+type HistoryMessage struct {
+	Role    HistoryRole `json:"role"`
+	Content string      `json:"content,omitempty"`
+	// ToolCalls are the tool invocations requested by an assistant turn.
+	ToolCalls []HistoryToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID and ToolName identify the call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	ToolName   string `json:"tool_name,omitempty"`
+}
+
+type HistoryToolCall struct {
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// HistoryExporter is implemented by generators that record the messages of
+// their most recent Generate call.
+type HistoryExporter interface {
+	ExportHistory() ConversationHistory
+}
+
+// ParseConversationHistory decodes and validates history JSON.
+func ParseConversationHistory(data []byte) (ConversationHistory, error) {
+	var history ConversationHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return ConversationHistory{}, utils.WrapIfNotNil(err)
+	}
+	if history.Version == 0 {
+		history.Version = ConversationHistoryVersion
+	}
+	if history.Version > ConversationHistoryVersion {
+		return ConversationHistory{}, utils.WrapIfNotNil(fmt.Errorf("unsupported conversation history version %d", history.Version))
+	}
+
+	for i, message := range history.Messages {
+		switch message.Role {
+		case HistoryRoleSystem, HistoryRoleUser, HistoryRoleAssistant, HistoryRoleTool:
+		default:
+			return ConversationHistory{}, utils.WrapIfNotNil(fmt.Errorf("unsupported role %q at message %d", message.Role, i))
+		}
+	}
+	return history, nil
+}
+
+// ImportHistory replays history into gen as prompt contexts so a new generator
+// can resume a conversation. Tool calls and results are rendered as text
+// because providers cannot accept tool blocks they did not produce themselves.
+// It returns the number of contexts added.
+func ImportHistory[T any](ctx context.Context, gen ContentGenerator[T], history ConversationHistory) int {
+	if gen == nil {
+		return 0
+	}
+
+	added := 0
+	for _, message := range history.Messages {
+		messageType, content := historyMessageAsContext(message)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		gen.AddPromptContext(ctx, messageType, content)
+		added++
+	}
+	return added
+}
+
+func historyMessageAsContext(message HistoryMessage) (ContextMessageType, string) {
+	switch message.Role {
+	case HistoryRoleSystem:
+		return ContextMessageTypeSystem, message.Content
+	case HistoryRoleAssistant:
+		parts := make([]string, 0, len(message.ToolCalls)+1)
+		if strings.TrimSpace(message.Content) != "" {
+			parts = append(parts, message.Content)
+		}
+		for _, call := range message.ToolCalls {
+			arguments := strings.TrimSpace(string(call.Arguments))
+			if arguments == "" {
+				arguments = "{}"
+			}
+			parts = append(parts, fmt.Sprintf("Called tool %s with arguments %s", call.Name, arguments))
+		}
+		return ContextMessageTypeAssistant, strings.Join(parts, "\n")
+	case HistoryRoleTool:
+		name := strings.TrimSpace(message.ToolName)
+		if name == "" {
+			name = "tool"
+		}
+		return ContextMessageTypeHuman, fmt.Sprintf("Tool result for %s:\n%s", name, message.Content)
+	default:
+		return ContextMessageTypeHuman, message.Content
+	}
+}
+
+// Clone returns a deep copy of h so callers can modify it freely.
+func (h ConversationHistory) Clone() ConversationHistory {
+	out := h
+	out.Messages = make([]HistoryMessage, 0, len(h.Messages))
+	for _, message := range h.Messages {
+		copied := message
+		if len(message.ToolCalls) > 0 {
+			copied.ToolCalls = make([]HistoryToolCall, 0, len(message.ToolCalls))
+			for _, call := range message.ToolCalls {
+				call.Arguments = append(json.RawMessage(nil), call.Arguments...)
+				copied.ToolCalls = append(copied.ToolCalls, call)
+			}
+		}
+		out.Messages = append(out.Messages, copied)
+	}
+	return out
+}
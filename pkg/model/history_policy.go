@@ -0,0 +1,222 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// HistoryPolicy selects the part of a ChatSession history sent with each turn.
+// The session keeps the full history; the policy only shapes the prompt
+// contexts. Policies keep system messages and a suffix of the conversation.
+type HistoryPolicy interface {
+	Window(ctx context.Context, history []ChatMessage) ([]ChatMessage, error)
+}
+
+// HistoryPolicyFunc adapts a function to HistoryPolicy.
+type HistoryPolicyFunc func(ctx context.Context, history []ChatMessage) ([]ChatMessage, error)
+
+func (f HistoryPolicyFunc) Window(ctx context.Context, history []ChatMessage) ([]ChatMessage, error) {
+	return f(ctx, history)
+}
+
+// LastTurnsPolicy keeps system messages and the last n turns. A turn starts
+// at a human message and includes the replies that follow it. n <= 0 keeps
+// only system messages.
+func LastTurnsPolicy(n int) HistoryPolicy {
+	return HistoryPolicyFunc(func(ctx context.Context, history []ChatMessage) ([]ChatMessage, error) {
+		cut := len(history)
+		for turns := 0; turns < n && cut > 0; {
+			cut--
+			if history[cut].Role == ContextMessageTypeHuman {
+				turns++
+			}
+		}
+		if n > 0 {
+			// Replies before the first human message belong to the oldest kept turn.
+			for cut > 0 && history[cut-1].Role == ContextMessageTypeAssistant && !hasHumanBefore(history, cut-1) {
+				cut--
+			}
+		}
+		return keepFrom(history, cut), nil
+	})
+}
+
+// TokenWindowPolicy keeps system messages and the newest whole turns whose
+// estimated size (see EstimateTokens), together with the system messages,
+// fits in maxTokens. System messages are kept even when they alone exceed it.
+func TokenWindowPolicy(maxTokens int) HistoryPolicy {
+	return HistoryPolicyFunc(func(ctx context.Context, history []ChatMessage) ([]ChatMessage, error) {
+		used := 0
+		for _, message := range history {
+			if message.Role == ContextMessageTypeSystem {
+				used += EstimateTokens(message.Content)
+			}
+		}
+
+		cut := len(history)
+		turnTokens := 0
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Role == ContextMessageTypeSystem {
+				continue
+			}
+			turnTokens += EstimateTokens(history[i].Content)
+			if history[i].Role != ContextMessageTypeHuman && i > 0 {
+				continue
+			}
+			if used+turnTokens > maxTokens {
+				break
+			}
+			used += turnTokens
+			turnTokens = 0
+			cut = i
+		}
+		return keepFrom(history, cut), nil
+	})
+}
+
+// SummarizeOverflowPolicy applies window and replaces the conversation it
+// drops with a system message summarizing it, generated with factory and
+// opts. The summary is updated incrementally as more of the conversation
+// overflows. Summary generation usage is not reported in the Send metadata.
+func SummarizeOverflowPolicy(window HistoryPolicy, factory NewStringContentGeneratorFunc, opts ...GeneratorOption) HistoryPolicy {
+	return &summarizeOverflowPolicy{
+		window:  window,
+		factory: factory,
+		opts:    append([]GeneratorOption(nil), opts...),
+	}
+}
+
+const historySummaryPrefix = "Summary of the earlier conversation:\n"
+
+type summarizeOverflowPolicy struct {
+	window  HistoryPolicy
+	factory NewStringContentGeneratorFunc
+	opts    []GeneratorOption
+
+	mu         sync.Mutex
+	summarized []ChatMessage
+	summary    string
+}
+
+func (p *summarizeOverflowPolicy) Window(ctx context.Context, history []ChatMessage) ([]ChatMessage, error) {
+	if p.window == nil || p.factory == nil {
+		return nil, utils.WrapIfNotNil(errors.New("summarize overflow policy requires a window policy and a generator factory"))
+	}
+
+	kept, err := p.window.Window(ctx, history)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	conversation := make([]ChatMessage, 0, len(history))
+	for _, message := range history {
+		if message.Role != ContextMessageTypeSystem {
+			conversation = append(conversation, message)
+		}
+	}
+	keptConversation := 0
+	for _, message := range kept {
+		if message.Role != ContextMessageTypeSystem {
+			keptConversation++
+		}
+	}
+	dropped := conversation[:len(conversation)-min(keptConversation, len(conversation))]
+	if len(dropped) == 0 {
+		return kept, nil
+	}
+
+	summary, err := p.summarize(ctx, dropped)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	out := make([]ChatMessage, 0, len(kept)+1)
+	inserted := false
+	for _, message := range kept {
+		if !inserted && message.Role != ContextMessageTypeSystem {
+			out = append(out, ChatMessage{Role: ContextMessageTypeSystem, Content: historySummaryPrefix + summary})
+			inserted = true
+		}
+		out = append(out, message)
+	}
+	if !inserted {
+		out = append(out, ChatMessage{Role: ContextMessageTypeSystem, Content: historySummaryPrefix + summary})
+	}
+	return out, nil
+}
+
+// summarize returns the summary of dropped, extending the previous summary
+// when dropped continues the conversation it covered.
+func (p *summarizeOverflowPolicy) summarize(ctx context.Context, dropped []ChatMessage) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := ""
+	pending := dropped
+	if len(p.summarized) > 0 && len(p.summarized) <= len(dropped) && equalChatMessages(p.summarized, dropped[:len(p.summarized)]) {
+		if len(p.summarized) == len(dropped) {
+			return p.summary, nil
+		}
+		previous = p.summary
+		pending = dropped[len(p.summarized):]
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Summarize the conversation below so it can replace the original messages. Keep facts, decisions, names, numbers and open questions. Answer with the summary only.\n\n")
+	if previous != "" {
+		prompt.WriteString("Summary so far:\n" + previous + "\n\n")
+	}
+	prompt.WriteString("Messages:\n")
+	for _, message := range pending {
+		prompt.WriteString(string(message.Role) + ": " + message.Content + "\n")
+	}
+
+	gen, err := p.factory(prompt.String(), p.opts...)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	summary, _, err := gen.Generate(ctx)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+
+	p.summarized = append([]ChatMessage(nil), dropped...)
+	p.summary = strings.TrimSpace(summary)
+	return p.summary, nil
+}
+
+// keepFrom returns the system messages before cut and every message from cut on.
+func keepFrom(history []ChatMessage, cut int) []ChatMessage {
+	out := make([]ChatMessage, 0, len(history))
+	for i, message := range history {
+		if i >= cut || message.Role == ContextMessageTypeSystem {
+			out = append(out, message)
+		}
+	}
+	return out
+}
+
+func hasHumanBefore(history []ChatMessage, index int) bool {
+	for i := 0; i < index; i++ {
+		if history[i].Role == ContextMessageTypeHuman {
+			return true
+		}
+	}
+	return false
+}
+
+func equalChatMessages(a, b []ChatMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
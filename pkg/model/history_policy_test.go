@@ -0,0 +1,145 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HistoryPolicySuite struct {
+	suite.Suite
+}
+
+func TestHistoryPolicySuite(t *testing.T) {
+	suite.Run(t, new(HistoryPolicySuite))
+}
+
+func policyHistory() []ChatMessage {
+	return []ChatMessage{
+		{Role: ContextMessageTypeSystem, Content: "be brief"},
+		{Role: ContextMessageTypeHuman, Content: "q1"},
+		{Role: ContextMessageTypeAssistant, Content: "a1"},
+		{Role: ContextMessageTypeHuman, Content: "q2"},
+		{Role: ContextMessageTypeAssistant, Content: "a2"},
+		{Role: ContextMessageTypeSystem, Content: "use metric units"},
+		{Role: ContextMessageTypeHuman, Content: "q3"},
+		{Role: ContextMessageTypeAssistant, Content: "a3"},
+	}
+}
+
+func contents(messages []ChatMessage) []string {
+	out := make([]string, 0, len(messages))
+	for _, message := range messages {
+		out = append(out, message.Content)
+	}
+	return out
+}
+
+func (s *HistoryPolicySuite) TestLastTurnsKeepsSystemMessagesInOrder() {
+	window, err := LastTurnsPolicy(1).Window(context.Background(), policyHistory())
+	s.Require().NoError(err)
+	s.Equal([]string{"be brief", "use metric units", "q3", "a3"}, contents(window))
+
+	window, err = LastTurnsPolicy(2).Window(context.Background(), policyHistory())
+	s.Require().NoError(err)
+	s.Equal([]string{"be brief", "q2", "a2", "use metric units", "q3", "a3"}, contents(window))
+
+	window, err = LastTurnsPolicy(10).Window(context.Background(), policyHistory())
+	s.Require().NoError(err)
+	s.Equal(policyHistory(), window)
+
+	window, err = LastTurnsPolicy(0).Window(context.Background(), policyHistory())
+	s.Require().NoError(err)
+	s.Equal([]string{"be brief", "use metric units"}, contents(window))
+}
+
+func (s *HistoryPolicySuite) TestTokenWindowKeepsWholeTurnsThatFit() {
+	history := []ChatMessage{
+		{Role: ContextMessageTypeSystem, Content: strings.Repeat("s", 40)},
+		{Role: ContextMessageTypeHuman, Content: strings.Repeat("x", 400)},
+		{Role: ContextMessageTypeAssistant, Content: strings.Repeat("x", 40)},
+		{Role: ContextMessageTypeHuman, Content: strings.Repeat("y", 40)},
+		{Role: ContextMessageTypeAssistant, Content: strings.Repeat("y", 40)},
+	}
+
+	// 10 system tokens + 20 for the last turn; the first turn needs 110 more.
+	window, err := TokenWindowPolicy(100).Window(context.Background(), history)
+	s.Require().NoError(err)
+	s.Equal([]ChatMessage{history[0], history[3], history[4]}, window)
+
+	window, err = TokenWindowPolicy(140).Window(context.Background(), history)
+	s.Require().NoError(err)
+	s.Equal(history, window)
+
+	window, err = TokenWindowPolicy(5).Window(context.Background(), history)
+	s.Require().NoError(err)
+	s.Equal([]ChatMessage{history[0]}, window)
+}
+
+func (s *HistoryPolicySuite) TestSummarizeOverflowSummarizesDroppedTurnsIncrementally() {
+	var prompts []string
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		prompts = append(prompts, prompt)
+		return &recordingGenerator{reply: " summary " + string(rune('0'+len(prompts))) + " "}, nil
+	}
+	policy := SummarizeOverflowPolicy(LastTurnsPolicy(1), factory)
+
+	history := policyHistory()
+	window, err := policy.Window(context.Background(), history)
+	s.Require().NoError(err)
+	s.Equal([]string{"be brief", "use metric units", historySummaryPrefix + "summary 1", "q3", "a3"}, contents(window))
+	s.Require().Len(prompts, 1)
+	s.Contains(prompts[0], "human: q1\nassistant: a1\nhuman: q2\nassistant: a2\n")
+
+	// The same overflow reuses the summary.
+	_, err = policy.Window(context.Background(), history)
+	s.Require().NoError(err)
+	s.Len(prompts, 1)
+
+	// More overflow extends the previous summary with the new messages only.
+	history = append(history, ChatMessage{Role: ContextMessageTypeHuman, Content: "q4"}, ChatMessage{Role: ContextMessageTypeAssistant, Content: "a4"})
+	window, err = policy.Window(context.Background(), history)
+	s.Require().NoError(err)
+	s.Equal([]string{"be brief", "use metric units", historySummaryPrefix + "summary 2", "q4", "a4"}, contents(window))
+	s.Require().Len(prompts, 2)
+	s.Contains(prompts[1], "Summary so far:\nsummary 1")
+	s.NotContains(prompts[1], "q1")
+	s.Contains(prompts[1], "human: q3\nassistant: a3\n")
+}
+
+func (s *HistoryPolicySuite) TestSummarizeOverflowErrors() {
+	_, err := SummarizeOverflowPolicy(nil, nil).Window(context.Background(), policyHistory())
+	s.Error(err)
+
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{err: errors.New("summarizer down")}, nil
+	}
+	_, err = SummarizeOverflowPolicy(LastTurnsPolicy(1), factory).Window(context.Background(), policyHistory())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "summarizer down")
+}
+
+func (s *HistoryPolicySuite) TestChatSessionSendsWindowButKeepsFullHistory() {
+	var last *recordingGenerator
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		last = &recordingGenerator{prompt: prompt, reply: "ok"}
+		return last, nil
+	}
+	session, err := NewChatSession(factory)
+	s.Require().NoError(err)
+	session.SetHistory(policyHistory())
+	session.SetHistoryPolicy(LastTurnsPolicy(1))
+
+	_, _, err = session.Send(context.Background(), "q4")
+	s.Require().NoError(err)
+
+	var sent []string
+	for _, contextItem := range last.contexts {
+		sent = append(sent, contextItem.Content)
+	}
+	s.Equal([]string{"be brief", "use metric units", "q3", "a3"}, sent)
+	s.Len(session.History(), 10)
+}
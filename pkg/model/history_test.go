@@ -0,0 +1,71 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HistorySuite struct {
+	suite.Suite
+}
+
+func TestHistorySuite(t *testing.T) {
+	suite.Run(t, new(HistorySuite))
+}
+
+func (s *HistorySuite) TestParseConversationHistory() {
+	history, err := ParseConversationHistory([]byte(`{"provider":"ollama","messages":[{"role":"user","content":"hi"}]}`))
+	s.Require().NoError(err)
+	s.Equal(ConversationHistoryVersion, history.Version)
+	s.Equal("ollama", history.Provider)
+	s.Len(history.Messages, 1)
+
+	_, err = ParseConversationHistory([]byte(`{"version":99,"messages":[]}`))
+	s.Error(err)
+
+	_, err = ParseConversationHistory([]byte(`{"messages":[{"role":"human","content":"hi"}]}`))
+	s.Error(err)
+}
+
+func (s *HistorySuite) TestImportHistoryReplaysAsContexts() {
+	history := ConversationHistory{
+		Version: ConversationHistoryVersion,
+		Messages: []HistoryMessage{
+			{Role: HistoryRoleSystem, Content: "be brief"},
+			{Role: HistoryRoleUser, Content: "weather in Oslo?"},
+			{Role: HistoryRoleAssistant, ToolCalls: []HistoryToolCall{{ID: "1", Name: "weather", Arguments: json.RawMessage(`{"city":"Oslo"}`)}}},
+			{Role: HistoryRoleTool, ToolCallID: "1", ToolName: "weather", Content: `{"temp":3}`},
+			{Role: HistoryRoleAssistant, Content: "3 degrees"},
+			{Role: HistoryRoleUser, Content: " "},
+		},
+	}
+
+	gen := &recordingGenerator{}
+	added := ImportHistory[string](context.Background(), gen, history)
+
+	s.Equal(5, added)
+	s.Require().Len(gen.contexts, 5)
+	s.Equal(ContextMessageTypeSystem, gen.contexts[0].MessageType)
+	s.Equal(ContextMessageTypeHuman, gen.contexts[1].MessageType)
+	s.Equal(ContextMessageTypeAssistant, gen.contexts[2].MessageType)
+	s.Equal(`Called tool weather with arguments {"city":"Oslo"}`, gen.contexts[2].Content)
+	s.Equal(ContextMessageTypeHuman, gen.contexts[3].MessageType)
+	s.Equal("Tool result for weather:\n{\"temp\":3}", gen.contexts[3].Content)
+	s.Equal("3 degrees", gen.contexts[4].Content)
+}
+
+func (s *HistorySuite) TestCloneIsIndependent() {
+	original := ConversationHistory{Messages: []HistoryMessage{
+		{Role: HistoryRoleAssistant, ToolCalls: []HistoryToolCall{{Name: "t", Arguments: json.RawMessage(`{}`)}}},
+	}}
+
+	clone := original.Clone()
+	clone.Messages[0].ToolCalls[0].Name = "changed"
+	clone.Messages[0].ToolCalls[0].Arguments[0] = '['
+
+	s.Equal("t", original.Messages[0].ToolCalls[0].Name)
+	s.Equal(`{}`, string(original.Messages[0].ToolCalls[0].Arguments))
+}
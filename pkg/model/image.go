@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// ImagePart is an image attached to a human prompt context (see
+// ImagePromptContext). Set either Data or URL.
+type ImagePart struct {
+	// MIMEType is one of image/png, image/jpeg, image/gif or image/webp.
+	// It is detected from Data when empty; URL images may leave it empty
+	// unless the provider needs it (Gemini).
+	MIMEType string
+	// Data is the raw image.
+	Data []byte
+	// URL is an http(s) URL the provider fetches, or a base64 data URL,
+	// which is decoded into Data.
+	URL string
+}
+
+var supportedImageMIMETypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ImagePromptContext returns a human context carrying text (which may be
+// empty) and images. Add it with AddImagePromptContext or return it from a
+// PromptContextProvider.
+func ImagePromptContext(text string, images ...ImagePart) *PromptContext {
+	return &PromptContext{
+		MessageType: ContextMessageTypeHuman,
+		Content:     text,
+		Images:      append([]ImagePart(nil), images...),
+	}
+}
+
+// AddImagePromptContext adds ImagePromptContext(text, images...) to gen
+// through AddPromptContextProvider, so it works with every generator.
+func AddImagePromptContext(ctx context.Context, gen interface {
+	AddPromptContextProvider(ctx context.Context, provider PromptContextProvider)
+}, text string, images ...ImagePart) {
+	gen.AddPromptContextProvider(ctx, StaticPromptContextProvider(ImagePromptContext(text, images...)))
+}
+
+// StaticPromptContextProvider returns a provider that always supplies
+// contexts, keeping their order and images.
+func StaticPromptContextProvider(contexts ...*PromptContext) PromptContextProvider {
+	return staticPromptContexts(append([]*PromptContext(nil), contexts...))
+}
+
+type staticPromptContexts []*PromptContext
+
+func (s staticPromptContexts) GenerateContext(ctx context.Context) ([]*PromptContext, error) {
+	return append([]*PromptContext(nil), s...), nil
+}
+
+// HasContent reports whether c carries text or images.
+func (c *PromptContext) HasContent() bool {
+	return c != nil && (strings.TrimSpace(c.Content) != "" || len(c.Images) > 0)
+}
+
+// ResolveImageParts validates the images of a context and normalizes them:
+// data URLs are decoded into Data and missing MIME types are detected.
+// Images are only supported on human contexts.
+func ResolveImageParts(contextItem *PromptContext) ([]ImagePart, error) {
+	if contextItem == nil || len(contextItem.Images) == 0 {
+		return nil, nil
+	}
+	if contextItem.MessageType != ContextMessageTypeHuman {
+		return nil, utils.WrapIfNotNil(fmt.Errorf("images are only supported in human prompt contexts, got %q", contextItem.MessageType))
+	}
+
+	out := make([]ImagePart, 0, len(contextItem.Images))
+	for i, part := range contextItem.Images {
+		resolved, err := resolveImagePart(part)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("image %d: %w", i, err))
+		}
+		out = append(out, resolved)
+	}
+	return out, nil
+}
+
+func resolveImagePart(part ImagePart) (ImagePart, error) {
+	url := strings.TrimSpace(part.URL)
+	if len(part.Data) > 0 && url != "" {
+		return ImagePart{}, utils.WrapIfNotNil(errors.New("set either data or url, not both"))
+	}
+
+	if strings.HasPrefix(url, "data:") {
+		mimeType, data, err := decodeImageDataURL(url)
+		if err != nil {
+			return ImagePart{}, utils.WrapIfNotNil(err)
+		}
+		if part.MIMEType == "" {
+			part.MIMEType = mimeType
+		}
+		part.Data = data
+		url = ""
+	}
+
+	part.MIMEType = strings.ToLower(strings.TrimSpace(part.MIMEType))
+	if url != "" {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return ImagePart{}, utils.WrapIfNotNil(fmt.Errorf("unsupported image url %q", url))
+		}
+		part.URL = url
+		if part.MIMEType != "" && !supportedImageMIMETypes[part.MIMEType] {
+			return ImagePart{}, utils.WrapIfNotNil(fmt.Errorf("unsupported image mime type %q", part.MIMEType))
+		}
+		return part, nil
+	}
+
+	if len(part.Data) == 0 {
+		return ImagePart{}, utils.WrapIfNotNil(errors.New("image data or url is required"))
+	}
+	part.URL = ""
+	if part.MIMEType == "" {
+		part.MIMEType = http.DetectContentType(part.Data)
+	}
+	if !supportedImageMIMETypes[part.MIMEType] {
+		return ImagePart{}, utils.WrapIfNotNil(fmt.Errorf("unsupported image mime type %q", part.MIMEType))
+	}
+	return part, nil
+}
+
+func decodeImageDataURL(url string) (string, []byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", nil, utils.WrapIfNotNil(errors.New("image data url must be base64 encoded"))
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, utils.WrapIfNotNil(err)
+	}
+	return strings.TrimSuffix(header, ";base64"), data, nil
+}
+
+// DataURL returns the image as a base64 data URL, or URL for URL images.
+func (p ImagePart) DataURL() string {
+	if len(p.Data) == 0 {
+		return p.URL
+	}
+	return "data:" + p.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
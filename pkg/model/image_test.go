@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ImagePartSuite struct {
+	suite.Suite
+}
+
+func TestImagePartSuite(t *testing.T) {
+	suite.Run(t, new(ImagePartSuite))
+}
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func (s *ImagePartSuite) TestResolveNormalizesImages() {
+	images, err := ResolveImageParts(ImagePromptContext("What is shown?",
+		ImagePart{Data: testPNG},
+		ImagePart{URL: "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString([]byte("jpeg"))},
+		ImagePart{URL: " https://example.org/xray.webp ", MIMEType: "Image/WebP"},
+		ImagePart{URL: "https://example.org/scan"},
+	))
+	s.Require().NoError(err)
+	s.Equal([]ImagePart{
+		{MIMEType: "image/png", Data: testPNG},
+		{MIMEType: "image/jpeg", Data: []byte("jpeg")},
+		{MIMEType: "image/webp", URL: "https://example.org/xray.webp"},
+		{URL: "https://example.org/scan"},
+	}, images)
+
+	s.Equal("data:image/png;base64,"+base64.StdEncoding.EncodeToString(testPNG), images[0].DataURL())
+	s.Equal("https://example.org/scan", images[3].DataURL())
+
+	images, err = ResolveImageParts(&PromptContext{MessageType: ContextMessageTypeHuman, Content: "text only"})
+	s.NoError(err)
+	s.Nil(images)
+}
+
+func (s *ImagePartSuite) TestResolveRejectsInvalidImages() {
+	cases := map[string]*PromptContext{
+		"only supported in human":                 {MessageType: ContextMessageTypeSystem, Images: []ImagePart{{Data: testPNG}}},
+		"either data or url":                      ImagePromptContext("", ImagePart{Data: testPNG, URL: "https://example.org/a.png"}),
+		"data or url is required":                 ImagePromptContext("", ImagePart{MIMEType: "image/png"}),
+		`unsupported image mime type "text/plain`: ImagePromptContext("", ImagePart{Data: []byte("plain text")}),
+		"must be base64 encoded":                  ImagePromptContext("", ImagePart{URL: "data:image/png,raw"}),
+		"unsupported image url":                   ImagePromptContext("", ImagePart{URL: "file:///tmp/a.png"}),
+	}
+	for want, contextItem := range cases {
+		_, err := ResolveImageParts(contextItem)
+		s.Require().Error(err, want)
+		s.Contains(err.Error(), want)
+	}
+}
+
+func (s *ImagePartSuite) TestAddImagePromptContextUsesProvider() {
+	gen := &providerRecordingGenerator{}
+	s.True(ImagePromptContext("", ImagePart{Data: testPNG}).HasContent())
+	s.False((&PromptContext{Content: " "}).HasContent())
+
+	AddImagePromptContext(context.Background(), gen, "Describe.", ImagePart{Data: testPNG})
+	s.Require().Len(gen.providers, 1)
+	contexts, err := gen.providers[0].GenerateContext(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]*PromptContext{{MessageType: ContextMessageTypeHuman, Content: "Describe.", Images: []ImagePart{{Data: testPNG}}}}, contexts)
+}
+
+type providerRecordingGenerator struct {
+	providers []PromptContextProvider
+}
+
+func (g *providerRecordingGenerator) AddPromptContextProvider(ctx context.Context, provider PromptContextProvider) {
+	g.providers = append(g.providers, provider)
+}
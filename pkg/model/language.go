@@ -0,0 +1,297 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// LanguageUndetermined is the ISO 639 code reported when no language could be
+// detected.
+const LanguageUndetermined = "und"
+
+// DefaultLanguageConfidence is the heuristic confidence at or above which a
+// LanguageDetector answers without calling the model.
+const DefaultLanguageConfidence = 0.8
+
+// LanguageGuess is a candidate language as an ISO 639-1 code with a
+// confidence between 0 and 1.
+type LanguageGuess struct {
+	Code       string  `json:"code"`
+	Confidence float64 `json:"confidence"`
+}
+
+// LanguageDetection is the result of LanguageDetector.Detect: the detected
+// language and every candidate, most likely first.
+type LanguageDetection struct {
+	Language   string          `json:"language"`
+	Confidence float64         `json:"confidence"`
+	Candidates []LanguageGuess `json:"candidates,omitempty"`
+}
+
+// DetectLanguage guesses the language of text locally, most likely first.
+// Non-Latin scripts are identified by their Unicode ranges; Latin-script
+// text is scored on common function words and letters of English, Spanish,
+// French, German, Italian, Portuguese and Dutch. Short texts get low
+// confidence. It returns nil when text has no letters.
+func DetectLanguage(text string) []LanguageGuess {
+	scripts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if code := scriptLanguage(r); code != "" {
+			scripts[code]++
+		}
+	}
+	if letters == 0 {
+		return nil
+	}
+
+	// Japanese mixes kana with Han characters.
+	if scripts["ja"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	if scripts["ru"] > 0 && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+		scripts["uk"] = scripts["ru"]
+		delete(scripts, "ru")
+	}
+
+	guesses := make([]LanguageGuess, 0, len(scripts)+len(latinStopwords))
+	latin := letters
+	for code, count := range scripts {
+		latin -= count
+		guesses = append(guesses, LanguageGuess{Code: code, Confidence: float64(count) / float64(letters)})
+	}
+	if latin > 0 {
+		share := float64(latin) / float64(letters)
+		for _, guess := range detectLatinLanguage(text) {
+			guess.Confidence *= share
+			guesses = append(guesses, guess)
+		}
+	}
+
+	sort.SliceStable(guesses, func(i, j int) bool {
+		if guesses[i].Confidence != guesses[j].Confidence {
+			return guesses[i].Confidence > guesses[j].Confidence
+		}
+		return guesses[i].Code < guesses[j].Code
+	})
+	for i := range guesses {
+		guesses[i].Confidence = math.Round(guesses[i].Confidence*1000) / 1000
+	}
+	return guesses
+}
+
+func scriptLanguage(r rune) string {
+	switch {
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return "ja"
+	case unicode.Is(unicode.Han, r):
+		return "zh"
+	case unicode.Is(unicode.Hangul, r):
+		return "ko"
+	case unicode.Is(unicode.Cyrillic, r):
+		return "ru"
+	case unicode.Is(unicode.Arabic, r):
+		return "ar"
+	case unicode.Is(unicode.Hebrew, r):
+		return "he"
+	case unicode.Is(unicode.Greek, r):
+		return "el"
+	case unicode.Is(unicode.Devanagari, r):
+		return "hi"
+	case unicode.Is(unicode.Thai, r):
+		return "th"
+	}
+	return ""
+}
+
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "was", "this", "you", "not", "have", "be", "on", "what", "he", "she"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "es", "por", "con", "para", "una", "del", "no", "se", "su", "está", "pero", "como", "muy"},
+	"fr": {"le", "la", "les", "de", "et", "est", "un", "une", "des", "que", "pour", "dans", "pas", "sur", "avec", "il", "je", "vous", "du", "au", "très"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "mit", "ein", "eine", "zu", "den", "von", "sie", "es", "auf", "für", "dem", "auch", "sich", "hat"},
+	"it": {"il", "la", "di", "che", "e", "è", "un", "una", "per", "non", "sono", "con", "del", "della", "gli", "le", "mi", "ho", "questo", "anche", "molto"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "é", "um", "uma", "para", "com", "não", "do", "da", "em", "no", "na", "por", "mais", "muito"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "ik", "op", "te", "zijn", "met", "voor", "maar", "er", "ook", "je", "wat", "hoe", "heeft"},
+}
+
+// latinLetters are letters that mostly occur in one language.
+var latinLetters = map[rune]string{
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ß': "de", 'ä': "de", 'ö': "de", 'ü': "de",
+	'ã': "pt", 'õ': "pt",
+	'è': "fr", 'ê': "fr", 'ç': "fr", 'œ': "fr",
+	'ì': "it", 'ò': "it",
+	'ĳ': "nl",
+}
+
+// latinSequences are letter sequences that mostly occur in one language.
+var latinSequences = map[string]string{
+	"ij":  "nl",
+	"ção": "pt",
+	"ões": "pt",
+	"th":  "en",
+}
+
+// latinEvidence is the score at which a Latin-script guess gets full weight;
+// lower scores scale the confidence down.
+const latinEvidence = 3
+
+// stopwordLanguages maps each stopword to the languages using it.
+var stopwordLanguages = func() map[string][]string {
+	out := make(map[string][]string)
+	for code, stopwords := range latinStopwords {
+		for _, stopword := range stopwords {
+			out[stopword] = append(out[stopword], code)
+		}
+	}
+	return out
+}()
+
+func detectLatinLanguage(text string) []LanguageGuess {
+	lower := strings.ToLower(text)
+	// A stopword shared by several languages ("de", "la") counts for each of
+	// them in proportion.
+	scores := make(map[string]float64)
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' }) {
+		codes := stopwordLanguages[word]
+		for _, code := range codes {
+			scores[code] += 1 / float64(len(codes))
+		}
+	}
+	for _, r := range lower {
+		if code, ok := latinLetters[r]; ok {
+			scores[code]++
+		}
+	}
+	for sequence, code := range latinSequences {
+		scores[code] += float64(strings.Count(lower, sequence))
+	}
+
+	total, top := 0.0, 0.0
+	for _, score := range scores {
+		total += score
+		top = math.Max(top, score)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	weight := math.Min(1, top/latinEvidence)
+	guesses := make([]LanguageGuess, 0, len(scores))
+	for code, score := range scores {
+		// Scale by how far each language leads the others.
+		share := score / total
+		lead := score / top
+		guesses = append(guesses, LanguageGuess{Code: code, Confidence: weight * math.Min(1, share*lead*2)})
+	}
+	return guesses
+}
+
+// LanguageDetector detects the language of a text with DetectLanguage and,
+// when the heuristic is not confident enough, asks a model.
+type LanguageDetector struct {
+	factory NewStructureContentGeneratorFunc[LanguageDetection]
+	opts    []GeneratorOption
+
+	mu            sync.Mutex
+	minConfidence float64
+}
+
+// NewLanguageDetector returns a detector that falls back to generators from
+// factory and opts. A nil factory uses the heuristic only.
+func NewLanguageDetector(factory NewStructureContentGeneratorFunc[LanguageDetection], opts ...GeneratorOption) *LanguageDetector {
+	return &LanguageDetector{
+		factory:       factory,
+		opts:          append([]GeneratorOption(nil), opts...),
+		minConfidence: DefaultLanguageConfidence,
+	}
+}
+
+// SetMinConfidence sets the heuristic confidence below which the model is
+// asked (default DefaultLanguageConfidence). 0 never asks the model.
+func (d *LanguageDetector) SetMinConfidence(confidence float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.minConfidence = confidence
+}
+
+const languageDetectionPrompt = "Detect the language of the text below. Answer with the ISO 639-1 code of the main language, your confidence between 0 and 1, and other likely languages as candidates.\n\nText:\n"
+
+// Detect returns the language of text. The metadata reports the language in
+// MetadataKeyLanguage and, when the model was asked, the generation usage.
+func (d *LanguageDetector) Detect(ctx context.Context, text string) (LanguageDetection, GenerationMetadata, error) {
+	if strings.TrimSpace(text) == "" {
+		return LanguageDetection{}, nil, utils.WrapIfNotNil(errors.New("text is required"))
+	}
+
+	d.mu.Lock()
+	minConfidence := d.minConfidence
+	d.mu.Unlock()
+
+	guesses := DetectLanguage(text)
+	if d.factory == nil || minConfidence <= 0 || (len(guesses) > 0 && guesses[0].Confidence >= minConfidence) {
+		detection := LanguageDetection{Language: LanguageUndetermined, Candidates: guesses}
+		if len(guesses) > 0 {
+			detection.Language = guesses[0].Code
+			detection.Confidence = guesses[0].Confidence
+		}
+		meta := GenerationMetadata{
+			MetadataKeyLanguage:           detection.Language,
+			MetadataKeyLanguageConfidence: strconv.FormatFloat(detection.Confidence, 'f', -1, 64),
+		}
+		return detection, meta, nil
+	}
+
+	gen, err := d.factory(languageDetectionPrompt+text, d.opts...)
+	if err != nil {
+		return LanguageDetection{}, nil, utils.WrapIfNotNil(err)
+	}
+	detection, meta, err := gen.Generate(ctx)
+	if err != nil {
+		return LanguageDetection{}, meta, utils.WrapIfNotNil(err)
+	}
+
+	detection.Language = strings.ToLower(strings.TrimSpace(detection.Language))
+	if detection.Language == "" {
+		detection.Language = LanguageUndetermined
+	}
+	detection.Confidence = math.Max(0, math.Min(1, detection.Confidence))
+	if meta == nil {
+		meta = GenerationMetadata{}
+	}
+	meta[MetadataKeyLanguage] = detection.Language
+	meta[MetadataKeyLanguageConfidence] = strconv.FormatFloat(detection.Confidence, 'f', -1, 64)
+	return detection, meta, nil
+}
+
+// SetTranscriptLanguage records the language of a transcript in meta:
+// opts.Language when set, otherwise the DetectLanguage guess for transcript.
+// Callers can route the transcript (for example to translation) on it.
+func SetTranscriptLanguage(meta GenerationMetadata, opts AudioOptions, transcript string) {
+	if meta == nil {
+		return
+	}
+	if language := strings.ToLower(strings.TrimSpace(opts.Language)); language != "" {
+		meta[MetadataKeyLanguage] = language
+		return
+	}
+	guesses := DetectLanguage(transcript)
+	if len(guesses) == 0 {
+		return
+	}
+	meta[MetadataKeyLanguage] = guesses[0].Code
+	meta[MetadataKeyLanguageConfidence] = strconv.FormatFloat(guesses[0].Confidence, 'f', -1, 64)
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LanguageDetectionSuite struct {
+	suite.Suite
+}
+
+func TestLanguageDetectionSuite(t *testing.T) {
+	suite.Run(t, new(LanguageDetectionSuite))
+}
+
+type languageGenerator struct {
+	prompt string
+	out    LanguageDetection
+}
+
+func (g *languageGenerator) Generate(ctx context.Context) (LanguageDetection, GenerationMetadata, error) {
+	return g.out, GenerationMetadata{MetadataKeyProvider: "fake"}, nil
+}
+
+func (g *languageGenerator) AddPromptContext(ctx context.Context, messageType ContextMessageType, content string) {
+}
+
+func (g *languageGenerator) AddPromptContextProvider(ctx context.Context, provider PromptContextProvider) {
+}
+
+func (s *LanguageDetectionSuite) TestDetectLanguageHeuristic() {
+	cases := map[string]string{
+		"The patient is stable and the kidney function is improving.":                          "en",
+		"El paciente está estable y la función renal mejora con el tratamiento.":               "es",
+		"Le patient est stable et la fonction rénale s'améliore avec le traitement.":           "fr",
+		"Der Patient ist stabil und die Nierenfunktion hat sich mit der Behandlung verbessert": "de",
+		"Il paziente è stabile e la funzione renale migliora con la terapia.":                  "it",
+		"O paciente está estável e a função renal melhora com o tratamento.":                   "pt",
+		"De patiënt is stabiel en de nierfunctie gaat vooruit met de behandeling.":             "nl",
+		"Пациент стабилен, функция почек улучшается.":                                          "ru",
+		"Пацієнт стабільний, функція нирок покращується.":                                      "uk",
+		"患者の腎機能は改善しています。":                                                                      "ja",
+		"患者病情稳定，肾功能正在改善。":                                                                      "zh",
+		"환자는 안정적입니다.":                                                                          "ko",
+		"المريض مستقر":                                                                         "ar",
+	}
+	for text, want := range cases {
+		guesses := DetectLanguage(text)
+		s.Require().NotEmpty(guesses, text)
+		s.Equal(want, guesses[0].Code, text)
+		s.GreaterOrEqual(guesses[0].Confidence, DefaultLanguageConfidence, text)
+	}
+
+	s.Nil(DetectLanguage("42 / 7.5 !"))
+	short := DetectLanguage("the")
+	s.Require().NotEmpty(short)
+	s.Less(short[0].Confidence, DefaultLanguageConfidence)
+}
+
+func (s *LanguageDetectionSuite) TestDetectorUsesModelOnlyWhenUnsure() {
+	calls := 0
+	var prompt string
+	factory := func(p string, opts ...GeneratorOption) (ContentGenerator[LanguageDetection], error) {
+		calls++
+		prompt = p
+		return &languageGenerator{out: LanguageDetection{Language: " SV ", Confidence: 1.4}}, nil
+	}
+	detector := NewLanguageDetector(factory)
+
+	detection, meta, err := detector.Detect(context.Background(), "The patient is stable and the kidney function is improving.")
+	s.Require().NoError(err)
+	s.Equal("en", detection.Language)
+	s.Equal("en", meta[MetadataKeyLanguage])
+	s.Equal(0, calls)
+
+	detection, meta, err = detector.Detect(context.Background(), "Patienten mår bra")
+	s.Require().NoError(err)
+	s.Equal(1, calls)
+	s.True(strings.HasSuffix(prompt, "Patienten mår bra"))
+	s.Equal(LanguageDetection{Language: "sv", Confidence: 1}, detection)
+	s.Equal("sv", meta[MetadataKeyLanguage])
+	s.Equal("fake", meta[MetadataKeyProvider])
+
+	detector.SetMinConfidence(0)
+	_, _, err = detector.Detect(context.Background(), "Patienten mår bra")
+	s.Require().NoError(err)
+	s.Equal(1, calls)
+
+	detection, _, err = NewLanguageDetector(nil).Detect(context.Background(), "1234")
+	s.Require().NoError(err)
+	s.Equal(LanguageUndetermined, detection.Language)
+
+	_, _, err = detector.Detect(context.Background(), "  ")
+	s.Error(err)
+}
+
+func (s *LanguageDetectionSuite) TestSetTranscriptLanguage() {
+	meta := GenerationMetadata{}
+	SetTranscriptLanguage(meta, AudioOptions{Language: "ES"}, "The patient is stable.")
+	s.Equal("es", meta[MetadataKeyLanguage])
+	s.NotContains(meta, MetadataKeyLanguageConfidence)
+
+	meta = GenerationMetadata{}
+	SetTranscriptLanguage(meta, AudioOptions{}, "The patient is stable and the kidney function is improving.")
+	s.Equal("en", meta[MetadataKeyLanguage])
+	s.Equal("1", meta[MetadataKeyLanguageConfidence])
+}
@@ -2,7 +2,12 @@ package model
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/schema"
 )
 
 // Provider implementation notes:
@@ -66,11 +71,86 @@ const (
 	MetadataKeyToolRounds        = "tool_rounds"
 	MetadataKeyResponseID        = "response_id"
 	MetadataKeyResponseStatus    = "response_status"
+
+	// Prompt cache breakdown for providers that report it. The sum of reads
+	// and writes is also in MetadataKeyCachedInputTokens.
+	MetadataKeyCacheReadInputTokens       = "cache_read_input_tokens"
+	MetadataKeyCacheCreationInputTokens   = "cache_creation_input_tokens"
+	MetadataKeyCacheCreation5mInputTokens = "cache_creation_5m_input_tokens"
+	MetadataKeyCacheCreation1hInputTokens = "cache_creation_1h_input_tokens"
+	// MetadataKeyServiceTier is the provider service tier that served the
+	// request (for example "standard", "priority" or "batch").
+	MetadataKeyServiceTier = "service_tier"
+
+	// MetadataKeyCitations holds the sources cited by built-in web search as
+	// a JSON array of Citation (see ParseCitations).
+	MetadataKeyCitations = "citations"
+	// MetadataKeyWebSearchCalls counts provider-executed web searches.
+	MetadataKeyWebSearchCalls = "web_search_calls"
+	// MetadataKeyWebSearchQueries holds the queries run by built-in web
+	// search as a JSON array of strings (see ParseWebSearchQueries).
+	MetadataKeyWebSearchQueries = "web_search_queries"
+	// MetadataKeyFileAnnotations holds files produced by the code interpreter
+	// or cited by file search as a JSON array of FileAnnotation (see
+	// ParseFileAnnotations).
+	MetadataKeyFileAnnotations = "file_annotations"
+	// MetadataKeyCodeInterpreterCalls counts provider-executed code runs.
+	MetadataKeyCodeInterpreterCalls = "code_interpreter_calls"
+	// MetadataKeyFileSearchCalls counts provider-executed file searches.
+	MetadataKeyFileSearchCalls = "file_search_calls"
+	// MetadataKeyRoundUsage holds the token usage of each API call as a JSON
+	// array of RoundUsage (see ParseRoundUsage).
+	MetadataKeyRoundUsage = "round_usage"
+	// MetadataKeyToolCalls holds every local tool call as a JSON array of
+	// ToolCallRecord (see ParseToolCalls).
+	MetadataKeyToolCalls = "tool_calls"
+	// MetadataKeyStructuredOutputMode is the StructuredOutputMode that
+	// produced a structured result ("native" or "prompt").
+	MetadataKeyStructuredOutputMode = "structured_output_mode"
+	// MetadataKeyContextTokens holds the estimated tokens of each prompt
+	// context and the prompt as a JSON array of ContextTokens (see
+	// WithContextTokenAccounting and ParseContextTokens).
+	MetadataKeyContextTokens = "context_tokens"
+	// MetadataKeyDedupedContexts counts the prompt contexts dropped as
+	// duplicates (see WithContextDedup).
+	MetadataKeyDedupedContexts = "deduped_contexts"
+	// MetadataKeyContextTokensSaved is the estimated tokens removed from
+	// prompt contexts by WithContextCompression.
+	MetadataKeyContextTokensSaved = "context_tokens_saved"
+	// MetadataKeyAIDisclosure is the AI-disclosure notice of the generation
+	// (see WithDisclosure).
+	MetadataKeyAIDisclosure = "ai_disclosure"
+	// MetadataKeyRawOutput holds the model text a structured value was parsed
+	// from (see WithRawOutput).
+	MetadataKeyRawOutput = "raw_output"
+	// MetadataKeyLanguage is the ISO 639-1 code of a detected or configured
+	// language and MetadataKeyLanguageConfidence its confidence (see
+	// LanguageDetector and AudioOptions.Language).
+	MetadataKeyLanguage           = "language"
+	MetadataKeyLanguageConfidence = "language_confidence"
+	// MetadataKeyInputCharacters is the number of characters sent to a
+	// SpeechGenerator, the unit TTS models are usually billed in.
+	MetadataKeyInputCharacters = "input_characters"
+	// MetadataKeyRedactedEntities counts the placeholders inserted by
+	// AudioOptions.RedactPII, as "EMAIL:1,PHONE:2".
+	MetadataKeyRedactedEntities = "redacted_entities"
+
+	// Gateway keys, set when WithGateway is configured (see GatewayTotals).
+	// MetadataKeyGatewayCost is the summed cost reported by the gateway, in
+	// its billing currency (USD for LiteLLM).
+	MetadataKeyGateway            = "gateway"
+	MetadataKeyGatewayCost        = "gateway_cost"
+	MetadataKeyGatewayRequestID   = "gateway_request_id"
+	MetadataKeyGatewayModel       = "gateway_model"
+	MetadataKeyGatewayCacheStatus = "gateway_cache_status"
 )
 
 type PromptContext struct {
 	MessageType ContextMessageType
 	Content     string
+	// Images are attached to human contexts for vision models (see
+	// ImagePromptContext).
+	Images []ImagePart
 }
 type PromptContextProvider interface {
 	GenerateContext(ctx context.Context) ([]*PromptContext, error)
@@ -104,26 +184,122 @@ type GeneratorOpts = GeneratorOption
 //
 // Field semantics:
 //   - IgnoreInvalidGeneratorOptions: ignore unsupported options instead of returning an error.
-//   - URL: override provider endpoint/base URL.
+//   - URL: override provider endpoint/base URL. OpenAI and Ollama also accept
+//     unix:// socket URLs (see ParseUnixSocketURL).
+//   - TLSConfig: optional TLS settings for self-hosted endpoints with a private CA or self-signed certificates (see WithTLSConfig).
+//   - RequestCompressionMinBytes: optional size from which request bodies are gzipped (see WithRequestCompression).
 //   - AuthToken: override provider API token/auth value.
 //   - Temperature: optional sampling temperature for text generation.
 //   - MaxTokens: optional output token limit for text generation.
+//   - TopP: optional nucleus sampling probability mass.
+//   - TopK: optional limit on the number of tokens sampled from.
+//   - StopSequences: optional strings that end generation when produced.
 //   - EmbeddingDimensions: optional embedding size where provider supports it.
+//   - EmbeddingTaskType: optional task the embeddings are optimised for.
+//   - EmbeddingTitle: optional document title for retrieval document embeddings.
 //   - Model: optional explicit model name override.
+//   - FallbackModels: optional models tried in order when the model is unavailable.
+//   - RetryPolicy: optional limits for retrying transient API errors.
+//   - StructuredRepairAttempts: optional re-prompts for unusable structured output.
+//   - Assertions: optional business checks on decoded structured output (see WithAssertion).
+//   - OutputSchema: optional hand-written schema replacing the one reflected from T.
+//   - SchemaOptions: reflector settings for schemas reflected from T.
+//   - FieldProvenance: ask for the tool call behind each structured output field (see WithFieldProvenance).
 //   - ReasoningLevel: optional reasoning effort level for models that support it.
 //   - Tools: optional local function/tool declarations and handlers.
 //   - MCPTools: optional remote MCP tool servers to expose during generation.
+//   - MCPAdapterPool: optional shared MCP connections for adapter-backed providers (see WithMCPAdapterPool).
+//   - MCPBridgeMode: optional choice between the provider's native MCP connector and local bridging (see WithMCPBridgeMode).
+//   - GCPProject: Google Cloud project for providers with a Vertex AI backend.
+//   - GCPLocation: Google Cloud location/region for providers with a Vertex AI backend.
+//   - HostingPlatform: optional cloud platform hosting the model (for example Anthropic models on Bedrock or Vertex AI).
+//   - MaxToolRounds: optional limit on tool-call rounds per generation (default DefaultMaxToolRounds).
+//   - ToolParallelism: optional cap on concurrent tool handler calls within one round (default DefaultToolParallelism; 1 runs calls sequentially).
+//   - ParallelToolCalls: optional switch for several tool calls per response (see WithParallelToolCalls).
+//   - Tenant: optional tenant ID; takes precedence over a tenant set on the context (see pkg/tenant).
+//   - PromptVersion: optional prompt identifier recorded in metadata for analysis.
+//   - Experiment, ExperimentVariant: optional A/B experiment recorded in metadata.
+//   - ToolInterceptors: optional hooks run around every local tool call (see WithToolInterceptor).
+//   - ToolErrorMode: optional handling of tool handler errors (default ToolErrorModeReport).
+//   - ToolErrorBudget: optional number of failed tool calls reported to the model per generation (default DefaultToolErrorBudget).
+//   - BuiltinTools: optional provider-executed tools such as BuiltinWebSearch (see WithBuiltinTools).
+//   - CodeInterpreter: optional sandbox configuration for BuiltinCodeInterpreter (see WithCodeInterpreter).
+//   - FileSearch: optional vector store configuration for BuiltinFileSearch (see WithFileSearch).
+//   - ProviderParams: optional raw fields merged into each generation request body (see WithProviderParams).
+//   - Gateway: optional AI gateway profile adding key/metadata headers and reading cost headers (see WithGateway).
+//   - CachedContent: optional provider cached content name to generate against (see WithCachedContent).
+//   - ContextTokenAccounting: report estimated tokens per prompt context in metadata (see WithContextTokenAccounting).
+//   - ContextDedup: optional removal of repeated prompt contexts during context assembly (see WithContextDedup).
+//   - ContextCompression: optional shrinking of human prompt contexts during context assembly (see WithContextCompression).
+//   - ServerSideState: chain tool rounds through provider-stored responses instead of resending history (see WithServerSideState).
+//   - Documents: optional PDFs, office documents or text files attached to the prompt (see WithDocuments).
+//   - RawOutput: keep the raw model text of structured generations in metadata (see WithRawOutput).
+//   - PromptCaching: mark stable prompt prefixes as cacheable where the provider needs explicit cache breakpoints.
+//   - StructuredOutputMode: optional native/prompt selection for structured output (default StructuredOutputModeAuto).
+//   - PostProcessors: optional rewrites applied in order to string generation output (see WithPostProcessors).
+//   - OutputTransformers: optional rewrites of raw model text before parsing or post-processing (see WithOutputTransformer).
+//   - PartialStreamOnDeadline: return the text streamed before the context deadline instead of the error (see WithPartialStreamOnDeadline).
+//   - Disclosure: optional AI-disclosure notice added to string output and metadata (see WithDisclosure).
+//   - MetricsRecorder: optional sink for per-generation metrics (see WithMetricsRecorder).
 type GeneratorConfig struct {
 	IgnoreInvalidGeneratorOptions bool
 	URL                           string
+	TLSConfig                     *tls.Config
+	RequestCompressionMinBytes    *int
 	AuthToken                     string
 	Temperature                   *float64
 	MaxTokens                     *int
+	TopP                          *float64
+	TopK                          *int
+	StopSequences                 []string
 	EmbeddingDimensions           *int
+	EmbeddingTaskType             EmbeddingTaskType
+	EmbeddingTitle                string
 	Model                         *string
+	FallbackModels                []string
+	RetryPolicy                   *RetryPolicy
+	StructuredRepairAttempts      *int
+	Assertions                    []Assertion
+	OutputSchema                  JSONSchema
+	SchemaOptions                 schema.Options
+	FieldProvenance               bool
 	ReasoningLevel                *ReasoningLevel
 	Tools                         []Tool
 	MCPTools                      []MCPTool
+	MCPAdapterPool                MCPAdapterPool
+	MCPBridgeMode                 *MCPBridgeMode
+	GCPProject                    string
+	GCPLocation                   string
+	HostingPlatform               *HostingPlatform
+	MaxToolRounds                 *int
+	ToolParallelism               *int
+	ParallelToolCalls             *bool
+	Tenant                        string
+	PromptVersion                 string
+	Experiment                    string
+	ExperimentVariant             string
+	ToolInterceptors              []ToolInterceptor
+	ToolErrorMode                 *ToolErrorMode
+	ToolErrorBudget               *int
+	BuiltinTools                  []BuiltinTool
+	CodeInterpreter               *CodeInterpreterConfig
+	FileSearch                    *FileSearchConfig
+	ProviderParams                map[string]any
+	StructuredOutputMode          *StructuredOutputMode
+	PromptCaching                 bool
+	Gateway                       *GatewayProfile
+	CachedContent                 string
+	ContextTokenAccounting        bool
+	ContextDedup                  *ContextDedupConfig
+	ContextCompression            *ContextCompressionConfig
+	ServerSideState               bool
+	Documents                     []DocumentPart
+	RawOutput                     bool
+	PostProcessors                []PostProcessor
+	OutputTransformers            []OutputTransformer
+	PartialStreamOnDeadline       bool
+	Disclosure                    *DisclosureConfig
+	MetricsRecorder               MetricsRecorder
 }
 
 type ReasoningLevel string
@@ -135,6 +311,24 @@ const (
 	ReasoningLevelHigh ReasoningLevel = "high"
 )
 
+// HostingPlatform selects where a provider's models are served from when the
+// same model family is available through several clouds.
+type HostingPlatform string
+
+const (
+	HostingPlatformDirect  HostingPlatform = "direct"
+	HostingPlatformBedrock HostingPlatform = "bedrock"
+	HostingPlatformVertex  HostingPlatform = "vertex"
+)
+
+// DefaultMaxToolRounds is the number of tool-call rounds a generation may run
+// when WithMaxToolRounds is not set.
+const DefaultMaxToolRounds = 12
+
+// DefaultToolParallelism is the number of tool handlers run concurrently in one
+// round when WithToolParallelism is not set.
+const DefaultToolParallelism = 4
+
 type JSONSchema map[string]any
 
 type Tool struct {
@@ -145,16 +339,24 @@ type Tool struct {
 	// Handler gets raw JSON args (already validated by you if you want),
 	// and returns JSON output.
 	Handler func(ctx context.Context, args json.RawMessage) (any, error)
+
+	// Timeout bounds a single handler invocation. Zero means no limit beyond
+	// the generation context. Providers invoke handlers through Call.
+	Timeout time.Duration
 }
 
 type MCPTool struct {
 	URL  string
 	Name string
 	// AuthToken is used by providers that require MCP auth outside HTTPHeaders (for example, Anthropic authorization_token).
+	// Without an Authorization header, bridged servers send it as a bearer token.
 	AuthToken   string
 	HTTPHeaders map[string]string
 	// AllowedTools restricts exposed MCP tools. If omitted, all server tools are discovered and used.
 	AllowedTools []string
+	// TLSConfig secures bridged connections to a server behind a private CA
+	// or requiring client certificates (see WithTLSConfig for providers).
+	TLSConfig *tls.Config
 }
 
 func ResolveGeneratorOpts(opts ...GeneratorOption) GeneratorConfig {
@@ -203,6 +405,35 @@ func WithMaxTokens(value int) GeneratorOption {
 	})
 }
 
+// WithTopP sets nucleus sampling: only tokens within the top value of
+// probability mass are sampled. Supported by Anthropic.
+func WithTopP(value float64) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.TopP = &value
+	})
+}
+
+// WithTopK samples only from the value most likely tokens. Supported by
+// Anthropic.
+func WithTopK(value int) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.TopK = &value
+	})
+}
+
+// WithStopSequences appends strings that stop generation when the model
+// produces them; the stop sequence itself is not returned. Supported by
+// Anthropic.
+func WithStopSequences(values ...string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		for _, value := range values {
+			if value != "" {
+				cfg.StopSequences = append(cfg.StopSequences, value)
+			}
+		}
+	})
+}
+
 // WithModel sets an explicit model name.
 func WithModel(value string) GeneratorOption {
 	return generatorOptionFunc(func(cfg *GeneratorConfig) {
@@ -210,6 +441,19 @@ func WithModel(value string) GeneratorOption {
 	})
 }
 
+// WithFallbackModels appends models to try, in order, when the configured
+// model is unavailable (for example a cold model that is still loading).
+// Supported by HuggingFace.
+func WithFallbackModels(models ...string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		for _, name := range models {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.FallbackModels = append(cfg.FallbackModels, name)
+			}
+		}
+	})
+}
+
 // WithTools sets local tool/function declarations for tool calling.
 func WithTools(tools []Tool) GeneratorOption {
 	return generatorOptionFunc(func(cfg *GeneratorConfig) {
@@ -224,6 +468,24 @@ func WithMCPTools(tools []MCPTool) GeneratorOption {
 	})
 }
 
+// MCPAdapterPool supplies the tools of an MCP server over connections that
+// outlive a single generation. mcp.AdapterPool implements it.
+type MCPAdapterPool interface {
+	MCPServerTools(ctx context.Context, server MCPTool) ([]Tool, error)
+}
+
+// WithMCPAdapterPool makes providers that bridge MCP through local tools
+// (Gemini, Bedrock, Ollama, HuggingFace, emulation, Anthropic for servers
+// its native connector cannot express, and OpenAI and Anthropic under
+// MCPBridgeModeLocal) take MCPTools
+// connections from pool instead of connecting and disconnecting on every
+// Generate. The pool owns the connections; close it when done.
+func WithMCPAdapterPool(pool MCPAdapterPool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.MCPAdapterPool = pool
+	})
+}
+
 // WithReasoningLevel sets reasoning effort for models/providers that support it.
 func WithReasoningLevel(level ReasoningLevel) GeneratorOption {
 	return generatorOptionFunc(func(cfg *GeneratorConfig) {
@@ -231,6 +493,124 @@ func WithReasoningLevel(level ReasoningLevel) GeneratorOption {
 	})
 }
 
+// WithPromptCaching marks stable prompt prefixes (tool definitions, system
+// prompt, context messages) as cacheable on providers with explicit cache
+// controls. Providers that cache automatically or not at all ignore it; cache
+// usage is reported in the cache metadata keys.
+func WithPromptCaching(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.PromptCaching = enabled
+	})
+}
+
+// WithServerSideState lets providers that keep conversation state on the
+// server chain tool rounds to the previous response instead of resending the
+// whole history each round (OpenAI Responses previous_response_id). The
+// responses are stored by the provider. Other providers ignore it.
+func WithServerSideState(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ServerSideState = enabled
+	})
+}
+
+// WithCachedContent references provider-side cached content by name (for
+// Gemini the name returned by gemini.CachedContentManager.Create), so the
+// cached context is not billed at the full input rate on every generation.
+func WithCachedContent(name string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.CachedContent = strings.TrimSpace(name)
+	})
+}
+
+// WithGCPProject sets the Google Cloud project and selects the Vertex AI backend
+// for providers that support it. Credentials come from Application Default Credentials.
+func WithGCPProject(value string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.GCPProject = value
+	})
+}
+
+// WithGCPLocation sets the Google Cloud location/region and selects the Vertex AI
+// backend for providers that support it.
+func WithGCPLocation(value string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.GCPLocation = value
+	})
+}
+
+// WithHostingPlatform routes requests through a cloud platform (for example
+// Anthropic models on Bedrock or Vertex AI) for providers that support it.
+func WithHostingPlatform(platform HostingPlatform) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.HostingPlatform = &platform
+	})
+}
+
+// WithMaxToolRounds limits how many tool-call rounds a generation may run
+// before failing with a *MaxToolRoundsError. Values below 1 are treated as 1.
+func WithMaxToolRounds(value int) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.MaxToolRounds = &value
+	})
+}
+
+// ResolveMaxToolRounds returns the effective tool round limit for cfg.
+func ResolveMaxToolRounds(cfg GeneratorConfig) int {
+	if cfg.MaxToolRounds == nil {
+		return DefaultMaxToolRounds
+	}
+	if *cfg.MaxToolRounds < 1 {
+		return 1
+	}
+	return *cfg.MaxToolRounds
+}
+
+// WithToolParallelism caps how many tool handlers run concurrently when a
+// response requests several tool calls in one round. Values below 1 are treated as 1.
+func WithToolParallelism(value int) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ToolParallelism = &value
+	})
+}
+
+// WithParallelToolCalls allows or forbids several tool calls in one model
+// response. OpenAI receives it as parallel_tool_calls and Anthropic as
+// disable_parallel_tool_use; every provider also runs the handlers of a round
+// one at a time when it is false, for handlers that must be serialized. Unset
+// leaves the provider default.
+func WithParallelToolCalls(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ParallelToolCalls = &enabled
+	})
+}
+
+// ParallelToolCallsDisabled reports whether WithParallelToolCalls(false) is set.
+func ParallelToolCallsDisabled(cfg GeneratorConfig) bool {
+	return cfg.ParallelToolCalls != nil && !*cfg.ParallelToolCalls
+}
+
+// ResolveToolParallelism returns the effective tool parallelism for cfg. It is
+// 1 when parallel tool calls are disabled.
+func ResolveToolParallelism(cfg GeneratorConfig) int {
+	if ParallelToolCallsDisabled(cfg) {
+		return 1
+	}
+	if cfg.ToolParallelism == nil {
+		return DefaultToolParallelism
+	}
+	if *cfg.ToolParallelism < 1 {
+		return 1
+	}
+	return *cfg.ToolParallelism
+}
+
+// WithTenant scopes the generator to a tenant, overriding any tenant on the context.
+func WithTenant(id string) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.Tenant = id
+	})
+}
+
 // Deprecated: use WithTemperature.
 func Temperature(value float64) GeneratorOption {
 	return WithTemperature(value)
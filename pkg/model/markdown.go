@@ -0,0 +1,411 @@
+package model
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MarkdownToPlainText is a PostProcessor that removes Markdown syntax:
+// emphasis and heading markers are dropped, links become "text (url)",
+// images their alt text, and code blocks keep their content. List markers
+// are kept so items stay readable.
+func MarkdownToPlainText(text string) (string, error) {
+	blocks := parseMarkdownBlocks(strings.Split(normalizeNewlines(text), "\n"))
+	return renderMarkdownPlain(blocks), nil
+}
+
+// MarkdownToHTML is a PostProcessor that renders Markdown as HTML that is
+// safe to embed in a page: raw HTML in the output is escaped, and links and
+// images are only kept for http, https and mailto URLs.
+func MarkdownToHTML(text string) (string, error) {
+	blocks := parseMarkdownBlocks(strings.Split(normalizeNewlines(text), "\n"))
+	return renderMarkdownHTML(blocks), nil
+}
+
+type markdownBlockKind int
+
+const (
+	markdownParagraph markdownBlockKind = iota
+	markdownHeading
+	markdownCode
+	markdownQuote
+	markdownList
+	markdownRule
+	markdownTable
+)
+
+type markdownListItem struct {
+	depth   int
+	ordered bool
+	marker  string
+	text    string
+}
+
+type markdownBlock struct {
+	kind     markdownBlockKind
+	level    int
+	lang     string
+	lines    []string
+	children []markdownBlock
+	items    []markdownListItem
+	rows     [][]string
+}
+
+var (
+	markdownHeadingPattern  = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	markdownFencePattern    = regexp.MustCompile("^\\s{0,3}(```+|~~~+)\\s*([\\w+#.-]*)")
+	markdownRulePattern     = regexp.MustCompile(`^\s{0,3}(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	markdownQuotePattern    = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	markdownListPattern     = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	markdownTableSeparator  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	markdownCodeSpanPattern = regexp.MustCompile("``(.+?)``|`([^`]+)`")
+	markdownImagePattern    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	markdownLinkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	markdownAutolinkPattern = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	markdownEscapedAutolink = regexp.MustCompile(`&lt;((?:https?|mailto):[^&\s]+)&gt;`)
+	markdownStrongPattern   = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	markdownStrikePattern   = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	markdownStarEmPattern   = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`)
+	markdownUnderEmPattern  = regexp.MustCompile(`(^|[^\w])_(\S(?:.*?\S)?)_([^\w]|$)`)
+	markdownEscapePattern   = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!~>|])`)
+)
+
+// markdownEscapeBase maps backslash-escaped characters into the Unicode
+// private use area so inline patterns skip them until rendering is done.
+const markdownEscapeBase = 0xE000
+
+func normalizeNewlines(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
+
+func parseMarkdownBlocks(lines []string) []markdownBlock {
+	var blocks []markdownBlock
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, markdownBlock{kind: markdownParagraph, lines: paragraph})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if match := markdownFencePattern.FindStringSubmatch(line); match != nil {
+			flush()
+			fence := match[1]
+			block := markdownBlock{kind: markdownCode, lang: match[2]}
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+				block.lines = append(block.lines, lines[i])
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+		if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil {
+			flush()
+			blocks = append(blocks, markdownBlock{kind: markdownHeading, level: len(match[1]), lines: []string{match[2]}})
+			continue
+		}
+		if markdownRulePattern.MatchString(line) {
+			flush()
+			blocks = append(blocks, markdownBlock{kind: markdownRule})
+			continue
+		}
+		if markdownQuotePattern.MatchString(line) {
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				match := markdownQuotePattern.FindStringSubmatch(lines[i])
+				if match == nil {
+					break
+				}
+				quoted = append(quoted, match[1])
+			}
+			i--
+			blocks = append(blocks, markdownBlock{kind: markdownQuote, children: parseMarkdownBlocks(quoted)})
+			continue
+		}
+		if markdownListPattern.MatchString(line) {
+			flush()
+			block := markdownBlock{kind: markdownList}
+			for ; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) == "" {
+					break
+				}
+				match := markdownListPattern.FindStringSubmatch(lines[i])
+				if match == nil {
+					// A lazy continuation line of the previous item.
+					last := &block.items[len(block.items)-1]
+					last.text += " " + strings.TrimSpace(lines[i])
+					continue
+				}
+				block.items = append(block.items, markdownListItem{
+					depth:   len(strings.ReplaceAll(match[1], "\t", "  ")) / 2,
+					ordered: !strings.ContainsAny(match[2], "-*+"),
+					marker:  match[2],
+					text:    match[3],
+				})
+			}
+			i--
+			blocks = append(blocks, block)
+			continue
+		}
+		if strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "-") && markdownTableSeparator.MatchString(lines[i+1]) {
+			flush()
+			block := markdownBlock{kind: markdownTable, rows: [][]string{splitMarkdownTableRow(line)}}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				block.rows = append(block.rows, splitMarkdownTableRow(lines[i]))
+			}
+			i--
+			blocks = append(blocks, block)
+			continue
+		}
+		paragraph = append(paragraph, strings.TrimSpace(line))
+	}
+	flush()
+	return blocks
+}
+
+func splitMarkdownTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+func renderMarkdownPlain(blocks []markdownBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.kind {
+		case markdownHeading, markdownParagraph:
+			parts = append(parts, renderMarkdownInline(strings.Join(block.lines, "\n"), false))
+		case markdownCode:
+			parts = append(parts, strings.Join(block.lines, "\n"))
+		case markdownQuote:
+			parts = append(parts, renderMarkdownPlain(block.children))
+		case markdownList:
+			lines := make([]string, 0, len(block.items))
+			for _, item := range block.items {
+				lines = append(lines, strings.Repeat("  ", item.depth)+plainListMarker(item)+" "+renderMarkdownInline(item.text, false))
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		case markdownTable:
+			lines := make([]string, 0, len(block.rows))
+			for _, row := range block.rows {
+				cells := make([]string, len(row))
+				for i, cell := range row {
+					cells[i] = renderMarkdownInline(cell, false)
+				}
+				lines = append(lines, strings.Join(cells, "\t"))
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func plainListMarker(item markdownListItem) string {
+	if !item.ordered {
+		return "-"
+	}
+	return strings.TrimRight(item.marker, ".)") + "."
+}
+
+func renderMarkdownHTML(blocks []markdownBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.kind {
+		case markdownHeading:
+			tag := "h" + strconv.Itoa(block.level)
+			parts = append(parts, "<"+tag+">"+renderMarkdownInline(block.lines[0], true)+"</"+tag+">")
+		case markdownParagraph:
+			parts = append(parts, "<p>"+renderMarkdownInline(strings.Join(block.lines, "\n"), true)+"</p>")
+		case markdownCode:
+			open := "<pre><code>"
+			if block.lang != "" {
+				open = `<pre><code class="language-` + html.EscapeString(block.lang) + `">`
+			}
+			parts = append(parts, open+html.EscapeString(strings.Join(block.lines, "\n"))+"</code></pre>")
+		case markdownQuote:
+			parts = append(parts, "<blockquote>\n"+renderMarkdownHTML(block.children)+"\n</blockquote>")
+		case markdownRule:
+			parts = append(parts, "<hr>")
+		case markdownList:
+			parts = append(parts, renderMarkdownHTMLList(block.items))
+		case markdownTable:
+			parts = append(parts, renderMarkdownHTMLTable(block.rows))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// renderMarkdownHTMLList nests items by depth; an item deeper than the
+// previous one opens a list inside it.
+func renderMarkdownHTMLList(items []markdownListItem) string {
+	var out strings.Builder
+	var open []string
+	for i, item := range items {
+		tag := "ul"
+		if item.ordered {
+			tag = "ol"
+		}
+		depth := item.depth
+		if depth > len(open) {
+			depth = len(open)
+		}
+		for len(open) > depth+1 {
+			out.WriteString("</li></" + open[len(open)-1] + ">")
+			open = open[:len(open)-1]
+		}
+		switch {
+		case len(open) == depth:
+			out.WriteString("<" + tag + ">")
+			open = append(open, tag)
+		case i > 0:
+			out.WriteString("</li>")
+		}
+		out.WriteString("<li>" + renderMarkdownInline(item.text, true))
+	}
+	for len(open) > 0 {
+		out.WriteString("</li></" + open[len(open)-1] + ">")
+		open = open[:len(open)-1]
+	}
+	return out.String()
+}
+
+func renderMarkdownHTMLTable(rows [][]string) string {
+	var out strings.Builder
+	out.WriteString("<table>")
+	for i, row := range rows {
+		cellTag := "td"
+		if i == 0 {
+			cellTag = "th"
+			out.WriteString("<thead>")
+		} else if i == 1 {
+			out.WriteString("<tbody>")
+		}
+		out.WriteString("<tr>")
+		for _, cell := range row {
+			out.WriteString("<" + cellTag + ">" + renderMarkdownInline(cell, true) + "</" + cellTag + ">")
+		}
+		out.WriteString("</tr>")
+		if i == 0 {
+			out.WriteString("</thead>")
+		}
+	}
+	if len(rows) > 1 {
+		out.WriteString("</tbody>")
+	}
+	out.WriteString("</table>")
+	return out.String()
+}
+
+// renderMarkdownInline converts inline syntax outside code spans; code span
+// content is kept verbatim (escaped for HTML).
+func renderMarkdownInline(text string, asHTML bool) string {
+	text = markdownEscapePattern.ReplaceAllStringFunc(text, func(match string) string {
+		return string(rune(markdownEscapeBase + int(match[1])))
+	})
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range markdownCodeSpanPattern.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(renderMarkdownSpans(text[last:loc[0]], asHTML))
+		code := ""
+		if loc[2] >= 0 {
+			code = strings.TrimSpace(text[loc[2]:loc[3]])
+		} else {
+			code = text[loc[4]:loc[5]]
+		}
+		code = restoreMarkdownEscapes(code, true, false)
+		if asHTML {
+			out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+		} else {
+			out.WriteString(code)
+		}
+		last = loc[1]
+	}
+	out.WriteString(renderMarkdownSpans(text[last:], asHTML))
+	return restoreMarkdownEscapes(out.String(), false, asHTML)
+}
+
+func renderMarkdownSpans(text string, asHTML bool) string {
+	if !asHTML {
+		text = markdownImagePattern.ReplaceAllString(text, "$1")
+		text = markdownLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+			parts := markdownLinkPattern.FindStringSubmatch(match)
+			if parts[1] == parts[2] {
+				return parts[2]
+			}
+			return parts[1] + " (" + parts[2] + ")"
+		})
+		text = markdownAutolinkPattern.ReplaceAllString(text, "$1")
+		text = markdownStrongPattern.ReplaceAllString(text, "$1$2")
+		text = markdownStrikePattern.ReplaceAllString(text, "$1")
+		text = markdownStarEmPattern.ReplaceAllString(text, "$1")
+		return markdownUnderEmPattern.ReplaceAllString(text, "$1$2$3")
+	}
+
+	text = html.EscapeString(text)
+	text = markdownImagePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownImagePattern.FindStringSubmatch(match)
+		if !isSafeMarkdownURL(parts[2], false) {
+			return parts[1]
+		}
+		return `<img src="` + parts[2] + `" alt="` + parts[1] + `">`
+	})
+	text = markdownLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLinkPattern.FindStringSubmatch(match)
+		if !isSafeMarkdownURL(parts[2], true) {
+			return parts[1]
+		}
+		return `<a href="` + parts[2] + `">` + parts[1] + `</a>`
+	})
+	text = markdownEscapedAutolink.ReplaceAllString(text, `<a href="$1">$1</a>`)
+	text = markdownStrongPattern.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = markdownStrikePattern.ReplaceAllString(text, "<del>$1</del>")
+	text = markdownStarEmPattern.ReplaceAllString(text, "<em>$1</em>")
+	return markdownUnderEmPattern.ReplaceAllString(text, "$1<em>$2</em>$3")
+}
+
+func isSafeMarkdownURL(rawURL string, allowMailto bool) bool {
+	lower := strings.ToLower(html.UnescapeString(rawURL))
+	if allowMailto && strings.HasPrefix(lower, "mailto:") {
+		return true
+	}
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// restoreMarkdownEscapes turns placeholders back into their characters.
+// Code spans keep the backslash, as Markdown does; HTML output escapes the
+// restored character.
+func restoreMarkdownEscapes(text string, keepBackslash, asHTML bool) string {
+	var out strings.Builder
+	for _, r := range text {
+		if r < markdownEscapeBase || r >= markdownEscapeBase+128 {
+			out.WriteRune(r)
+			continue
+		}
+		original := string(r - markdownEscapeBase)
+		if keepBackslash {
+			original = "\\" + original
+		}
+		if asHTML {
+			original = html.EscapeString(original)
+		}
+		out.WriteString(original)
+	}
+	return out.String()
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MarkdownSuite struct {
+	suite.Suite
+}
+
+func TestMarkdownSuite(t *testing.T) {
+	suite.Run(t, new(MarkdownSuite))
+}
+
+const markdownSample = "# Kidney *function*\n\n" +
+	"Your **eGFR** is `48` and ~~stable~~ _declining_; see [KDIGO](https://kdigo.org).\n\n" +
+	"- Limit salt\n" +
+	"  - under 2 g/day\n" +
+	"- Review meds\n\n" +
+	"1) Recheck in 3 months\n\n" +
+	"> Note: snake_case_names stay \\*as is\\*.\n\n" +
+	"```go\nfmt.Println(\"**not bold**\")\n```\n\n" +
+	"| Lab | Value |\n| --- | ---: |\n| eGFR | 48 |\n\n" +
+	"---"
+
+func (s *MarkdownSuite) TestMarkdownToPlainText() {
+	text, err := MarkdownToPlainText(markdownSample)
+	s.Require().NoError(err)
+	s.Equal("Kidney function\n\n"+
+		"Your eGFR is 48 and stable declining; see KDIGO (https://kdigo.org).\n\n"+
+		"- Limit salt\n  - under 2 g/day\n- Review meds\n\n"+
+		"1. Recheck in 3 months\n\n"+
+		"Note: snake_case_names stay *as is*.\n\n"+
+		"fmt.Println(\"**not bold**\")\n\n"+
+		"Lab\tValue\neGFR\t48", text)
+}
+
+func (s *MarkdownSuite) TestMarkdownToHTML() {
+	text, err := MarkdownToHTML(markdownSample)
+	s.Require().NoError(err)
+	s.Equal("<h1>Kidney <em>function</em></h1>\n"+
+		`<p>Your <strong>eGFR</strong> is <code>48</code> and <del>stable</del> <em>declining</em>; see <a href="https://kdigo.org">KDIGO</a>.</p>`+"\n"+
+		"<ul><li>Limit salt<ul><li>under 2 g/day</li></ul></li><li>Review meds</li></ul>\n"+
+		"<ol><li>Recheck in 3 months</li></ol>\n"+
+		"<blockquote>\n<p>Note: snake_case_names stay *as is*.</p>\n</blockquote>\n"+
+		`<pre><code class="language-go">fmt.Println(&#34;**not bold**&#34;)</code></pre>`+"\n"+
+		"<table><thead><tr><th>Lab</th><th>Value</th></tr></thead><tbody><tr><td>eGFR</td><td>48</td></tr></tbody></table>\n"+
+		"<hr>", text)
+}
+
+func (s *MarkdownSuite) TestMarkdownToHTMLEscapesRawHTMLAndUnsafeLinks() {
+	text, err := MarkdownToHTML(`<script>alert(1)</script> [click](javascript:steal) ![x](data:image/png;base64,AA) <https://example.com/a?b=1>`)
+	s.Require().NoError(err)
+	s.Equal(`<p>&lt;script&gt;alert(1)&lt;/script&gt; click x <a href="https://example.com/a?b=1">https://example.com/a?b=1</a></p>`, text)
+	s.NotContains(text, "<script>")
+	s.NotContains(text, "javascript:")
+}
+
+func (s *MarkdownSuite) TestMarkdownToHTMLKeepsCodeSpanContentLiteral() {
+	text, err := MarkdownToHTML("Use `a <b> *c*` here")
+	s.Require().NoError(err)
+	s.Equal("<p>Use <code>a &lt;b&gt; *c*</code> here</p>", text)
+}
+
+func (s *MarkdownSuite) TestWorksAsPostProcessor() {
+	cfg := ResolveGeneratorOpts(WithPostProcessors(MarkdownToPlainText, CollapseWhitespace))
+	text, err := ApplyPostProcessors(cfg, "## Plan\n\n\n\n**Rest**   well")
+	s.Require().NoError(err)
+	s.Equal("Plan\n\nRest well", text)
+}
@@ -0,0 +1,38 @@
+package model
+
+// MCPBridgeMode selects who calls MCP servers for providers with a native MCP
+// connector (OpenAI Responses and Anthropic).
+type MCPBridgeMode string
+
+const (
+	// MCPBridgeModeNative lets the provider's servers call the MCP server
+	// directly. Anthropic still bridges servers its connector cannot express.
+	MCPBridgeModeNative MCPBridgeMode = "native"
+	// MCPBridgeModeLocal connects to every MCP server from this process
+	// through pkg/mcp and offers its tools as local tools, so tool arguments
+	// and results never leave the network the MCP server runs in.
+	MCPBridgeModeLocal MCPBridgeMode = "local"
+)
+
+// WithMCPBridgeMode selects how providers with a native MCP connector run
+// MCPTools. The default is MCPBridgeModeNative. Other providers always bridge
+// MCP locally.
+func WithMCPBridgeMode(mode MCPBridgeMode) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.MCPBridgeMode = &mode
+	})
+}
+
+// ResolveMCPBridgeMode returns the effective MCP bridge mode for cfg. Unknown
+// values resolve to MCPBridgeModeNative.
+func ResolveMCPBridgeMode(cfg GeneratorConfig) MCPBridgeMode {
+	if cfg.MCPBridgeMode == nil {
+		return MCPBridgeModeNative
+	}
+	switch mode := *cfg.MCPBridgeMode; mode {
+	case MCPBridgeModeLocal:
+		return mode
+	default:
+		return MCPBridgeModeNative
+	}
+}
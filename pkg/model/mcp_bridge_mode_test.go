@@ -0,0 +1,22 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MCPBridgeModeSuite struct {
+	suite.Suite
+}
+
+func TestMCPBridgeModeSuite(t *testing.T) {
+	suite.Run(t, new(MCPBridgeModeSuite))
+}
+
+func (s *MCPBridgeModeSuite) TestResolve() {
+	s.Equal(MCPBridgeModeNative, ResolveMCPBridgeMode(ResolveGeneratorOpts()))
+	s.Equal(MCPBridgeModeNative, ResolveMCPBridgeMode(ResolveGeneratorOpts(WithMCPBridgeMode("remote"))))
+	s.Equal(MCPBridgeModeNative, ResolveMCPBridgeMode(ResolveGeneratorOpts(WithMCPBridgeMode(MCPBridgeModeNative))))
+	s.Equal(MCPBridgeModeLocal, ResolveMCPBridgeMode(ResolveGeneratorOpts(WithMCPBridgeMode(MCPBridgeModeLocal))))
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Usage is the token and API call accounting of a generation, read from
+// GenerationMetadata by its Usage method. Fields follow the metadata keys:
+// OutputTokens includes ReasoningTokens and InputTokens includes
+// CachedInputTokens. Counters a provider does not report are zero.
+type Usage struct {
+	InputTokens              int64
+	OutputTokens             int64
+	TotalTokens              int64
+	CachedInputTokens        int64
+	ReasoningTokens          int64
+	CacheReadInputTokens     int64
+	CacheCreationInputTokens int64
+	APICalls                 int
+	ToolRounds               int
+}
+
+// Usage returns the token and API call counters of m without strconv
+// parsing at the call site. Missing or malformed values are zero.
+func (m GenerationMetadata) Usage() Usage {
+	return Usage{
+		InputTokens:              m.intValue(MetadataKeyInputTokens),
+		OutputTokens:             m.intValue(MetadataKeyOutputTokens),
+		TotalTokens:              m.intValue(MetadataKeyTotalTokens),
+		CachedInputTokens:        m.intValue(MetadataKeyCachedInputTokens),
+		ReasoningTokens:          m.intValue(MetadataKeyReasoningTokens),
+		CacheReadInputTokens:     m.intValue(MetadataKeyCacheReadInputTokens),
+		CacheCreationInputTokens: m.intValue(MetadataKeyCacheCreationInputTokens),
+		APICalls:                 int(m.intValue(MetadataKeyAPICalls)),
+		ToolRounds:               int(m.intValue(MetadataKeyToolRounds)),
+	}
+}
+
+// Latency returns MetadataKeyLatencyMs as a duration, zero when absent.
+func (m GenerationMetadata) Latency() time.Duration {
+	return time.Duration(m.intValue(MetadataKeyLatencyMs)) * time.Millisecond
+}
+
+// Provider returns MetadataKeyProvider, empty when absent.
+func (m GenerationMetadata) Provider() string {
+	return strings.TrimSpace(m[MetadataKeyProvider])
+}
+
+// Model returns MetadataKeyModel, empty when absent.
+func (m GenerationMetadata) Model() string {
+	return strings.TrimSpace(m[MetadataKeyModel])
+}
+
+// ResponseID returns MetadataKeyResponseID, empty when absent.
+func (m GenerationMetadata) ResponseID() string {
+	return strings.TrimSpace(m[MetadataKeyResponseID])
+}
+
+// Int returns the integer stored under key and whether it was present and
+// well formed, for numeric keys without a typed accessor.
+func (m GenerationMetadata) Int(key string) (int64, bool) {
+	value, err := strconv.ParseInt(strings.TrimSpace(m[key]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func (m GenerationMetadata) intValue(key string) int64 {
+	value, _ := m.Int(key)
+	return value
+}
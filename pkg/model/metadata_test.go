@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetadataSuite struct {
+	suite.Suite
+}
+
+func TestMetadataSuite(t *testing.T) {
+	suite.Run(t, new(MetadataSuite))
+}
+
+func (s *MetadataSuite) TestTypedAccessors() {
+	meta := GenerationMetadata{
+		MetadataKeyProvider:                 "anthropic",
+		MetadataKeyModel:                    " claude ",
+		MetadataKeyResponseID:               "msg_1",
+		MetadataKeyLatencyMs:                "1250",
+		MetadataKeyInputTokens:              "120",
+		MetadataKeyOutputTokens:             "45",
+		MetadataKeyTotalTokens:              "165",
+		MetadataKeyCachedInputTokens:        "100",
+		MetadataKeyReasoningTokens:          "5",
+		MetadataKeyCacheReadInputTokens:     "80",
+		MetadataKeyCacheCreationInputTokens: "20",
+		MetadataKeyAPICalls:                 "2",
+		MetadataKeyToolRounds:               "1",
+	}
+
+	s.Equal("anthropic", meta.Provider())
+	s.Equal("claude", meta.Model())
+	s.Equal("msg_1", meta.ResponseID())
+	s.Equal(1250*time.Millisecond, meta.Latency())
+	s.Equal(Usage{
+		InputTokens:              120,
+		OutputTokens:             45,
+		TotalTokens:              165,
+		CachedInputTokens:        100,
+		ReasoningTokens:          5,
+		CacheReadInputTokens:     80,
+		CacheCreationInputTokens: 20,
+		APICalls:                 2,
+		ToolRounds:               1,
+	}, meta.Usage())
+	s.Equal("165", meta[MetadataKeyTotalTokens])
+}
+
+func (s *MetadataSuite) TestMissingAndMalformedValuesAreZero() {
+	meta := GenerationMetadata{MetadataKeyInputTokens: "lots", MetadataKeyWebSearchCalls: " 3 "}
+
+	s.Equal(Usage{}, meta.Usage())
+	s.Zero(meta.Latency())
+	s.Empty(meta.Provider())
+
+	value, ok := meta.Int(MetadataKeyWebSearchCalls)
+	s.True(ok)
+	s.Equal(int64(3), value)
+	_, ok = meta.Int(MetadataKeyInputTokens)
+	s.False(ok)
+
+	var empty GenerationMetadata
+	s.Equal(Usage{}, empty.Usage())
+	s.Empty(empty.Model())
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// GenerationRecord describes one finished content generation for a
+// MetricsRecorder.
+type GenerationRecord struct {
+	Provider string
+	Model    string
+	// Duration is the wall time of the whole generation, tool rounds and
+	// structured output repair included.
+	Duration     time.Duration
+	InputTokens  int64
+	OutputTokens int64
+	ToolRounds   int
+	// Labels holds the prompt version and experiment of the generation, keyed
+	// as ExperimentLabels returns them, for recorders that use them as metric
+	// dimensions. Unset values are left out.
+	Labels map[string]string
+	// Err is the error Generate returned, nil on success.
+	Err error
+}
+
+// MetricsRecorder receives a GenerationRecord after every Generate and
+// GenerateStream call of string and structured generators, successful or
+// not. Implementations must be safe for concurrent use and should return
+// quickly. The separate pkg/metrics module has a Prometheus implementation.
+type MetricsRecorder interface {
+	RecordGeneration(ctx context.Context, record GenerationRecord)
+}
+
+// MetricsRecorderFunc adapts a function to MetricsRecorder.
+type MetricsRecorderFunc func(ctx context.Context, record GenerationRecord)
+
+// RecordGeneration calls f.
+func (f MetricsRecorderFunc) RecordGeneration(ctx context.Context, record GenerationRecord) {
+	f(ctx, record)
+}
+
+// WithMetricsRecorder makes every provider report each content generation to
+// recorder. Embedding, audio and speech generators do not report.
+func WithMetricsRecorder(recorder MetricsRecorder) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.MetricsRecorder = recorder
+	})
+}
+
+// RecordGeneration reports a generation that started at start to
+// cfg.MetricsRecorder, reading the model, usage and ExperimentLabels from meta.
+// provider is used when meta has no provider, and the prompt version and
+// experiment of cfg when meta has none, as on early errors. Providers call it
+// when Generate returns.
+func RecordGeneration(ctx context.Context, cfg GeneratorConfig, provider string, start time.Time, meta GenerationMetadata, err error) {
+	if cfg.MetricsRecorder == nil {
+		return
+	}
+	usage := meta.Usage()
+	record := GenerationRecord{
+		Provider:     provider,
+		Model:        "unknown",
+		Duration:     time.Since(start),
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		ToolRounds:   usage.ToolRounds,
+		Labels:       experimentValues(cfg.PromptVersion, cfg.Experiment, cfg.ExperimentVariant),
+		Err:          err,
+	}
+	maps.Copy(record.Labels, ExperimentLabels(meta))
+	if value := meta.Provider(); value != "" {
+		record.Provider = value
+	}
+	if value := meta.Model(); value != "" {
+		record.Model = value
+	}
+	cfg.MetricsRecorder.RecordGeneration(ctx, record)
+}
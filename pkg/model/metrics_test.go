@@ -0,0 +1,72 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
+
+func (s *MetricsSuite) TestRecordGenerationReadsMetadata() {
+	var records []GenerationRecord
+	cfg := ResolveGeneratorOpts(WithMetricsRecorder(MetricsRecorderFunc(func(ctx context.Context, record GenerationRecord) {
+		records = append(records, record)
+	})))
+	meta := GenerationMetadata{
+		MetadataKeyProvider:      "openai",
+		MetadataKeyModel:         "gpt-4o",
+		MetadataKeyInputTokens:   "120",
+		MetadataKeyOutputTokens:  "45",
+		MetadataKeyToolRounds:    "2",
+		MetadataKeyPromptVersion: "summary-v3",
+		MetadataKeyExperiment:    "summary-length",
+	}
+
+	RecordGeneration(context.Background(), cfg, "fallback", time.Now().Add(-time.Second), meta, nil)
+
+	s.Require().Len(records, 1)
+	s.Equal("openai", records[0].Provider)
+	s.Equal("gpt-4o", records[0].Model)
+	s.Equal(int64(120), records[0].InputTokens)
+	s.Equal(int64(45), records[0].OutputTokens)
+	s.Equal(2, records[0].ToolRounds)
+	s.GreaterOrEqual(records[0].Duration, time.Second)
+	s.Equal(map[string]string{MetadataKeyPromptVersion: "summary-v3", MetadataKeyExperiment: "summary-length"}, records[0].Labels)
+	s.NoError(records[0].Err)
+}
+
+func (s *MetricsSuite) TestRecordGenerationFallsBackOnEarlyErrors() {
+	var records []GenerationRecord
+	cfg := ResolveGeneratorOpts(
+		WithMetricsRecorder(MetricsRecorderFunc(func(ctx context.Context, record GenerationRecord) {
+			records = append(records, record)
+		})),
+		WithExperiment("summary-length", "short"),
+	)
+	boom := errors.New("boom")
+
+	RecordGeneration(context.Background(), cfg, "anthropic", time.Now(), nil, boom)
+
+	s.Require().Len(records, 1)
+	s.Equal("anthropic", records[0].Provider)
+	s.Equal("unknown", records[0].Model)
+	s.Zero(records[0].InputTokens)
+	s.Equal(map[string]string{MetadataKeyExperiment: "summary-length", MetadataKeyExperimentVariant: "short"}, records[0].Labels)
+	s.ErrorIs(records[0].Err, boom)
+}
+
+func (s *MetricsSuite) TestRecordGenerationWithoutRecorderIsNoop() {
+	s.NotPanics(func() {
+		RecordGeneration(context.Background(), ResolveGeneratorOpts(), "openai", time.Now(), GenerationMetadata{}, nil)
+	})
+}
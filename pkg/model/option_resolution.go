@@ -0,0 +1,60 @@
+package model
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+)
+
+// OptionAction is what a provider did with a generator option while
+// resolving the configuration for a request.
+type OptionAction string
+
+const (
+	// OptionActionDropped removes an option the provider or model does not
+	// support, because IgnoreInvalidGeneratorOptions is set.
+	OptionActionDropped OptionAction = "dropped"
+	// OptionActionEnv fills an unset option from an environment variable.
+	OptionActionEnv OptionAction = "env"
+	// OptionActionClamped moves an out-of-range option into the range the
+	// provider accepts, because IgnoreInvalidGeneratorOptions is set (see
+	// ApplyGenerationLimits).
+	OptionActionClamped OptionAction = "clamped"
+)
+
+// EffectiveConfigReporter is implemented by content generators that can
+// report the configuration a generation would run with: options dropped by
+// the provider are removed and Model and URL hold the resolved model name and
+// endpoint, including environment fallbacks and defaults. Auth tokens are left
+// as configured. No request is sent.
+type EffectiveConfigReporter interface {
+	EffectiveConfig(ctx context.Context) (GeneratorConfig, error)
+}
+
+// LogOptionResolution logs at debug level, as
+// "option resolution provider=... option=... action=... detail=...", what a
+// provider did with option, so options dropped under
+// IgnoreInvalidGeneratorOptions or taken from the environment can be traced.
+// log may be nil.
+func LogOptionResolution(log logging.Logger, provider string, option string, action OptionAction, detail string) {
+	if log == nil {
+		return
+	}
+	log.Debugf("option resolution provider=%q option=%q action=%q detail=%q", provider, option, action, detail)
+}
+
+// EnvFallback returns value trimmed, or the trimmed environment variable env
+// when value is blank, logging a fallback with LogOptionResolution. The value
+// itself is never logged, as it may be a credential.
+func EnvFallback(log logging.Logger, provider string, option string, value string, env string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return value
+	}
+	value = strings.TrimSpace(os.Getenv(env))
+	if value != "" {
+		LogOptionResolution(log, provider, option, OptionActionEnv, env)
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/stretchr/testify/suite"
+)
+
+type OptionResolutionSuite struct {
+	suite.Suite
+}
+
+func TestOptionResolutionSuite(t *testing.T) {
+	suite.Run(t, new(OptionResolutionSuite))
+}
+
+type debugRecorder struct {
+	logging.Logger
+	lines []string
+}
+
+func (r *debugRecorder) Debugf(format string, args ...any) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func (r *debugRecorder) Warnf(format string, args ...any) {}
+
+func (s *OptionResolutionSuite) TestEnvFallbackLogsSourceNotValue() {
+	s.T().Setenv("POLYGLOT_TEST_TOKEN", " secret-token ")
+	log := &debugRecorder{}
+
+	s.Equal("configured", EnvFallback(log, "test", "auth_token", " configured ", "POLYGLOT_TEST_TOKEN"))
+	s.Empty(log.lines)
+
+	s.Equal("secret-token", EnvFallback(log, "test", "auth_token", "", "POLYGLOT_TEST_TOKEN"))
+	s.Equal([]string{`option resolution provider="test" option="auth_token" action="env" detail="POLYGLOT_TEST_TOKEN"`}, log.lines)
+
+	s.Empty(EnvFallback(log, "test", "url", "", "POLYGLOT_TEST_UNSET"))
+	s.Len(log.lines, 1)
+}
+
+func (s *OptionResolutionSuite) TestLogOptionResolutionAcceptsNilLogger() {
+	s.NotPanics(func() {
+		LogOptionResolution(nil, "test", "temperature", OptionActionDropped, "not supported")
+	})
+}
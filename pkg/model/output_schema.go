@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/schema"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// WithOutputSchema makes structured generators send schema instead of the
+// schema reflected from T, for constraints reflection cannot express such as
+// enums, descriptions or oneOf. The answer is validated against schema and
+// then unmarshalled into T, so the schema must describe T's JSON shape.
+// OpenAI's strict mode also needs every property listed in "required" and
+// "additionalProperties": false on each object.
+func WithOutputSchema(schema JSONSchema) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.OutputSchema = schema
+	})
+}
+
+// WithSchemaOptions configures how structured generators reflect the schema
+// of T, for example to accept undeclared properties on some objects or to
+// rename fields (see schema.Options).
+func WithSchemaOptions(opts schema.Options) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.SchemaOptions = opts
+	})
+}
+
+// StructuredOutputSchema returns the schema a structured generator for T
+// sends: the WithOutputSchema override, else T reflected with the
+// WithSchemaOptions settings, wrapped for WithFieldProvenance when set.
+func StructuredOutputSchema[T any](cfg GeneratorConfig) (map[string]any, error) {
+	out, err := ResolveOutputSchema(cfg, func() (map[string]any, error) {
+		return schema.Reflect[T](cfg.SchemaOptions)
+	})
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if cfg.FieldProvenance {
+		out = provenanceSchema(out)
+	}
+	return out, nil
+}
+
+// ResolveOutputSchema returns a copy of the schema set with WithOutputSchema,
+// normalized through JSON so it holds the same value types as a reflected
+// schema, or the result of reflect when none is set.
+func ResolveOutputSchema(cfg GeneratorConfig, reflect func() (map[string]any, error)) (map[string]any, error) {
+	if len(cfg.OutputSchema) == 0 {
+		reflected, err := reflect()
+		return reflected, utils.WrapIfNotNil(err)
+	}
+
+	encoded, err := json.Marshal(cfg.OutputSchema)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	var resolved map[string]any
+	if err := json.Unmarshal(encoded, &resolved); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if schemaType, ok := resolved["type"]; ok && schemaType != "object" {
+		return nil, utils.WrapIfNotNil(errors.New(`output schema must have type "object"`))
+	}
+	return resolved, nil
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/schema"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutputSchemaSuite struct {
+	suite.Suite
+}
+
+func TestOutputSchemaSuite(t *testing.T) {
+	suite.Run(t, new(OutputSchemaSuite))
+}
+
+func (s *OutputSchemaSuite) TestReflectsWithoutOverride() {
+	reflected := map[string]any{"type": "object"}
+	schema, err := ResolveOutputSchema(ResolveGeneratorOpts(), func() (map[string]any, error) {
+		return reflected, nil
+	})
+	s.Require().NoError(err)
+	s.Equal(reflected, schema)
+}
+
+func (s *OutputSchemaSuite) TestOverrideIsNormalizedCopy() {
+	override := JSONSchema{
+		"type":       "object",
+		"properties": map[string]any{"status": map[string]any{"type": "string", "enum": []string{"ok", "failed"}}},
+		"required":   []string{"status"},
+	}
+	cfg := ResolveGeneratorOpts(WithOutputSchema(override))
+	schema, err := ResolveOutputSchema(cfg, func() (map[string]any, error) {
+		s.Fail("reflection should not run")
+		return nil, nil
+	})
+	s.Require().NoError(err)
+	s.Equal([]any{"status"}, schema["required"])
+
+	schema["required"] = []any{}
+	s.Equal([]string{"status"}, override["required"])
+
+	err = ValidateJSONSchema(schema, []byte(`{"status":"maybe"}`))
+	s.ErrorIs(err, ErrSchemaValidation)
+}
+
+func (s *OutputSchemaSuite) TestStructuredOutputSchemaUsesSchemaOptions() {
+	type result struct {
+		Extra struct {
+			Source string `json:"source"`
+		} `json:"extra"`
+	}
+	cfg := ResolveGeneratorOpts(WithSchemaOptions(schema.Options{AdditionalPropertiesAt: []string{"/properties/extra"}}))
+	reflected, err := StructuredOutputSchema[result](cfg)
+	s.Require().NoError(err)
+	s.NoError(ValidateJSONSchema(reflected, []byte(`{"extra":{"source":"lab","unit":"mg"}}`)))
+
+	reflected, err = StructuredOutputSchema[result](ResolveGeneratorOpts())
+	s.Require().NoError(err)
+	s.ErrorIs(ValidateJSONSchema(reflected, []byte(`{"extra":{"source":"lab","unit":"mg"}}`)), ErrSchemaValidation)
+}
+
+func (s *OutputSchemaSuite) TestRejectsNonObjectRoot() {
+	cfg := ResolveGeneratorOpts(WithOutputSchema(JSONSchema{"type": "array"}))
+	_, err := ResolveOutputSchema(cfg, func() (map[string]any, error) { return nil, errors.New("unused") })
+	s.Error(err)
+}
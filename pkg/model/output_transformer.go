@@ -0,0 +1,39 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// OutputTransformer rewrites the raw text a model returned before anything
+// else reads it. Returning an error fails the generation.
+type OutputTransformer func(ctx context.Context, raw string) (string, error)
+
+// WithOutputTransformer adds a transformer that every provider runs on the
+// raw model text: before JSON extraction and parsing in structured
+// generators, including on repair answers, and before trimming and
+// PostProcessors in string generators. It suits provider-specific artifacts
+// such as thinking tags or XML wrappers. Transformers accumulate across calls
+// and run in order. Streamed chunks are not transformed, and raw_output keeps
+// the untransformed text.
+func WithOutputTransformer(transformer OutputTransformer) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		if transformer != nil {
+			cfg.OutputTransformers = append(cfg.OutputTransformers, transformer)
+		}
+	})
+}
+
+// TransformOutput runs cfg.OutputTransformers over raw.
+func TransformOutput(ctx context.Context, cfg GeneratorConfig, raw string) (string, error) {
+	for i, transformer := range cfg.OutputTransformers {
+		transformed, err := transformer(ctx, raw)
+		if err != nil {
+			return "", utils.WrapIfNotNil(fmt.Errorf("output transformer %d: %w", i, err))
+		}
+		raw = transformed
+	}
+	return raw, nil
+}
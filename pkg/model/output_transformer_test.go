@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OutputTransformerSuite struct {
+	suite.Suite
+}
+
+func TestOutputTransformerSuite(t *testing.T) {
+	suite.Run(t, new(OutputTransformerSuite))
+}
+
+var thinkTagPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+func stripThinking(ctx context.Context, raw string) (string, error) {
+	return thinkTagPattern.ReplaceAllString(raw, ""), nil
+}
+
+func (s *OutputTransformerSuite) TestTransformersRunBeforePostProcessors() {
+	var seen []string
+	cfg := ResolveGeneratorOpts(
+		WithOutputTransformer(stripThinking),
+		WithOutputTransformer(func(ctx context.Context, raw string) (string, error) {
+			seen = append(seen, raw)
+			return raw, nil
+		}),
+		WithPostProcessors(func(text string) (string, error) {
+			seen = append(seen, text)
+			return text, nil
+		}),
+	)
+
+	text, err := FinishTextOutput(context.Background(), cfg, "<think>plan</think>\n  Done. ")
+	s.Require().NoError(err)
+	s.Equal("Done.", text)
+	s.Equal([]string{"\n  Done. ", "Done."}, seen)
+
+	_, err = FinishTextOutput(context.Background(), cfg, "<think>only thoughts</think>")
+	s.ErrorContains(err, "response output is empty")
+}
+
+func (s *OutputTransformerSuite) TestTransformerErrorFailsGeneration() {
+	errBlocked := errors.New("blocked")
+	cfg := ResolveGeneratorOpts(WithOutputTransformer(func(ctx context.Context, raw string) (string, error) {
+		return "", errBlocked
+	}))
+
+	_, err := FinishTextOutput(context.Background(), cfg, "hello")
+	s.ErrorIs(err, errBlocked)
+	s.ErrorContains(err, "output transformer 0")
+
+	_, err = DecodeStructuredOutputWithRepair[provenanceTarget](
+		context.Background(), cfg, GenerationMetadata{}, nil, `{}`, 0, func(text string) string { return text }, nil,
+	)
+	s.ErrorIs(err, errBlocked)
+}
+
+func (s *OutputTransformerSuite) TestStructuredOutputIsTransformedBeforeParsing() {
+	cfg := ResolveGeneratorOpts(
+		WithOutputTransformer(stripThinking),
+		WithOutputTransformer(func(ctx context.Context, raw string) (string, error) {
+			return regexp.MustCompile(`</?answer>`).ReplaceAllString(raw, ""), nil
+		}),
+	)
+	schema, err := StructuredOutputSchema[provenanceTarget](cfg)
+	s.Require().NoError(err)
+
+	meta := GenerationMetadata{}
+	raw := `<think>{"name":"draft"}</think><answer>{"name":"Ada","age":36}</answer>`
+	out, err := DecodeStructuredOutputWithRepair[provenanceTarget](
+		context.Background(), cfg, meta, schema, raw, 0, func(text string) string { return text }, nil,
+	)
+	s.Require().NoError(err)
+	s.Equal(provenanceTarget{Name: "Ada", Age: 36}, out)
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PIIEntity is a kind of personal data RedactPII replaces.
+type PIIEntity string
+
+const (
+	PIIEntityEmail         PIIEntity = "EMAIL"
+	PIIEntitySSN           PIIEntity = "SSN"
+	PIIEntityCreditCard    PIIEntity = "CREDIT_CARD"
+	PIIEntityPhone         PIIEntity = "PHONE"
+	PIIEntityIPAddress     PIIEntity = "IP_ADDRESS"
+	PIIEntityMedicalRecord PIIEntity = "MRN"
+	PIIEntityDate          PIIEntity = "DATE"
+)
+
+// Placeholder returns the text that replaces a redacted entity, for example
+// "[EMAIL]".
+func (e PIIEntity) Placeholder() string {
+	return "[" + string(e) + "]"
+}
+
+type piiRule struct {
+	entity  PIIEntity
+	pattern *regexp.Regexp
+	// group is the submatch that is replaced; 0 replaces the whole match.
+	group int
+	// valid filters matches, for example with a checksum.
+	valid func(string) bool
+}
+
+// piiRules run in order, so more specific formats (SSN, card numbers) are
+// replaced before the phone pattern can match parts of them.
+var piiRules = []piiRule{
+	{entity: PIIEntityEmail, pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{entity: PIIEntitySSN, pattern: regexp.MustCompile(`\b\d{3}[- ]\d{2}[- ]\d{4}\b`)},
+	{entity: PIIEntityCreditCard, pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhnValid},
+	{entity: PIIEntityMedicalRecord, pattern: regexp.MustCompile(`(?i)\b(?:MRN|medical record(?: number)?)(?:\s*(?:number|no\.?|#|:|is))*\s*([A-Z0-9][A-Z0-9-]{4,})\b`), group: 1},
+	{entity: PIIEntityPhone, pattern: regexp.MustCompile(`(?:\+?1[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`)},
+	{entity: PIIEntityIPAddress, pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+	{entity: PIIEntityDate, pattern: regexp.MustCompile(`(?i)\b\d{1,2}[/-]\d{1,2}[/-]\d{2,4}\b|\b\d{4}-\d{2}-\d{2}\b|\b(?:jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.? \d{1,2}(?:st|nd|rd|th)?,? \d{4}\b`)},
+}
+
+// RedactPII replaces emails, SSNs, card numbers, medical record numbers,
+// phone numbers, IP addresses and dates in text with entity placeholders
+// such as "[PHONE]" and counts the replacements per entity. Detection is
+// pattern based: it favours structured identifiers and does not find names
+// or addresses.
+func RedactPII(text string) (string, map[PIIEntity]int) {
+	counts := make(map[PIIEntity]int)
+	for _, rule := range piiRules {
+		text = redactPIIRule(text, rule, counts)
+	}
+	return text, counts
+}
+
+func redactPIIRule(text string, rule piiRule, counts map[PIIEntity]int) string {
+	matches := rule.pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*rule.group], match[2*rule.group+1]
+		if start < 0 || (rule.valid != nil && !rule.valid(text[start:end])) {
+			continue
+		}
+		out.WriteString(text[last:start])
+		out.WriteString(rule.entity.Placeholder())
+		last = end
+		counts[rule.entity]++
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+func luhnValid(candidate string) bool {
+	sum, digits := 0, 0
+	for i := len(candidate) - 1; i >= 0; i-- {
+		c := candidate[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// RedactTranscript returns transcript scrubbed with RedactPII when
+// opts.RedactPII is set and records the replacement counts in meta under
+// MetadataKeyRedactedEntities. Otherwise transcript is returned unchanged.
+func RedactTranscript(meta GenerationMetadata, opts AudioOptions, transcript string) string {
+	if !opts.RedactPII {
+		return transcript
+	}
+	redacted, counts := RedactPII(transcript)
+	if meta != nil {
+		meta[MetadataKeyRedactedEntities] = formatPIICounts(counts)
+	}
+	return redacted
+}
+
+// formatPIICounts encodes counts as "EMAIL:1,PHONE:2" in entity order.
+func formatPIICounts(counts map[PIIEntity]int) string {
+	entities := make([]string, 0, len(counts))
+	for entity, count := range counts {
+		if count > 0 {
+			entities = append(entities, string(entity)+":"+strconv.Itoa(count))
+		}
+	}
+	sort.Strings(entities)
+	return strings.Join(entities, ",")
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PIIRedactionSuite struct {
+	suite.Suite
+}
+
+func TestPIIRedactionSuite(t *testing.T) {
+	suite.Run(t, new(PIIRedactionSuite))
+}
+
+func (s *PIIRedactionSuite) TestRedactPIIReplacesEntitiesWithPlaceholders() {
+	text := "Patient born March 3rd, 1956, MRN is A12345678, SSN 123-45-6789. " +
+		"Call (555) 123-4567 or email jane.doe@example.com. Card 4111 1111 1111 1111, order 1234 5678 9012 3456. " +
+		"Seen 04/12/2024 from 10.0.0.12. Creatinine 1.8, eGFR 42."
+
+	redacted, counts := RedactPII(text)
+
+	s.Equal("Patient born [DATE], MRN is [MRN], SSN [SSN]. "+
+		"Call [PHONE] or email [EMAIL]. Card [CREDIT_CARD], order 1234 5678 9012 3456. "+
+		"Seen [DATE] from [IP_ADDRESS]. Creatinine 1.8, eGFR 42.", redacted)
+	s.Equal(map[PIIEntity]int{
+		PIIEntityDate:          2,
+		PIIEntityMedicalRecord: 1,
+		PIIEntitySSN:           1,
+		PIIEntityPhone:         1,
+		PIIEntityEmail:         1,
+		PIIEntityCreditCard:    1,
+		PIIEntityIPAddress:     1,
+	}, counts)
+}
+
+func (s *PIIRedactionSuite) TestRedactTranscriptOnlyWhenEnabled() {
+	meta := GenerationMetadata{}
+	s.Equal("Call 555-123-4567.", RedactTranscript(meta, AudioOptions{}, "Call 555-123-4567."))
+	s.NotContains(meta, MetadataKeyRedactedEntities)
+
+	s.Equal("Call [PHONE] or [PHONE].", RedactTranscript(meta, AudioOptions{RedactPII: true}, "Call 555-123-4567 or 555.987.6543."))
+	s.Equal("PHONE:2", meta[MetadataKeyRedactedEntities])
+
+	s.Equal("Stable.", RedactTranscript(meta, AudioOptions{RedactPII: true}, "Stable."))
+	s.Equal("", meta[MetadataKeyRedactedEntities])
+}
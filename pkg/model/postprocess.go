@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// PostProcessor rewrites the final text of a string generation. Returning an
+// error fails the generation.
+type PostProcessor func(text string) (string, error)
+
+// WithPostProcessors appends processors that run, in order, on the output of
+// string generators before it is returned. Structured generators and
+// streamed chunks are not processed; GenerateStream returns the processed
+// full text.
+func WithPostProcessors(processors ...PostProcessor) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		for _, processor := range processors {
+			if processor != nil {
+				cfg.PostProcessors = append(cfg.PostProcessors, processor)
+			}
+		}
+	})
+}
+
+// ApplyPostProcessors runs cfg.PostProcessors over text.
+func ApplyPostProcessors(cfg GeneratorConfig, text string) (string, error) {
+	for i, processor := range cfg.PostProcessors {
+		processed, err := processor(text)
+		if err != nil {
+			return "", utils.WrapIfNotNil(fmt.Errorf("post-processor %d: %w", i, err))
+		}
+		text = processed
+	}
+	return text, nil
+}
+
+// FinishTextOutput applies cfg.OutputTransformers, trims provider output,
+// rejects an empty response, applies cfg.PostProcessors and adds the
+// WithDisclosure notice. String generators call it on their final text.
+func FinishTextOutput(ctx context.Context, cfg GeneratorConfig, text string) (string, error) {
+	text, err := TransformOutput(ctx, cfg, text)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", utils.WrapIfNotNil(errors.New("response output is empty"))
+	}
+	text, err = ApplyPostProcessors(cfg, text)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return ApplyDisclosure(cfg, text), nil
+}
+
+var (
+	inlineWhitespacePattern = regexp.MustCompile(`[^\S\n]+`)
+	blankLinesPattern       = regexp.MustCompile(`\n{3,}`)
+	codeFencePattern        = regexp.MustCompile("(?s)^```[\\w+-]*[^\\S\\n]*\\n(.*?)\\n?```$")
+)
+
+// CollapseWhitespace turns runs of spaces and tabs into one space, drops
+// trailing spaces and keeps at most one blank line between paragraphs.
+func CollapseWhitespace(text string) (string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(inlineWhitespacePattern.ReplaceAllString(line, " "), unicode.IsSpace)
+	}
+	text = blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text), nil
+}
+
+// StripCodeFence unwraps output that is entirely one fenced code block, as
+// models often return for "only output the text" instructions. Other text is
+// returned unchanged.
+func StripCodeFence(text string) (string, error) {
+	trimmed := strings.TrimSpace(text)
+	match := codeFencePattern.FindStringSubmatch(trimmed)
+	if match == nil || strings.Contains(match[1], "```") {
+		return text, nil
+	}
+	return strings.TrimSpace(match[1]), nil
+}
+
+// MaxLength returns a PostProcessor that cuts text to at most maxRunes
+// characters, at the last word boundary when there is one. Zero or negative
+// disables the limit.
+func MaxLength(maxRunes int) PostProcessor {
+	return func(text string) (string, error) {
+		if maxRunes <= 0 || utf8.RuneCountInString(text) <= maxRunes {
+			return text, nil
+		}
+		runes := []rune(text)
+		cut := runes[:maxRunes]
+		if !unicode.IsSpace(runes[maxRunes]) {
+			if boundary := strings.LastIndexFunc(string(cut), unicode.IsSpace); boundary > 0 {
+				return strings.TrimRightFunc(string(cut)[:boundary], unicode.IsSpace), nil
+			}
+		}
+		return strings.TrimRightFunc(string(cut), unicode.IsSpace), nil
+	}
+}
+
+// MaskWords returns a PostProcessor that replaces each whole-word,
+// case-insensitive match of words with mask repeated once per character,
+// for example to hide profanity from a caller-supplied list.
+func MaskWords(words []string, mask rune) PostProcessor {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return func(text string) (string, error) {
+			return text, nil
+		}
+	}
+	pattern := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	return func(text string) (string, error) {
+		return pattern.ReplaceAllStringFunc(text, func(match string) string {
+			return strings.Repeat(string(mask), utf8.RuneCountInString(match))
+		}), nil
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PostProcessSuite struct {
+	suite.Suite
+}
+
+func TestPostProcessSuite(t *testing.T) {
+	suite.Run(t, new(PostProcessSuite))
+}
+
+func (s *PostProcessSuite) TestWithPostProcessorsAppendsInOrder() {
+	cfg := ResolveGeneratorOpts(
+		WithPostProcessors(func(text string) (string, error) { return text + "a", nil }, nil),
+		WithPostProcessors(func(text string) (string, error) { return text + "b", nil }),
+	)
+	s.Len(cfg.PostProcessors, 2)
+
+	text, err := ApplyPostProcessors(cfg, "x")
+	s.Require().NoError(err)
+	s.Equal("xab", text)
+}
+
+func (s *PostProcessSuite) TestApplyPostProcessorsStopsOnError() {
+	cfg := ResolveGeneratorOpts(WithPostProcessors(
+		func(text string) (string, error) { return "", errors.New("blocked") },
+		func(text string) (string, error) { s.Fail("later processor must not run"); return text, nil },
+	))
+
+	_, err := ApplyPostProcessors(cfg, "x")
+	s.Error(err)
+	s.Contains(err.Error(), "post-processor 0: blocked")
+}
+
+func (s *PostProcessSuite) TestFinishTextOutputTrimsAndRejectsEmpty() {
+	text, err := FinishTextOutput(context.Background(), GeneratorConfig{}, "  hello \n")
+	s.Require().NoError(err)
+	s.Equal("hello", text)
+
+	_, err = FinishTextOutput(context.Background(), GeneratorConfig{}, " \n ")
+	s.Error(err)
+	s.Contains(err.Error(), "response output is empty")
+}
+
+func (s *PostProcessSuite) TestCollapseWhitespace() {
+	text, err := CollapseWhitespace("a  \t b   \r\nc\n\n\n\n d  ")
+	s.Require().NoError(err)
+	s.Equal("a b\nc\n\n d", text)
+}
+
+func (s *PostProcessSuite) TestStripCodeFence() {
+	text, err := StripCodeFence("```markdown\nHello **there**\n```")
+	s.Require().NoError(err)
+	s.Equal("Hello **there**", text)
+
+	mixed := "Intro\n```go\nx := 1\n```"
+	text, err = StripCodeFence(mixed)
+	s.Require().NoError(err)
+	s.Equal(mixed, text)
+
+	twoBlocks := "```\na\n```\n\n```\nb\n```"
+	text, err = StripCodeFence(twoBlocks)
+	s.Require().NoError(err)
+	s.Equal(twoBlocks, text)
+}
+
+func (s *PostProcessSuite) TestMaxLengthCutsAtWordBoundary() {
+	text, err := MaxLength(12)("The kidney filters blood")
+	s.Require().NoError(err)
+	s.Equal("The kidney", text)
+
+	text, err = MaxLength(4)("Nephrology")
+	s.Require().NoError(err)
+	s.Equal("Neph", text)
+
+	text, err = MaxLength(0)("unchanged")
+	s.Require().NoError(err)
+	s.Equal("unchanged", text)
+
+	text, err = MaxLength(3)("héllo")
+	s.Require().NoError(err)
+	s.Equal("hél", text)
+}
+
+func (s *PostProcessSuite) TestMaskWords() {
+	text, err := MaskWords([]string{"darn", " "}, '*')("Darn it, darnation, DARN!")
+	s.Require().NoError(err)
+	s.Equal("**** it, darnation, ****!", text)
+
+	text, err = MaskWords(nil, '*')("darn")
+	s.Require().NoError(err)
+	s.Equal("darn", text)
+	s.False(strings.Contains(text, "*"))
+}
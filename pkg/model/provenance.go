@@ -0,0 +1,142 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// MetadataKeyFieldProvenance holds the tool call each structured output field
+// came from as a JSON object of FieldSource keyed by JSON Pointer (see
+// WithFieldProvenance and ParseFieldProvenance).
+const MetadataKeyFieldProvenance = "field_provenance"
+
+// FieldSource is the tool call a structured output field was taken from.
+// CallID is empty when the provider does not show call IDs to the model.
+type FieldSource struct {
+	Tool   string `json:"tool"`
+	CallID string `json:"call_id,omitempty"`
+}
+
+// FieldProvenance maps JSON Pointers of structured output fields, such as
+// "/patient/age", to the tool call they came from.
+type FieldProvenance map[string]FieldSource
+
+// WithFieldProvenance asks structured generators to report which tool call
+// each output field came from. The schema sent to the model is wrapped as
+// {"result": <schema>, "provenance": [{"field", "tool", "call_id"}]}; result
+// is decoded into T and the provenance is stored in metadata under
+// MetadataKeyFieldProvenance. Fields the model did not take from a tool
+// result are left out. Provenance is the model's own annotation and is not
+// checked against the tool calls that were made.
+func WithFieldProvenance(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.FieldProvenance = enabled
+	})
+}
+
+// ParseFieldProvenance decodes MetadataKeyFieldProvenance from meta. It
+// returns nil when the key is absent.
+func ParseFieldProvenance(meta GenerationMetadata) (FieldProvenance, error) {
+	raw, ok := meta[MetadataKeyFieldProvenance]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var provenance FieldProvenance
+	if err := json.Unmarshal([]byte(raw), &provenance); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return provenance, nil
+}
+
+// provenanceSchema wraps schema in the result/provenance envelope. Every
+// property is required and objects are closed, as OpenAI's strict mode needs.
+func provenanceSchema(schema map[string]any) map[string]any {
+	result := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if key == "$schema" || key == "$id" {
+			continue
+		}
+		result[key] = value
+	}
+
+	entry := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"field": map[string]any{
+				"type":        "string",
+				"description": `JSON Pointer of the field in result, for example "/patient/age".`,
+			},
+			"tool": map[string]any{
+				"type":        "string",
+				"description": "Name of the tool whose result the value was taken from.",
+			},
+			"call_id": map[string]any{
+				"type":        "string",
+				"description": "ID of that tool call, or an empty string when it is not known.",
+			},
+		},
+		"required":             []any{"field", "tool", "call_id"},
+		"additionalProperties": false,
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"result": result,
+			"provenance": map[string]any{
+				"type":        "array",
+				"description": "One entry per field of result whose value came from a tool result. Leave out fields that did not.",
+				"items":       entry,
+			},
+		},
+		"required":             []any{"result", "provenance"},
+		"additionalProperties": false,
+	}
+}
+
+// decodeWithProvenance validates payload against the wrapped schema, decodes
+// its result into T and records its provenance in meta.
+func decodeWithProvenance[T any](schema map[string]any, payload string, meta GenerationMetadata) (T, error) {
+	var zero T
+	if err := ValidateJSONSchema(schema, []byte(payload)); err != nil {
+		return zero, utils.WrapIfNotNil(err)
+	}
+
+	var envelope struct {
+		Result     json.RawMessage `json:"result"`
+		Provenance []struct {
+			Field  string `json:"field"`
+			Tool   string `json:"tool"`
+			CallID string `json:"call_id"`
+		} `json:"provenance"`
+	}
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		return zero, utils.WrapIfNotNil(err)
+	}
+	var out T
+	if err := json.Unmarshal(envelope.Result, &out); err != nil {
+		return zero, utils.WrapIfNotNil(err)
+	}
+
+	provenance := FieldProvenance{}
+	for _, entry := range envelope.Provenance {
+		field := strings.TrimSpace(entry.Field)
+		tool := strings.TrimSpace(entry.Tool)
+		if field == "" || tool == "" {
+			continue
+		}
+		if !strings.HasPrefix(field, "/") {
+			field = "/" + field
+		}
+		provenance[field] = FieldSource{Tool: tool, CallID: strings.TrimSpace(entry.CallID)}
+	}
+	if meta != nil {
+		encoded, err := json.Marshal(provenance)
+		if err != nil {
+			return zero, utils.WrapIfNotNil(err)
+		}
+		meta[MetadataKeyFieldProvenance] = string(encoded)
+	}
+	return out, nil
+}
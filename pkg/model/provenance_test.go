@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProvenanceSuite struct {
+	suite.Suite
+}
+
+func TestProvenanceSuite(t *testing.T) {
+	suite.Run(t, new(ProvenanceSuite))
+}
+
+type provenanceTarget struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (s *ProvenanceSuite) TestSchemaIsWrappedOnlyWhenEnabled() {
+	plain, err := StructuredOutputSchema[provenanceTarget](ResolveGeneratorOpts())
+	s.Require().NoError(err)
+	s.Contains(plain["properties"], "name")
+
+	wrapped, err := StructuredOutputSchema[provenanceTarget](ResolveGeneratorOpts(WithFieldProvenance(true)))
+	s.Require().NoError(err)
+	s.Equal("object", wrapped["type"])
+	s.Equal([]any{"result", "provenance"}, wrapped["required"])
+	properties := wrapped["properties"].(map[string]any)
+	result := properties["result"].(map[string]any)
+	s.Contains(result["properties"], "name")
+	s.NotContains(result, "$schema")
+	s.Equal("array", properties["provenance"].(map[string]any)["type"])
+}
+
+func (s *ProvenanceSuite) TestDecodeRecordsProvenance() {
+	cfg := ResolveGeneratorOpts(WithFieldProvenance(true))
+	schema, err := StructuredOutputSchema[provenanceTarget](cfg)
+	s.Require().NoError(err)
+
+	meta := GenerationMetadata{}
+	payload := `{"result":{"name":"Ada","age":36},"provenance":[` +
+		`{"field":"/name","tool":"lookup_patient","call_id":"call_1"},` +
+		`{"field":"age","tool":"lookup_labs","call_id":""},` +
+		`{"field":"/ignored","tool":"","call_id":""}]}`
+	out, err := DecodeStructuredOutputWithRepair[provenanceTarget](
+		context.Background(), cfg, meta, schema, payload, 0, func(text string) string { return text }, nil,
+	)
+	s.Require().NoError(err)
+	s.Equal(provenanceTarget{Name: "Ada", Age: 36}, out)
+
+	provenance, err := ParseFieldProvenance(meta)
+	s.Require().NoError(err)
+	s.Equal(FieldProvenance{
+		"/name": {Tool: "lookup_patient", CallID: "call_1"},
+		"/age":  {Tool: "lookup_labs"},
+	}, provenance)
+}
+
+func (s *ProvenanceSuite) TestDecodeValidatesEnvelope() {
+	cfg := ResolveGeneratorOpts(WithFieldProvenance(true))
+	schema, err := StructuredOutputSchema[provenanceTarget](cfg)
+	s.Require().NoError(err)
+
+	meta := GenerationMetadata{}
+	_, err = DecodeStructuredOutputWithRepair[provenanceTarget](
+		context.Background(), cfg, meta, schema, `{"name":"Ada","age":36}`, 0, func(text string) string { return text }, nil,
+	)
+	s.ErrorIs(err, ErrSchemaValidation)
+	s.NotContains(meta, MetadataKeyFieldProvenance)
+}
+
+func (s *ProvenanceSuite) TestParseWithoutProvenance() {
+	provenance, err := ParseFieldProvenance(GenerationMetadata{})
+	s.Require().NoError(err)
+	s.Nil(provenance)
+}
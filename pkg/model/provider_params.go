@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// WithProviderParams adds raw fields to every generation request body, for
+// provider features that have no first-class option yet (the "extra_body"
+// pattern). Nested objects are merged key by key into the body the provider
+// builds, and other values replace the field at that path, so
+// {"reasoning": {"summary": "auto"}} keeps the reasoning effort set by
+// WithReasoningLevel. The option can be passed several times; later values
+// win. Fields that change the wire protocol (for example "stream") are the
+// caller's responsibility.
+func WithProviderParams(params map[string]any) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		if len(params) == 0 {
+			return
+		}
+		if cfg.ProviderParams == nil {
+			cfg.ProviderParams = map[string]any{}
+		}
+		mergeParams(cfg.ProviderParams, params)
+	})
+}
+
+// MergeProviderParams merges params into the JSON object body with the
+// semantics of WithProviderParams. Providers with hand-rolled HTTP clients
+// call it on the encoded request; body is returned unchanged when params is
+// empty.
+func MergeProviderParams(body []byte, params map[string]any) ([]byte, error) {
+	if len(params) == 0 {
+		return body, nil
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if decoded == nil {
+		decoded = map[string]any{}
+	}
+	mergeParams(decoded, params)
+
+	merged, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return merged, nil
+}
+
+func mergeParams(dst map[string]any, src map[string]any) {
+	for key, value := range src {
+		nested, isMap := value.(map[string]any)
+		existing, hasMap := dst[key].(map[string]any)
+		if isMap && hasMap {
+			mergeParams(existing, nested)
+			continue
+		}
+		if isMap {
+			copied := make(map[string]any, len(nested))
+			mergeParams(copied, nested)
+			value = copied
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProviderParamsSuite struct {
+	suite.Suite
+}
+
+func TestProviderParamsSuite(t *testing.T) {
+	suite.Run(t, new(ProviderParamsSuite))
+}
+
+func (s *ProviderParamsSuite) TestWithProviderParamsMergesCalls() {
+	first := map[string]any{"top_k": 5, "metadata": map[string]any{"user_id": "u-1"}}
+	cfg := ResolveGeneratorOpts(
+		WithProviderParams(first),
+		WithProviderParams(map[string]any{"top_k": 7, "metadata": map[string]any{"trace": "t-1"}}),
+		WithProviderParams(nil),
+	)
+
+	s.Equal(map[string]any{
+		"top_k":    7,
+		"metadata": map[string]any{"user_id": "u-1", "trace": "t-1"},
+	}, cfg.ProviderParams)
+	s.Equal(map[string]any{"user_id": "u-1"}, first["metadata"], "caller maps must not be modified")
+	s.Nil(ResolveGeneratorOpts().ProviderParams)
+}
+
+func (s *ProviderParamsSuite) TestMergeProviderParams() {
+	body := []byte(`{"model":"m","options":{"num_predict":16},"tools":[{"name":"a"}]}`)
+
+	unchanged, err := MergeProviderParams(body, nil)
+	s.Require().NoError(err)
+	s.Equal(body, unchanged)
+
+	merged, err := MergeProviderParams(body, map[string]any{
+		"options": map[string]any{"num_ctx": 8192},
+		"tools":   []any{},
+		"extra":   true,
+	})
+	s.Require().NoError(err)
+	s.JSONEq(`{"model":"m","options":{"num_predict":16,"num_ctx":8192},"tools":[],"extra":true}`, string(merged))
+
+	_, err = MergeProviderParams([]byte(`[1]`), map[string]any{"a": 1})
+	s.Error(err)
+}
@@ -0,0 +1,25 @@
+package model
+
+// WithRawOutput makes structured generators keep the model text the value was
+// parsed from in MetadataKeyRawOutput, for logging, debugging or showing the
+// answer when parsing fails. The text is recorded on parse errors too. Text
+// generators ignore the option.
+func WithRawOutput(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.RawOutput = enabled
+	})
+}
+
+// SetRawOutput records text in meta when cfg enables WithRawOutput.
+func SetRawOutput(meta GenerationMetadata, cfg GeneratorConfig, text string) {
+	if meta == nil || !cfg.RawOutput {
+		return
+	}
+	meta[MetadataKeyRawOutput] = text
+}
+
+// RawOutput returns the raw structured output recorded with WithRawOutput.
+func RawOutput(meta GenerationMetadata) (string, bool) {
+	text, ok := meta[MetadataKeyRawOutput]
+	return text, ok
+}
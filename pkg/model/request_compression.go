@@ -0,0 +1,106 @@
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// DefaultRequestCompressionMinBytes is the smallest request body
+// WithRequestCompression compresses when given a threshold below 1.
+const DefaultRequestCompressionMinBytes = 16 << 10
+
+// WithRequestCompression gzips request bodies of at least minBytes (below 1
+// uses DefaultRequestCompressionMinBytes) and sends them with
+// Content-Encoding: gzip. It cuts transfer time for very large prompts, such
+// as RAG contexts. Only the hand-rolled HTTP clients (Anthropic on every
+// hosting platform, HuggingFace and Ollama chat requests) compress; enable it
+// only for endpoints or gateways that accept gzip request bodies. Gzip
+// responses are decompressed whether or not the option is set.
+func WithRequestCompression(minBytes int) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.RequestCompressionMinBytes = &minBytes
+	})
+}
+
+// ResolveRequestCompressionMinBytes returns the body size from which requests
+// are compressed, or 0 when WithRequestCompression is not set.
+func ResolveRequestCompressionMinBytes(cfg GeneratorConfig) int {
+	if cfg.RequestCompressionMinBytes == nil {
+		return 0
+	}
+	if *cfg.RequestCompressionMinBytes < 1 {
+		return DefaultRequestCompressionMinBytes
+	}
+	return *cfg.RequestCompressionMinBytes
+}
+
+// NewCompressedRequest builds an HTTP request with body, gzipped when
+// minBytes is positive and body is at least that long. It returns the bytes
+// actually sent, which request signing must use.
+func NewCompressedRequest(ctx context.Context, method string, url string, body []byte, minBytes int) (*http.Request, []byte, error) {
+	compressed := minBytes > 0 && len(body) >= minBytes
+	if compressed {
+		var buf bytes.Buffer
+		writer, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+		if err != nil {
+			return nil, nil, utils.WrapIfNotNil(err)
+		}
+		if _, err := writer.Write(body); err != nil {
+			return nil, nil, utils.WrapIfNotNil(err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, nil, utils.WrapIfNotNil(err)
+		}
+		body = buf.Bytes()
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, utils.WrapIfNotNil(err)
+	}
+	if compressed {
+		httpRequest.Header.Set("Content-Encoding", "gzip")
+	}
+	return httpRequest, body, nil
+}
+
+// DecompressResponse replaces a gzip-encoded response body with its
+// decompressed stream. Go's transport already does this when it asked for
+// gzip itself; this covers requests that set Accept-Encoding explicitly, for
+// example through gateway headers.
+func DecompressResponse(httpResponse *http.Response) error {
+	if httpResponse == nil || httpResponse.Uncompressed ||
+		!strings.EqualFold(strings.TrimSpace(httpResponse.Header.Get("Content-Encoding")), "gzip") {
+		return nil
+	}
+	reader, err := gzip.NewReader(httpResponse.Body)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return utils.WrapIfNotNil(err)
+	}
+	httpResponse.Body = &gzipResponseBody{Reader: reader, body: httpResponse.Body}
+	httpResponse.Header.Del("Content-Encoding")
+	httpResponse.Header.Del("Content-Length")
+	httpResponse.ContentLength = -1
+	httpResponse.Uncompressed = true
+	return nil
+}
+
+// gzipResponseBody closes both the gzip reader and the underlying body.
+type gzipResponseBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipResponseBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RequestCompressionSuite struct {
+	suite.Suite
+}
+
+func TestRequestCompressionSuite(t *testing.T) {
+	suite.Run(t, new(RequestCompressionSuite))
+}
+
+func (s *RequestCompressionSuite) TestResolveMinBytes() {
+	s.Equal(0, ResolveRequestCompressionMinBytes(ResolveGeneratorOpts()))
+	s.Equal(DefaultRequestCompressionMinBytes, ResolveRequestCompressionMinBytes(ResolveGeneratorOpts(WithRequestCompression(0))))
+	s.Equal(1024, ResolveRequestCompressionMinBytes(ResolveGeneratorOpts(WithRequestCompression(1024))))
+}
+
+func (s *RequestCompressionSuite) TestSmallBodiesAreSentAsIs() {
+	body := []byte(`{"prompt":"hi"}`)
+	for _, minBytes := range []int{0, len(body) + 1} {
+		request, sent, err := NewCompressedRequest(context.Background(), http.MethodPost, "http://example.test", body, minBytes)
+		s.Require().NoError(err)
+		s.Equal(body, sent)
+		s.Empty(request.Header.Get("Content-Encoding"))
+	}
+}
+
+func (s *RequestCompressionSuite) TestLargeBodiesAreGzipped() {
+	body := []byte(`{"context":"` + strings.Repeat("eGFR 48 mL/min. ", 500) + `"}`)
+	request, sent, err := NewCompressedRequest(context.Background(), http.MethodPost, "http://example.test", body, 1024)
+	s.Require().NoError(err)
+	s.Equal("gzip", request.Header.Get("Content-Encoding"))
+	s.Less(len(sent), len(body))
+	s.Equal(int64(len(sent)), request.ContentLength)
+
+	reader, err := gzip.NewReader(request.Body)
+	s.Require().NoError(err)
+	decoded, err := io.ReadAll(reader)
+	s.Require().NoError(err)
+	s.Equal(body, decoded)
+}
+
+func (s *RequestCompressionSuite) TestDecompressResponse() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		_, _ = writer.Write([]byte(`{"ok":true}`))
+		_ = writer.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	s.Require().NoError(err)
+	// An explicit Accept-Encoding turns off the transport's own decompression.
+	request.Header.Set("Accept-Encoding", "gzip")
+	response, err := http.DefaultClient.Do(request)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	s.Require().NoError(DecompressResponse(response))
+	s.True(response.Uncompressed)
+	s.Empty(response.Header.Get("Content-Encoding"))
+	body, err := io.ReadAll(response.Body)
+	s.Require().NoError(err)
+	s.Equal(`{"ok":true}`, string(body))
+
+	plain := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader("plain"))}
+	s.Require().NoError(DecompressResponse(plain))
+	body, err = io.ReadAll(plain.Body)
+	s.Require().NoError(err)
+	s.Equal("plain", string(body))
+}
@@ -0,0 +1,199 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Defaults for RetryPolicy.
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBaseDelay = time.Second
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// MetadataKeyRetries is the number of API call retries a generation needed,
+// across tool rounds and embedded calls; absent when there were none.
+const MetadataKeyRetries = "retries"
+
+// ErrRetryBudgetExhausted is returned when a retry is refused because the
+// generation's retry budget is spent.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryPolicy bounds how providers retry transient API errors, such as a
+// HuggingFace model that is still loading. Zero fields use the defaults.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt (default
+	// DefaultMaxRetries); negative disables retrying.
+	MaxRetries int
+	// BaseDelay is the wait before the first retry when the server suggests
+	// none, doubled for each later retry (default DefaultRetryBaseDelay).
+	BaseDelay time.Duration
+	// MaxDelay caps each wait, including waits the server asks for (default
+	// DefaultRetryMaxDelay).
+	MaxDelay time.Duration
+	// GenerationBudget caps the retries of one generation as a whole, across
+	// every API call of its tool rounds and embedded calls such as structured
+	// output repair, on top of the per-call MaxRetries. Zero leaves it
+	// unbounded; negative allows no retries at all.
+	GenerationBudget int
+}
+
+// WithRetryPolicy sets how transient API errors are retried. MaxRetries and
+// the delays are used by HuggingFace for cold models; GenerationBudget also
+// bounds the SDK retries of OpenAI and Bedrock.
+func WithRetryPolicy(policy RetryPolicy) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.RetryPolicy = &policy
+	})
+}
+
+// ResolveRetryPolicy returns cfg.RetryPolicy with defaults applied.
+func ResolveRetryPolicy(cfg GeneratorConfig) RetryPolicy {
+	policy := RetryPolicy{}
+	if cfg.RetryPolicy != nil {
+		policy = *cfg.RetryPolicy
+	}
+	switch {
+	case policy.MaxRetries < 0:
+		policy.MaxRetries = 0
+	case policy.MaxRetries == 0:
+		policy.MaxRetries = DefaultMaxRetries
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultRetryBaseDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultRetryMaxDelay
+	}
+	return policy
+}
+
+// Delay returns the wait before the given retry (0 for the first): the
+// server's suggested wait when positive, else BaseDelay doubled per retry,
+// capped at MaxDelay.
+func (p RetryPolicy) Delay(retry int, suggested time.Duration) time.Duration {
+	delay := suggested
+	if delay <= 0 {
+		delay = p.BaseDelay
+		for i := 0; i < retry && delay < p.MaxDelay; i++ {
+			delay *= 2
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// WaitForRetry sleeps for delay, returning early with the context's error
+// when ctx ends first.
+func WaitForRetry(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return utils.WrapIfNotNil(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryBudget counts the retries of one generation and refuses them once its
+// limit is spent. A nil *RetryBudget allows every retry. It is safe for
+// concurrent use, as parallel tool calls may share it.
+type RetryBudget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+}
+
+// NewRetryBudget returns a budget of limit retries. Zero means unbounded and
+// negative allows none, as for RetryPolicy.GenerationBudget.
+func NewRetryBudget(limit int) *RetryBudget {
+	return &RetryBudget{limit: limit}
+}
+
+// TryRetry reserves one retry, reporting false when the budget is spent.
+func (b *RetryBudget) TryRetry() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exhausted() {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// Exhausted reports whether no retries remain.
+func (b *RetryBudget) Exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exhausted()
+}
+
+func (b *RetryBudget) exhausted() bool {
+	return b.limit < 0 || (b.limit > 0 && b.used >= b.limit)
+}
+
+// Used returns the number of retries taken.
+func (b *RetryBudget) Used() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+type retryBudgetKey struct{}
+
+// ContextWithRetryBudget returns ctx carrying budget. Providers draw retries
+// from the budget on the context of each API call, so callers can also bound
+// several generations with one budget.
+func ContextWithRetryBudget(ctx context.Context, budget *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, budget)
+}
+
+// RetryBudgetFromContext returns the budget on ctx, or nil when there is none.
+func RetryBudgetFromContext(ctx context.Context) *RetryBudget {
+	budget, _ := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	return budget
+}
+
+// StartRetryBudget returns ctx carrying the retry budget of a generation: the
+// budget already on ctx, so nested generations share their caller's, else a
+// new one from cfg's RetryPolicy.GenerationBudget.
+func StartRetryBudget(ctx context.Context, cfg GeneratorConfig) (context.Context, *RetryBudget) {
+	if budget := RetryBudgetFromContext(ctx); budget != nil {
+		return ctx, budget
+	}
+	limit := 0
+	if cfg.RetryPolicy != nil {
+		limit = cfg.RetryPolicy.GenerationBudget
+	}
+	budget := NewRetryBudget(limit)
+	return ContextWithRetryBudget(ctx, budget), budget
+}
+
+// SetRetryMetadata records the retries taken from budget in meta under
+// MetadataKeyRetries when there were any.
+func SetRetryMetadata(meta GenerationMetadata, budget *RetryBudget) {
+	if meta == nil {
+		return
+	}
+	if used := budget.Used(); used > 0 {
+		meta[MetadataKeyRetries] = strconv.Itoa(used)
+	}
+}
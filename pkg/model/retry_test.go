@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RetryPolicySuite struct {
+	suite.Suite
+}
+
+func TestRetryPolicySuite(t *testing.T) {
+	suite.Run(t, new(RetryPolicySuite))
+}
+
+func (s *RetryPolicySuite) TestResolveAppliesDefaults() {
+	policy := ResolveRetryPolicy(ResolveGeneratorOpts())
+	s.Equal(RetryPolicy{MaxRetries: DefaultMaxRetries, BaseDelay: DefaultRetryBaseDelay, MaxDelay: DefaultRetryMaxDelay}, policy)
+
+	policy = ResolveRetryPolicy(ResolveGeneratorOpts(WithRetryPolicy(RetryPolicy{MaxRetries: -1, MaxDelay: time.Minute})))
+	s.Equal(0, policy.MaxRetries)
+	s.Equal(time.Minute, policy.MaxDelay)
+}
+
+func (s *RetryPolicySuite) TestDelayBacksOffAndHonoursSuggestion() {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	s.Equal(time.Second, policy.Delay(0, 0))
+	s.Equal(2*time.Second, policy.Delay(1, 0))
+	s.Equal(4*time.Second, policy.Delay(2, 0))
+	s.Equal(5*time.Second, policy.Delay(3, 0))
+	s.Equal(3*time.Second, policy.Delay(0, 3*time.Second))
+	s.Equal(5*time.Second, policy.Delay(0, 40*time.Second))
+}
+
+func (s *RetryPolicySuite) TestWaitForRetryStopsWithContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(WaitForRetry(ctx, time.Hour), context.Canceled)
+	s.NoError(WaitForRetry(context.Background(), time.Millisecond))
+}
+
+func (s *RetryPolicySuite) TestRetryBudgetLimits() {
+	budget := NewRetryBudget(2)
+	s.True(budget.TryRetry())
+	s.False(budget.Exhausted())
+	s.True(budget.TryRetry())
+	s.True(budget.Exhausted())
+	s.False(budget.TryRetry())
+	s.Equal(2, budget.Used())
+
+	s.False(NewRetryBudget(-1).TryRetry())
+	unbounded := NewRetryBudget(0)
+	for range 100 {
+		s.True(unbounded.TryRetry())
+	}
+
+	var missing *RetryBudget
+	s.True(missing.TryRetry())
+	s.False(missing.Exhausted())
+	s.Zero(missing.Used())
+}
+
+func (s *RetryPolicySuite) TestStartRetryBudgetReusesContextBudget() {
+	cfg := ResolveGeneratorOpts(WithRetryPolicy(RetryPolicy{GenerationBudget: 3}))
+	ctx, budget := StartRetryBudget(context.Background(), cfg)
+	s.Same(budget, RetryBudgetFromContext(ctx))
+	s.True(budget.TryRetry())
+
+	nestedCtx, nested := StartRetryBudget(ctx, ResolveGeneratorOpts())
+	s.Same(budget, nested)
+	s.Equal(ctx, nestedCtx)
+
+	meta := GenerationMetadata{}
+	SetRetryMetadata(meta, budget)
+	s.Equal("1", meta[MetadataKeyRetries])
+	SetRetryMetadata(meta, NewRetryBudget(0))
+	s.Equal("1", meta[MetadataKeyRetries])
+	s.Nil(RetryBudgetFromContext(context.Background()))
+}
@@ -0,0 +1,445 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// maxReportedViolations caps the violations listed in
+// SchemaValidationError.Error; all of them stay in Violations.
+const maxReportedViolations = 5
+
+// ErrSchemaValidation matches any *SchemaValidationError with errors.Is.
+var ErrSchemaValidation = errors.New("structured output does not match schema")
+
+// SchemaViolation is one place where structured output breaks its schema.
+type SchemaViolation struct {
+	// Path is a JSON Pointer to the offending value, such as "/items/2/name";
+	// "" is the whole document.
+	Path    string
+	Message string
+}
+
+// SchemaValidationError is returned by structured generators when the model's
+// JSON does not match the schema reflected from the output type, listing every
+// violating path.
+type SchemaValidationError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	parts := make([]string, 0, min(len(e.Violations), maxReportedViolations)+1)
+	for i, violation := range e.Violations {
+		if i == maxReportedViolations {
+			parts = append(parts, fmt.Sprintf("and %d more", len(e.Violations)-i))
+			break
+		}
+		path := violation.Path
+		if path == "" {
+			path = "/"
+		}
+		parts = append(parts, path+": "+violation.Message)
+	}
+	return ErrSchemaValidation.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is reports whether target is ErrSchemaValidation.
+func (e *SchemaValidationError) Is(target error) bool {
+	return target == ErrSchemaValidation
+}
+
+// DecodeStructuredOutput validates payload against schema and unmarshals it
+// into T. Malformed JSON returns the decoding error; JSON that breaks the
+// schema returns a *SchemaValidationError.
+func DecodeStructuredOutput[T any](schema map[string]any, payload string) (T, error) {
+	var out T
+	if err := ValidateJSONSchema(schema, []byte(payload)); err != nil {
+		return out, utils.WrapIfNotNil(err)
+	}
+	if err := json.Unmarshal([]byte(payload), &out); err != nil {
+		var zero T
+		return zero, utils.WrapIfNotNil(err)
+	}
+	return out, nil
+}
+
+// ValidateJSONSchema checks data against the JSON Schema keywords that
+// reflected Go types use: type, properties, required, additionalProperties,
+// items, enum, const, anyOf/oneOf/allOf, local $ref, string length and
+// pattern, numeric bounds and array length. Unknown keywords are ignored, and
+// null is accepted for any value.
+func ValidateJSONSchema(schema map[string]any, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	if len(schema) == 0 {
+		return nil
+	}
+
+	v := schemaValidator{root: schema}
+	v.validate(schema, value, "")
+	if len(v.violations) > 0 {
+		return &SchemaValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	root       map[string]any
+	violations []SchemaViolation
+	refDepth   int
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target := v.resolveRef(ref)
+		if target == nil || v.refDepth > 64 {
+			v.fail(path, "unresolvable schema reference %q", ref)
+			return
+		}
+		v.refDepth++
+		v.validate(target, value, path)
+		v.refDepth--
+	}
+
+	if value == nil {
+		// Reflected schemas do not mark Go pointer, slice and map fields as
+		// nullable, and null decodes to the zero value, so null is accepted.
+		return
+	}
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesAnyType(types, value) {
+		v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsJSONValue(enum, value) {
+		v.fail(path, "value %s is not one of %s", compactJSON(value), compactJSON(enum))
+	}
+	if constant, ok := schema["const"]; ok && !jsonValuesEqual(constant, value) {
+		v.fail(path, "value %s must be %s", compactJSON(value), compactJSON(constant))
+	}
+	v.validateCombinators(schema, value, path)
+
+	switch typed := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, typed, path)
+	case []any:
+		v.validateArray(schema, typed, path)
+	case string:
+		v.validateString(schema, typed, path)
+	case json.Number:
+		v.validateNumber(schema, typed, path)
+	}
+}
+
+func (v *schemaValidator) validateCombinators(schema map[string]any, value any, path string) {
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			if subSchema, ok := sub.(map[string]any); ok {
+				v.validate(subSchema, value, path)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && v.countMatches(anyOf, value, path) == 0 {
+		v.fail(path, "value does not match any allowed schema")
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if matches := v.countMatches(oneOf, value, path); matches != 1 {
+			v.fail(path, "value matches %d schemas, expected exactly one", matches)
+		}
+	}
+}
+
+func (v *schemaValidator) countMatches(schemas []any, value any, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		subSchema, ok := sub.(map[string]any)
+		if !ok {
+			continue
+		}
+		probe := schemaValidator{root: v.root, refDepth: v.refDepth}
+		probe.validate(subSchema, value, path)
+		if len(probe.violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func (v *schemaValidator) validateObject(schema map[string]any, object map[string]any, path string) {
+	properties, _ := schema["properties"].(map[string]any)
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := object[key]; key != "" && !present {
+				v.fail(path+"/"+escapePointer(key), "missing required property")
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childPath := path + "/" + escapePointer(key)
+		if propertySchema, ok := properties[key].(map[string]any); ok {
+			v.validate(propertySchema, object[key], childPath)
+			continue
+		}
+		if _, declared := properties[key]; declared {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(childPath, "additional property is not allowed")
+			}
+		case map[string]any:
+			v.validate(additional, object[key], childPath)
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]any, array []any, path string) {
+	if minItems, ok := schemaInt(schema["minItems"]); ok && len(array) < minItems {
+		v.fail(path, "expected at least %d items, got %d", minItems, len(array))
+	}
+	if maxItems, ok := schemaInt(schema["maxItems"]); ok && len(array) > maxItems {
+		v.fail(path, "expected at most %d items, got %d", maxItems, len(array))
+	}
+	items, ok := schema["items"].(map[string]any)
+	if !ok {
+		return
+	}
+	for i, item := range array {
+		v.validate(items, item, path+"/"+strconv.Itoa(i))
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]any, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if minLength, ok := schemaInt(schema["minLength"]); ok && length < minLength {
+		v.fail(path, "expected at least %d characters, got %d", minLength, length)
+	}
+	if maxLength, ok := schemaInt(schema["maxLength"]); ok && length > maxLength {
+		v.fail(path, "expected at most %d characters, got %d", maxLength, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(value) {
+			v.fail(path, "value %q does not match pattern %q", value, pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]any, value json.Number, path string) {
+	number, err := value.Float64()
+	if err != nil {
+		return
+	}
+	if minimum, ok := schemaFloat(schema["minimum"]); ok && number < minimum {
+		v.fail(path, "value %s is less than minimum %v", value, minimum)
+	}
+	if maximum, ok := schemaFloat(schema["maximum"]); ok && number > maximum {
+		v.fail(path, "value %s is greater than maximum %v", value, maximum)
+	}
+	if minimum, ok := schemaFloat(schema["exclusiveMinimum"]); ok && number <= minimum {
+		v.fail(path, "value %s must be greater than %v", value, minimum)
+	}
+	if maximum, ok := schemaFloat(schema["exclusiveMaximum"]); ok && number >= maximum {
+		v.fail(path, "value %s must be less than %v", value, maximum)
+	}
+}
+
+// resolveRef resolves "#" and "#/$defs/Name" style references within the
+// root schema.
+func (v *schemaValidator) resolveRef(ref string) map[string]any {
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	current := any(v.root)
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if token == "" {
+			continue
+		}
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = object[strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")]
+	}
+	resolved, _ := current.(map[string]any)
+	return resolved
+}
+
+func schemaTypes(raw any) []string {
+	switch typed := raw.(type) {
+	case string:
+		return []string{typed}
+	case []any:
+		types := make([]string, 0, len(typed))
+		for _, entry := range typed {
+			if name, ok := entry.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(types []string, value any) bool {
+	for _, name := range types {
+		if matchesType(name, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(name string, value any) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		if _, err := number.Int64(); err == nil {
+			return true
+		}
+		f, err := number.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return true
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsJSONValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if jsonValuesEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValuesEqual compares a schema value (decoded without UseNumber) with a
+// document value (decoded with it).
+func jsonValuesEqual(schemaValue, value any) bool {
+	if number, ok := value.(json.Number); ok {
+		f, err := number.Float64()
+		expected, isNumber := schemaFloat(schemaValue)
+		return err == nil && isNumber && f == expected
+	}
+	return reflect.DeepEqual(normalizeJSONNumbers(schemaValue), normalizeJSONNumbers(value))
+}
+
+func normalizeJSONNumbers(value any) any {
+	switch typed := value.(type) {
+	case json.Number:
+		f, _ := typed.Float64()
+		return f
+	case int:
+		return float64(typed)
+	case int64:
+		return float64(typed)
+	case map[string]any:
+		normalized := make(map[string]any, len(typed))
+		for key, entry := range typed {
+			normalized[key] = normalizeJSONNumbers(entry)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(typed))
+		for i, entry := range typed {
+			normalized[i] = normalizeJSONNumbers(entry)
+		}
+		return normalized
+	}
+	return value
+}
+
+func schemaFloat(raw any) (float64, bool) {
+	switch typed := raw.(type) {
+	case float64:
+		return typed, true
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case json.Number:
+		f, err := typed.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func schemaInt(raw any) (int, bool) {
+	f, ok := schemaFloat(raw)
+	return int(f), ok
+}
+
+func compactJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
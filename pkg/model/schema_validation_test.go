@@ -0,0 +1,128 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaValidationSuite struct {
+	suite.Suite
+}
+
+func TestSchemaValidationSuite(t *testing.T) {
+	suite.Run(t, new(SchemaValidationSuite))
+}
+
+var labReportSchema = map[string]any{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []any{"patient", "results"},
+	"properties": map[string]any{
+		"patient": map[string]any{"type": "string", "minLength": 1},
+		"stage":   map[string]any{"type": "string", "enum": []any{"G1", "G2", "G3a", "G3b", "G4", "G5"}},
+		"results": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []any{"name", "value"},
+				"properties": map[string]any{
+					"name":  map[string]any{"type": "string"},
+					"value": map[string]any{"type": "number", "minimum": 0},
+					"count": map[string]any{"type": "integer"},
+				},
+			},
+		},
+		"notes": map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+	},
+}
+
+func (s *SchemaValidationSuite) TestValidDocumentPasses() {
+	err := ValidateJSONSchema(labReportSchema, []byte(`{
+		"patient": "p-1",
+		"stage": "G3a",
+		"results": [{"name": "egfr", "value": 48.5, "count": 2}],
+		"notes": {"source": "lab"}
+	}`))
+	s.NoError(err)
+
+	// null decodes to the zero value, so it is accepted.
+	s.NoError(ValidateJSONSchema(labReportSchema, []byte(`{"patient": "p-1", "stage": null, "results": null}`)))
+}
+
+func (s *SchemaValidationSuite) TestViolationsListEveryPath() {
+	err := ValidateJSONSchema(labReportSchema, []byte(`{
+		"patient": "",
+		"stage": "G9",
+		"results": [{"name": "egfr", "value": -1, "count": 1.5, "unit": "ml"}, {"value": "high"}],
+		"notes": {"source": 3},
+		"extra/key": true
+	}`))
+	s.Require().Error(err)
+	s.True(errors.Is(err, ErrSchemaValidation))
+
+	var validationErr *SchemaValidationError
+	s.Require().True(errors.As(err, &validationErr))
+	s.Equal([]SchemaViolation{
+		{Path: "/extra~1key", Message: "additional property is not allowed"},
+		{Path: "/notes/source", Message: "expected string, got number"},
+		{Path: "/patient", Message: "expected at least 1 characters, got 0"},
+		{Path: "/results/0/count", Message: "expected integer, got number"},
+		{Path: "/results/0/unit", Message: "additional property is not allowed"},
+		{Path: "/results/0/value", Message: "value -1 is less than minimum 0"},
+		{Path: "/results/1/name", Message: "missing required property"},
+		{Path: "/results/1/value", Message: "expected number, got string"},
+		{Path: "/stage", Message: `value "G9" is not one of ["G1","G2","G3a","G3b","G4","G5"]`},
+	}, validationErr.Violations)
+	s.Contains(err.Error(), "structured output does not match schema: /extra~1key: additional property is not allowed;")
+	s.Contains(err.Error(), "and 4 more")
+}
+
+func (s *SchemaValidationSuite) TestDecodeStructuredOutput() {
+	type report struct {
+		Patient string `json:"patient"`
+		Results []struct {
+			Name  string  `json:"name"`
+			Value float64 `json:"value"`
+		} `json:"results"`
+	}
+	out, err := DecodeStructuredOutput[report](labReportSchema, `{"patient":"p-1","results":[{"name":"egfr","value":48}]}`)
+	s.Require().NoError(err)
+	s.Equal("p-1", out.Patient)
+	s.Require().Len(out.Results, 1)
+	s.InDelta(48, out.Results[0].Value, 1e-9)
+
+	_, err = DecodeStructuredOutput[report](labReportSchema, `{"patient":`)
+	s.Require().Error(err)
+	s.False(errors.Is(err, ErrSchemaValidation))
+
+	_, err = DecodeStructuredOutput[report](labReportSchema, `{"patient":"p-1"}`)
+	s.ErrorIs(err, ErrSchemaValidation)
+}
+
+func (s *SchemaValidationSuite) TestRefsAndCombinators() {
+	schema := map[string]any{
+		"$ref": "#/$defs/Item",
+		"$defs": map[string]any{
+			"Item": map[string]any{
+				"type":     "object",
+				"required": []any{"kind"},
+				"properties": map[string]any{
+					"kind":  map[string]any{"const": "lab"},
+					"value": map[string]any{"anyOf": []any{map[string]any{"type": "number"}, map[string]any{"type": "string"}}},
+				},
+			},
+		},
+	}
+	s.NoError(ValidateJSONSchema(schema, []byte(`{"kind":"lab","value":"12"}`)))
+
+	err := ValidateJSONSchema(schema, []byte(`{"kind":"vital","value":true}`))
+	var validationErr *SchemaValidationError
+	s.Require().True(errors.As(err, &validationErr))
+	s.Equal([]SchemaViolation{
+		{Path: "/kind", Message: `value "vital" must be "lab"`},
+		{Path: "/value", Message: "value does not match any allowed schema"},
+	}, validationErr.Violations)
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// ChatMessage is one turn in a ChatSession history.
+type ChatMessage struct {
+	Role    ContextMessageType `json:"role"`
+	Content string             `json:"content"`
+}
+
+// ChatSession is a multi-turn conversation layered on a provider's
+// NewStringContentGenerator. Each Send builds a one-shot generator whose prompt
+// is the new message and whose prompt contexts are the accumulated history, so
+// it works with every provider.
+type ChatSession struct {
+	mu      sync.Mutex
+	factory NewStringContentGeneratorFunc
+	opts    []GeneratorOption
+	history []ChatMessage
+	policy  HistoryPolicy
+}
+
+type chatSessionJSON struct {
+	Messages []ChatMessage `json:"messages"`
+}
+
+// NewChatSession creates a session that generates replies with factory and opts.
+func NewChatSession(factory NewStringContentGeneratorFunc, opts ...GeneratorOption) (*ChatSession, error) {
+	if factory == nil {
+		return nil, utils.WrapIfNotNil(errors.New("generator factory is required"))
+	}
+
+	return &ChatSession{
+		factory: factory,
+		opts:    append([]GeneratorOption(nil), opts...),
+	}, nil
+}
+
+// AddSystemMessage appends a system instruction to the history.
+func (s *ChatSession) AddSystemMessage(content string) {
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, ChatMessage{Role: ContextMessageTypeSystem, Content: content})
+}
+
+// SetHistoryPolicy selects the history sent with each turn (see
+// LastTurnsPolicy, TokenWindowPolicy and SummarizeOverflowPolicy). A nil
+// policy sends the full history. History still returns every message.
+func (s *ChatSession) SetHistoryPolicy(policy HistoryPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+// Send generates a reply to message using the session history. The message and
+// reply are appended to the history only when generation succeeds.
+func (s *ChatSession) Send(ctx context.Context, message string) (string, GenerationMetadata, error) {
+	if strings.TrimSpace(message) == "" {
+		return "", nil, utils.WrapIfNotNil(errors.New("message is required"))
+	}
+
+	// Serialize turns so replies are appended in order.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gen, err := s.factory(message, s.opts...)
+	if err != nil {
+		return "", nil, utils.WrapIfNotNil(err)
+	}
+	window := s.history
+	if s.policy != nil {
+		window, err = s.policy.Window(ctx, append([]ChatMessage(nil), s.history...))
+		if err != nil {
+			return "", nil, utils.WrapIfNotNil(err)
+		}
+	}
+	for _, turn := range window {
+		gen.AddPromptContext(ctx, turn.Role, turn.Content)
+	}
+
+	reply, meta, err := gen.Generate(ctx)
+	if err != nil {
+		return "", meta, utils.WrapIfNotNil(err)
+	}
+
+	s.history = append(s.history,
+		ChatMessage{Role: ContextMessageTypeHuman, Content: message},
+		ChatMessage{Role: ContextMessageTypeAssistant, Content: reply},
+	)
+	return reply, meta, nil
+}
+
+// History returns a copy of the conversation so far.
+func (s *ChatSession) History() []ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ChatMessage(nil), s.history...)
+}
+
+// SetHistory replaces the conversation, for example after loading it from storage.
+func (s *ChatSession) SetHistory(history []ChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append([]ChatMessage(nil), history...)
+}
+
+// MarshalJSON serializes the session history as {"messages":[...]}.
+func (s *ChatSession) MarshalJSON() ([]byte, error) {
+	out, err := json.Marshal(chatSessionJSON{Messages: s.History()})
+	return out, utils.WrapIfNotNil(err)
+}
+
+// UnmarshalJSON restores history produced by MarshalJSON. The generator factory
+// and options are not serialized; create the session with NewChatSession first.
+func (s *ChatSession) UnmarshalJSON(data []byte) error {
+	var decoded chatSessionJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+
+	for _, message := range decoded.Messages {
+		switch message.Role {
+		case ContextMessageTypeSystem, ContextMessageTypeHuman, ContextMessageTypeAssistant:
+		default:
+			return utils.WrapIfNotNil(errors.New("unsupported chat message role: " + string(message.Role)))
+		}
+	}
+
+	s.SetHistory(decoded.Messages)
+	return nil
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ChatSessionSuite struct {
+	suite.Suite
+}
+
+func TestChatSessionSuite(t *testing.T) {
+	suite.Run(t, new(ChatSessionSuite))
+}
+
+type recordingGenerator struct {
+	prompt   string
+	contexts []*PromptContext
+	reply    string
+	err      error
+}
+
+func (g *recordingGenerator) Generate(ctx context.Context) (string, GenerationMetadata, error) {
+	return g.reply, GenerationMetadata{MetadataKeyProvider: "recording"}, g.err
+}
+
+func (g *recordingGenerator) AddPromptContext(ctx context.Context, messageType ContextMessageType, content string) {
+	g.contexts = append(g.contexts, &PromptContext{MessageType: messageType, Content: content})
+}
+
+func (g *recordingGenerator) AddPromptContextProvider(ctx context.Context, provider PromptContextProvider) {
+}
+
+func (s *ChatSessionSuite) TestSendAccumulatesHistory() {
+	var generators []*recordingGenerator
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		gen := &recordingGenerator{prompt: prompt, reply: "reply to " + prompt}
+		generators = append(generators, gen)
+		return gen, nil
+	}
+
+	session, err := NewChatSession(factory, WithModel("m"))
+	s.Require().NoError(err)
+	session.AddSystemMessage("be brief")
+
+	reply, meta, err := session.Send(context.Background(), "first")
+	s.Require().NoError(err)
+	s.Equal("reply to first", reply)
+	s.Equal("recording", meta[MetadataKeyProvider])
+
+	_, _, err = session.Send(context.Background(), "second")
+	s.Require().NoError(err)
+
+	s.Require().Len(generators, 2)
+	s.Equal("second", generators[1].prompt)
+	s.Require().Len(generators[1].contexts, 3)
+	s.Equal(ContextMessageTypeSystem, generators[1].contexts[0].MessageType)
+	s.Equal(ContextMessageTypeHuman, generators[1].contexts[1].MessageType)
+	s.Equal("first", generators[1].contexts[1].Content)
+	s.Equal(ContextMessageTypeAssistant, generators[1].contexts[2].MessageType)
+	s.Equal("reply to first", generators[1].contexts[2].Content)
+
+	s.Len(session.History(), 5)
+}
+
+func (s *ChatSessionSuite) TestFailedSendDoesNotChangeHistory() {
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{err: errors.New("provider down")}, nil
+	}
+
+	session, err := NewChatSession(factory)
+	s.Require().NoError(err)
+
+	_, _, err = session.Send(context.Background(), "hello")
+	s.Error(err)
+	s.Empty(session.History())
+
+	_, _, err = session.Send(context.Background(), " ")
+	s.Error(err)
+}
+
+func (s *ChatSessionSuite) TestJSONRoundTrip() {
+	factory := func(prompt string, opts ...GeneratorOption) (ContentGenerator[string], error) {
+		return &recordingGenerator{reply: "ok"}, nil
+	}
+
+	session, err := NewChatSession(factory)
+	s.Require().NoError(err)
+	session.AddSystemMessage("system")
+	_, _, err = session.Send(context.Background(), "hi")
+	s.Require().NoError(err)
+
+	data, err := json.Marshal(session)
+	s.Require().NoError(err)
+	s.JSONEq(`{"messages":[{"role":"system","content":"system"},{"role":"human","content":"hi"},{"role":"assistant","content":"ok"}]}`, string(data))
+
+	restored, err := NewChatSession(factory)
+	s.Require().NoError(err)
+	s.Require().NoError(json.Unmarshal(data, restored))
+	s.Equal(session.History(), restored.History())
+
+	s.Error(json.Unmarshal([]byte(`{"messages":[{"role":"tool","content":"x"}]}`), restored))
+}
+
+func (s *ChatSessionSuite) TestNewChatSessionRequiresFactory() {
+	_, err := NewChatSession(nil)
+	s.Error(err)
+}
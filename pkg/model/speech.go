@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"os"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// NewSpeechGeneratorFunc creates a text-to-speech generator for text.
+type NewSpeechGeneratorFunc func(text string, opts SpeechOptions) (SpeechGenerator, error)
+
+// SpeechGenerator represents "text in, audio out".
+type SpeechGenerator interface {
+	Generate(ctx context.Context) (SpeechAudio, GenerationMetadata, error)
+}
+
+// SpeechFormat is the encoding of generated speech.
+type SpeechFormat string
+
+const (
+	SpeechFormatMP3  SpeechFormat = "mp3"
+	SpeechFormatOpus SpeechFormat = "opus"
+	SpeechFormatAAC  SpeechFormat = "aac"
+	SpeechFormatFLAC SpeechFormat = "flac"
+	SpeechFormatWAV  SpeechFormat = "wav"
+	// SpeechFormatPCM is raw 16-bit little-endian mono samples (24 kHz for
+	// OpenAI and Gemini).
+	SpeechFormatPCM SpeechFormat = "pcm"
+)
+
+var speechFormatMIMETypes = map[SpeechFormat]string{
+	SpeechFormatMP3:  "audio/mpeg",
+	SpeechFormatOpus: "audio/ogg",
+	SpeechFormatAAC:  "audio/aac",
+	SpeechFormatFLAC: "audio/flac",
+	SpeechFormatWAV:  "audio/wav",
+	SpeechFormatPCM:  "audio/L16",
+}
+
+// MIMEType returns the MIME type of f, or "" for unknown formats.
+func (f SpeechFormat) MIMEType() string {
+	return speechFormatMIMETypes[f]
+}
+
+// SpeechOptions configures a SpeechGenerator, like AudioOptions does for
+// transcription.
+type SpeechOptions struct {
+	IgnoreInvalidGeneratorOptions bool
+	URL                           string
+	AuthToken                     string
+	Model                         string
+	// TLSConfig configures TLS for self-hosted endpoints (see WithTLSConfig).
+	TLSConfig *tls.Config
+	// GCPProject and GCPLocation select the Vertex AI backend for providers that support it.
+	GCPProject  string
+	GCPLocation string
+	// Voice is a provider voice name (for example "alloy" for OpenAI or
+	// "Kore" for Gemini). Empty uses the provider default.
+	Voice string
+	// Format is the output encoding. Empty uses the provider default (mp3
+	// for OpenAI, wav for Gemini).
+	Format SpeechFormat
+	// Speed scales the speaking rate where supported (OpenAI: 0.25 to 4).
+	Speed *float64
+	// Instructions steer tone and delivery (for example "speak calmly").
+	Instructions string
+}
+
+// SpeechAudio is generated speech.
+type SpeechAudio struct {
+	Data     []byte
+	Format   SpeechFormat
+	MIMEType string
+}
+
+// WriteFile writes the audio to path.
+func (a SpeechAudio) WriteFile(path string) error {
+	if len(a.Data) == 0 {
+		return utils.WrapIfNotNil(errors.New("speech audio is empty"))
+	}
+	return utils.WrapIfNotNil(os.WriteFile(path, a.Data, 0o644))
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// StreamChunk is an incremental piece of generated text.
+type StreamChunk struct {
+	Text string
+}
+
+// StreamHandler receives chunks as they are generated. Returning an error stops the stream.
+type StreamHandler func(chunk StreamChunk) error
+
+// StreamingContentGenerator is implemented by text generators that can emit
+// output incrementally. GenerateStream calls onChunk for each piece of final
+// answer text and returns the full text once generation completes. Text of
+// rounds that call tools is never passed to onChunk; a generator that cannot
+// tell whether a round will call tools holds its text until the round ends.
+type StreamingContentGenerator interface {
+	ContentGenerator[string]
+	GenerateStream(ctx context.Context, onChunk StreamHandler) (string, GenerationMetadata, error)
+}
+
+// MetadataKeyTruncated is "true" when a streaming generation returned the
+// text streamed before its deadline instead of an error (see
+// WithPartialStreamOnDeadline).
+const MetadataKeyTruncated = "truncated"
+
+// WithPartialStreamOnDeadline makes GenerateStream return the text streamed so
+// far, with MetadataKeyTruncated set to "true" and a nil error, when the
+// context deadline expires mid-stream, so a UI can keep what it has shown.
+// Streams that produced no text, Generate calls and cancellations other than
+// the deadline still return the error.
+func WithPartialStreamOnDeadline(enabled bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.PartialStreamOnDeadline = enabled
+	})
+}
+
+// PartialStream keeps the text a streaming generator has handed to its
+// StreamHandler, for returning it under WithPartialStreamOnDeadline. Only
+// final answer text reaches the handler (see StreamingContentGenerator), so
+// after tool rounds it holds the answer that was cut off, not the rounds'
+// narration; generators must not pass tool-round text through Wrap. The zero
+// value is ready to use; it is not safe for concurrent use.
+type PartialStream struct {
+	text strings.Builder
+}
+
+// Wrap returns a handler that records each chunk onChunk accepts. It returns
+// nil when onChunk is nil, so non-streaming calls stay non-streaming.
+func (p *PartialStream) Wrap(onChunk StreamHandler) StreamHandler {
+	if onChunk == nil {
+		return nil
+	}
+	return func(chunk StreamChunk) error {
+		if err := onChunk(chunk); err != nil {
+			return err
+		}
+		p.text.WriteString(chunk.Text)
+		return nil
+	}
+}
+
+// Salvage returns the streamed text and true when cfg enables
+// WithPartialStreamOnDeadline, err comes from the context deadline and some
+// text was streamed. It then marks meta as truncated and adds the
+// WithDisclosure notice.
+func (p *PartialStream) Salvage(ctx context.Context, cfg GeneratorConfig, meta GenerationMetadata, err error) (string, bool) {
+	if !cfg.PartialStreamOnDeadline || err == nil || p.text.Len() == 0 {
+		return "", false
+	}
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", false
+	}
+	if meta != nil {
+		meta[MetadataKeyTruncated] = "true"
+	}
+	return ApplyDisclosure(cfg, p.text.String()), true
+}
+
+// StreamWriteOption configures GenerateTo.
+type StreamWriteOption func(*streamWriteConfig)
+
+type streamWriteConfig struct {
+	flushEveryChunks int
+	flushInterval    time.Duration
+}
+
+// WithFlushEveryChunks flushes the writer after every n chunks (default 1).
+func WithFlushEveryChunks(n int) StreamWriteOption {
+	return func(cfg *streamWriteConfig) {
+		cfg.flushEveryChunks = n
+	}
+}
+
+// WithFlushInterval flushes the writer at most once per interval instead of per chunk.
+// Buffered output is always flushed when generation ends.
+func WithFlushInterval(interval time.Duration) StreamWriteOption {
+	return func(cfg *streamWriteConfig) {
+		cfg.flushInterval = interval
+	}
+}
+
+// GenerateTo writes the final answer text to w as it is produced. Generators that do
+// not implement StreamingContentGenerator are run with Generate and their full
+// output is written once. Writers exposing Flush() (for example
+// http.ResponseWriter via http.Flusher) or Flush() error (for example
+// bufio.Writer) are flushed according to the configured cadence. When a
+// streaming generator implements EffectiveConfigReporter, its WithDisclosure
+// notice is written before the first chunk or after the last, as Generate
+// would return it.
+func GenerateTo(ctx context.Context, gen ContentGenerator[string], w io.Writer, opts ...StreamWriteOption) (GenerationMetadata, error) {
+	if gen == nil {
+		return nil, utils.WrapIfNotNil(errors.New("generator is required"))
+	}
+	if w == nil {
+		return nil, utils.WrapIfNotNil(errors.New("writer is required"))
+	}
+
+	cfg := streamWriteConfig{flushEveryChunks: 1}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	streaming, ok := gen.(StreamingContentGenerator)
+	if !ok {
+		text, meta, err := gen.Generate(ctx)
+		if err != nil {
+			return meta, utils.WrapIfNotNil(err)
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return meta, utils.WrapIfNotNil(err)
+		}
+		return meta, utils.WrapIfNotNil(flushWriter(w))
+	}
+
+	var prefix, suffix string
+	if reporter, ok := gen.(EffectiveConfigReporter); ok {
+		genCfg, err := reporter.EffectiveConfig(ctx)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		prefix, suffix = disclosureAffixes(genCfg)
+	}
+
+	pending := 0
+	lastFlush := time.Now()
+	_, meta, err := streaming.GenerateStream(ctx, func(chunk StreamChunk) error {
+		if chunk.Text == "" {
+			return nil
+		}
+		if _, err := io.WriteString(w, prefix+chunk.Text); err != nil {
+			return err
+		}
+		prefix = ""
+
+		pending++
+		due := cfg.flushEveryChunks > 0 && pending >= cfg.flushEveryChunks
+		if cfg.flushInterval > 0 {
+			due = time.Since(lastFlush) >= cfg.flushInterval
+		}
+		if !due {
+			return nil
+		}
+
+		pending = 0
+		lastFlush = time.Now()
+		return flushWriter(w)
+	})
+	if err == nil && prefix+suffix != "" {
+		_, err = io.WriteString(w, prefix+suffix)
+	}
+	if flushErr := flushWriter(w); err == nil {
+		err = flushErr
+	}
+	return meta, utils.WrapIfNotNil(err)
+}
+
+func flushWriter(w io.Writer) error {
+	switch flusher := w.(type) {
+	case interface{ Flush() error }:
+		return flusher.Flush()
+	case interface{ Flush() }:
+		flusher.Flush()
+	}
+	return nil
+}
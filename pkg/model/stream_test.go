@@ -0,0 +1,196 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamSuite struct {
+	suite.Suite
+}
+
+func TestStreamSuite(t *testing.T) {
+	suite.Run(t, new(StreamSuite))
+}
+
+type staticGenerator struct {
+	text string
+	err  error
+}
+
+func (g *staticGenerator) Generate(ctx context.Context) (string, GenerationMetadata, error) {
+	return g.text, GenerationMetadata{MetadataKeyProvider: "static"}, g.err
+}
+
+func (g *staticGenerator) AddPromptContext(ctx context.Context, messageType ContextMessageType, content string) {
+}
+
+func (g *staticGenerator) AddPromptContextProvider(ctx context.Context, provider PromptContextProvider) {
+}
+
+type chunkedGenerator struct {
+	staticGenerator
+	chunks []string
+}
+
+func (g *chunkedGenerator) GenerateStream(ctx context.Context, onChunk StreamHandler) (string, GenerationMetadata, error) {
+	for _, chunk := range g.chunks {
+		if err := onChunk(StreamChunk{Text: chunk}); err != nil {
+			return "", nil, err
+		}
+	}
+	return g.text, GenerationMetadata{MetadataKeyProvider: "chunked"}, g.err
+}
+
+// reportingGenerator streams chunks and reports cfg as its effective
+// configuration.
+type reportingGenerator struct {
+	chunkedGenerator
+	cfg GeneratorConfig
+}
+
+func (g *reportingGenerator) EffectiveConfig(ctx context.Context) (GeneratorConfig, error) {
+	return g.cfg, nil
+}
+
+type flushCountingWriter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (w *flushCountingWriter) Flush() {
+	w.flushes++
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func (s *StreamSuite) TestGenerateToFallsBackToGenerate() {
+	writer := &flushCountingWriter{}
+	meta, err := GenerateTo(context.Background(), &staticGenerator{text: "hello"}, writer)
+
+	s.Require().NoError(err)
+	s.Equal("hello", writer.String())
+	s.Equal("static", meta[MetadataKeyProvider])
+	s.Equal(1, writer.flushes)
+}
+
+func (s *StreamSuite) TestGenerateToStreamsChunksAndFlushesEachChunk() {
+	writer := &flushCountingWriter{}
+	gen := &chunkedGenerator{staticGenerator: staticGenerator{text: "abc"}, chunks: []string{"a", "", "b", "c"}}
+
+	meta, err := GenerateTo(context.Background(), gen, writer)
+
+	s.Require().NoError(err)
+	s.Equal("abc", writer.String())
+	s.Equal("chunked", meta[MetadataKeyProvider])
+	s.Equal(4, writer.flushes)
+}
+
+func (s *StreamSuite) TestGenerateToFlushCadence() {
+	writer := &flushCountingWriter{}
+	gen := &chunkedGenerator{chunks: []string{"a", "b", "c", "d", "e"}}
+
+	_, err := GenerateTo(context.Background(), gen, writer, WithFlushEveryChunks(2))
+
+	s.Require().NoError(err)
+	s.Equal("abcde", writer.String())
+	s.Equal(3, writer.flushes)
+}
+
+func (s *StreamSuite) TestGenerateToWriteErrorStopsStream() {
+	gen := &chunkedGenerator{chunks: []string{"a", "b"}}
+
+	_, err := GenerateTo(context.Background(), gen, failingWriter{})
+	s.Error(err)
+	s.Contains(err.Error(), "write failed")
+}
+
+func (s *StreamSuite) TestGenerateToWritesDisclosureAroundStream() {
+	notice := "Drafted by AI; reviewed by clinician."
+	cases := []struct {
+		name      string
+		placement DisclosurePlacement
+		chunks    []string
+		want      string
+	}{
+		{name: "append", placement: DisclosurePlacementAppend, chunks: []string{"eGFR ", "is 52."}, want: "eGFR is 52.\n\n" + notice},
+		{name: "prepend", placement: DisclosurePlacementPrepend, chunks: []string{"eGFR ", "is 52."}, want: notice + "\n\neGFR is 52."},
+		{name: "embed", placement: DisclosurePlacementEmbed, chunks: []string{"eGFR ", "is 52."}, want: "eGFR is 52." + encodeDisclosureWatermark(notice)},
+		{name: "metadata", placement: DisclosurePlacementMetadata, chunks: []string{"eGFR ", "is 52."}, want: "eGFR is 52."},
+		{name: "prepend without chunks", placement: DisclosurePlacementPrepend, want: notice + "\n\n"},
+	}
+	for _, tc := range cases {
+		s.Run(tc.name, func() {
+			cfg := ResolveGeneratorOpts(WithDisclosure(DisclosureConfig{Notice: notice, Placement: tc.placement}))
+			gen := &reportingGenerator{chunkedGenerator: chunkedGenerator{chunks: tc.chunks}, cfg: cfg}
+
+			writer := &flushCountingWriter{}
+			_, err := GenerateTo(context.Background(), gen, writer)
+			s.Require().NoError(err)
+			s.Equal(tc.want, writer.String())
+			s.Equal(ApplyDisclosure(cfg, strings.Join(tc.chunks, "")), writer.String(), "streaming matches Generate")
+		})
+	}
+}
+
+func (s *StreamSuite) TestGenerateToSkipsDisclosureWhenStreamFails() {
+	cfg := ResolveGeneratorOpts(WithDisclosure(DisclosureConfig{}))
+	gen := &reportingGenerator{
+		chunkedGenerator: chunkedGenerator{staticGenerator: staticGenerator{err: errors.New("stream broke")}, chunks: []string{"eGFR "}},
+		cfg:              cfg,
+	}
+
+	writer := &flushCountingWriter{}
+	_, err := GenerateTo(context.Background(), gen, writer)
+	s.ErrorContains(err, "stream broke")
+	s.Equal("eGFR ", writer.String())
+}
+
+func (s *StreamSuite) TestGenerateToValidatesInputs() {
+	_, err := GenerateTo(context.Background(), nil, &bytes.Buffer{})
+	s.Error(err)
+
+	_, err = GenerateTo(context.Background(), &staticGenerator{}, nil)
+	s.Error(err)
+}
+
+func (s *StreamSuite) TestPartialStreamSalvage() {
+	deadline := fmt.Errorf("read body: %w", context.DeadlineExceeded)
+	enabled := ResolveGeneratorOpts(WithPartialStreamOnDeadline(true))
+
+	partial := &PartialStream{}
+	s.Nil(partial.Wrap(nil))
+	_, ok := partial.Salvage(context.Background(), enabled, GenerationMetadata{}, deadline)
+	s.False(ok, "nothing was streamed")
+
+	handler := partial.Wrap(func(chunk StreamChunk) error {
+		if chunk.Text == "rejected" {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	s.Require().NoError(handler(StreamChunk{Text: "Hello, "}))
+	s.Require().Error(handler(StreamChunk{Text: "rejected"}))
+	s.Require().NoError(handler(StreamChunk{Text: "wor"}))
+
+	meta := GenerationMetadata{}
+	text, ok := partial.Salvage(context.Background(), enabled, meta, deadline)
+	s.True(ok)
+	s.Equal("Hello, wor", text)
+	s.Equal("true", meta[MetadataKeyTruncated])
+
+	_, ok = partial.Salvage(context.Background(), ResolveGeneratorOpts(), GenerationMetadata{}, deadline)
+	s.False(ok, "option disabled")
+	_, ok = partial.Salvage(context.Background(), enabled, GenerationMetadata{}, context.Canceled)
+	s.False(ok, "cancellation is not a deadline")
+}
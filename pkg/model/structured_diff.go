@@ -0,0 +1,215 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// DiffKind is how a field differs between two structured outputs.
+type DiffKind string
+
+const (
+	// DiffAdded is a field only the right value has.
+	DiffAdded DiffKind = "added"
+	// DiffRemoved is a field only the left value has.
+	DiffRemoved DiffKind = "removed"
+	// DiffChanged is a field both have with different values, including
+	// different JSON types.
+	DiffChanged DiffKind = "changed"
+)
+
+// FieldDiff is one difference between two structured outputs. Left and
+// Right hold the decoded JSON values (nil on the side that lacks the field).
+type FieldDiff struct {
+	// Path is a JSON Pointer to the field, such as "/medications/1/dose";
+	// "" is the whole document.
+	Path  string
+	Kind  DiffKind
+	Left  any
+	Right any
+}
+
+func (d FieldDiff) String() string {
+	path := d.Path
+	if path == "" {
+		path = "/"
+	}
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%s: added %s", path, diffValueString(d.Right))
+	case DiffRemoved:
+		return fmt.Sprintf("%s: removed %s", path, diffValueString(d.Left))
+	}
+	return fmt.Sprintf("%s: %s -> %s", path, diffValueString(d.Left), diffValueString(d.Right))
+}
+
+// DiffOption configures DiffJSON and DiffStructured.
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	tolerance float64
+	ignore    map[string]bool
+}
+
+// WithDiffTolerance treats numbers that differ by at most tolerance as equal,
+// for scores and measurements that vary slightly between models.
+func WithDiffTolerance(tolerance float64) DiffOption {
+	return func(cfg *diffConfig) {
+		cfg.tolerance = math.Abs(tolerance)
+	}
+}
+
+// WithDiffIgnore skips the fields at the given JSON Pointers and everything
+// below them, for example "/generated_at".
+func WithDiffIgnore(paths ...string) DiffOption {
+	return func(cfg *diffConfig) {
+		for _, path := range paths {
+			cfg.ignore[strings.TrimSpace(path)] = true
+		}
+	}
+}
+
+// DiffStructured compares two values field by field through their JSON
+// encoding, so fields are named by their json tags. It is meant for
+// comparing the structured outputs of two providers or models, for example
+// while migrating between them. The result is sorted by path and empty when
+// the values are equal.
+func DiffStructured[T any](left T, right T, opts ...DiffOption) ([]FieldDiff, error) {
+	leftJSON, err := json.Marshal(left)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	rightJSON, err := json.Marshal(right)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	diffs, err := DiffJSON(leftJSON, rightJSON, opts...)
+	return diffs, utils.WrapIfNotNil(err)
+}
+
+// DiffJSON compares two JSON documents like DiffStructured. Objects are
+// compared by key and arrays by index, so an inserted array item shows as
+// changes to every later index plus an added last item.
+func DiffJSON(left []byte, right []byte, opts ...DiffOption) ([]FieldDiff, error) {
+	cfg := diffConfig{ignore: map[string]bool{}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	leftValue, err := decodeDiffJSON(left)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(fmt.Errorf("left document: %w", err))
+	}
+	rightValue, err := decodeDiffJSON(right)
+	if err != nil {
+		return nil, utils.WrapIfNotNil(fmt.Errorf("right document: %w", err))
+	}
+
+	var diffs []FieldDiff
+	cfg.diff("", leftValue, rightValue, &diffs)
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs, nil
+}
+
+func decodeDiffJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (cfg diffConfig) diff(path string, left any, right any, diffs *[]FieldDiff) {
+	if cfg.ignore[path] {
+		return
+	}
+
+	switch leftTyped := left.(type) {
+	case map[string]any:
+		rightTyped, ok := right.(map[string]any)
+		if !ok {
+			break
+		}
+		for key, leftField := range leftTyped {
+			fieldPath := path + "/" + escapePointer(key)
+			rightField, found := rightTyped[key]
+			if !found {
+				if !cfg.ignore[fieldPath] {
+					*diffs = append(*diffs, FieldDiff{Path: fieldPath, Kind: DiffRemoved, Left: leftField})
+				}
+				continue
+			}
+			cfg.diff(fieldPath, leftField, rightField, diffs)
+		}
+		for key, rightField := range rightTyped {
+			fieldPath := path + "/" + escapePointer(key)
+			if _, found := leftTyped[key]; !found && !cfg.ignore[fieldPath] {
+				*diffs = append(*diffs, FieldDiff{Path: fieldPath, Kind: DiffAdded, Right: rightField})
+			}
+		}
+		return
+	case []any:
+		rightTyped, ok := right.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(leftTyped), len(rightTyped)) {
+			itemPath := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(rightTyped):
+				if !cfg.ignore[itemPath] {
+					*diffs = append(*diffs, FieldDiff{Path: itemPath, Kind: DiffRemoved, Left: leftTyped[i]})
+				}
+			case i >= len(leftTyped):
+				if !cfg.ignore[itemPath] {
+					*diffs = append(*diffs, FieldDiff{Path: itemPath, Kind: DiffAdded, Right: rightTyped[i]})
+				}
+			default:
+				cfg.diff(itemPath, leftTyped[i], rightTyped[i], diffs)
+			}
+		}
+		return
+	case json.Number:
+		if rightTyped, ok := right.(json.Number); ok && cfg.numbersEqual(leftTyped, rightTyped) {
+			return
+		}
+	default:
+		if left == right {
+			return
+		}
+	}
+	*diffs = append(*diffs, FieldDiff{Path: path, Kind: DiffChanged, Left: left, Right: right})
+}
+
+func (cfg diffConfig) numbersEqual(left json.Number, right json.Number) bool {
+	if left == right {
+		return true
+	}
+	leftFloat, leftErr := left.Float64()
+	rightFloat, rightErr := right.Float64()
+	if leftErr != nil || rightErr != nil {
+		return false
+	}
+	return math.Abs(leftFloat-rightFloat) <= cfg.tolerance
+}
+
+func diffValueString(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
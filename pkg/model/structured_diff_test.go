@@ -0,0 +1,87 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StructuredDiffSuite struct {
+	suite.Suite
+}
+
+func TestStructuredDiffSuite(t *testing.T) {
+	suite.Run(t, new(StructuredDiffSuite))
+}
+
+type diffMedication struct {
+	Name string  `json:"name"`
+	Dose float64 `json:"dose"`
+}
+
+type diffNote struct {
+	Patient     string           `json:"patient"`
+	Age         int              `json:"age"`
+	Medications []diffMedication `json:"medications"`
+	Notes       *string          `json:"notes,omitempty"`
+}
+
+func (s *StructuredDiffSuite) TestDiffStructuredReportsFieldChanges() {
+	note := "stable"
+	left := diffNote{Patient: "Ada", Age: 36, Medications: []diffMedication{{Name: "lisinopril", Dose: 10}}}
+	right := diffNote{
+		Patient:     "Ada",
+		Age:         37,
+		Medications: []diffMedication{{Name: "lisinopril", Dose: 10.0001}, {Name: "metformin", Dose: 500}},
+		Notes:       &note,
+	}
+
+	diffs, err := DiffStructured(left, right)
+	s.Require().NoError(err)
+	s.Equal([]FieldDiff{
+		{Path: "/age", Kind: DiffChanged, Left: json.Number("36"), Right: json.Number("37")},
+		{Path: "/medications/0/dose", Kind: DiffChanged, Left: json.Number("10"), Right: json.Number("10.0001")},
+		{Path: "/medications/1", Kind: DiffAdded, Right: map[string]any{"name": "metformin", "dose": json.Number("500")}},
+		{Path: "/notes", Kind: DiffAdded, Right: "stable"},
+	}, diffs)
+	s.Equal("/age: 36 -> 37", diffs[0].String())
+	s.Equal(`/notes: added "stable"`, diffs[3].String())
+
+	diffs, err = DiffStructured(left, right, WithDiffTolerance(0.01), WithDiffIgnore("/medications", "/notes"))
+	s.Require().NoError(err)
+	s.Equal([]string{"/age"}, diffPaths(diffs))
+
+	diffs, err = DiffStructured(left, left)
+	s.Require().NoError(err)
+	s.Empty(diffs)
+}
+
+func (s *StructuredDiffSuite) TestDiffJSONHandlesTypesAndEscaping() {
+	diffs, err := DiffJSON(
+		[]byte(`{"a/b":1,"list":[1,2],"kind":"x","gone":true}`),
+		[]byte(`{"a/b":1.0,"list":[1],"kind":{"name":"x"}}`),
+	)
+	s.Require().NoError(err)
+	s.Equal([]FieldDiff{
+		{Path: "/gone", Kind: DiffRemoved, Left: true},
+		{Path: "/kind", Kind: DiffChanged, Left: "x", Right: map[string]any{"name": "x"}},
+		{Path: "/list/1", Kind: DiffRemoved, Left: json.Number("2")},
+	}, diffs)
+
+	diffs, err = DiffJSON([]byte(`[1]`), []byte(`"1"`))
+	s.Require().NoError(err)
+	s.Equal("/: [1] -> \"1\"", diffs[0].String())
+
+	_, err = DiffJSON([]byte(`{`), []byte(`{}`))
+	s.Require().Error(err)
+	s.Contains(err.Error(), "left document")
+}
+
+func diffPaths(diffs []FieldDiff) []string {
+	paths := make([]string, len(diffs))
+	for i, diff := range diffs {
+		paths[i] = diff.Path
+	}
+	return paths
+}
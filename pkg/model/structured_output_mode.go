@@ -0,0 +1,41 @@
+package model
+
+// StructuredOutputMode selects how structured generators ask the model for
+// JSON on providers with a native JSON schema mode (OpenAI, Ollama) or an
+// output tool (Anthropic, Bedrock).
+type StructuredOutputMode string
+
+const (
+	// StructuredOutputModeAuto uses the native strict JSON schema mode and
+	// falls back to StructuredOutputModePrompt when the endpoint rejects it,
+	// as some OpenAI-compatible gateways do.
+	StructuredOutputModeAuto StructuredOutputMode = "auto"
+	// StructuredOutputModeNative always uses the native JSON schema mode and
+	// returns the endpoint error when it is rejected.
+	StructuredOutputModeNative StructuredOutputMode = "native"
+	// StructuredOutputModePrompt skips the native mode and sends the schema
+	// as an instruction, parsing JSON from the text answer.
+	StructuredOutputModePrompt StructuredOutputMode = "prompt"
+)
+
+// WithStructuredOutputMode selects how structured output is requested. The
+// default is StructuredOutputModeAuto.
+func WithStructuredOutputMode(mode StructuredOutputMode) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.StructuredOutputMode = &mode
+	})
+}
+
+// ResolveStructuredOutputMode returns the effective structured output mode
+// for cfg. Unknown values resolve to StructuredOutputModeAuto.
+func ResolveStructuredOutputMode(cfg GeneratorConfig) StructuredOutputMode {
+	if cfg.StructuredOutputMode == nil {
+		return StructuredOutputModeAuto
+	}
+	switch mode := *cfg.StructuredOutputMode; mode {
+	case StructuredOutputModeNative, StructuredOutputModePrompt:
+		return mode
+	default:
+		return StructuredOutputModeAuto
+	}
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StructuredOutputModeSuite struct {
+	suite.Suite
+}
+
+func TestStructuredOutputModeSuite(t *testing.T) {
+	suite.Run(t, new(StructuredOutputModeSuite))
+}
+
+func (s *StructuredOutputModeSuite) TestResolve() {
+	s.Equal(StructuredOutputModeAuto, ResolveStructuredOutputMode(ResolveGeneratorOpts()))
+	s.Equal(StructuredOutputModeAuto, ResolveStructuredOutputMode(ResolveGeneratorOpts(WithStructuredOutputMode("strict"))))
+	s.Equal(StructuredOutputModeNative, ResolveStructuredOutputMode(ResolveGeneratorOpts(WithStructuredOutputMode(StructuredOutputModeNative))))
+	s.Equal(StructuredOutputModePrompt, ResolveStructuredOutputMode(ResolveGeneratorOpts(WithStructuredOutputMode(StructuredOutputModePrompt))))
+}
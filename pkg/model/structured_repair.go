@@ -0,0 +1,129 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// MetadataKeyStructuredRepairs is the number of repair prompts a structured
+// generation needed (see WithStructuredRepairAttempts); absent when the first
+// answer decoded.
+const MetadataKeyStructuredRepairs = "structured_repairs"
+
+// StructuredRepairSystemPrompt is the system prompt providers send with
+// StructuredRepairPrompt.
+const StructuredRepairSystemPrompt = "You are a strict JSON formatter."
+
+// WithStructuredRepairAttempts sets how many times a structured generator
+// re-prompts the model with the decoding or schema validation error when its
+// answer cannot be used. Zero disables repair. Without this option Ollama
+// makes one attempt and other providers none, or one when WithAssertion is set.
+func WithStructuredRepairAttempts(n int) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.StructuredRepairAttempts = &n
+	})
+}
+
+// ResolveStructuredRepairAttempts returns the configured repair attempts, or
+// providerDefault when none are set (at least one with assertions).
+func ResolveStructuredRepairAttempts(cfg GeneratorConfig, providerDefault int) int {
+	if cfg.StructuredRepairAttempts == nil {
+		if len(cfg.Assertions) > 0 {
+			return max(providerDefault, 1)
+		}
+		return providerDefault
+	}
+	return max(*cfg.StructuredRepairAttempts, 0)
+}
+
+// StructuredRepairFunc sends a repair prompt to the model as a new single-turn
+// request without tools, returning its answer and the call's metadata.
+type StructuredRepairFunc func(ctx context.Context, prompt string) (string, GenerationMetadata, error)
+
+// StructuredRepairPrompt asks the model to rewrite output as JSON matching
+// schema, quoting the error that made output unusable.
+func StructuredRepairPrompt(schema map[string]any, output string, decodeErr error) (string, error) {
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return "", utils.WrapIfNotNil(err)
+	}
+	return "The output below could not be used: " + decodeErr.Error() + "\n\n" +
+		"Rewrite it as valid JSON matching this schema. Keep the original values where they are valid. " +
+		"Return only JSON, without markdown fences.\n\n" +
+		"Schema:\n" + string(schemaBytes) + "\n\n" +
+		"Output:\n" + output, nil
+}
+
+// DecodeStructuredOutputWithRepair decodes output like DecodeStructuredOutput,
+// after cfg.OutputTransformers rewrite the model text and extract pulls the
+// JSON out of it. While decoding fails
+// and attempts remain, it sends StructuredRepairPrompt through repair and
+// decodes the answer. Repair usage is added to meta, raw_output follows the
+// latest answer and structured_repairs counts the prompts. When repair fails
+// or runs out, the last decoding error is returned. With WithFieldProvenance
+// the payload is the provenance envelope of StructuredOutputSchema. Decoded
+// output must also pass cfg.Assertions; an *AssertionError is repaired like a
+// decoding error.
+func DecodeStructuredOutputWithRepair[T any](
+	ctx context.Context,
+	cfg GeneratorConfig,
+	meta GenerationMetadata,
+	schema map[string]any,
+	output string,
+	attempts int,
+	extract func(string) string,
+	repair StructuredRepairFunc,
+) (T, error) {
+	log := logging.NewLogger(ctx)
+	decode := func(output string) (T, error) {
+		transformed, err := TransformOutput(ctx, cfg, output)
+		if err != nil {
+			var zero T
+			return zero, utils.WrapIfNotNil(err)
+		}
+		payload := extract(transformed)
+		var out T
+		if cfg.FieldProvenance {
+			out, err = decodeWithProvenance[T](schema, payload, meta)
+		} else {
+			out, err = DecodeStructuredOutput[T](schema, payload)
+		}
+		if err != nil {
+			return out, err
+		}
+		if err := CheckAssertions(cfg, out); err != nil {
+			var zero T
+			return zero, utils.WrapIfNotNil(err)
+		}
+		return out, nil
+	}
+	out, err := decode(output)
+	for attempt := 1; err != nil && attempt <= attempts && repair != nil; attempt++ {
+		log.Warnf("structured output unusable, repair attempt %d of %d: %v", attempt, attempts, err)
+		prompt, promptErr := StructuredRepairPrompt(schema, output, err)
+		if promptErr != nil {
+			break
+		}
+		repaired, repairMeta, repairErr := repair(ctx, prompt)
+		MergeUsageMetadata(meta, repairMeta)
+		if meta != nil {
+			meta[MetadataKeyStructuredRepairs] = strconv.Itoa(attempt)
+		}
+		if repairErr != nil {
+			log.Warnf("structured output repair failed: %v", repairErr)
+			break
+		}
+		output = repaired
+		SetRawOutput(meta, cfg, output)
+		out, err = decode(output)
+	}
+	if err != nil {
+		var zero T
+		return zero, utils.WrapIfNotNil(err)
+	}
+	return out, nil
+}
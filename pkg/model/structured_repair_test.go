@@ -0,0 +1,83 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StructuredRepairSuite struct {
+	suite.Suite
+}
+
+func TestStructuredRepairSuite(t *testing.T) {
+	suite.Run(t, new(StructuredRepairSuite))
+}
+
+type repairTarget struct {
+	Name string `json:"name"`
+}
+
+var repairSchema = map[string]any{
+	"type":                 "object",
+	"properties":           map[string]any{"name": map[string]any{"type": "string"}},
+	"required":             []any{"name"},
+	"additionalProperties": false,
+}
+
+func (s *StructuredRepairSuite) TestResolveUsesProviderDefault() {
+	s.Equal(1, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(), 1))
+	s.Equal(0, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(WithStructuredRepairAttempts(-2)), 1))
+	s.Equal(3, ResolveStructuredRepairAttempts(ResolveGeneratorOpts(WithStructuredRepairAttempts(3)), 0))
+}
+
+func (s *StructuredRepairSuite) TestRepairsUntilOutputDecodes() {
+	replies := []string{`{"nome":"x"}`, `{"name":"fixed"}`}
+	var prompts []string
+	repair := func(ctx context.Context, prompt string) (string, GenerationMetadata, error) {
+		prompts = append(prompts, prompt)
+		reply := replies[0]
+		replies = replies[1:]
+		return reply, GenerationMetadata{MetadataKeyAPICalls: "1", MetadataKeyInputTokens: "10"}, nil
+	}
+	cfg := ResolveGeneratorOpts(WithRawOutput(true))
+	meta := GenerationMetadata{MetadataKeyAPICalls: "1", MetadataKeyInputTokens: "5"}
+
+	out, err := DecodeStructuredOutputWithRepair[repairTarget](context.Background(), cfg, meta, repairSchema, "not json", 3, strings.TrimSpace, repair)
+	s.Require().NoError(err)
+	s.Equal("fixed", out.Name)
+	s.Len(prompts, 2)
+	s.Contains(prompts[0], "Output:\nnot json")
+	s.Contains(prompts[1], "/nome")
+	s.Equal("2", meta[MetadataKeyStructuredRepairs])
+	s.Equal("3", meta[MetadataKeyAPICalls])
+	s.Equal("25", meta[MetadataKeyInputTokens])
+	s.Equal(`{"name":"fixed"}`, meta[MetadataKeyRawOutput])
+}
+
+func (s *StructuredRepairSuite) TestReturnsDecodeErrorWhenRepairFails() {
+	repair := func(ctx context.Context, prompt string) (string, GenerationMetadata, error) {
+		return "", nil, errors.New("unavailable")
+	}
+	meta := GenerationMetadata{}
+
+	_, err := DecodeStructuredOutputWithRepair[repairTarget](context.Background(), GeneratorConfig{}, meta, repairSchema, `{}`, 2, strings.TrimSpace, repair)
+	s.Require().Error(err)
+	s.ErrorIs(err, ErrSchemaValidation)
+	s.Equal("1", meta[MetadataKeyStructuredRepairs])
+}
+
+func (s *StructuredRepairSuite) TestNoAttemptsSkipsRepair() {
+	repair := func(ctx context.Context, prompt string) (string, GenerationMetadata, error) {
+		s.Fail("repair should not be called")
+		return "", nil, nil
+	}
+	meta := GenerationMetadata{}
+
+	_, err := DecodeStructuredOutputWithRepair[repairTarget](context.Background(), GeneratorConfig{}, meta, repairSchema, `{}`, 0, strings.TrimSpace, repair)
+	s.Require().Error(err)
+	s.NotContains(meta, MetadataKeyStructuredRepairs)
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// TranscriptFormat is the output format of AudioTranscriptionGenerator (see
+// AudioOptions.OutputFormat).
+type TranscriptFormat string
+
+const (
+	// TranscriptFormatText is the plain transcript (the default).
+	TranscriptFormatText TranscriptFormat = "text"
+	// TranscriptFormatJSON is a TranscriptionResult encoded as JSON.
+	TranscriptFormatJSON TranscriptFormat = "json"
+	// TranscriptFormatSRT and TranscriptFormatVTT are SubRip and WebVTT
+	// subtitles with one cue per segment.
+	TranscriptFormatSRT TranscriptFormat = "srt"
+	TranscriptFormatVTT TranscriptFormat = "vtt"
+)
+
+// ResolveTranscriptFormat returns the normalized opts.OutputFormat,
+// defaulting to TranscriptFormatText, or an error for unknown formats.
+func ResolveTranscriptFormat(opts AudioOptions) (TranscriptFormat, error) {
+	format := TranscriptFormat(strings.ToLower(strings.TrimSpace(string(opts.OutputFormat))))
+	switch format {
+	case "":
+		return TranscriptFormatText, nil
+	case TranscriptFormatText, TranscriptFormatJSON, TranscriptFormatSRT, TranscriptFormatVTT:
+		return format, nil
+	default:
+		return "", utils.WrapIfNotNil(fmt.Errorf("unsupported transcript output format %q", opts.OutputFormat))
+	}
+}
+
+// FormatTranscript renders result in format. Subtitle formats need
+// segments; speakers are written as "Speaker: text" in SRT and as voice
+// tags in VTT.
+func FormatTranscript(result TranscriptionResult, format TranscriptFormat) (string, error) {
+	switch format {
+	case "", TranscriptFormatText:
+		if len(result.Speakers()) > 0 {
+			return FormatSpeakerTranscript(result.Segments), nil
+		}
+		return result.Text, nil
+	case TranscriptFormatJSON:
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return "", utils.WrapIfNotNil(err)
+		}
+		return string(encoded), nil
+	case TranscriptFormatSRT, TranscriptFormatVTT:
+		if len(result.Segments) == 0 {
+			return "", utils.WrapIfNotNil(errors.New("subtitle output needs transcript segments"))
+		}
+		if format == TranscriptFormatSRT {
+			return FormatSRT(result.Segments), nil
+		}
+		return FormatVTT(result.Segments), nil
+	default:
+		return "", utils.WrapIfNotNil(fmt.Errorf("unsupported transcript output format %q", format))
+	}
+}
+
+// FormatSRT renders segments as SubRip subtitles.
+func FormatSRT(segments []TranscriptSegment) string {
+	var out strings.Builder
+	cue := 0
+	for _, segment := range segments {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" {
+			continue
+		}
+		if segment.Speaker != "" {
+			text = segment.Speaker + ": " + text
+		}
+		cue++
+		fmt.Fprintf(&out, "%d\n%s --> %s\n%s\n\n", cue, subtitleTimestamp(segment.Start, ","), subtitleTimestamp(segment.End, ","), text)
+	}
+	return out.String()
+}
+
+// FormatVTT renders segments as WebVTT subtitles.
+func FormatVTT(segments []TranscriptSegment) string {
+	var out strings.Builder
+	out.WriteString("WEBVTT\n\n")
+	for _, segment := range segments {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" {
+			continue
+		}
+		if segment.Speaker != "" {
+			text = "<v " + segment.Speaker + ">" + text
+		}
+		fmt.Fprintf(&out, "%s --> %s\n%s\n\n", subtitleTimestamp(segment.Start, "."), subtitleTimestamp(segment.End, "."), text)
+	}
+	return out.String()
+}
+
+// subtitleTimestamp formats d as HH:MM:SS followed by separator and
+// milliseconds.
+func subtitleTimestamp(d time.Duration, separator string) string {
+	if d < 0 {
+		d = 0
+	}
+	milliseconds := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d",
+		milliseconds/3_600_000, milliseconds/60_000%60, milliseconds/1000%60, separator, milliseconds%1000)
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SubtitlesSuite struct {
+	suite.Suite
+}
+
+func TestSubtitlesSuite(t *testing.T) {
+	suite.Run(t, new(SubtitlesSuite))
+}
+
+func (s *SubtitlesSuite) segments() []TranscriptSegment {
+	return []TranscriptSegment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: " Swelling is better. ", Speaker: "A"},
+		{Start: 1500 * time.Millisecond, End: 2 * time.Second, Text: ""},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "Good."},
+	}
+}
+
+func (s *SubtitlesSuite) TestFormatSRT() {
+	s.Equal("1\n00:00:00,000 --> 00:00:01,500\nA: Swelling is better.\n\n"+
+		"2\n01:02:03,045 --> 01:02:05,000\nGood.\n\n", FormatSRT(s.segments()))
+}
+
+func (s *SubtitlesSuite) TestFormatVTT() {
+	s.Equal("WEBVTT\n\n00:00:00.000 --> 00:00:01.500\n<v A>Swelling is better.\n\n"+
+		"01:02:03.045 --> 01:02:05.000\nGood.\n\n", FormatVTT(s.segments()))
+}
+
+func (s *SubtitlesSuite) TestFormatTranscript() {
+	result := TranscriptionResult{Text: "Swelling is better.", Segments: []TranscriptSegment{{End: time.Second, Text: "Swelling is better."}}}
+
+	text, err := FormatTranscript(result, TranscriptFormatText)
+	s.Require().NoError(err)
+	s.Equal("Swelling is better.", text)
+
+	encoded, err := FormatTranscript(result, TranscriptFormatJSON)
+	s.Require().NoError(err)
+	s.JSONEq(`{"text":"Swelling is better.","segments":[{"id":0,"start":0,"end":1000000000,"text":"Swelling is better."}]}`, encoded)
+
+	_, err = FormatTranscript(TranscriptionResult{Text: "no timings"}, TranscriptFormatSRT)
+	s.Require().Error(err)
+}
+
+func (s *SubtitlesSuite) TestResolveTranscriptFormat() {
+	format, err := ResolveTranscriptFormat(AudioOptions{})
+	s.Require().NoError(err)
+	s.Equal(TranscriptFormatText, format)
+
+	format, err = ResolveTranscriptFormat(AudioOptions{OutputFormat: " VTT "})
+	s.Require().NoError(err)
+	s.Equal(TranscriptFormatVTT, format)
+
+	_, err = ResolveTranscriptFormat(AudioOptions{OutputFormat: "docx"})
+	s.Require().Error(err)
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"context"
+	"strings"
+)
+
+// MetadataKeyTenant records the tenant a generation was served for.
+const MetadataKeyTenant = "tenant"
+
+type tenantContextKey struct{}
+
+// ContextWithTenant returns a context scoped to tenant id.
+func ContextWithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, strings.TrimSpace(id))
+}
+
+// TenantFromContext returns the tenant set by ContextWithTenant, or "".
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(tenantContextKey{}).(string)
+	return id
+}
+
+// ResolveTenant returns cfg.Tenant when set, otherwise the context tenant.
+func ResolveTenant(ctx context.Context, cfg GeneratorConfig) string {
+	if id := strings.TrimSpace(cfg.Tenant); id != "" {
+		return id
+	}
+	return TenantFromContext(ctx)
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+)
+
+// WithTLSConfig sets the TLS configuration of the provider's HTTP client, for
+// self-hosted endpoints (Ollama, TGI, vLLM) behind a private CA or requiring
+// client certificates. The config is cloned.
+func WithTLSConfig(config *tls.Config) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		if config == nil {
+			cfg.TLSConfig = nil
+			return
+		}
+		cfg.TLSConfig = config.Clone()
+	})
+}
+
+// WithRootCAs trusts the certificates in pool instead of the system roots.
+func WithRootCAs(pool *x509.CertPool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.TLSConfig = cloneTLSConfig(cfg.TLSConfig)
+		cfg.TLSConfig.RootCAs = pool
+	})
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification. Use it only
+// for trusted self-hosted endpoints during development: every client built
+// with it logs a warning.
+func WithInsecureSkipVerify(skip bool) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.TLSConfig = cloneTLSConfig(cfg.TLSConfig)
+		cfg.TLSConfig.InsecureSkipVerify = skip
+	})
+}
+
+func cloneTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		return &tls.Config{}
+	}
+	return config.Clone()
+}
+
+// NewHTTPClient returns an HTTP client with timeout for provider requests.
+// Without cfg.TLSConfig or a unix:// cfg.URL it is a plain http.Client;
+// otherwise it uses the transport of NewHTTPTransport.
+func NewHTTPClient(cfg GeneratorConfig, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if transport := NewHTTPTransport(cfg); transport != nil {
+		client.Transport = transport
+	}
+	return client
+}
+
+// NewHTTPTransport returns a copy of http.DefaultTransport using
+// cfg.TLSConfig and, for a unix:// cfg.URL, dialing the socket (see
+// ParseUnixSocketURL). It returns nil when neither applies, and logs a
+// warning when certificate verification is disabled.
+func NewHTTPTransport(cfg GeneratorConfig) *http.Transport {
+	socketPath, _, err := ParseUnixSocketURL(cfg.URL)
+	if err != nil {
+		socketPath = ""
+	}
+	if cfg.TLSConfig == nil && socketPath == "" {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if socketPath != "" {
+		dialUnixSocket(transport, socketPath)
+	}
+	if cfg.TLSConfig == nil {
+		return transport
+	}
+	if cfg.TLSConfig.InsecureSkipVerify {
+		endpoint := strings.TrimSpace(cfg.URL)
+		if endpoint == "" {
+			endpoint = "the default endpoint"
+		}
+		logging.NewLogger(context.Background()).Warnf(
+			"TLS certificate verification is DISABLED for %s; responses can be intercepted. Use a custom CA (WithRootCAs) outside development.",
+			endpoint,
+		)
+	}
+	transport.TLSClientConfig = cfg.TLSConfig.Clone()
+	return transport
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TLSOptionsSuite struct {
+	suite.Suite
+}
+
+func TestTLSOptionsSuite(t *testing.T) {
+	suite.Run(t, new(TLSOptionsSuite))
+}
+
+func (s *TLSOptionsSuite) TestOptionsCloneAndCombine() {
+	base := &tls.Config{ServerName: "llm.internal"}
+	pool := x509.NewCertPool()
+
+	cfg := ResolveGeneratorOpts(WithTLSConfig(base), WithRootCAs(pool), WithInsecureSkipVerify(true))
+
+	s.Require().NotNil(cfg.TLSConfig)
+	s.Equal("llm.internal", cfg.TLSConfig.ServerName)
+	s.Same(pool, cfg.TLSConfig.RootCAs)
+	s.True(cfg.TLSConfig.InsecureSkipVerify)
+	s.False(base.InsecureSkipVerify, "the caller's config is not modified")
+	s.Nil(base.RootCAs)
+
+	s.Nil(ResolveGeneratorOpts(WithTLSConfig(base), WithTLSConfig(nil)).TLSConfig)
+}
+
+func (s *TLSOptionsSuite) TestNewHTTPClientTrustsConfiguredCA() {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(GeneratorConfig{}, time.Second)
+	s.Nil(client.Transport)
+	_, err := client.Get(server.URL)
+	s.Require().Error(err, "self-signed certificates are rejected by default")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client = NewHTTPClient(ResolveGeneratorOpts(WithRootCAs(pool)), time.Second)
+	response, err := client.Get(server.URL)
+	s.Require().NoError(err)
+	_ = response.Body.Close()
+	s.Equal(http.StatusNoContent, response.StatusCode)
+
+	client = NewHTTPClient(ResolveGeneratorOpts(WithURL(server.URL), WithInsecureSkipVerify(true)), time.Second)
+	response, err = client.Get(server.URL)
+	s.Require().NoError(err)
+	_ = response.Body.Close()
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+var (
+	// ErrToolTimeout is returned by Tool.Call when a handler exceeds its Timeout.
+	ErrToolTimeout = errors.New("tool call timed out")
+	// ErrMaxToolRoundsExceeded matches any *MaxToolRoundsError with errors.Is.
+	ErrMaxToolRoundsExceeded = errors.New("exceeded tool call loop limit")
+	// ErrToolErrorBudgetExceeded matches a *ToolCallError returned because
+	// more tool calls failed than WithToolErrorBudget allows.
+	ErrToolErrorBudgetExceeded = errors.New("tool error budget exceeded")
+)
+
+// MaxToolRoundsError is returned when the model keeps requesting tools after
+// Limit rounds (see WithMaxToolRounds).
+type MaxToolRoundsError struct {
+	Limit int
+}
+
+func (e *MaxToolRoundsError) Error() string {
+	return fmt.Sprintf("exceeded tool call loop limit (%d)", e.Limit)
+}
+
+// Is reports whether target is ErrMaxToolRoundsExceeded.
+func (e *MaxToolRoundsError) Is(target error) bool {
+	return target == ErrMaxToolRoundsExceeded
+}
+
+// ToolCallError is returned when a failed tool call aborts a generation,
+// either under ToolErrorModeFailFast or once the tool error budget is used up.
+type ToolCallError struct {
+	Tool           string
+	Err            error
+	BudgetExceeded bool
+}
+
+func (e *ToolCallError) Error() string {
+	if e.BudgetExceeded {
+		return fmt.Sprintf("tool %q failed and the tool error budget is used up: %v", e.Tool, e.Err)
+	}
+	return fmt.Sprintf("tool %q failed: %v", e.Tool, e.Err)
+}
+
+// Unwrap returns the handler error.
+func (e *ToolCallError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrToolErrorBudgetExceeded and the budget was
+// the reason for the failure.
+func (e *ToolCallError) Is(target error) bool {
+	return e.BudgetExceeded && target == ErrToolErrorBudgetExceeded
+}
+
+// Call invokes the tool handler, enforcing Timeout with a context deadline.
+// A handler that ignores its context is abandoned when the deadline passes so
+// it cannot stall the generation; its goroutine finishes in the background.
+func (t Tool) Call(ctx context.Context, args json.RawMessage) (any, error) {
+	if t.Handler == nil {
+		return nil, utils.WrapIfNotNil(fmt.Errorf("tool %q has no handler", t.Name))
+	}
+	if t.Timeout <= 0 {
+		return t.Handler(ctx, args)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	type callResult struct {
+		value any
+		err   error
+	}
+	done := make(chan callResult, 1)
+	go func() {
+		value, err := t.Handler(callCtx, args)
+		done <- callResult{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("tool %q after %s: %w", t.Name, t.Timeout, ErrToolTimeout))
+		}
+		return result.value, result.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return nil, utils.WrapIfNotNil(ctx.Err())
+		}
+		return nil, utils.WrapIfNotNil(fmt.Errorf("tool %q after %s: %w", t.Name, t.Timeout, ErrToolTimeout))
+	}
+}
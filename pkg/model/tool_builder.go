@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/schema"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// NewTool builds a Tool whose InputSchema is reflected from TArgs (with the
+// same jsonschema reflector the providers use for structured output, so
+// `json` and `jsonschema` struct tags apply) and whose Handler unmarshals the
+// model's arguments into TArgs before calling handler. TArgs must reflect to
+// a JSON object, typically a struct. Missing or null arguments decode to the
+// zero value.
+func NewTool[TArgs any](name string, description string, handler func(ctx context.Context, args TArgs) (any, error)) (Tool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Tool{}, utils.WrapIfNotNil(fmt.Errorf("tool name is required"))
+	}
+	if handler == nil {
+		return Tool{}, utils.WrapIfNotNil(fmt.Errorf("tool handler is required for %q", name))
+	}
+
+	schema, err := reflectToolSchema[TArgs]()
+	if err != nil {
+		return Tool{}, utils.WrapIfNotNil(fmt.Errorf("tool %q: %w", name, err))
+	}
+
+	return Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: schema,
+		Handler: func(ctx context.Context, raw json.RawMessage) (any, error) {
+			var args TArgs
+			trimmed := strings.TrimSpace(string(raw))
+			if trimmed != "" && trimmed != "null" {
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, utils.WrapIfNotNil(fmt.Errorf("invalid arguments for tool %q: %w", name, err))
+				}
+			}
+			return handler(ctx, args)
+		},
+	}, nil
+}
+
+func reflectToolSchema[TArgs any]() (JSONSchema, error) {
+	reflected, err := schema.Reflect[TArgs](schema.Options{})
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	if reflected["type"] != "object" {
+		var value TArgs
+		return nil, utils.WrapIfNotNil(fmt.Errorf("tool arguments must be a JSON object, got %T", value))
+	}
+
+	// Function declarations are embedded in provider requests, where
+	// document-level keys are rejected by some APIs (for example Gemini).
+	delete(reflected, "$schema")
+	delete(reflected, "$id")
+	return reflected, nil
+}
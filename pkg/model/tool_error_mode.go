@@ -0,0 +1,95 @@
+package model
+
+// ToolErrorMode selects what a generation does when a local tool handler
+// returns an error.
+type ToolErrorMode string
+
+const (
+	// ToolErrorModeReport sends the error back to the model as the tool
+	// result (see ToolErrorResult) so it can retry or answer without the
+	// tool. Once more calls fail than the tool error budget allows, the
+	// generation fails with a *ToolCallError matching ErrToolErrorBudgetExceeded.
+	ToolErrorModeReport ToolErrorMode = "report"
+	// ToolErrorModeFailFast aborts the generation with a *ToolCallError on the
+	// first failed call.
+	ToolErrorModeFailFast ToolErrorMode = "fail_fast"
+)
+
+// DefaultToolErrorBudget is the number of failed tool calls reported to the
+// model per generation when WithToolErrorBudget is not set.
+const DefaultToolErrorBudget = 3
+
+// WithToolErrorMode selects how tool handler errors are handled. The default
+// is ToolErrorModeReport.
+func WithToolErrorMode(mode ToolErrorMode) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ToolErrorMode = &mode
+	})
+}
+
+// WithToolErrorBudget sets how many failed tool calls ToolErrorModeReport
+// reports to the model in one generation before giving up. Values below 0 are
+// treated as 0, which fails on the first error like ToolErrorModeFailFast.
+func WithToolErrorBudget(value int) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		cfg.ToolErrorBudget = &value
+	})
+}
+
+// ResolveToolErrorMode returns the effective tool error mode for cfg.
+func ResolveToolErrorMode(cfg GeneratorConfig) ToolErrorMode {
+	if cfg.ToolErrorMode == nil || *cfg.ToolErrorMode != ToolErrorModeFailFast {
+		return ToolErrorModeReport
+	}
+	return ToolErrorModeFailFast
+}
+
+// ResolveToolErrorBudget returns the effective tool error budget for cfg.
+func ResolveToolErrorBudget(cfg GeneratorConfig) int {
+	if cfg.ToolErrorBudget == nil {
+		return DefaultToolErrorBudget
+	}
+	if *cfg.ToolErrorBudget < 0 {
+		return 0
+	}
+	return *cfg.ToolErrorBudget
+}
+
+// ToolErrorPolicy applies the tool error mode and budget for one generation.
+// Flows create one per Generate call and consult it while processing tool
+// results in call order; it is not safe for concurrent use.
+type ToolErrorPolicy struct {
+	mode     ToolErrorMode
+	budget   int
+	failures int
+}
+
+// NewToolErrorPolicy creates the policy for one generation from cfg.
+func NewToolErrorPolicy(cfg GeneratorConfig) *ToolErrorPolicy {
+	return &ToolErrorPolicy{
+		mode:   ResolveToolErrorMode(cfg),
+		budget: ResolveToolErrorBudget(cfg),
+	}
+}
+
+// Handle records a failed call of tool. It returns nil when the error should
+// be reported to the model (send ToolErrorResult(err) as the tool output), or
+// a *ToolCallError to abort the generation with.
+func (p *ToolErrorPolicy) Handle(tool string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if p.mode == ToolErrorModeFailFast {
+		return &ToolCallError{Tool: tool, Err: err}
+	}
+	p.failures++
+	if p.failures > p.budget {
+		return &ToolCallError{Tool: tool, Err: err, BudgetExceeded: true}
+	}
+	return nil
+}
+
+// ToolErrorResult is the tool output reported to the model for a failed call.
+func ToolErrorResult(err error) map[string]any {
+	return map[string]any{"error": err.Error()}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ToolErrorModeSuite struct {
+	suite.Suite
+}
+
+func TestToolErrorModeSuite(t *testing.T) {
+	suite.Run(t, new(ToolErrorModeSuite))
+}
+
+func (s *ToolErrorModeSuite) TestDefaults() {
+	cfg := ResolveGeneratorOpts()
+	s.Equal(ToolErrorModeReport, ResolveToolErrorMode(cfg))
+	s.Equal(DefaultToolErrorBudget, ResolveToolErrorBudget(cfg))
+
+	cfg = ResolveGeneratorOpts(WithToolErrorMode("unknown"), WithToolErrorBudget(-2))
+	s.Equal(ToolErrorModeReport, ResolveToolErrorMode(cfg))
+	s.Equal(0, ResolveToolErrorBudget(cfg))
+}
+
+func (s *ToolErrorModeSuite) TestReportModeSpendsBudget() {
+	errBoom := errors.New("boom")
+	policy := NewToolErrorPolicy(ResolveGeneratorOpts(WithToolErrorBudget(2)))
+
+	s.NoError(policy.Handle("lookup", nil))
+	s.NoError(policy.Handle("lookup", errBoom))
+	s.NoError(policy.Handle("lookup", errBoom))
+
+	err := policy.Handle("lookup", errBoom)
+	s.ErrorIs(err, ErrToolErrorBudgetExceeded)
+	s.ErrorIs(err, errBoom)
+	var callErr *ToolCallError
+	s.Require().ErrorAs(err, &callErr)
+	s.Equal("lookup", callErr.Tool)
+	s.Equal(`tool "lookup" failed and the tool error budget is used up: boom`, err.Error())
+
+	s.Equal(map[string]any{"error": "boom"}, ToolErrorResult(errBoom))
+}
+
+func (s *ToolErrorModeSuite) TestFailFast() {
+	errBoom := errors.New("boom")
+	policy := NewToolErrorPolicy(ResolveGeneratorOpts(WithToolErrorMode(ToolErrorModeFailFast)))
+
+	err := policy.Handle("lookup", errBoom)
+	s.ErrorIs(err, errBoom)
+	s.NotErrorIs(err, ErrToolErrorBudgetExceeded)
+	s.Equal(`tool "lookup" failed: boom`, err.Error())
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ToolInvocation describes one call of a local tool handler.
+type ToolInvocation struct {
+	Name      string
+	Arguments json.RawMessage
+}
+
+// ToolInterceptor observes or alters local tool calls for every provider.
+// Interceptors registered with WithToolInterceptor run in registration order
+// for BeforeCall and in reverse order for AfterCall and OnError, like nested
+// middleware. Remote MCP tools executed by the provider are not intercepted.
+type ToolInterceptor interface {
+	// BeforeCall runs before the handler and may rewrite call.Arguments.
+	// Returning handled=true skips the handler (and later interceptors) and
+	// uses result instead, which is how mocks are built. A non-nil error
+	// aborts the call (for example a rate limit). In both cases only the
+	// interceptors registered before this one see AfterCall/OnError.
+	BeforeCall(ctx context.Context, call *ToolInvocation) (result any, handled bool, err error)
+	// AfterCall runs after a successful call and returns the result to send
+	// back to the model; returning an error turns the call into a failure.
+	AfterCall(ctx context.Context, call ToolInvocation, result any) (any, error)
+	// OnError runs when the handler or an interceptor fails. It returns the
+	// error to report; returning nil recovers with a nil result.
+	OnError(ctx context.Context, call ToolInvocation, err error) error
+}
+
+// ToolInterceptorFuncs adapts plain functions to ToolInterceptor. Nil fields
+// are pass-through.
+type ToolInterceptorFuncs struct {
+	Before func(ctx context.Context, call *ToolInvocation) (any, bool, error)
+	After  func(ctx context.Context, call ToolInvocation, result any) (any, error)
+	Error  func(ctx context.Context, call ToolInvocation, err error) error
+}
+
+// BeforeCall implements ToolInterceptor.
+func (f ToolInterceptorFuncs) BeforeCall(ctx context.Context, call *ToolInvocation) (any, bool, error) {
+	if f.Before == nil {
+		return nil, false, nil
+	}
+	return f.Before(ctx, call)
+}
+
+// AfterCall implements ToolInterceptor.
+func (f ToolInterceptorFuncs) AfterCall(ctx context.Context, call ToolInvocation, result any) (any, error) {
+	if f.After == nil {
+		return result, nil
+	}
+	return f.After(ctx, call, result)
+}
+
+// OnError implements ToolInterceptor.
+func (f ToolInterceptorFuncs) OnError(ctx context.Context, call ToolInvocation, err error) error {
+	if f.Error == nil {
+		return err
+	}
+	return f.Error(ctx, call, err)
+}
+
+// WithToolInterceptor adds interceptors around every local tool call
+// (including MCP tools run through the local adapter). It can be passed
+// several times; interceptors accumulate.
+func WithToolInterceptor(interceptors ...ToolInterceptor) GeneratorOption {
+	return generatorOptionFunc(func(cfg *GeneratorConfig) {
+		for _, interceptor := range interceptors {
+			if interceptor != nil {
+				cfg.ToolInterceptors = append(cfg.ToolInterceptors, interceptor)
+			}
+		}
+	})
+}
+
+// InterceptTools returns copies of tools whose handlers run through
+// interceptors. The original handler is still invoked with Tool.Call, so its
+// Timeout applies to the handler alone and a timeout reaches OnError as
+// ErrToolTimeout. Providers call this once per generation when building
+// their handler maps.
+func InterceptTools(tools []Tool, interceptors []ToolInterceptor) []Tool {
+	if len(interceptors) == 0 || len(tools) == 0 {
+		return tools
+	}
+
+	out := make([]Tool, len(tools))
+	for i, tool := range tools {
+		out[i] = tool
+		if tool.Handler == nil {
+			continue
+		}
+		inner := tool
+		out[i].Timeout = 0
+		out[i].Handler = func(ctx context.Context, args json.RawMessage) (any, error) {
+			return callIntercepted(ctx, inner, interceptors, args)
+		}
+	}
+	return out
+}
+
+func callIntercepted(ctx context.Context, tool Tool, interceptors []ToolInterceptor, args json.RawMessage) (any, error) {
+	call := ToolInvocation{Name: tool.Name, Arguments: args}
+
+	// ran counts interceptors whose BeforeCall completed; only those see
+	// AfterCall/OnError, so each interceptor's hooks stay balanced.
+	ran := 0
+	var (
+		result  any
+		handled bool
+		err     error
+	)
+	for _, interceptor := range interceptors {
+		result, handled, err = interceptor.BeforeCall(ctx, &call)
+		if err != nil || handled {
+			break
+		}
+		ran++
+	}
+	if err == nil && !handled {
+		result, err = tool.Call(ctx, call.Arguments)
+	}
+
+	for i := ran - 1; i >= 0; i-- {
+		if err != nil {
+			err = interceptors[i].OnError(ctx, call, err)
+			if err == nil {
+				result = nil
+			}
+			continue
+		}
+		result, err = interceptors[i].AfterCall(ctx, call, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ToolInterceptorSuite struct {
+	suite.Suite
+}
+
+func TestToolInterceptorSuite(t *testing.T) {
+	suite.Run(t, new(ToolInterceptorSuite))
+}
+
+func (s *ToolInterceptorSuite) recorder(name string, events *[]string) ToolInterceptor {
+	return ToolInterceptorFuncs{
+		Before: func(ctx context.Context, call *ToolInvocation) (any, bool, error) {
+			*events = append(*events, name+":before:"+call.Name)
+			return nil, false, nil
+		},
+		After: func(ctx context.Context, call ToolInvocation, result any) (any, error) {
+			*events = append(*events, name+":after")
+			return result, nil
+		},
+		Error: func(ctx context.Context, call ToolInvocation, err error) error {
+			*events = append(*events, name+":error")
+			return err
+		},
+	}
+}
+
+func (s *ToolInterceptorSuite) TestRunsAsNestedMiddleware() {
+	var events []string
+	tool := Tool{Name: "echo", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		events = append(events, "handler:"+string(args))
+		return "ok", nil
+	}}
+	rewrite := ToolInterceptorFuncs{Before: func(ctx context.Context, call *ToolInvocation) (any, bool, error) {
+		call.Arguments = json.RawMessage(`{"rewritten":true}`)
+		return nil, false, nil
+	}}
+	cfg := ResolveGeneratorOpts(WithToolInterceptor(s.recorder("a", &events)), WithToolInterceptor(rewrite, s.recorder("b", &events)))
+
+	tools := InterceptTools([]Tool{tool}, cfg.ToolInterceptors)
+	out, err := tools[0].Call(context.Background(), json.RawMessage(`{}`))
+	s.Require().NoError(err)
+	s.Equal("ok", out)
+	s.Equal([]string{"a:before:echo", "b:before:echo", `handler:{"rewritten":true}`, "b:after", "a:after"}, events)
+}
+
+func (s *ToolInterceptorSuite) TestShortCircuitMocksHandler() {
+	var events []string
+	called := false
+	tool := Tool{Name: "lookup", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		called = true
+		return nil, nil
+	}}
+	mock := ToolInterceptorFuncs{Before: func(ctx context.Context, call *ToolInvocation) (any, bool, error) {
+		return "mocked", true, nil
+	}}
+
+	tools := InterceptTools([]Tool{tool}, []ToolInterceptor{s.recorder("outer", &events), mock, s.recorder("inner", &events)})
+	out, err := tools[0].Call(context.Background(), nil)
+	s.Require().NoError(err)
+	s.Equal("mocked", out)
+	s.False(called)
+	s.Equal([]string{"outer:before:lookup", "outer:after"}, events)
+}
+
+func (s *ToolInterceptorSuite) TestErrorsReachOnError() {
+	var events []string
+	errLimited := errors.New("limited")
+	limiter := ToolInterceptorFuncs{Before: func(ctx context.Context, call *ToolInvocation) (any, bool, error) {
+		return nil, false, errLimited
+	}}
+	tool := Tool{Name: "slow", Timeout: 10 * time.Millisecond, Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	limited := InterceptTools([]Tool{tool}, []ToolInterceptor{s.recorder("log", &events), limiter})
+	_, err := limited[0].Call(context.Background(), nil)
+	s.ErrorIs(err, errLimited)
+	s.Equal([]string{"log:before:slow", "log:error"}, events)
+
+	events = nil
+	timed := InterceptTools([]Tool{tool}, []ToolInterceptor{s.recorder("log", &events)})
+	_, err = timed[0].Call(context.Background(), nil)
+	s.ErrorIs(err, ErrToolTimeout)
+	s.Equal([]string{"log:before:slow", "log:error"}, events)
+
+	recovering := ToolInterceptorFuncs{Error: func(ctx context.Context, call ToolInvocation, err error) error {
+		return nil
+	}}
+	recovered := InterceptTools([]Tool{tool}, []ToolInterceptor{recovering})
+	out, err := recovered[0].Call(context.Background(), nil)
+	s.NoError(err)
+	s.Nil(out)
+}
+
+func (s *ToolInterceptorSuite) TestNoInterceptorsReturnsToolsUnchanged() {
+	tools := []Tool{{Name: "a"}}
+	s.Equal(tools, InterceptTools(tools, nil))
+	s.Nil(InterceptTools([]Tool{{Name: "nil"}}, []ToolInterceptor{ToolInterceptorFuncs{}})[0].Handler)
+}
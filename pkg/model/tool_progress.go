@@ -0,0 +1,39 @@
+package model
+
+import "context"
+
+// ToolProgress is a progress report from a long-running tool call, such as
+// an MCP notifications/progress message.
+type ToolProgress struct {
+	// Tool is the name of the tool being called.
+	Tool string
+	// Progress increases as the call advances, in units the tool chooses.
+	Progress float64
+	// Total is the Progress value at completion; zero means unknown.
+	Total float64
+	// Message is an optional human-readable status.
+	Message string
+}
+
+// ToolProgressFunc receives progress reports. It runs on the goroutine that
+// delivers the report, so it should return quickly.
+type ToolProgressFunc func(ctx context.Context, progress ToolProgress)
+
+type toolProgressContextKey struct{}
+
+// ContextWithToolProgress returns a context whose tool calls report progress
+// to fn. Pass it to Generate; tools that support progress (MCP tools run
+// through the local adapter) forward their reports while they run.
+func ContextWithToolProgress(ctx context.Context, fn ToolProgressFunc) context.Context {
+	return context.WithValue(ctx, toolProgressContextKey{}, fn)
+}
+
+// ToolProgressFromContext returns the function set by
+// ContextWithToolProgress, or nil.
+func ToolProgressFromContext(ctx context.Context) ToolProgressFunc {
+	if ctx == nil {
+		return nil
+	}
+	fn, _ := ctx.Value(toolProgressContextKey{}).(ToolProgressFunc)
+	return fn
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ToolSuite struct {
+	suite.Suite
+}
+
+func TestToolSuite(t *testing.T) {
+	suite.Run(t, new(ToolSuite))
+}
+
+func (s *ToolSuite) TestCallWithoutTimeout() {
+	tool := Tool{Name: "echo", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		_, hasDeadline := ctx.Deadline()
+		return map[string]any{"args": string(args), "deadline": hasDeadline}, nil
+	}}
+
+	out, err := tool.Call(context.Background(), json.RawMessage(`{}`))
+	s.Require().NoError(err)
+	s.Equal(map[string]any{"args": "{}", "deadline": false}, out)
+}
+
+func (s *ToolSuite) TestCallAbandonsHangingHandler() {
+	release := make(chan struct{})
+	defer close(release)
+	tool := Tool{
+		Name:    "hang",
+		Timeout: 10 * time.Millisecond,
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			<-release
+			return "late", nil
+		},
+	}
+
+	start := time.Now()
+	_, err := tool.Call(context.Background(), nil)
+	s.ErrorIs(err, ErrToolTimeout)
+	s.Contains(err.Error(), `"hang"`)
+	s.Less(time.Since(start), time.Second)
+}
+
+func (s *ToolSuite) TestCallReportsDeadlineFromCooperativeHandler() {
+	tool := Tool{
+		Name:    "slow",
+		Timeout: 10 * time.Millisecond,
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	_, err := tool.Call(context.Background(), nil)
+	s.ErrorIs(err, ErrToolTimeout)
+}
+
+func (s *ToolSuite) TestCallPropagatesParentCancellation() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tool := Tool{
+		Name:    "cancelled",
+		Timeout: time.Minute,
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	_, err := tool.Call(ctx, nil)
+	s.True(errors.Is(err, context.Canceled))
+	s.False(errors.Is(err, ErrToolTimeout))
+}
+
+func (s *ToolSuite) TestResolveToolParallelism() {
+	s.Equal(DefaultToolParallelism, ResolveToolParallelism(ResolveGeneratorOpts()))
+	s.Equal(8, ResolveToolParallelism(ResolveGeneratorOpts(WithToolParallelism(8))))
+	s.Equal(1, ResolveToolParallelism(ResolveGeneratorOpts(WithToolParallelism(0))))
+	s.Equal(1, ResolveToolParallelism(ResolveGeneratorOpts(WithToolParallelism(8), WithParallelToolCalls(false))))
+	s.Equal(8, ResolveToolParallelism(ResolveGeneratorOpts(WithToolParallelism(8), WithParallelToolCalls(true))))
+}
+
+func (s *ToolSuite) TestMaxToolRounds() {
+	s.Equal(DefaultMaxToolRounds, ResolveMaxToolRounds(ResolveGeneratorOpts()))
+	s.Equal(3, ResolveMaxToolRounds(ResolveGeneratorOpts(WithMaxToolRounds(3))))
+	s.Equal(1, ResolveMaxToolRounds(ResolveGeneratorOpts(WithMaxToolRounds(-2))))
+
+	err := fmt.Errorf("wrapped: %w", &MaxToolRoundsError{Limit: 3})
+	s.ErrorIs(err, ErrMaxToolRoundsExceeded)
+	var roundsErr *MaxToolRoundsError
+	s.Require().ErrorAs(err, &roundsErr)
+	s.Equal(3, roundsErr.Limit)
+	s.Equal("exceeded tool call loop limit (3)", roundsErr.Error())
+}
+
+type lookupArgs struct {
+	PatientID string   `json:"patient_id" jsonschema:"description=Patient identifier"`
+	Labs      []string `json:"labs,omitempty"`
+}
+
+func (s *ToolSuite) TestNewToolReflectsSchemaAndDecodesArgs() {
+	tool, err := NewTool("lookup_labs", "Look up labs", func(ctx context.Context, args lookupArgs) (any, error) {
+		return fmt.Sprintf("%s:%d", args.PatientID, len(args.Labs)), nil
+	})
+	s.Require().NoError(err)
+
+	s.Equal("lookup_labs", tool.Name)
+	s.Equal("object", tool.InputSchema["type"])
+	s.Equal(false, tool.InputSchema["additionalProperties"])
+	s.Equal([]any{"patient_id"}, tool.InputSchema["required"])
+	s.NotContains(tool.InputSchema, "$schema")
+	properties, ok := tool.InputSchema["properties"].(map[string]any)
+	s.Require().True(ok)
+	s.Equal("Patient identifier", properties["patient_id"].(map[string]any)["description"])
+
+	out, err := tool.Call(context.Background(), json.RawMessage(`{"patient_id":"p1","labs":["egfr","k"]}`))
+	s.Require().NoError(err)
+	s.Equal("p1:2", out)
+
+	out, err = tool.Call(context.Background(), nil)
+	s.Require().NoError(err)
+	s.Equal(":0", out)
+
+	_, err = tool.Call(context.Background(), json.RawMessage(`{"patient_id":7}`))
+	s.Require().Error(err)
+	s.Contains(err.Error(), `invalid arguments for tool "lookup_labs"`)
+}
+
+func (s *ToolSuite) TestNewToolValidation() {
+	handler := func(ctx context.Context, args lookupArgs) (any, error) { return nil, nil }
+
+	_, err := NewTool(" ", "d", handler)
+	s.Error(err)
+	_, err = NewTool[lookupArgs]("t", "d", nil)
+	s.Error(err)
+	_, err = NewTool("t", "d", func(ctx context.Context, args string) (any, error) { return nil, nil })
+	s.Error(err)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// ToolCallRecord is one local tool call of a generation. Round counts tool
+// rounds from 1 and Index is the call's position within its round. CallID is
+// the provider's call ID when it has one. Error is the handler error, also
+// when the error was reported to the model instead of failing the generation.
+type ToolCallRecord struct {
+	Round      int    `json:"round"`
+	Index      int    `json:"index"`
+	Name       string `json:"name"`
+	CallID     string `json:"call_id,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SetToolCalls stores records in meta under MetadataKeyToolCalls as a JSON
+// array. Nothing is stored when records is empty.
+func SetToolCalls(meta GenerationMetadata, records []ToolCallRecord) {
+	if meta == nil || len(records) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(records)
+	if err != nil {
+		return
+	}
+	meta[MetadataKeyToolCalls] = string(encoded)
+}
+
+// ParseToolCalls decodes MetadataKeyToolCalls from meta. It returns nil when
+// the key is absent.
+func ParseToolCalls(meta GenerationMetadata) ([]ToolCallRecord, error) {
+	raw, ok := meta[MetadataKeyToolCalls]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var records []ToolCallRecord
+	if err := json.Unmarshal([]byte(raw), &records); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return records, nil
+}
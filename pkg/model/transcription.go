@@ -0,0 +1,149 @@
+package model
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+)
+
+// NewVerboseTranscriptionGeneratorFunc creates a generator that returns a
+// timed transcript for a source file.
+type NewVerboseTranscriptionGeneratorFunc func(filePath string, opts AudioOptions) (VerboseTranscriptionGenerator, error)
+
+// VerboseTranscriptionGenerator represents "audio file in, timed transcript
+// out". It takes the same AudioOptions as AudioTranscriptionGenerator.
+type VerboseTranscriptionGenerator interface {
+	Generate(ctx context.Context) (TranscriptionResult, GenerationMetadata, error)
+}
+
+// TimestampGranularity selects the timings of a TranscriptionResult.
+type TimestampGranularity string
+
+const (
+	TimestampGranularitySegment TimestampGranularity = "segment"
+	TimestampGranularityWord    TimestampGranularity = "word"
+)
+
+// TranscriptionResult is a transcript with its segments and, when requested
+// with TimestampGranularityWord, word timings.
+type TranscriptionResult struct {
+	Text string `json:"text"`
+	// Language is the ISO 639-1 code reported in MetadataKeyLanguage.
+	Language string              `json:"language,omitempty"`
+	Duration time.Duration       `json:"duration,omitempty"`
+	Segments []TranscriptSegment `json:"segments,omitempty"`
+	Words    []TranscriptWord    `json:"words,omitempty"`
+}
+
+// TranscriptSegment is a span of speech, usually a sentence or phrase.
+type TranscriptSegment struct {
+	ID    int           `json:"id"`
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"`
+	// Speaker labels the speaker when AudioOptions.Diarize is set. Labels
+	// are provider-assigned ("A", "Speaker 1") and only consistent within
+	// one transcript.
+	Speaker string `json:"speaker,omitempty"`
+	// Confidence is between 0 and 1, or 0 when the provider does not report
+	// it (OpenAI derives it from the segment's average log probability).
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// TranscriptWord is the timing of one word.
+type TranscriptWord struct {
+	Word  string        `json:"word"`
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+}
+
+// Speakers returns the distinct segment speakers in order of first
+// appearance.
+func (r TranscriptionResult) Speakers() []string {
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, segment := range r.Segments {
+		if segment.Speaker != "" && !seen[segment.Speaker] {
+			seen[segment.Speaker] = true
+			speakers = append(speakers, segment.Speaker)
+		}
+	}
+	return speakers
+}
+
+// FormatSpeakerTranscript renders diarized segments as one "Speaker: text"
+// line per turn, joining consecutive segments of the same speaker.
+// Segments without a speaker continue the current turn.
+func FormatSpeakerTranscript(segments []TranscriptSegment) string {
+	var lines []string
+	current := ""
+	for _, segment := range segments {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" {
+			continue
+		}
+		if len(lines) > 0 && (segment.Speaker == "" || segment.Speaker == current) {
+			lines[len(lines)-1] += " " + text
+			continue
+		}
+		current = segment.Speaker
+		if current == "" {
+			lines = append(lines, text)
+			continue
+		}
+		lines = append(lines, current+": "+text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WantsTimestamps reports whether opts.TimestampGranularities includes
+// granularity. Segments are always returned, so an empty list means
+// segment timings only.
+func (o AudioOptions) WantsTimestamps(granularity TimestampGranularity) bool {
+	if granularity == TimestampGranularitySegment {
+		return true
+	}
+	for _, candidate := range o.TimestampGranularities {
+		if TimestampGranularity(strings.ToLower(strings.TrimSpace(string(candidate)))) == granularity {
+			return true
+		}
+	}
+	return false
+}
+
+// SecondsToDuration converts the fractional seconds providers report.
+func SecondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
+
+// FinishTranscriptionResult applies the transcript post-processing of
+// AudioTranscriptionGenerator to a verbose result: it records the language
+// (see SetTranscriptLanguage) and, with opts.RedactPII, scrubs the text and
+// segments and drops the words, which cannot be scrubbed one at a time.
+func FinishTranscriptionResult(meta GenerationMetadata, opts AudioOptions, result TranscriptionResult) TranscriptionResult {
+	result.Text = strings.TrimSpace(result.Text)
+	SetTranscriptLanguage(meta, opts, result.Text)
+	if meta != nil {
+		result.Language = meta[MetadataKeyLanguage]
+	}
+	if result.Duration == 0 && len(result.Segments) > 0 {
+		result.Duration = result.Segments[len(result.Segments)-1].End
+	}
+	if !opts.RedactPII {
+		return result
+	}
+
+	result.Text = RedactTranscript(meta, opts, result.Text)
+	segments := make([]TranscriptSegment, len(result.Segments))
+	for i, segment := range result.Segments {
+		segment.Text, _ = RedactPII(segment.Text)
+		segments[i] = segment
+	}
+	result.Segments = segments
+	result.Words = nil
+	return result
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TranscriptionResultSuite struct {
+	suite.Suite
+}
+
+func TestTranscriptionResultSuite(t *testing.T) {
+	suite.Run(t, new(TranscriptionResultSuite))
+}
+
+func (s *TranscriptionResultSuite) TestFormatSpeakerTranscriptJoinsTurns() {
+	segments := []TranscriptSegment{
+		{Speaker: "A", Text: " How is the swelling? "},
+		{Speaker: "B", Text: "Better."},
+		{Speaker: "B", Text: "Mostly in the evening."},
+		{Text: "(cough)"},
+		{Speaker: "A", Text: ""},
+		{Speaker: "A", Text: "Good."},
+	}
+
+	s.Equal("A: How is the swelling?\nB: Better. Mostly in the evening. (cough)\nA: Good.", FormatSpeakerTranscript(segments))
+	s.Equal([]string{"A", "B"}, TranscriptionResult{Segments: segments}.Speakers())
+	s.Equal("plain", FormatSpeakerTranscript([]TranscriptSegment{{Text: "plain"}}))
+}
+
+func (s *TranscriptionResultSuite) TestFinishTranscriptionResultRedactsAndFillsDuration() {
+	meta := GenerationMetadata{}
+	result := FinishTranscriptionResult(meta, AudioOptions{Language: "en", RedactPII: true}, TranscriptionResult{
+		Text:     " Call 555-123-4567 ",
+		Segments: []TranscriptSegment{{End: SecondsToDuration(2.5), Text: "Call 555-123-4567", Speaker: "A"}},
+		Words:    []TranscriptWord{{Word: "Call"}},
+	})
+
+	s.Equal("Call [PHONE]", result.Text)
+	s.Equal("Call [PHONE]", result.Segments[0].Text)
+	s.Equal("A", result.Segments[0].Speaker)
+	s.Nil(result.Words)
+	s.Equal("en", result.Language)
+	s.Equal(SecondsToDuration(2.5), result.Duration)
+	s.Equal("PHONE:1", meta[MetadataKeyRedactedEntities])
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// unixSocketHost is the placeholder host of requests sent over a unix
+// socket; the dialer ignores it.
+const unixSocketHost = "http://localhost"
+
+// ParseUnixSocketURL splits a unix:// base URL such as
+// "unix:///var/run/ollama.sock" into the socket path and the HTTP base URL
+// requests are built against. An optional path query sets the HTTP path
+// prefix, for example "unix:///run/gateway.sock?path=/v1" for an
+// OpenAI-compatible gateway. Other URLs return an empty socket path and are
+// returned unchanged.
+func ParseUnixSocketURL(rawURL string) (socketPath string, baseURL string, err error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.HasPrefix(strings.ToLower(rawURL), "unix://") {
+		return "", rawURL, nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", utils.WrapIfNotNil(err)
+	}
+	socketPath = parsed.Host + parsed.Path
+	if socketPath == "" {
+		return "", "", utils.WrapIfNotNil(errors.New("unix socket URL " + rawURL + " has no socket path"))
+	}
+
+	baseURL = unixSocketHost
+	if prefix := strings.Trim(parsed.Query().Get("path"), "/"); prefix != "" {
+		baseURL += "/" + prefix
+	}
+	return socketPath, baseURL, nil
+}
+
+// dialUnixSocket makes transport connect every request to socketPath.
+func dialUnixSocket(transport *http.Transport, socketPath string) {
+	dialer := &net.Dialer{}
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+}
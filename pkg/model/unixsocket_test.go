@@ -0,0 +1,73 @@
+package model
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type UnixSocketSuite struct {
+	suite.Suite
+}
+
+func TestUnixSocketSuite(t *testing.T) {
+	suite.Run(t, new(UnixSocketSuite))
+}
+
+func (s *UnixSocketSuite) TestParseUnixSocketURL() {
+	socketPath, baseURL, err := ParseUnixSocketURL(" unix:///var/run/ollama.sock ")
+	s.Require().NoError(err)
+	s.Equal("/var/run/ollama.sock", socketPath)
+	s.Equal("http://localhost", baseURL)
+
+	socketPath, baseURL, err = ParseUnixSocketURL("unix:///run/gateway.sock?path=/v1/")
+	s.Require().NoError(err)
+	s.Equal("/run/gateway.sock", socketPath)
+	s.Equal("http://localhost/v1", baseURL)
+
+	socketPath, baseURL, err = ParseUnixSocketURL("http://localhost:11434")
+	s.Require().NoError(err)
+	s.Empty(socketPath)
+	s.Equal("http://localhost:11434", baseURL)
+
+	_, _, err = ParseUnixSocketURL("unix://")
+	s.Require().Error(err)
+}
+
+func (s *UnixSocketSuite) TestNewHTTPClientDialsSocket() {
+	// Socket paths are limited to about 100 bytes, so avoid T.TempDir.
+	dir, err := os.MkdirTemp("", "sock")
+	s.Require().NoError(err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	socketPath := filepath.Join(dir, "llm.sock")
+	listener, err := net.Listen("unix", socketPath)
+	s.Require().NoError(err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	s.Nil(NewHTTPTransport(GeneratorConfig{URL: "http://localhost"}))
+
+	cfg := GeneratorConfig{URL: "unix://" + socketPath + "?path=/v1"}
+	_, baseURL, err := ParseUnixSocketURL(cfg.URL)
+	s.Require().NoError(err)
+	response, err := NewHTTPClient(cfg, 0).Get(baseURL + "/models")
+	s.Require().NoError(err)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := io.ReadAll(response.Body)
+	s.Require().NoError(err)
+	s.Equal("/v1/models", string(body))
+}
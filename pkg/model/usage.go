@@ -0,0 +1,80 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// RoundUsage is the token usage of one API call in a generation. The first
+// entry is the initial request and each later entry is a tool round. Fields
+// follow the totals: OutputTokens includes ReasoningTokens and InputTokens
+// includes CachedInputTokens.
+type RoundUsage struct {
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	TotalTokens       int64 `json:"total_tokens"`
+	CachedInputTokens int64 `json:"cached_input_tokens,omitempty"`
+	ReasoningTokens   int64 `json:"reasoning_tokens,omitempty"`
+}
+
+// SetRoundUsage stores rounds in meta under MetadataKeyRoundUsage as a JSON
+// array. Nothing is stored when rounds is empty.
+func SetRoundUsage(meta GenerationMetadata, rounds []RoundUsage) {
+	if meta == nil || len(rounds) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(rounds)
+	if err != nil {
+		return
+	}
+	meta[MetadataKeyRoundUsage] = string(encoded)
+}
+
+// ParseRoundUsage decodes MetadataKeyRoundUsage from meta. It returns nil
+// when the key is absent.
+func ParseRoundUsage(meta GenerationMetadata) ([]RoundUsage, error) {
+	raw, ok := meta[MetadataKeyRoundUsage]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var rounds []RoundUsage
+	if err := json.Unmarshal([]byte(raw), &rounds); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return rounds, nil
+}
+
+// usageMetadataKeys are the counters MergeUsageMetadata sums.
+var usageMetadataKeys = []string{
+	MetadataKeyInputTokens,
+	MetadataKeyOutputTokens,
+	MetadataKeyTotalTokens,
+	MetadataKeyCachedInputTokens,
+	MetadataKeyReasoningTokens,
+	MetadataKeyAPICalls,
+}
+
+// MergeUsageMetadata adds the token and API call counters of meta to total
+// and copies its provider and model, for callers that combine several
+// generations into one result.
+func MergeUsageMetadata(total GenerationMetadata, meta GenerationMetadata) {
+	if total == nil || meta == nil {
+		return
+	}
+	for _, key := range []string{MetadataKeyProvider, MetadataKeyModel} {
+		if value := strings.TrimSpace(meta[key]); value != "" {
+			total[key] = value
+		}
+	}
+	for _, key := range usageMetadataKeys {
+		value, err := strconv.ParseInt(strings.TrimSpace(meta[key]), 10, 64)
+		if err != nil {
+			continue
+		}
+		current, _ := strconv.ParseInt(total[key], 10, 64)
+		total[key] = strconv.FormatInt(current+value, 10)
+	}
+}
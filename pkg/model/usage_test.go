@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RoundUsageSuite struct {
+	suite.Suite
+}
+
+func TestRoundUsageSuite(t *testing.T) {
+	suite.Run(t, new(RoundUsageSuite))
+}
+
+func (s *RoundUsageSuite) TestRoundTrip() {
+	meta := GenerationMetadata{}
+	SetRoundUsage(meta, nil)
+	s.NotContains(meta, MetadataKeyRoundUsage)
+
+	rounds, err := ParseRoundUsage(meta)
+	s.NoError(err)
+	s.Nil(rounds)
+
+	want := []RoundUsage{
+		{InputTokens: 10, OutputTokens: 4, TotalTokens: 14, ReasoningTokens: 2},
+		{InputTokens: 20, OutputTokens: 1, TotalTokens: 21, CachedInputTokens: 8},
+	}
+	SetRoundUsage(meta, want)
+	s.JSONEq(`[{"input_tokens":10,"output_tokens":4,"total_tokens":14,"reasoning_tokens":2},{"input_tokens":20,"output_tokens":1,"total_tokens":21,"cached_input_tokens":8}]`, meta[MetadataKeyRoundUsage])
+
+	rounds, err = ParseRoundUsage(meta)
+	s.NoError(err)
+	s.Equal(want, rounds)
+
+	meta[MetadataKeyRoundUsage] = "{"
+	_, err = ParseRoundUsage(meta)
+	s.Error(err)
+}
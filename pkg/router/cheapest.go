@@ -0,0 +1,174 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Capabilities describes what a route's model supports, for
+// StrategyCheapest.
+type Capabilities struct {
+	Tools            bool
+	Vision           bool
+	StructuredOutput bool
+	// ContextWindow is the most input plus output tokens the model accepts;
+	// zero means unknown.
+	ContextWindow int
+}
+
+// Requirements is what a request needs from a route. Generate derives it
+// from the request: Tools from WithTools or WithMCPTools, StructuredOutput
+// from WithOutputSchema, Vision from images supplied by prompt context
+// providers (such as model.AddImagePromptContext), and ContextTokens from the
+// estimated input, provided contexts included, plus WithMaxTokens.
+// ContextWithRequirements adds requirements the request does not show.
+type Requirements struct {
+	Tools            bool
+	Vision           bool
+	StructuredOutput bool
+	ContextTokens    int
+}
+
+type requirementsContextKey struct{}
+
+// ContextWithRequirements returns a context whose generations also require
+// req, on top of what Generate derives from the request.
+func ContextWithRequirements(ctx context.Context, req Requirements) context.Context {
+	return context.WithValue(ctx, requirementsContextKey{}, req)
+}
+
+// RequirementsFromContext returns the requirements set by
+// ContextWithRequirements.
+func RequirementsFromContext(ctx context.Context) Requirements {
+	if ctx == nil {
+		return Requirements{}
+	}
+	req, _ := ctx.Value(requirementsContextKey{}).(Requirements)
+	return req
+}
+
+// missing names the first requirement caps does not meet, or returns "".
+func (req Requirements) missing(caps Capabilities) string {
+	switch {
+	case req.Tools && !caps.Tools:
+		return "tools"
+	case req.Vision && !caps.Vision:
+		return "vision"
+	case req.StructuredOutput && !caps.StructuredOutput:
+		return "structured output"
+	case caps.ContextWindow > 0 && req.ContextTokens > caps.ContextWindow:
+		return "context size"
+	}
+	return ""
+}
+
+// costSelection is the StrategyCheapest decision for one generation.
+type costSelection struct {
+	cheapest  string
+	incapable map[string]bool
+	estimates map[string]model.CostEstimate
+}
+
+func (s *costSelection) estimate(name string) (model.CostEstimate, bool) {
+	if s == nil {
+		return model.CostEstimate{}, false
+	}
+	estimate, ok := s.estimates[name]
+	return estimate, ok
+}
+
+// resolveProvidedContexts runs every context provider once.
+func resolveProvidedContexts(ctx context.Context, providers []model.PromptContextProvider) ([]*model.PromptContext, error) {
+	var contexts []*model.PromptContext
+	for _, provider := range providers {
+		provided, err := provider.GenerateContext(ctx)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		contexts = append(contexts, provided...)
+	}
+	return contexts, nil
+}
+
+// selectCheapest estimates the request with contexts on every route and picks
+// the cheapest priced route that meets the request's requirements.
+func (g *generator) selectCheapest(ctx context.Context, cfg *Config, contexts []*model.PromptContext) *costSelection {
+	log := logging.NewLogger(ctx)
+	selection := &costSelection{
+		incapable: map[string]bool{},
+		estimates: map[string]model.CostEstimate{},
+	}
+
+	for _, route := range g.router.routes {
+		routeCfg := model.ResolveGeneratorOpts(g.routeOptions(route, cfg.ModelPins[route.Name])...)
+		estimate, err := model.EstimateCost(route.Provider, g.prompt, contexts, routeCfg)
+
+		req := requestRequirements(ctx, routeCfg, contexts, estimate.InputTokens)
+		if missing := req.missing(routeCapabilities(route, routeCfg)); missing != "" {
+			log.Debugf("route %q lacks %s; skipping", route.Name, missing)
+			selection.incapable[route.Name] = true
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, model.ErrPricingUnknown) {
+				log.Debugf("route %q has no cost estimate: %v", route.Name, err)
+			}
+			continue
+		}
+
+		log.Debugf("route %q estimated_cost=%g model=%q", route.Name, estimate.TotalCost, estimate.Model)
+		selection.estimates[route.Name] = estimate
+		if current, ok := selection.estimates[selection.cheapest]; !ok || estimate.TotalCost < current.TotalCost {
+			selection.cheapest = route.Name
+		}
+	}
+	return selection
+}
+
+// requestRequirements derives what a request needs from its resolved options
+// and prompt contexts.
+func requestRequirements(ctx context.Context, cfg model.GeneratorConfig, contexts []*model.PromptContext, inputTokens int) Requirements {
+	req := RequirementsFromContext(ctx)
+	req.Tools = req.Tools || len(cfg.Tools) > 0 || len(cfg.MCPTools) > 0
+	req.StructuredOutput = req.StructuredOutput || cfg.OutputSchema != nil
+	for _, promptContext := range contexts {
+		if promptContext != nil && len(promptContext.Images) > 0 {
+			req.Vision = true
+		}
+	}
+	tokens := inputTokens
+	if cfg.MaxTokens != nil && *cfg.MaxTokens > 0 {
+		tokens += *cfg.MaxTokens
+	}
+	req.ContextTokens = max(req.ContextTokens, tokens)
+	return req
+}
+
+// routeCapabilities returns the declared capabilities of route, narrowed by
+// the model capability registry. Undeclared routes are assumed capable.
+func routeCapabilities(route Route, cfg model.GeneratorConfig) Capabilities {
+	caps := Capabilities{Tools: true, Vision: true, StructuredOutput: true}
+	if route.Capabilities != nil {
+		caps = *route.Capabilities
+	}
+	if cfg.Model != nil && route.Provider != "" && !model.SupportsToolCalling(route.Provider, strings.TrimSpace(*cfg.Model)) {
+		caps.Tools = false
+	}
+	return caps
+}
+
+// routeOptions returns the options a route's generator is built with.
+func (g *generator) routeOptions(route Route, pinnedModel string) []model.GeneratorOption {
+	opts := make([]model.GeneratorOption, 0, len(route.Options)+len(g.opts)+1)
+	opts = append(opts, route.Options...)
+	opts = append(opts, g.opts...)
+	if strings.TrimSpace(pinnedModel) != "" {
+		opts = append(opts, model.WithModel(pinnedModel))
+	}
+	return opts
+}
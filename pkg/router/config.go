@@ -0,0 +1,150 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// Config is the traffic policy applied by a Router. It can be replaced at
+// runtime with Router.SetConfig or Router.Watch; in-flight generations keep
+// the snapshot they started with.
+//
+// Field semantics:
+//   - Weights: relative share of first attempts per route name. Routes with a
+//     zero or missing weight are only used as fallbacks. When no weights are
+//     set, Fallback order (then registration order) decides the first attempt.
+//   - ModelPins: forces a model per route name, applied after the caller's options.
+//   - Fallback: order in which remaining routes are tried after the first attempt fails.
+//   - Budgets: per-route request/token limits; exhausted routes are skipped.
+//   - Strategy: how the first attempt is chosen; empty means StrategyWeighted.
+type Config struct {
+	Weights   map[string]float64 `json:"weights,omitempty"`
+	ModelPins map[string]string  `json:"model_pins,omitempty"`
+	Fallback  []string           `json:"fallback,omitempty"`
+	Budgets   map[string]Budget  `json:"budgets,omitempty"`
+	Strategy  Strategy           `json:"strategy,omitempty"`
+}
+
+// Strategy selects the route of the first attempt.
+type Strategy string
+
+const (
+	// StrategyWeighted picks by Weights (the default).
+	StrategyWeighted Strategy = "weighted"
+	// StrategyLeastLatency picks the route with the lowest smoothed latency,
+	// inflated by its error rate (see Router.Stats). Unmeasured routes are
+	// tried first. Weights are ignored.
+	StrategyLeastLatency Strategy = "least_latency"
+	// StrategyCheapest picks the route whose model has the lowest
+	// model.EstimateCost for the request among routes that meet its
+	// Requirements; routes known to lack them are not tried at all. Routes
+	// without pricing are only used as fallbacks. Weights are ignored.
+	StrategyCheapest Strategy = "cheapest"
+)
+
+// Budget limits how much a route may be used per Window. A zero limit is
+// unlimited; a zero Window means the limit applies for the router's lifetime.
+type Budget struct {
+	MaxRequests int64    `json:"max_requests,omitempty"`
+	MaxTokens   int64    `json:"max_tokens,omitempty"`
+	Window      Duration `json:"window,omitempty"`
+}
+
+// Duration is a time.Duration that encodes to JSON as a string such as "1m30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	out, err := json.Marshal(time.Duration(d).String())
+	return out, utils.WrapIfNotNil(err)
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var nanos int64
+		if numErr := json.Unmarshal(data, &nanos); numErr != nil {
+			return utils.WrapIfNotNil(err)
+		}
+		*d = Duration(nanos)
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ParseConfig decodes a JSON Config.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, utils.WrapIfNotNil(err)
+	}
+	return cfg, nil
+}
+
+// validate checks that cfg only references known routes and uses sane values.
+func (c Config) validate(known map[string]struct{}) error {
+	for name, weight := range c.Weights {
+		if _, ok := known[name]; !ok {
+			return utils.WrapIfNotNil(fmt.Errorf("weight set for unknown route %q", name))
+		}
+		if weight < 0 {
+			return utils.WrapIfNotNil(fmt.Errorf("weight for route %q must not be negative", name))
+		}
+	}
+	for name := range c.ModelPins {
+		if _, ok := known[name]; !ok {
+			return utils.WrapIfNotNil(fmt.Errorf("model pin set for unknown route %q", name))
+		}
+	}
+	for _, name := range c.Fallback {
+		if _, ok := known[name]; !ok {
+			return utils.WrapIfNotNil(fmt.Errorf("fallback references unknown route %q", name))
+		}
+	}
+	switch c.Strategy {
+	case "", StrategyWeighted, StrategyLeastLatency, StrategyCheapest:
+	default:
+		return utils.WrapIfNotNil(fmt.Errorf("unknown strategy %q", c.Strategy))
+	}
+	for name, budget := range c.Budgets {
+		if _, ok := known[name]; !ok {
+			return utils.WrapIfNotNil(fmt.Errorf("budget set for unknown route %q", name))
+		}
+		if budget.MaxRequests < 0 || budget.MaxTokens < 0 || budget.Window < 0 {
+			return utils.WrapIfNotNil(fmt.Errorf("budget for route %q must not be negative", name))
+		}
+	}
+	return nil
+}
+
+// clone deep-copies c so a stored snapshot cannot be mutated by the caller.
+func (c Config) clone() Config {
+	out := Config{Fallback: append([]string(nil), c.Fallback...), Strategy: c.Strategy}
+	if c.Weights != nil {
+		out.Weights = make(map[string]float64, len(c.Weights))
+		for k, v := range c.Weights {
+			out.Weights[k] = v
+		}
+	}
+	if c.ModelPins != nil {
+		out.ModelPins = make(map[string]string, len(c.ModelPins))
+		for k, v := range c.ModelPins {
+			out.ModelPins[k] = v
+		}
+	}
+	if c.Budgets != nil {
+		out.Budgets = make(map[string]Budget, len(c.Budgets))
+		for k, v := range c.Budgets {
+			out.Budgets[k] = v
+		}
+	}
+	return out
+}
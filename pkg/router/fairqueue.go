@@ -0,0 +1,164 @@
+package router
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+// MetadataKeyQueueWait is how long the generation waited for a route slot,
+// in milliseconds. It is only set when fair queueing is enabled.
+const MetadataKeyQueueWait = "router_queue_wait_ms"
+
+// WithFairQueue limits every route to maxConcurrent in-flight generations.
+// Callers beyond the limit wait in per-tenant queues (see
+// model.ResolveTenant) that are served round-robin, so a burst from one
+// tenant sharing a provider key cannot starve the others. Requests without a
+// tenant share one queue. Zero or negative disables queueing.
+func WithFairQueue(maxConcurrent int) Option {
+	return func(r *Router) {
+		r.maxConcurrent = maxConcurrent
+	}
+}
+
+// QueueStats is a point-in-time view of one route's fair queue.
+type QueueStats struct {
+	InFlight int
+	Queued   int
+	// QueuedByTenant counts waiting generations per tenant; the empty key is
+	// requests without a tenant.
+	QueuedByTenant map[string]int
+	// MaxQueued is the deepest the queue has been since the router started.
+	MaxQueued int
+}
+
+// QueueStats returns the queue depth of every route, or nil when fair
+// queueing is disabled.
+func (r *Router) QueueStats() map[string]QueueStats {
+	if r.queues == nil {
+		return nil
+	}
+	stats := make(map[string]QueueStats, len(r.queues))
+	for name, queue := range r.queues {
+		stats[name] = queue.stats()
+	}
+	return stats
+}
+
+// fairQueue hands route slots to waiting tenants in round-robin order.
+type fairQueue struct {
+	mu        sync.Mutex
+	limit     int
+	inFlight  int
+	queued    int
+	maxQueued int
+	waiting   map[string][]chan struct{}
+	// order lists tenants with waiters; the head is served next and moves to
+	// the back while it still has waiters.
+	order []string
+}
+
+func newFairQueue(limit int) *fairQueue {
+	return &fairQueue{limit: limit, waiting: map[string][]chan struct{}{}}
+}
+
+// acquire blocks until tenant may start a generation or ctx is done. Every
+// successful acquire must be paired with release.
+func (q *fairQueue) acquire(ctx context.Context, tenant string) error {
+	q.mu.Lock()
+	if q.inFlight < q.limit && len(q.order) == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	if len(q.waiting[tenant]) == 0 {
+		q.order = append(q.order, tenant)
+	}
+	q.waiting[tenant] = append(q.waiting[tenant], ready)
+	q.queued++
+	if q.queued > q.maxQueued {
+		q.maxQueued = q.queued
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		removed := q.remove(tenant, ready)
+		q.mu.Unlock()
+		if !removed {
+			// The slot was handed over while ctx was being cancelled.
+			q.release()
+		}
+		return utils.WrapIfNotNil(ctx.Err())
+	}
+}
+
+// release frees a slot, passing it straight to the next waiting tenant.
+func (q *fairQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		q.inFlight--
+		return
+	}
+	tenant := q.order[0]
+	q.order = q.order[1:]
+	waiters := q.waiting[tenant]
+	next := waiters[0]
+	if len(waiters) > 1 {
+		q.waiting[tenant] = waiters[1:]
+		q.order = append(q.order, tenant)
+	} else {
+		delete(q.waiting, tenant)
+	}
+	q.queued--
+	close(next)
+}
+
+// remove drops a cancelled waiter. It reports false when the waiter was
+// already served. Callers must hold mu.
+func (q *fairQueue) remove(tenant string, ready chan struct{}) bool {
+	waiters := q.waiting[tenant]
+	for i, candidate := range waiters {
+		if candidate != ready {
+			continue
+		}
+		waiters = append(waiters[:i:i], waiters[i+1:]...)
+		q.queued--
+		if len(waiters) > 0 {
+			q.waiting[tenant] = waiters
+			return true
+		}
+		delete(q.waiting, tenant)
+		for j, name := range q.order {
+			if name == tenant {
+				q.order = append(q.order[:j:j], q.order[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (q *fairQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := QueueStats{
+		InFlight:       q.inFlight,
+		Queued:         q.queued,
+		QueuedByTenant: make(map[string]int, len(q.waiting)),
+		MaxQueued:      q.maxQueued,
+	}
+	for tenant, waiters := range q.waiting {
+		stats.QueuedByTenant[tenant] = len(waiters)
+	}
+	return stats
+}
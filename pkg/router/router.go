@@ -0,0 +1,445 @@
+// Package router spreads text generation across several provider routes with
+// weights, per-route model pins, budgets and ordered fallback, optionally
+// queueing callers fairly per tenant (WithFairQueue). The policy is a
+// Config that can be swapped atomically at runtime, for example from a
+// ConfigWatcher, without restarting the service.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const (
+	// MetadataKeyRoute names the route that produced the response.
+	MetadataKeyRoute = "router_route"
+	// MetadataKeyAttempts counts the routes tried, including the successful one.
+	MetadataKeyAttempts = "router_attempts"
+	// MetadataKeyEstimatedCost is the model.EstimateCost total, in US
+	// dollars, of the route that produced the response under StrategyCheapest.
+	MetadataKeyEstimatedCost = "router_estimated_cost"
+)
+
+// ErrNoRouteAvailable is returned when every route is over budget or, under
+// StrategyCheapest, lacks what the request requires.
+var ErrNoRouteAvailable = errors.New("no route available")
+
+// Route is a named provider target. Options are applied before the caller's
+// options; a ModelPins entry in Config overrides both. Provider and
+// Capabilities are only used by StrategyCheapest.
+type Route struct {
+	Name    string
+	Factory model.NewStringContentGeneratorFunc
+	Options []model.GeneratorOption
+	// Provider is the provider name used to look up model pricing and
+	// capabilities, such as "openai".
+	Provider string
+	// Capabilities describes the route's model; nil means unknown, in which
+	// case the route is assumed capable.
+	Capabilities *Capabilities
+}
+
+// Option configures a Router.
+type Option func(*Router)
+
+// WithRandom overrides the source of weighted selection, which must return
+// values in [0, 1). Intended for deterministic tests.
+func WithRandom(random func() float64) Option {
+	return func(r *Router) {
+		if random != nil {
+			r.random = random
+		}
+	}
+}
+
+// WithClock overrides the clock used for budget windows and latency stats.
+func WithClock(now func() time.Time) Option {
+	return func(r *Router) {
+		if now != nil {
+			r.now = now
+		}
+	}
+}
+
+// Router selects routes per generation according to its current Config.
+type Router struct {
+	routes []Route
+	index  map[string]Route
+	known  map[string]struct{}
+	config atomic.Pointer[Config]
+
+	random func() float64
+	now    func() time.Time
+
+	budgetMu sync.Mutex
+	usage    map[string]*routeUsage
+
+	maxConcurrent int
+	// queues holds one fair queue per route when WithFairQueue is set.
+	queues map[string]*fairQueue
+
+	smoothing  float64
+	statsMu    sync.Mutex
+	modelStats map[statsKey]*smoothedStats
+	routeStats map[string]*smoothedStats
+	lastModel  map[string]string
+}
+
+type routeUsage struct {
+	windowStart time.Time
+	requests    int64
+	tokens      int64
+}
+
+// New creates a Router over routes with an initial cfg.
+func New(routes []Route, cfg Config, opts ...Option) (*Router, error) {
+	if len(routes) == 0 {
+		return nil, utils.WrapIfNotNil(errors.New("at least one route is required"))
+	}
+
+	r := &Router{
+		index:  make(map[string]Route, len(routes)),
+		known:  make(map[string]struct{}, len(routes)),
+		random: rand.Float64,
+		now:    time.Now,
+		usage:  map[string]*routeUsage{},
+
+		smoothing:  DefaultStatsSmoothing,
+		modelStats: map[statsKey]*smoothedStats{},
+		routeStats: map[string]*smoothedStats{},
+		lastModel:  map[string]string{},
+	}
+	for _, route := range routes {
+		name := strings.TrimSpace(route.Name)
+		if name == "" {
+			return nil, utils.WrapIfNotNil(errors.New("route name is required"))
+		}
+		if route.Factory == nil {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("route %q has no factory", name))
+		}
+		if _, exists := r.index[name]; exists {
+			return nil, utils.WrapIfNotNil(fmt.Errorf("duplicate route %q", name))
+		}
+		route.Name = name
+		route.Provider = strings.TrimSpace(route.Provider)
+		route.Options = append([]model.GeneratorOption(nil), route.Options...)
+		r.routes = append(r.routes, route)
+		r.index[name] = route
+		r.known[name] = struct{}{}
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	if r.maxConcurrent > 0 {
+		r.queues = make(map[string]*fairQueue, len(r.routes))
+		for _, route := range r.routes {
+			r.queues[route.Name] = newFairQueue(r.maxConcurrent)
+		}
+	}
+
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	return r, nil
+}
+
+// SetConfig validates cfg and atomically replaces the current policy. An
+// invalid cfg is rejected and the previous policy stays in effect.
+func (r *Router) SetConfig(cfg Config) error {
+	if err := cfg.validate(r.known); err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	snapshot := cfg.clone()
+	r.config.Store(&snapshot)
+	return nil
+}
+
+// Config returns a copy of the current policy.
+func (r *Router) Config() Config {
+	return r.config.Load().clone()
+}
+
+// NewStringContentGenerator matches model.NewStringContentGeneratorFunc, so a
+// Router can be used anywhere a provider constructor is expected.
+func (r *Router) NewStringContentGenerator(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+	if strings.TrimSpace(prompt) == "" {
+		return nil, utils.WrapIfNotNil(errors.New("prompt is required"))
+	}
+	return &generator{
+		router: r,
+		prompt: prompt,
+		opts:   append([]model.GeneratorOption(nil), opts...),
+	}, nil
+}
+
+// plan returns the routes to try, in order, for one generation. selection is
+// only set under StrategyCheapest.
+func (r *Router) plan(cfg *Config, selection *costSelection) []Route {
+	order := make([]Route, 0, len(r.routes))
+	seen := make(map[string]struct{}, len(r.routes))
+	add := func(name string) {
+		if _, done := seen[name]; done {
+			return
+		}
+		if selection != nil && selection.incapable[name] {
+			return
+		}
+		seen[name] = struct{}{}
+		order = append(order, r.index[name])
+	}
+
+	var first string
+	var ok bool
+	switch cfg.Strategy {
+	case StrategyLeastLatency:
+		first, ok = r.pickLeastLatency()
+	case StrategyCheapest:
+		first, ok = selection.cheapest, selection != nil && selection.cheapest != ""
+	default:
+		first, ok = r.pickWeighted(cfg.Weights)
+	}
+	if ok {
+		add(first)
+	}
+	for _, name := range cfg.Fallback {
+		add(name)
+	}
+	for _, route := range r.routes {
+		add(route.Name)
+	}
+	return order
+}
+
+func (r *Router) pickWeighted(weights map[string]float64) (string, bool) {
+	total := 0.0
+	for _, route := range r.routes {
+		total += weights[route.Name]
+	}
+	if total <= 0 {
+		return "", false
+	}
+
+	target := r.random() * total
+	for _, route := range r.routes {
+		weight := weights[route.Name]
+		if weight <= 0 {
+			continue
+		}
+		if target < weight {
+			return route.Name, true
+		}
+		target -= weight
+	}
+	// Floating point rounding can leave target just above the last bucket.
+	for i := len(r.routes) - 1; i >= 0; i-- {
+		if weights[r.routes[i].Name] > 0 {
+			return r.routes[i].Name, true
+		}
+	}
+	return "", false
+}
+
+// reserve records a request against the route budget, or reports that the
+// route is exhausted.
+func (r *Router) reserve(name string, budget Budget, limited bool) bool {
+	if !limited {
+		return true
+	}
+
+	r.budgetMu.Lock()
+	defer r.budgetMu.Unlock()
+
+	usage := r.currentUsage(name, budget)
+	if budget.MaxRequests > 0 && usage.requests >= budget.MaxRequests {
+		return false
+	}
+	if budget.MaxTokens > 0 && usage.tokens >= budget.MaxTokens {
+		return false
+	}
+	usage.requests++
+	return true
+}
+
+func (r *Router) recordTokens(name string, budget Budget, limited bool, meta model.GenerationMetadata) {
+	if !limited || meta == nil {
+		return
+	}
+	tokens := meta.Usage().TotalTokens
+	if tokens <= 0 {
+		return
+	}
+
+	r.budgetMu.Lock()
+	defer r.budgetMu.Unlock()
+	r.currentUsage(name, budget).tokens += tokens
+}
+
+// currentUsage returns the usage counters for name, starting a new window when
+// the previous one has elapsed. Callers must hold budgetMu.
+func (r *Router) currentUsage(name string, budget Budget) *routeUsage {
+	now := r.now()
+	usage, ok := r.usage[name]
+	if !ok {
+		usage = &routeUsage{windowStart: now}
+		r.usage[name] = usage
+	}
+	if budget.Window > 0 && now.Sub(usage.windowStart) >= time.Duration(budget.Window) {
+		*usage = routeUsage{windowStart: now}
+	}
+	return usage
+}
+
+type generator struct {
+	router *Router
+	prompt string
+	opts   []model.GeneratorOption
+
+	promptContextMu        sync.RWMutex
+	promptContexts         []*model.PromptContext
+	promptContextProviders []model.PromptContextProvider
+}
+
+func (g *generator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.promptContextMu.Lock()
+	defer g.promptContextMu.Unlock()
+	g.promptContexts = append(g.promptContexts, &model.PromptContext{MessageType: messageType, Content: content})
+}
+
+func (g *generator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+	if provider == nil {
+		return
+	}
+	g.promptContextMu.Lock()
+	defer g.promptContextMu.Unlock()
+	g.promptContextProviders = append(g.promptContextProviders, provider)
+}
+
+// Generate tries routes in plan order until one succeeds. The Config snapshot
+// is taken once, so a concurrent SetConfig never splits a single request
+// across two policies.
+func (g *generator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	log := logging.NewLogger(ctx)
+	cfg := g.router.config.Load()
+
+	tenant := ""
+	if g.router.queues != nil {
+		tenant = model.ResolveTenant(ctx, model.ResolveGeneratorOpts(g.opts...))
+	}
+
+	g.promptContextMu.RLock()
+	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
+	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
+	g.promptContextMu.RUnlock()
+
+	var selection *costSelection
+	if cfg.Strategy == StrategyCheapest {
+		// Provided contexts can carry images and add tokens, so they are
+		// resolved before choosing and handed to every route as resolved.
+		provided, err := resolveProvidedContexts(ctx, providers)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			return "", nil, utils.WrapIfNotNil(err)
+		}
+		providers = nil
+		if len(provided) > 0 {
+			providers = []model.PromptContextProvider{model.StaticPromptContextProvider(provided...)}
+		}
+		selection = g.selectCheapest(ctx, cfg, slices.Concat(contexts, provided))
+	}
+
+	var errs []error
+	attempts := 0
+	for _, route := range g.router.plan(cfg, selection) {
+		queue := g.router.queues[route.Name]
+		waitStart := time.Now()
+		if queue != nil {
+			if err := queue.acquire(ctx, tenant); err != nil {
+				log.Errorf("error: %v", err)
+				return "", nil, utils.WrapIfNotNil(err)
+			}
+		}
+		queueWait := time.Since(waitStart)
+
+		budget, limited := cfg.Budgets[route.Name]
+		if !g.router.reserve(route.Name, budget, limited) {
+			if queue != nil {
+				queue.release()
+			}
+			log.Debugf("route %q is over budget; skipping", route.Name)
+			continue
+		}
+
+		attempts++
+		attemptStart := g.router.now()
+		text, meta, err := g.generateWith(ctx, route, cfg.ModelPins[route.Name], contexts, providers)
+		if queue != nil {
+			queue.release()
+		}
+		g.router.recordOutcome(route.Name, cfg.ModelPins[route.Name], meta, g.router.now().Sub(attemptStart), err != nil)
+		g.router.recordTokens(route.Name, budget, limited, meta)
+		if err != nil {
+			log.Warnf("route %q failed: %v", route.Name, err)
+			errs = append(errs, fmt.Errorf("route %q: %w", route.Name, err))
+			continue
+		}
+
+		if meta == nil {
+			meta = model.GenerationMetadata{}
+		}
+		meta[MetadataKeyRoute] = route.Name
+		meta[MetadataKeyAttempts] = strconv.Itoa(attempts)
+		if queue != nil {
+			meta[MetadataKeyQueueWait] = strconv.FormatInt(queueWait.Milliseconds(), 10)
+		}
+		if estimate, ok := selection.estimate(route.Name); ok {
+			meta[MetadataKeyEstimatedCost] = strconv.FormatFloat(estimate.TotalCost, 'f', -1, 64)
+		}
+		return text, meta, nil
+	}
+
+	if attempts == 0 {
+		err := ErrNoRouteAvailable
+		log.Errorf("error: %v", err)
+		return "", nil, utils.WrapIfNotNil(err)
+	}
+	err := errors.Join(errs...)
+	log.Errorf("error: %v", err)
+	return "", model.GenerationMetadata{MetadataKeyAttempts: strconv.Itoa(attempts)}, utils.WrapIfNotNil(err)
+}
+
+func (g *generator) generateWith(
+	ctx context.Context,
+	route Route,
+	pinnedModel string,
+	contexts []*model.PromptContext,
+	providers []model.PromptContextProvider,
+) (string, model.GenerationMetadata, error) {
+	gen, err := route.Factory(g.prompt, g.routeOptions(route, pinnedModel)...)
+	if err != nil {
+		return "", nil, utils.WrapIfNotNil(err)
+	}
+
+	for _, promptContext := range contexts {
+		gen.AddPromptContext(ctx, promptContext.MessageType, promptContext.Content)
+	}
+	for _, provider := range providers {
+		gen.AddPromptContextProvider(ctx, provider)
+	}
+
+	text, meta, err := gen.Generate(ctx)
+	return text, meta, utils.WrapIfNotNil(err)
+}
@@ -0,0 +1,559 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type RouterSuite struct {
+	suite.Suite
+}
+
+func TestRouterSuite(t *testing.T) {
+	suite.Run(t, new(RouterSuite))
+}
+
+type fakeGenerator struct {
+	name      string
+	model     string
+	err       error
+	contexts  []*model.PromptContext
+	providers []model.PromptContextProvider
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	if g.err != nil {
+		return "", nil, g.err
+	}
+	return g.name + ":" + g.model, model.GenerationMetadata{
+		model.MetadataKeyProvider:    g.name,
+		model.MetadataKeyTotalTokens: "10",
+	}, nil
+}
+
+func (g *fakeGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.contexts = append(g.contexts, &model.PromptContext{MessageType: messageType, Content: content})
+}
+
+func (g *fakeGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+	g.providers = append(g.providers, provider)
+}
+
+// countingContextProvider supplies contexts and counts its calls.
+type countingContextProvider struct {
+	contexts []*model.PromptContext
+	calls    int
+}
+
+func (p *countingContextProvider) GenerateContext(ctx context.Context) ([]*model.PromptContext, error) {
+	p.calls++
+	return p.contexts, nil
+}
+
+type fakeProvider struct {
+	mu    sync.Mutex
+	name  string
+	err   error
+	built []*fakeGenerator
+}
+
+func (p *fakeProvider) factory(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+	cfg := model.ResolveGeneratorOpts(opts...)
+	gen := &fakeGenerator{name: p.name, err: p.err}
+	if cfg.Model != nil {
+		gen.model = *cfg.Model
+	}
+	p.mu.Lock()
+	p.built = append(p.built, gen)
+	p.mu.Unlock()
+	return gen, nil
+}
+
+func (p *fakeProvider) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.built)
+}
+
+func (s *RouterSuite) newRouter(cfg Config, random float64, providers ...*fakeProvider) *Router {
+	routes := make([]Route, 0, len(providers))
+	for _, provider := range providers {
+		routes = append(routes, Route{Name: provider.name, Factory: provider.factory, Options: []model.GeneratorOption{model.WithModel(provider.name + "-default")}})
+	}
+	r, err := New(routes, cfg, WithRandom(func() float64 { return random }))
+	s.Require().NoError(err)
+	return r
+}
+
+func (s *RouterSuite) generate(r *Router) (string, model.GenerationMetadata, error) {
+	gen, err := r.NewStringContentGenerator("hello")
+	s.Require().NoError(err)
+	return gen.Generate(context.Background())
+}
+
+func (s *RouterSuite) TestWeightedSelectionAndModelPins() {
+	a, b := &fakeProvider{name: "a"}, &fakeProvider{name: "b"}
+	cfg := Config{
+		Weights:   map[string]float64{"a": 1, "b": 3},
+		ModelPins: map[string]string{"b": "b-pinned"},
+	}
+
+	text, meta, err := s.generate(s.newRouter(cfg, 0.1, a, b))
+	s.Require().NoError(err)
+	s.Equal("a:a-default", text)
+	s.Equal("a", meta[MetadataKeyRoute])
+
+	text, _, err = s.generate(s.newRouter(cfg, 0.5, a, b))
+	s.Require().NoError(err)
+	s.Equal("b:b-pinned", text)
+}
+
+func (s *RouterSuite) TestFallbackOrderOnFailure() {
+	a := &fakeProvider{name: "a", err: errors.New("down")}
+	b := &fakeProvider{name: "b", err: errors.New("down")}
+	c := &fakeProvider{name: "c"}
+	r := s.newRouter(Config{Fallback: []string{"b", "c", "a"}}, 0, a, b, c)
+
+	gen, err := r.NewStringContentGenerator("hello")
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "be brief")
+
+	text, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("c:c-default", text)
+	s.Equal("2", meta[MetadataKeyAttempts])
+	s.Equal(0, a.calls())
+	s.Require().Len(c.built, 1)
+	s.Len(c.built[0].contexts, 1)
+
+	a.err, c.err = errors.New("down"), errors.New("down")
+	_, meta, err = s.generate(r)
+	s.Error(err)
+	s.Equal("3", meta[MetadataKeyAttempts])
+}
+
+func (s *RouterSuite) TestBudgetsSkipExhaustedRoutes() {
+	now := time.Unix(0, 0)
+	a, b := &fakeProvider{name: "a"}, &fakeProvider{name: "b"}
+	r, err := New(
+		[]Route{{Name: "a", Factory: a.factory}, {Name: "b", Factory: b.factory}},
+		Config{
+			Fallback: []string{"a", "b"},
+			Budgets: map[string]Budget{
+				"a": {MaxRequests: 1, Window: Duration(time.Minute)},
+				"b": {MaxTokens: 10},
+			},
+		},
+		WithClock(func() time.Time { return now }),
+	)
+	s.Require().NoError(err)
+
+	_, meta, err := s.generate(r)
+	s.Require().NoError(err)
+	s.Equal("a", meta[MetadataKeyRoute])
+
+	_, meta, err = s.generate(r)
+	s.Require().NoError(err)
+	s.Equal("b", meta[MetadataKeyRoute])
+
+	_, _, err = s.generate(r)
+	s.ErrorIs(err, ErrNoRouteAvailable)
+
+	now = now.Add(time.Minute)
+	_, meta, err = s.generate(r)
+	s.Require().NoError(err)
+	s.Equal("a", meta[MetadataKeyRoute])
+}
+
+func (s *RouterSuite) TestSetConfigRejectsInvalidPolicy() {
+	r := s.newRouter(Config{Weights: map[string]float64{"a": 1}}, 0, &fakeProvider{name: "a"})
+
+	s.Error(r.SetConfig(Config{Weights: map[string]float64{"missing": 1}}))
+	s.Error(r.SetConfig(Config{Weights: map[string]float64{"a": -1}}))
+	s.Error(r.SetConfig(Config{Fallback: []string{"missing"}}))
+	s.Equal(1.0, r.Config().Weights["a"])
+
+	_, err := New([]Route{{Name: "a"}}, Config{})
+	s.Error(err)
+}
+
+type channelWatcher struct {
+	updates chan Config
+}
+
+func (w *channelWatcher) Watch(ctx context.Context) (<-chan Config, error) {
+	return w.updates, nil
+}
+
+func (s *RouterSuite) TestWatchSwapsConfig() {
+	a, b := &fakeProvider{name: "a"}, &fakeProvider{name: "b"}
+	r := s.newRouter(Config{Fallback: []string{"a"}}, 0, a, b)
+
+	watcher := &channelWatcher{updates: make(chan Config)}
+	done := make(chan error, 1)
+	go func() { done <- r.Watch(context.Background(), watcher) }()
+
+	watcher.updates <- Config{Fallback: []string{"missing"}}
+	watcher.updates <- Config{Fallback: []string{"b"}}
+	close(watcher.updates)
+	s.Require().NoError(<-done)
+
+	_, meta, err := s.generate(r)
+	s.Require().NoError(err)
+	s.Equal("b", meta[MetadataKeyRoute])
+}
+
+func (s *RouterSuite) TestFileWatcherEmitsOnChange() {
+	path := filepath.Join(s.T().TempDir(), "router.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{"weights":{"a":1},"budgets":{"a":{"max_requests":5,"window":"1m"}}}`), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := NewFileWatcher(path, 5*time.Millisecond).Watch(ctx)
+	s.Require().NoError(err)
+
+	first := <-updates
+	s.Equal(1.0, first.Weights["a"])
+	s.Equal(Duration(time.Minute), first.Budgets["a"].Window)
+
+	s.Require().NoError(os.WriteFile(path, []byte(`{"weights":{"a":2}}`), 0o600))
+	select {
+	case second := <-updates:
+		s.Equal(2.0, second.Weights["a"])
+	case <-time.After(2 * time.Second):
+		s.Fail("file change was not detected")
+	}
+}
+
+type gatedGenerator struct {
+	served chan<- string
+	gate   <-chan struct{}
+}
+
+func (g *gatedGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	g.served <- model.TenantFromContext(ctx)
+	<-g.gate
+	return "ok", model.GenerationMetadata{}, nil
+}
+
+func (g *gatedGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+}
+
+func (g *gatedGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+func (s *RouterSuite) TestFairQueueServesTenantsRoundRobin() {
+	served := make(chan string)
+	gate := make(chan struct{})
+	factory := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		return &gatedGenerator{served: served, gate: gate}, nil
+	}
+	r, err := New([]Route{{Name: "shared", Factory: factory}}, Config{}, WithFairQueue(1))
+	s.Require().NoError(err)
+
+	var wg sync.WaitGroup
+	metas := make(chan model.GenerationMetadata, 4)
+	start := func(tenant string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gen, err := r.NewStringContentGenerator("hello")
+			if !s.NoError(err) {
+				return
+			}
+			_, meta, err := gen.Generate(model.ContextWithTenant(context.Background(), tenant))
+			s.NoError(err)
+			metas <- meta
+		}()
+	}
+	queued := func(n int) {
+		s.Eventually(func() bool { return r.QueueStats()["shared"].Queued == n }, time.Second, time.Millisecond)
+	}
+
+	start("a")
+	s.Equal("a", <-served)
+	start("a")
+	queued(1)
+	start("a")
+	queued(2)
+	start("b")
+	queued(3)
+
+	stats := r.QueueStats()["shared"]
+	s.Equal(1, stats.InFlight)
+	s.Equal(map[string]int{"a": 2, "b": 1}, stats.QueuedByTenant)
+
+	var order []string
+	for range 3 {
+		gate <- struct{}{}
+		order = append(order, <-served)
+	}
+	gate <- struct{}{}
+	wg.Wait()
+	close(metas)
+
+	s.Equal([]string{"a", "b", "a"}, order, "b must not wait behind a's whole burst")
+	for meta := range metas {
+		s.Contains(meta, MetadataKeyQueueWait)
+	}
+	stats = r.QueueStats()["shared"]
+	s.Equal(0, stats.InFlight)
+	s.Equal(3, stats.MaxQueued)
+}
+
+func (s *RouterSuite) TestFairQueueCancelledWaiterLeavesQueue() {
+	served := make(chan string, 1)
+	gate := make(chan struct{})
+	factory := func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		return &gatedGenerator{served: served, gate: gate}, nil
+	}
+	r, err := New([]Route{{Name: "shared", Factory: factory}}, Config{}, WithFairQueue(1))
+	s.Require().NoError(err)
+	s.Nil(s.newRouter(Config{}, 0, &fakeProvider{name: "a"}).QueueStats())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = s.generate(r)
+	}()
+	<-served
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	gen, err := r.NewStringContentGenerator("hello")
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(ctx)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Equal(0, r.QueueStats()["shared"].Queued)
+
+	gate <- struct{}{}
+	<-done
+	s.Equal(0, r.QueueStats()["shared"].InFlight)
+}
+
+type timedProvider struct {
+	name    string
+	model   string
+	latency time.Duration
+	err     error
+	now     *time.Time
+}
+
+func (p *timedProvider) factory(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+	return &timedGenerator{provider: p}, nil
+}
+
+type timedGenerator struct {
+	provider *timedProvider
+}
+
+func (g *timedGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	*g.provider.now = g.provider.now.Add(g.provider.latency)
+	if g.provider.err != nil {
+		return "", nil, g.provider.err
+	}
+	return g.provider.name, model.GenerationMetadata{
+		model.MetadataKeyProvider: g.provider.name + "-provider",
+		model.MetadataKeyModel:    g.provider.model,
+	}, nil
+}
+
+func (g *timedGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+}
+
+func (g *timedGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+func (s *RouterSuite) TestStatsSmoothLatencyAndErrors() {
+	now := time.Unix(0, 0)
+	fast := &timedProvider{name: "fast", model: "small", latency: 100 * time.Millisecond, now: &now}
+	r, err := New([]Route{{Name: "fast", Factory: fast.factory}}, Config{}, WithClock(func() time.Time { return now }), WithStatsSmoothing(0.5))
+	s.Require().NoError(err)
+
+	_, _, err = s.generate(r)
+	s.Require().NoError(err)
+	fast.latency = 300 * time.Millisecond
+	_, _, err = s.generate(r)
+	s.Require().NoError(err)
+	fast.err = errors.New("down")
+	_, _, err = s.generate(r)
+	s.Require().Error(err)
+
+	stats := r.Stats()
+	s.Require().Len(stats, 1, "failures are attributed to the route's last model")
+	s.Equal("fast", stats[0].Route)
+	s.Equal("fast-provider", stats[0].Provider)
+	s.Equal("small", stats[0].Model)
+	s.Equal(int64(3), stats[0].Requests)
+	s.Equal(int64(1), stats[0].Errors)
+	s.InDelta(250, stats[0].LatencyMs, 0.001)
+	s.InDelta(0.5, stats[0].ErrorRate, 0.001)
+	s.Equal(now, stats[0].UpdatedAt)
+}
+
+func (s *RouterSuite) TestLeastLatencyStrategy() {
+	now := time.Unix(0, 0)
+	slow := &timedProvider{name: "slow", latency: 400 * time.Millisecond, now: &now}
+	fast := &timedProvider{name: "fast", latency: 100 * time.Millisecond, now: &now}
+	r, err := New(
+		[]Route{{Name: "slow", Factory: slow.factory}, {Name: "fast", Factory: fast.factory}},
+		Config{Strategy: StrategyLeastLatency},
+		WithClock(func() time.Time { return now }),
+	)
+	s.Require().NoError(err)
+
+	var routes []string
+	for range 4 {
+		_, meta, err := s.generate(r)
+		s.Require().NoError(err)
+		routes = append(routes, meta[MetadataKeyRoute])
+	}
+	s.Equal([]string{"slow", "fast", "fast", "fast"}, routes, "unmeasured routes are sampled first")
+
+	fast.err = errors.New("down")
+	for range 10 {
+		_, _, _ = s.generate(r)
+	}
+	_, meta, err := s.generate(r)
+	s.Require().NoError(err)
+	s.Equal("slow", meta[MetadataKeyRoute], "a failing route loses to a slower healthy one")
+
+	s.Error(r.SetConfig(Config{Strategy: "fastest"}))
+}
+
+func (s *RouterSuite) TestCheapestStrategy() {
+	premium, budget, local := &fakeProvider{name: "premium"}, &fakeProvider{name: "budget"}, &fakeProvider{name: "local"}
+	r, err := New([]Route{
+		{Name: "premium", Factory: premium.factory, Provider: "openai", Options: []model.GeneratorOption{model.WithModel("gpt-4o")}},
+		{Name: "local", Factory: local.factory, Options: []model.GeneratorOption{model.WithModel("llama3")}},
+		{
+			Name:         "budget",
+			Factory:      budget.factory,
+			Provider:     "openai",
+			Options:      []model.GeneratorOption{model.WithModel("gpt-4o-mini")},
+			Capabilities: &Capabilities{Tools: true, ContextWindow: 1000},
+		},
+	}, Config{Strategy: StrategyCheapest})
+	s.Require().NoError(err)
+
+	_, meta, err := s.generate(r)
+	s.Require().NoError(err)
+	s.Equal("budget", meta[MetadataKeyRoute])
+	s.NotEmpty(meta[MetadataKeyEstimatedCost])
+
+	gen, err := r.NewStringContentGenerator("describe the image")
+	s.Require().NoError(err)
+	_, meta, err = gen.Generate(ContextWithRequirements(context.Background(), Requirements{Vision: true}))
+	s.Require().NoError(err)
+	s.Equal("premium", meta[MetadataKeyRoute], "routes lacking a capability are skipped")
+
+	gen, err = r.NewStringContentGenerator("summarize", model.WithMaxTokens(4000))
+	s.Require().NoError(err)
+	_, meta, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("premium", meta[MetadataKeyRoute], "routes whose context window is too small are skipped")
+
+	premium.err = errors.New("down")
+	gen, err = r.NewStringContentGenerator("describe the image")
+	s.Require().NoError(err)
+	_, meta, err = gen.Generate(ContextWithRequirements(context.Background(), Requirements{Vision: true}))
+	s.Require().NoError(err)
+	s.Equal("local", meta[MetadataKeyRoute], "unpriced routes are fallbacks")
+	s.Equal("2", meta[MetadataKeyAttempts])
+	s.Empty(meta[MetadataKeyEstimatedCost])
+	s.Equal(1, budget.calls())
+}
+
+func (s *RouterSuite) TestCheapestStrategyResolvesContextProviders() {
+	premium, budget := &fakeProvider{name: "premium", err: errors.New("down")}, &fakeProvider{name: "budget"}
+	vision := &fakeProvider{name: "vision"}
+	r, err := New([]Route{
+		{Name: "premium", Factory: premium.factory, Provider: "openai", Options: []model.GeneratorOption{model.WithModel("gpt-4o")}},
+		{Name: "vision", Factory: vision.factory},
+		{
+			Name:         "budget",
+			Factory:      budget.factory,
+			Provider:     "openai",
+			Options:      []model.GeneratorOption{model.WithModel("gpt-4o-mini")},
+			Capabilities: &Capabilities{Tools: true, StructuredOutput: true},
+		},
+	}, Config{Strategy: StrategyCheapest})
+	s.Require().NoError(err)
+
+	scan := model.ImagePromptContext("Kidney ultrasound.", model.ImagePart{URL: "https://example.com/scan.png"})
+	provider := &countingContextProvider{contexts: []*model.PromptContext{scan}}
+	gen, err := r.NewStringContentGenerator("describe the image")
+	s.Require().NoError(err)
+	gen.AddPromptContextProvider(context.Background(), provider)
+
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("vision", meta[MetadataKeyRoute], "the cheaper route without vision is skipped")
+	s.Equal("2", meta[MetadataKeyAttempts])
+	s.Zero(budget.calls())
+	s.Equal(1, provider.calls, "providers run once, before the routes are chosen")
+
+	s.Require().Len(vision.built, 1)
+	s.Require().Len(vision.built[0].providers, 1)
+	provided, err := vision.built[0].providers[0].GenerateContext(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]*model.PromptContext{scan}, provided)
+	s.Equal(1, provider.calls)
+}
+
+func (s *RouterSuite) TestCheapestStrategyEstimatesProvidedContexts() {
+	short, long := &fakeProvider{name: "short"}, &fakeProvider{name: "long"}
+	r, err := New([]Route{
+		{
+			Name:         "short",
+			Factory:      short.factory,
+			Provider:     "openai",
+			Options:      []model.GeneratorOption{model.WithModel("gpt-4o-mini")},
+			Capabilities: &Capabilities{Tools: true, ContextWindow: 500},
+		},
+		{Name: "long", Factory: long.factory, Provider: "openai", Options: []model.GeneratorOption{model.WithModel("gpt-4o")}},
+	}, Config{Strategy: StrategyCheapest})
+	s.Require().NoError(err)
+
+	gen, err := r.NewStringContentGenerator("summarize the chart")
+	s.Require().NoError(err)
+	_, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("short", meta[MetadataKeyRoute])
+
+	notes := &model.PromptContext{MessageType: model.ContextMessageTypeHuman, Content: strings.Repeat("Creatinine rose from 1.2 to 1.7 mg/dL. ", 200)}
+	gen, err = r.NewStringContentGenerator("summarize the chart")
+	s.Require().NoError(err)
+	gen.AddPromptContextProvider(context.Background(), model.StaticPromptContextProvider(notes))
+	_, meta, err = gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("long", meta[MetadataKeyRoute], "provided contexts count toward the context size")
+}
+
+func (s *RouterSuite) TestCheapestStrategyReturnsContextProviderErrors() {
+	only := &fakeProvider{name: "only"}
+	r, err := New([]Route{{Name: "only", Factory: only.factory}}, Config{Strategy: StrategyCheapest})
+	s.Require().NoError(err)
+
+	gen, err := r.NewStringContentGenerator("summarize the chart")
+	s.Require().NoError(err)
+	gen.AddPromptContextProvider(context.Background(), failingContextProvider{})
+	_, _, err = gen.Generate(context.Background())
+	s.ErrorContains(err, "chart unavailable")
+	s.Zero(only.calls())
+}
+
+type failingContextProvider struct{}
+
+func (failingContextProvider) GenerateContext(ctx context.Context) ([]*model.PromptContext, error) {
+	return nil, errors.New("chart unavailable")
+}
@@ -0,0 +1,174 @@
+package router
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+// DefaultStatsSmoothing is the weight of the newest observation in the
+// exponentially smoothed latency and error rate.
+const DefaultStatsSmoothing = 0.2
+
+// maxScoredErrorRate keeps the least-latency score finite for routes that
+// fail every request.
+const maxScoredErrorRate = 0.99
+
+// WithStatsSmoothing sets the smoothing factor of the latency and error-rate
+// averages, in (0, 1]; higher values react faster to change. Other values
+// keep DefaultStatsSmoothing.
+func WithStatsSmoothing(alpha float64) Option {
+	return func(r *Router) {
+		if alpha > 0 && alpha <= 1 {
+			r.smoothing = alpha
+		}
+	}
+}
+
+// ModelStats is the smoothed performance of one route and model. Every
+// attempt counts, including failures and attempts after a fallback.
+type ModelStats struct {
+	Route    string
+	Provider string
+	// Model is the model reported by the provider, else the pinned model;
+	// empty when neither is known.
+	Model    string
+	Requests int64
+	Errors   int64
+	// LatencyMs and ErrorRate are exponentially weighted moving averages
+	// (see WithStatsSmoothing).
+	LatencyMs float64
+	ErrorRate float64
+	UpdatedAt time.Time
+}
+
+type statsKey struct {
+	route string
+	model string
+}
+
+type smoothedStats struct {
+	provider  string
+	requests  int64
+	errors    int64
+	latencyMs float64
+	errorRate float64
+	updatedAt time.Time
+}
+
+func (s *smoothedStats) observe(alpha float64, latency time.Duration, failed bool, now time.Time) {
+	latencyMs := float64(latency) / float64(time.Millisecond)
+	failure := 0.0
+	if failed {
+		failure = 1
+		s.errors++
+	}
+	if s.requests == 0 {
+		s.latencyMs, s.errorRate = latencyMs, failure
+	} else {
+		s.latencyMs += alpha * (latencyMs - s.latencyMs)
+		s.errorRate += alpha * (failure - s.errorRate)
+	}
+	s.requests++
+	s.updatedAt = now
+}
+
+// score ranks routes for StrategyLeastLatency: latency inflated by the share
+// of requests that fail and have to be retried elsewhere.
+func (s *smoothedStats) score() float64 {
+	errorRate := s.errorRate
+	if errorRate > maxScoredErrorRate {
+		errorRate = maxScoredErrorRate
+	}
+	return s.latencyMs / (1 - errorRate)
+}
+
+// Stats returns a snapshot of the smoothed latency and error rate per route
+// and model, sorted by route then model, for dashboards and metrics
+// exporters.
+func (r *Router) Stats() []ModelStats {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	out := make([]ModelStats, 0, len(r.modelStats))
+	for key, stats := range r.modelStats {
+		out = append(out, ModelStats{
+			Route:     key.route,
+			Provider:  stats.provider,
+			Model:     key.model,
+			Requests:  stats.requests,
+			Errors:    stats.errors,
+			LatencyMs: stats.latencyMs,
+			ErrorRate: stats.errorRate,
+			UpdatedAt: stats.updatedAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Route != out[j].Route {
+			return out[i].Route < out[j].Route
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// recordOutcome folds one attempt into the route and model averages.
+// Failed attempts usually have no metadata, so they are attributed to the
+// pinned model or the model the route last reported.
+func (r *Router) recordOutcome(route, pinnedModel string, meta model.GenerationMetadata, latency time.Duration, failed bool) {
+	now := r.now()
+
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	modelName := strings.TrimSpace(meta[model.MetadataKeyModel])
+	if modelName == "" {
+		modelName = strings.TrimSpace(pinnedModel)
+	}
+	if modelName == "" {
+		modelName = r.lastModel[route]
+	}
+	if modelName != "" {
+		r.lastModel[route] = modelName
+	}
+
+	key := statsKey{route: route, model: modelName}
+	stats, ok := r.modelStats[key]
+	if !ok {
+		stats = &smoothedStats{provider: route}
+		r.modelStats[key] = stats
+	}
+	if provider := strings.TrimSpace(meta[model.MetadataKeyProvider]); provider != "" {
+		stats.provider = provider
+	}
+	stats.observe(r.smoothing, latency, failed, now)
+
+	routeStats, ok := r.routeStats[route]
+	if !ok {
+		routeStats = &smoothedStats{provider: stats.provider}
+		r.routeStats[route] = routeStats
+	}
+	routeStats.observe(r.smoothing, latency, failed, now)
+}
+
+// pickLeastLatency returns the route with the lowest score. Routes without
+// observations come first, in registration order, so every route is
+// measured before the averages decide.
+func (r *Router) pickLeastLatency() (string, bool) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	best, bestScore := "", 0.0
+	for _, route := range r.routes {
+		stats, ok := r.routeStats[route.Name]
+		if !ok {
+			return route.Name, true
+		}
+		if score := stats.score(); best == "" || score < bestScore {
+			best, bestScore = route.Name, score
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const defaultFilePollInterval = 5 * time.Second
+
+// ConfigWatcher delivers new router policies. Watch returns a channel that
+// receives a Config whenever the source changes and is closed when ctx ends or
+// the source stops.
+type ConfigWatcher interface {
+	Watch(ctx context.Context) (<-chan Config, error)
+}
+
+// Watch applies every Config delivered by watcher until ctx is cancelled or
+// the channel closes. Invalid configs are logged and skipped, leaving the
+// previous policy in effect. Watch blocks; run it in its own goroutine.
+func (r *Router) Watch(ctx context.Context, watcher ConfigWatcher) error {
+	if watcher == nil {
+		return utils.WrapIfNotNil(errors.New("config watcher is required"))
+	}
+	log := logging.NewLogger(ctx)
+
+	updates, err := watcher.Watch(ctx)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case cfg, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if err := r.SetConfig(cfg); err != nil {
+				log.Warnf("ignoring invalid router config: %v", err)
+				continue
+			}
+			log.Infof("router config updated")
+		}
+	}
+}
+
+// FileWatcher polls a JSON config file and emits it whenever its content changes.
+type FileWatcher struct {
+	Path     string
+	Interval time.Duration
+}
+
+// NewFileWatcher watches path, checking every interval (default 5s).
+func NewFileWatcher(path string, interval time.Duration) *FileWatcher {
+	return &FileWatcher{Path: path, Interval: interval}
+}
+
+// Watch emits the current file content immediately, then again after each
+// change. Read and parse errors are logged; an unparsable file is retried once it changes.
+func (w *FileWatcher) Watch(ctx context.Context) (<-chan Config, error) {
+	if w.Path == "" {
+		return nil, utils.WrapIfNotNil(errors.New("config path is required"))
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultFilePollInterval
+	}
+
+	updates := make(chan Config)
+	go func() {
+		defer close(updates)
+		log := logging.NewLogger(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last []byte
+		for {
+			data, err := os.ReadFile(w.Path)
+			switch {
+			case err != nil:
+				log.Warnf("reading router config %q: %v", w.Path, err)
+			case !bytes.Equal(data, last):
+				cfg, parseErr := ParseConfig(data)
+				if parseErr != nil {
+					log.Warnf("parsing router config %q: %v", w.Path, parseErr)
+					last = data
+					break
+				}
+				select {
+				case updates <- cfg:
+					last = data
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return updates, nil
+}
@@ -0,0 +1,91 @@
+// Package schema reflects JSON Schemas from Go types for structured output
+// and tool arguments. Every provider uses it, so struct tags behave the same
+// everywhere: `json` names properties and omitempty makes them optional, and
+// `jsonschema` tags add keywords such as
+// `jsonschema:"description=Patient age in years,minimum=0"`,
+// `jsonschema:"enum=low,enum=high"` or `jsonschema:"required"`. Nested types
+// are inlined rather than referenced, and objects reject undeclared
+// properties unless Options allow them. Recursive types are not supported.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+	"github.com/invopop/jsonschema"
+)
+
+// Options configures Reflect. The zero value gives the schema providers send
+// by default.
+type Options struct {
+	// AllowAdditionalProperties leaves additionalProperties unset on every
+	// object, so undeclared properties are accepted.
+	AllowAdditionalProperties bool
+	// AdditionalPropertiesAt lists JSON Pointers of object schemas that accept
+	// undeclared properties, for example "/properties/attributes" for a
+	// field named attributes.
+	AdditionalPropertiesAt []string
+	// RequiredFromTags makes only fields tagged `jsonschema:"required"`
+	// required, instead of every field without omitempty.
+	RequiredFromTags bool
+	// FieldNameTag reads property names from this struct tag instead of
+	// `json`.
+	FieldNameTag string
+	// KeyNamer renames every property, for example to snake_case.
+	KeyNamer func(string) string
+}
+
+// Reflect returns the JSON Schema of T as a map, in the form providers embed
+// in requests.
+func Reflect[T any](opts Options) (map[string]any, error) {
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties:  opts.AllowAdditionalProperties,
+		RequiredFromJSONSchemaTags: opts.RequiredFromTags,
+		DoNotReference:             true,
+		FieldNameTag:               strings.TrimSpace(opts.FieldNameTag),
+		KeyNamer:                   opts.KeyNamer,
+	}
+
+	var value T
+	schemaJSON, err := json.Marshal(reflector.Reflect(value))
+	if err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		return nil, utils.WrapIfNotNil(err)
+	}
+	for _, pointer := range opts.AdditionalPropertiesAt {
+		object, err := resolvePointer(schema, pointer)
+		if err != nil {
+			return nil, utils.WrapIfNotNil(err)
+		}
+		delete(object, "additionalProperties")
+	}
+	return schema, nil
+}
+
+// resolvePointer returns the object schema at a JSON Pointer such as
+// "/properties/attributes".
+func resolvePointer(schema map[string]any, pointer string) (map[string]any, error) {
+	current := schema
+	trimmed := strings.TrimPrefix(strings.TrimSpace(pointer), "/")
+	if trimmed == "" {
+		return current, nil
+	}
+	for _, token := range strings.Split(trimmed, "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		next, ok := current[token].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema has no object at %q", pointer)
+		}
+		current = next
+	}
+	if current["type"] != "object" {
+		return nil, fmt.Errorf("schema at %q is not an object", pointer)
+	}
+	return current, nil
+}
@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaSuite struct {
+	suite.Suite
+}
+
+func TestSchemaSuite(t *testing.T) {
+	suite.Run(t, new(SchemaSuite))
+}
+
+type triage struct {
+	Severity   string                  `json:"severity" jsonschema:"enum=low,enum=high,description=How urgent the case is"`
+	Note       string                  `json:"note,omitempty"`
+	Reviewer   string                  `json:"reviewer,omitempty" jsonschema:"required"`
+	Attributes struct{ Source string } `json:"attributes"`
+	Labels     map[string]string       `json:"labels,omitempty"`
+}
+
+func properties(schema map[string]any) map[string]any {
+	return schema["properties"].(map[string]any)
+}
+
+func (s *SchemaSuite) TestDefaultsHonourTags() {
+	schema, err := Reflect[triage](Options{})
+	s.Require().NoError(err)
+
+	s.Equal("object", schema["type"])
+	s.Equal(false, schema["additionalProperties"])
+	severity := properties(schema)["severity"].(map[string]any)
+	s.Equal([]any{"low", "high"}, severity["enum"])
+	s.Equal("How urgent the case is", severity["description"])
+	s.ElementsMatch([]any{"severity", "reviewer", "attributes"}, schema["required"])
+
+	attributes := properties(schema)["attributes"].(map[string]any)
+	s.Equal(false, attributes["additionalProperties"])
+	s.NotContains(attributes, "$ref")
+}
+
+func (s *SchemaSuite) TestAdditionalPropertiesAt() {
+	schema, err := Reflect[triage](Options{AdditionalPropertiesAt: []string{"/properties/attributes"}})
+	s.Require().NoError(err)
+	s.Equal(false, schema["additionalProperties"])
+	s.NotContains(properties(schema)["attributes"], "additionalProperties")
+
+	_, err = Reflect[triage](Options{AdditionalPropertiesAt: []string{"/properties/severity"}})
+	s.ErrorContains(err, "not an object")
+	_, err = Reflect[triage](Options{AdditionalPropertiesAt: []string{"/properties/missing"}})
+	s.ErrorContains(err, "no object")
+
+	schema, err = Reflect[triage](Options{AllowAdditionalProperties: true})
+	s.Require().NoError(err)
+	s.NotContains(schema, "additionalProperties")
+}
+
+func (s *SchemaSuite) TestRequiredFromTagsAndRenaming() {
+	schema, err := Reflect[triage](Options{RequiredFromTags: true, KeyNamer: strings.ToUpper})
+	s.Require().NoError(err)
+	s.Equal([]any{"REVIEWER"}, schema["required"])
+	s.Contains(properties(schema), "SEVERITY")
+
+	type tagged struct {
+		Name string `json:"name" llm:"full_name"`
+	}
+	schema, err = Reflect[tagged](Options{FieldNameTag: "llm"})
+	s.Require().NoError(err)
+	s.Contains(properties(schema), "full_name")
+}
@@ -0,0 +1,213 @@
+// Package sse proxies generated text to browsers as Server-Sent Events.
+//
+// Each streamed chunk is sent as a "message" event, a comment heartbeat keeps
+// idle connections open, failures are reported as an "error" event, and a final
+// "done" event carries the generation metadata.
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const (
+	defaultHeartbeatInterval = 15 * time.Second
+
+	EventMessage = "message"
+	EventError   = "error"
+	EventDone    = "done"
+)
+
+// Option configures Stream and Handler.
+type Option func(*config)
+
+type config struct {
+	heartbeatInterval time.Duration
+}
+
+// WithHeartbeatInterval sets how often a comment heartbeat is sent (default 15s).
+// Zero or negative disables heartbeats.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.heartbeatInterval = interval
+	}
+}
+
+// GeneratorFactory builds the generator for an incoming request.
+type GeneratorFactory func(r *http.Request) (model.ContentGenerator[string], error)
+
+// Handler returns an http.Handler that streams the generator built by factory.
+func Handler(factory GeneratorFactory, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logging.NewLogger(r.Context())
+		if factory == nil {
+			http.Error(w, "generator factory is required", http.StatusInternalServerError)
+			return
+		}
+
+		gen, err := factory(r)
+		if err != nil {
+			log.Errorf("error: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := Stream(r.Context(), w, gen, opts...); err != nil {
+			log.Errorf("error: %v", err)
+		}
+	})
+}
+
+// Stream runs gen and writes its output to w as Server-Sent Events. Generation
+// errors are sent to the client as an "error" event and also returned.
+func Stream(ctx context.Context, w http.ResponseWriter, gen model.ContentGenerator[string], opts ...Option) error {
+	if w == nil {
+		return utils.WrapIfNotNil(errors.New("response writer is required"))
+	}
+	if gen == nil {
+		return utils.WrapIfNotNil(errors.New("generator is required"))
+	}
+
+	cfg := config{heartbeatInterval: defaultHeartbeatInterval}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	events := NewEventWriter(w)
+	stopHeartbeat := events.startHeartbeat(ctx, cfg.heartbeatInterval)
+	defer stopHeartbeat()
+
+	meta, err := model.GenerateTo(ctx, gen, &messageWriter{events: events})
+	stopHeartbeat()
+	if err != nil {
+		if writeErr := events.WriteJSON(EventError, map[string]string{"error": err.Error()}); writeErr != nil {
+			return utils.WrapIfNotNil(errors.Join(err, writeErr))
+		}
+		return utils.WrapIfNotNil(err)
+	}
+
+	if meta == nil {
+		meta = model.GenerationMetadata{}
+	}
+	return utils.WrapIfNotNil(events.WriteJSON(EventDone, meta))
+}
+
+// EventWriter writes Server-Sent Events and flushes after each one. It is safe
+// for concurrent use.
+type EventWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewEventWriter wraps w, which is flushed after each event when it implements http.Flusher.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w}
+}
+
+// WriteEvent writes one event. Multi-line data is split into several data lines.
+func (e *EventWriter) WriteEvent(event string, data string) error {
+	var frame strings.Builder
+	if event != "" && event != EventMessage {
+		frame.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		frame.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	frame.WriteString("\n")
+	return e.write(frame.String())
+}
+
+// WriteJSON writes one event whose data is value encoded as JSON.
+func (e *EventWriter) WriteJSON(event string, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	return e.WriteEvent(event, string(encoded))
+}
+
+// WriteComment writes a comment line, which clients ignore (used for heartbeats).
+func (e *EventWriter) WriteComment(comment string) error {
+	return e.write(": " + comment + "\n\n")
+}
+
+func (e *EventWriter) write(frame string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := io.WriteString(e.w, frame); err != nil {
+		return utils.WrapIfNotNil(err)
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (e *EventWriter) startHeartbeat(ctx context.Context, interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := e.WriteComment("heartbeat"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
+
+// messageWriter frames each Write from model.GenerateTo as a message event.
+type messageWriter struct {
+	events *EventWriter
+}
+
+func (m *messageWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := m.events.WriteEvent(EventMessage, string(p)); err != nil {
+		return 0, utils.WrapIfNotNil(err)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,106 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type SSESuite struct {
+	suite.Suite
+}
+
+func TestSSESuite(t *testing.T) {
+	suite.Run(t, new(SSESuite))
+}
+
+type chunkGenerator struct {
+	chunks []string
+	err    error
+	delay  time.Duration
+}
+
+func (g *chunkGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	return strings.Join(g.chunks, ""), model.GenerationMetadata{model.MetadataKeyProvider: "fake"}, g.err
+}
+
+func (g *chunkGenerator) GenerateStream(ctx context.Context, onChunk model.StreamHandler) (string, model.GenerationMetadata, error) {
+	for _, chunk := range g.chunks {
+		if g.delay > 0 {
+			time.Sleep(g.delay)
+		}
+		if err := onChunk(model.StreamChunk{Text: chunk}); err != nil {
+			return "", nil, err
+		}
+	}
+	return strings.Join(g.chunks, ""), model.GenerationMetadata{model.MetadataKeyProvider: "fake"}, g.err
+}
+
+func (g *chunkGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+}
+
+func (g *chunkGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+func (s *SSESuite) TestStreamWritesMessagesAndDone() {
+	recorder := httptest.NewRecorder()
+	err := Stream(context.Background(), recorder, &chunkGenerator{chunks: []string{"Hel", "lo\nworld"}})
+
+	s.Require().NoError(err)
+	s.Equal("text/event-stream", recorder.Header().Get("Content-Type"))
+	s.Equal("no-cache", recorder.Header().Get("Cache-Control"))
+	s.Equal(
+		"data: Hel\n\n"+
+			"data: lo\ndata: world\n\n"+
+			"event: done\ndata: {\"provider\":\"fake\"}\n\n",
+		recorder.Body.String(),
+	)
+	s.True(recorder.Flushed)
+}
+
+func (s *SSESuite) TestStreamWritesErrorEvent() {
+	recorder := httptest.NewRecorder()
+	err := Stream(context.Background(), recorder, &chunkGenerator{chunks: []string{"partial"}, err: errors.New("boom")})
+
+	s.Error(err)
+	body := recorder.Body.String()
+	s.Contains(body, "data: partial\n\n")
+	s.Contains(body, "event: error\ndata: {\"error\":")
+	s.Contains(body, "boom")
+	s.NotContains(body, "event: done")
+}
+
+func (s *SSESuite) TestStreamSendsHeartbeats() {
+	recorder := httptest.NewRecorder()
+	gen := &chunkGenerator{chunks: []string{"a", "b"}, delay: 30 * time.Millisecond}
+
+	err := Stream(context.Background(), recorder, gen, WithHeartbeatInterval(10*time.Millisecond))
+
+	s.Require().NoError(err)
+	s.Contains(recorder.Body.String(), ": heartbeat\n\n")
+}
+
+func (s *SSESuite) TestHandler() {
+	handler := Handler(func(r *http.Request) (model.ContentGenerator[string], error) {
+		if r.URL.Query().Get("prompt") == "" {
+			return nil, errors.New("prompt is required")
+		}
+		return &chunkGenerator{chunks: []string{r.URL.Query().Get("prompt")}}, nil
+	}, WithHeartbeatInterval(0))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stream?prompt=hi", nil))
+	s.Equal(http.StatusOK, recorder.Code)
+	s.True(strings.HasPrefix(recorder.Body.String(), "data: hi\n\n"))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	s.Equal(http.StatusBadRequest, recorder.Code)
+}
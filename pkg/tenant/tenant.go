@@ -0,0 +1,270 @@
+// Package tenant scopes generation to customers so one service instance can
+// serve many of them safely. A Registry holds a Policy per tenant (provider
+// auth tokens, budget, rate limit, audit tags) and Wrap applies it to any
+// provider constructor. The tenant comes from model.WithTenant or, when that
+// option is not set, from model.ContextWithTenant on the Generate context.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+var (
+	// ErrTenantRequired is returned when neither the options nor the context name a tenant.
+	ErrTenantRequired = errors.New("tenant is required")
+	// ErrUnknownTenant is returned for tenants without a registered Policy.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrRateLimited is returned when a tenant exceeds its request rate.
+	ErrRateLimited = errors.New("tenant rate limit exceeded")
+	// ErrBudgetExceeded is returned when a tenant has used up its budget.
+	ErrBudgetExceeded = errors.New("tenant budget exceeded")
+)
+
+// Policy is the per-tenant configuration.
+//
+// Field semantics:
+//   - AuthTokens: provider name (for example "openai") to auth token; applied
+//     with model.WithAuthToken so each tenant is billed on its own key.
+//   - Budget: request/token limits per window; zero values are unlimited.
+//   - RateLimit: token-bucket request rate; zero RequestsPerSecond is unlimited.
+//   - Tags: copied into metadata as "tenant_<key>" and into audit records.
+type Policy struct {
+	AuthTokens map[string]string
+	Budget     Budget
+	RateLimit  RateLimit
+	Tags       map[string]string
+}
+
+// Budget limits usage per Window. A zero Window applies the limit for the
+// lifetime of the registry entry.
+type Budget struct {
+	MaxRequests int64
+	MaxTokens   int64
+	Window      time.Duration
+}
+
+// RateLimit allows RequestsPerSecond on average with bursts of up to Burst
+// requests (at least 1).
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// AuditRecord describes one tenant-scoped generation attempt.
+// PromptVersion, Experiment and ExperimentVariant come from
+// model.WithPromptVersion and model.WithExperiment.
+type AuditRecord struct {
+	Tenant            string
+	Provider          string
+	Model             string
+	PromptVersion     string
+	Experiment        string
+	ExperimentVariant string
+	Tags              map[string]string
+	Metadata          model.GenerationMetadata
+	Err               error
+}
+
+// AuditFunc receives an AuditRecord after every generation, including rejected ones.
+type AuditFunc func(ctx context.Context, record AuditRecord)
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithAuditFunc sends audit records to fn in addition to the debug log.
+func WithAuditFunc(fn AuditFunc) Option {
+	return func(r *Registry) {
+		r.audit = fn
+	}
+}
+
+// WithClock overrides the clock used for budgets and rate limits.
+func WithClock(now func() time.Time) Option {
+	return func(r *Registry) {
+		if now != nil {
+			r.now = now
+		}
+	}
+}
+
+// Registry stores tenant policies and their usage. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantState
+	audit   AuditFunc
+	now     func() time.Time
+}
+
+type tenantState struct {
+	policy Policy
+
+	windowStart time.Time
+	requests    int64
+	tokens      int64
+
+	bucketTokens float64
+	bucketLast   time.Time
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		tenants: map[string]*tenantState{},
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// Set registers or replaces the policy for tenant id. Replacing a policy keeps
+// the tenant's current usage counters.
+func (r *Registry) Set(id string, policy Policy) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return utils.WrapIfNotNil(ErrTenantRequired)
+	}
+	if policy.Budget.MaxRequests < 0 || policy.Budget.MaxTokens < 0 || policy.Budget.Window < 0 {
+		return utils.WrapIfNotNil(fmt.Errorf("budget for tenant %q must not be negative", id))
+	}
+	if policy.RateLimit.RequestsPerSecond < 0 || policy.RateLimit.Burst < 0 {
+		return utils.WrapIfNotNil(fmt.Errorf("rate limit for tenant %q must not be negative", id))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.tenants[id]
+	if !ok {
+		now := r.now()
+		state = &tenantState{windowStart: now, bucketLast: now, bucketTokens: float64(burst(policy.RateLimit))}
+		r.tenants[id] = state
+	}
+	state.policy = clonePolicy(policy)
+	return nil
+}
+
+// Remove deletes tenant id and its usage.
+func (r *Registry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tenants, strings.TrimSpace(id))
+}
+
+// Policy returns a copy of the policy for tenant id.
+func (r *Registry) Policy(id string) (Policy, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.tenants[strings.TrimSpace(id)]
+	if !ok {
+		return Policy{}, false
+	}
+	return clonePolicy(state.policy), true
+}
+
+// admit checks the rate limit and budget for id and, when allowed, counts the
+// request. It returns the policy to apply.
+func (r *Registry) admit(id string) (Policy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.tenants[id]
+	if !ok {
+		return Policy{}, utils.WrapIfNotNil(fmt.Errorf("%w: %q", ErrUnknownTenant, id))
+	}
+	now := r.now()
+
+	budget := state.policy.Budget
+	if budget.Window > 0 && now.Sub(state.windowStart) >= budget.Window {
+		state.windowStart = now
+		state.requests = 0
+		state.tokens = 0
+	}
+	if (budget.MaxRequests > 0 && state.requests >= budget.MaxRequests) ||
+		(budget.MaxTokens > 0 && state.tokens >= budget.MaxTokens) {
+		return Policy{}, utils.WrapIfNotNil(fmt.Errorf("%w: %q", ErrBudgetExceeded, id))
+	}
+
+	limit := state.policy.RateLimit
+	if limit.RequestsPerSecond > 0 {
+		capacity := float64(burst(limit))
+		state.bucketTokens += now.Sub(state.bucketLast).Seconds() * limit.RequestsPerSecond
+		if state.bucketTokens > capacity {
+			state.bucketTokens = capacity
+		}
+		state.bucketLast = now
+		if state.bucketTokens < 1 {
+			return Policy{}, utils.WrapIfNotNil(fmt.Errorf("%w: %q", ErrRateLimited, id))
+		}
+		state.bucketTokens--
+	}
+
+	state.requests++
+	return clonePolicy(state.policy), nil
+}
+
+func (r *Registry) recordTokens(id string, meta model.GenerationMetadata) {
+	tokens := meta.Usage().TotalTokens
+	if tokens <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if state, ok := r.tenants[id]; ok {
+		state.tokens += tokens
+	}
+}
+
+func (r *Registry) recordAudit(ctx context.Context, record AuditRecord) {
+	log := logging.NewLogger(ctx)
+	log.Debugf(
+		"tenant=%q provider=%q model=%q prompt_version=%q experiment=%q variant=%q tags=%v error=%v",
+		record.Tenant,
+		record.Provider,
+		record.Model,
+		record.PromptVersion,
+		record.Experiment,
+		record.ExperimentVariant,
+		record.Tags,
+		record.Err,
+	)
+	if r.audit != nil {
+		r.audit(ctx, record)
+	}
+}
+
+func burst(limit RateLimit) int {
+	if limit.Burst < 1 {
+		return 1
+	}
+	return limit.Burst
+}
+
+func clonePolicy(policy Policy) Policy {
+	out := policy
+	if policy.AuthTokens != nil {
+		out.AuthTokens = make(map[string]string, len(policy.AuthTokens))
+		for k, v := range policy.AuthTokens {
+			out.AuthTokens[k] = v
+		}
+	}
+	if policy.Tags != nil {
+		out.Tags = make(map[string]string, len(policy.Tags))
+		for k, v := range policy.Tags {
+			out.Tags[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type TenantSuite struct {
+	suite.Suite
+}
+
+func TestTenantSuite(t *testing.T) {
+	suite.Run(t, new(TenantSuite))
+}
+
+type fakeGenerator struct {
+	cfg      model.GeneratorConfig
+	contexts int
+	tenant   string
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context) (string, model.GenerationMetadata, error) {
+	g.tenant = model.TenantFromContext(ctx)
+	return "token=" + g.cfg.AuthToken, model.GenerationMetadata{
+		model.MetadataKeyModel:       "m",
+		model.MetadataKeyTotalTokens: "10",
+	}, nil
+}
+
+func (g *fakeGenerator) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.contexts++
+}
+
+func (g *fakeGenerator) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+}
+
+type fakeFactory struct {
+	built []*fakeGenerator
+}
+
+func (f *fakeFactory) build(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+	gen := &fakeGenerator{cfg: model.ResolveGeneratorOpts(opts...)}
+	f.built = append(f.built, gen)
+	return gen, nil
+}
+
+func (s *TenantSuite) TestAppliesTenantTokenAndTags() {
+	var audits []AuditRecord
+	registry := NewRegistry(WithAuditFunc(func(ctx context.Context, record AuditRecord) {
+		audits = append(audits, record)
+	}))
+	s.Require().NoError(registry.Set("acme", Policy{
+		AuthTokens: map[string]string{"openai": "acme-key"},
+		Tags:       map[string]string{"plan": "gold"},
+	}))
+	s.Require().NoError(registry.Set("globex", Policy{AuthTokens: map[string]string{"openai": "globex-key"}}))
+
+	factory := &fakeFactory{}
+	newGen := WrapString(registry, "openai", factory.build)
+
+	gen, err := newGen("hello", model.WithAuthToken("shared-key"))
+	s.Require().NoError(err)
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "be brief")
+
+	out, meta, err := gen.Generate(model.ContextWithTenant(context.Background(), "acme"))
+	s.Require().NoError(err)
+	s.Equal("token=acme-key", out)
+	s.Equal("acme", meta[model.MetadataKeyTenant])
+	s.Equal("gold", meta["tenant_plan"])
+	s.Equal("acme", factory.built[0].tenant)
+	s.Equal(1, factory.built[0].contexts)
+
+	gen, err = newGen("hello", model.WithTenant("globex"))
+	s.Require().NoError(err)
+	out, _, err = gen.Generate(model.ContextWithTenant(context.Background(), "acme"))
+	s.Require().NoError(err)
+	s.Equal("token=globex-key", out)
+
+	s.Require().Len(audits, 2)
+	s.Equal("acme", audits[0].Tenant)
+	s.Equal("m", audits[0].Model)
+	s.Equal("gold", audits[0].Tags["plan"])
+}
+
+func (s *TenantSuite) TestAuditRecordsExperiment() {
+	var audits []AuditRecord
+	registry := NewRegistry(WithAuditFunc(func(ctx context.Context, record AuditRecord) {
+		audits = append(audits, record)
+	}))
+	s.Require().NoError(registry.Set("acme", Policy{}))
+
+	gen, err := WrapString(registry, "openai", (&fakeFactory{}).build)(
+		"hello",
+		model.WithPromptVersion("v2"),
+		model.WithExperiment("tone", "formal"),
+	)
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(model.ContextWithTenant(context.Background(), "acme"))
+	s.Require().NoError(err)
+	_, _, err = gen.Generate(context.Background())
+	s.ErrorIs(err, ErrTenantRequired)
+
+	s.Require().Len(audits, 2)
+	for _, audit := range audits {
+		s.Equal("v2", audit.PromptVersion)
+		s.Equal("tone", audit.Experiment)
+		s.Equal("formal", audit.ExperimentVariant)
+	}
+}
+
+func (s *TenantSuite) TestRejectsMissingAndUnknownTenants() {
+	registry := NewRegistry()
+	factory := &fakeFactory{}
+	gen, err := WrapString(registry, "openai", factory.build)("hello")
+	s.Require().NoError(err)
+
+	_, _, err = gen.Generate(context.Background())
+	s.ErrorIs(err, ErrTenantRequired)
+
+	_, _, err = gen.Generate(model.ContextWithTenant(context.Background(), "nobody"))
+	s.ErrorIs(err, ErrUnknownTenant)
+	s.Empty(factory.built)
+}
+
+func (s *TenantSuite) TestBudgetAndRateLimit() {
+	now := time.Unix(0, 0)
+	registry := NewRegistry(WithClock(func() time.Time { return now }))
+	s.Require().NoError(registry.Set("budgeted", Policy{Budget: Budget{MaxTokens: 15, Window: time.Hour}}))
+	s.Require().NoError(registry.Set("limited", Policy{RateLimit: RateLimit{RequestsPerSecond: 1, Burst: 2}}))
+
+	factory := &fakeFactory{}
+	generate := func(tenantID string) error {
+		gen, err := WrapString(registry, "openai", factory.build)("hello", model.WithTenant(tenantID))
+		s.Require().NoError(err)
+		_, _, err = gen.Generate(context.Background())
+		return err
+	}
+
+	s.NoError(generate("budgeted"))
+	s.NoError(generate("budgeted"))
+	s.ErrorIs(generate("budgeted"), ErrBudgetExceeded)
+	now = now.Add(time.Hour)
+	s.NoError(generate("budgeted"))
+
+	s.NoError(generate("limited"))
+	s.NoError(generate("limited"))
+	s.ErrorIs(generate("limited"), ErrRateLimited)
+	now = now.Add(time.Second)
+	s.NoError(generate("limited"))
+}
+
+func (s *TenantSuite) TestSetValidatesPolicy() {
+	registry := NewRegistry()
+	s.Error(registry.Set(" ", Policy{}))
+	s.Error(registry.Set("a", Policy{Budget: Budget{MaxTokens: -1}}))
+	s.Error(registry.Set("a", Policy{RateLimit: RateLimit{RequestsPerSecond: -1}}))
+
+	s.Require().NoError(registry.Set("a", Policy{Tags: map[string]string{"k": "v"}}))
+	policy, ok := registry.Policy("a")
+	s.True(ok)
+	policy.Tags["k"] = "changed"
+	policy, _ = registry.Policy("a")
+	s.Equal("v", policy.Tags["k"])
+
+	registry.Remove("a")
+	_, ok = registry.Policy("a")
+	s.False(ok)
+}
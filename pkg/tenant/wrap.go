@@ -0,0 +1,160 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/logging"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/utils"
+)
+
+const metadataTagPrefix = "tenant_"
+
+// Wrap returns a constructor equivalent to factory whose generators enforce
+// the tenant policy from r. provider selects the entry in Policy.AuthTokens.
+// The provider generator is only built at Generate time, once the tenant is
+// known, so the same constructor can serve every tenant.
+func Wrap[T any](r *Registry, provider string, factory model.NewStructureContentGeneratorFunc[T]) model.NewStructureContentGeneratorFunc[T] {
+	return func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[T], error) {
+		if r == nil {
+			return nil, utils.WrapIfNotNil(errors.New("tenant registry is required"))
+		}
+		if factory == nil {
+			return nil, utils.WrapIfNotNil(errors.New("generator factory is required"))
+		}
+		if strings.TrimSpace(prompt) == "" {
+			return nil, utils.WrapIfNotNil(errors.New("prompt is required"))
+		}
+
+		return &generator[T]{
+			registry: r,
+			provider: strings.TrimSpace(provider),
+			factory:  factory,
+			prompt:   prompt,
+			opts:     append([]model.GeneratorOption(nil), opts...),
+		}, nil
+	}
+}
+
+// WrapString is Wrap for model.NewStringContentGeneratorFunc constructors.
+func WrapString(r *Registry, provider string, factory model.NewStringContentGeneratorFunc) model.NewStringContentGeneratorFunc {
+	wrapped := Wrap[string](r, provider, model.NewStructureContentGeneratorFunc[string](factory))
+	return func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[string], error) {
+		return wrapped(prompt, opts...)
+	}
+}
+
+type generator[T any] struct {
+	registry *Registry
+	provider string
+	factory  model.NewStructureContentGeneratorFunc[T]
+	prompt   string
+	opts     []model.GeneratorOption
+
+	promptContextMu        sync.RWMutex
+	promptContexts         []*model.PromptContext
+	promptContextProviders []model.PromptContextProvider
+}
+
+func (g *generator[T]) AddPromptContext(ctx context.Context, messageType model.ContextMessageType, content string) {
+	g.promptContextMu.Lock()
+	defer g.promptContextMu.Unlock()
+	g.promptContexts = append(g.promptContexts, &model.PromptContext{MessageType: messageType, Content: content})
+}
+
+func (g *generator[T]) AddPromptContextProvider(ctx context.Context, provider model.PromptContextProvider) {
+	if provider == nil {
+		return
+	}
+	g.promptContextMu.Lock()
+	defer g.promptContextMu.Unlock()
+	g.promptContextProviders = append(g.promptContextProviders, provider)
+}
+
+func (g *generator[T]) Generate(ctx context.Context) (T, model.GenerationMetadata, error) {
+	log := logging.NewLogger(ctx)
+	var zero T
+
+	cfg := model.ResolveGeneratorOpts(g.opts...)
+	tenantID := model.ResolveTenant(ctx, cfg)
+	record := AuditRecord{
+		Tenant:            tenantID,
+		Provider:          g.provider,
+		PromptVersion:     cfg.PromptVersion,
+		Experiment:        cfg.Experiment,
+		ExperimentVariant: cfg.ExperimentVariant,
+	}
+	if cfg.Model != nil {
+		record.Model = *cfg.Model
+	}
+
+	if tenantID == "" {
+		record.Err = ErrTenantRequired
+		g.registry.recordAudit(ctx, record)
+		log.Errorf("error: %v", record.Err)
+		return zero, nil, utils.WrapIfNotNil(record.Err)
+	}
+
+	policy, err := g.registry.admit(tenantID)
+	if err != nil {
+		record.Err = err
+		g.registry.recordAudit(ctx, record)
+		log.Errorf("error: %v", err)
+		return zero, nil, utils.WrapIfNotNil(err)
+	}
+	record.Tags = policy.Tags
+
+	out, meta, err := g.generate(ctx, tenantID, policy)
+	if meta != nil {
+		g.registry.recordTokens(tenantID, meta)
+		meta[model.MetadataKeyTenant] = tenantID
+		for key, value := range policy.Tags {
+			meta[metadataTagPrefix+key] = value
+		}
+		if modelName := meta[model.MetadataKeyModel]; modelName != "" {
+			record.Model = modelName
+		}
+	}
+	record.Metadata = meta
+	record.Err = err
+	g.registry.recordAudit(ctx, record)
+
+	if err != nil {
+		log.Errorf("error: %v", err)
+		return zero, meta, utils.WrapIfNotNil(err)
+	}
+	return out, meta, nil
+}
+
+func (g *generator[T]) generate(ctx context.Context, tenantID string, policy Policy) (T, model.GenerationMetadata, error) {
+	var zero T
+
+	opts := append([]model.GeneratorOption(nil), g.opts...)
+	if token := strings.TrimSpace(policy.AuthTokens[g.provider]); token != "" {
+		opts = append(opts, model.WithAuthToken(token))
+	}
+	opts = append(opts, model.WithTenant(tenantID))
+
+	gen, err := g.factory(g.prompt, opts...)
+	if err != nil {
+		return zero, nil, utils.WrapIfNotNil(err)
+	}
+
+	g.promptContextMu.RLock()
+	contexts := append([]*model.PromptContext(nil), g.promptContexts...)
+	providers := append([]model.PromptContextProvider(nil), g.promptContextProviders...)
+	g.promptContextMu.RUnlock()
+
+	for _, promptContext := range contexts {
+		gen.AddPromptContext(ctx, promptContext.MessageType, promptContext.Content)
+	}
+	for _, provider := range providers {
+		gen.AddPromptContextProvider(ctx, provider)
+	}
+
+	out, meta, err := gen.Generate(model.ContextWithTenant(ctx, tenantID))
+	return out, meta, utils.WrapIfNotNil(err)
+}
@@ -0,0 +1,585 @@
+// Package testsupport holds reusable test helpers for provider
+// implementations. RunConformance checks that a provider honours the shared
+// pkg/model contracts (option handling, prompt context mapping, tool loop
+// semantics, metadata keys and error behaviour) against a scripted fake
+// server, so new providers behave like the existing ones without live keys.
+package testsupport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+// Message roles used in Request.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Request is the provider-neutral view of one API request.
+type Request struct {
+	Model    string
+	Messages []Message
+	// Tools lists the names of the tools declared in the request.
+	Tools []string
+}
+
+// Message is one conversation entry of a Request. Tool results use RoleTool
+// with the handler output in Content; ToolCallID is set when the provider's
+// protocol carries call IDs.
+type Message struct {
+	Role       string
+	Content    string
+	ToolCallID string
+}
+
+// ToolCall is a tool invocation requested by a scripted Turn.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+}
+
+// Turn is one scripted model reply.
+type Turn struct {
+	Text         string
+	ToolCalls    []ToolCall
+	InputTokens  int64
+	OutputTokens int64
+	// StatusCode, when set to a non-2xx value, makes the fake server answer
+	// with a provider API error carrying ErrorMessage.
+	StatusCode   int
+	ErrorMessage string
+	// Hang makes the fake server wait until the client gives up.
+	Hang bool
+}
+
+// Wire translates between the neutral types and a provider's HTTP protocol.
+type Wire interface {
+	DecodeRequest(r *http.Request) (Request, error)
+	// WriteResponse writes turn as a successful reply or, when turn.StatusCode
+	// is non-2xx, as the provider's error body.
+	WriteResponse(w http.ResponseWriter, turn Turn)
+}
+
+// Record is the structured output type used by the conformance checks.
+type Record struct {
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
+// Harness describes the provider under test.
+//
+// Field semantics:
+//   - Provider: expected model.MetadataKeyProvider value.
+//   - Wire: protocol adapter for the fake server.
+//   - Options: extra options for every generator (for example model.WithAuthToken).
+//   - NewString / NewStructured: the provider constructors; NewStructured may be nil.
+//   - UnsupportedOptions: options the provider must reject unless
+//     model.WithIgnoreInvalidGeneratorOptions(true) is set.
+type Harness struct {
+	Provider           string
+	Wire               Wire
+	Options            []model.GeneratorOption
+	NewString          model.NewStringContentGeneratorFunc
+	NewStructured      func(prompt string, opts ...model.GeneratorOption) (model.ContentGenerator[Record], error)
+	UnsupportedOptions []model.GeneratorOption
+}
+
+// RunConformance runs the conformance suite for h.
+func RunConformance(t *testing.T, h Harness) {
+	t.Helper()
+	suite.Run(t, &ConformanceSuite{harness: h})
+}
+
+// FakeServer replays scripted turns through a Wire and records the decoded
+// requests. Once the script is exhausted the last turn is repeated.
+type FakeServer struct {
+	URL string
+
+	mu       sync.Mutex
+	turns    []Turn
+	requests []Request
+}
+
+// NewFakeServer starts a FakeServer that is closed when t finishes.
+func NewFakeServer(t testing.TB, wire Wire, turns ...Turn) *FakeServer {
+	t.Helper()
+	fake := &FakeServer{turns: turns}
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, err := wire.DecodeRequest(r)
+		if err != nil {
+			t.Errorf("decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fake.mu.Lock()
+		turn := Turn{}
+		if index := len(fake.requests); index < len(fake.turns) {
+			turn = fake.turns[index]
+		} else if len(fake.turns) > 0 {
+			turn = fake.turns[len(fake.turns)-1]
+		}
+		fake.requests = append(fake.requests, request)
+		fake.mu.Unlock()
+
+		if turn.Hang {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+			return
+		}
+		wire.WriteResponse(w, turn)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	fake.URL = server.URL
+	return fake
+}
+
+// Requests returns the decoded requests received so far.
+func (f *FakeServer) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// ConformanceSuite is the suite run by RunConformance.
+type ConformanceSuite struct {
+	suite.Suite
+	harness Harness
+}
+
+func (s *ConformanceSuite) SetupSuite() {
+	s.Require().NotNil(s.harness.Wire, "Harness.Wire is required")
+	s.Require().NotNil(s.harness.NewString, "Harness.NewString is required")
+}
+
+func (s *ConformanceSuite) options(serverURL string, extra ...model.GeneratorOption) []model.GeneratorOption {
+	opts := append([]model.GeneratorOption{model.WithURL(serverURL)}, s.harness.Options...)
+	return append(opts, extra...)
+}
+
+func (s *ConformanceSuite) newString(prompt string, serverURL string, extra ...model.GeneratorOption) model.ContentGenerator[string] {
+	gen, err := s.harness.NewString(prompt, s.options(serverURL, extra...)...)
+	s.Require().NoError(err)
+	return gen
+}
+
+func (s *ConformanceSuite) TestEmptyPromptIsRejected() {
+	gen, err := s.harness.NewString("  ", s.harness.Options...)
+	if err == nil {
+		_, _, err = gen.Generate(context.Background())
+	}
+	s.Error(err)
+}
+
+func (s *ConformanceSuite) TestTextAndMetadata() {
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{Text: "hello", InputTokens: 5, OutputTokens: 3})
+
+	out, meta, err := s.newString("Say hello.", fake.URL, model.WithModel("conformance-model")).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("hello", out)
+
+	requests := fake.Requests()
+	s.Require().Len(requests, 1)
+	s.Equal("conformance-model", requests[0].Model)
+	s.Empty(requests[0].Tools)
+
+	s.Equal(s.harness.Provider, meta[model.MetadataKeyProvider])
+	s.NotEmpty(meta[model.MetadataKeyModel])
+	s.NotEmpty(meta[model.MetadataKeyLatencyMs])
+	s.Equal("1", meta[model.MetadataKeyAPICalls])
+	s.Equal("0", meta[model.MetadataKeyToolRounds])
+	s.Equal("5", meta[model.MetadataKeyInputTokens])
+	s.Equal("3", meta[model.MetadataKeyOutputTokens])
+	s.Equal("8", meta[model.MetadataKeyTotalTokens])
+}
+
+func (s *ConformanceSuite) TestPromptContextMapping() {
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{Text: "ok"})
+	ctx := context.Background()
+
+	gen := s.newString("The prompt.", fake.URL)
+	gen.AddPromptContext(ctx, model.ContextMessageTypeSystem, "System rules.")
+	gen.AddPromptContext(ctx, model.ContextMessageTypeHuman, "Earlier question.")
+	gen.AddPromptContext(ctx, model.ContextMessageTypeAssistant, "Earlier answer.")
+	gen.AddPromptContext(ctx, model.ContextMessageTypeHuman, "   ")
+	gen.AddPromptContextProvider(ctx, contextProviderFunc(func(ctx context.Context) ([]*model.PromptContext, error) {
+		return []*model.PromptContext{{MessageType: model.ContextMessageTypeHuman, Content: "Provided context."}}, nil
+	}))
+
+	_, _, err := gen.Generate(ctx)
+	s.Require().NoError(err)
+
+	requests := fake.Requests()
+	s.Require().Len(requests, 1)
+	system, conversation := splitSystem(requests[0].Messages)
+	s.Contains(system, "System rules.")
+	s.Require().Len(conversation, 4)
+	s.Equal(Message{Role: RoleUser, Content: "Earlier question."}, conversation[0])
+	s.Equal(Message{Role: RoleAssistant, Content: "Earlier answer."}, conversation[1])
+	s.Equal(Message{Role: RoleUser, Content: "Provided context."}, conversation[2])
+	s.Equal(RoleUser, conversation[3].Role)
+	s.Contains(conversation[3].Content, "The prompt.")
+}
+
+func (s *ConformanceSuite) TestPromptContextProviderErrorFailsGeneration() {
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{Text: "ok"})
+	errProvider := errors.New("context store unavailable")
+
+	gen := s.newString("The prompt.", fake.URL)
+	gen.AddPromptContextProvider(context.Background(), contextProviderFunc(func(ctx context.Context) ([]*model.PromptContext, error) {
+		return nil, errProvider
+	}))
+
+	_, _, err := gen.Generate(context.Background())
+	s.ErrorIs(err, errProvider)
+	s.Empty(fake.Requests())
+}
+
+func (s *ConformanceSuite) TestToolLoop() {
+	fake := NewFakeServer(s.T(), s.harness.Wire,
+		Turn{
+			ToolCalls: []ToolCall{
+				{ID: "call_1", Name: "lookup", Arguments: json.RawMessage(`{"id":1}`)},
+				{ID: "call_2", Name: "lookup", Arguments: json.RawMessage(`{"id":2}`)},
+			},
+			InputTokens:  10,
+			OutputTokens: 4,
+		},
+		Turn{Text: "Found both.", InputTokens: 20, OutputTokens: 6},
+	)
+
+	var mu sync.Mutex
+	var seen []string
+	tool := model.Tool{
+		Name:        "lookup",
+		Description: "Look up a record by id.",
+		InputSchema: model.JSONSchema{"type": "object", "properties": map[string]any{"id": map[string]any{"type": "integer"}}},
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			var in struct {
+				ID int `json:"id"`
+			}
+			if err := json.Unmarshal(args, &in); err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			seen = append(seen, string(args))
+			mu.Unlock()
+			return map[string]any{"record": in.ID}, nil
+		},
+	}
+
+	out, meta, err := s.newString("Find records 1 and 2.", fake.URL, model.WithTools([]model.Tool{tool})).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Found both.", out)
+	s.Len(seen, 2)
+
+	requests := fake.Requests()
+	s.Require().Len(requests, 2)
+	s.Equal([]string{"lookup"}, requests[0].Tools)
+
+	var results []Message
+	for _, message := range requests[1].Messages {
+		if message.Role == RoleTool {
+			results = append(results, message)
+		}
+	}
+	s.Require().Len(results, 2, "tool results must be sent back, one per call")
+	s.JSONEq(`{"record":1}`, results[0].Content)
+	s.JSONEq(`{"record":2}`, results[1].Content)
+	if results[0].ToolCallID != "" {
+		s.Equal("call_1", results[0].ToolCallID)
+		s.Equal("call_2", results[1].ToolCallID)
+	}
+
+	s.Equal("2", meta[model.MetadataKeyAPICalls])
+	s.Equal("1", meta[model.MetadataKeyToolRounds])
+	toolCalls, err := model.ParseToolCalls(meta)
+	s.Require().NoError(err)
+	s.Require().Len(toolCalls, 2, "every local tool call must be in the tool_calls transcript")
+	for i, record := range toolCalls {
+		s.Equal(1, record.Round)
+		s.Equal(i, record.Index)
+		s.Equal("lookup", record.Name)
+		s.GreaterOrEqual(record.DurationMs, int64(0))
+		s.Empty(record.Error)
+		if record.CallID != "" {
+			s.Equal(fmt.Sprintf("call_%d", i+1), record.CallID)
+		}
+	}
+	s.Equal("30", meta[model.MetadataKeyInputTokens])
+	s.Equal("10", meta[model.MetadataKeyOutputTokens])
+	s.Equal("40", meta[model.MetadataKeyTotalTokens])
+}
+
+func (s *ConformanceSuite) TestHistoryExport() {
+	fake := NewFakeServer(s.T(), s.harness.Wire,
+		Turn{ToolCalls: []ToolCall{{ID: "call_1", Name: "lookup", Arguments: json.RawMessage(`{"id":1}`)}}},
+		Turn{Text: "Found it."},
+	)
+	tool := model.Tool{Name: "lookup", Description: "Look up a record by id.", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return map[string]any{"record": 1}, nil
+	}}
+
+	gen := s.newString("Find record 1.", fake.URL, model.WithTools([]model.Tool{tool}), model.WithModel("conformance-model"))
+	gen.AddPromptContext(context.Background(), model.ContextMessageTypeSystem, "System rules.")
+	_, _, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+
+	exporter, ok := gen.(model.HistoryExporter)
+	s.Require().True(ok, "generators must implement model.HistoryExporter")
+	history := exporter.ExportHistory()
+	s.Equal(model.ConversationHistoryVersion, history.Version)
+	s.Equal(s.harness.Provider, history.Provider)
+	s.Equal("conformance-model", history.Model)
+
+	roles := make([]model.HistoryRole, 0, len(history.Messages))
+	for _, message := range history.Messages {
+		roles = append(roles, message.Role)
+	}
+	s.Require().Equal([]model.HistoryRole{
+		model.HistoryRoleSystem,
+		model.HistoryRoleUser,
+		model.HistoryRoleAssistant,
+		model.HistoryRoleTool,
+		model.HistoryRoleAssistant,
+	}, roles)
+	s.Contains(history.Messages[0].Content, "System rules.")
+	s.Contains(history.Messages[1].Content, "Find record 1.")
+
+	s.Require().Len(history.Messages[2].ToolCalls, 1)
+	call := history.Messages[2].ToolCalls[0]
+	s.Equal("lookup", call.Name)
+	s.JSONEq(`{"id":1}`, string(call.Arguments))
+
+	result := history.Messages[3]
+	s.Equal("lookup", result.ToolName)
+	s.Contains(result.Content, `"record":1`)
+	if call.ID != "" {
+		s.Equal("call_1", call.ID)
+		s.Equal("call_1", result.ToolCallID)
+	}
+	s.Equal("Found it.", history.Messages[4].Content)
+}
+
+func (s *ConformanceSuite) TestMaxToolRounds() {
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{ToolCalls: []ToolCall{{ID: "call_1", Name: "again", Arguments: json.RawMessage(`{}`)}}})
+	tool := model.Tool{Name: "again", Description: "Always called again.", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "ok", nil
+	}}
+
+	_, _, err := s.newString("Loop.", fake.URL, model.WithTools([]model.Tool{tool}), model.WithMaxToolRounds(2)).Generate(context.Background())
+	s.ErrorIs(err, model.ErrMaxToolRoundsExceeded)
+	var roundsErr *model.MaxToolRoundsError
+	s.Require().ErrorAs(err, &roundsErr)
+	s.Equal(2, roundsErr.Limit)
+	s.Len(fake.Requests(), 2)
+}
+
+func (s *ConformanceSuite) TestToolErrorsAreReportedToModel() {
+	fake := NewFakeServer(s.T(), s.harness.Wire,
+		Turn{ToolCalls: []ToolCall{{ID: "call_1", Name: "lookup", Arguments: json.RawMessage(`{"id":7}`)}}},
+		Turn{Text: "Record 7 is missing."},
+	)
+	tool := model.Tool{Name: "lookup", Description: "Look up a record by id.", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return nil, errors.New("record 7 missing")
+	}}
+
+	out, meta, err := s.newString("Find record 7.", fake.URL, model.WithTools([]model.Tool{tool})).Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal("Record 7 is missing.", out)
+	toolCalls, err := model.ParseToolCalls(meta)
+	s.Require().NoError(err)
+	s.Require().Len(toolCalls, 1)
+	s.Equal("record 7 missing", toolCalls[0].Error)
+
+	requests := fake.Requests()
+	s.Require().Len(requests, 2)
+	var results []Message
+	for _, message := range requests[1].Messages {
+		if message.Role == RoleTool {
+			results = append(results, message)
+		}
+	}
+	s.Require().Len(results, 1)
+	s.JSONEq(`{"error":"record 7 missing"}`, results[0].Content)
+}
+
+func (s *ConformanceSuite) TestToolErrorModes() {
+	errMissing := errors.New("record missing")
+	tool := model.Tool{Name: "lookup", Description: "Look up a record by id.", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return nil, errMissing
+	}}
+	failing := Turn{ToolCalls: []ToolCall{{ID: "call_1", Name: "lookup", Arguments: json.RawMessage(`{}`)}}}
+
+	fake := NewFakeServer(s.T(), s.harness.Wire, failing, Turn{Text: "unreachable"})
+	_, _, err := s.newString("Find it.", fake.URL, model.WithTools([]model.Tool{tool}), model.WithToolErrorMode(model.ToolErrorModeFailFast)).Generate(context.Background())
+	s.ErrorIs(err, errMissing)
+	var callErr *model.ToolCallError
+	s.Require().ErrorAs(err, &callErr)
+	s.Equal("lookup", callErr.Tool)
+	s.Len(fake.Requests(), 1)
+
+	fake = NewFakeServer(s.T(), s.harness.Wire, failing)
+	_, _, err = s.newString("Find it.", fake.URL, model.WithTools([]model.Tool{tool}), model.WithToolErrorBudget(1)).Generate(context.Background())
+	s.ErrorIs(err, model.ErrToolErrorBudgetExceeded)
+	s.ErrorIs(err, errMissing)
+	s.Len(fake.Requests(), 2)
+}
+
+func (s *ConformanceSuite) TestAPIErrorIsReturned() {
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{StatusCode: http.StatusInternalServerError, ErrorMessage: "conformance upstream failure"})
+
+	out, _, err := s.newString("Fail.", fake.URL).Generate(context.Background())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "conformance upstream failure")
+	s.Empty(out)
+}
+
+func (s *ConformanceSuite) TestMetricsRecorderIsCalledOnce() {
+	fake := NewFakeServer(s.T(), s.harness.Wire,
+		Turn{Text: "hello", InputTokens: 5, OutputTokens: 3},
+		Turn{StatusCode: http.StatusInternalServerError, ErrorMessage: "conformance upstream failure"},
+	)
+	var mu sync.Mutex
+	var records []model.GenerationRecord
+	recorder := model.WithMetricsRecorder(model.MetricsRecorderFunc(func(ctx context.Context, record model.GenerationRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		records = append(records, record)
+	}))
+
+	_, _, err := s.newString("Say hello.", fake.URL, recorder, model.WithModel("conformance-model")).Generate(context.Background())
+	s.Require().NoError(err)
+	_, _, err = s.newString("Fail.", fake.URL, recorder).Generate(context.Background())
+	s.Require().Error(err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.Require().Len(records, 2)
+	s.Equal(s.harness.Provider, records[0].Provider)
+	s.NotEmpty(records[0].Model)
+	s.Equal(int64(5), records[0].InputTokens)
+	s.Equal(int64(3), records[0].OutputTokens)
+	s.NoError(records[0].Err)
+	s.Equal(s.harness.Provider, records[1].Provider)
+	s.Error(records[1].Err)
+}
+
+func (s *ConformanceSuite) TestContextDeadlineIsHonoured() {
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{Hang: true})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := s.newString("Wait.", fake.URL).Generate(ctx)
+	s.Require().Error(err)
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *ConformanceSuite) TestUnsupportedOptions() {
+	for _, option := range s.harness.UnsupportedOptions {
+		fake := NewFakeServer(s.T(), s.harness.Wire, Turn{Text: "ok"})
+
+		gen, err := s.harness.NewString("Hi.", s.options(fake.URL, option)...)
+		if err == nil {
+			_, _, err = gen.Generate(context.Background())
+		}
+		s.Error(err, "unsupported option must fail without WithIgnoreInvalidGeneratorOptions")
+
+		out, _, err := s.newString("Hi.", fake.URL, option, model.WithIgnoreInvalidGeneratorOptions(true)).Generate(context.Background())
+		s.Require().NoError(err)
+		s.Equal("ok", out)
+	}
+}
+
+func (s *ConformanceSuite) TestStructuredOutput() {
+	if s.harness.NewStructured == nil {
+		s.T().Skip("provider has no structured generator")
+	}
+	fake := NewFakeServer(s.T(), s.harness.Wire, Turn{Text: "```json\n{\"status\":\"ok\",\"count\":2}\n```"})
+
+	gen, err := s.harness.NewStructured("Report the status.", s.options(fake.URL)...)
+	s.Require().NoError(err)
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal(Record{Status: "ok", Count: 2}, out)
+	s.Equal(s.harness.Provider, meta[model.MetadataKeyProvider])
+
+	requests := fake.Requests()
+	s.Require().NotEmpty(requests)
+	var sent strings.Builder
+	for _, message := range requests[0].Messages {
+		sent.WriteString(message.Content)
+	}
+	s.Contains(sent.String(), "status", "the output schema should be described to the model")
+}
+
+func (s *ConformanceSuite) TestStructuredAssertionFailuresAreRepaired() {
+	if s.harness.NewStructured == nil {
+		s.T().Skip("provider has no structured generator")
+	}
+	fake := NewFakeServer(s.T(), s.harness.Wire,
+		Turn{Text: `{"status":"ok","count":-1}`},
+		Turn{Text: `{"status":"ok","count":2}`},
+	)
+	countNotNegative := model.WithAssertion(func(record Record) error {
+		if record.Count < 0 {
+			return fmt.Errorf("count %d must not be negative", record.Count)
+		}
+		return nil
+	})
+
+	gen, err := s.harness.NewStructured("Report the status.", s.options(fake.URL, countNotNegative)...)
+	s.Require().NoError(err)
+	out, meta, err := gen.Generate(context.Background())
+	s.Require().NoError(err)
+	s.Equal(Record{Status: "ok", Count: 2}, out)
+	s.Equal("1", meta[model.MetadataKeyStructuredRepairs])
+
+	requests := fake.Requests()
+	s.Require().Len(requests, 2)
+	var repairPrompt strings.Builder
+	for _, message := range requests[1].Messages {
+		repairPrompt.WriteString(message.Content)
+	}
+	s.Contains(repairPrompt.String(), "count -1 must not be negative")
+}
+
+func splitSystem(messages []Message) (string, []Message) {
+	var system []string
+	conversation := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == RoleSystem {
+			system = append(system, message.Content)
+			continue
+		}
+		conversation = append(conversation, message)
+	}
+	return strings.Join(system, "\n"), conversation
+}
+
+type contextProviderFunc func(ctx context.Context) ([]*model.PromptContext, error)
+
+func (f contextProviderFunc) GenerateContext(ctx context.Context) ([]*model.PromptContext, error) {
+	return f(ctx)
+}
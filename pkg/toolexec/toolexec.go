@@ -0,0 +1,81 @@
+// Package toolexec runs the tool calls requested in one model round.
+package toolexec
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+)
+
+// Result is the outcome of one tool call.
+type Result struct {
+	Value    any
+	Err      error
+	Duration time.Duration
+}
+
+// Run invokes call for each index in [0, count) with at most parallelism calls
+// in flight and returns the results in call order. A parallelism below 2 runs
+// the calls sequentially. Every call runs even if an earlier one fails, so
+// callers decide how to handle errors once the round is complete.
+func Run(ctx context.Context, count int, parallelism int, call func(ctx context.Context, index int) (any, error)) []Result {
+	results := make([]Result, count)
+	if count == 0 {
+		return results
+	}
+
+	if parallelism < 2 || count == 1 {
+		for i := 0; i < count; i++ {
+			results[i] = timedCall(ctx, i, call)
+		}
+		return results
+	}
+
+	if parallelism > count {
+		parallelism = count
+	}
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[index] = timedCall(ctx, index, call)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// Transcript returns the model.ToolCallRecord of each result of one round,
+// numbered from 1. describe returns the tool name and the provider's call ID
+// (empty when it has none) of call i. Every provider builds its tool_calls
+// metadata through it so the records match field for field.
+func Transcript(round int, results []Result, describe func(i int) (name string, callID string)) []model.ToolCallRecord {
+	records := make([]model.ToolCallRecord, 0, len(results))
+	for i, result := range results {
+		name, callID := describe(i)
+		record := model.ToolCallRecord{
+			Round:      round,
+			Index:      i,
+			Name:       name,
+			CallID:     callID,
+			DurationMs: result.Duration.Milliseconds(),
+		}
+		if result.Err != nil {
+			record.Error = result.Err.Error()
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func timedCall(ctx context.Context, index int, call func(ctx context.Context, index int) (any, error)) Result {
+	start := time.Now()
+	value, err := call(ctx, index)
+	return Result{Value: value, Err: err, Duration: time.Since(start)}
+}
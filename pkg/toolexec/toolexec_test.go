@@ -0,0 +1,83 @@
+package toolexec
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nephrolytics-ai/polyglot-llm/pkg/model"
+	"github.com/stretchr/testify/suite"
+)
+
+type ToolExecSuite struct {
+	suite.Suite
+}
+
+func TestToolExecSuite(t *testing.T) {
+	suite.Run(t, new(ToolExecSuite))
+}
+
+func (s *ToolExecSuite) TestRunPreservesOrderAndBoundsConcurrency() {
+	var inFlight, peak int32
+	results := Run(context.Background(), 6, 3, func(ctx context.Context, i int) (any, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt32(&peak)
+			if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(6-i) * 5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		if i == 4 {
+			return nil, errors.New("failed")
+		}
+		return i * 10, nil
+	})
+
+	s.Require().Len(results, 6)
+	for i, result := range results {
+		if i == 4 {
+			s.Error(result.Err)
+			continue
+		}
+		s.NoError(result.Err)
+		s.Equal(i*10, result.Value)
+	}
+	s.LessOrEqual(peak, int32(3))
+	s.Greater(peak, int32(1))
+}
+
+func (s *ToolExecSuite) TestRunSequentially() {
+	var order []int
+	Run(context.Background(), 3, 1, func(ctx context.Context, i int) (any, error) {
+		order = append(order, i)
+		return nil, nil
+	})
+	s.Equal([]int{0, 1, 2}, order)
+
+	s.Empty(Run(context.Background(), 0, 4, nil))
+}
+
+func (s *ToolExecSuite) TestTranscript() {
+	results := Run(context.Background(), 2, 2, func(ctx context.Context, i int) (any, error) {
+		if i == 1 {
+			time.Sleep(5 * time.Millisecond)
+			return nil, errors.New("failed")
+		}
+		return "ok", nil
+	})
+
+	records := Transcript(3, results, func(i int) (string, string) {
+		return []string{"a", "b"}[i], []string{"call_a", ""}[i]
+	})
+	s.Require().Len(records, 2)
+	s.Equal(model.ToolCallRecord{Round: 3, Index: 0, Name: "a", CallID: "call_a", DurationMs: records[0].DurationMs}, records[0])
+	s.Equal(1, records[1].Index)
+	s.Empty(records[1].CallID)
+	s.Equal("failed", records[1].Error)
+	s.GreaterOrEqual(records[1].DurationMs, int64(5))
+	s.GreaterOrEqual(results[1].Duration, 5*time.Millisecond)
+}